@@ -0,0 +1,176 @@
+package gouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// LogFormat selects how Logger writes its lines
+type LogFormat string
+
+const (
+	LogText LogFormat = "text" // One readable line per request (default)
+	LogJSON LogFormat = "json" // One JSON object per line
+)
+
+// LoggerOption configures the Logger middleware
+type LoggerOption func(c *loggerConfig)
+
+// loggerConfig is the configuration built from LoggerOptions
+type loggerConfig struct {
+	format LogFormat
+	out    io.Writer
+	mu     sync.Mutex // Serializes the lines written to out
+}
+
+// LoggerFormat sets the line format of Logger
+func LoggerFormat(f LogFormat) LoggerOption {
+	return func(c *loggerConfig) {
+		c.format = f
+	}
+}
+
+// LoggerOutput writes the lines of Logger to out instead of log.Print
+// Lines are written one at a time, so out need not be safe for concurrent
+// use
+func LoggerOutput(out io.Writer) LoggerOption {
+	return func(c *loggerConfig) {
+		c.out = out
+	}
+}
+
+// AccessLogEntry is one request recorded by Logger; it is the JSON form
+// of the line
+type AccessLogEntry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Route      string    `json:"route,omitempty"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	BytesIn    int64     `json:"bytes_in"`  // Request bytes read from the connection
+	BytesOut   int64     `json:"bytes_out"` // Response bytes written, headers included
+	RemoteAddr string    `json:"remote_addr"`
+	DurationMs float64   `json:"duration_ms"`
+}
+
+// Logger creates a middleware logging each request once its response was
+// sent: method, path, status, body bytes, client address, duration, and the
+// bytes of the request and response on the wire
+// Args:
+//   - opts: LoggerFormat and LoggerOutput; the default is text through log.Print
+//
+// Register it last, or with a high Priority, so it wraps the other
+// middlewares and their time is counted.
+// Bytes are those of the body written by the handler: default error bodies
+// added afterwards are not included, unlike in BytesOut. Long paths are
+// shortened as in the other logs. A handler that panics is logged with
+// the 500 sent in its place. Requests served outside a connection
+// (e.g., cache revalidations) are not logged
+func Logger(opts ...LoggerOption) Middleware {
+	cfg := loggerConfig{format: LogText}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			start := r.Clock().Now()
+			// A panicking handler is logged as the 500 it becomes
+			defer func() {
+				rec := recover()
+				logRequest(&cfg, r, w, start, rec != nil)
+				if rec != nil {
+					panic(rec)
+				}
+			}()
+			next(r, w)
+		}
+	}
+}
+
+// logRequest records the request once its response is sent
+func logRequest(cfg *loggerConfig, r *Request, w *Writer, start time.Time, panicked bool) {
+	entry := AccessLogEntry{
+		Time:       start,
+		Method:     r.Method,
+		Path:       displayPath(r.path),
+		RemoteAddr: r.RemoteAddrs,
+	}
+	if r.route != nil {
+		entry.Route = r.route.Path
+	}
+	d := r.Clock().Now().Sub(start)
+	entry.DurationMs = float64(d.Microseconds()) / 1000
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	entry.Status, entry.Bytes = w.status(), w.bodyBytes()
+	if panicked && !w.headersSent {
+		// The buffered response is replaced (see Writer.failed)
+		entry.Status, entry.Bytes = http.StatusInternalServerError, 0
+	}
+	w.sentHooks = append(w.sentHooks, func(in, out int64) {
+		entry.BytesIn, entry.BytesOut = in, out
+		cfg.write(entry, d)
+	})
+}
+
+// write outputs one entry
+func (cfg *loggerConfig) write(e AccessLogEntry, d time.Duration) {
+	var line string
+	if cfg.format == LogJSON {
+		b, err := json.Marshal(e)
+		if err != nil {
+			log.Error(fmt.Errorf("failed to encode access log entry: %w", err))
+			return
+		}
+		line = string(b)
+	} else {
+		line = fmt.Sprintf("%s %s %s %d %dB %s %s in=%dB out=%dB", e.Time.Format(time.RFC3339), e.Method, e.Path, e.Status, e.Bytes, e.RemoteAddr, d.Round(time.Microsecond), e.BytesIn, e.BytesOut)
+	}
+
+	if cfg.out != nil {
+		cfg.mu.Lock()
+		io.WriteString(cfg.out, line+"\n")
+		cfg.mu.Unlock()
+		return
+	}
+	log.Print(line)
+}
+
+// sent runs the hooks waiting for the response to be sent, with the bytes
+// read and written for the request on the connection
+func (w *Writer) sent(in, out int64) {
+	w.mu.Lock()
+	hooks := w.sentHooks
+	w.sentHooks = nil
+	w.mu.Unlock()
+	for _, hook := range hooks {
+		hook(in, out)
+	}
+}
+
+// status returns the response status, 200 when the handler set none
+// The caller holds w.mu
+func (w *Writer) status() int {
+	if w.code == 0 {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// bodyBytes returns the size of the body written so far, buffered or
+// streamed; HEAD responses send none. The caller holds w.mu
+func (w *Writer) bodyBytes() int64 {
+	if w.noBody {
+		return 0
+	}
+	return w.streamed + int64(len(w.body))
+}
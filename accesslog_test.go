@@ -0,0 +1,248 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for the server and the test goroutine
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) lines() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return strings.Split(strings.TrimSpace(b.buf.String()), "\n")
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestLoggerWireBytes(t *testing.T) {
+	var out syncBuffer
+	r := newTestRouter()
+	r.Use(Logger(LoggerFormat(LogJSON), LoggerOutput(&out)))
+	r.Post("/echo", func(req *Request, w *Writer) {
+		b, _ := io.ReadAll(req.Body)
+		w.Write(b)
+	})
+	r.Get("/missing", func(req *Request, w *Writer) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	reqs := []string{
+		"POST /echo HTTP/1.1\r\nHost: x\r\nContent-Length: 11\r\n\r\nhello world",
+		"GET /missing HTTP/1.1\r\nHost: x\r\nX-Padding: " + strings.Repeat("p", 100) + "\r\n\r\n",
+	}
+	c, err := net.Dial("tcp", serveRouter(t, r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	// Pipelined, so the first request reads the second one ahead
+	io.WriteString(c, reqs[0]+reqs[1])
+
+	cr := &countingReader{r: c}
+	br := bufio.NewReaderSize(cr, 16)
+	var sizes []int
+	for range reqs {
+		before := cr.n - br.Buffered()
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		sizes = append(sizes, cr.n-br.Buffered()-before)
+	}
+
+	eventually(t, "both log lines", func() bool { return len(out.lines()) == 2 })
+	for i, line := range out.lines() {
+		var e AccessLogEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.BytesIn != int64(len(reqs[i])) {
+			t.Errorf("entry %d BytesIn = %d, want %d", i, e.BytesIn, len(reqs[i]))
+		}
+		if e.BytesOut != int64(sizes[i]) {
+			t.Errorf("entry %d BytesOut = %d, want %d", i, e.BytesOut, sizes[i])
+		}
+	}
+}
+
+func TestLoggerTextLine(t *testing.T) {
+	var out syncBuffer
+	r := newTestRouter()
+	r.Use(Logger(LoggerOutput(&out)))
+	r.Get("/items/:id", func(req *Request, w *Writer) {
+		w.Write([]byte("item"))
+	})
+
+	raw := "GET /items/7 HTTP/1.1\r\nHost: x\r\n\r\n"
+	rawExchange(t, serveRouter(t, r), raw, 1)
+
+	eventually(t, "the log line", func() bool { return out.lines()[0] != "" })
+	line := out.lines()[0]
+	for _, want := range []string{" GET /items/7 200 4B ", " in=" + strconv.Itoa(len(raw)) + "B ", " out="} {
+		if !strings.Contains(line, want) {
+			t.Errorf("log line %q lacks %q", line, want)
+		}
+	}
+}
+
+// overlapWriter records writes that overlap, as an output unsafe for
+// concurrent use would see them
+type overlapWriter struct {
+	busy     atomic.Bool
+	overlaps atomic.Int32
+	lines    atomic.Int32
+}
+
+func (o *overlapWriter) Write(p []byte) (int, error) {
+	if !o.busy.CompareAndSwap(false, true) {
+		o.overlaps.Add(1)
+	}
+	time.Sleep(time.Millisecond)
+	o.lines.Add(int32(bytes.Count(p, []byte("\n"))))
+	o.busy.Store(false)
+	return len(p), nil
+}
+
+func TestLoggerSerializesOutput(t *testing.T) {
+	const n = 16
+	var out overlapWriter
+	r := newTestRouter()
+	r.Use(Logger(LoggerOutput(&out)))
+	r.Get("/", func(req *Request, w *Writer) {
+		w.Write([]byte("ok"))
+	})
+	addr := serveRouter(t, r)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := http.Get("http://" + addr + "/")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+
+	eventually(t, "every log line", func() bool { return out.lines.Load() == n })
+	if got := out.overlaps.Load(); got != 0 {
+		t.Errorf("%d log lines written concurrently to the output", got)
+	}
+}
+
+func TestLoggerEntries(t *testing.T) {
+	start := time.Date(2026, 5, 1, 9, 30, 0, 0, time.UTC)
+	var elapsed atomic.Int64
+	var out syncBuffer
+	r := newTestRouter()
+	r.SetClock(func() time.Time { return start.Add(time.Duration(elapsed.Load())) })
+	r.Use(Logger(LoggerFormat(LogJSON), LoggerOutput(&out)))
+	r.Post("/items/:id", func(req *Request, w *Writer) {
+		elapsed.Add(int64(250 * time.Millisecond))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	})
+	r.Get("/plain", func(req *Request, w *Writer) { w.Write([]byte("plain")) })
+	r.Get("/stream", func(req *Request, w *Writer) {
+		w.Write([]byte("first "))
+		w.Flush()
+		w.Write([]byte("second"))
+	})
+	r.Get("/panic", func(req *Request, w *Writer) { panic("boom") })
+
+	c, err := net.Dial("tcp", serveRouter(t, r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	methods := []string{"POST", "GET", "HEAD", "GET", "GET"}
+	io.WriteString(c, "POST /items/7 HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n"+
+		"GET /plain HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"HEAD /plain HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"GET /stream HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"GET /panic HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(c)
+	for _, m := range methods {
+		resp, err := http.ReadResponse(br, &http.Request{Method: m})
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, resp.Body)
+	}
+
+	eventually(t, "every log line", func() bool { return len(out.lines()) == len(methods) })
+	want := []AccessLogEntry{
+		{Method: "POST", Path: "/items/7", Route: "/items/:id", Status: 201, Bytes: 7, DurationMs: 250},
+		{Method: "GET", Path: "/plain", Route: "/plain", Status: 200, Bytes: 5},
+		{Method: "HEAD", Path: "/plain", Route: "/plain", Status: 200, Bytes: 0},
+		{Method: "GET", Path: "/stream", Route: "/stream", Status: 200, Bytes: 12},
+		{Method: "GET", Path: "/panic", Route: "/panic", Status: 500},
+	}
+	for i, line := range out.lines() {
+		var e AccessLogEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.RemoteAddr != c.LocalAddr().String() {
+			t.Errorf("entry %d remote_addr = %q, want %q", i, e.RemoteAddr, c.LocalAddr())
+		}
+		if !e.Time.Equal(start.Add(250 * time.Millisecond * time.Duration(min(i, 1)))) {
+			t.Errorf("entry %d time = %v, want the router clock", i, e.Time)
+		}
+		w := want[i]
+		if e.Method != w.Method || e.Path != w.Path || e.Route != w.Route || e.Status != w.Status || e.Bytes != w.Bytes || e.DurationMs != w.DurationMs {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+}
+
+func TestLoggerDefaultOutput(t *testing.T) {
+	out := captureServe(t, func(t *testing.T) {
+		r := newTestRouter()
+		r.Use(Logger())
+		r.Get("/hello", func(req *Request, w *Writer) { w.Write([]byte("hi")) })
+		rawExchange(t, serveRouter(t, r), "GET /hello HTTP/1.1\r\nHost: x\r\n\r\n", 1)
+	})
+	if !strings.Contains(out, " GET /hello 200 2B ") {
+		t.Errorf("stdout = %q, want the access line through the log package", out)
+	}
+}
@@ -0,0 +1,136 @@
+package gouter
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Request values set by the auth middlewares
+const (
+	authUserKey   = "gouter.authUser"
+	authClaimsKey = "gouter.authClaims"
+)
+
+// defaultAuthRealm is the realm of auth challenges without an AuthRealm option
+const defaultAuthRealm = "Restricted"
+
+// AuthOption configures BasicAuth and BearerAuth
+type AuthOption func(c *authConfig)
+
+// authConfig is the configuration built from AuthOptions
+type authConfig struct {
+	realm string
+}
+
+// AuthRealm sets the realm announced in the WWW-Authenticate challenge
+func AuthRealm(realm string) AuthOption {
+	return func(c *authConfig) {
+		c.realm = realm
+	}
+}
+
+func newAuthConfig(opts []AuthOption) authConfig {
+	cfg := authConfig{realm: defaultAuthRealm}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	return cfg
+}
+
+// BasicAuth creates a middleware requiring HTTP Basic credentials that
+// validator accepts
+// Args:
+//   - validator: Checks a user name and password; compare secrets with
+//     crypto/subtle.ConstantTimeCompare
+//   - opts: AuthRealm
+//
+// Other requests get 401 with a Basic challenge. The accepted user name is
+// available to the next handlers through Request.AuthUser
+func BasicAuth(validator func(user, pass string) bool, opts ...AuthOption) Middleware {
+	cfg := newAuthConfig(opts)
+	challenge := `Basic realm=` + strconv.Quote(cfg.realm) + `, charset="UTF-8"`
+
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			user, pass, ok := basicCredentials(r.Headers.Get("Authorization"))
+			if !ok || !validator(user, pass) {
+				w.SetHeader("WWW-Authenticate", challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			r.SetValue(authUserKey, user)
+			next(r, w)
+		}
+	}
+}
+
+// BearerAuth creates a middleware requiring a bearer token that validator
+// accepts
+// Args:
+//   - validator: Checks a token and returns what it grants, e.g. its claims
+//   - opts: AuthRealm
+//
+// Other requests get 401 with a Bearer challenge, flagged invalid_token
+// when a token was sent (RFC 6750). The claims are available to the next
+// handlers through Request.AuthClaims
+func BearerAuth(validator func(token string) (claims any, ok bool), opts ...AuthOption) Middleware {
+	cfg := newAuthConfig(opts)
+	challenge := `Bearer realm=` + strconv.Quote(cfg.realm)
+
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			token, found := authCredentials(r.Headers.Get("Authorization"), "Bearer")
+			if !found || token == "" {
+				w.SetHeader("WWW-Authenticate", challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			claims, ok := validator(token)
+			if !ok {
+				w.SetHeader("WWW-Authenticate", challenge+`, error="invalid_token"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			r.SetValue(authClaimsKey, claims)
+			next(r, w)
+		}
+	}
+}
+
+// AuthUser returns the user name accepted by BasicAuth, or ""
+func (r *Request) AuthUser() string {
+	user, _ := r.Value(authUserKey).(string)
+	return user
+}
+
+// AuthClaims returns what the BearerAuth validator returned for the
+// request token, or nil
+func (r *Request) AuthClaims() any {
+	return r.Value(authClaimsKey)
+}
+
+// authCredentials returns the credentials of an Authorization header using
+// scheme, which is matched without case
+func authCredentials(header, scheme string) (string, bool) {
+	prefix, rest, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(prefix, scheme) {
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// basicCredentials decodes the user and password of a Basic Authorization
+// header
+func basicCredentials(header string) (user, pass string, ok bool) {
+	encoded, ok := authCredentials(header, "Basic")
+	if !ok {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
@@ -0,0 +1,115 @@
+package gouter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// authGet sends GET path to addr with an optional Authorization header
+func authGet(t *testing.T, addr, path, authorization string) *http.Response {
+	t.Helper()
+	raw := "GET " + path + " HTTP/1.1\r\nHost: x\r\n"
+	if authorization != "" {
+		raw += "Authorization: " + authorization + "\r\n"
+	}
+	return rawExchange(t, addr, raw+"\r\n", 1)[0]
+}
+
+func basic(user, pass string) string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+}
+
+func TestBasicAuth(t *testing.T) {
+	r := newTestRouter()
+	r.Use(BasicAuth(func(user, pass string) bool {
+		return user == "ana" && pass == "s3:cr3t"
+	}, AuthRealm("Admin")))
+	r.Get("/me", func(req *Request, w *Writer) {
+		w.Write([]byte(req.AuthUser()))
+	})
+	addr := serveRouter(t, r)
+
+	const challenge = `Basic realm="Admin", charset="UTF-8"`
+	for _, authorization := range []string{
+		"",
+		basic("ana", "wrong"),
+		basic("bob", "s3:cr3t"),
+		"Basic not-base64!",
+		"Basic " + base64.StdEncoding.EncodeToString([]byte("no-colon")),
+		"Bearer " + base64.StdEncoding.EncodeToString([]byte("ana:s3:cr3t")),
+	} {
+		resp := authGet(t, addr, "/me", authorization)
+		if resp.StatusCode != 401 || resp.Header.Get("WWW-Authenticate") != challenge {
+			t.Errorf("Authorization %q = %d, WWW-Authenticate %q", authorization, resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+
+	// The scheme is matched without case and the password may hold colons
+	for _, authorization := range []string{basic("ana", "s3:cr3t"), "basic " + basic("ana", "s3:cr3t")[len("Basic "):]} {
+		resp := authGet(t, addr, "/me", authorization)
+		if resp.StatusCode != 200 || bodyString(t, resp) != "ana" {
+			t.Errorf("Authorization %q = %d", authorization, resp.StatusCode)
+		}
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	r := newTestRouter()
+	r.Use(BearerAuth(func(token string) (any, bool) {
+		if token != "t0ken" {
+			return nil, false
+		}
+		return map[string]string{"sub": "ana"}, true
+	}))
+	r.Get("/me", func(req *Request, w *Writer) {
+		fmt.Fprint(w, req.AuthClaims(), req.AuthUser() == "")
+	})
+	addr := serveRouter(t, r)
+
+	for authorization, want := range map[string]string{
+		"":                   `Bearer realm="Restricted"`,
+		"Bearer":             `Bearer realm="Restricted"`,
+		"Bearer ":            `Bearer realm="Restricted"`,
+		basic("ana", "pass"): `Bearer realm="Restricted"`,
+		"Bearer other":       `Bearer realm="Restricted", error="invalid_token"`,
+	} {
+		resp := authGet(t, addr, "/me", authorization)
+		if resp.StatusCode != 401 || resp.Header.Get("WWW-Authenticate") != want {
+			t.Errorf("Authorization %q = %d, WWW-Authenticate %q, want %q", authorization, resp.StatusCode, resp.Header.Get("WWW-Authenticate"), want)
+		}
+	}
+
+	resp := authGet(t, addr, "/me", "bearer t0ken")
+	if resp.StatusCode != 200 {
+		t.Fatalf("valid token = %d", resp.StatusCode)
+	}
+	if got := bodyString(t, resp); got != "map[sub:ana] true" {
+		t.Errorf("handler saw %q, want the claims and no user", got)
+	}
+}
+
+func TestAuthOnGroup(t *testing.T) {
+	r := newTestRouter()
+	r.Group("/admin", func(g *Group) {
+		g.Use(BasicAuth(func(user, pass string) bool { return user == "root" && pass == "x" }))
+		g.Get("/stats", func(req *Request, w *Writer) {})
+	})
+	r.Get("/public", func(req *Request, w *Writer) {
+		if req.AuthUser() != "" {
+			t.Error("AuthUser set outside the guarded group")
+		}
+	})
+	addr := serveRouter(t, r)
+
+	if resp := authGet(t, addr, "/public", ""); resp.StatusCode != 200 {
+		t.Errorf("public route = %d", resp.StatusCode)
+	}
+	if resp := authGet(t, addr, "/admin/stats", ""); resp.StatusCode != 401 {
+		t.Errorf("guarded route without credentials = %d", resp.StatusCode)
+	}
+	if resp := authGet(t, addr, "/admin/stats", basic("root", "x")); resp.StatusCode != 200 {
+		t.Errorf("guarded route with credentials = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,98 @@
+package gouter
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+)
+
+// availability restricts when and for whom a route exists
+type availability struct {
+	after    time.Time // Route is unavailable before this instant
+	until    time.Time // Route is unavailable from this instant on
+	header   string    // Header that must be present on the request
+	status   int       // Status answered while unavailable (404 or 503)
+	hideDocs bool      // Hide from the docs while unavailable
+}
+
+// getAvailability returns the availability settings, creating them if needed
+func (r *RouteInfo) getAvailability() *availability {
+	if r.availability == nil {
+		r.availability = &availability{status: http.StatusNotFound}
+	}
+	return r.availability
+}
+
+// AvailableAfter makes the route respond as missing before t
+func (r *RouteInfo) AvailableAfter(t time.Time) *RouteInfo {
+	r.getAvailability().after = t
+	return r
+}
+
+// AvailableUntil makes the route respond as missing from t on
+func (r *RouteInfo) AvailableUntil(t time.Time) *RouteInfo {
+	r.getAvailability().until = t
+	return r
+}
+
+// RequireFeatureHeader makes the route respond as missing unless the request
+// carries a non-empty value for the given header (e.g., "X-Beta-Access")
+func (r *RouteInfo) RequireFeatureHeader(name string) *RouteInfo {
+	r.getAvailability().header = name
+	return r
+}
+
+// WhenUnavailable sets the status answered while the route is unavailable
+// Use 404 (default) to pretend the route does not exist, or 503 to announce
+// it with a Retry-After header when the opening time is known
+func (r *RouteInfo) WhenUnavailable(code int) *RouteInfo {
+	r.getAvailability().status = code
+	return r
+}
+
+// HideUntilAvailable hides the route from the docs while outside its window
+func (r *RouteInfo) HideUntilAvailable() *RouteInfo {
+	r.getAvailability().hideDocs = true
+	return r
+}
+
+// inWindow reports whether now falls inside the availability window
+func (a *availability) inWindow(now time.Time) bool {
+	if !a.after.IsZero() && now.Before(a.after) {
+		return false
+	}
+	if !a.until.IsZero() && !now.Before(a.until) {
+		return false
+	}
+	return true
+}
+
+// availabilityGuard wraps a route handler with its availability checks
+// The settings are read at request time, so setters called after registration
+// take effect; routes without settings only pay a nil check
+func availabilityGuard(router *Router, info *RouteInfo, next Handler) Handler {
+	return func(r *Request, w *Writer) {
+		a := info.availability
+		if a == nil {
+			next(r, w)
+			return
+		}
+
+		now := router.clock()
+		if !a.inWindow(now) {
+			if a.status == http.StatusServiceUnavailable && !a.after.IsZero() && now.Before(a.after) {
+				w.Headers.Add("Retry-After", httpdate.RetryAfter(a.after.Sub(now)))
+			}
+			w.WriteHeader(a.status)
+			return
+		}
+
+		if a.header != "" && r.Headers.Get(a.header) == "" {
+			w.WriteHeader(a.status)
+			return
+		}
+
+		next(r, w)
+	}
+}
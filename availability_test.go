@@ -0,0 +1,94 @@
+package gouter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAvailabilityWindow(t *testing.T) {
+	open := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	closing := open.Add(time.Hour)
+	now := open.Add(-time.Minute)
+
+	r := newTestRouter()
+	r.SetClock(func() time.Time { return now })
+	r.Get("/sale", func(req *Request, w *Writer) {
+		w.Write([]byte("sale"))
+	}).AvailableAfter(open).AvailableUntil(closing).HideUntilAvailable()
+	r.Get("/launch", func(req *Request, w *Writer) {}).AvailableAfter(open).WhenUnavailable(http.StatusServiceUnavailable)
+	addr := serveRouter(t, r)
+
+	get := func(path string) *http.Response {
+		return rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	}
+	visible := func() bool {
+		for _, doc := range r.visibleDocs() {
+			if doc.Path == "/sale" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if resp := get("/sale"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("before the window: %d, want 404", resp.StatusCode)
+	}
+	if visible() {
+		t.Error("route listed in the docs before its window")
+	}
+	resp := get("/launch")
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "60" {
+		t.Errorf("503 route before opening: %d Retry-After %q, want 503 and 60", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	now = open
+	if resp := get("/sale"); resp.StatusCode != http.StatusOK || bodyString(t, resp) != "sale" {
+		t.Errorf("at the opening instant: %d, want 200", resp.StatusCode)
+	}
+	if !visible() {
+		t.Error("route hidden from the docs inside its window")
+	}
+	if resp := get("/launch"); resp.StatusCode != http.StatusOK {
+		t.Errorf("503 route after opening: %d, want 200", resp.StatusCode)
+	}
+
+	now = closing.Add(-time.Nanosecond)
+	if resp := get("/sale"); resp.StatusCode != http.StatusOK {
+		t.Errorf("just before closing: %d, want 200", resp.StatusCode)
+	}
+
+	now = closing
+	if resp := get("/sale"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("at the closing instant: %d, want 404", resp.StatusCode)
+	}
+	if visible() {
+		t.Error("route listed in the docs after its window")
+	}
+}
+
+func TestAvailabilityFeatureHeader(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/beta", func(req *Request, w *Writer) {
+		w.Write([]byte("beta"))
+	}).RequireFeatureHeader("X-Beta-Access")
+	r.Get("/beta503", func(req *Request, w *Writer) {}).RequireFeatureHeader("X-Beta-Access").WhenUnavailable(http.StatusServiceUnavailable)
+	addr := serveRouter(t, r)
+
+	if resp := rawExchange(t, addr, "GET /beta HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != http.StatusNotFound {
+		t.Errorf("without the header: %d, want 404", resp.StatusCode)
+	}
+	if resp := rawExchange(t, addr, "GET /beta HTTP/1.1\r\nHost: x\r\nX-Beta-Access: \r\n\r\n", 1)[0]; resp.StatusCode != http.StatusNotFound {
+		t.Errorf("with an empty header: %d, want 404", resp.StatusCode)
+	}
+	resp := rawExchange(t, addr, "GET /beta HTTP/1.1\r\nHost: x\r\nx-beta-access: 1\r\n\r\n", 1)[0]
+	if resp.StatusCode != http.StatusOK || bodyString(t, resp) != "beta" {
+		t.Errorf("with the header: %d, want 200", resp.StatusCode)
+	}
+
+	// Header gating has no opening time to announce
+	resp = rawExchange(t, addr, "GET /beta503 HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") != "" {
+		t.Errorf("503 route without the header: %d Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+}
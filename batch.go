@@ -0,0 +1,221 @@
+package gouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// ErrBatchTooLarge is returned by BatchJSON for arrays over the element limit
+var ErrBatchTooLarge = errors.New("batch has too many elements")
+
+// BatchError reports which element of a batch failed to decode
+type BatchError struct {
+	Index int   // Zero-based element position
+	Err   error // Decoding or validation failure
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("element %d: %s", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchResult is the outcome of one batch element
+type BatchResult struct {
+	Status int    `json:"status"`          // HTTP status of the element
+	Body   any    `json:"body,omitempty"`  // Element response
+	Error  string `json:"error,omitempty"` // Failure description
+}
+
+// BatchJSON decodes a request body holding a top-level JSON array
+// Args:
+//   - r: Request whose body is decoded
+//   - max: Maximum number of elements, 0 for no limit
+//
+// Elements are decoded one at a time from the stream. Struct fields tagged
+// `json:"name,required"` must be present in every element. Errors are built
+// with StatusError so typed handlers answer them directly: 400 wrapping a
+// *BatchError naming the element, or 413 wrapping ErrBatchTooLarge or
+// ErrBodyTooLarge
+// The body is subject to ParserConfig.MaxBodyBytes and the request memory budget
+func BatchJSON[T any](r *Request, max int) ([]T, error) {
+	var body io.Reader = r.Body
+	if r.budget != nil {
+		body = budgetReader{r: body, b: r.budget}
+	}
+	dec := json.NewDecoder(body)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, batchStatusError(fmt.Errorf("invalid batch: %w", err))
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, StatusError(http.StatusBadRequest, errors.New("invalid batch: expected a JSON array"))
+	}
+
+	required := requiredJSONFields(reflect.TypeOf((*T)(nil)).Elem())
+
+	var items []T
+	for i := 0; dec.More(); i++ {
+		if max > 0 && i >= max {
+			return nil, StatusError(http.StatusRequestEntityTooLarge, fmt.Errorf("%w (max %d)", ErrBatchTooLarge, max))
+		}
+
+		var item T
+		if len(required) == 0 {
+			err = dec.Decode(&item)
+		} else {
+			err = decodeRequired(dec, &item, required)
+		}
+		if err != nil {
+			return nil, batchStatusError(&BatchError{Index: i, Err: err})
+		}
+		items = append(items, item)
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, batchStatusError(fmt.Errorf("invalid batch: %w", err))
+	}
+	return items, nil
+}
+
+// batchStatusError wraps a BatchJSON read error in a 413 StatusError when
+// the body went over MaxBodyBytes, and in a 400 otherwise
+func batchStatusError(err error) error {
+	if errors.Is(err, ErrBodyTooLarge) {
+		return StatusError(http.StatusRequestEntityTooLarge, err)
+	}
+	return StatusError(http.StatusBadRequest, err)
+}
+
+// StreamJSONArray decodes a request body holding a top-level JSON array one
+// element at a time, so arbitrarily long arrays use constant memory
+// Args:
+//   - fn: Called once per element with dec positioned to Decode it
+//
+// fn must consume exactly one element; returning an error stops the stream.
+// Errors are *BatchError values naming the element: malformed JSON is wrapped
+// in a 400 StatusError and a body over ParserConfig.MaxBodyBytes in a 413.
+// Unread bytes left by an early exit are drained after the handler, or the
+// connection is closed when too many remain
+func (r *Request) StreamJSONArray(fn func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r.Body)
+
+	tok, err := dec.Token()
+	if err != nil {
+		return streamError(-1, err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return StatusError(http.StatusBadRequest, errors.New("invalid body: expected a JSON array"))
+	}
+
+	for i := 0; dec.More(); i++ {
+		offset := dec.InputOffset()
+		if err := fn(dec); err != nil {
+			return streamError(i, err)
+		}
+		if dec.InputOffset() == offset {
+			return &BatchError{Index: i, Err: errors.New("element was not decoded")}
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return streamError(-1, err)
+	}
+	return nil
+}
+
+// streamError wraps an error of StreamJSONArray, attaching its status when
+// it comes from the body rather than from the callback
+// index is -1 for errors outside an element
+func streamError(index int, err error) error {
+	var (
+		syntax  *json.SyntaxError
+		typeErr *json.UnmarshalTypeError
+	)
+	if index >= 0 {
+		err = &BatchError{Index: index, Err: err}
+	} else {
+		err = fmt.Errorf("invalid body: %w", err)
+	}
+
+	switch {
+	case errors.Is(err, ErrBodyTooLarge):
+		return StatusError(http.StatusRequestEntityTooLarge, err)
+	case errors.As(err, &syntax), errors.As(err, &typeErr),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return StatusError(http.StatusBadRequest, err)
+	}
+	return err
+}
+
+// decodeRequired decodes one element, checking its required fields
+func decodeRequired(dec *json.Decoder, v any, required []string) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	for _, name := range required {
+		if _, ok := fields[name]; !ok {
+			return fmt.Errorf("missing field %q", name)
+		}
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// requiredJSONFields lists the JSON names of struct fields tagged ",required"
+func requiredJSONFields(t reflect.Type) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" || !strings.Contains(","+opts+",", ",required,") {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// WriteBatchResults writes one result per batch element as a JSON array
+// The response is 200 when every element succeeded (2xx), and 207 Multi-Status
+// otherwise; elements without a status are reported as 200
+func (w *Writer) WriteBatchResults(results []BatchResult) error {
+	status := http.StatusOK
+	for i := range results {
+		if results[i].Status == 0 {
+			results[i].Status = http.StatusOK
+		}
+		if results[i].Status < 200 || results[i].Status > 299 {
+			status = http.StatusMultiStatus
+		}
+	}
+	if results == nil {
+		results = []BatchResult{}
+	}
+
+	w.WriteHeader(status)
+	return w.WriteJson(results)
+}
@@ -0,0 +1,287 @@
+package gouter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// batchOp is one element of the batches sent in these tests
+type batchOp struct {
+	ID  string `json:"id,required"`
+	Qty int    `json:"qty"`
+}
+
+// batchRouter accepts up to three operations and rejects a zero quantity
+func batchRouter(t *testing.T) string {
+	r := newTestRouter()
+	r.Post("/batch", func(req *Request, w *Writer) {
+		ops, err := BatchJSON[batchOp](req, 3)
+		if err != nil {
+			code := http.StatusInternalServerError
+			var he *httpError
+			if errors.As(err, &he) {
+				code = he.code
+			}
+			Error(w, err, code)
+			return
+		}
+		results := make([]BatchResult, len(ops))
+		for i, op := range ops {
+			if op.Qty <= 0 {
+				results[i] = BatchResult{Status: http.StatusUnprocessableEntity, Error: "qty must be positive"}
+				continue
+			}
+			results[i] = BatchResult{Status: http.StatusCreated, Body: op.ID}
+		}
+		w.WriteBatchResults(results)
+	})
+	return serveRouter(t, r)
+}
+
+func TestBatchJSON(t *testing.T) {
+	addr := batchRouter(t)
+	post := func(body string) (int, string) {
+		raw := "POST /batch HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+		resp := rawExchange(t, addr, raw, 1)[0]
+		return resp.StatusCode, bodyString(t, resp)
+	}
+
+	code, body := post(`[{"id":"a","qty":1},{"id":"b","qty":2}]`)
+	var results []BatchResult
+	if err := json.Unmarshal([]byte(body), &results); err != nil || code != http.StatusOK || len(results) != 2 {
+		t.Fatalf("all succeeded: %d %s (%v), want 200 with two results", code, body, err)
+	}
+	if results[0].Status != http.StatusCreated || results[1].Body != "b" {
+		t.Errorf("results = %+v, want per-element 201 with the ids", results)
+	}
+
+	code, body = post(`[{"id":"a","qty":1},{"id":"b","qty":0}]`)
+	results = nil
+	json.Unmarshal([]byte(body), &results)
+	if code != http.StatusMultiStatus || len(results) != 2 {
+		t.Fatalf("partial failure: %d %s, want 207 with two results", code, body)
+	}
+	if r := results[1]; r.Status != http.StatusUnprocessableEntity || r.Error == "" || r.Body != nil {
+		t.Errorf("failed element = %+v, want 422 with an error and no body", r)
+	}
+	if results[0].Status != http.StatusCreated {
+		t.Errorf("succeeded element = %+v, want 201", results[0])
+	}
+
+	cases := []struct {
+		body string
+		code int
+		want string
+	}{
+		{`[{"id":"a"},{"id":"b"},{"qty":3}]`, 400, `element 2: missing field "id"`},
+		{`[{"id":"a"},{"id":7}]`, 400, "element 1:"},
+		{`[{"id":"a"},{"id":"b"},{"id":"c"},{"id":"d"}]`, 413, "too many elements"},
+		{`{"id":"a"}`, 400, "expected a JSON array"},
+		{`[{"id":"a"}`, 400, "element 1:"},
+		{``, 400, "invalid batch"},
+	}
+	for _, c := range cases {
+		code, body := post(c.body)
+		if code != c.code || !strings.Contains(body, c.want) {
+			t.Errorf("batch %s = %d %s, want %d mentioning %q", c.body, code, body, c.code, c.want)
+		}
+	}
+}
+
+func TestBatchJSONLarge(t *testing.T) {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i := 0; i < 10000; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		fmt.Fprintf(&b, `{"id":"op%d","qty":%d}`, i, i)
+	}
+	b.WriteByte(']')
+
+	req := newRequest()
+	req.Body = strings.NewReader(b.String())
+	ops, err := BatchJSON[batchOp](req, 0)
+	if err != nil || len(ops) != 10000 {
+		t.Fatalf("BatchJSON = %d elements, %v, want 10000", len(ops), err)
+	}
+	if last := ops[9999]; last.ID != "op9999" || last.Qty != 9999 {
+		t.Errorf("last element = %+v", last)
+	}
+
+	var batchErr *BatchError
+	req.Body = strings.NewReader(`[{"id":"a"},{"qty":1}]`)
+	if _, err := BatchJSON[batchOp](req, 0); !errors.As(err, &batchErr) || batchErr.Index != 1 {
+		t.Errorf("missing id = %v, want a *BatchError for element 1", err)
+	}
+	req.Body = strings.NewReader(`[{"id":"a"},{"id":"b"},{"id":"c"}]`)
+	if _, err := BatchJSON[batchOp](req, 2); !errors.Is(err, ErrBatchTooLarge) {
+		t.Errorf("over max = %v, want ErrBatchTooLarge", err)
+	}
+}
+
+func TestBatchJSONBodyLimit(t *testing.T) {
+	r := newTestRouter()
+	r.UpdateParser(func(p *ParserConfig) { p.MaxBodyBytes = 64 })
+	r.Post("/batch", func(req *Request, w *Writer) {
+		_, err := BatchJSON[batchOp](req, 0)
+		var he *httpError
+		if !errors.As(err, &he) {
+			t.Errorf("BatchJSON = %v, want a StatusError", err)
+			return
+		}
+		Error(w, err, he.code)
+	})
+	addr := serveRouter(t, r)
+
+	body := "[" + strings.Repeat(`{"id":"a"},`, 20) + `{"id":"a"}]`
+	raw := "POST /batch HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		strconv.FormatInt(int64(len(body)), 16) + "\r\n" + body + "\r\n0\r\n\r\n"
+	resp := rawExchange(t, addr, raw, 1)[0]
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed batch over MaxBodyBytes = %d %s, want 413", resp.StatusCode, bodyString(t, resp))
+	}
+}
+
+// arrayReader generates a JSON array of n batchOp elements on the fly
+type arrayReader struct {
+	n, next int
+	buf     []byte
+}
+
+func (a *arrayReader) Read(p []byte) (int, error) {
+	for len(a.buf) < len(p) && a.next <= a.n {
+		switch {
+		case a.next == a.n:
+			a.buf = append(a.buf, ']')
+		case a.next == 0:
+			a.buf = append(a.buf, `[{"id":"op0","qty":0}`...)
+		default:
+			a.buf = fmt.Appendf(a.buf, `,{"id":"op%d","qty":%d}`, a.next, a.next)
+		}
+		a.next++
+	}
+	if len(a.buf) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, a.buf)
+	a.buf = a.buf[:copy(a.buf, a.buf[n:])]
+	return n, nil
+}
+
+func TestStreamJSONArrayConstantMemory(t *testing.T) {
+	const elements = 100000
+	live := func() uint64 {
+		var m runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		return m.HeapAlloc
+	}
+
+	req := newRequest()
+	req.Body = &arrayReader{n: elements}
+	base := live()
+	var count, sum int
+	var peak uint64
+	err := req.StreamJSONArray(func(dec *json.Decoder) error {
+		var op batchOp
+		if err := dec.Decode(&op); err != nil {
+			return err
+		}
+		count++
+		sum += op.Qty
+		if count%20000 == 0 {
+			peak = max(peak, live())
+		}
+		return nil
+	})
+	if err != nil || count != elements || sum != elements*(elements-1)/2 {
+		t.Fatalf("StreamJSONArray = %v after %d elements (sum %d)", err, count, sum)
+	}
+	// The ~3MB body never has to be held at once
+	if peak > base+512<<10 {
+		t.Errorf("live heap grew by %d bytes while streaming, want under 512KB", peak-base)
+	}
+}
+
+func TestStreamJSONArray(t *testing.T) {
+	abort := errors.New("import aborted")
+	r := newTestRouter()
+	r.UpdateParser(func(p *ParserConfig) { p.MaxBodyBytes = 1 << 20 })
+	r.Post("/import", func(req *Request, w *Writer) {
+		stop, _ := strconv.Atoi(req.Query().Get("stop"))
+		err := req.StreamJSONArray(func(dec *json.Decoder) error {
+			var op batchOp
+			if err := dec.Decode(&op); err != nil {
+				return err
+			}
+			if stop > 0 && op.Qty == stop {
+				return abort
+			}
+			return nil
+		})
+		var he *httpError
+		switch {
+		case errors.As(err, &he):
+			Error(w, err, he.code)
+		case err != nil:
+			Error(w, err, http.StatusUnprocessableEntity)
+		}
+	})
+	r.Get("/next", func(req *Request, w *Writer) { w.Write([]byte("next")) })
+	addr := serveRouter(t, r)
+
+	array := func(n int) string {
+		b, _ := io.ReadAll(&arrayReader{n: n})
+		return string(b)
+	}
+	post := func(query, body string, chunked bool) string {
+		head := "POST /import" + query + " HTTP/1.1\r\nHost: x\r\n"
+		if chunked {
+			return head + "Transfer-Encoding: chunked\r\n\r\n" + strconv.FormatInt(int64(len(body)), 16) + "\r\n" + body + "\r\n0\r\n\r\n"
+		}
+		return head + "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	}
+	next := "GET /next HTTP/1.1\r\nHost: x\r\n\r\n"
+
+	// An early exit with a small rest drains it and keeps the connection
+	resps := rawExchange(t, addr, post("?stop=5", array(100), false)+next, 2)
+	if body := bodyString(t, resps[0]); resps[0].StatusCode != 422 || !strings.Contains(body, "element 5: import aborted") {
+		t.Errorf("early abort = %d %q, want 422 naming element 5", resps[0].StatusCode, body)
+	}
+	if resps[0].Close || bodyString(t, resps[1]) != "next" {
+		t.Error("connection not reused after draining a small rest")
+	}
+
+	// Too much left to drain: the connection closes after the response
+	resps = rawExchange(t, addr, post("?stop=5", array(30000), false), 1)
+	if resps[0].StatusCode != 422 || !resps[0].Close {
+		t.Errorf("early abort with a large rest = %d close=%v, want 422 closing the connection", resps[0].StatusCode, resps[0].Close)
+	}
+
+	cases := []struct {
+		body    string
+		chunked bool
+		code    int
+		want    string
+	}{
+		{array(60000), true, 413, "request body too large"},
+		{`{"id":"a"}`, false, 400, "expected a JSON array"},
+		{`[{"id":"a"},{"qty":"x"}]`, false, 400, "element 1:"},
+		{`[{"id":"a"}`, false, 400, "element 1:"},
+		{`[]`, false, 200, ""},
+	}
+	for _, c := range cases {
+		resp := rawExchange(t, addr, post("", c.body, c.chunked), 1)[0]
+		if body := bodyString(t, resp); resp.StatusCode != c.code || !strings.Contains(body, c.want) {
+			t.Errorf("body %.40s = %d %q, want %d mentioning %q", c.body, resp.StatusCode, body, c.code, c.want)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package gouter
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SetMaxBodyBytes replaces ParserConfig.MaxBodyBytes for the route, e.g.
+// to accept large uploads on one endpoint only; a negative n removes the
+// limit
+// Declared bodies over the limit get 413 before the handler runs; chunked
+// bodies fail with ErrBodyTooLarge once a read passes it
+func (r *RouteInfo) SetMaxBodyBytes(n int64) *RouteInfo {
+	r.maxBody = n
+	return r
+}
+
+// bodyLimit returns the body limit of a request matched to route, which is
+// nil for unmatched requests
+func (r *Router) bodyLimit(route *RouteInfo) int64 {
+	if route != nil && route.maxBody != 0 {
+		return route.maxBody
+	}
+	return r.parserConfig.MaxBodyBytes
+}
+
+// limitBody applies a positive body limit to req
+// It returns ErrBodyTooLarge without reading anything when the declared
+// Content-Length is over the limit, and otherwise caps reads of the body
+func limitBody(req *Request, limit int64) error {
+	if limit <= 0 || req.Body == nil {
+		return nil
+	}
+	if cl := req.Headers.Get("content-length"); cl != "" {
+		// Validated by newBodyReader, duplicates included
+		v, _, _ := strings.Cut(cl, ",")
+		if n, _ := strconv.ParseInt(strings.TrimSpace(v), 10, 64); n > limit {
+			return ErrBodyTooLarge
+		}
+	}
+	req.Body = &maxBytesReader{r: req.Body, remaining: limit}
+	return nil
+}
@@ -0,0 +1,134 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// sendBody sends body to path, declared with Content-Length or chunked
+func sendBody(t *testing.T, addr, method, path, body string, chunked bool) *http.Response {
+	t.Helper()
+	raw := method + " " + path + " HTTP/1.1\r\nHost: x\r\n"
+	if chunked {
+		raw += "Transfer-Encoding: chunked\r\n\r\n" + strconv.FormatInt(int64(len(body)), 16) + "\r\n" + body + "\r\n0\r\n\r\n"
+	} else {
+		raw += "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	}
+	return rawExchange(t, addr, raw, 1)[0]
+}
+
+func TestRouteBodyLimits(t *testing.T) {
+	var ran atomic.Int32
+	echo := func(req *Request, w *Writer) {
+		ran.Add(1)
+		b, err := io.ReadAll(req.Body)
+		if errors.Is(err, ErrBodyTooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write([]byte(strconv.Itoa(len(b))))
+	}
+	r := newTestRouter()
+	r.UpdateParser(func(p *ParserConfig) { p.MaxBodyBytes = 100 })
+	r.Post("/default", echo)
+	r.Post("/large", echo).SetMaxBodyBytes(1000)
+	r.Post("/small", echo).SetMaxBodyBytes(10)
+	r.Post("/unlimited", echo).SetMaxBodyBytes(-1)
+	// Each method of a path keeps its own limit
+	r.Get("/split", echo).SetMaxBodyBytes(10)
+	r.Post("/split", echo).SetMaxBodyBytes(1000)
+	r.Put("/split", echo)
+	r.Group("/g", func(g *Group) {
+		g.Get("/split/:id", echo)
+		g.Post("/split/:id", echo).SetMaxBodyBytes(1000)
+	})
+	addr := serveRouter(t, r)
+
+	for _, tc := range []struct {
+		method, path string
+		size         int
+		want         int
+	}{
+		{"POST", "/default", 100, 200},
+		{"POST", "/default", 101, 413},
+		{"POST", "/large", 1000, 200},
+		{"POST", "/large", 1001, 413},
+		{"POST", "/small", 10, 200},
+		{"POST", "/small", 11, 413},
+		{"POST", "/unlimited", 5000, 200},
+		{"POST", "/split", 500, 200},
+		{"POST", "/split", 1001, 413},
+		{"GET", "/split", 10, 200},
+		{"GET", "/split", 11, 413},
+		{"PUT", "/split", 100, 200},
+		{"PUT", "/split", 101, 413},
+		{"POST", "/g/split/1", 500, 200},
+		{"GET", "/g/split/1", 101, 413},
+	} {
+		for _, chunked := range []bool{false, true} {
+			ran.Store(0)
+			resp := sendBody(t, addr, tc.method, tc.path, strings.Repeat("x", tc.size), chunked)
+			if resp.StatusCode != tc.want {
+				t.Errorf("%s %s with %d bytes (chunked %v) = %d, want %d", tc.method, tc.path, tc.size, chunked, resp.StatusCode, tc.want)
+			}
+			// Declared lengths over the limit are refused before the handler
+			if !chunked && tc.want == 413 && ran.Load() != 0 {
+				t.Errorf("%s %s with %d declared bytes ran the handler", tc.method, tc.path, tc.size)
+			}
+		}
+	}
+}
+
+func TestRouteBodyLimitAtRequestTime(t *testing.T) {
+	r := newTestRouter()
+	route := r.Post("/up", func(req *Request, w *Writer) {})
+	addr := serveRouter(t, r)
+
+	if resp := sendBody(t, addr, "POST", "/up", strings.Repeat("x", 64), false); resp.StatusCode != 200 {
+		t.Fatalf("unlimited by default = %d", resp.StatusCode)
+	}
+	route.SetMaxBodyBytes(32)
+	if resp := sendBody(t, addr, "POST", "/up", strings.Repeat("x", 64), false); resp.StatusCode != 413 {
+		t.Errorf("after SetMaxBodyBytes = %d, want 413", resp.StatusCode)
+	}
+}
+
+func TestReceiveFileLimit(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRouter()
+	r.Post("/file/:name", func(req *Request, w *Writer) {
+		f, err := ReceiveFile(req, filepath.Join(dir, req.Params.Get("name")))
+		if errors.Is(err, ErrBodyTooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		f.Close()
+	}).SetMaxBodyBytes(1 << 20)
+	addr := serveRouter(t, r)
+
+	if resp := sendBody(t, addr, "POST", "/file/ok.bin", strings.Repeat("a", 1<<20), true); resp.StatusCode != 200 {
+		t.Fatalf("upload at the limit = %d", resp.StatusCode)
+	}
+	if info, err := os.Stat(filepath.Join(dir, "ok.bin")); err != nil || info.Size() != 1<<20 {
+		t.Errorf("stored upload = %v, %v", info, err)
+	}
+
+	// A chunked body passing the limit fails while streamed, leaving no file
+	if resp := sendBody(t, addr, "POST", "/file/big.bin", strings.Repeat("a", 1<<20+1), true); resp.StatusCode != 413 {
+		t.Errorf("upload over the limit = %d, want 413", resp.StatusCode)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "big.bin")); !os.IsNotExist(err) {
+		t.Errorf("partial upload left behind: %v", err)
+	}
+}
@@ -0,0 +1,240 @@
+package gouter
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+)
+
+// BreakerState is the state of a route circuit
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Requests flow, outcomes are counted
+	BreakerOpen     BreakerState = "open"      // Requests are answered 503 until the cooldown ends
+	BreakerHalfOpen BreakerState = "half-open" // Trial requests decide between closed and open
+)
+
+// BreakerConfig configures a CircuitBreaker
+// Zero values use the defaults given in each field
+type BreakerConfig struct {
+	FailureThreshold float64       // Failure rate in the window that opens the circuit (default: 0.5)
+	MinRequests      int           // Requests in the window before the rate counts (default: 10)
+	Window           time.Duration // Period outcomes are counted over (default: 10s)
+	Cooldown         time.Duration // Time the circuit stays open (default: 30s)
+	HalfOpenRequests int           // Trial requests let through while half-open (default: 1)
+
+	// Classify reports whether an outcome is a failure; err is non-nil when
+	// the handler panicked. Default: status >= 500 or a panic
+	Classify func(status int, err error) bool
+
+	// OnStateChange is called after a route circuit changes state, e.g. to
+	// feed metrics; it must not block
+	OnStateChange func(route string, from, to BreakerState)
+
+	Now func() time.Time // Time source, the Clock of the request when nil
+}
+
+// RouteCircuit describes the circuit of one route
+type RouteCircuit struct {
+	Route     string       `json:"route"`
+	State     BreakerState `json:"state"`
+	Requests  int          `json:"requests"`   // Requests counted in the current window
+	Failures  int          `json:"failures"`   // Failures counted in the current window
+	OpenUntil time.Time    `json:"open_until"` // End of the cooldown while open
+}
+
+// CircuitBreaker answers 503 for routes whose handlers keep failing
+// Circuits are kept per matched route pattern
+type CircuitBreaker struct {
+	cfg BreakerConfig
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// circuit is the state of one route
+type circuit struct {
+	state       BreakerState
+	windowStart time.Time
+	requests    int
+	failures    int
+	openUntil   time.Time
+	trials      int // Trial requests in flight while half-open
+}
+
+// NewCircuitBreaker creates a breaker; register it with Use(cb.Middleware())
+func NewCircuitBreaker(cfg BreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 0.5
+	}
+	if cfg.MinRequests <= 0 {
+		cfg.MinRequests = 10
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 30 * time.Second
+	}
+	if cfg.HalfOpenRequests <= 0 {
+		cfg.HalfOpenRequests = 1
+	}
+	if cfg.Classify == nil {
+		cfg.Classify = func(status int, err error) bool {
+			return err != nil || status >= 500
+		}
+	}
+	return &CircuitBreaker{cfg: cfg, circuits: make(map[string]*circuit)}
+}
+
+// Middleware counts handler outcomes and rejects requests to open circuits
+// with 503 and Retry-After. A panic counts as a failure and keeps unwinding
+func (cb *CircuitBreaker) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			route := ""
+			if r.route != nil {
+				route = r.route.Path
+			}
+
+			trial, wait, ok := cb.allow(route, cb.now(r))
+			if !ok {
+				w.SetHeader("Retry-After", httpdate.RetryAfter(wait))
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			defer func() {
+				if rec := recover(); rec != nil {
+					cb.record(route, trial, cb.cfg.Classify(http.StatusInternalServerError, fmt.Errorf("panic: %v", rec)), cb.now(r))
+					panic(rec)
+				}
+			}()
+			next(r, w)
+
+			w.mu.Lock()
+			status := w.code
+			w.mu.Unlock()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			cb.record(route, trial, cb.cfg.Classify(status, nil), cb.now(r))
+		}
+	}
+}
+
+// now returns the time from BreakerConfig.Now or the request Clock
+func (cb *CircuitBreaker) now(r *Request) time.Time {
+	if cb.cfg.Now != nil {
+		return cb.cfg.Now()
+	}
+	return r.Clock().Now()
+}
+
+// allow decides whether a request may run; trial marks half-open trials and
+// wait is the cooldown left for rejected requests
+func (cb *CircuitBreaker) allow(route string, now time.Time) (trial bool, wait time.Duration, ok bool) {
+	cb.mu.Lock()
+	c := cb.circuits[route]
+	if c == nil {
+		c = &circuit{state: BreakerClosed, windowStart: now}
+		cb.circuits[route] = c
+	}
+
+	var changed BreakerState
+	if c.state == BreakerOpen {
+		if now.Before(c.openUntil) {
+			cb.mu.Unlock()
+			return false, c.openUntil.Sub(now), false
+		}
+		c.state, c.trials = BreakerHalfOpen, 0
+		changed = BreakerOpen
+	}
+
+	if c.state == BreakerHalfOpen {
+		if c.trials >= cb.cfg.HalfOpenRequests {
+			cb.mu.Unlock()
+			cb.notify(route, changed, BreakerHalfOpen)
+			return false, cb.cfg.Cooldown, false
+		}
+		c.trials++
+		cb.mu.Unlock()
+		cb.notify(route, changed, BreakerHalfOpen)
+		return true, 0, true
+	}
+
+	cb.mu.Unlock()
+	return false, 0, true
+}
+
+// record counts an outcome, opening or closing the circuit as needed
+func (cb *CircuitBreaker) record(route string, trial, failed bool, now time.Time) {
+	cb.mu.Lock()
+	c := cb.circuits[route]
+	from := c.state
+	switch {
+	case trial && c.state == BreakerHalfOpen:
+		c.trials--
+		if failed {
+			c.state, c.openUntil = BreakerOpen, now.Add(cb.cfg.Cooldown)
+		} else if c.trials == 0 {
+			c.state, c.windowStart, c.requests, c.failures = BreakerClosed, now, 0, 0
+		}
+
+	case c.state == BreakerClosed:
+		if now.Sub(c.windowStart) >= cb.cfg.Window {
+			c.windowStart, c.requests, c.failures = now, 0, 0
+		}
+		c.requests++
+		if failed {
+			c.failures++
+		}
+		if c.requests >= cb.cfg.MinRequests && float64(c.failures)/float64(c.requests) >= cb.cfg.FailureThreshold {
+			c.state, c.openUntil = BreakerOpen, now.Add(cb.cfg.Cooldown)
+		}
+	}
+	to := c.state
+	cb.mu.Unlock()
+
+	if from != to {
+		cb.notify(route, from, to)
+	}
+}
+
+// notify reports a state change; from is empty when nothing changed
+func (cb *CircuitBreaker) notify(route string, from, to BreakerState) {
+	if from != "" && from != to && cb.cfg.OnStateChange != nil {
+		cb.cfg.OnStateChange(route, from, to)
+	}
+}
+
+// Circuits returns the state of every route seen so far, sorted by route
+func (cb *CircuitBreaker) Circuits() []RouteCircuit {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	circuits := make([]RouteCircuit, 0, len(cb.circuits))
+	for route, c := range cb.circuits {
+		rc := RouteCircuit{Route: route, State: c.state, Requests: c.requests, Failures: c.failures}
+		if c.state == BreakerOpen {
+			rc.OpenUntil = c.openUntil
+		}
+		circuits = append(circuits, rc)
+	}
+	sort.Slice(circuits, func(i, j int) bool {
+		return circuits[i].Route < circuits[j].Route
+	})
+	return circuits
+}
+
+// AdminHandler answers the Circuits as JSON
+// It is meant for an internal route, e.g. r.Route("/admin/circuits", cb.AdminHandler)
+func (cb *CircuitBreaker) AdminHandler(r *Request, w *Writer) {
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteJson(cb.Circuits())
+}
@@ -0,0 +1,192 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// breakerLog records the state changes of a CircuitBreaker
+type breakerLog struct {
+	mu      sync.Mutex
+	changes []string
+}
+
+func (l *breakerLog) record(route string, from, to BreakerState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes = append(l.changes, route+" "+string(from)+"→"+string(to))
+}
+
+func (l *breakerLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(l.changes, ", ")
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var elapsed atomic.Int64
+	advance := func(d time.Duration) { elapsed.Add(int64(d)) }
+	changes := &breakerLog{}
+	cb := NewCircuitBreaker(BreakerConfig{
+		MinRequests:   4,
+		Window:        10 * time.Second,
+		Cooldown:      30 * time.Second,
+		OnStateChange: changes.record,
+		Now:           func() time.Time { return start.Add(time.Duration(elapsed.Load())) },
+	})
+
+	var failing atomic.Bool
+	var calls atomic.Int32
+	r := newTestRouter()
+	r.Use(cb.Middleware())
+	r.Get("/flaky/:id", func(req *Request, w *Writer) {
+		calls.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+	r.Get("/steady", func(req *Request, w *Writer) {})
+	r.Get("/admin/circuits", cb.AdminHandler)
+	addr := serveRouter(t, r)
+
+	get := func(path string) (int, string) {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		return resp.StatusCode, resp.Header.Get("Retry-After")
+	}
+	expect := func(step, path string, code int, retryAfter string) {
+		t.Helper()
+		if got, ra := get(path); got != code || ra != retryAfter {
+			t.Errorf("%s: %s = %d (Retry-After %q), want %d (%q)", step, path, got, ra, code, retryAfter)
+		}
+	}
+
+	// Failures outside the window do not count
+	failing.Store(true)
+	expect("old failure", "/flaky/1", 502, "")
+	advance(11 * time.Second)
+	failing.Store(false)
+	expect("closed", "/flaky/1", 200, "")
+	expect("closed", "/flaky/2", 200, "")
+	failing.Store(true)
+	expect("closed", "/flaky/3", 502, "")
+	if changes.String() != "" {
+		t.Fatalf("circuit changed after 1 failure in 3 requests: %s", changes)
+	}
+	expect("threshold", "/flaky/4", 502, "")
+
+	// Open: rejected without running the handler, other routes unaffected
+	before := calls.Load()
+	expect("open", "/flaky/5", 503, "30")
+	advance(10 * time.Second)
+	expect("open", "/flaky/6", 503, "20")
+	if calls.Load() != before {
+		t.Error("handler ran while the circuit was open")
+	}
+	expect("other route", "/steady", 200, "")
+
+	var circuits []RouteCircuit
+	resp := rawExchange(t, addr, "GET /admin/circuits HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if err := json.Unmarshal([]byte(bodyString(t, resp)), &circuits); err != nil {
+		t.Fatal(err)
+	}
+	var flaky RouteCircuit
+	for _, c := range circuits {
+		if c.Route == "/flaky/:id" {
+			flaky = c
+		}
+	}
+	if flaky.State != BreakerOpen || flaky.Failures != 2 || !flaky.OpenUntil.Equal(start.Add(41*time.Second)) {
+		t.Errorf("admin circuit = %+v, want open on the route pattern until the cooldown ends", flaky)
+	}
+
+	// A failed trial opens the circuit again, a good one closes it
+	advance(20 * time.Second)
+	expect("failed trial", "/flaky/7", 502, "")
+	expect("reopened", "/flaky/8", 503, "30")
+	advance(30 * time.Second)
+	failing.Store(false)
+	expect("good trial", "/flaky/9", 200, "")
+	expect("closed again", "/flaky/10", 200, "")
+
+	want := "/flaky/:id closed→open, /flaky/:id open→half-open, /flaky/:id half-open→open, " +
+		"/flaky/:id open→half-open, /flaky/:id half-open→closed"
+	if got := changes.String(); got != want {
+		t.Errorf("transitions = %s\nwant %s", got, want)
+	}
+}
+
+func TestCircuitBreakerHalfOpenTrials(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	cb := NewCircuitBreaker(BreakerConfig{
+		MinRequests: 1,
+		Cooldown:    time.Minute,
+		// Rate limiting upstream counts as a failure too
+		Classify: func(status int, err error) bool { return err != nil || status == http.StatusTooManyRequests },
+		Now: func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			return now
+		},
+	})
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	var mode atomic.Value
+	mode.Store("throttled")
+	r := newTestRouter()
+	r.Use(cb.Middleware())
+	r.Get("/dep", func(req *Request, w *Writer) {
+		switch mode.Load() {
+		case "throttled":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "panic":
+			panic("dependency exploded")
+		case "slow":
+			close(entered)
+			<-release
+		}
+	})
+	addr := serveRouter(t, r)
+	get := func() int {
+		return rawExchange(t, addr, "GET /dep HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0].StatusCode
+	}
+
+	if code := get(); code != 429 || cb.Circuits()[0].State != BreakerOpen {
+		t.Fatalf("429 = %d with circuit %+v, want it classified as a failure", code, cb.Circuits())
+	}
+
+	// A panicking trial counts as a failure
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	mode.Store("panic")
+	if code := get(); code != 500 || cb.Circuits()[0].State != BreakerOpen {
+		t.Fatalf("panicking trial = %d with circuit %+v, want it open again", code, cb.Circuits())
+	}
+
+	// While the single trial runs, other requests are still rejected
+	mu.Lock()
+	now = now.Add(time.Minute)
+	mu.Unlock()
+	mode.Store("slow")
+	trial := make(chan int)
+	go func() { trial <- get() }()
+	<-entered
+	if code := get(); code != 503 {
+		t.Errorf("request during the trial = %d, want 503", code)
+	}
+	close(release)
+	if code := <-trial; code != 200 {
+		t.Errorf("trial = %d, want 200", code)
+	}
+	if st := cb.Circuits()[0]; st.State != BreakerClosed || st.Requests != 0 {
+		t.Errorf("after a good trial = %+v, want closed with a fresh window", st)
+	}
+}
@@ -0,0 +1,86 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// headerEntryOverhead approximates the map and string headers of one header
+const headerEntryOverhead = 48
+
+// ErrMemoryBudget is returned when a request exceeds Server.MaxMemoryPerRequest
+// The request is answered with 503 unless its response was already sent
+var ErrMemoryBudget = errors.New("request memory budget exceeded")
+
+// memBudget tracks the approximate memory a request buffers: its headers,
+// bodies read into memory and the buffered response
+// It is bookkeeping only, the allocator is not involved
+type memBudget struct {
+	limit    int64 // 0 for no limit
+	used     atomic.Int64
+	exceeded atomic.Bool
+}
+
+// newMemBudget returns a budget, or nil when limit disables accounting
+func newMemBudget(limit int64) *memBudget {
+	if limit <= 0 {
+		return nil
+	}
+	return &memBudget{limit: limit}
+}
+
+// charge accounts n more bytes, failing once the limit is passed
+func (b *memBudget) charge(n int) error {
+	if b == nil {
+		return nil
+	}
+	if b.used.Add(int64(n)) > b.limit {
+		b.exceeded.Store(true)
+		return ErrMemoryBudget
+	}
+	return nil
+}
+
+// release returns n bytes no longer held, e.g. a buffer that was flushed
+func (b *memBudget) release(n int) {
+	if b != nil {
+		b.used.Add(-int64(n))
+	}
+}
+
+// overBudget reports whether any charge failed
+func (b *memBudget) overBudget() bool {
+	return b != nil && b.exceeded.Load()
+}
+
+// headerCost estimates the memory held by parsed headers
+func headerCost(h Headers) int {
+	n := 0
+	for k, v := range h {
+		n += len(k) + len(v) + headerEntryOverhead
+	}
+	return n
+}
+
+// budgetReader charges every byte read into memory against a budget
+type budgetReader struct {
+	r io.Reader
+	b *memBudget
+}
+
+func (br budgetReader) Read(p []byte) (int, error) {
+	n, err := br.r.Read(p)
+	if cerr := br.b.charge(n); cerr != nil {
+		return n, cerr
+	}
+	return n, err
+}
+
+// readAllBudget reads r into memory, charging the request budget
+func (r *Request) readAllBudget(body io.Reader) ([]byte, error) {
+	if r.budget == nil {
+		return io.ReadAll(body)
+	}
+	return io.ReadAll(budgetReader{r: body, b: r.budget})
+}
@@ -0,0 +1,85 @@
+package gouter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// budgetRouter serves s with routes buffering n response bytes
+// (/write/:n) or a multipart field (/field), and returns where the last
+// Write or parse error is stored
+func budgetRouter(t *testing.T, s *Server) (string, *error) {
+	var writeErr error
+	r := newTestRouter()
+	r.Get("/write/:n", func(req *Request, w *Writer) {
+		n, _ := strconv.Atoi(req.Params.Get("n"))
+		_, writeErr = w.Write([]byte(strings.Repeat("x", n)))
+	})
+	r.Post("/field", func(req *Request, w *Writer) {
+		var form struct {
+			Note string `gouter:"note"`
+		}
+		if err := req.ParseMultipart(&form); err != nil {
+			writeErr = err
+			return
+		}
+		w.Write([]byte(strconv.Itoa(len(form.Note))))
+	})
+	s.Router = r
+	return serveTest(t, s), &writeErr
+}
+
+func TestMemoryBudgetResponse(t *testing.T) {
+	const limit = 4096
+	addr, writeErr := budgetRouter(t, &Server{MaxMemoryPerRequest: limit})
+
+	// The parsed headers are charged too, so leave room for them
+	resp := rawExchange(t, addr, "GET /write/3000 HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != 200 || len(bodyString(t, resp)) != 3000 || *writeErr != nil {
+		t.Errorf("response under the budget = %d, err %v", resp.StatusCode, *writeErr)
+	}
+
+	resp = rawExchange(t, addr, "GET /write/"+strconv.Itoa(limit+1)+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("response over the budget = %d, want 503", resp.StatusCode)
+	}
+	if !errors.Is(*writeErr, ErrMemoryBudget) {
+		t.Errorf("Write over the budget = %v, want ErrMemoryBudget", *writeErr)
+	}
+}
+
+func TestMemoryBudgetMultipartField(t *testing.T) {
+	addr, writeErr := budgetRouter(t, &Server{MaxMemoryPerRequest: 2048})
+
+	body := "--b\r\nContent-Disposition: form-data; name=\"note\"\r\n\r\n" + strings.Repeat("n", 4096) + "\r\n--b--\r\n"
+	raw := "POST /field HTTP/1.1\r\nHost: x\r\nContent-Type: multipart/form-data; boundary=b\r\nContent-Length: " +
+		strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	resp := rawExchange(t, addr, raw, 1)[0]
+	if resp.StatusCode != http.StatusServiceUnavailable || !errors.Is(*writeErr, ErrMemoryBudget) {
+		t.Errorf("field over the budget = %d, err %v; want 503 and ErrMemoryBudget", resp.StatusCode, *writeErr)
+	}
+}
+
+func TestMaxBufferedResponseStreams(t *testing.T) {
+	const limit = 1024
+	addr, writeErr := budgetRouter(t, &Server{MaxBufferedResponseBytes: limit})
+
+	resp := rawExchange(t, addr, "GET /write/"+strconv.Itoa(limit)+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.Header.Get("Content-Length") != strconv.Itoa(limit) || len(bodyString(t, resp)) != limit {
+		t.Errorf("response at the cap = %v, want it buffered with a Content-Length", resp.Header)
+	}
+
+	resp = rawExchange(t, addr, "GET /write/"+strconv.Itoa(limit+1)+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != 200 || resp.Header.Get("Content-Length") != "" || len(bodyString(t, resp)) != limit+1 {
+		t.Errorf("response over the cap = %d %v, want it streamed in full", resp.StatusCode, resp.Header)
+	}
+	if len(resp.TransferEncoding) != 1 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("streamed response Transfer-Encoding = %v, want chunked", resp.TransferEncoding)
+	}
+	if *writeErr != nil {
+		t.Errorf("Write over the cap = %v, want nil", *writeErr)
+	}
+}
@@ -0,0 +1,129 @@
+package gouter
+
+import (
+	"bufio"
+	"io"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+)
+
+const (
+	// Smallest and largest read buffer classes, as powers of two (512B to 64KB)
+	minBufferShift = 9
+	maxBufferShift = 16
+	bufferClasses  = maxBufferShift - minBufferShift + 1
+
+	// Default bounds applied to the adaptive read buffer size
+	defaultMinReadBuffer = 1 << 10
+	defaultMaxReadBuffer = 64 << 10
+
+	// Header sizes observed between two re-evaluations of the buffer size
+	bufferSampleWindow = 1024
+	// Share of header blocks that should fit in one buffer
+	bufferPercentile = 0.9
+)
+
+// readerPools recycles connection read buffers, one pool per size class
+var readerPools [bufferClasses]sync.Pool
+
+// bufferSizer tracks a decaying histogram of header block sizes and picks
+// the read buffer size that fits most of them
+// Observations are single atomic adds; the recomputation runs on the
+// goroutine completing a sample window
+type bufferSizer struct {
+	hist    [bufferClasses]atomic.Int64
+	samples atomic.Int64
+	size    atomic.Int64 // Chosen size, 0 until the first window completes
+}
+
+// bufferClass returns the smallest class holding n bytes
+func bufferClass(n int) int {
+	if n <= 1<<minBufferShift {
+		return 0
+	}
+	class := bits.Len(uint(n-1)) - minBufferShift
+	if class >= bufferClasses {
+		return bufferClasses - 1
+	}
+	return class
+}
+
+// observe records the size of a parsed header block
+func (s *bufferSizer) observe(headerBytes int) {
+	s.hist[bufferClass(headerBytes)].Add(1)
+	if s.samples.Add(1)%bufferSampleWindow == 0 {
+		s.recompute()
+	}
+}
+
+// recompute picks the percentile class and halves the histogram so the
+// choice follows changes in traffic
+func (s *bufferSizer) recompute() {
+	var counts [bufferClasses]int64
+	var total int64
+	for i := range s.hist {
+		counts[i] = s.hist[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return
+	}
+
+	target := int64(float64(total) * bufferPercentile)
+	var seen int64
+	for i, n := range counts {
+		seen += n
+		if seen >= target {
+			s.size.Store(1 << (i + minBufferShift))
+			break
+		}
+	}
+
+	for i := range s.hist {
+		s.hist[i].Add(-counts[i] / 2)
+	}
+}
+
+// current returns the chosen size clamped to [min, max]
+func (s *bufferSizer) current(min, max int) int {
+	size := int(s.size.Load())
+	if size == 0 {
+		size = connReadBufferSize
+	}
+	if min <= 0 {
+		min = defaultMinReadBuffer
+	}
+	if max <= 0 {
+		max = defaultMaxReadBuffer
+	}
+	if size < min {
+		size = min
+	}
+	if size > max {
+		size = max
+	}
+	return size
+}
+
+// ReadBufferSize returns the connection read buffer size currently chosen
+// from the observed header sizes, before Server bounds are applied
+func (r *Router) ReadBufferSize() int {
+	return r.bufSizer.current(1, 1<<maxBufferShift)
+}
+
+// acquireReader takes a pooled read buffer of the class holding size bytes
+func acquireReader(c io.Reader, size int) *bufio.Reader {
+	class := bufferClass(size)
+	if br, ok := readerPools[class].Get().(*bufio.Reader); ok {
+		br.Reset(c)
+		return br
+	}
+	return bufio.NewReaderSize(c, 1<<(class+minBufferShift))
+}
+
+// releaseReader returns a read buffer to its pool
+func releaseReader(br *bufio.Reader) {
+	br.Reset(nil)
+	readerPools[bufferClass(br.Size())].Put(br)
+}
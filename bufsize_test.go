@@ -0,0 +1,155 @@
+package gouter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBufferClass(t *testing.T) {
+	cases := []struct{ n, class int }{
+		{0, 0}, {1, 0}, {512, 0}, {513, 1}, {1024, 1}, {1025, 2},
+		{4096, 3}, {64 << 10, bufferClasses - 1}, {1 << 20, bufferClasses - 1},
+	}
+	for _, c := range cases {
+		if got := bufferClass(c.n); got != c.class {
+			t.Errorf("bufferClass(%d) = %d, want %d", c.n, got, c.class)
+		}
+	}
+}
+
+func TestBufferSizerFollowsTraffic(t *testing.T) {
+	var s bufferSizer
+	if got := s.current(0, 0); got != connReadBufferSize {
+		t.Errorf("size before any window = %d, want %d", got, connReadBufferSize)
+	}
+
+	// Health checks: one window of tiny header blocks
+	for i := 0; i < bufferSampleWindow; i++ {
+		s.observe(150)
+	}
+	if got := s.current(1, 1<<20); got != 512 {
+		t.Errorf("size for small headers = %d, want 512", got)
+	}
+	if got := s.current(0, 0); got != defaultMinReadBuffer {
+		t.Errorf("default bounds = %d, want the %d floor", got, defaultMinReadBuffer)
+	}
+
+	// Cookie-heavy browsers take over; the decayed histogram follows
+	for w := 0; w < 4; w++ {
+		for i := 0; i < bufferSampleWindow; i++ {
+			s.observe(6000)
+		}
+	}
+	if got := s.current(0, 0); got != 8192 {
+		t.Errorf("size for 6KB headers = %d, want 8192", got)
+	}
+	if got := s.current(0, 4096); got != 4096 {
+		t.Errorf("size under a 4KB ceiling = %d, want 4096", got)
+	}
+}
+
+func TestLargeHeadersWithSmallBuffers(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) {
+		w.Write([]byte(req.Headers.Get("Cookie")))
+	})
+	addr := serveTest(t, &Server{Router: r, MinReadBufferSize: 512, MaxReadBufferSize: 512})
+
+	cookie := "session=" + strings.Repeat("c", 6000)
+	resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\nCookie: "+cookie+"\r\n\r\nGET / HTTP/1.1\r\nHost: x\r\n\r\n", 2)
+	if got := bodyString(t, resp[0]); got != cookie {
+		t.Errorf("cookie read through a 512B buffer has %d bytes, want %d", len(got), len(cookie))
+	}
+	if resp[1].StatusCode != 200 {
+		t.Errorf("pipelined request after large headers = %d, want 200", resp[1].StatusCode)
+	}
+}
+
+// readCountingListener counts the reads made on accepted connections
+type readCountingListener struct {
+	net.Listener
+	reads *atomic.Int64
+}
+
+func (l readCountingListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return readCountingConn{c, l.reads}, nil
+}
+
+type readCountingConn struct {
+	net.Conn
+	reads *atomic.Int64
+}
+
+func (c readCountingConn) Read(p []byte) (int, error) {
+	c.reads.Add(1)
+	return c.Conn.Read(p)
+}
+
+// benchmarkReadBuffer serves requests carrying headerBytes of cookies and
+// reports the connection reads per request. A fixed server pins the buffer
+// at the former 4KB; an adaptive one is warmed up with a window of requests
+func benchmarkReadBuffer(b *testing.B, headerBytes int, fixed bool) {
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) {})
+	s := &Server{Router: r}
+	if fixed {
+		s.MinReadBufferSize, s.MaxReadBufferSize = connReadBufferSize, connReadBufferSize
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	var reads atomic.Int64
+	addr := serveListener(b, s, readCountingListener{l, &reads})
+	raw := "GET / HTTP/1.1\r\nHost: x\r\nCookie: " + strings.Repeat("c", headerBytes) + "\r\n\r\n"
+
+	send := func(c net.Conn, br *bufio.Reader, n int) {
+		for i := 0; i < n; i++ {
+			c.SetDeadline(time.Now().Add(3 * time.Second))
+			if _, err := io.WriteString(c, raw); err != nil {
+				b.Fatal(err)
+			}
+			resp, err := http.ReadResponse(br, nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			io.Copy(io.Discard, resp.Body)
+		}
+	}
+	dial := func() (net.Conn, *bufio.Reader) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { c.Close() })
+		return c, bufio.NewReader(c)
+	}
+
+	// The buffer is sized when a connection opens
+	c, br := dial()
+	send(c, br, bufferSampleWindow)
+	c, br = dial()
+
+	b.ReportAllocs()
+	reads.Store(0)
+	b.ResetTimer()
+	send(c, br, b.N)
+	b.StopTimer()
+	b.ReportMetric(float64(reads.Load())/float64(b.N), "reads/op")
+	b.ReportMetric(float64(r.bufSizer.current(s.MinReadBufferSize, s.MaxReadBufferSize)), "buffer-bytes")
+}
+
+func BenchmarkReadBufferSmallHeadersFixed(b *testing.B)    { benchmarkReadBuffer(b, 100, true) }
+func BenchmarkReadBufferSmallHeadersAdaptive(b *testing.B) { benchmarkReadBuffer(b, 100, false) }
+func BenchmarkReadBufferLargeHeadersFixed(b *testing.B)    { benchmarkReadBuffer(b, 12<<10, true) }
+func BenchmarkReadBufferLargeHeadersAdaptive(b *testing.B) { benchmarkReadBuffer(b, 12<<10, false) }
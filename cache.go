@@ -0,0 +1,434 @@
+package gouter
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// Default limits of a ResponseCache, see Limit
+const (
+	DefaultCacheEntries = 10000
+	DefaultCacheBytes   = 64 << 20
+)
+
+// ResponseCache keeps successful GET responses in memory for a fixed time
+// Entries are keyed by host, path and query string, and are dropped early by
+// Invalidate or Purge for any of the surrogate keys of the response. Past
+// its limits the least recently used entries are evicted
+type ResponseCache struct {
+	ttl             time.Duration
+	whileRevalidate time.Duration // Default stale-while-revalidate window
+	ifError         time.Duration // Default stale-if-error window
+	maxEntries      int
+	maxBytes        int64
+
+	mu           sync.Mutex
+	entries      map[string]*cachedResponse
+	lru          *list.List                     // Entry keys, most recently used first
+	size         int64                          // Sum of the entry sizes
+	nextSweep    time.Time                      // When store next drops the expired entries
+	byKey        map[string]map[string]struct{} // Surrogate key to entry keys
+	revalidating map[string]struct{}            // Entry keys with a background refresh running
+}
+
+// cachedResponse is a stored response
+type cachedResponse struct {
+	elem            *list.Element
+	size            int64
+	code            int
+	headers         Headers
+	body            []byte
+	keys            []string
+	stored          time.Time
+	expires         time.Time
+	age             time.Duration // Age of the response when it was stored
+	whileRevalidate time.Duration // Time past expires it may be served while refreshed
+	ifError         time.Duration // Time past expires it may replace a 5xx
+}
+
+// cacheRevalidateKey is the request value holding the context of a
+// background revalidation
+const cacheRevalidateKey = "gouter.cacheRevalidate"
+
+// NewResponseCache creates a cache keeping responses for ttl
+// The cache is registered for Purge for the life of the process
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	c := &ResponseCache{
+		ttl:          ttl,
+		maxEntries:   DefaultCacheEntries,
+		maxBytes:     DefaultCacheBytes,
+		entries:      make(map[string]*cachedResponse),
+		lru:          list.New(),
+		byKey:        make(map[string]map[string]struct{}),
+		revalidating: make(map[string]struct{}),
+	}
+
+	purgeRegistry.mu.Lock()
+	purgeRegistry.caches = append(purgeRegistry.caches, c)
+	purgeRegistry.mu.Unlock()
+	return c
+}
+
+// StaleWindows sets how long expired responses stay usable
+// Args:
+//   - whileRevalidate: Expired responses are served at once for this long
+//     while a single background request refreshes them
+//   - ifError: Expired responses replace 5xx answers of the handler for this long
+//
+// The stale-while-revalidate and stale-if-error directives of a response's
+// Cache-Control override them for that response
+func (c *ResponseCache) StaleWindows(whileRevalidate, ifError time.Duration) *ResponseCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.whileRevalidate, c.ifError = whileRevalidate, ifError
+	return c
+}
+
+// Limit caps the number of entries and the memory held by their headers
+// and bodies; a zero or negative value removes that limit
+// Storing past a limit evicts the least recently used entries, and
+// responses larger than maxBytes are not stored
+// Default: DefaultCacheEntries and DefaultCacheBytes
+func (c *ResponseCache) Limit(maxEntries int, maxBytes int64) *ResponseCache {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxEntries, c.maxBytes = maxEntries, maxBytes
+	c.evictLocked()
+	return c
+}
+
+// Middleware serves cached responses and stores new ones
+// GET responses are stored and serve both GET and HEAD requests. Requests
+// with Authorization or Cookie bypass the cache, and only 200 responses
+// without Set-Cookie, Vary, or a private or no-store Cache-Control are
+// stored; streamed responses are not. Hits carry an Age header counting the time
+// spent in the cache plus the age the response had when stored, and
+// X-Cache: HIT, or X-Cache: STALE with a Warning for expired copies served
+// within their stale windows (see StaleWindows)
+func (c *ResponseCache) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			if r.Method != "GET" && r.Method != "HEAD" ||
+				r.Headers.Get("Authorization") != "" || r.Headers.Get("Cookie") != "" {
+				next(r, w)
+				return
+			}
+
+			key := r.Headers.Get("Host") + r.path
+			if r.rawQuery != "" {
+				key += "?" + r.rawQuery
+			}
+			now := r.Clock().Now()
+			e := c.lookup(key, now)
+			switch {
+			case e == nil:
+			case !now.After(e.expires):
+				c.serve(w, e, now, "HIT", "")
+				return
+			case !now.After(e.expires.Add(e.whileRevalidate)):
+				c.serve(w, e, now, "STALE", `110 - "Response is Stale"`)
+				c.revalidate(key, r, next)
+				return
+			}
+
+			// Within stale-if-error the handler answer is kept in the buffer
+			// until it is known not to be a 5xx
+			var before Headers
+			if e != nil {
+				before = cloneHeaders(w.Headers)
+			}
+
+			next(r, w)
+
+			w.mu.Lock()
+			if e != nil && !w.headersSent && w.code >= 500 {
+				w.code, w.body, w.Headers = 0, w.body[:0], before
+				w.mu.Unlock()
+				c.serve(w, e, now, "STALE", `111 - "Revalidation Failed"`)
+				return
+			}
+			defer w.mu.Unlock()
+			// HEAD responses have no body to serve GET with
+			if r.Method == "GET" {
+				c.keep(key, w, now)
+			}
+		}
+	}
+}
+
+// serve answers with a cached entry
+func (c *ResponseCache) serve(w *Writer, e *cachedResponse, now time.Time, state, warning string) {
+	w.Headers.Merge(e.headers, MergeOverride)
+	w.Headers.Add("Age", strconv.Itoa(int((e.age+now.Sub(e.stored))/time.Second)))
+	w.Headers.Add("X-Cache", state)
+	if warning != "" {
+		w.Headers.Add("Warning", warning)
+	}
+	w.WriteHeader(e.code)
+	w.Write(e.body)
+}
+
+// keep stores the buffered response of w when it may be cached; the caller
+// holds w.mu
+func (c *ResponseCache) keep(key string, w *Writer, now time.Time) {
+	if w.headersSent || (w.code != 0 && w.code != http.StatusOK) || !storable(w.Headers) {
+		return
+	}
+
+	c.mu.Lock()
+	whileRevalidate, ifError := c.whileRevalidate, c.ifError
+	c.mu.Unlock()
+	if d, ok := cacheControlSeconds(w.Headers, "stale-while-revalidate"); ok {
+		whileRevalidate = d
+	}
+	if d, ok := cacheControlSeconds(w.Headers, "stale-if-error"); ok {
+		ifError = d
+	}
+
+	c.store(key, &cachedResponse{
+		code:            http.StatusOK,
+		headers:         cloneHeaders(w.Headers),
+		body:            append([]byte(nil), w.body...),
+		keys:            w.surrogateKeys,
+		stored:          now,
+		expires:         now.Add(c.ttl),
+		age:             initialAge(w.Headers, now),
+		whileRevalidate: whileRevalidate,
+		ifError:         ifError,
+	})
+}
+
+// revalidate refreshes an entry in the background, once at a time per key
+// The handler runs on a copy of the request with a discarded response, as a
+// task started with Go: it outlives the original request and is canceled by
+// Server.Shutdown. Failed refreshes leave the stale entry in place
+func (c *ResponseCache) revalidate(key string, r *Request, next Handler) {
+	c.mu.Lock()
+	if _, running := c.revalidating[key]; running {
+		c.mu.Unlock()
+		return
+	}
+	c.revalidating[key] = struct{}{}
+	c.mu.Unlock()
+
+	// A HEAD hit refreshes the entry with the full GET response
+	br := detachRequest(r)
+	br.Method = "GET"
+	Go(func(ctx context.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				log.Error("cache revalidation of", key, "panicked:", err)
+			}
+			c.mu.Lock()
+			delete(c.revalidating, key)
+			c.mu.Unlock()
+		}()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		br.SetValue(cacheRevalidateKey, ctx)
+		br.ctx = ctx
+
+		bw := newWriter(discardConn{})
+		bw.req = br
+		next(br, bw)
+
+		bw.mu.Lock()
+		defer bw.mu.Unlock()
+		c.keep(key, bw, br.Clock().Now())
+	})
+}
+
+// Revalidation returns the context of a background cache revalidation, and
+// false for requests sent by clients
+// The context is canceled when the server shuts down; Context returns it too
+func (r *Request) Revalidation() (context.Context, bool) {
+	ctx, ok := r.Value(cacheRevalidateKey).(context.Context)
+	return ctx, ok
+}
+
+// detachRequest copies what a handler reads from a bodiless request, so it
+// can run after the original is released
+func detachRequest(r *Request) *Request {
+	c := newRequest()
+	c.Method = r.Method
+	c.path = r.path
+	c.rawQuery = r.rawQuery
+	c.basePath = r.basePath
+	c.Version = r.Version
+	c.RemoteAddrs = r.RemoteAddrs
+	c.route = r.route
+	c.clk = r.clk
+	c.ids = r.ids
+	c.Body = http.NoBody
+	c.Headers = cloneHeaders(r.Headers)
+	for k, v := range r.Params {
+		c.Params[k] = v
+	}
+	return c
+}
+
+// storable reports whether a response with headers h may be shared
+// Set-Cookie and private responses belong to one client, and responses
+// varying on request headers would need one entry per variant
+func storable(h Headers) bool {
+	if h.Get("Set-Cookie") != "" || h.Get("Vary") != "" {
+		return false
+	}
+	for _, part := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, _, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if strings.EqualFold(name, "private") || strings.EqualFold(name, "no-store") {
+			return false
+		}
+	}
+	return true
+}
+
+// cloneHeaders returns a copy of h
+func cloneHeaders(h Headers) Headers {
+	c := make(Headers, len(h))
+	for k, v := range h {
+		c[k] = v
+	}
+	return c
+}
+
+// cacheControlSeconds reads a delta-seconds directive from Cache-Control
+func cacheControlSeconds(h Headers, directive string) (time.Duration, bool) {
+	for _, part := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok || !strings.EqualFold(name, directive) {
+			continue
+		}
+		if n, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil && n >= 0 {
+			return time.Duration(n) * time.Second, true
+		}
+	}
+	return 0, false
+}
+
+// discardConn is the connection of responses nobody reads
+type discardConn struct{ net.Conn }
+
+func (discardConn) Write(p []byte) (int, error) { return len(p), nil }
+func (discardConn) Close() error                { return nil }
+
+// initialAge is the age of a response received at now (RFC 7234 section
+// 4.2.3): the larger of its Age header and the time elapsed since its Date,
+// both set when the handler relays an upstream response
+func initialAge(h Headers, now time.Time) time.Duration {
+	var age time.Duration
+	if v, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && v > 0 {
+		age = time.Duration(v) * time.Second
+	}
+	if date, err := httpdate.Parse(h.Get("Date")); err == nil {
+		age = max(age, now.Sub(date))
+	}
+	return age
+}
+
+// Invalidate drops the cached responses tagged with any of keys
+func (c *ResponseCache) Invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, k := range keys {
+		for entry := range c.byKey[k] {
+			c.removeLocked(entry)
+		}
+	}
+}
+
+// lookup returns the entry for key while it is fresh or within a stale
+// window, or nil
+func (c *ResponseCache) lookup(key string, now time.Time) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if e.dead(now) {
+		c.removeLocked(key)
+		return nil
+	}
+	c.lru.MoveToFront(e.elem)
+	return e
+}
+
+// dead reports whether e is past its stale windows at now
+func (e *cachedResponse) dead(now time.Time) bool {
+	return now.After(e.expires.Add(max(e.whileRevalidate, e.ifError)))
+}
+
+// store saves an entry, replacing any previous one for key
+// Expired entries are swept once per ttl, then the least recently used
+// ones are evicted until the cache is within its limits
+func (c *ResponseCache) store(key string, e *cachedResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.removeLocked(key)
+	if !e.stored.Before(c.nextSweep) {
+		for k, old := range c.entries {
+			if old.dead(e.stored) {
+				c.removeLocked(k)
+			}
+		}
+		c.nextSweep = e.stored.Add(max(c.ttl, time.Second))
+	}
+
+	e.size = int64(len(key) + len(e.body))
+	for k, v := range e.headers {
+		e.size += int64(len(k) + len(v))
+	}
+	if c.maxBytes > 0 && e.size > c.maxBytes {
+		return
+	}
+
+	e.elem = c.lru.PushFront(key)
+	c.size += e.size
+	c.entries[key] = e
+	for _, k := range e.keys {
+		if c.byKey[k] == nil {
+			c.byKey[k] = make(map[string]struct{})
+		}
+		c.byKey[k][key] = struct{}{}
+	}
+	c.evictLocked()
+}
+
+// evictLocked drops the least recently used entries until the cache is
+// within its limits; the caller holds c.mu
+func (c *ResponseCache) evictLocked() {
+	for (c.maxEntries > 0 && len(c.entries) > c.maxEntries) || (c.maxBytes > 0 && c.size > c.maxBytes) {
+		c.removeLocked(c.lru.Back().Value.(string))
+	}
+}
+
+// removeLocked drops an entry and its key index; the caller holds c.mu
+func (c *ResponseCache) removeLocked(key string) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	c.lru.Remove(e.elem)
+	c.size -= e.size
+	for _, k := range e.keys {
+		delete(c.byKey[k], key)
+		if len(c.byKey[k]) == 0 {
+			delete(c.byKey, k)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package gouter
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+)
+
+// cacheFetch sends method path to addr with the given request headers
+// Returns the body and the X-Cache header
+func cacheFetch(t *testing.T, addr, method, path string, headers ...string) (string, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, "http://"+addr+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	return bodyString(t, resp), resp.Header.Get("X-Cache")
+}
+
+// cachedRouter serves handler at every path under a cache
+// Returns the address and the number of handler calls
+func cachedRouter(t *testing.T, c *ResponseCache, handler Handler) (string, *atomic.Int32) {
+	calls := new(atomic.Int32)
+	r := newTestRouter()
+	r.Use(c.Middleware())
+	r.Get("/:name", func(req *Request, w *Writer) {
+		calls.Add(1)
+		handler(req, w)
+	})
+	return serveRouter(t, r), calls
+}
+
+func TestCacheHeadDoesNotStoreEmptyBody(t *testing.T) {
+	addr, calls := cachedRouter(t, NewResponseCache(time.Minute), func(req *Request, w *Writer) {
+		if req.Method == "GET" {
+			w.Write([]byte("full"))
+		}
+	})
+
+	cacheFetch(t, addr, "HEAD", "/a")
+	if body, state := cacheFetch(t, addr, "GET", "/a"); body != "full" || state == "HIT" {
+		t.Fatalf("GET after HEAD = %q %q, want the handler body", body, state)
+	}
+	if _, state := cacheFetch(t, addr, "HEAD", "/a"); state != "HIT" {
+		t.Errorf("HEAD after GET X-Cache = %q, want HIT", state)
+	}
+	if body, state := cacheFetch(t, addr, "GET", "/a"); body != "full" || state != "HIT" {
+		t.Errorf("second GET = %q %q, want %q HIT", body, state, "full")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("handler calls = %d, want 2", n)
+	}
+}
+
+func TestCacheSkipsPrivateResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  []string
+		response []string
+	}{
+		{"authorization", []string{"Authorization", "Bearer x"}, nil},
+		{"cookie", []string{"Cookie", "session=1"}, nil},
+		{"private", nil, []string{"Cache-Control", "private, max-age=60"}},
+		{"no-store", nil, []string{"Cache-Control", "no-store"}},
+		{"vary", nil, []string{"Vary", "Accept-Language"}},
+		{"set-cookie", nil, []string{"Set-Cookie", "a=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, calls := cachedRouter(t, NewResponseCache(time.Minute), func(req *Request, w *Writer) {
+				if tt.response != nil {
+					w.SetHeader(tt.response[0], tt.response[1])
+				}
+				w.Write([]byte("x"))
+			})
+			for i := 0; i < 2; i++ {
+				if _, state := cacheFetch(t, addr, "GET", "/a", tt.request...); state != "" {
+					t.Errorf("request %d X-Cache = %q, want none", i+1, state)
+				}
+			}
+			if n := calls.Load(); n != 2 {
+				t.Errorf("handler calls = %d, want 2", n)
+			}
+		})
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	addr, _ := cachedRouter(t, NewResponseCache(time.Minute).Limit(2, 0), func(req *Request, w *Writer) {
+		w.Write([]byte(req.Params["name"]))
+	})
+
+	cacheFetch(t, addr, "GET", "/a")
+	cacheFetch(t, addr, "GET", "/b")
+	cacheFetch(t, addr, "GET", "/a") // b is now the least recently used
+	cacheFetch(t, addr, "GET", "/c")
+
+	// b last, its miss stores it again
+	for _, tt := range []struct{ path, want string }{{"/a", "HIT"}, {"/c", "HIT"}, {"/b", ""}} {
+		if _, state := cacheFetch(t, addr, "GET", tt.path); state != tt.want {
+			t.Errorf("%s X-Cache = %q, want %q", tt.path, state, tt.want)
+		}
+	}
+}
+
+func TestCacheByteLimit(t *testing.T) {
+	c := NewResponseCache(time.Minute).Limit(0, 1024)
+	addr, _ := cachedRouter(t, c, func(req *Request, w *Writer) {
+		if req.Params["name"] == "big" {
+			w.Write([]byte(strings.Repeat("x", 2048)))
+			return
+		}
+		w.Write([]byte(strings.Repeat("x", 400)))
+	})
+
+	cacheFetch(t, addr, "GET", "/big")
+	if _, state := cacheFetch(t, addr, "GET", "/big"); state != "" {
+		t.Errorf("response over the limit X-Cache = %q, want none", state)
+	}
+	for _, p := range []string{"/a", "/b", "/c"} {
+		cacheFetch(t, addr, "GET", p)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.size > 1024 {
+		t.Errorf("cache size = %d, want at most 1024", c.size)
+	}
+	for key := range c.entries {
+		if strings.HasSuffix(key, "/a") {
+			t.Errorf("oldest entry kept past the byte limit")
+		}
+	}
+}
+
+func TestCacheSweepsExpiredEntries(t *testing.T) {
+	c := NewResponseCache(time.Minute)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	entry := func(at time.Time) *cachedResponse {
+		return &cachedResponse{code: 200, headers: Headers{}, stored: at, expires: at.Add(c.ttl)}
+	}
+
+	c.store("a", entry(start))
+	c.store("b", entry(start.Add(30*time.Second)))
+	// The sweep runs once per ttl and only drops entries past their windows
+	c.store("c", entry(start.Add(90*time.Second)))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries["a"]; ok {
+		t.Errorf("expired entry a was not swept")
+	}
+	if _, ok := c.entries["b"]; !ok {
+		t.Errorf("fresh entry b was swept")
+	}
+	if c.lru.Len() != len(c.entries) {
+		t.Errorf("lru holds %d keys for %d entries", c.lru.Len(), len(c.entries))
+	}
+}
+
+func TestCacheAge(t *testing.T) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var elapsed atomic.Int64
+	now := func() time.Time { return start.Add(time.Duration(elapsed.Load())) }
+
+	r := newTestRouter()
+	r.SetClock(now)
+	r.Use(NewResponseCache(time.Hour).Middleware())
+	r.Get("/local", func(req *Request, w *Writer) { w.Write([]byte("local")) })
+	// A relayed response is already 100s old by its Date, and claims 30s
+	r.Get("/relayed", func(req *Request, w *Writer) {
+		w.Headers.Add("Date", httpdate.Format(now().Add(-100*time.Second)))
+		w.Headers.Add("Age", "30")
+		w.Write([]byte("relayed"))
+	})
+	// An Age header over the Date difference wins
+	r.Get("/aged", func(req *Request, w *Writer) {
+		w.Headers.Add("Date", "Thursday, 01-Jan-26 11:59:50 GMT")
+		w.Headers.Add("Age", "500")
+		w.Write([]byte("aged"))
+	})
+	addr := serveRouter(t, r)
+
+	age := func(path string) (string, string) {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		return resp.Header.Get("X-Cache"), resp.Header.Get("Age")
+	}
+	for _, path := range []string{"/local", "/relayed", "/aged"} {
+		age(path)
+	}
+	elapsed.Store(int64(20*time.Second + 900*time.Millisecond))
+
+	for path, want := range map[string]string{"/local": "20", "/relayed": "120", "/aged": "520"} {
+		if state, got := age(path); state != "HIT" || got != want {
+			t.Errorf("%s = %s with Age %q, want HIT with Age %s", path, state, got, want)
+		}
+	}
+}
+
+// staleFetch sends GET /a to addr
+// Returns the status, body, X-Cache and Warning headers
+func staleFetch(t *testing.T, addr string) (int, string, string, string) {
+	t.Helper()
+	resp := rawExchange(t, addr, "GET /a HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	return resp.StatusCode, bodyString(t, resp), resp.Header.Get("X-Cache"), resp.Header.Get("Warning")
+}
+
+// staleRouter serves handler at /a under c on a clock moved by the
+// returned function
+func staleRouter(t *testing.T, c *ResponseCache, handler Handler) (string, func(time.Duration)) {
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var elapsed atomic.Int64
+	r := newTestRouter()
+	r.SetClock(func() time.Time { return start.Add(time.Duration(elapsed.Load())) })
+	r.Use(c.Middleware())
+	r.Get("/a", handler)
+	return serveRouter(t, r), func(d time.Duration) { elapsed.Add(int64(d)) }
+}
+
+func TestCacheStaleWhileRevalidate(t *testing.T) {
+	var version, refreshes atomic.Int32
+	gate := make(chan struct{})
+	refreshed := make(chan error, 1)
+	addr, advance := staleRouter(t, NewResponseCache(10*time.Second).StaleWindows(30*time.Second, 0), func(req *Request, w *Writer) {
+		if ctx, ok := req.Revalidation(); ok {
+			refreshes.Add(1)
+			<-gate
+			// The refresh outlives the client request that started it
+			if ctx != req.Context() {
+				refreshed <- errors.New("Context is not the revalidation context")
+			} else {
+				refreshed <- ctx.Err()
+			}
+		}
+		w.Write([]byte("v" + strconv.Itoa(int(version.Add(1)))))
+	})
+
+	if _, body, state, _ := staleFetch(t, addr); body != "v1" || state != "" {
+		t.Fatalf("first fetch = %q %q, want v1 from the handler", body, state)
+	}
+	advance(5 * time.Second)
+	if _, body, state, _ := staleFetch(t, addr); body != "v1" || state != "HIT" {
+		t.Errorf("fresh fetch = %q %q, want a v1 HIT", body, state)
+	}
+
+	// Past the ttl the stale copy is served at once, and the concurrent
+	// stale hits share one background refresh
+	advance(10 * time.Second)
+	for i := 0; i < 3; i++ {
+		code, body, state, warning := staleFetch(t, addr)
+		if code != 200 || body != "v1" || state != "STALE" || warning != `110 - "Response is Stale"` {
+			t.Errorf("stale fetch %d = %d %q %q %q", i+1, code, body, state, warning)
+		}
+	}
+	close(gate)
+	if err := <-refreshed; err != nil {
+		t.Errorf("revalidation context: %v", err)
+	}
+	if n := refreshes.Load(); n != 1 {
+		t.Errorf("background refreshes = %d, want 1", n)
+	}
+	eventually(t, "the refreshed entry", func() bool {
+		_, body, state, _ := staleFetch(t, addr)
+		return body == "v2" && state == "HIT"
+	})
+
+	// Past both windows the entry is gone and the handler answers
+	advance(time.Minute)
+	if _, body, state, _ := staleFetch(t, addr); body != "v3" || state != "" {
+		t.Errorf("fetch past the windows = %q %q, want v3 from the handler", body, state)
+	}
+}
+
+func TestCacheStaleIfError(t *testing.T) {
+	var version atomic.Int32
+	var failing atomic.Bool
+	addr, advance := staleRouter(t, NewResponseCache(10*time.Second).StaleWindows(0, time.Minute), func(req *Request, w *Writer) {
+		if failing.Load() {
+			w.SetHeader("Retry-After", "5")
+			w.WriteHeader(503)
+			w.Write([]byte("down"))
+			return
+		}
+		w.Write([]byte("v" + strconv.Itoa(int(version.Add(1)))))
+	})
+
+	staleFetch(t, addr)
+	failing.Store(true)
+	advance(20 * time.Second)
+	resp := rawExchange(t, addr, "GET /a HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if body := bodyString(t, resp); resp.StatusCode != 200 || body != "v1" ||
+		resp.Header.Get("X-Cache") != "STALE" || resp.Header.Get("Warning") != `111 - "Revalidation Failed"` {
+		t.Errorf("stale-if-error = %d %q %v", resp.StatusCode, body, resp.Header)
+	}
+	if resp.Header.Get("Retry-After") != "" {
+		t.Error("the stale copy carries headers of the 5xx answer")
+	}
+
+	// A success in the window replaces the entry
+	failing.Store(false)
+	if _, body, state, _ := staleFetch(t, addr); body != "v2" || state != "" {
+		t.Errorf("recovered fetch = %q %q, want v2 from the handler", body, state)
+	}
+
+	// Past the window the 5xx reaches the client
+	failing.Store(true)
+	advance(71 * time.Second)
+	if code, body, state, _ := staleFetch(t, addr); code != 503 || body != "down" || state != "" {
+		t.Errorf("fetch past stale-if-error = %d %q %q, want the 503", code, body, state)
+	}
+}
+
+func TestCacheStaleDirectives(t *testing.T) {
+	var version atomic.Int32
+	var failing atomic.Bool
+	// The response windows override the cache defaults
+	c := NewResponseCache(10*time.Second).StaleWindows(time.Hour, time.Hour)
+	addr, advance := staleRouter(t, c, func(req *Request, w *Writer) {
+		if failing.Load() {
+			w.WriteHeader(500)
+			return
+		}
+		w.SetHeader("Cache-Control", "max-age=10, stale-while-revalidate=5, stale-if-error=0")
+		w.Write([]byte("v" + strconv.Itoa(int(version.Add(1)))))
+	})
+
+	staleFetch(t, addr)
+	failing.Store(true)
+	advance(13 * time.Second)
+	if _, body, state, _ := staleFetch(t, addr); body != "v1" || state != "STALE" {
+		t.Errorf("inside stale-while-revalidate = %q %q, want a v1 STALE", body, state)
+	}
+	// The failed refresh keeps the stale entry
+	eventually(t, "the failed refresh", func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return len(c.revalidating) == 0
+	})
+	if _, body, state, _ := staleFetch(t, addr); body != "v1" || state != "STALE" {
+		t.Errorf("after a failed refresh = %q %q, want a v1 STALE", body, state)
+	}
+
+	advance(3 * time.Second)
+	if code, _, state, _ := staleFetch(t, addr); code != 500 || state != "" {
+		t.Errorf("past stale-while-revalidate with stale-if-error=0 = %d %q, want the 500", code, state)
+	}
+}
@@ -0,0 +1,121 @@
+package gouter
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// routeChain is the middleware chain of one route, composed when a request
+// needs it: Use on the router, a group or the route bumps the generation of
+// the router, and the next request rebuilds the chain once
+type routeChain struct {
+	top   *Router           // Router owning the global middlewares
+	group *Group            // Innermost group of the route, nil outside groups
+	route []middlewareEntry // Middlewares added with RouteInfo.Use
+	inner Handler           // Guards and handler
+	docs  []*RouteInfo      // Entries whose Middlewares list this chain
+	tail  []string          // Names of middlewares inside inner, for mounted routes
+
+	built atomic.Pointer[builtChain]
+}
+
+// builtChain is a composed chain and the generation it was built for
+type builtChain struct {
+	gen     uint64
+	handler Handler
+}
+
+// newRouteChain creates the chain of a route
+func newRouteChain(top *Router, group *Group, inner Handler) *routeChain {
+	return &routeChain{top: top, group: group, inner: inner}
+}
+
+// serve runs the request through the current chain
+func (c *routeChain) serve(r *Request, w *Writer) {
+	c.handler()(r, w)
+}
+
+// handler returns the composed chain, rebuilding it after a Use
+func (c *routeChain) handler() Handler {
+	gen := c.top.mwGen.Load()
+	if b := c.built.Load(); b != nil && b.gen == gen {
+		return b.handler
+	}
+
+	c.top.chainMu.Lock()
+	defer c.top.chainMu.Unlock()
+	gen = c.top.mwGen.Load()
+	if b := c.built.Load(); b != nil && b.gen == gen {
+		return b.handler
+	}
+	h := applyMiddlewares(c.inner, c.entries())
+	c.built.Store(&builtChain{gen: gen, handler: h})
+	return h
+}
+
+// entries returns the middlewares of the route, outermost first
+// Global middlewares wrap group ones, which wrap route ones, then
+// priorities reorder the chain; the caller holds top.chainMu
+func (c *routeChain) entries() []middlewareEntry {
+	chain := outermostFirst(c.top.mws)
+	chain = append(chain, groupMiddlewares(c.group)...)
+	chain = append(chain, outermostFirst(c.route)...)
+	sort.SliceStable(chain, func(i, j int) bool {
+		return chain[i].priority > chain[j].priority
+	})
+	return chain
+}
+
+// names lists the middlewares of the route for its docs entries
+// The caller holds top.chainMu
+func (c *routeChain) names() []string {
+	var names []string
+	for _, m := range c.entries() {
+		names = append(names, m.name)
+	}
+	return append(names, c.tail...)
+}
+
+// refreshDocs updates the Middlewares of the docs entries of the route
+// The caller holds top.chainMu
+func (c *routeChain) refreshDocs() {
+	names := c.names()
+	for _, doc := range c.docs {
+		doc.Middlewares = append([]string(nil), names...)
+	}
+}
+
+// groupMiddlewares returns the middlewares of g and its enclosing groups,
+// outer groups first; the caller holds top.chainMu
+func groupMiddlewares(g *Group) []middlewareEntry {
+	if g == nil {
+		return nil
+	}
+	return append(groupMiddlewares(g.parent), outermostFirst(g.mw)...)
+}
+
+// middlewaresChanged rebuilds the chains of r at their next request and
+// refreshes the route docs; the caller holds r.chainMu
+func (r *Router) middlewaresChanged() {
+	r.mwGen.Add(1)
+	for _, c := range r.chains {
+		c.refreshDocs()
+	}
+}
+
+// Use adds middlewares to this route only, inside the global and group ones
+// The last one given is the outermost, like repeated calls to Router.Use
+func (r *RouteInfo) Use(mws ...Middleware) *RouteInfo {
+	c := r.chain
+	if c == nil {
+		return r
+	}
+
+	c.top.chainMu.Lock()
+	defer c.top.chainMu.Unlock()
+	for _, mw := range mws {
+		c.route = append(c.route, newMiddlewareEntry(mw, nil))
+	}
+	c.top.middlewaresChanged()
+	return r
+}
@@ -0,0 +1,131 @@
+package gouter
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// tagLayer appends name to the X-Chain response header and counts how many
+// times the chain wrapping it was composed
+func tagLayer(name string, built *atomic.Int32) Middleware {
+	return func(next Handler) Handler {
+		if built != nil {
+			built.Add(1)
+		}
+		return func(req *Request, w *Writer) {
+			tag := name
+			if prev := w.Headers.Get("X-Chain"); prev != "" {
+				tag = prev + "," + name
+			}
+			w.Headers.Add("X-Chain", tag)
+			next(req, w)
+		}
+	}
+}
+
+// chainOf returns the X-Chain values of a GET to path
+func chainOf(t *testing.T, addr, path string) string {
+	t.Helper()
+	resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	return resp.Header.Get("X-Chain")
+}
+
+func TestLateUse(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/a", func(req *Request, w *Writer) {})
+	users := r.Get("/b", func(req *Request, w *Writer) {})
+	r.Post("/b", func(req *Request, w *Writer) {})
+	var group *Group
+	r.Group("/api", func(g *Group) {
+		group = g
+		g.Get("/c", func(req *Request, w *Writer) {})
+	})
+	addr := serveRouter(t, r)
+
+	if got := chainOf(t, addr, "/a"); got != "" {
+		t.Fatalf("chain before any Use = %q", got)
+	}
+
+	// Middlewares added while serving reach routes registered before them
+	var built atomic.Int32
+	r.Use(tagLayer("global", &built), MiddlewareName("global"))
+	group.Use(tagLayer("group", nil), MiddlewareName("group"))
+	users.Use(tagLayer("route-1", nil), tagLayer("route-2", nil))
+
+	cases := map[string]string{
+		"/a":     "global",
+		"/b":     "global,route-2,route-1",
+		"/api/c": "global,group",
+	}
+	for i := 0; i < 3; i++ {
+		for path, want := range cases {
+			if got := chainOf(t, addr, path); got != want {
+				t.Errorf("GET %s chain = %q, want %q", path, got, want)
+			}
+		}
+	}
+	// Route middlewares stay on the method they were added to
+	resp := rawExchange(t, addr, "POST /b HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n", 1)[0]
+	if got := resp.Header.Get("X-Chain"); got != "global" {
+		t.Errorf("POST /b chain = %q, want only the global middleware", got)
+	}
+
+	// Each route and method composed its chain once for the new generation
+	if n := built.Load(); n != 4 {
+		t.Errorf("global middleware wrapped %d times for 4 routes, want 4", n)
+	}
+
+	if got := users.Middlewares; len(got) != 3 || got[0] != "global" {
+		t.Errorf("docs Middlewares of /b = %v, want the refreshed chain", got)
+	}
+	if got := r.MiddlewareChain("/api/c", "GET"); len(got) < 2 || got[0] != "global" || got[1] != "group" {
+		t.Errorf("MiddlewareChain(/api/c) = %v, want global then group", got)
+	}
+}
+
+func TestUseWhileServing(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/a", func(req *Request, w *Writer) {})
+	addr := serveRouter(t, r)
+
+	const final = "9,8,7,6,5,4,3,2,1,0"
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				resp, err := http.Get("http://" + addr + "/a")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				resp.Body.Close()
+				// Layers are added outermost, so a chain is always a suffix
+				// of the final one
+				if got := resp.Header.Get("X-Chain"); !strings.HasSuffix(final, got) {
+					t.Errorf("chain %q is not a suffix of %q", got, final)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 10; i++ {
+		r.Use(tagLayer(string(rune('0'+i)), nil))
+	}
+	got := chainOf(t, addr, "/a")
+	close(stop)
+	wg.Wait()
+	if got != final {
+		t.Errorf("chain after the last Use = %q, want %q", got, final)
+	}
+}
@@ -0,0 +1,273 @@
+// Package cli implements the gouter command: route table inspection, diffing
+// and documentation checks for CI
+//
+// The command reads tables produced by Router.ExportTable. Programs can also
+// embed it to inspect their live router, e.g. behind a flag:
+//
+//	if len(os.Args) > 1 && os.Args[1] == "routes" {
+//		cli.Main(Routes())
+//	}
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// Exit codes, stable so CI jobs can branch on them
+const (
+	ExitOK       = 0 // Command succeeded, nothing to report
+	ExitFindings = 1 // Diff found changes or lint found issues
+	ExitError    = 2 // Bad usage or unreadable input
+)
+
+const usage = `usage: gouter <command> [flags]
+
+commands:
+  routes list [-table file] [-hidden]       print the route table
+  routes diff old.json [new.json]           list added, removed and changed routes
+  docs lint [-table file]                   report routes with incomplete docs
+  docs export -format openapi|postman [-table file] [-o file] [-title name] [-base url]
+
+-table defaults to stdin ("-"); programs embedding the command use their router instead
+`
+
+// errUsage is returned for malformed command lines
+var errUsage = errors.New("invalid usage")
+
+// Main runs the command against a live router and exits the process
+func Main(r *gouter.Router) {
+	os.Exit(RunRouter(r, os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// Run executes the command line args reading exported table files
+// Returns the process exit code
+func Run(args []string, stdout, stderr io.Writer) int {
+	return run(nil, args, stdout, stderr)
+}
+
+// RunRouter executes the command line args against a router; -table is
+// still honored and takes precedence
+// Returns the process exit code
+func RunRouter(r *gouter.Router, args []string, stdout, stderr io.Writer) int {
+	return run(r, args, stdout, stderr)
+}
+
+// command is the state shared by the subcommands
+type command struct {
+	router *gouter.Router
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func run(r *gouter.Router, args []string, stdout, stderr io.Writer) int {
+	cmd := &command{router: r, stdin: os.Stdin, stdout: stdout, stderr: stderr}
+
+	if len(args) < 2 {
+		fmt.Fprint(stderr, usage)
+		return ExitError
+	}
+
+	var (
+		code int
+		err  error
+	)
+	switch args[0] + " " + args[1] {
+	case "routes list":
+		code, err = cmd.routesList(args[2:])
+	case "routes diff":
+		code, err = cmd.routesDiff(args[2:])
+	case "docs lint":
+		code, err = cmd.docsLint(args[2:])
+	case "docs export":
+		code, err = cmd.docsExport(args[2:])
+	default:
+		fmt.Fprint(stderr, usage)
+		return ExitError
+	}
+
+	if err != nil {
+		if !errors.Is(err, errUsage) && !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintln(stderr, "gouter:", err)
+		}
+		return ExitError
+	}
+	return code
+}
+
+// flags creates a flag set reporting to the command stderr
+func (c *command) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet("gouter "+name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	return fs
+}
+
+// parse parses flags, turning parse failures into errUsage
+func parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+// load returns an exported table: the file at path ("-" for stdin), or the
+// router when path is empty and the command is embedded
+func (c *command) load(path string) ([]byte, error) {
+	if path == "" {
+		if c.router != nil {
+			return c.router.ExportTable()
+		}
+		path = "-"
+	}
+	if path == "-" {
+		return io.ReadAll(c.stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// entries loads and decodes a table
+func (c *command) entries(path string) ([]gouter.TableEntry, error) {
+	data, err := c.load(path)
+	if err != nil {
+		return nil, err
+	}
+	return gouter.ParseTable(data)
+}
+
+func (c *command) routesList(args []string) (int, error) {
+	fs := c.flags("routes list")
+	table := fs.String("table", "", "exported route table (\"-\" for stdin)")
+	hidden := fs.Bool("hidden", false, "include routes hidden from the docs")
+	if err := parse(fs, args); err != nil {
+		return 0, err
+	}
+
+	entries, err := c.entries(*table)
+	if err != nil {
+		return 0, err
+	}
+
+	tw := tabwriter.NewWriter(c.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER\tMIDDLEWARES")
+	for _, e := range entries {
+		if e.Hidden && !*hidden {
+			continue
+		}
+		method := e.Method
+		if e.Protocol != "" {
+			method += " (" + e.Protocol + ")"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", method, e.Path, e.Handler, strings.Join(e.Middlewares, ", "))
+	}
+	return ExitOK, tw.Flush()
+}
+
+func (c *command) routesDiff(args []string) (int, error) {
+	fs := c.flags("routes diff")
+	if err := parse(fs, args); err != nil {
+		return 0, err
+	}
+
+	var newPath string
+	switch fs.NArg() {
+	case 1:
+		// Compare against the embedded router
+		if c.router == nil {
+			fmt.Fprintln(c.stderr, "gouter: routes diff needs two tables")
+			return 0, errUsage
+		}
+	case 2:
+		newPath = fs.Arg(1)
+	default:
+		fmt.Fprintln(c.stderr, "gouter: routes diff needs two tables")
+		return 0, errUsage
+	}
+
+	oldData, err := c.load(fs.Arg(0))
+	if err != nil {
+		return 0, err
+	}
+	newData, err := c.load(newPath)
+	if err != nil {
+		return 0, err
+	}
+
+	changes, err := gouter.DiffTables(oldData, newData)
+	if err != nil {
+		return 0, err
+	}
+	for _, ch := range changes {
+		fmt.Fprintln(c.stdout, ch)
+	}
+	if len(changes) > 0 {
+		return ExitFindings, nil
+	}
+	return ExitOK, nil
+}
+
+func (c *command) docsLint(args []string) (int, error) {
+	fs := c.flags("docs lint")
+	table := fs.String("table", "", "exported route table (\"-\" for stdin)")
+	if err := parse(fs, args); err != nil {
+		return 0, err
+	}
+
+	entries, err := c.entries(*table)
+	if err != nil {
+		return 0, err
+	}
+
+	issues := gouter.LintTable(entries)
+	for _, issue := range issues {
+		fmt.Fprintln(c.stdout, issue)
+	}
+	if len(issues) > 0 {
+		return ExitFindings, nil
+	}
+	return ExitOK, nil
+}
+
+func (c *command) docsExport(args []string) (int, error) {
+	fs := c.flags("docs export")
+	table := fs.String("table", "", "exported route table (\"-\" for stdin)")
+	format := fs.String("format", "openapi", "output format: openapi or postman")
+	out := fs.String("o", "", "output file (default stdout)")
+	title := fs.String("title", "API", "API title")
+	base := fs.String("base", "http://localhost:8080", "server base URL")
+	if err := parse(fs, args); err != nil {
+		return 0, err
+	}
+
+	entries, err := c.entries(*table)
+	if err != nil {
+		return 0, err
+	}
+
+	var data []byte
+	switch *format {
+	case "openapi":
+		data, err = openAPIDocument(entries, *title, *base)
+	case "postman":
+		data, err = postmanCollection(entries, *title, *base)
+	default:
+		fmt.Fprintln(c.stderr, "gouter: unknown export format "+*format)
+		return 0, errUsage
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if *out == "" {
+		_, err = c.stdout.Write(append(data, '\n'))
+		return ExitOK, err
+	}
+	return ExitOK, os.WriteFile(*out, append(data, '\n'), 0o644)
+}
@@ -0,0 +1,144 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// runCLI runs the command line and returns its exit code and outputs
+func runCLI(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := Run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRoutesList(t *testing.T) {
+	code, out, _ := runCLI("routes", "list", "-table", "testdata/v2.json")
+	if code != ExitOK {
+		t.Fatalf("exit code %d, want %d", code, ExitOK)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 5 || strings.Contains(out, "/internal/metrics") {
+		t.Errorf("listed %d lines with hidden routes %v:\n%s", len(lines), strings.Contains(out, "/internal/metrics"), out)
+	}
+	for _, want := range []string{"GET (websocket)", "auth, logger, cache"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+
+	_, out, _ = runCLI("routes", "list", "-hidden", "-table", "testdata/v2.json")
+	if !strings.Contains(out, "/internal/metrics") {
+		t.Errorf("-hidden did not list the hidden route:\n%s", out)
+	}
+}
+
+func TestRoutesDiff(t *testing.T) {
+	code, out, _ := runCLI("routes", "diff", "testdata/v1.json", "testdata/v2.json")
+	want := "added POST /users\n" +
+		"removed DELETE /users/:id\n" +
+		"changed GET /users/:id [middlewares]\n" +
+		"added GET /ws\n"
+	if code != ExitFindings || out != want {
+		t.Errorf("exit code %d with\n%s\nwant %d with\n%s", code, out, ExitFindings, want)
+	}
+
+	code, out, _ = runCLI("routes", "diff", "testdata/v1.json", "testdata/v1.json")
+	if code != ExitOK || out != "" {
+		t.Errorf("identical tables: exit code %d with %q, want %d and no output", code, out, ExitOK)
+	}
+}
+
+func TestDocsLint(t *testing.T) {
+	code, out, _ := runCLI("docs", "lint", "-table", "testdata/v1.json")
+	if code != ExitOK || out != "" {
+		t.Errorf("documented table: exit code %d with %q, want %d", code, out, ExitOK)
+	}
+
+	code, out, _ = runCLI("docs", "lint", "-table", "testdata/v2.json")
+	want := "POST /users: missing description\nPOST /users: unnamed handler main.go:42\n"
+	if code != ExitFindings || out != want {
+		t.Errorf("exit code %d with\n%s\nwant %d with\n%s", code, out, ExitFindings, want)
+	}
+}
+
+func TestDocsExport(t *testing.T) {
+	dir := t.TempDir()
+	for _, format := range []string{"openapi", "postman"} {
+		path := filepath.Join(dir, format+".json")
+		code, _, stderr := runCLI("docs", "export", "-format", format, "-table", "testdata/v2.json", "-o", path)
+		if code != ExitOK {
+			t.Fatalf("%s: exit code %d: %s", format, code, stderr)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var doc map[string]any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Errorf("%s: invalid JSON: %v", format, err)
+		}
+		if strings.Contains(string(data), "/internal/metrics") {
+			t.Errorf("%s: hidden route exported", format)
+		}
+	}
+
+	code, out, _ := runCLI("docs", "export", "-table", "testdata/v1.json")
+	if code != ExitOK || !strings.Contains(out, `"/users/{id}"`) {
+		t.Errorf("OpenAPI on stdout: exit code %d, output lacks /users/{id}", code)
+	}
+}
+
+func TestExitErrors(t *testing.T) {
+	cases := [][]string{
+		nil,
+		{"routes"},
+		{"routes", "rename"},
+		{"routes", "list", "-nope"},
+		{"routes", "list", "-table", "testdata/missing.json"},
+		{"routes", "list", "-table", "testdata/future.json"},
+		{"routes", "diff", "testdata/v1.json"},
+		{"docs", "export", "-format", "raml", "-table", "testdata/v1.json"},
+	}
+	for _, args := range cases {
+		code, out, stderr := runCLI(args...)
+		if code != ExitError || out != "" || stderr == "" {
+			t.Errorf("%q: exit code %d, stdout %q, stderr %q, want %d with a message", args, code, out, stderr, ExitError)
+		}
+	}
+}
+
+func TestRunRouter(t *testing.T) {
+	r := gouter.NewRouter()
+	r.Update(func(d *gouter.Doc) { d.Active = false })
+	r.Get("/health", func(req *gouter.Request, w *gouter.Writer) {}).SetName("health").SetDescription("Liveness probe")
+
+	var stdout, stderr bytes.Buffer
+	if code := RunRouter(r, []string{"routes", "diff", "testdata/v1.json"}, &stdout, &stderr); code != ExitFindings {
+		t.Fatalf("exit code %d, want %d: %s", code, ExitFindings, stderr.String())
+	}
+	if !strings.Contains(stdout.String(), "removed GET /users/:id") || strings.Contains(stdout.String(), "/health") {
+		t.Errorf("diff against the live router:\n%s", stdout.String())
+	}
+}
+
+func TestTemplatePath(t *testing.T) {
+	cases := map[string]string{
+		"/users/:id":         "/users/{id}",
+		"/files/:name.:ext":  "/files/{name}.{ext}",
+		"/static/*":          "/static/{path}",
+		"/files/*filepath":   "/files/{filepath}",
+		"/u/:id/files/*rest": "/u/{id}/files/{rest}",
+	}
+	for pattern, want := range cases {
+		if got := templatePath(pattern); got != want {
+			t.Errorf("templatePath(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
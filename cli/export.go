@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// embeddedParam matches a parameter inside a multi-parameter segment
+var embeddedParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// templatePath converts a route pattern to an OpenAPI path template
+// ("/files/:name.:ext" becomes "/files/{name}.{ext}", "*" becomes "{path}"
+// and "*name" becomes "{name}")
+func templatePath(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		switch {
+		case seg == "*":
+			segs[i] = "{path}"
+		case strings.HasPrefix(seg, "*"):
+			segs[i] = "{" + seg[1:] + "}"
+		case strings.HasPrefix(seg, ":") && strings.Count(seg, ":") == 1:
+			segs[i] = "{" + seg[1:] + "}"
+		default:
+			segs[i] = embeddedParam.ReplaceAllString(seg, "{$1}")
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// registrationLocation matches the file:line name of unnamed handlers
+var registrationLocation = regexp.MustCompile(`\.go:\d+$`)
+
+// visible drops the routes hidden from the docs
+func visible(entries []gouter.TableEntry) []gouter.TableEntry {
+	var out []gouter.TableEntry
+	for _, e := range entries {
+		if !e.Hidden {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// openAPIDocument renders the table as a minimal OpenAPI 3.0 document
+func openAPIDocument(entries []gouter.TableEntry, title, base string) ([]byte, error) {
+	type parameter struct {
+		Name        string            `json:"name"`
+		In          string            `json:"in"`
+		Required    bool              `json:"required"`
+		Description string            `json:"description,omitempty"`
+		Schema      map[string]string `json:"schema"`
+	}
+	type operation struct {
+		OperationID string         `json:"operationId,omitempty"`
+		Summary     string         `json:"summary,omitempty"`
+		Parameters  []parameter    `json:"parameters,omitempty"`
+		Responses   map[string]any `json:"responses"`
+	}
+
+	paths := make(map[string]map[string]operation)
+	for _, e := range visible(entries) {
+		op := operation{
+			Summary:   e.Description,
+			Responses: map[string]any{"default": map[string]string{"description": "response"}},
+		}
+		if !registrationLocation.MatchString(e.Handler) {
+			op.OperationID = e.Handler
+		}
+		for _, p := range e.Parameters {
+			typ := p.Type
+			if typ == "" {
+				typ = "string"
+			}
+			op.Parameters = append(op.Parameters, parameter{
+				Name:        p.Name,
+				In:          "path",
+				Required:    true,
+				Description: p.Description,
+				Schema:      map[string]string{"type": typ},
+			})
+		}
+
+		path := templatePath(e.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]operation)
+		}
+		paths[path][strings.ToLower(e.Method)] = op
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": title, "version": "1.0.0"},
+		"servers": []map[string]string{{"url": base}},
+		"paths":   paths,
+	}, "", "  ")
+}
+
+// postmanCollection renders the table as a Postman v2.1 collection
+func postmanCollection(entries []gouter.TableEntry, title, base string) ([]byte, error) {
+	type variable struct {
+		Key         string `json:"key"`
+		Value       string `json:"value,omitempty"`
+		Description string `json:"description,omitempty"`
+	}
+	type url struct {
+		Raw      string     `json:"raw"`
+		Host     []string   `json:"host"`
+		Path     []string   `json:"path"`
+		Variable []variable `json:"variable,omitempty"`
+	}
+	type request struct {
+		Method      string `json:"method"`
+		URL         url    `json:"url"`
+		Description string `json:"description,omitempty"`
+	}
+	type item struct {
+		Name    string  `json:"name"`
+		Request request `json:"request"`
+	}
+
+	items := []item{}
+	for _, e := range visible(entries) {
+		u := url{
+			Raw:  "{{baseUrl}}" + e.Path,
+			Host: []string{"{{baseUrl}}"},
+			Path: strings.Split(strings.TrimPrefix(e.Path, "/"), "/"),
+		}
+		for _, p := range e.Parameters {
+			u.Variable = append(u.Variable, variable{Key: p.Name, Description: p.Description})
+		}
+		items = append(items, item{
+			Name:    e.Method + " " + e.Path,
+			Request: request{Method: e.Method, URL: u, Description: e.Description},
+		})
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"info": map[string]string{
+			"name":   title,
+			"schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+		},
+		"item":     items,
+		"variable": []variable{{Key: "baseUrl", Value: base}},
+	}, "", "  ")
+}
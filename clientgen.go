@@ -0,0 +1,302 @@
+package gouter
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goClientRuntime is the part of a generated client shared by every route
+const goClientRuntime = `// Client calls the routes of the API
+type Client struct {
+	BaseURL string       // Scheme, host and optional path prefix, without a trailing slash
+	HTTP    *http.Client // Client sending the requests, http.DefaultClient when nil
+}
+
+// NewClient creates a client for the API served at baseURL
+func NewClient(baseURL string, hc *http.Client) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/"), HTTP: hc}
+}
+
+// Error is returned for answers outside the 2xx range
+type Error struct {
+	Status int
+	Body   []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Status, http.StatusText(e.Status), bytes.TrimSpace(e.Body))
+}
+
+// do sends a request with in as its JSON body, when not nil, and returns
+// the response body
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in any) (json.RawMessage, error) {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(b)
+	}
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	hc := c.HTTP
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	resp, err := hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	out, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, &Error{Status: resp.StatusCode, Body: out}
+	}
+	return out, nil
+}
+`
+
+// GenerateGoClient renders a Go client for the documented routes
+// Args:
+//   - pkg: Package name of the generated file
+//
+// Each visible HTTP route becomes one method per HTTP method it serves,
+// HEAD aside, taking a context, its path parameters ("int" parameters as
+// int, others as string, a wildcard as the rest of the path), url.Values
+// when it documents query parameters, and a JSON body for POST, PUT and
+// PATCH. Responses are returned as json.RawMessage. Websocket routes are
+// listed in comments only.
+// The output is gofmt-formatted and sorted like ExportTable, so it can be
+// generated by go generate and compared against a committed copy
+func (r *Router) GenerateGoClient(pkg string) ([]byte, error) {
+	if !token.IsIdentifier(pkg) {
+		return nil, fmt.Errorf("invalid package name %q", pkg)
+	}
+
+	docs := make([]*RouteInfo, 0, len(r.docs))
+	for _, doc := range r.docs {
+		if !doc.Hidden {
+			docs = append(docs, doc)
+		}
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		if docs[i].Path != docs[j].Path {
+			return docs[i].Path < docs[j].Path
+		}
+		return docs[i].Method < docs[j].Method
+	})
+
+	var methods strings.Builder
+	used := make(map[string]bool)
+	for _, doc := range docs {
+		methods.WriteString("\n")
+		if doc.Protocol == "websocket" {
+			methods.WriteString("// " + doc.Path + " is a websocket route and has no method\n")
+			continue
+		}
+		for _, method := range r.docMethods(doc) {
+			writeGoClientMethod(&methods, doc, method, used)
+		}
+	}
+
+	imports := []string{"bytes", "context", "encoding/json", "fmt", "io", "net/http", "net/url", "strings"}
+	if strings.Contains(methods.String(), "strconv.") {
+		imports = append(imports, "strconv")
+	}
+	sort.Strings(imports)
+
+	var b strings.Builder
+	b.WriteString("// Code generated by gouter; DO NOT EDIT.\n\n")
+	b.WriteString("package " + pkg + "\n\nimport (\n")
+	for _, imp := range imports {
+		b.WriteString(strconv.Quote(imp) + "\n")
+	}
+	b.WriteString(")\n\n")
+	b.WriteString(goClientRuntime)
+	b.WriteString(methods.String())
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated client: %w", err)
+	}
+	return src, nil
+}
+
+// docMethods returns the methods served by the route of doc, in the order
+// they were registered, without HEAD; doc.Method for routes not split by
+// method
+func (r *Router) docMethods(doc *RouteInfo) []string {
+	table := r.methods[doc.Path]
+	if table == nil || doc.Host != "" {
+		return []string{doc.Method}
+	}
+	var methods []string
+	for _, m := range table.allow {
+		if e, ok := table.routes[m]; ok && e.info == doc && m != "HEAD" {
+			methods = append(methods, m)
+		}
+	}
+	if len(methods) == 0 {
+		return []string{doc.Method}
+	}
+	return methods
+}
+
+// writeGoClientMethod renders the method calling one route with method
+func writeGoClientMethod(b *strings.Builder, doc *RouteInfo, method string, used map[string]bool) {
+	name := goClientMethodName(method, doc.Path)
+	for i := 2; used[name]; i++ {
+		name = goClientMethodName(method, doc.Path) + strconv.Itoa(i)
+	}
+	used[name] = true
+
+	types := make(map[string]string)
+	for _, p := range doc.Parameters {
+		types[p.Name] = p.Type
+	}
+
+	// Arguments, and the path built from them with adjacent literals merged
+	args := []string{"ctx context.Context"}
+	taken := map[string]bool{"ctx": true, "query": true, "body": true, "c": true}
+	var path []string
+	lit := ""
+	for _, seg := range strings.Split(strings.Trim(doc.Path, "/"), "/") {
+		lit += "/"
+		if strings.HasPrefix(seg, "*") {
+			// The rest of the path, slashes included
+			path = append(path, strconv.Quote(lit))
+			lit = ""
+			arg := goIdentifier(seg[1:], false)
+			if arg == "" {
+				arg = "path"
+			}
+			for taken[arg] || token.IsKeyword(arg) {
+				arg += "Param"
+			}
+			args = append(args, arg+" string")
+			path = append(path, "(&url.URL{Path: "+arg+"}).EscapedPath()")
+			continue
+		}
+		for _, t := range parseSegment(seg) {
+			if t.param == "" {
+				lit += t.lit
+				continue
+			}
+			path = append(path, strconv.Quote(lit))
+			lit = ""
+
+			arg := goIdentifier(t.param, false)
+			if arg == "" || !unicode.IsLetter(rune(arg[0])) {
+				arg = "p" + arg
+			}
+			for taken[arg] || token.IsKeyword(arg) {
+				arg += "Param"
+			}
+			taken[arg] = true
+			if types[t.param] == "int" {
+				args = append(args, arg+" int")
+				path = append(path, "url.PathEscape(strconv.Itoa("+arg+"))")
+			} else {
+				args = append(args, arg+" string")
+				path = append(path, "url.PathEscape("+arg+")")
+			}
+		}
+	}
+	if lit != "" {
+		path = append(path, strconv.Quote(lit))
+	}
+
+	query := "nil"
+	if len(doc.QueryParams) > 0 {
+		args = append(args, "query url.Values")
+		query = "query"
+	}
+	body := "nil"
+	switch method {
+	case "POST", "PUT", "PATCH":
+		args = append(args, "body any")
+		body = "body"
+	}
+
+	b.WriteString("// " + name + " calls " + method + " " + doc.Path + "\n")
+	if doc.Description != "" {
+		b.WriteString("// " + doc.Description + "\n")
+	}
+	for _, p := range doc.QueryParams {
+		b.WriteString("//   - query " + p.Name + ": " + p.Description + "\n")
+	}
+	b.WriteString("// No response schema is declared: the body is returned as json.RawMessage\n")
+	if doc.Deprecation != "" {
+		b.WriteString("//\n// Deprecated: " + doc.Deprecation + "\n")
+	}
+	fmt.Fprintf(b, "func (c *Client) %s(%s) (json.RawMessage, error) {\n", name, strings.Join(args, ", "))
+	fmt.Fprintf(b, "return c.do(ctx, %q, %s, %s, %s)\n}\n", method, strings.Join(path, " + "), query, body)
+}
+
+// goClientMethodName derives a method name from the method and path of a
+// route, e.g. GET /users/:id becomes GetUsersByID and GET /static/* becomes
+// GetStaticPath
+func goClientMethodName(method, path string) string {
+	name := goIdentifier(strings.ToLower(method), true)
+	for _, seg := range strings.Split(path, "/") {
+		if rest, ok := strings.CutPrefix(seg, "*"); ok {
+			if rest == "" {
+				rest = "path"
+			}
+			name += goIdentifier(rest, true)
+			continue
+		}
+		for _, t := range parseSegment(seg) {
+			if t.param != "" {
+				name += "By" + goIdentifier(t.param, true)
+			} else {
+				name += goIdentifier(t.lit, true)
+			}
+		}
+	}
+	if name == goIdentifier(strings.ToLower(method), true) {
+		name += "Root"
+	}
+	return name
+}
+
+// goIdentifier turns a name made of words into camel case, upper for
+// exported names; the words "id", "url" and "api" are written in capitals
+func goIdentifier(s string, exported bool) string {
+	words := strings.FieldsFunc(s, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+
+	var b strings.Builder
+	for i, w := range words {
+		switch {
+		case i == 0 && !exported:
+			b.WriteString(strings.ToLower(w[:1]) + w[1:])
+		case strings.EqualFold(w, "id") || strings.EqualFold(w, "url") || strings.EqualFold(w, "api"):
+			b.WriteString(strings.ToUpper(w))
+		default:
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	return b.String()
+}
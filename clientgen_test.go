@@ -0,0 +1,66 @@
+package gouter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// clientRouter has a route for each construct the client generator handles
+func clientRouter() *Router {
+	r := newTestRouter()
+	noop := func(req *Request, w *Writer) {}
+	r.Get("/", noop).SetDescription("API index")
+	r.Route("/users", noop, "GET", "POST").SetDescription("List or create users")
+	r.Get("/users/:id", noop).SetParam("id", "int", "User ID")
+	r.Get("/users/:id/files/*name", noop)
+	r.Get("/orgs/:org/repos", noop).Paginated(PageDefaults{})
+	r.Delete("/legacy/:type", noop).Deprecate("use /v2/legacy")
+	r.Get("/files/:name.:ext", noop)
+	r.Get("/static/*", noop)
+	r.Get("/internal", noop).Hide()
+	r.WebSocket("/ws", func(ws *WebSocket, req *Request) {}, WebSocketConfig{})
+	return r
+}
+
+func TestGenerateGoClientGolden(t *testing.T) {
+	src, err := clientRouter().GenerateGoClient("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "client.golden", src)
+
+	if strings.Contains(string(src), "/internal") {
+		t.Errorf("hidden route in the client")
+	}
+	for _, want := range []string{"func (c *Client) GetUsers(", "func (c *Client) PostUsers(", "func (c *Client) GetStaticPath(ctx context.Context, path string)"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("client lacks %q", want)
+		}
+	}
+}
+
+func TestGenerateGoClientCompiles(t *testing.T) {
+	src, err := clientRouter().GenerateGoClient("api")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The client needs only the standard library
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/api\n\ngo 1.23\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "client.go"), src, 0o644)
+	cmd := exec.Command("go", "vet", ".")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go vet of the generated client: %v\n%s", err, out)
+	}
+}
+
+func TestGenerateGoClientPackageName(t *testing.T) {
+	if _, err := clientRouter().GenerateGoClient("my-api"); err == nil {
+		t.Errorf("invalid package name accepted")
+	}
+}
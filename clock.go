@@ -0,0 +1,123 @@
+package gouter
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+)
+
+// Clock is the time source of time-dependent features: route availability,
+// response cache ages, circuit breaker windows, pool ejections and rate
+// limit windows. Tests swap it for a fake (see the gtest package)
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+	After(d time.Duration) <-chan time.Time
+}
+
+// Timer is a single timer created by a Clock
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// IDGenerator produces unique, unguessable identifiers such as CSP nonces
+type IDGenerator interface {
+	NewID() (string, error)
+}
+
+// SystemClock reads the real time
+var SystemClock Clock = systemClock{}
+
+// RandomIDs generates 16 random bytes in unpadded base64url
+var RandomIDs IDGenerator = randomIDs{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (systemClock) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+
+// systemTimer adapts *time.Timer to Timer
+type systemTimer struct{ t *time.Timer }
+
+func (t systemTimer) C() <-chan time.Time        { return t.t.C }
+func (t systemTimer) Stop() bool                 { return t.t.Stop() }
+func (t systemTimer) Reset(d time.Duration) bool { return t.t.Reset(d) }
+
+// funcClock reads the time from a function, with real timers
+type funcClock struct {
+	systemClock
+	now func() time.Time
+}
+
+func (c funcClock) Now() time.Time { return c.now() }
+
+type randomIDs struct{}
+
+// NewID returns 16 random bytes, which CSP accepts as a nonce and
+// html/template leaves unescaped
+func (randomIDs) NewID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// SetClock replaces the time source used by time-dependent route features
+// Mainly useful in tests to freeze time around availability boundaries;
+// timers keep running on real time, see SetClockSource to fake them too
+func (r *Router) SetClock(now func() time.Time) {
+	r.clk = funcClock{now: now}
+}
+
+// SetClockSource replaces the time source of the router and of the
+// requests it serves (Request.Clock)
+func (r *Router) SetClockSource(c Clock) {
+	r.clk = c
+}
+
+// SetIDGenerator replaces the generator of CSP nonces and Request.NewID
+func (r *Router) SetIDGenerator(g IDGenerator) {
+	r.ids = g
+}
+
+// clockSource returns the configured Clock, SystemClock by default
+func (r *Router) clockSource() Clock {
+	if r.clk != nil {
+		return r.clk
+	}
+	return SystemClock
+}
+
+// clock returns the current time from the configured time source
+func (r *Router) clock() time.Time {
+	return r.clockSource().Now()
+}
+
+// idSource returns the configured IDGenerator, RandomIDs by default
+func (r *Router) idSource() IDGenerator {
+	if r.ids != nil {
+		return r.ids
+	}
+	return RandomIDs
+}
+
+// Clock returns the time source of the router serving the request
+// Middlewares should read the time from it rather than from time.Now
+func (r *Request) Clock() Clock {
+	if r.clk != nil {
+		return r.clk
+	}
+	return SystemClock
+}
+
+// NewID returns an identifier from the IDGenerator of the router
+func (r *Request) NewID() (string, error) {
+	if r.ids != nil {
+		return r.ids.NewID()
+	}
+	return RandomIDs.NewID()
+}
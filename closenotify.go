@@ -0,0 +1,141 @@
+package gouter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// closeNotifier watches the connection of one request for a disconnect
+type closeNotifier struct {
+	ch   chan struct{}
+	once sync.Once // Closes ch
+
+	c  net.Conn
+	br *bufio.Reader
+
+	mu       sync.Mutex
+	started  bool
+	stopping bool
+	stopped  chan struct{} // Closed when the watch goroutine returns
+}
+
+// CloseNotify returns a channel closed once when the client disconnects
+// The connection is watched from the first call until the handler returns,
+// starting once the request body has been read to its end. The channel
+// belongs to the current request: on a keep-alive connection it never fires
+// after the response is sent, and a pipelined request from the client means
+// it is still there. A failed write to the client also fires it.
+// A client that shuts down only its sending side reads as gone as well: on
+// TCP the end of its data cannot be told apart from a closed connection
+func (w *Writer) CloseNotify() <-chan struct{} {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.notifier != nil {
+		return w.notifier.ch
+	}
+	n := &closeNotifier{ch: make(chan struct{}), c: w.c, br: w.br}
+	w.notifier = n
+	if w.done || w.br == nil || w.req == nil {
+		return n.ch
+	}
+
+	// The watch reads from the connection, so it waits for the body
+	req := w.req
+	if req.Body == nil || req.Body == http.NoBody || bodyConsumed(req.Body) {
+		n.start()
+	} else {
+		req.Body = &eofHook{r: req.Body, fn: n.start}
+	}
+	return n.ch
+}
+
+// bodyConsumed reports whether a framed body has no bytes left
+func bodyConsumed(body io.Reader) bool {
+	lr, ok := body.(*io.LimitedReader)
+	return ok && lr.N <= 0
+}
+
+// start launches the watch goroutine unless the request already ended
+func (n *closeNotifier) start() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.started || n.stopping {
+		return
+	}
+	n.started = true
+	n.stopped = make(chan struct{})
+	spawn("closenotify", n.watch)
+}
+
+// watch waits for the client to close the connection
+// Read timeouts are not disconnects: the deadline is cleared and the wait
+// goes on, unless stop set it to end the watch
+func (n *closeNotifier) watch() {
+	defer close(n.stopped)
+	for {
+		_, err := n.br.Peek(1)
+		if err == nil {
+			return
+		}
+
+		var ne net.Error
+		if errors.As(err, &ne) && ne.Timeout() {
+			n.mu.Lock()
+			if n.stopping {
+				n.mu.Unlock()
+				return
+			}
+			n.c.SetReadDeadline(time.Time{})
+			n.mu.Unlock()
+			continue
+		}
+
+		n.fire()
+		return
+	}
+}
+
+// fire closes the channel; later calls do nothing
+func (n *closeNotifier) fire() {
+	n.once.Do(func() { close(n.ch) })
+}
+
+// stop ends the watch and waits for it, so the connection reader is free
+// for the rest of the request; the read deadline is cleared
+func (n *closeNotifier) stop() {
+	n.mu.Lock()
+	n.stopping = true
+	started := n.started
+	if started {
+		n.c.SetReadDeadline(time.Unix(1, 0))
+	}
+	n.mu.Unlock()
+
+	if started {
+		<-n.stopped
+		n.c.SetReadDeadline(time.Time{})
+	}
+}
+
+// eofHook calls fn once its reader reaches the end
+type eofHook struct {
+	r    io.Reader
+	fn   func()
+	done bool
+}
+
+func (e *eofHook) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if errors.Is(err, io.EOF) && !e.done {
+		e.done = true
+		e.fn()
+	}
+	return n, err
+}
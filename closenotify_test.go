@@ -0,0 +1,145 @@
+package gouter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCloseNotifyLongPoll(t *testing.T) {
+	type outcome struct {
+		fired bool
+		cause error
+		body  string
+	}
+	results := make(chan outcome, 4)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	r := newTestRouter()
+	r.Route("/poll", func(req *Request, w *Writer) {
+		// Asking before reading the body must not take its bytes
+		gone := w.CloseNotify()
+		body, _ := io.ReadAll(req.Body)
+		ctx := req.Context()
+		select {
+		case <-gone:
+			<-ctx.Done()
+			results <- outcome{true, context.Cause(ctx), string(body)}
+		case <-release:
+			results <- outcome{false, nil, string(body)}
+		case <-time.After(300 * time.Millisecond):
+			results <- outcome{false, nil, string(body)}
+			w.Write([]byte("timeout"))
+		}
+	})
+	addr := serveRouter(t, r)
+
+	// The client gives up mid-poll
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(c, "POST /poll HTTP/1.1\r\nHost: x\r\nContent-Length: 4\r\n\r\nping")
+	time.Sleep(50 * time.Millisecond)
+	closed := time.Now()
+	c.Close()
+
+	select {
+	case got := <-results:
+		if !got.fired || !errors.Is(got.cause, ErrClientGone) || got.body != "ping" {
+			t.Errorf("after a disconnect = %+v, want the channel fired, ErrClientGone and the body intact", got)
+		}
+		if d := time.Since(closed); d > 200*time.Millisecond {
+			t.Errorf("channel fired %v after the disconnect", d)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("channel did not fire after the client disconnected")
+	}
+
+	// On a keep-alive connection each request gets its own channel, and a
+	// pipelined request shows the client is still there
+	c, err = net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	poll := "POST /poll HTTP/1.1\r\nHost: x\r\nContent-Length: 2\r\n\r\n"
+	io.WriteString(c, poll+"a1"+poll+"a2")
+	br := bufio.NewReader(c)
+	for _, want := range []string{"a1", "a2"} {
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		if got := <-results; got.fired || got.body != want {
+			t.Errorf("pipelined request = %+v, want %q to time out without firing", got, want)
+		}
+	}
+}
+
+func TestCloseNotifyStreaming(t *testing.T) {
+	fired := make(chan time.Duration, 1)
+	r := newTestRouter()
+	r.Get("/events", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Type", "text/event-stream")
+		gone := w.CloseNotify()
+		start := time.Now()
+		tick := time.NewTicker(10 * time.Millisecond)
+		defer tick.Stop()
+		for {
+			select {
+			case <-gone:
+				fired <- time.Since(start)
+				return
+			case <-tick.C:
+				w.Write([]byte("data: tick\n\n"))
+				w.Flush()
+			}
+		}
+	})
+	addr := serveRouter(t, r)
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(c, "GET /events HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(c)
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	for events := 0; events < 3; {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.HasPrefix(line, "data: tick") {
+			events++
+		}
+	}
+	c.Close()
+
+	select {
+	case <-fired:
+	case <-time.After(2 * time.Second):
+		t.Fatal("stream kept running after the client disconnected")
+	}
+
+	// Outside a connection the channel exists but never fires
+	w := &Writer{Headers: make(Headers)}
+	select {
+	case <-w.CloseNotify():
+		t.Error("detached Writer reported a disconnect")
+	default:
+	}
+	if w.CloseNotify() != w.CloseNotify() {
+		t.Error("CloseNotify returned a new channel on the second call")
+	}
+}
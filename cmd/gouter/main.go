@@ -0,0 +1,12 @@
+// Command gouter inspects exported route tables; see package cli
+package main
+
+import (
+	"os"
+
+	"github.com/Murilinho145SG/gouter/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+}
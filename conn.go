@@ -17,6 +17,7 @@ package gouter
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -30,17 +31,31 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"reflect"
+	"runtime/debug"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
 	"github.com/Murilinho145SG/gouter/log"
 )
 
 const (
 	// Maximum allowed size for HTTP headers (1MB)
 	defaultMaxHeaderBytes = 1 << 20
+	// Maximum number of header lines accepted per request
+	defaultMaxHeaderCount = 100
+	// Time allowed to receive the request line and headers
+	defaultHeaderTimeout = 10 * time.Second
+	// Maximum length of a chunk-size line in chunked bodies
+	maxChunkLineBytes = 4096
+	// Initial size of the per-connection read buffer, until header sizes
+	// have been observed (see bufferSizer)
+	connReadBufferSize = 4096
+	// Most unread body bytes discarded to keep a connection alive
+	maxDrainBytes = 256 << 10
 )
 
 // Doc configures the documentation server settings
@@ -48,6 +63,58 @@ type Doc struct {
 	Active bool   // Enable/disable documentation server
 	Port   string // Documentation server port (default: "7665")
 	Addrs  string // Documentation server bind address
+
+	FailOnError bool // Make Run and Serve return the error when docs cannot start
+	AutoPort    bool // Try the next 10 ports when Port is taken
+
+	Title     string // Title of the docs page and OpenAPI document (default: "Gouter Documentation")
+	SwaggerUI bool   // Also serve Swagger UI at /swagger; its assets load from unpkg.com
+	TryIt     bool   // Add a panel sending requests to the app, run by the doc server
+}
+
+// ParserConfig configures request parsing limits and strictness
+type ParserConfig struct {
+	MaxHeaderBytes int           // Maximum size of request line plus headers (default: 1MB)
+	MaxHeaderCount int           // Maximum number of header lines (default: 100)
+	HeaderTimeout  time.Duration // Deadline for receiving the full header block (default: 10s)
+	AllowObsFold   bool          // Unfold obsolete line folding instead of rejecting it
+	MaxBodyBytes   int64         // Maximum request body size, 0 for no limit (default: 0); see RouteInfo.SetMaxBodyBytes
+
+	// HeaderAliases renames request headers sent under legacy names, keyed
+	// by lowercase alias (e.g., "content_length" → "content-length"). When
+	// set, other header names containing "_" are rejected with 400. nil
+	// disables the normalization; see DefaultHeaderAliases
+	HeaderAliases map[string]string
+}
+
+// DefaultHeaderAliases maps the underscore spellings of common headers, as
+// sent by some legacy clients and CGI-style proxies
+var DefaultHeaderAliases = map[string]string{
+	"content_length":    "content-length",
+	"content_type":      "content-type",
+	"content_encoding":  "content-encoding",
+	"transfer_encoding": "transfer-encoding",
+	"accept_encoding":   "accept-encoding",
+	"accept_language":   "accept-language",
+	"user_agent":        "user-agent",
+	"if_none_match":     "if-none-match",
+	"if_modified_since": "if-modified-since",
+	"x_forwarded_for":   "x-forwarded-for",
+	"x_forwarded_proto": "x-forwarded-proto",
+	"x_real_ip":         "x-real-ip",
+	"x_request_id":      "x-request-id",
+}
+
+// ErrBodyTooLarge is returned when reading a body past ParserConfig.MaxBodyBytes
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// defaultParserConfig returns the parser configuration used by new routers
+func defaultParserConfig() *ParserConfig {
+	return &ParserConfig{
+		MaxHeaderBytes: defaultMaxHeaderBytes,
+		MaxHeaderCount: defaultMaxHeaderCount,
+		HeaderTimeout:  defaultHeaderTimeout,
+	}
 }
 
 // RunTLS starts an HTTPS server with TLS configuration
@@ -64,6 +131,8 @@ type Doc struct {
 //   - TLS 1.2 minimum version
 //   - P256 and X25519 curve preferences
 //   - Server-side cipher suite preferences
+//
+// It speaks HTTP/1.1 only; see Server.EnableHTTP2
 func RunTLS(addrs string, r *Router, certStr, key string) error {
 	cert, err := tls.LoadX509KeyPair(certStr, key)
 	if err != nil {
@@ -76,35 +145,41 @@ func RunTLS(addrs string, r *Router, certStr, key string) error {
 		PreferServerCipherSuites: true,
 		CurvePreferences:         []tls.CurveID{tls.CurveP256, tls.X25519},
 	}
+	recordServerName(config)
+
+	if r == nil {
+		return ErrNoRouter
+	}
+	r.lazyInit()
+	if err := r.runStartupSelfTest(); err != nil {
+		return err
+	}
 
-	l, err := net.Listen("tcp", addrs)
+	l, err := listen(addrs)
 	if err != nil {
 		return fmt.Errorf("failed to create listener: %w", err)
 	}
+	r.setServeAddr(l.Addr(), true)
 
 	if r.docConfig.Active {
-		go startDoc(r)
+		if _, err := startDoc(r); err != nil {
+			return err
+		}
 	}
 
+	var backoff acceptBackoff
 	for {
 		conn, err := l.Accept()
 		if err != nil {
 			log.Error(fmt.Errorf("connection accept error: %w", err))
+			backoff.wait()
 			continue
 		}
+		backoff.reset()
 
-		tlsConn := tls.Server(conn, config)
-		handshakeDeadline := time.Now().Add(5 * time.Second)
-		tlsConn.SetDeadline(handshakeDeadline)
-
-		if err := tlsConn.Handshake(); err != nil {
-			tlsConn.Close()
-			log.Error(fmt.Errorf("TLS handshake failed: %w", err))
-			continue
-		}
-
-		tlsConn.SetDeadline(time.Time{})
-		go handleConn(tlsConn, r)
+		// TLS wraps the raw conn; byte counting happens above it in handleConn,
+		// after the handshake
+		spawn("conn", func() { handleConn(tls.Server(conn, config), r, connOptions{}) })
 	}
 }
 
@@ -116,166 +191,590 @@ func RunTLS(addrs string, r *Router, certStr, key string) error {
 // Returns:
 //   - error: Any error encountered during server startup
 func Run(addrs string, r *Router) error {
-	l, err := net.Listen("tcp", addrs)
+	l, err := listen(addrs)
 	if err != nil {
 		return fmt.Errorf("failed to create listener: %w", err)
 	}
+	defer l.Close()
 
-	if r.docConfig.Active {
-		go startDoc(r)
-	}
+	return Serve(l, r)
+}
 
-	for {
-		conn, err := l.Accept()
-		if err != nil {
-			log.Error(fmt.Errorf("connection accept error: %w", err))
-		}
-		go handleConn(conn, r)
-	}
+// Serve accepts HTTP connections on an existing listener
+// Args:
+//   - l: Listener to accept connections from (e.g., bound to ":0" in tests)
+//   - r: Initialized Router instance
+//
+// Returns:
+//   - error: Wraps net.ErrClosed once the listener is closed, which is
+//     how callers stop the server
+func Serve(l net.Listener, r *Router) error {
+	return (&Server{Router: r}).Serve(l)
 }
 
 // handleConn processes incoming HTTP connections
 // Args:
 //   - c: Network connection to handle
 //   - r: Router instance for request routing
+//   - opts: Per-connection timeouts, zero values disable them
 //
 // Connection Handling:
-//   - Sets a 10-second read timeout
-//   - Automatically closes connection after handling
+//   - Bounds header reads by the parser HeaderTimeout
+//   - Serves requests until the client or a response asks to close, or the
+//     idle timeout expires between requests
+//   - Keeps the connection after rejecting a body with intact framing, and
+//     sends Connection: close when the framing is lost
 //   - Recovers from panics in handler functions
-func handleConn(c net.Conn, r *Router) {
-	defer c.Close()
+//   - Counts bytes in both directions for observers; TLS connections are
+//     counted above the TLS layer, i.e. plaintext bytes
+func handleConn(conn net.Conn, r *Router, opts connOptions) {
+	r.lazyInit()
+	c := newCountingConn(conn)
+	start := time.Now()
+	stats := ConnStats{RemoteAddr: c.RemoteAddr().String()}
+
+	if !opts.tracker.add(c) {
+		c.Close()
+		return
+	}
+	if tc, ok := conn.(*tls.Conn); ok && !opts.handshakes.handshake(tc, r) {
+		opts.tracker.remove(c)
+		c.Close()
+		return
+	}
+
+	r.connOpened()
+	defer func() {
+		opts.tracker.remove(c)
+		r.connClosed()
+		c.Close()
+		stats.Duration = time.Since(start)
+		stats.BytesIn, stats.BytesOut = c.in.Load(), c.out.Load()
+		r.notifyConn(stats)
+	}()
+	// Handler panics are answered 500 by serveHandler; anything else that
+	// panics while serving the connection only closes it
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Error(fmt.Errorf("panic on connection from %s: %v", stats.RemoteAddr, rec), "\n"+string(debug.Stack()))
+		}
+	}()
+
+	if tc, ok := conn.(*tls.Conn); ok && opts.http2 != nil && tc.ConnectionState().NegotiatedProtocol == "h2" {
+		if opts.tracker.setState(c, connActive) {
+			opts.http2.serve(tc)
+		}
+		return
+	}
+
+	br := acquireReader(c, r.bufSizer.current(opts.minReadBuffer, opts.maxReadBuffer))
+	defer releaseReader(br)
+	for {
+		// Wait for the next request; between requests within the idle limit
+		idle := r.parserConfig.HeaderTimeout
+		if stats.Requests > 0 && opts.idleTimeout > 0 {
+			idle = opts.idleTimeout
+		}
+		if idle > 0 {
+			c.SetReadDeadline(time.Now().Add(idle))
+		}
+		if _, err := br.Peek(1); err != nil {
+			return
+		}
+		if !opts.tracker.setState(c, connActive) {
+			return
+		}
+
+		served, keepAlive := serveRequest(c, br, r, opts)
+		if served {
+			stats.Requests++
+		}
+		if !keepAlive {
+			return
+		}
+
+		// Clear the per-request timeouts before waiting for the next request
+		c.SetDeadline(time.Time{})
+		if !opts.tracker.setState(c, connIdle) {
+			return
+		}
+	}
+}
+
+// serveRequest reads one request from the connection and writes its response
+// Returns whether a request was served, and whether the connection can be
+// reused for another request
+func serveRequest(c *countingConn, br *bufio.Reader, r *Router, opts connOptions) (bool, bool) {
+	start := time.Now()
+	// Bytes read ahead into br belong to this request or later ones
+	inStart, outStart := c.in.Load()-int64(br.Buffered()), c.out.Load()
+	wireBytes := func() (int64, int64) {
+		return c.in.Load() - int64(br.Buffered()) - inStart, c.out.Load() - outStart
+	}
+
+	// Create response writer; Request and Writer go back to their pools
+	// once the response is sent, unless the connection was taken over
+	w := acquireWriter(c)
+	w.br = br
+	w.tracker = opts.tracker
+	w.surrogateHeaders = r.surrogateHeaders
+	var req *Request
+	defer func() {
+		if w.hijacked {
+			return
+		}
+		if req != nil {
+			releaseRequest(req)
+		}
+		releaseWriter(w)
+	}()
 
 	// Parse HTTP request
-	req, err := parserConn(c)
+	req, err := parserConn(c, br, r.parserConfig, maxURILength(opts.maxURILength), opts.strict)
 	if err != nil {
+		var he *httpError
+		if errors.As(err, &he) {
+			// A rejected request leaves the stream in an unknown state
+			writeError(r, nil, w, he.code)
+			if opts.strict {
+				w.Headers.Add("Date", httpdate.Format(r.clock()))
+			}
+			w.Headers.Add("Connection", "close")
+			w.write()
+			log.Error(err)
+			return false, false
+		} else if errors.Is(err, io.EOF) {
+			// Client closed the connection without sending a request
+			return false, false
+		}
 		log.Error(err)
-		return
+		return false, false
 	}
 
-	// Create response writer
-	w := newWriter(c)
+	r.checkRequestSoftLimits(req)
+	r.bufSizer.observe(req.headerBytes)
+
+	// The parser only bounds the header block; these bound the rest
+	if opts.readTimeout > 0 {
+		c.SetReadDeadline(start.Add(opts.readTimeout))
+	}
+	writeStart := time.Now()
+	if opts.writeTimeout > 0 {
+		c.SetWriteDeadline(writeStart.Add(opts.writeTimeout))
+	}
+	req.conn = newConnContext(w, writeStart, opts.writeTimeout)
+
+	r.startTrace(req)
+
+	if r.methodOverride {
+		overrideMethod(req)
+	}
 
 	// Find matching route handler
 	handler, basePath := r.parseRoute(req)
 	req.basePath = basePath
-	if handler != nil {
-		handler(req, w)
+	bodyErr := limitBody(req, r.bodyLimit(req.route))
+
+	if r.inflight.enabled.Load() {
+		entry := &inflightEntry{
+			info: InflightInfo{
+				Method:    req.Method,
+				Path:      req.path,
+				ClientIP:  req.RemoteIP(),
+				Start:     start,
+				Goroutine: goroutineID(),
+			},
+			conn:     c,
+			inStart:  inStart,
+			outStart: outStart,
+		}
+		if req.route != nil {
+			entry.info.Route = req.route.Path
+		}
+		defer r.inflight.remove(r.inflight.add(entry))
+	}
+
+	// Responses to HEAD carry the headers of the GET response only
+	w.noBody = req.Method == "HEAD"
+	w.req = req
+
+	w.templates = r.templates
+	if opts.strict {
+		w.Headers.Add("Date", httpdate.Format(r.clock()))
+	}
+	if r.dev.on.Load() {
+		w.dev = true
+		w.Headers.Add("X-Dev-Mode", "on")
+	}
+
+	req.inspector = r.uploadInspector
+	req.clk, req.ids = r.clk, r.ids
+
+	// Parsed headers are the first allocation charged to the request
+	req.budget = newMemBudget(opts.maxRequestMemory)
+	w.budget = req.budget
+	w.maxBuffered = opts.maxBufferedResponse
+
+	ring := r.errorRing
+	if ring != nil {
+		ring.watch(req)
+	}
+	startDebugSample(req)
+
+	var stack []byte
+	if req.budget.charge(headerCost(req.Headers)) != nil {
+		w.code = http.StatusServiceUnavailable
+	} else if bodyErr != nil {
+		// Left unread, the body is drained below when small enough
+		w.code = http.StatusRequestEntityTooLarge
+	} else if handler != nil {
+		if req.trace != nil && req.route != nil {
+			req.trace.add(TraceStep{Kind: "route", Name: req.route.Path})
+		}
+		stack = serveHandler(handler, req, w)
+		if recovered, ok := req.Value(panicStackKey).([]byte); ok && stack == nil {
+			stack = recovered
+		}
 	} else {
 		w.code = http.StatusNotFound
 	}
+	w.complete()
+	if w.notifier != nil {
+		w.notifier.stop()
+	}
+	req.conn.cancel(context.Canceled)
+
+	// A request over budget gets a 503 instead of its partial response
+	if req.budget.overBudget() && !w.headersSent && w.code != http.StatusServiceUnavailable {
+		w.Headers = make(Headers)
+		w.body = nil
+		w.code = http.StatusServiceUnavailable
+	}
+
+	// The connection was taken over (e.g., by a websocket)
+	if w.hijacked {
+		w.sent(wireBytes())
+		return true, false
+	}
+
+	// Skip what the handler left unread so the next request starts cleanly
+	// A shutting down server closes connections after their current request
+	keepAlive := req.WantsKeepAlive() && !w.closeAfter && !opts.tracker.closing() &&
+		drainBody(c, req.wire, r.parserConfig.HeaderTimeout)
 
 	// Send response if headers haven't been sent
 	if !w.headersSent {
+		// Error statuses left without a body get the default error body
+		if w.code >= 400 && len(w.body) == 0 {
+			writeError(r, req, w, w.code)
+		}
+
+		finishTrace(req, w)
+
+		if !keepAlive {
+			w.Headers.Add("Connection", "close")
+		} else if req.Version == "HTTP/1.0" {
+			w.Headers.Add("Connection", "keep-alive")
+		}
+
 		err = w.write()
 		if err != nil && errors.Is(err, net.ErrWriteToConnected) {
 			log.Error(err)
 		}
 	}
+
+	if ring != nil && w.code >= 500 {
+		ring.capture(req, w, stack, r.clock())
+	}
+	if req.debugCapture != nil {
+		status := w.code
+		if status == 0 {
+			status = http.StatusOK
+		}
+		dumpDebugSample(req, w, status, time.Since(start))
+	}
+
+	w.sent(wireBytes())
+	if len(r.observers) > 0 {
+		bytesIn, bytesOut := wireBytes()
+		timing := RequestTiming{
+			Method:   req.Method,
+			Path:     req.path,
+			Status:   w.code,
+			Start:    start,
+			Duration: time.Since(start),
+			BytesIn:  bytesIn,
+			BytesOut: bytesOut,
+		}
+		if timing.Status == 0 {
+			timing.Status = http.StatusOK
+		}
+		if req.route != nil {
+			timing.Route = req.route.Path
+		}
+		r.notifyRequest(timing)
+	}
+
+	// Streamed responses without a length are delimited by closing; 204 and
+	// 304 responses have no body to delimit
+	if w.headersSent && statusAllowsBody(w.code) && w.Headers.Get("Content-Length") == "" && !strings.EqualFold(w.Headers.Get("Transfer-Encoding"), "chunked") {
+		keepAlive = false
+	}
+	if w.closeAfter || strings.EqualFold(w.Headers.Get("Connection"), "close") {
+		keepAlive = false
+	}
+
+	return true, keepAlive
+}
+
+// WantsKeepAlive reports whether the client allows reusing the connection
+// HTTP/1.1 defaults to persistent connections, HTTP/1.0 has to ask for them
+func (r *Request) WantsKeepAlive() bool {
+	conn := strings.ToLower(r.Headers.Get("Connection"))
+	if r.Version == "HTTP/1.0" {
+		return strings.Contains(conn, "keep-alive")
+	}
+	return !strings.Contains(conn, "close")
+}
+
+// drainBody discards the unread rest of a request body
+// Returns false when more than maxDrainBytes are left or reading fails, in
+// which case the connection must be closed
+func drainBody(c net.Conn, body io.Reader, timeout time.Duration) bool {
+	if body == nil {
+		return true
+	}
+	// A declared length over the limit is not waited for
+	if lr, ok := body.(*io.LimitedReader); ok && lr.N > maxDrainBytes {
+		return false
+	}
+	if timeout > 0 {
+		c.SetReadDeadline(time.Now().Add(timeout))
+		defer c.SetReadDeadline(time.Time{})
+	}
+
+	_, err := io.CopyN(io.Discard, body, maxDrainBytes+1)
+	return errors.Is(err, io.EOF)
+}
+
+// serveHandler runs a handler, turning a panic into a 500 response
+// when nothing has been sent to the client yet
+// Returns the stack of the panicking goroutine, nil when the handler returned
+func serveHandler(handler Handler, req *Request, w *Writer) (stack []byte) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			stack = debug.Stack()
+			log.Error(fmt.Errorf("panic serving %s: %v", displayPath(req.path), rec), "\n"+string(stack))
+			w.failed()
+		}
+	}()
+
+	handler(req, w)
+	return nil
+}
+
+// failed replaces the response with a 500 after a panic, or marks a
+// response already on the wire as cut short
+func (w *Writer) failed() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.headersSent {
+		w.Headers = make(Headers)
+		w.body = nil
+		w.code = http.StatusInternalServerError
+		w.filters, w.filtered = nil, false
+	} else if w.chunked {
+		// A cut stream must not end like a complete one
+		w.chunked = false
+		w.closeAfter = true
+	}
 }
 
 // parserConn parses HTTP request from network connection
 // Args:
-//   - c: Active network connection
+//   - c: Active network connection, used for read deadlines
+//   - br: Buffered reader over c, kept across requests on the connection
+//   - cfg: Parser limits and strictness flags
+//   - maxURI: Request-target limit answered with 414, 0 disables it
+//   - strict: Apply the strict mode checks (see Server.StrictHTTP)
 //
 // Returns:
 //   - *Request: Parsed request object
-//   - error: Any parsing errors encountered
+//   - error: Any parsing errors encountered, as *httpError when the
+//     client should receive an error response
 //
 // Parsing Features:
-//   - 10-second header read timeout
+//   - Header read timeout
 //   - Chunked encoding support
-//   - Maximum header size enforcement
-func parserConn(c net.Conn) (*Request, error) {
-	var (
-		buffer     bytes.Buffer
-		headersLen int
-	)
-
-	// Read headers until we find the empty line separator
+//   - Maximum request-target, header size and count enforcement
+//   - Rejection of ambiguous message framing
+func parserConn(c net.Conn, br *bufio.Reader, cfg *ParserConfig, maxURI int, strict bool) (*Request, error) {
+	if strict {
+		cfg = strictParserConfig(cfg)
+	}
+	var buffer bytes.Buffer
+	requestLine := true
+
+	if cfg.HeaderTimeout > 0 {
+		c.SetReadDeadline(time.Now().Add(cfg.HeaderTimeout))
+		defer c.SetReadDeadline(time.Time{})
+	}
+
+	// Read header lines until the empty line separator; bytes after it stay
+	// buffered in br for the body and any pipelined request
 	for {
-		temp := make([]byte, 4096)
-		n, err := c.Read(temp)
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				continue
-			}
-			return nil, err
+		line, err := br.ReadSlice('\n')
+		if errors.Is(err, bufio.ErrBufferFull) {
+			err = nil
 		}
 
-		buffer.Write(temp[:n])
-		headersLen = buffer.Len()
+		// Empty lines before the request line are ignored (RFC 9112 2.2)
+		if buffer.Len() > 0 || (string(line) != "\r\n" && string(line) != "\n") {
+			buffer.Write(line)
+		}
+
+		// Oversized targets are rejected before the headers are read
+		if requestLine && buffer.Len() > 0 {
+			if err := checkRequestTarget(buffer.Bytes(), maxURI, br, cfg.MaxHeaderBytes); err != nil {
+				return nil, err
+			}
+			requestLine = bytes.IndexByte(buffer.Bytes(), '\n') < 0
+		}
 
 		// Check for header termination sequence
-		if bytes.Contains(buffer.Bytes(), []byte("\r\n\r\n")) {
+		if bytes.HasSuffix(buffer.Bytes(), []byte("\r\n\r\n")) {
 			break
 		}
 
 		// Prevent header overflow
-		if headersLen >= defaultMaxHeaderBytes {
-			return nil, errors.New("headers exceed maximum size")
+		if buffer.Len() >= cfg.MaxHeaderBytes {
+			return nil, &httpError{http.StatusRequestHeaderFieldsTooLarge, errors.New("headers exceed maximum size")}
 		}
-	}
 
-	data := buffer.Bytes()
-	idx := bytes.Index(data, []byte("\r\n\r\n"))
-	if idx == -1 {
-		return nil, errors.New("malformed headers")
+		if err != nil {
+			if errors.Is(err, io.EOF) && buffer.Len() > 0 {
+				return nil, &httpError{http.StatusBadRequest, errors.New("connection closed before end of headers")}
+			}
+			return nil, err
+		}
 	}
 
-	// Split headers and body
-	headers := data[:idx]
-	bodyStart := idx + len("\r\n\r\n")
-	initialBody := data[bodyStart:]
+	headers := bytes.TrimSuffix(buffer.Bytes(), []byte("\r\n\r\n"))
 
-	req := newRequest()
-	if err := req.parser(headers); err != nil {
+	req := acquireRequest()
+	aliased, err := req.parser(headers, cfg)
+	if err != nil {
+		releaseRequest(req)
 		return nil, err
 	}
-
-	// Check for chunked transfer encoding
-	var isChunked bool
-	if te := req.Headers.Get("transfer-encoding"); te != "" {
-		isChunked = (te == "chunked")
+	if aliased != "" {
+		if cc, ok := c.(*countingConn); !ok || !cc.aliasWarned {
+			log.Warn("header " + aliased + " from " + c.RemoteAddr().String() + " renamed by HeaderAliases; later renames on this connection are not logged")
+			if ok {
+				cc.aliasWarned = true
+			}
+		}
 	}
-
-	// Create appropriate body reader
-	var bodyReader io.Reader
-	if isChunked {
-		bodyReader = newChunkedReader(io.MultiReader(bytes.NewReader(initialBody), c))
-	} else {
-		// Handle content-length based body
-		contentLength, _ := strconv.Atoi(req.Headers.Get("content-length"))
-		if contentLength > 0 {
-			remaining := int64(contentLength) - int64(len(initialBody))
-			bodyReader = io.MultiReader(
-				bytes.NewReader(initialBody),
-				io.LimitReader(c, remaining),
-			)
-		} else {
-			bodyReader = bytes.NewReader(initialBody)
+	req.headerBytes = buffer.Len()
+	if strict {
+		if err := checkStrict(req); err != nil {
+			releaseRequest(req)
+			return nil, err
 		}
 	}
 
+	bodyReader, err := newBodyReader(req.Headers, br)
+	if err != nil {
+		releaseRequest(req)
+		return nil, err
+	}
+
 	req.Body = bodyReader
+	req.wire = bodyReader
 	req.RemoteAddrs = c.RemoteAddr().String()
 
 	return req, nil
 }
 
+// newBodyReader selects the body framing from the parsed headers
+// Requests carrying both Transfer-Encoding and Content-Length, or conflicting
+// Content-Length values, are rejected since they enable request smuggling.
+// The size limit is applied once the route is known (see limitBody)
+// The body is read from the connection reader br itself, so bytes of a
+// pipelined request after the body stay buffered for the next parse
+func newBodyReader(h Headers, br *bufio.Reader) (io.Reader, error) {
+	te := strings.ToLower(h.Get("transfer-encoding"))
+	cl := h.Get("content-length")
+
+	if te != "" {
+		if cl != "" {
+			return nil, &httpError{http.StatusBadRequest, errors.New("both transfer-encoding and content-length present")}
+		}
+		if te != "chunked" {
+			return nil, &httpError{http.StatusNotImplemented, errors.New("unsupported transfer-encoding: " + te)}
+		}
+		return newChunkedReader(br), nil
+	}
+
+	if cl == "" {
+		return http.NoBody, nil
+	}
+
+	// Duplicate Content-Length headers are joined by the parser and are only
+	// acceptable when every value is identical
+	values := strings.Split(cl, ",")
+	contentLength, err := strconv.ParseInt(strings.TrimSpace(values[0]), 10, 64)
+	if err != nil || contentLength < 0 {
+		return nil, &httpError{http.StatusBadRequest, errors.New("invalid content-length: " + cl)}
+	}
+	for _, v := range values[1:] {
+		if strings.TrimSpace(v) != strings.TrimSpace(values[0]) {
+			return nil, &httpError{http.StatusBadRequest, errors.New("conflicting content-length values: " + cl)}
+		}
+	}
+
+	return io.LimitReader(br, contentLength), nil
+}
+
+// maxBytesReader fails with ErrBodyTooLarge once more than remaining bytes are read
+type maxBytesReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (m *maxBytesReader) Read(p []byte) (int, error) {
+	if m.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// Read one byte past the limit to tell an exact fit from an overflow
+	if int64(len(p)) > m.remaining+1 {
+		p = p[:m.remaining+1]
+	}
+	n, err := m.r.Read(p)
+	m.remaining -= int64(n)
+	if m.remaining < 0 {
+		return n + int(m.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
+
 // chunkedReader handles chunked transfer encoding decoding
 type chunkedReader struct {
-	r    io.Reader
-	done bool
+	r         *bufio.Reader
+	remaining int64 // Bytes left in the current chunk
+	done      bool
+	err       error // First framing error, returned by every later Read
 }
 
 // newChunkedReader creates a new chunked encoding reader
 // Args:
-//   - r: io.Reader containing chunked data
+//   - r: Connection reader positioned at the chunked data
 //
 // Returns properly initialized chunkedReader
-func newChunkedReader(r io.Reader) io.Reader {
-	return &chunkedReader{r: bufio.NewReader(r)}
+// r is used as is: wrapping it in another bufio.Reader would read past the
+// last chunk into the next request whenever r is smaller than the wrapper
+func newChunkedReader(r *bufio.Reader) io.Reader {
+	return &chunkedReader{r: r}
 }
 
 // Read implements chunked encoding decoding logic
@@ -287,58 +786,89 @@ func newChunkedReader(r io.Reader) io.Reader {
 //   - Supports chunk extensions
 //   - Validates chunk size
 //   - Handles trailing headers
-func (cr *chunkedReader) Read(p []byte) (n int, err error) {
+//   - Streams chunk data into p without buffering whole chunks
+//   - Errors are sticky: the stream cannot be resynchronized after one
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	if cr.err != nil {
+		return 0, cr.err
+	}
+	n, err := cr.read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		cr.err = err
+	}
+	return n, err
+}
+
+// read decodes the next piece of chunked data into p
+func (cr *chunkedReader) read(p []byte) (n int, err error) {
 	if cr.done {
 		return 0, io.EOF
 	}
 
-	line, err := cr.readLine()
-	if err != nil {
-		return 0, fmt.Errorf("chunk size read error: %w", err)
-	}
+	if cr.remaining == 0 {
+		line, err := cr.readLine()
+		if err != nil {
+			return 0, fmt.Errorf("chunk size read error: %w", err)
+		}
 
-	chunkSizeHex := strings.TrimSpace(strings.Split(string(line), ";")[0])
-	chunkSize, err := strconv.ParseInt(chunkSizeHex, 16, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid chunk size '%s': %w", chunkSizeHex, err)
-	}
+		chunkSizeHex := strings.TrimSpace(strings.Split(string(line), ";")[0])
+		chunkSize, err := strconv.ParseUint(chunkSizeHex, 16, 63)
+		if err != nil {
+			return 0, fmt.Errorf("invalid chunk size '%s': %w", chunkSizeHex, err)
+		}
 
-	if chunkSize == 0 {
-		cr.done = true
-		for {
-			line, err := cr.readLine()
-			if err != nil || len(line) == 0 {
-				break
+		if chunkSize == 0 {
+			cr.done = true
+			for {
+				line, err := cr.readLine()
+				if err != nil || len(line) == 0 {
+					break
+				}
 			}
+			return 0, io.EOF
 		}
-		return 0, io.EOF
+
+		cr.remaining = int64(chunkSize)
+	}
+
+	if int64(len(p)) > cr.remaining {
+		p = p[:cr.remaining]
 	}
 
-	data := make([]byte, chunkSize)
-	if _, err := io.ReadFull(cr.r, data); err != nil {
-		return 0, fmt.Errorf("chunk data read error: %w", err)
+	n, err = cr.r.Read(p)
+	cr.remaining -= int64(n)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return n, fmt.Errorf("chunk data read error: %w", err)
 	}
 
-	if _, err := cr.readLine(); err != nil {
-		return 0, fmt.Errorf("chunk terminator read error: %w", err)
+	if cr.remaining == 0 {
+		if _, err := cr.readLine(); err != nil {
+			return n, fmt.Errorf("chunk terminator read error: %w", err)
+		}
 	}
 
-	n = copy(p, data)
 	return n, nil
 }
 
 // readLine reads CRLF-terminated lines from chunked stream
+// Lines longer than maxChunkLineBytes are rejected
 func (cr *chunkedReader) readLine() ([]byte, error) {
 	var line []byte
 	for {
-		b := make([]byte, 1)
-		if _, err := cr.r.Read(b); err != nil {
+		b, err := cr.r.ReadByte()
+		if err != nil {
 			return nil, err
 		}
-		line = append(line, b[0])
-		if len(line) >= 2 && bytes.Equal(line[len(line)-2:], []byte("\r\n")) {
+		line = append(line, b)
+		if len(line) >= 2 && line[len(line)-2] == '\r' && line[len(line)-1] == '\n' {
 			break
 		}
+		if len(line) > maxChunkLineBytes {
+			return nil, errors.New("chunk line too long")
+		}
 	}
 	return line[:len(line)-2], nil
 }
@@ -350,8 +880,16 @@ type Headers map[string]string
 // Args:
 //   - key: Header name (case-insensitive)
 //   - value: Header value
+//
+// The value replaces a previous one, except for list-valued headers (Vary,
+// Link, Set-Cookie) where it is appended; see Headers.Merge
 func (h Headers) Add(key, value string) {
-	h[strings.ToLower(key)] = value
+	key = strings.ToLower(key)
+	if _, list := listHeaders[key]; list {
+		h.appendValue(key, value)
+		return
+	}
+	h[key] = value
 }
 
 // Get retrieves a header value by name
@@ -381,15 +919,33 @@ func (h Params) Get(key string) string {
 
 // Request represents an HTTP request
 type Request struct {
-	Method      string
-	path        string
-	basePath    string
-	Headers     Headers
-	Version     string
-	Body        io.Reader
-	Params      Params
-	RemoteAddrs string
-	tempFiles   []*os.File
+	Method       string
+	path         string
+	rawQuery     string // Query string of the request target, without the "?"
+	basePath     string
+	Headers      Headers
+	Version      string
+	Body         io.Reader
+	wire         io.Reader // Body as framed on the connection, drained after the handler
+	Params       Params
+	RemoteAddrs  string
+	tempFiles    []*os.File
+	route        *RouteInfo      // Metadata of the matched route
+	trace        *requestTrace   // Execution trace, nil unless tracing is enabled
+	values       map[string]any  // Request-scoped values set by guards and middlewares
+	budget       *memBudget      // Memory accounting, nil without MaxMemoryPerRequest
+	inspector    UploadInspector // Router UploadInspector, nil when not set
+	debugCapture *captureReader  // Body capture of a request sampled by DebugSample
+	clk          Clock           // Time source of the router
+	ids          IDGenerator     // Identifier source of the router
+	headerBytes  int             // Size of the request line plus header block
+	ctx          context.Context // Set by WithContext, replaces conn
+	conn         *connContext    // Context of the connection, nil outside serveRequest
+	form         url.Values      // Query and body values, set by ParseForm
+	postForm     url.Values      // Urlencoded body values, set by ParseForm
+
+	originalMethod string    // Method sent by the client, set when it was overridden
+	pool           poolState // Use-after-release detection (gouterdebug)
 }
 
 type Path struct {
@@ -405,12 +961,25 @@ func newRequest() *Request {
 }
 
 func (r *Request) Path() *Path {
+	r.pool.checkReleased("Request", 1)
 	return &Path{
 		basePath: r.basePath,
 		reqPath:  r.path,
 	}
 }
 
+// RawQuery returns the query string of the request target, without the "?"
+func (r *Request) RawQuery() string {
+	return r.rawQuery
+}
+
+// Query parses the query string of the request target
+// Malformed pairs are skipped
+func (r *Request) Query() url.Values {
+	values, _ := url.ParseQuery(r.rawQuery)
+	return values
+}
+
 func (p *Path) GetPath() string {
 	return p.reqPath
 }
@@ -423,264 +992,190 @@ func (p *Path) GetDifPath() string {
 	return strings.TrimPrefix(p.reqPath, p.basePath)
 }
 
+// RemoteIP returns the client IP without the port
+// IPv6 addresses are returned without brackets (e.g., "::1")
+func (r *Request) RemoteIP() string {
+	host, _, err := net.SplitHostPort(r.RemoteAddrs)
+	if err != nil {
+		// Address without a port, possibly a bracketed IPv6 literal
+		return strings.TrimSuffix(strings.TrimPrefix(r.RemoteAddrs, "["), "]")
+	}
+	return host
+}
+
+// RemotePort returns the client port or an empty string if unknown
+func (r *Request) RemotePort() string {
+	_, port, err := net.SplitHostPort(r.RemoteAddrs)
+	if err != nil {
+		return ""
+	}
+	return port
+}
+
 // ReadJson deserializes request body into provided struct
 // Args:
 //   - v: Target struct for JSON decoding
 //
 // Returns error if decoding fails
 func (r *Request) ReadJson(v any) error {
+	r.pool.checkReleased("Request", 1)
 	return json.NewDecoder(r.Body).Decode(v)
 }
 
 // parser processes HTTP request headers
-func (r *Request) parser(headersByte []byte) error {
+// Duplicate headers are combined into a comma-separated list, except Host
+// which must appear only once
+// Returns the first header renamed by ParserConfig.HeaderAliases, if any
+func (r *Request) parser(headersByte []byte, cfg *ParserConfig) (aliased string, err error) {
 	lines := bytes.Split(headersByte, []byte("\r\n"))
-	if len(lines) == 0 {
-		return errors.New("empty request headers")
+	if len(lines) == 0 || len(lines[0]) == 0 {
+		return "", &httpError{http.StatusBadRequest, errors.New("empty request headers")}
+	}
+
+	if len(lines)-1 > cfg.MaxHeaderCount {
+		return "", &httpError{http.StatusRequestHeaderFieldsTooLarge, errors.New("too many headers")}
 	}
 
 	titleParts := bytes.Split(lines[0], []byte(" "))
-	if len(titleParts) != 3 {
-		return errors.New("invalid request line format")
+	if len(titleParts) != 3 || !isToken(titleParts[0]) || len(titleParts[1]) == 0 {
+		return "", &httpError{http.StatusBadRequest, errors.New("invalid request line format")}
+	}
+
+	if bytes.IndexFunc(lines[0], isForbiddenHeaderByte) != -1 {
+		return "", &httpError{http.StatusBadRequest, errors.New("invalid character in request line")}
 	}
 
 	r.Method = string(titleParts[0])
-	r.path = strings.TrimSpace(string(titleParts[1]))
+	// Routes match the path alone; the query string is kept apart
+	r.path, r.rawQuery, _ = strings.Cut(strings.TrimSpace(string(titleParts[1])), "?")
 	r.Version = string(titleParts[2])
 
+	var lastKey string
 	for i := 1; i < len(lines); i++ {
 		line := lines[i]
 		if len(line) == 0 {
 			continue
 		}
 
-		parts := bytes.SplitN(line, []byte(":"), 2)
-		if len(parts) != 2 {
-			return errors.New("invalid header format")
+		if bytes.IndexFunc(line, isForbiddenHeaderByte) != -1 {
+			return "", &httpError{http.StatusBadRequest, errors.New("invalid character in header")}
 		}
 
-		key := textproto.TrimBytes(parts[0])
-		value := textproto.TrimBytes(parts[1])
-
-		normalizedKey := strings.ToLower(string(key))
-		normalizedValue := strings.TrimSpace(string(value))
-		r.Headers.Add(normalizedKey, normalizedValue)
-	}
-
-	return nil
-}
-
-// FileUpload represents a file being uploaded via multipart/form-data
-type FileUpload struct {
-	File     *os.File // The temporary file object
-	Filename string   // Original filename from the client
-	r        *Request // Pointer to the parent request (used for cleanup)
-}
-
-// newFileUpload creates a new instance of FileUpload
-func newFileUpload(file *os.File, filename string) *FileUpload {
-	return &FileUpload{
-		File:     file,
-		Filename: filename,
-	}
-}
-
-// Save writes the uploaded file to a specified directory
-func (fu *FileUpload) Save(dir string) (*os.File, error) {
-	defer fu.r.Cleanup() // Clean up temporary files when done
-
-	f, err := os.Create(dir) // Create the destination file
-	if err != nil {
-		return nil, err
-	}
-
-	// Copy the contents of the uploaded file to the destination
-	_, err = io.Copy(f, fu.File)
-	if err != nil {
-		return nil, err
-	}
-
-	return f, nil
-}
-
-// parseStruct parses form-data content into a given struct
-func (r *Request) parseStruct(v interface{}, headers map[string]string, content []byte) error {
-	val := reflect.ValueOf(v)
-
-	// Ensure v is a pointer to a struct
-	if val.Kind() != reflect.Ptr {
-		return errors.New("is need ptr")
-	}
-
-	val = val.Elem()
-	if val.Kind() != reflect.Struct {
-		return errors.New("is need struct")
-	}
-
-	// Loop through the struct fields to match the form-data tag
-	for i := 0; i < val.NumField(); i++ {
-		f := val.Type().Field(i)
-		field := val.Field(i)
-
-		// Check if the field has a `gouter` tag
-		tag, ok := f.Tag.Lookup("gouter")
-		if !ok {
+		// Obsolete line folding continues the previous header value
+		if line[0] == ' ' || line[0] == '\t' {
+			if !cfg.AllowObsFold || lastKey == "" {
+				return "", &httpError{http.StatusBadRequest, errors.New("obsolete line folding is not allowed")}
+			}
+			r.Headers[lastKey] += " " + strings.TrimSpace(string(line))
 			continue
 		}
 
-		// Skip if tag does not match Content-Disposition name
-		if headers["Content-Disposition-Name"] != tag {
-			continue
+		parts := bytes.SplitN(line, []byte(":"), 2)
+		if len(parts) != 2 || !isToken(parts[0]) {
+			return "", &httpError{http.StatusBadRequest, errors.New("invalid header format")}
 		}
 
-		// If it's a file (with a filename), handle file upload
-		if headers["Content-Disposition-Filename-gouter"] == "filename" {
-			tempFile, err := os.CreateTemp("", "upload-*.tmp")
-			if err != nil {
-				return err
-			}
+		key := textproto.TrimBytes(parts[0])
+		value := textproto.TrimBytes(parts[1])
 
-			if _, err := tempFile.Write(content); err != nil {
-				return err
-			}
+		normalizedKey := strings.ToLower(string(key))
+		normalizedValue := strings.TrimSpace(string(value))
 
-			if _, err := tempFile.Seek(0, 0); err != nil {
-				return err
+		// Aliases are looked up by the lowercase name already built; an
+		// unknown underscore name could be read as a different header by
+		// a proxy, so it is refused
+		if cfg.HeaderAliases != nil {
+			if name, ok := cfg.HeaderAliases[normalizedKey]; ok {
+				if aliased == "" {
+					aliased = normalizedKey
+				}
+				normalizedKey = name
+			} else if strings.IndexByte(normalizedKey, '_') != -1 {
+				return "", &httpError{http.StatusBadRequest, errors.New("invalid character in header name: " + normalizedKey)}
 			}
+		}
 
-			// Assign the file to the struct field if it's a FileUpload type
-			if field.Type() == reflect.TypeOf((*FileUpload)(nil)) {
-				r.tempFiles = append(r.tempFiles, tempFile)
-				tmpFileU := newFileUpload(tempFile, headers["Content-Disposition-Filename"])
-				tmpFileU.r = r
-				field.Set(reflect.ValueOf(tmpFileU))
+		if prev, ok := r.Headers[normalizedKey]; ok {
+			switch normalizedKey {
+			case "host":
+				return "", &httpError{http.StatusBadRequest, errors.New("duplicate host header")}
+			case "cookie":
+				normalizedValue = prev + "; " + normalizedValue
+			default:
+				normalizedValue = prev + ", " + normalizedValue
 			}
 		}
 
-		// Set string content directly if field is of string type
-		if field.Kind() == reflect.String {
-			field.SetString(string(content))
-		}
+		r.Headers[normalizedKey] = normalizedValue
+		lastKey = normalizedKey
 	}
 
-	return nil
-}
-
-// Cleanup removes all temporary uploaded files
-func (r *Request) Cleanup() {
-	for _, f := range r.tempFiles {
-		f.Close()
-		os.Remove(f.Name())
-	}
+	return aliased, nil
 }
 
-// ParseMultipart processes a multipart/form-data body and populates the provided struct
-func (r *Request) ParseMultipart(v interface{}) error {
-	contentType := r.Headers.Get("Content-Type")
-	if !strings.Contains(contentType, "multipart/form-data") {
-		return errors.New("invalid header")
-	}
-
-	// Parse boundary parameter from Content-Type header
-	_, params, err := mime.ParseMediaType(contentType)
-	if err != nil {
-		return err
-	}
-
-	boundary := params["boundary"]
-	if boundary == "" {
-		return errors.New("boundary not found")
-	}
-
-	// Read full body content
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		return err
+// isToken reports whether b is a valid RFC 7230 token (method or header name)
+func isToken(b []byte) bool {
+	if len(b) == 0 {
+		return false
 	}
-
-	// Split body using boundary
-	delimiter := []byte("--" + boundary)
-	parts := bytes.Split(body, delimiter)
-
-	for _, part := range parts {
-		part := bytes.Trim(part, "\r\n-")
-		if len(part) == 0 {
-			continue
-		}
-
-		// Separate headers from content
-		sections := bytes.SplitN(part, []byte("\r\n\r\n"), 2)
-		if len(sections) < 2 {
-			continue
-		}
-
-		headerRaw, content := sections[0], sections[1]
-
-		// Parse headers and bind content to the struct
-		headers := parseHeaders(headerRaw)
-		err = r.parseStruct(v, headers, content)
-		if err != nil {
-			return err
+	for _, c := range b {
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) != -1 {
+			return false
 		}
 	}
-
-	return nil
+	return true
 }
 
-// parseHeaders parses raw header bytes into a map
-func parseHeaders(headersRaw []byte) map[string]string {
-	headers := make(map[string]string)
-	headersSection := bytes.SplitN(headersRaw, []byte("\r\n\r\n"), 2)[0]
-	lines := bytes.Split(headersSection, []byte("\r\n"))
-
-	for _, line := range lines {
-		if len(line) == 0 {
-			continue
-		}
-
-		colon := bytes.IndexByte(line, ':')
-		if colon == -1 {
-			continue
-		}
-
-		key := http.CanonicalHeaderKey(string(bytes.TrimSpace(line[:colon])))
-		value := string(bytes.TrimSpace(line[colon+1:]))
-
-		// Parse Content-Disposition or Content-Type with parameters
-		if key == "Content-Disposition" || key == "Content-Type" {
-			mainValue, params := parseHeaderWithParams(value)
-			headers[key] = mainValue
-
-			for paramName, paramValue := range params {
-				paramKey := key + "-" + http.CanonicalHeaderKey(paramName)
-				headers[paramKey] = paramValue
-
-				// Special marker for filename detection
-				if paramName == "filename" {
-					headers[paramKey+"-gouter"] = "filename"
-				}
-			}
-		} else {
-			headers[key] = value
-		}
-	}
-
-	return headers
+// isForbiddenHeaderByte reports control characters never allowed in a header line
+func isForbiddenHeaderByte(r rune) bool {
+	return r == 0 || r == '\r' || r == '\n'
 }
 
-// parseHeaderWithParams parses a header value and extracts parameters
-func parseHeaderWithParams(value string) (string, map[string]string) {
-	mainValue, params, _ := mime.ParseMediaType(value)
-	return mainValue, params
-}
+// ErrResponseDone is returned by Writer methods called after the response
+// was completed, e.g. from a goroutine that outlived its handler
+var ErrResponseDone = errors.New("response already completed")
 
 // Writer handles HTTP response generation
+//
+// Concurrency: Write, WriteJson, WriteHeader, WriteHeaders, SetHeader and
+// DelHeader may be called from goroutines spawned by the handler. Calls are
+// serialized, so each Write lands whole in the buffered body or on the wire.
+// The Headers map itself is not synchronized; use SetHeader/DelHeader when
+// other goroutines are writing. Once the handler returns the response is
+// completed and further calls fail with ErrResponseDone, so producers must
+// be finished (or stopped) before the handler returns. The body buffer is
+// recycled once the response is sent, so slices returned by Body must not
+// be retained
 type Writer struct {
-	code        uint
+	mu          sync.Mutex
+	done        bool // Handler returned, the framework owns the response
+	code        int
 	body        []byte
 	Headers     Headers
 	c           net.Conn
 	headersSent bool
+	noBody      bool           // Omit the body on the wire (HEAD requests)
+	chunked     bool           // Streamed body sent with chunked encoding
+	streamed    int64          // Body bytes sent before the handler returned
+	hijacked    bool           // Connection taken over by the handler
+	closeAfter  bool           // Close the connection once the response is sent
+	budget      *memBudget     // Request memory accounting, shared with the Request
+	maxBuffered int            // Buffered body size that switches to streaming, 0 for no limit
+	tracker     *connTracker   // Shutdown registry receiving upgraded websockets
+	cspNonce    string         // Nonce merged into Render data, set by CSPNonce
+	br          *bufio.Reader  // Connection reader, watched by CloseNotify
+	req         *Request       // Request being answered, for CloseNotify
+	notifier    *closeNotifier // Created by CloseNotify
+	dev         bool           // Router in DevMode when the request arrived
+	templates   *templateSet   // Templates of the router, for RenderTemplate
+	pool        poolState      // Use-after-release detection (gouterdebug)
+
+	surrogateKeys    []string              // Keys set by SurrogateKeys
+	surrogateHeaders []string              // Headers carrying them, nil for the default
+	sentHooks        []func(in, out int64) // Run by sent, see Logger
+	parentHeaders    Headers               // Set outside a mounted router, see Mount
+	filters          []io.WriteCloser      // Body filters, see FilterBody; the last one receives writes
+	filtered         bool                  // A filter was installed, the handler Content-Length no longer holds
 	io.Writer
 }
 
@@ -698,24 +1193,115 @@ func newWriter(c net.Conn) *Writer {
 //
 // Returns error if serialization fails
 func (w *Writer) WriteJson(v any) error {
+	w.pool.checkReleased("Writer", 1)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return ErrResponseDone
+	}
 	w.Headers.Add("Content-Type", "application/json")
-	return json.NewEncoder(w).Encode(v)
+	_, err := w.writeLocked(buf.Bytes())
+	return err
 }
 
 // WriteHeader sets the HTTP status code
 // Note: Can only be called once per response
-func (w *Writer) WriteHeader(statusCode uint) {
+// Codes outside the valid 100-999 range are replaced by 500
+func (w *Writer) WriteHeader(statusCode int) {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		log.WarnE(2, "WriteHeader called after the response was completed")
+		return
+	}
 	if w.code != 0 {
 		log.WarnE(2, "WriteHeader called multiple times")
 		return
 	}
+	if !validStatus(statusCode) {
+		log.WarnE(2, "Invalid status code "+strconv.Itoa(statusCode)+", sending 500")
+		statusCode = http.StatusInternalServerError
+	}
 	w.code = statusCode
 }
 
+// WriteHeaderUint sets the HTTP status code from a uint
+//
+// Deprecated: status codes are int across the package, matching net/http.
+// Use WriteHeader instead; this shim will be removed in a future release.
+func (w *Writer) WriteHeaderUint(statusCode uint) {
+	w.WriteHeader(uintStatus(statusCode))
+}
+
+// validStatus reports whether code fits the three-digit status code syntax
+func validStatus(code int) bool {
+	return code >= 100 && code <= 999
+}
+
+// uintStatus converts a legacy uint status, mapping values that would
+// overflow int to an invalid code so they are coerced to 500
+func uintStatus(code uint) int {
+	if code > 999 {
+		return -1
+	}
+	return int(code)
+}
+
 // Write implements io.Writer interface
 func (w *Writer) Write(p []byte) (n int, err error) {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return 0, ErrResponseDone
+	}
+	return w.writeLocked(p)
+}
+
+// writeLocked passes p through the body filters, then buffers or streams
+// it; the caller holds w.mu
+func (w *Writer) writeLocked(p []byte) (int, error) {
+	if n := len(w.filters); n > 0 && !w.hijacked {
+		return w.filters[n-1].Write(p)
+	}
+	return w.sendLocked(p)
+}
+
+// sendLocked buffers or streams body bytes past the filters; the caller
+// holds w.mu
+func (w *Writer) sendLocked(p []byte) (int, error) {
 	if w.headersSent {
-		return w.c.Write(p)
+		if w.noBody {
+			return len(p), nil
+		}
+		n, err := w.writeBody(p)
+		if err != nil && w.notifier != nil {
+			w.notifier.fire()
+		}
+		return n, err
+	}
+
+	// Large responses are streamed instead of growing the buffer; without a
+	// Content-Length the body is sent chunked
+	if w.maxBuffered > 0 && len(w.body)+len(p) > w.maxBuffered {
+		if err := w.flushBuffered(); err != nil {
+			return 0, err
+		}
+		return w.sendLocked(p)
+	}
+
+	if err := w.budget.charge(len(p)); err != nil {
+		return 0, err
 	}
 	w.body = append(w.body, p...)
 	return len(p), nil
@@ -726,24 +1312,30 @@ func (w *Writer) write() error {
 	if w.headersSent {
 		return nil
 	}
+	w.mergeParentHeaders()
 
 	statusLine := "HTTP/1.1 200 OK\r\n"
 	if w.code != 0 {
-		statusText := http.StatusText(int(w.code))
+		statusText := http.StatusText(w.code)
 		statusLine = fmt.Sprintf("HTTP/1.1 %d %s\r\n", w.code, statusText)
 	}
 
 	var headersBuilder strings.Builder
-	if len(w.body) > 0 && w.Headers.Get("content-length") == "" {
+	w.dropFilteredLength()
+	// Always frame the body so the connection can be reused
+	if w.Headers.Get("content-length") == "" && w.Headers.Get("transfer-encoding") == "" && statusAllowsBody(w.code) {
 		w.Headers.Add("content-length", strconv.Itoa(len(w.body)))
 	}
+	w.checkFraming()
 
-	for k, v := range w.Headers {
-		headersBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
+	writeHeaderLines(&headersBuilder, w.Headers)
 
 	fullHeader := statusLine + headersBuilder.String() + "\r\n"
-	if _, err := w.c.Write(append([]byte(fullHeader), w.body...)); err != nil {
+	body := w.body
+	if w.noBody {
+		body = nil
+	}
+	if _, err := w.c.Write(append([]byte(fullHeader), body...)); err != nil {
 		return fmt.Errorf("failed to write response: %w", err)
 	}
 
@@ -751,25 +1343,211 @@ func (w *Writer) write() error {
 	return nil
 }
 
-// WriteHeaders sends headers without body (for streaming responses)
-func (w *Writer) WriteHeaders() error {
+// flushBuffered sends the headers and the buffered body, switching the
+// response to streaming; the caller holds w.mu
+func (w *Writer) flushBuffered() error {
+	if err := w.writeHeaders(); err != nil {
+		return err
+	}
+	body := w.body
+	w.body = nil
+	w.budget.release(len(body))
+	if w.noBody || len(body) == 0 {
+		return nil
+	}
+	_, err := w.writeBody(body)
+	return err
+}
+
+// writeBody sends part of a streamed body, as a chunk when the response is
+// chunked; the caller holds w.mu
+func (w *Writer) writeBody(p []byte) (int, error) {
+	if !w.chunked {
+		n, err := w.c.Write(p)
+		w.streamed += int64(n)
+		return n, err
+	}
+	// An empty chunk would end the body
+	if len(p) == 0 {
+		return 0, nil
+	}
+	frame := make([]byte, 0, len(p)+20)
+	frame = strconv.AppendInt(frame, int64(len(p)), 16)
+	frame = append(frame, "\r\n"...)
+	frame = append(frame, p...)
+	frame = append(frame, "\r\n"...)
+	if _, err := w.c.Write(frame); err != nil {
+		return 0, err
+	}
+	w.streamed += int64(len(p))
+	return len(p), nil
+}
+
+// frameStream picks how a streamed body is delimited when the handler set
+// no Content-Length: chunked for HTTP/1.1, closing the connection for
+// HTTP/1.0 clients, which cannot decode chunks; the caller holds w.mu
+func (w *Writer) frameStream() {
+	if !statusAllowsBody(w.code) || w.Headers.Get("Content-Length") != "" || w.Headers.Get("Transfer-Encoding") != "" {
+		return
+	}
+	if w.req != nil && w.req.Version == "HTTP/1.0" {
+		w.Headers.Add("Connection", "close")
+		w.closeAfter = true
+		return
+	}
+	w.Headers.Add("Transfer-Encoding", "chunked")
+	w.chunked = !w.noBody
+}
+
+// checkFraming is the last check before a response goes out: a body must be
+// delimited by Content-Length, chunked encoding or, for HTTP/1.0, closing the
+// connection. Strict intermediaries reject anything else, so an unframed
+// response is logged and forced to close; the caller holds w.mu
+func (w *Writer) checkFraming() {
+	if !statusAllowsBody(w.code) || w.Headers.Get("Content-Length") != "" || w.closeAfter {
+		return
+	}
+	if te := strings.ToLower(w.Headers.Get("Transfer-Encoding")); strings.HasSuffix(strings.TrimSpace(te), "chunked") {
+		return
+	}
+	where := "response"
+	if w.req != nil {
+		where = "response to " + w.req.Method + " " + displayPath(w.req.path)
+	}
+	log.Error(fmt.Errorf("%s has a body but neither Content-Length nor chunked encoding; closing the connection", where))
+	w.Headers.Add("Connection", "close")
+	w.closeAfter = true
+}
+
+// statusAllowsBody reports whether a response with the status may carry a body
+// 0 stands for the implicit 200
+func statusAllowsBody(code int) bool {
+	return !(code >= 100 && code < 200) && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// WriteHeaders sends headers without body (for streaming responses)
+func (w *Writer) WriteHeaders() error {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return ErrResponseDone
+	}
+	return w.writeHeaders()
+}
+
+// Flush sends the headers and the buffered body, switching the response to
+// streaming: later writes go straight to the client. Without a
+// Content-Length header the body is sent with chunked encoding (HTTP/1.0
+// clients get it delimited by closing the connection), so large or slow
+// responses such as event streams and exports need no buffering
+// Headers set after the first Flush are not sent
+func (w *Writer) Flush() error {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return ErrResponseDone
+	}
+	if w.hijacked {
+		return nil
+	}
+	if err := w.flushFilters(); err != nil {
+		return err
+	}
+	if w.headersSent {
+		return nil
+	}
+	return w.flushBuffered()
+}
+
+// SetHeader sets a response header, safe for use alongside other writers
+// Returns ErrResponseDone when the response was already completed
+func (w *Writer) SetHeader(key, value string) error {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return ErrResponseDone
+	}
+	w.Headers.Add(key, value)
+	return nil
+}
+
+// DelHeader removes a response header, safe for use alongside other writers
+// Returns ErrResponseDone when the response was already completed
+func (w *Writer) DelHeader(key string) error {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done {
+		return ErrResponseDone
+	}
+	delete(w.Headers, strings.ToLower(key))
+	return nil
+}
+
+// CloseAfterReply closes the connection once this response is sent, even
+// when the client asked to keep it alive
+// Sends Connection: close unless the headers are already on the wire;
+// it has no effect on hijacked connections (e.g., websockets)
+func (w *Writer) CloseAfterReply() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done || w.hijacked {
+		return
+	}
+	w.closeAfter = true
+	if !w.headersSent {
+		w.Headers.Add("Connection", "close")
+	}
+}
+
+// complete hands the response over to the framework once the handler
+// returned; later calls from leftover goroutines fail with ErrResponseDone
+// Body filters are closed and a chunked body gets its last chunk here
+func (w *Writer) complete() {
+	w.mu.Lock()
+	w.done = true
+	w.closeFilters()
+	if w.chunked {
+		w.chunked = false
+		if _, err := w.c.Write([]byte("0\r\n\r\n")); err != nil && w.notifier != nil {
+			w.notifier.fire()
+		}
+	}
+	w.mu.Unlock()
+}
+
+// writeHeaders sends the status line and headers; the caller holds w.mu
+func (w *Writer) writeHeaders() error {
 	if w.headersSent {
 		return nil
 	}
+	w.mergeParentHeaders()
+	w.dropFilteredLength()
+	w.frameStream()
+	w.checkFraming()
 
 	statusLine := "HTTP/1.1 200 OK\r\n"
 	if w.code != 0 {
-		statusText := http.StatusText(int(w.code))
+		statusText := http.StatusText(w.code)
 		statusLine = fmt.Sprintf("HTTP/1.1 %d %s\r\n", w.code, statusText)
 	}
 
 	var headersBuilder strings.Builder
-	for k, v := range w.Headers {
-		headersBuilder.WriteString(fmt.Sprintf("%s: %s\r\n", k, v))
-	}
+	writeHeaderLines(&headersBuilder, w.Headers)
 
 	fullHeader := statusLine + headersBuilder.String() + "\r\n"
 	if _, err := w.c.Write([]byte(fullHeader)); err != nil {
+		if w.notifier != nil {
+			w.notifier.fire()
+		}
 		return fmt.Errorf("failed to write headers: %w", err)
 	}
 
@@ -784,20 +1562,35 @@ func (w *Writer) WriteHeaders() error {
 //
 // Returns:
 //   - *os.File: Opened file handle
-//   - error: Any file operation errors
+//   - error: Any file operation errors; ErrUploadRejected in a 422
+//     StatusError when the router UploadInspector rejects the content
 func ReceiveFile(r *Request, path string) (*os.File, error) {
-	b, err := io.ReadAll(r.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read request body: %w", err)
-	}
-
+	// The body is streamed to disk, bounded by the body limit of the route;
+	// a rejected or failed upload leaves no file behind
 	f, err := os.Create(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create file: %w", err)
 	}
 
-	if _, err := f.Write(b); err != nil {
+	if r.inspector == nil {
+		if _, err := io.Copy(f, r.Body); err != nil {
+			f.Close()
+			os.Remove(path)
+			return nil, fmt.Errorf("failed to write file contents: %w", err)
+		}
+		return f, nil
+	}
+
+	info := UploadPartInfo{
+		Filename:    filepath.Base(path),
+		ContentType: r.Headers.Get("Content-Type"),
+	}
+	if err := r.spoolUpload(f, r.Body, info); err != nil {
 		f.Close()
+		os.Remove(path)
+		if errors.Is(err, ErrUploadRejected) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to write file contents: %w", err)
 	}
 
@@ -812,13 +1605,21 @@ func ReceiveFile(r *Request, path string) (*os.File, error) {
 //
 // Returns error if template execution fails
 func ListenFiles(w *Writer, r *Request, path string) error {
-	w.Headers.Add("Access-Control-Allow-Methods", "GET, OPTIONS")
+	return listFiles(w, r, path, nil)
+}
+
+// listFiles renders the listing of ListenFiles, leaving out the entries
+// hide reports (may be nil)
+func listFiles(w *Writer, r *Request, path string, hide func(name string) bool) error {
+	w.Headers.Add("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(200)
 		return nil
 	}
 
-	if r.Method != "GET" {
+	// HEAD renders the listing too, so its Content-Length is the GET one
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Headers.Add("Allow", "GET, HEAD, OPTIONS")
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return errors.New("method not allowed")
 	}
@@ -827,6 +1628,9 @@ func ListenFiles(w *Writer, r *Request, path string) error {
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %w", err)
 	}
+	if hide != nil {
+		entries = slices.DeleteFunc(entries, func(e os.DirEntry) bool { return hide(e.Name()) })
+	}
 
 	tmpl := template.Must(template.New("files").Funcs(template.FuncMap{
 		"IsDir": func(e os.DirEntry) bool { return e.IsDir() },
@@ -896,50 +1700,97 @@ func ListenFiles(w *Writer, r *Request, path string) error {
 //   - w: Response writer
 //   - err: Error to display
 //   - code: HTTP status code
-func Error(w *Writer, err error, code uint) {
+func Error(w *Writer, err error, code int) {
 	w.WriteHeader(code)
 	w.Write([]byte(err.Error()))
 }
 
-// ServerStatic configures static file serving for a directory
+// ErrorUint sends an error response with a uint status code
+//
+// Deprecated: status codes are int across the package, matching net/http.
+// Use Error instead; this shim will be removed in a future release.
+func ErrorUint(w *Writer, err error, code uint) {
+	Error(w, err, uintStatus(code))
+}
+
+// ServerFiles serves a file below fsRoot for the part of the request path
+// after the route base path
 // Args:
 //   - Request: Request for this route
-//	 - Writer: Writer for write the req
+//   - Writer: Writer for write the req
 //   - fsRoot: Filesystem root directory to serve files from
 //
 // Security Features:
 //   - Path traversal protection
+//   - Backslashes and NUL bytes in the path are rejected
 //   - MIME type detection
-//   - Directory listing prevention
 func ServerFiles(r *Request, w *Writer, fsRoot string) {
-	w.Headers.Add("Access-Control-Allow-Methods", "GET, OPTIONS")
+	serveStatic(r, w, filepath.Clean(fsRoot), &StaticOptions{})
+}
+
+// ServerStatic configures static file serving for a directory
+// Args:
+//   - router: Router instance to register handlers on
+//   - basePath: URL prefix to serve files from
+//   - fsRoot: Filesystem root directory to serve files from
+//
+// Security Features:
+//   - Path traversal protection
+//   - Backslashes and NUL bytes in the path are rejected
+//   - MIME type detection
+func ServerStatic(router *Router, basePath, fsRoot string) {
+	ServerStaticOptions(router, basePath, fsRoot, StaticOptions{})
+}
+
+// ServerStaticOptions configures static file serving for a directory,
+// refusing the names matched by opts with 404
+// See ServerStatic
+func ServerStaticOptions(router *Router, basePath, fsRoot string, opts StaticOptions) {
+	basePath = "/" + strings.Trim(basePath, "/")
+	fsRoot = filepath.Clean(fsRoot)
+	router.staticRoots = append(router.staticRoots, fsRoot)
+
+	router.Route(basePath+"/*", func(r *Request, w *Writer) {
+		serveStatic(r, w, fsRoot, &opts)
+	})
+}
+
+// serveStatic answers a request for a file or directory below fsRoot
+func serveStatic(r *Request, w *Writer, fsRoot string, opts *StaticOptions) {
+	w.Headers.Add("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
 	if r.Method == "OPTIONS" {
 		w.WriteHeader(200)
 		return
 	}
 
-	if r.Method != "GET" {
+	// HEAD gets the headers of GET, Content-Length included, and no body
+	if r.Method != "GET" && r.Method != "HEAD" {
+		w.Headers.Add("Allow", "GET, HEAD, OPTIONS")
 		w.WriteHeader(http.StatusMethodNotAllowed)
 		return
 	}
 
-	decodedPath, err := url.PathUnescape(r.Path().GetDifPath())
-	if err != nil {
-		w.WriteHeader(400)
+	cleanPath, status := resolveStaticPath(fsRoot, r.Path().GetDifPath(), opts)
+	if status != 0 {
+		w.WriteHeader(status)
 		return
 	}
 
-	filePath := filepath.Join(fsRoot, decodedPath)
-	cleanPath := filepath.Clean(filePath)
-
 	info, err := os.Stat(cleanPath)
 	if err != nil {
 		w.WriteHeader(404)
 		return
 	}
 
+	// Listings leave out the names a direct request would be refused
 	if info.IsDir() {
-		ListenFiles(w, r, cleanPath)
+		var dir []string
+		if rel, _ := filepath.Rel(fsRoot, cleanPath); rel != "." {
+			dir = strings.Split(filepath.ToSlash(rel), "/")
+		}
+		listFiles(w, r, cleanPath, func(name string) bool {
+			return opts.denies(append(dir[:len(dir):len(dir)], name))
+		})
 		return
 	}
 
@@ -951,7 +1802,9 @@ func ServerFiles(r *Request, w *Writer, fsRoot string) {
 	defer file.Close()
 
 	stat, _ := file.Stat()
-	w.Headers.Add("Content-Length", strconv.FormatInt(stat.Size(), 10))
+	if w.dev {
+		w.Headers.Add("Cache-Control", "no-store")
+	}
 
 	if mimeType := mime.TypeByExtension(filepath.Ext(cleanPath)); mimeType != "" {
 		w.Headers.Add("Content-Type", mimeType)
@@ -959,142 +1812,167 @@ func ServerFiles(r *Request, w *Writer, fsRoot string) {
 		w.Headers.Add("Content-Type", "application/octet-stream")
 	}
 
-	w.WriteHeader(200)
-	if err := w.WriteHeaders(); err != nil {
-		log.Error(err)
-		return
-	}
-
-	if _, err := io.Copy(w.c, file); err != nil && !isClosedConnectionError(err) {
+	if err := sendFile(r, w, file, stat.Size()); err != nil && !isClosedConnectionError(err) {
 		log.Error(fmt.Errorf("error copying file: %w", err))
 	}
 }
 
-// ServerStatic configures static file serving for a directory
-// Args:
-//   - router: Router instance to register handlers on
-//   - basePath: URL prefix to serve files from
-//   - fsRoot: Filesystem root directory to serve files from
-//
-// Security Features:
-//   - Path traversal protection
-//   - MIME type detection
-//   - Directory listing prevention
-func ServerStatic(router *Router, basePath, fsRoot string) {
-	basePath = "/" + strings.Trim(basePath, "/")
-	fsRoot = filepath.Clean(fsRoot)
-
-	router.Route(basePath+"/*", func(r *Request, w *Writer) {
-		w.Headers.Add("Access-Control-Allow-Methods", "GET, OPTIONS")
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(200)
-			return
-		}
-
-		if r.Method != "GET" {
-			w.WriteHeader(http.StatusMethodNotAllowed)
-			return
-		}
-
-		decodedPath, err := url.PathUnescape(r.Path().GetDifPath())
-		if err != nil {
-			w.WriteHeader(400)
-			return
-		}
-
-		filePath := filepath.Join(fsRoot, decodedPath)
-		cleanPath := filepath.Clean(filePath)
+// isClosedConnectionError checks for common connection closure errors
+func isClosedConnectionError(err error) bool {
+	return strings.Contains(err.Error(), "closed") ||
+		strings.Contains(err.Error(), "broken pipe") ||
+		strings.Contains(err.Error(), "reset by peer")
+}
 
-		info, err := os.Stat(cleanPath)
-		if err != nil {
-			w.WriteHeader(404)
-			return
-		}
+// docAutoPortRange is how many ports after Doc.Port AutoPort tries
+const docAutoPortRange = 10
 
-		if info.IsDir() {
-			ListenFiles(w, r, cleanPath)
-			return
+// startDoc binds the documentation server and serves it in the background
+// The bind happens before returning, so DocsAddr is settled once the caller
+// proceeds; the error is only returned when Doc.FailOnError is set
+// Returns the listener, nil when the server did not start, so a Server can
+// close it on Shutdown
+func startDoc(r *Router) (net.Listener, error) {
+	listener, err := listenDoc(r.docConfig)
+	if err != nil {
+		err = fmt.Errorf("failed to start documentation server: %w", err)
+		if r.docConfig.FailOnError {
+			return nil, err
 		}
+		log.Error(err)
+		return nil, nil
+	}
 
-		file, err := os.Open(cleanPath)
-		if err != nil {
-			w.WriteHeader(404)
-			return
-		}
-		defer file.Close()
+	r.docsMu.Lock()
+	r.docsAddr = listener.Addr().String()
+	r.docsMu.Unlock()
 
-		stat, _ := file.Stat()
-		w.Headers.Add("Content-Length", strconv.FormatInt(stat.Size(), 10))
+	log.System("Auto Documentation enabled: http://" + listener.Addr().String())
+	spawn("docs", func() { serveDoc(listener, r) })
+	return listener, nil
+}
 
-		if mimeType := mime.TypeByExtension(filepath.Ext(cleanPath)); mimeType != "" {
-			w.Headers.Add("Content-Type", mimeType)
-		} else {
-			w.Headers.Add("Content-Type", "application/octet-stream")
-		}
+// listenDoc binds the configured documentation address, walking up to
+// docAutoPortRange following ports when AutoPort is set
+func listenDoc(cfg *Doc) (net.Listener, error) {
+	host := strings.Trim(cfg.Addrs, "[]")
+	// JoinHostPort adds the brackets required by IPv6 literals
+	listener, err := listen(net.JoinHostPort(host, cfg.Port))
+	if err == nil || !cfg.AutoPort {
+		return listener, err
+	}
 
-		w.WriteHeader(200)
-		if err := w.WriteHeaders(); err != nil {
-			log.Error(err)
-			return
-		}
+	port, convErr := strconv.Atoi(cfg.Port)
+	if convErr != nil || port == 0 {
+		return nil, err
+	}
 
-		if _, err := io.Copy(w.c, file); err != nil && !isClosedConnectionError(err) {
-			log.Error(fmt.Errorf("error copying file: %w", err))
+	for p := port + 1; p <= port+docAutoPortRange; p++ {
+		l, tryErr := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(p)))
+		if tryErr == nil {
+			log.Warn("Documentation port " + cfg.Port + " unavailable, using " + strconv.Itoa(p))
+			return l, nil
 		}
-	})
-}
-
-// isClosedConnectionError checks for common connection closure errors
-func isClosedConnectionError(err error) bool {
-	return strings.Contains(err.Error(), "closed") ||
-		strings.Contains(err.Error(), "broken pipe") ||
-		strings.Contains(err.Error(), "reset by peer")
-}
-
-// startDoc initializes documentation server
-func startDoc(r *Router) {
-	listener, err := net.Listen("tcp", r.docConfig.Addrs+":"+r.docConfig.Port)
-	if err != nil {
-		log.Error(fmt.Errorf("failed to start documentation server: %w", err))
-		return
 	}
+	return nil, fmt.Errorf("ports %d-%d unavailable: %w", port, port+docAutoPortRange, err)
+}
 
-	log.System("Auto Documentation enabled: http://localhost:" + r.docConfig.Port)
+// serveDoc accepts documentation connections until the listener fails or
+// is closed
+func serveDoc(listener net.Listener, r *Router) {
 	for {
 		conn, err := listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
 		if err != nil {
 			log.Error(fmt.Errorf("doc server accept error: %w", err))
 			return
 		}
-		go handleDocRequest(conn, r)
+		spawn("docs-conn", func() { handleDocRequest(conn, r) })
 	}
 }
 
+// DocsAddr returns the address the documentation server listens on
+// Returns false when docs are disabled or failed to start; it is settled
+// before Run, RunTLS or Serve start accepting connections
+func (r *Router) DocsAddr() (string, bool) {
+	r.docsMu.Lock()
+	defer r.docsMu.Unlock()
+	return r.docsAddr, r.docsAddr != ""
+}
+
 // handleDocRequest serves documentation UI
 func handleDocRequest(c net.Conn, r *Router) {
 	defer c.Close()
 
-	_, err := parserConn(c)
+	req, err := parserConn(c, bufio.NewReader(c), r.parserConfig, defaultMaxURILength, false)
+	if err == nil {
+		err = limitBody(req, r.parserConfig.MaxBodyBytes)
+	}
 	if err != nil {
 		log.Error(fmt.Errorf("doc request parsing failed: %w", err))
 		return
 	}
 
 	w := newWriter(c)
+	w.noBody = req.Method == "HEAD"
+	r.serveDocs(req, req.path, w)
+	if err := w.write(); err != nil {
+		log.Error(fmt.Errorf("doc response failed: %w", err))
+	}
+}
+
+// serveDocs answers a documentation path: the OpenAPI document, Swagger UI
+// and "Try it" calls when enabled, and the docs page for anything else
+func (r *Router) serveDocs(req *Request, path string, w *Writer) {
+	switch {
+	case path == "/try" && r.docConfig.TryIt:
+		r.serveTryIt(req, w)
+		return
+	case path == "/openapi.json":
+		doc, err := r.OpenAPI()
+		if err != nil {
+			log.Error(fmt.Errorf("openapi document failed: %w", err))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Headers.Add("Content-Type", "application/json")
+		w.Write(doc)
+		return
+	case path == "/swagger" && r.docConfig.SwaggerUI:
+		w.Headers.Add("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, swaggerUIPage)
+		return
+	}
+
 	tmpl := template.Must(template.New("docs").Funcs(template.FuncMap{
 		"json": func(v interface{}) string {
 			b, _ := json.MarshalIndent(v, "", "  ")
 			return string(b)
 		},
 		"lower": strings.ToLower,
+		"curl":  exampleCurl,
 	}).Parse(docsTemplate))
 
 	data := struct {
-		Title  string
-		Routes []*RouteInfo
+		Title    string
+		Routes   []*RouteInfo
+		WSBase   string
+		HTTPBase string
+
+		MethodOverride bool
+		SwaggerUI      bool
+		TryIt          bool
 	}{
-		Title:  "Gouter Documentation",
-		Routes: r.docs,
+		Title:    r.docsTitle(),
+		Routes:   r.visibleDocs(),
+		WSBase:   r.wsBase(),
+		HTTPBase: r.httpBase(),
+
+		MethodOverride: r.methodOverride,
+		SwaggerUI:      r.docConfig.SwaggerUI,
+		TryIt:          r.docConfig.TryIt,
 	}
 
 	w.Headers.Add("Content-Type", "text/html; charset=utf-8")
@@ -1102,26 +1980,11 @@ func handleDocRequest(c net.Conn, r *Router) {
 
 	if err := tmpl.Execute(w, data); err != nil {
 		log.Error(fmt.Errorf("template execution failed: %w", err))
-		return
-	}
-
-	if err := w.write(); err != nil {
-		log.Error(fmt.Errorf("doc response failed: %w", err))
 	}
 }
 
-// HTML template constant omitted for brevity
-const docsTemplate = `<!DOCTYPE html>
-<html lang="en">
-
-<head>
-    <meta charset="UTF-8">
-    <meta name="viewport" content="width=device-width, initial-scale=1.0">
-    <title>{{.Title}}</title>
-    <!-- Inter font from Google Fonts -->
-    <link href="https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap" rel="stylesheet">
-    <style>
-        :root {
+// docsThemeCSS holds the color palette shared by the docs UI and default error pages
+const docsThemeCSS = `:root {
             --bg-dark: #1e1e1e;
             --bg-panel: #111111;
             --bg-code: #222222;
@@ -1143,7 +2006,20 @@ const docsTemplate = `<!DOCTYPE html>
             --json-number: #b5cea8;
             --json-brace: #d4d4d4;
             --line-number: #858585;
-        }
+        }`
+
+// HTML template constant omitted for brevity
+const docsTemplate = `<!DOCTYPE html>
+<html lang="en">
+
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>{{.Title}}</title>
+    <!-- Inter font from Google Fonts -->
+    <link href="https://fonts.googleapis.com/css2?family=Inter:wght@400;500;600;700&display=swap" rel="stylesheet">
+    <style>
+` + docsThemeCSS + `
 
         * {
             box-sizing: border-box;
@@ -1198,6 +2074,40 @@ const docsTemplate = `<!DOCTYPE html>
             color: var(--text-primary);
         }
 
+        .try-it label {
+            display: block;
+            margin-bottom: 10px;
+            font-size: 13px;
+            color: var(--text-secondary);
+        }
+
+        .try-it input,
+        .try-it textarea {
+            display: block;
+            width: 100%;
+            margin-top: 4px;
+            padding: 6px 8px;
+            background: var(--bg-code);
+            color: var(--text-primary);
+            border: 1px solid var(--border);
+            border-radius: 4px;
+            font-family: monospace;
+        }
+
+        .try-result {
+            margin-top: 10px;
+            white-space: pre-wrap;
+        }
+
+        .spec-links {
+            margin: -10px 0 20px;
+            font-size: 13px;
+        }
+
+        .spec-links a {
+            color: var(--accent);
+        }
+
         .sidebar h3 {
             margin-top: 25px;
             margin-bottom: 15px;
@@ -1295,6 +2205,16 @@ const docsTemplate = `<!DOCTYPE html>
             color: white;
         }
 
+        .method-ws {
+            background-color: var(--accent);
+            color: white;
+            margin-left: 6px;
+        }
+
+        .endpoint-host {
+            color: var(--text-secondary);
+        }
+
         .endpoint-path {
             font-family: 'Consolas', 'Monaco', monospace;
             font-size: 16px;
@@ -1328,6 +2248,27 @@ const docsTemplate = `<!DOCTYPE html>
             font-weight: 600;
         }
 
+        .example {
+            margin: 10px 0 20px 0;
+        }
+
+        .example-header {
+            display: flex;
+            justify-content: space-between;
+            color: var(--text-secondary);
+            margin-bottom: 6px;
+        }
+
+        .example-code {
+            background-color: var(--bg-code);
+            border: 1px solid var(--border);
+            border-radius: 8px;
+            padding: 12px;
+            font-family: 'Consolas', 'Monaco', monospace;
+            white-space: pre-wrap;
+            overflow-x: auto;
+        }
+
         .params-table {
             width: 100%;
             border-collapse: collapse;
@@ -1827,6 +2768,7 @@ const docsTemplate = `<!DOCTYPE html>
     <div class="container">
         <div class="sidebar" id="sidebar">
             <h2>{{.Title}}</h2>
+            <p class="spec-links"><a href="openapi.json">openapi.json</a>{{ if .SwaggerUI }} · <a href="swagger">Swagger UI</a>{{ end }}</p>
 
             <div class="search-container">
                 <input type="text" id="searchInput" class="search-input" placeholder="Search endpoints...">
@@ -1839,7 +2781,8 @@ const docsTemplate = `<!DOCTYPE html>
                 <li data-path="{{ .Path }}" data-method="{{ .Method }}">
                     <a href="#{{ .Method | lower }}-{{ .Path }}">
                         <span class="endpoint-method method-{{ .Method | lower }}">{{ .Method }}</span>
-                        {{ .Path }}
+                        {{ if eq .Protocol "websocket" }}<span class="endpoint-method method-ws">WS</span>{{ end }}
+                        {{ if .Host }}<span class="endpoint-host">{{ .Host }}</span>{{ end }}{{ .Path }}
                     </a>
                 </li>
                 {{ end }}
@@ -1856,12 +2799,17 @@ const docsTemplate = `<!DOCTYPE html>
                 <div class="endpoint-header">
                     <div class="header-anchor">
                         <span class="endpoint-method method-{{ .Method | lower }}">{{ .Method }}</span>
+                        {{ if eq .Protocol "websocket" }}<span class="endpoint-method method-ws">WS</span>{{ end }}
                         <span class="endpoint-path">
-                            {{ .Path }}
+                            {{ if .Host }}<span class="endpoint-host">{{ .Host }}</span>{{ end }}{{ .Path }}
                             <a href="#{{ .Method | lower }}-{{ .Path }}">#</a>
                         </span>
                     </div>
+                    {{ if eq .Protocol "websocket" }}
+                    <button class="copy-btn" onclick="copyToClipboard(wsURL('{{ .Path }}'))">Copy URL</button>
+                    {{ else }}
                     <button class="copy-btn" onclick="copyToClipboard('{{ .Path }}')">Copy URL</button>
+                    {{ end }}
                 </div>
 
                 <div class="endpoint-body">
@@ -1871,6 +2819,18 @@ const docsTemplate = `<!DOCTYPE html>
                     </div>
                     {{ end }}
 
+                    {{ if and $.MethodOverride (or (eq .Method "PUT") (eq .Method "PATCH") (eq .Method "DELETE")) }}
+                    <div class="endpoint-description">
+                        Also reachable with <code>POST</code> and <code>X-HTTP-Method-Override: {{ .Method }}</code>, or a <code>_method={{ .Method }}</code> form field
+                    </div>
+                    {{ end }}
+
+                    {{ if eq .Protocol "websocket" }}
+                    <div class="endpoint-description">
+                        Upgrades to a WebSocket connection{{ if .Subprotocols }}; subprotocols: {{ range $i, $p := .Subprotocols }}{{ if $i }}, {{ end }}<code>{{ $p }}</code>{{ end }}{{ end }}
+                    </div>
+                    {{ end }}
+
                     {{ if .Parameters }}
                     <h3 class="section-title">Parameters</h3>
                     <table class="params-table">
@@ -1892,6 +2852,76 @@ const docsTemplate = `<!DOCTYPE html>
                         </tbody>
                     </table>
                     {{ end }}
+
+                    {{ if .QueryParams }}
+                    <h3 class="section-title">Query Parameters</h3>
+                    <table class="params-table">
+                        <thead>
+                            <tr>
+                                <th>Name</th>
+                                <th>Type</th>
+                                <th>Description</th>
+                            </tr>
+                        </thead>
+                        <tbody>
+                            {{ range .QueryParams }}
+                            <tr>
+                                <td class="param-name">{{ .Name }}</td>
+                                <td class="param-type">{{ .Type }}</td>
+                                <td>{{ .Description }}</td>
+                            </tr>
+                            {{ end }}
+                        </tbody>
+                    </table>
+                    {{ end }}
+
+                    {{ with .RequestBody }}
+                    <h3 class="section-title">Request Body</h3>
+                    {{ template "body" . }}
+                    {{ end }}
+
+                    {{ if .Responses }}
+                    <h3 class="section-title">Responses</h3>
+                    {{ range .Responses }}
+                    <div class="example">
+                        <div class="example-header">
+                            <span>{{ .Status }} {{ .StatusText }}</span>
+                        </div>
+                        {{ if .Schema }}{{ template "body" . }}{{ end }}
+                    </div>
+                    {{ end }}
+                    {{ end }}
+
+                    {{ if .Examples }}
+                    {{ $route := . }}
+                    <h3 class="section-title">Examples</h3>
+                    {{ range .Examples }}
+                    <div class="example">
+                        <div class="example-header">
+                            <span>{{ .Name }}</span>
+                            {{ if .Response.Status }}<span class="param-type">expects {{ .Response.Status }}</span>{{ end }}
+                        </div>
+                        <pre class="example-code">{{ curl $.HTTPBase $route.Method $route.Path . }}</pre>
+                    </div>
+                    {{ end }}
+                    {{ end }}
+
+                    {{ if and $.TryIt (ne .Protocol "websocket") }}
+                    <h3 class="section-title">Try it</h3>
+                    <form class="try-it" data-method="{{ .Method }}" onsubmit="return tryIt(this)">
+                        <label>Path <input name="path" value="{{ .Path }}"></label>
+                        {{ range .Parameters }}
+                        <label>{{ .Name }} <input data-param="{{ .Name }}" placeholder="{{ .Type }}"></label>
+                        {{ end }}
+                        <label>Query <input name="query" placeholder="a=1&b=2"></label>
+                        <label>Headers <textarea name="headers" rows="2" placeholder="Name: value">{{ if .RequestBody }}Content-Type: application/json{{ end }}</textarea></label>
+                        {{ if not (or (eq .Method "GET") (eq .Method "HEAD")) }}
+                        <label>Body <textarea name="body" rows="6">{{ with .RequestBody }}{{ .Example }}{{ end }}</textarea></label>
+                        {{ end }}
+                        <button type="submit" class="copy-btn">Send</button>
+                        <pre class="example-code try-result" hidden></pre>
+                    </form>
+                    {{ end }}
                 </div>
             </div>
             {{ end }}
@@ -2018,12 +3048,68 @@ const docsTemplate = `<!DOCTYPE html>
             setTimeout(() => n.classList.remove('show'), 2000);
         }
 
+        // Websocket URLs point at the served application, not the docs server
+        function wsURL(path) {
+            const base = '{{ .WSBase }}';
+            if (base) {
+                return base + path;
+            }
+            return (location.protocol === 'https:' ? 'wss://' : 'ws://') + location.hostname + path;
+        }
+
         function copyToClipboard(text) {
             navigator.clipboard.writeText(text)
                 .then(() => showNotification('URL copied to clipboard!'))
                 .catch(() => alert('Failed to copy URL'));
         }
 
+        // "Try it" posts the request to the doc server, which runs it against
+        // the application and returns the response
+        function tryIt(form) {
+            let path = form.elements.path.value;
+            form.querySelectorAll('[data-param]').forEach(input => {
+                const token = new RegExp('[:*]' + input.dataset.param + '(?![A-Za-z0-9_])');
+                path = path.replace(token, m => m[0] === '*' ? encodeURI(input.value) : encodeURIComponent(input.value));
+            });
+            const query = form.elements.query.value.replace(/^\?/, '');
+            if (query) {
+                path += '?' + query;
+            }
+
+            const headers = {};
+            form.elements.headers.value.split('\n').forEach(line => {
+                const i = line.indexOf(':');
+                if (i > 0) {
+                    headers[line.slice(0, i).trim()] = line.slice(i + 1).trim();
+                }
+            });
+            const body = form.elements.body ? form.elements.body.value : '';
+
+            const out = form.querySelector('.try-result');
+            out.hidden = false;
+            out.textContent = 'Sending...';
+            fetch('try', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ method: form.dataset.method, path: path, headers: headers, body: body })
+            })
+                .then(resp => resp.json())
+                .then(res => {
+                    if (res.error) {
+                        out.textContent = res.error;
+                        return;
+                    }
+                    const lines = [res.status + ' (' + res.duration_ms + ' ms)'];
+                    Object.keys(res.headers || {}).sort().forEach(k => lines.push(k + ': ' + res.headers[k]));
+                    lines.push('', res.body + (res.truncated ? '\n[truncated]' : ''));
+                    out.textContent = lines.join('\n');
+                })
+                .catch(err => {
+                    out.textContent = 'Request failed: ' + err;
+                });
+            return false;
+        }
+
         function copyCode(btn) {
             const code = btn.nextElementSibling.innerText.replace(/^\s*\d+\s/gm, '');
             navigator.clipboard.writeText(code)
@@ -2034,4 +3120,29 @@ const docsTemplate = `<!DOCTYPE html>
 </body>
 
 </html>
+{{ define "body" }}
+{{ with .Schema.Fields }}
+<table class="params-table">
+    <thead>
+        <tr>
+            <th>Field</th>
+            <th>Type</th>
+            <th>Required</th>
+            <th>Description</th>
+        </tr>
+    </thead>
+    <tbody>
+        {{ range . }}
+        <tr>
+            <td class="param-name">{{ .Name }}</td>
+            <td class="param-type">{{ .Type }}</td>
+            <td>{{ if .Required }}yes{{ end }}</td>
+            <td>{{ .Description }}</td>
+        </tr>
+        {{ end }}
+    </tbody>
+</table>
+{{ end }}
+{{ if .Example }}<pre class="example-code">{{ .Example }}</pre>{{ end }}
+{{ end }}
 `
@@ -0,0 +1,373 @@
+package gouter
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPipelinedRequestAfterChunkedBody(t *testing.T) {
+	r := newTestRouter()
+	r.Post("/a", func(req *Request, w *Writer) {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			t.Errorf("read body: %v", err)
+		}
+		w.Write(b)
+	})
+	r.Get("/b", func(req *Request, w *Writer) {
+		w.Write([]byte("b"))
+	})
+	// Read buffers below 4KB used to be wrapped again by the chunked reader,
+	// which then swallowed the pipelined request
+	addr := serveTest(t, &Server{Router: r, MinReadBufferSize: 512, MaxReadBufferSize: 512})
+
+	raw := "POST /a HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n" +
+		"GET /b HTTP/1.1\r\nHost: x\r\n\r\n"
+	resps := rawExchange(t, addr, raw, 2)
+
+	if got := bodyString(t, resps[0]); got != "hello" {
+		t.Errorf("first body = %q, want %q", got, "hello")
+	}
+	if got := bodyString(t, resps[1]); resps[1].StatusCode != 200 || got != "b" {
+		t.Errorf("second response = %d %q, want 200 %q", resps[1].StatusCode, got, "b")
+	}
+}
+
+func TestRemoteIPAndPort(t *testing.T) {
+	tests := []struct {
+		addr, ip, port string
+	}{
+		{"[::1]:8080", "::1", "8080"},
+		{"[2001:db8::1]:443", "2001:db8::1", "443"},
+		{"[fe80::1%eth0]:80", "fe80::1%eth0", "80"},
+		{"[::ffff:192.0.2.1]:1", "::ffff:192.0.2.1", "1"},
+		{"192.0.2.1:5000", "192.0.2.1", "5000"},
+		{"[::1]", "::1", ""},
+		{"::1", "::1", ""},
+		{"192.0.2.1", "192.0.2.1", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		r := &Request{RemoteAddrs: tt.addr}
+		if got := r.RemoteIP(); got != tt.ip {
+			t.Errorf("RemoteIP(%q) = %q, want %q", tt.addr, got, tt.ip)
+		}
+		if got := r.RemotePort(); got != tt.port {
+			t.Errorf("RemotePort(%q) = %q, want %q", tt.addr, got, tt.port)
+		}
+	}
+}
+
+// listenIPv6 listens on [::1]:0, skipping the test when IPv6 is unavailable
+func listenIPv6(t *testing.T) net.Listener {
+	t.Helper()
+	l, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	return l
+}
+
+func TestRemoteAddrOverIPv6(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) {
+		w.Write([]byte(req.RemoteIP() + " " + req.RemotePort()))
+	})
+	addr := serveListener(t, &Server{Router: r}, listenIPv6(t))
+
+	resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: [::1]\r\n\r\n", 1)[0]
+	ip, port, _ := strings.Cut(bodyString(t, resp), " ")
+	if ip != "::1" {
+		t.Errorf("RemoteIP = %q, want %q", ip, "::1")
+	}
+	if port == "" || strings.ContainsAny(port, "[]:") {
+		t.Errorf("RemotePort = %q, want a bare port number", port)
+	}
+}
+
+func TestDocsAddrIPv6(t *testing.T) {
+	listenIPv6(t).Close()
+	for _, host := range []string{"::1", "[::1]"} {
+		r := NewRouter()
+		r.Update(func(d *Doc) {
+			d.Addrs = host
+			d.Port = "0"
+		})
+
+		stdout := os.Stdout
+		rd, wr, _ := os.Pipe()
+		os.Stdout = wr
+		l, err := startDoc(r)
+		os.Stdout = stdout
+		wr.Close()
+		out, _ := io.ReadAll(rd)
+		if err != nil || l == nil {
+			t.Fatalf("startDoc with Addrs %q: %v", host, err)
+		}
+		l.Close()
+
+		addr, ok := r.DocsAddr()
+		if !ok || !strings.HasPrefix(addr, "[::1]:") {
+			t.Errorf("DocsAddr with Addrs %q = %q, want [::1]:port", host, addr)
+		}
+		if !strings.Contains(string(out), "http://"+addr) {
+			t.Errorf("startup log %q does not link http://%s", out, addr)
+		}
+	}
+}
+
+func TestStatusCodeCoercion(t *testing.T) {
+	cases := []struct {
+		path string
+		set  func(w *Writer)
+		want int
+	}{
+		{"/int", func(w *Writer) { w.WriteHeader(http.StatusTeapot) }, 418},
+		{"/max", func(w *Writer) { w.WriteHeader(999) }, 999},
+		{"/zero", func(w *Writer) { w.WriteHeader(0) }, 500},
+		{"/negative", func(w *Writer) { w.WriteHeader(-200) }, 500},
+		{"/short", func(w *Writer) { w.WriteHeader(42) }, 500},
+		{"/long", func(w *Writer) { w.WriteHeader(1000) }, 500},
+		{"/uint", func(w *Writer) { w.WriteHeaderUint(201) }, 201},
+		{"/uint-wrap", func(w *Writer) { w.WriteHeaderUint(1<<32 + 200) }, 500},
+		{"/uint-max", func(w *Writer) { w.WriteHeaderUint(math.MaxUint) }, 500},
+		{"/error", func(w *Writer) { Error(w, errors.New("gone"), http.StatusGone) }, 410},
+		{"/error-uint", func(w *Writer) { ErrorUint(w, errors.New("gone"), 410) }, 410},
+		{"/error-uint-wrap", func(w *Writer) { ErrorUint(w, errors.New("gone"), math.MaxUint-89) }, 500},
+	}
+
+	r := newTestRouter()
+	seen := make(map[string]int)
+	for _, c := range cases {
+		c := c
+		r.Get(c.path, func(req *Request, w *Writer) {
+			c.set(w)
+			seen[c.path] = w.Status()
+		})
+	}
+	addr := serveRouter(t, r)
+
+	for _, c := range cases {
+		resp := rawExchange(t, addr, "GET "+c.path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		if resp.StatusCode != c.want || seen[c.path] != c.want {
+			t.Errorf("%s: sent %d, Status() %d, want %d", c.path, resp.StatusCode, seen[c.path], c.want)
+		}
+	}
+}
+
+// bodyLimitRouter limits bodies to 10 bytes and serves POST /up and GET /ok
+func bodyLimitRouter(t *testing.T) string {
+	r := newTestRouter()
+	r.Update(func(d *Doc) {})
+	r.parserConfig.MaxBodyBytes = 10
+	r.Post("/up", func(req *Request, w *Writer) {
+		b, err := io.ReadAll(req.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.Write(b)
+	})
+	r.Get("/ok", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	return serveRouter(t, r)
+}
+
+func TestKeepAliveAfterBodyTooLarge(t *testing.T) {
+	addr := bodyLimitRouter(t)
+	body := strings.Repeat("x", 100)
+	chunked := "POST /up HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n64\r\n" + body + "\r\n0\r\n\r\n"
+
+	for name, first := range map[string]string{
+		"content-length": "POST /up HTTP/1.1\r\nHost: x\r\nContent-Length: 100\r\n\r\n" + body,
+		"chunked":        chunked,
+	} {
+		resps := rawExchange(t, addr, first+"GET /ok HTTP/1.1\r\nHost: x\r\n\r\n", 2)
+		if resps[0].StatusCode != http.StatusRequestEntityTooLarge || resps[0].Close {
+			t.Errorf("%s: first response = %d close=%v, want a 413 keeping the connection", name, resps[0].StatusCode, resps[0].Close)
+		}
+		if got := bodyString(t, resps[1]); resps[1].StatusCode != 200 || got != "ok" {
+			t.Errorf("%s: request after the 413 = %d %q, want 200 ok", name, resps[1].StatusCode, got)
+		}
+	}
+}
+
+func TestCloseAfterUndrainableRejection(t *testing.T) {
+	addr := bodyLimitRouter(t)
+
+	// A body too large to drain is not waited for
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(time.Second))
+	io.WriteString(c, "POST /up HTTP/1.1\r\nHost: x\r\nContent-Length: "+strconv.Itoa(maxDrainBytes+1)+"\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatalf("no response before the body was sent: %v", err)
+	}
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || !resp.Close {
+		t.Errorf("undrainable body = %d close=%v, want 413 with Connection: close", resp.StatusCode, resp.Close)
+	}
+
+	// Broken framing cannot be skipped
+	for name, raw := range map[string]string{
+		"bad chunk size":   "POST /up HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n",
+		"malformed header": "GET /ok HTTP/1.1\r\nHost: x\r\nBad Header\r\n\r\n",
+		"both lengths":     "POST /up HTTP/1.1\r\nHost: x\r\nContent-Length: 3\r\nTransfer-Encoding: chunked\r\n\r\n",
+	} {
+		resp := rawExchange(t, addr, raw, 1)[0]
+		if resp.StatusCode < 400 || !resp.Close {
+			t.Errorf("%s: %d close=%v, want an error with Connection: close", name, resp.StatusCode, resp.Close)
+		}
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	const writers, lines = 50, 20
+	late := make(chan error, 2)
+
+	r := newTestRouter()
+	handler := func(stream bool) Handler {
+		return func(req *Request, w *Writer) {
+			if stream {
+				w.SetHeader("x-mode", "stream")
+				if err := w.Flush(); err != nil {
+					t.Errorf("flush: %v", err)
+				}
+			}
+			var wg sync.WaitGroup
+			for g := 0; g < writers; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					w.SetHeader("x-writer", strconv.Itoa(g))
+					for i := 0; i < lines; i++ {
+						fmt.Fprintf(w, "g%02d-%02d\n", g, i)
+					}
+				}(g)
+			}
+			wg.Wait()
+			// A producer that outlives the handler must be refused
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				_, err := w.Write([]byte("late\n"))
+				late <- err
+			}()
+		}
+	}
+	r.Get("/buffered", handler(false))
+	r.Get("/stream", handler(true))
+	addr := serveRouter(t, r)
+
+	for _, path := range []string{"/buffered", "/stream"} {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		body := bodyString(t, resp)
+		if resp.StatusCode != 200 {
+			t.Errorf("%s: status %d, want 200", path, resp.StatusCode)
+		}
+		if want := writers * lines * len("g00-00\n"); len(body) != want {
+			t.Errorf("%s: body is %d bytes, want %d", path, len(body), want)
+		}
+		seen := make(map[string]bool)
+		for _, line := range strings.Split(strings.TrimSuffix(body, "\n"), "\n") {
+			if len(line) != len("g00-00") || seen[line] {
+				t.Errorf("%s: torn or repeated line %q", path, line)
+				break
+			}
+			seen[line] = true
+		}
+		if path == "/stream" && len(resp.TransferEncoding) == 0 {
+			t.Errorf("%s: streamed response is not chunked", path)
+		}
+		if err := <-late; !errors.Is(err, ErrResponseDone) {
+			t.Errorf("%s: write after the handler returned = %v, want ErrResponseDone", path, err)
+		}
+	}
+}
+
+func TestCloseAfterReply(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/bye", func(req *Request, w *Writer) {
+		w.CloseAfterReply()
+		w.Write([]byte("bye"))
+	})
+	r.Get("/stream", func(req *Request, w *Writer) {
+		w.Write([]byte("part"))
+		w.Flush()
+		w.CloseAfterReply()
+		w.Write([]byte("-rest"))
+	})
+	r.Get("/ok", func(req *Request, w *Writer) {
+		w.Write([]byte("ok"))
+	})
+	addr := serveRouter(t, r)
+
+	for _, path := range []string{"/bye", "/stream"} {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetDeadline(time.Now().Add(3 * time.Second))
+		br := bufio.NewReader(c)
+		io.WriteString(c, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n")
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		body := bodyString(t, resp)
+		if path == "/bye" && (!resp.Close || body != "bye") {
+			t.Errorf("%s: close=%v body %q, want Connection: close and bye", path, resp.Close, body)
+		}
+		if path == "/stream" && body != "part-rest" {
+			t.Errorf("%s: body %q, want the whole stream", path, body)
+		}
+
+		// The server closes instead of serving a second request
+		io.WriteString(c, "GET /ok HTTP/1.1\r\nHost: x\r\n\r\n")
+		if _, err := http.ReadResponse(br, nil); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("%s: second request got %v, want the connection closed", path, err)
+		}
+		c.Close()
+	}
+}
+
+func TestWantsKeepAlive(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) {
+		w.Write([]byte(strconv.FormatBool(req.WantsKeepAlive())))
+	})
+	addr := serveRouter(t, r)
+
+	cases := []struct {
+		version, connection string
+		want                string
+	}{
+		{"HTTP/1.1", "", "true"},
+		{"HTTP/1.1", "close", "false"},
+		{"HTTP/1.1", "Keep-Alive", "true"},
+		{"HTTP/1.0", "", "false"},
+		{"HTTP/1.0", "keep-alive", "true"},
+	}
+	for _, tc := range cases {
+		raw := "GET / " + tc.version + "\r\nHost: x\r\n"
+		if tc.connection != "" {
+			raw += "Connection: " + tc.connection + "\r\n"
+		}
+		resp := rawExchange(t, addr, raw+"\r\n", 1)[0]
+		if got := bodyString(t, resp); got != tc.want {
+			t.Errorf("%s with Connection %q: WantsKeepAlive = %s, want %s", tc.version, tc.connection, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package gouter
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rejectWriteTimeout bounds the 503 written to a connection over the limit,
+// which runs on the accept loop
+const rejectWriteTimeout = 100 * time.Millisecond
+
+// ConnectionCounts describes the connections of a Server
+type ConnectionCounts struct {
+	Active   int64 // Connections being served
+	Waiting  int64 // Accepted connections waiting for a slot
+	Rejected int64 // Connections answered 503 because no slot freed up
+}
+
+// Connections returns the connection counters of the server
+func (s *Server) Connections() ConnectionCounts {
+	return s.limiter.counts()
+}
+
+// connLimiter caps the connections served at once
+// The accept loop takes a slot before spawning the connection goroutine.
+// When the server is full it rejects the connection at once, or waits up to
+// the queue timeout for a slot without accepting, so new clients wait in
+// the listen backlog of the kernel meanwhile
+type connLimiter struct {
+	once  sync.Once
+	slots chan struct{} // nil when unlimited
+
+	active, waiting, rejected atomic.Int64
+}
+
+// configure sets the limit; only the first call has an effect, so Serve
+// calls on several listeners share one limit
+func (l *connLimiter) configure(max int) {
+	l.once.Do(func() {
+		if max > 0 {
+			l.slots = make(chan struct{}, max)
+		}
+	})
+}
+
+func (l *connLimiter) counts() ConnectionCounts {
+	return ConnectionCounts{
+		Active:   l.active.Load(),
+		Waiting:  l.waiting.Load(),
+		Rejected: l.rejected.Load(),
+	}
+}
+
+// acquire takes a slot for a new connection, waiting up to timeout for one
+// Returns false when none freed up in time
+func (l *connLimiter) acquire(timeout time.Duration) bool {
+	if l.slots == nil {
+		l.active.Add(1)
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		l.active.Add(1)
+		return true
+	default:
+	}
+	if timeout <= 0 {
+		l.rejected.Add(1)
+		return false
+	}
+
+	l.waiting.Add(1)
+	defer l.waiting.Add(-1)
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		l.active.Add(1)
+		return true
+	case <-t.C:
+		l.rejected.Add(1)
+		return false
+	}
+}
+
+// release frees the slot of a closed connection
+func (l *connLimiter) release() {
+	l.active.Add(-1)
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// rejectConn answers 503 to a connection over the limit and closes it
+// TLS connections are closed without an answer: it would need a handshake
+func rejectConn(c net.Conn) {
+	defer c.Close()
+	if _, ok := c.(*tls.Conn); ok {
+		return
+	}
+
+	c.SetWriteDeadline(time.Now().Add(rejectWriteTimeout))
+	w := newWriter(c)
+	w.SetHeader("Retry-After", "1")
+	w.SetHeader("Connection", "close")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	w.write()
+}
@@ -0,0 +1,145 @@
+package gouter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// limitServer serves /hold, which blocks until release is closed, and /ok
+func limitServer(t *testing.T, max int, queue time.Duration) (*Server, string, chan struct{}) {
+	release := make(chan struct{})
+	r := newTestRouter()
+	r.Get("/hold", func(req *Request, w *Writer) { <-release })
+	r.Get("/ok", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	s := &Server{Router: r, MaxConcurrentConnections: max, ConnectionQueueTimeout: queue}
+	return s, serveTest(t, s), release
+}
+
+// holdConn opens a connection busy with /hold until it is released
+func holdConn(t *testing.T, addr string) net.Conn {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	io.WriteString(c, "GET /hold HTTP/1.1\r\nHost: x\r\n\r\n")
+	return c
+}
+
+// readAnswer reads the response to a request already written on c
+func readAnswer(t *testing.T, c net.Conn) *http.Response {
+	t.Helper()
+	c.SetReadDeadline(time.Now().Add(3 * time.Second))
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestConnectionLimitRejects(t *testing.T) {
+	s, addr, release := limitServer(t, 2, 0)
+	held := []net.Conn{holdConn(t, addr), holdConn(t, addr)}
+	eventually(t, "two active connections", func() bool { return s.Connections().Active == 2 })
+
+	// Over the limit without a queue: 503 at once, and the connection closes
+	over := holdConn(t, addr)
+	resp := readAnswer(t, over)
+	if resp.StatusCode != 503 || resp.Header.Get("Retry-After") != "1" || !resp.Close {
+		t.Errorf("over the limit = %d, Retry-After %q, close %v", resp.StatusCode, resp.Header.Get("Retry-After"), resp.Close)
+	}
+	if got := s.Connections(); got != (ConnectionCounts{Active: 2, Rejected: 1}) {
+		t.Errorf("counts = %+v, want 2 active and 1 rejected", got)
+	}
+
+	// Closing a connection frees its slot
+	close(release)
+	for _, c := range held {
+		readAnswer(t, c)
+		c.Close()
+	}
+	eventually(t, "the slots to free", func() bool { return s.Connections().Active == 0 })
+	if got := bodyString(t, rawExchange(t, addr, "GET /ok HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]); got != "ok" {
+		t.Errorf("after the slots freed = %q", got)
+	}
+}
+
+func TestConnectionLimitQueues(t *testing.T) {
+	s, addr, release := limitServer(t, 1, 2*time.Second)
+	first := holdConn(t, addr)
+	eventually(t, "the first connection", func() bool { return s.Connections().Active == 1 })
+
+	// The next connection waits for the slot instead of being refused
+	second := holdConn(t, addr)
+	eventually(t, "a waiting connection", func() bool { return s.Connections().Waiting == 1 })
+	close(release)
+	readAnswer(t, first)
+	first.Close()
+	if resp := readAnswer(t, second); resp.StatusCode != 200 {
+		t.Errorf("queued connection = %d, want it served once the slot freed", resp.StatusCode)
+	}
+	if got := s.Connections(); got.Waiting != 0 || got.Rejected != 0 {
+		t.Errorf("counts = %+v, want nothing waiting or rejected", got)
+	}
+}
+
+func TestConnectionLimitQueueIsOneDeep(t *testing.T) {
+	s, addr, release := limitServer(t, 1, 2*time.Second)
+	first := holdConn(t, addr)
+	eventually(t, "the first connection", func() bool { return s.Connections().Active == 1 })
+
+	// The accept loop waits on the second connection, so the others stay
+	// in the kernel backlog instead of being counted as waiting
+	second := holdConn(t, addr)
+	eventually(t, "a waiting connection", func() bool { return s.Connections().Waiting == 1 })
+	third, fourth := holdConn(t, addr), holdConn(t, addr)
+	time.Sleep(50 * time.Millisecond)
+	if got := s.Connections(); got.Waiting != 1 || got.Active != 1 {
+		t.Errorf("counts = %+v, want one connection waiting", got)
+	}
+
+	// Each freed slot goes to the next connection in turn
+	close(release)
+	for _, c := range []net.Conn{first, second, third, fourth} {
+		if resp := readAnswer(t, c); resp.StatusCode != 200 {
+			t.Errorf("queued connection = %d", resp.StatusCode)
+		}
+		c.Close()
+	}
+}
+
+func TestConnectionLimitQueueTimeout(t *testing.T) {
+	s, addr, release := limitServer(t, 1, 50*time.Millisecond)
+	defer close(release)
+	holdConn(t, addr)
+	eventually(t, "the first connection", func() bool { return s.Connections().Active == 1 })
+
+	start := time.Now()
+	resp := readAnswer(t, holdConn(t, addr))
+	if resp.StatusCode != 503 {
+		t.Errorf("after the queue timeout = %d, want 503", resp.StatusCode)
+	}
+	if waited := time.Since(start); waited < 50*time.Millisecond {
+		t.Errorf("rejected after %v, before the queue timeout", waited)
+	}
+	if got := s.Connections(); got.Rejected != 1 || got.Waiting != 0 {
+		t.Errorf("counts = %+v, want 1 rejected", got)
+	}
+}
+
+func TestConnectionCountsUnlimited(t *testing.T) {
+	s, addr, release := limitServer(t, 0, 0)
+	for i := 0; i < 5; i++ {
+		holdConn(t, addr)
+	}
+	eventually(t, "five active connections", func() bool { return s.Connections().Active == 5 })
+	close(release)
+	if got := s.Connections(); got.Rejected != 0 {
+		t.Errorf("counts = %+v, want no rejection without a limit", got)
+	}
+}
@@ -0,0 +1,113 @@
+package gouter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrClientGone is the cause of a request context canceled because the
+// client disconnected
+var ErrClientGone = errors.New("client disconnected")
+
+// ErrRequestTimeout is the cause of a request context whose connection
+// write deadline passed
+var ErrRequestTimeout = errors.New("request timed out")
+
+// connContext is the context of a request served on a connection
+// The disconnect watch costs a goroutine reading the connection, so it only
+// starts once the context is asked for
+type connContext struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	w      *Writer
+	once   sync.Once // Starts the disconnect watch
+}
+
+// newConnContext creates the context of a request answered by w
+// A positive writeTimeout sets its deadline to the write deadline of the
+// connection, after which the response can no longer be sent
+func newConnContext(w *Writer, start time.Time, writeTimeout time.Duration) *connContext {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	if writeTimeout > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithDeadlineCause(ctx, start.Add(writeTimeout), ErrRequestTimeout)
+		parent := cancel
+		cancel = func(cause error) {
+			parent(cause)
+			stop()
+		}
+	}
+	return &connContext{ctx: ctx, cancel: cancel, w: w}
+}
+
+// context returns the context, watching the connection from the first call
+func (c *connContext) context() context.Context {
+	c.once.Do(func() {
+		gone := c.w.CloseNotify()
+		done := c.ctx.Done()
+		spawn("context", func() {
+			select {
+			case <-gone:
+				c.cancel(ErrClientGone)
+			case <-done:
+			}
+		})
+	})
+	return c.ctx
+}
+
+// Context returns the context of the request
+// It is canceled when the client disconnects (cause ErrClientGone), when
+// the Server.WriteTimeout of the response passes (cause ErrRequestTimeout),
+// and once the handler returns. Pass it to database and outgoing calls so
+// they stop when nobody waits for the answer; context.Cause tells why.
+// Disconnects are noticed once the request body has been read
+func (r *Request) Context() context.Context {
+	r.pool.checkReleased("Request", 1)
+	switch {
+	case r.ctx != nil:
+		return r.ctx
+	case r.conn != nil:
+		return r.conn.context()
+	}
+	return context.Background()
+}
+
+// WithContext returns a shallow copy of the request using ctx as its
+// context, e.g. to add values or a shorter deadline for the next handler
+// The copy shares the headers, parameters, values and body of r
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.pool.checkReleased("Request", 1)
+	if ctx == nil {
+		panic("gouter: nil context")
+	}
+	// Field by field: the pool state must not be copied
+	return &Request{
+		Method:         r.Method,
+		path:           r.path,
+		rawQuery:       r.rawQuery,
+		basePath:       r.basePath,
+		Headers:        r.Headers,
+		Version:        r.Version,
+		Body:           r.Body,
+		Params:         r.Params,
+		RemoteAddrs:    r.RemoteAddrs,
+		tempFiles:      r.tempFiles,
+		route:          r.route,
+		trace:          r.trace,
+		values:         r.values,
+		budget:         r.budget,
+		inspector:      r.inspector,
+		debugCapture:   r.debugCapture,
+		clk:            r.clk,
+		ids:            r.ids,
+		headerBytes:    r.headerBytes,
+		ctx:            ctx,
+		conn:           r.conn,
+		form:           r.form,
+		postForm:       r.postForm,
+		originalMethod: r.originalMethod,
+	}
+}
@@ -0,0 +1,191 @@
+package gouter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+func TestRequestContextLifetime(t *testing.T) {
+	type outcome struct {
+		cause       error
+		hasDeadline bool
+		elapsed     time.Duration
+	}
+	results := make(chan outcome, 1)
+	kept := make(chan context.Context, 1)
+
+	r := newTestRouter()
+	r.Get("/slow", func(req *Request, w *Writer) {
+		start := time.Now()
+		ctx := req.Context()
+		_, ok := ctx.Deadline()
+		select {
+		case <-ctx.Done():
+			results <- outcome{context.Cause(ctx), ok, time.Since(start)}
+		case <-time.After(2 * time.Second):
+			results <- outcome{nil, ok, time.Since(start)}
+		}
+	})
+	r.Get("/quick", func(req *Request, w *Writer) {
+		kept <- req.Context()
+	})
+	addr := serveTest(t, &Server{Router: r, WriteTimeout: 150 * time.Millisecond})
+
+	// The write deadline of the response ends the context
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "GET /slow HTTP/1.1\r\nHost: x\r\n\r\n")
+	got := <-results
+	if !errors.Is(got.cause, ErrRequestTimeout) || !got.hasDeadline {
+		t.Errorf("slow handler = %+v, want ErrRequestTimeout with a deadline", got)
+	}
+	if got.elapsed > time.Second {
+		t.Errorf("context ended after %v, WriteTimeout is 150ms", got.elapsed)
+	}
+
+	// Contexts kept past the handler are canceled when it returns
+	rawExchange(t, addr, "GET /quick HTTP/1.1\r\nHost: x\r\n\r\n", 1)
+	ctx := <-kept
+	eventually(t, "the context canceled after the handler", func() bool { return ctx.Err() != nil })
+	if cause := context.Cause(ctx); !errors.Is(cause, context.Canceled) {
+		t.Errorf("cause after the handler = %v, want context.Canceled", cause)
+	}
+}
+
+func TestWithContext(t *testing.T) {
+	type outcome struct {
+		value    any
+		cause    error
+		body     string
+		param    string
+		header   string
+		same     bool
+		deadline time.Duration
+	}
+	results := make(chan outcome, 1)
+
+	r := newTestRouter()
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			ctx, cancel := context.WithTimeoutCause(context.WithValue(req.Context(), ctxKey{}, "tenant-7"), 50*time.Millisecond, errors.New("budget spent"))
+			defer cancel()
+			next(req.WithContext(ctx), w)
+		}
+	})
+	r.Post("/items/:id", func(req *Request, w *Writer) {
+		ctx := req.Context()
+		<-ctx.Done()
+		body, _ := io.ReadAll(req.Body)
+		d, _ := ctx.Deadline()
+		results <- outcome{ctx.Value(ctxKey{}), context.Cause(ctx), string(body), req.Params.Get("id"),
+			req.Headers.Get("X-Trace"), req.Context() == ctx, time.Until(d)}
+		w.Write([]byte("ok"))
+	})
+	addr := serveRouter(t, r)
+
+	resp := rawExchange(t, addr, "POST /items/42 HTTP/1.1\r\nHost: x\r\nX-Trace: abc\r\nContent-Length: 4\r\n\r\nping", 1)[0]
+	if body := bodyString(t, resp); resp.StatusCode != http.StatusOK || body != "ok" {
+		t.Fatalf("response = %d %q", resp.StatusCode, body)
+	}
+	got := <-results
+	if got.value != "tenant-7" || got.cause == nil || got.cause.Error() != "budget spent" || !got.same {
+		t.Errorf("derived context = %+v, want its value, cause and identity", got)
+	}
+	if got.body != "ping" || got.param != "42" || got.header != "abc" {
+		t.Errorf("copy lost request data: %+v", got)
+	}
+}
+
+func TestWithContextCopiesFields(t *testing.T) {
+	// Every field but these must reach the copy; a new Request field
+	// fails here until WithContext copies it or it is listed
+	skipped := map[string]bool{
+		"ctx":  true, // Replaced
+		"wire": true, // Drained by the connection through the original
+		"pool": true, // Pool state of the original
+	}
+
+	orig := &Request{}
+	v := reflect.ValueOf(orig).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		name := v.Type().Field(i).Name
+		if skipped[name] {
+			continue
+		}
+		// Unexported fields are set through an addressable alias
+		f = reflect.NewAt(f.Type(), f.Addr().UnsafePointer()).Elem()
+		switch f.Kind() {
+		case reflect.String:
+			f.SetString(name)
+		case reflect.Int, reflect.Int64:
+			f.SetInt(int64(i + 1))
+		case reflect.Pointer, reflect.Map, reflect.Slice:
+			f.Set(reflect.New(f.Type()).Elem())
+			if f.Kind() == reflect.Pointer {
+				f.Set(reflect.New(f.Type().Elem()))
+			} else if f.Kind() == reflect.Map {
+				f.Set(reflect.MakeMap(f.Type()))
+			} else {
+				f.Set(reflect.MakeSlice(f.Type(), 1, 1))
+			}
+		case reflect.Func:
+			f.Set(reflect.MakeFunc(f.Type(), func([]reflect.Value) []reflect.Value { return nil }))
+		case reflect.Interface:
+			switch f.Type() {
+			case reflect.TypeOf((*io.Reader)(nil)).Elem():
+				f.Set(reflect.ValueOf(strings.NewReader(name)))
+			case reflect.TypeOf((*Clock)(nil)).Elem():
+				f.Set(reflect.ValueOf(SystemClock))
+			case reflect.TypeOf((*IDGenerator)(nil)).Elem():
+				f.Set(reflect.ValueOf(RandomIDs))
+			default:
+				t.Fatalf("field %s of type %s has no test value", name, f.Type())
+			}
+		default:
+			t.Fatalf("field %s of kind %s has no test value", name, f.Kind())
+		}
+	}
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, 1)
+	c := reflect.ValueOf(orig.WithContext(ctx)).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if skipped[name] {
+			continue
+		}
+		a := reflect.NewAt(v.Field(i).Type(), v.Field(i).Addr().UnsafePointer()).Elem()
+		b := reflect.NewAt(c.Field(i).Type(), c.Field(i).Addr().UnsafePointer()).Elem()
+		same := a.Kind() == reflect.Func && a.Pointer() == b.Pointer() ||
+			a.Kind() != reflect.Func && reflect.DeepEqual(a.Interface(), b.Interface())
+		if !same {
+			t.Errorf("WithContext does not copy %s", name)
+		}
+	}
+}
+
+func TestRequestContextOutsideServer(t *testing.T) {
+	req := newRequest()
+	if ctx := req.Context(); ctx != context.Background() {
+		t.Errorf("Context outside a server = %v, want Background", ctx)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("WithContext(nil) did not panic")
+		}
+	}()
+	req.WithContext(nil)
+}
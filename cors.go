@@ -0,0 +1,159 @@
+package gouter
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig configures the CORS middleware
+type CORSConfig struct {
+	AllowedOrigins        []string      // Allowed origins, "*" allows any origin
+	AllowedMethods        []string      // Methods allowed in preflight (default: those of the route, or GET, POST, PUT, PATCH, DELETE)
+	AllowedHeaders        []string      // Request headers allowed in preflight
+	AllowRequestedHeaders bool          // Reflect Access-Control-Request-Headers instead of AllowedHeaders
+	ExposeHeaders         []string      // Response headers readable by the browser
+	MaxAge                time.Duration // How long browsers may cache a preflight result
+	AllowCredentials      bool          // Allow cookies and authorization headers
+	AllowPrivateNetwork   bool          // Answer Private Network Access preflights
+}
+
+// defaultCORSMethods are allowed when AllowedMethods is empty
+var defaultCORSMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// SetCORS layers a route-level CORS policy over the global CORS middleware
+// A non-empty AllowedOrigins narrows the global list: origins must be allowed
+// by both. Other non-zero fields replace the global values for this route
+func (r *RouteInfo) SetCORS(cfg CORSConfig) *RouteInfo {
+	r.cors = &cfg
+	return r
+}
+
+// CORS creates a middleware applying the given cross-origin policy
+// Preflight requests (OPTIONS with Access-Control-Request-Method) are answered
+// directly with 204 and never reach the route handler
+func CORS(cfg CORSConfig) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			origin := r.Headers.Get("Origin")
+			if origin == "" {
+				next(r, w)
+				return
+			}
+
+			policy := cfg
+			var routePolicy *CORSConfig
+			if r.route != nil && r.route.cors != nil {
+				routePolicy = r.route.cors
+				policy = mergeCORS(cfg, *routePolicy)
+			}
+
+			allowed := originAllowed(cfg.AllowedOrigins, origin)
+			if routePolicy != nil && len(routePolicy.AllowedOrigins) > 0 {
+				allowed = allowed && originAllowed(routePolicy.AllowedOrigins, origin)
+			}
+
+			preflight := r.Method == "OPTIONS" && r.Headers.Get("Access-Control-Request-Method") != ""
+
+			w.Headers.Add("Vary", "Origin")
+			if !allowed {
+				if preflight {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next(r, w)
+				return
+			}
+
+			// A wildcard cannot be combined with credentials
+			if policy.AllowCredentials || !originAllowed(policy.AllowedOrigins, "*") {
+				w.Headers.Add("Access-Control-Allow-Origin", origin)
+			} else {
+				w.Headers.Add("Access-Control-Allow-Origin", "*")
+			}
+
+			if policy.AllowCredentials {
+				w.Headers.Add("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if len(policy.ExposeHeaders) > 0 {
+					w.Headers.Add("Access-Control-Expose-Headers", strings.Join(policy.ExposeHeaders, ", "))
+				}
+				next(r, w)
+				return
+			}
+
+			// Routes registered with methods announce exactly those
+			methods := policy.AllowedMethods
+			if len(methods) == 0 {
+				methods = defaultCORSMethods
+				if allowed, ok := r.Value(allowedMethodsKey).([]string); ok {
+					methods = allowed
+				}
+			}
+			w.Headers.Add("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+			if policy.AllowRequestedHeaders {
+				// The answer depends on the request headers even when none were asked for
+				w.Headers.Add("Vary", "Access-Control-Request-Headers")
+				if requested := r.Headers.Get("Access-Control-Request-Headers"); requested != "" {
+					w.Headers.Add("Access-Control-Allow-Headers", requested)
+				}
+			} else if len(policy.AllowedHeaders) > 0 {
+				w.Headers.Add("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+			}
+
+			if policy.MaxAge > 0 {
+				w.Headers.Add("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+			}
+
+			if policy.AllowPrivateNetwork && strings.EqualFold(r.Headers.Get("Access-Control-Request-Private-Network"), "true") {
+				w.Headers.Add("Access-Control-Allow-Private-Network", "true")
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}
+}
+
+// mergeCORS overlays the non-zero fields of a route policy on the global one
+func mergeCORS(global, route CORSConfig) CORSConfig {
+	merged := global
+	if len(route.AllowedMethods) > 0 {
+		merged.AllowedMethods = route.AllowedMethods
+	}
+	if len(route.AllowedHeaders) > 0 {
+		merged.AllowedHeaders = route.AllowedHeaders
+	}
+	if route.AllowRequestedHeaders {
+		merged.AllowRequestedHeaders = true
+	}
+	if len(route.ExposeHeaders) > 0 {
+		merged.ExposeHeaders = route.ExposeHeaders
+	}
+	if route.MaxAge > 0 {
+		merged.MaxAge = route.MaxAge
+	}
+	if route.AllowCredentials {
+		merged.AllowCredentials = true
+	}
+	if route.AllowPrivateNetwork {
+		merged.AllowPrivateNetwork = true
+	}
+	if len(route.AllowedOrigins) > 0 {
+		merged.AllowedOrigins = route.AllowedOrigins
+	}
+	return merged
+}
+
+// originAllowed reports whether origin matches an entry of the allow list
+func originAllowed(allowed []string, origin string) bool {
+	for _, o := range allowed {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package gouter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// preflight sends an OPTIONS preflight for path from origin with extra
+// request headers
+func preflight(t *testing.T, addr, path, origin string, headers ...string) *http.Response {
+	t.Helper()
+	raw := "OPTIONS " + path + " HTTP/1.1\r\nHost: x\r\nOrigin: " + origin + "\r\nAccess-Control-Request-Method: GET\r\n"
+	for _, h := range headers {
+		raw += h + "\r\n"
+	}
+	return rawExchange(t, addr, raw+"\r\n", 1)[0]
+}
+
+func TestCORSPrivateNetworkAndMaxAge(t *testing.T) {
+	r := newTestRouter()
+	r.Use(CORS(CORSConfig{
+		AllowedOrigins:      []string{"https://app.example"},
+		MaxAge:              10 * time.Minute,
+		AllowPrivateNetwork: true,
+	}))
+	r.Get("/data", func(req *Request, w *Writer) {
+		t.Error("preflight reached the handler")
+	})
+	addr := serveRouter(t, r)
+
+	resp := preflight(t, addr, "/data", "https://app.example", "Access-Control-Request-Private-Network: true")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("preflight status = %d, want 204", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Private-Network"); got != "true" {
+		t.Errorf("Access-Control-Allow-Private-Network = %q, want true", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Methods"); got != "GET, HEAD" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want the methods of the route", got)
+	}
+
+	resp = preflight(t, addr, "/data", "https://app.example")
+	if got := resp.Header.Get("Access-Control-Allow-Private-Network"); got != "" {
+		t.Errorf("PNA header sent without a PNA preflight: %q", got)
+	}
+
+	resp = preflight(t, addr, "/data", "https://evil.example", "Access-Control-Request-Private-Network: true")
+	if resp.Header.Get("Access-Control-Allow-Origin") != "" || resp.Header.Get("Access-Control-Allow-Private-Network") != "" {
+		t.Errorf("disallowed origin got CORS headers: %v", resp.Header)
+	}
+}
+
+func TestCORSPrivateNetworkOffByDefault(t *testing.T) {
+	r := newTestRouter()
+	r.Use(CORS(CORSConfig{AllowedOrigins: []string{"*"}}))
+	r.Get("/data", func(req *Request, w *Writer) {})
+	addr := serveRouter(t, r)
+
+	resp := preflight(t, addr, "/data", "https://app.example", "Access-Control-Request-Private-Network: true")
+	if got := resp.Header.Get("Access-Control-Allow-Private-Network"); got != "" {
+		t.Errorf("Access-Control-Allow-Private-Network = %q without AllowPrivateNetwork", got)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Access-Control-Max-Age = %q without MaxAge", got)
+	}
+}
+
+func TestCORSAllowRequestedHeaders(t *testing.T) {
+	r := newTestRouter()
+	r.Use(CORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowRequestedHeaders: true}))
+	r.Get("/data", func(req *Request, w *Writer) {})
+	addr := serveRouter(t, r)
+
+	resp := preflight(t, addr, "/data", "https://app.example", "Access-Control-Request-Headers: X-Trace, Content-Type")
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); got != "X-Trace, Content-Type" {
+		t.Errorf("Access-Control-Allow-Headers = %q, want the requested headers", got)
+	}
+	if got := resp.Header.Get("Vary"); got != "Origin, Access-Control-Request-Headers" {
+		t.Errorf("Vary = %q", got)
+	}
+
+	resp = preflight(t, addr, "/data", "https://app.example")
+	if got := resp.Header.Get("Access-Control-Allow-Headers"); got != "" {
+		t.Errorf("Access-Control-Allow-Headers = %q without requested headers", got)
+	}
+	if got := resp.Header.Get("Vary"); got != "Origin, Access-Control-Request-Headers" {
+		t.Errorf("Vary = %q, want it to name the request headers either way", got)
+	}
+}
+
+func TestCORSRouteOverride(t *testing.T) {
+	r := newTestRouter()
+	r.Use(CORS(CORSConfig{
+		AllowedOrigins: []string{"https://a.example", "https://b.example"},
+		MaxAge:         time.Minute,
+	}))
+	noop := func(req *Request, w *Writer) {}
+	r.Get("/open", noop)
+	r.Get("/narrow", noop).SetCORS(CORSConfig{
+		AllowedOrigins:      []string{"https://b.example", "https://c.example"},
+		MaxAge:              time.Hour,
+		AllowPrivateNetwork: true,
+	})
+	addr := serveRouter(t, r)
+
+	allowed := func(path, origin string) *http.Response {
+		resp := preflight(t, addr, path, origin, "Access-Control-Request-Private-Network: true")
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" && got != origin {
+			t.Errorf("%s from %s: Access-Control-Allow-Origin = %q", path, origin, got)
+		}
+		return resp
+	}
+
+	if resp := allowed("/open", "https://a.example"); resp.Header.Get("Access-Control-Allow-Origin") == "" ||
+		resp.Header.Get("Access-Control-Max-Age") != "60" || resp.Header.Get("Access-Control-Allow-Private-Network") != "" {
+		t.Errorf("/open uses the global policy: %v", resp.Header)
+	}
+	// The route narrows the global list: only origins both allow pass
+	if resp := allowed("/narrow", "https://a.example"); resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Error("/narrow allowed an origin missing from the route list")
+	}
+	if resp := allowed("/narrow", "https://c.example"); resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Error("/narrow allowed an origin missing from the global list")
+	}
+	resp := allowed("/narrow", "https://b.example")
+	if resp.Header.Get("Access-Control-Allow-Origin") != "https://b.example" {
+		t.Errorf("/narrow rejected an origin both lists allow: %v", resp.Header)
+	}
+	if got := resp.Header.Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("/narrow Access-Control-Max-Age = %q, want the route value 3600", got)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Private-Network"); got != "true" {
+		t.Errorf("/narrow Access-Control-Allow-Private-Network = %q, want the route setting", got)
+	}
+}
+
+func TestCORSPolicy(t *testing.T) {
+	r := newTestRouter()
+	r.Group("/open", func(g *Group) {
+		g.Use(CORS(CORSConfig{
+			AllowedOrigins: []string{"*"},
+			AllowedHeaders: []string{"Content-Type", "X-Trace"},
+			ExposeHeaders:  []string{"X-Total", "ETag"},
+		}))
+		g.Get("/items", func(req *Request, w *Writer) { w.Write([]byte("items")) })
+		g.Post("/items", func(req *Request, w *Writer) {})
+		g.Route("/any", func(req *Request, w *Writer) {})
+	})
+	r.Group("/private", func(g *Group) {
+		g.Use(CORS(CORSConfig{
+			AllowedOrigins:   []string{"https://app.example"},
+			AllowedMethods:   []string{"GET", "DELETE"},
+			AllowCredentials: true,
+		}))
+		g.Get("/items", func(req *Request, w *Writer) { w.Write([]byte("secret")) })
+	})
+	addr := serveRouter(t, r)
+
+	cases := []struct {
+		path, origin              string
+		allowOrigin, methods, hdr string
+		credentials               bool
+	}{
+		// Methods registered for the path, including the implicit HEAD
+		{"/open/items", "https://x.example", "*", "GET, HEAD, POST", "Content-Type, X-Trace", false},
+		// A route serving any method falls back to the default list
+		{"/open/any", "https://x.example", "*", "GET, POST, PUT, PATCH, DELETE", "Content-Type, X-Trace", false},
+		// Credentials echo the origin: browsers refuse "*" with them
+		{"/private/items", "https://app.example", "https://app.example", "GET, DELETE", "", true},
+		{"/private/items", "https://evil.example", "", "", "", false},
+	}
+	for _, c := range cases {
+		resp := preflight(t, addr, c.path, c.origin)
+		h := resp.Header
+		if resp.StatusCode != http.StatusNoContent || h.Get("Vary") != "Origin" {
+			t.Errorf("%s from %s: %d, Vary %q", c.path, c.origin, resp.StatusCode, h.Get("Vary"))
+		}
+		if h.Get("Access-Control-Allow-Origin") != c.allowOrigin || h.Get("Access-Control-Allow-Methods") != c.methods ||
+			h.Get("Access-Control-Allow-Headers") != c.hdr || (h.Get("Access-Control-Allow-Credentials") == "true") != c.credentials {
+			t.Errorf("%s from %s: headers %v", c.path, c.origin, h)
+		}
+	}
+
+	// Actual requests reach the handler; exposed headers are announced on them
+	get := func(path, origin string) *http.Response {
+		raw := "GET " + path + " HTTP/1.1\r\nHost: x\r\n"
+		if origin != "" {
+			raw += "Origin: " + origin + "\r\n"
+		}
+		return rawExchange(t, addr, raw+"\r\n", 1)[0]
+	}
+	resp := get("/open/items", "https://x.example")
+	if bodyString(t, resp) != "items" || resp.Header.Get("Access-Control-Expose-Headers") != "X-Total, ETag" ||
+		resp.Header.Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("simple request headers = %v", resp.Header)
+	}
+	// Same-origin requests carry no Origin and get no CORS headers
+	resp = get("/open/items", "")
+	if bodyString(t, resp) != "items" || resp.Header.Get("Vary") != "" || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("request without Origin got %v", resp.Header)
+	}
+	// A refused origin is still served, the browser hides the response
+	resp = get("/private/items", "https://evil.example")
+	if bodyString(t, resp) != "secret" || resp.Header.Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("refused origin got %v", resp.Header)
+	}
+}
@@ -0,0 +1,97 @@
+package gouter
+
+import (
+	"html/template"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestCSPNonceMatchesRenderedHTML(t *testing.T) {
+	page := template.Must(template.New("page").Parse(`<script nonce="{{.CSPNonce}}"></script><h1>{{.Title}}</h1>`))
+	type pageData struct {
+		Title    string
+		CSPNonce string
+	}
+
+	r := newTestRouter()
+	r.Use(CSPNonce(""))
+	r.Get("/map", func(req *Request, w *Writer) {
+		w.Render(page, map[string]any{"Title": "map"})
+	})
+	r.Get("/struct", func(req *Request, w *Writer) {
+		w.Render(page, pageData{Title: "struct"})
+	})
+	r.Get("/pointer", func(req *Request, w *Writer) {
+		w.Render(page, &pageData{Title: "pointer"})
+	})
+	r.Get("/nil", func(req *Request, w *Writer) {
+		w.Render(template.Must(template.New("nil").Parse(`{{.CSPNonce}}|`+req.CSPNonce())), nil)
+	})
+	addr := serveRouter(t, r)
+
+	headerNonce := regexp.MustCompile(`script-src 'self' 'nonce-([A-Za-z0-9_-]+)'`)
+	seen := make(map[string]bool)
+	for _, path := range []string{"/map", "/struct", "/pointer", "/nil", "/map"} {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		body := bodyString(t, resp)
+		policy := resp.Header.Get("Content-Security-Policy")
+		m := headerNonce.FindStringSubmatch(policy)
+		if m == nil {
+			t.Fatalf("%s: policy %q has no script nonce", path, policy)
+		}
+		nonce := m[1]
+		if len(nonce) != 22 {
+			t.Errorf("%s: nonce %q is not 128 bits of base64url", path, nonce)
+		}
+		if !strings.Contains(policy, "style-src 'self' 'nonce-"+nonce+"'") {
+			t.Errorf("%s: policy %q uses another nonce for styles", path, policy)
+		}
+		want := `<script nonce="` + nonce + `"></script><h1>` + strings.TrimPrefix(path, "/") + `</h1>`
+		if path == "/nil" {
+			want = nonce + "|" + nonce
+		}
+		if body != want {
+			t.Errorf("%s: body %q, want %q", path, body, want)
+		}
+		if seen[nonce] {
+			t.Errorf("%s: nonce %q reused", path, nonce)
+		}
+		seen[nonce] = true
+		if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+			t.Errorf("%s: Content-Type = %q", path, ct)
+		}
+	}
+}
+
+func TestCSPHeader(t *testing.T) {
+	cases := []struct{ policy, want string }{
+		{"default-src 'self'; script-src 'nonce-{nonce}'; style-src 'nonce-{nonce}'", "default-src 'self'; script-src 'nonce-N'; style-src 'nonce-N'"},
+		{"default-src 'self'; script-src 'self'", "default-src 'self'; script-src 'self' 'nonce-N'"},
+		{"Script-Src 'self'; img-src *", "Script-Src 'self' 'nonce-N'; img-src *"},
+		{"default-src 'self';", "default-src 'self'; script-src 'nonce-N'"},
+	}
+	for _, c := range cases {
+		if got := cspHeader(c.policy, "N"); got != c.want {
+			t.Errorf("cspHeader(%q) = %q, want %q", c.policy, got, c.want)
+		}
+	}
+}
+
+func TestRenderWithoutNonce(t *testing.T) {
+	type data struct{ CSPNonce string }
+	tmpl := template.Must(template.New("t").Parse(`[{{.CSPNonce}}]`))
+	w := &Writer{Headers: make(Headers)}
+	if err := w.Render(tmpl, data{CSPNonce: "given"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := string(w.body); got != "[given]" {
+		t.Errorf("body = %q, want the data unchanged without the middleware", got)
+	}
+
+	failing := template.Must(template.New("f").Parse(`{{.Missing.Field}}`))
+	w = &Writer{Headers: make(Headers)}
+	if err := w.Render(failing, data{}); err == nil || len(w.body) != 0 {
+		t.Errorf("failing template = %v with %q written, want an error and no output", err, w.body)
+	}
+}
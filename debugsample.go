@@ -0,0 +1,125 @@
+package gouter
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// debugSampleBodyBytes is the captured size of each body in a debug dump
+const debugSampleBodyBytes = 2048
+
+// sampleState drives the sampling generator; each call advances it once
+var sampleState atomic.Uint64
+
+func init() {
+	sampleState.Store(uint64(time.Now().UnixNano()))
+}
+
+// DebugSample dumps a fraction of the requests of the route to the log
+// Args:
+//   - rate: Fraction of requests dumped, from 0 (none) to 1 (all)
+//
+// Sampled requests are logged at Debug level with their request ID,
+// headers and the first bytes of both bodies; the values of the headers in
+// DefaultRedactedHeaders are hidden. Steps of traced requests are included
+func (r *RouteInfo) DebugSample(rate float64) *RouteInfo {
+	r.debugRate = max(0, min(rate, 1))
+	return r
+}
+
+// sampled reports whether a request falls in the sampled fraction
+// A Weyl sequence mixed by xorshift keeps the decision cheap and lock-free
+func sampled(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if rate >= 1 {
+		return true
+	}
+
+	x := sampleState.Add(0x9e3779b97f4a7c15)
+	x ^= x << 13
+	x ^= x >> 7
+	x ^= x << 17
+	return float64(x>>11)/(1<<53) < rate
+}
+
+// startDebugSample decides whether a routed request is dumped, capturing
+// its body as the handler reads it
+func startDebugSample(req *Request) {
+	if req.route == nil || !sampled(req.route.debugRate) {
+		return
+	}
+
+	capture := &captureReader{r: http.NoBody, max: debugSampleBodyBytes}
+	if req.Body != nil && req.Body != http.NoBody {
+		capture.r = req.Body
+		req.Body = capture
+	}
+	req.debugCapture = capture
+}
+
+// dumpDebugSample logs a sampled request and its response
+func dumpDebugSample(req *Request, w *Writer, status int, elapsed time.Duration) {
+	requestID := req.Headers.Get("X-Request-Id")
+	if requestID == "" {
+		requestID = w.Headers.Get("X-Request-Id")
+	}
+
+	var b strings.Builder
+	target := req.path
+	if req.rawQuery != "" {
+		target += "?" + req.rawQuery
+	}
+	b.WriteString("[sample " + requestID + "] " + req.Method + " " + displayPath(target))
+	b.WriteString(" -> " + strconv.Itoa(status) + " in " + elapsed.String())
+
+	b.WriteString("\n  request headers:")
+	writeRedactedHeaders(&b, req.Headers)
+	if body := req.debugCapture.buf; len(body) > 0 {
+		b.WriteString("\n  request body: " + strconv.Quote(string(body)))
+	}
+
+	b.WriteString("\n  response headers:")
+	writeRedactedHeaders(&b, w.Headers)
+	switch {
+	case w.headersSent && len(w.body) == 0:
+		b.WriteString("\n  response body: (streamed)")
+	case len(w.body) > 0:
+		b.WriteString("\n  response body: " + strconv.Quote(string(truncate(w.body, debugSampleBodyBytes))))
+	}
+
+	if req.trace != nil {
+		for _, step := range req.trace.steps {
+			b.WriteString("\n  trace: " + step.String())
+		}
+	}
+
+	log.Debug(b.String())
+}
+
+// writeRedactedHeaders writes sorted headers, hiding DefaultRedactedHeaders
+func writeRedactedHeaders(b *strings.Builder, h Headers) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := h[k]
+		for _, name := range DefaultRedactedHeaders {
+			if strings.EqualFold(k, name) {
+				v = redactedValue
+				break
+			}
+		}
+		b.WriteString("\n    " + k + ": " + v)
+	}
+}
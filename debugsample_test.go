@@ -0,0 +1,89 @@
+package gouter
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// captureServe runs fn in a subtest with stdout captured until its server
+// has shut down, so no request still writes to the pipe
+func captureServe(t *testing.T, fn func(t *testing.T)) string {
+	t.Helper()
+	stdout := os.Stdout
+	rd, wr, _ := os.Pipe()
+	os.Stdout = wr
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(rd)
+		done <- out
+	}()
+	t.Run("serve", fn)
+	os.Stdout = stdout
+	wr.Close()
+	return string(<-done)
+}
+
+func TestDebugSample(t *testing.T) {
+	echo := func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.Headers.Add("Set-Cookie", "session=abc")
+		w.Write([]byte("echo:" + string(body)))
+	}
+	r := newTestRouter()
+	r.Post("/always", echo).DebugSample(1)
+	r.Post("/never", echo).DebugSample(0)
+	r.Post("/plain", echo)
+
+	post := func(t *testing.T, addr, path, id string) {
+		raw := "POST " + path + "?q=1 HTTP/1.1\r\nHost: x\r\nAuthorization: Bearer hunter2\r\nX-Request-Id: " + id +
+			"\r\nContent-Length: 5\r\n\r\nhello"
+		if got := bodyString(t, rawExchange(t, addr, raw, 1)[0]); got != "echo:hello" {
+			t.Errorf("%s = %q", path, got)
+		}
+	}
+	out := captureServe(t, func(t *testing.T) {
+		addr := serveRouter(t, r)
+		post(t, addr, "/always", "req-always")
+		post(t, addr, "/never", "req-never")
+		post(t, addr, "/plain", "req-plain")
+	})
+
+	for _, want := range []string{
+		"[sample req-always] POST /always?q=1 -> 200",
+		"authorization: " + redactedValue,
+		"x-request-id: req-always",
+		`request body: "hello"`,
+		"set-cookie: " + redactedValue,
+		`response body: "echo:hello"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dump lacks %q:\n%s", want, out)
+		}
+	}
+	for _, leak := range []string{"hunter2", "session=abc", "req-never", "req-plain"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("log contains %q:\n%s", leak, out)
+		}
+	}
+}
+
+func TestSampledRate(t *testing.T) {
+	const n = 20000
+	hits := 0
+	for i := 0; i < n; i++ {
+		if sampled(0.25) {
+			hits++
+		}
+	}
+	if hits < n/5 || hits > n*3/10 {
+		t.Errorf("sampled %d of %d at rate 0.25", hits, n)
+	}
+	if sampled(0) || !sampled(1) {
+		t.Error("rates 0 and 1 must never and always sample")
+	}
+	if got := (&RouteInfo{}).DebugSample(3).debugRate; got != 1 {
+		t.Errorf("DebugSample(3) stored %v, want it clamped to 1", got)
+	}
+}
@@ -0,0 +1,65 @@
+package gouter
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxDecompressedBytes caps decoded bodies when DecompressRequest
+// is given no limit
+const defaultMaxDecompressedBytes = 10 << 20
+
+// DecompressRequest creates a middleware decoding request bodies sent with
+// Content-Encoding gzip or deflate, so handlers and ReadJson see plain bytes
+// Args:
+//   - maxBytes: Limit of the decoded body, past which reads fail with
+//     ErrBodyTooLarge; 0 uses 10MB. It stops small compressed bodies from
+//     expanding without bound (zip bombs)
+//
+// Other encodings get 415 and a corrupt stream header 400. Content-Encoding
+// and Content-Length are removed from the request headers once decoded
+func DecompressRequest(maxBytes int64) Middleware {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxDecompressedBytes
+	}
+
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			encoding := r.Headers.Get("Content-Encoding")
+			if encoding == "" || r.Body == nil || r.Body == http.NoBody || r.Headers.Get("Content-Length") == "0" {
+				next(r, w)
+				return
+			}
+
+			// Codings are listed in the order they were applied
+			codings := strings.Split(encoding, ",")
+			body := r.Body
+			for i := len(codings) - 1; i >= 0; i-- {
+				var err error
+				switch strings.ToLower(strings.TrimSpace(codings[i])) {
+				case "identity", "":
+					continue
+				case "gzip", "x-gzip":
+					body, err = gzip.NewReader(body)
+				case "deflate":
+					body, err = zlib.NewReader(body)
+				default:
+					w.SetHeader("Accept-Encoding", "gzip, deflate")
+					w.WriteHeader(http.StatusUnsupportedMediaType)
+					return
+				}
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+			}
+
+			r.Body = &maxBytesReader{r: body, remaining: maxBytes}
+			delete(r.Headers, "content-encoding")
+			delete(r.Headers, "content-length")
+			next(r, w)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package gouter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func gzipped(data []byte) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+func deflated(data []byte) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+// postEncoded sends body to /echo with the given Content-Encoding
+func postEncoded(t *testing.T, addr, encoding string, body []byte) *http.Response {
+	t.Helper()
+	raw := "POST /echo HTTP/1.1\r\nHost: x\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n"
+	if encoding != "" {
+		raw += "Content-Encoding: " + encoding + "\r\n"
+	}
+	return rawExchange(t, addr, raw+"\r\n"+string(body), 1)[0]
+}
+
+func TestDecompressRequest(t *testing.T) {
+	r := newTestRouter()
+	r.Use(DecompressRequest(64))
+	r.Post("/echo", func(req *Request, w *Writer) {
+		b, err := io.ReadAll(req.Body)
+		if errors.Is(err, ErrBodyTooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.SetHeader("X-Encoding", req.Headers.Get("Content-Encoding"))
+		w.SetHeader("X-Length", req.Headers.Get("Content-Length"))
+		w.Write(b)
+	})
+	addr := serveRouter(t, r)
+
+	plain := []byte(`{"name":"gouter"}`)
+	for encoding, body := range map[string][]byte{
+		"":              plain,
+		"gzip":          gzipped(plain),
+		"X-GZIP":        gzipped(plain),
+		"deflate":       deflated(plain),
+		"identity":      plain,
+		"deflate, gzip": gzipped(deflated(plain)),
+	} {
+		resp := postEncoded(t, addr, encoding, body)
+		if got := bodyString(t, resp); resp.StatusCode != 200 || got != string(plain) {
+			t.Errorf("%q: %d %q", encoding, resp.StatusCode, got)
+			continue
+		}
+		// Decoded requests no longer describe the bytes on the wire
+		if encoding != "" && (resp.Header.Get("X-Encoding") != "" || resp.Header.Get("X-Length") != "") {
+			t.Errorf("%q: handler saw Content-Encoding %q, Content-Length %q", encoding, resp.Header.Get("X-Encoding"), resp.Header.Get("X-Length"))
+		}
+	}
+
+	resp := postEncoded(t, addr, "br", plain)
+	if resp.StatusCode != http.StatusUnsupportedMediaType || resp.Header.Get("Accept-Encoding") != "gzip, deflate" {
+		t.Errorf("br = %d, Accept-Encoding %q", resp.StatusCode, resp.Header.Get("Accept-Encoding"))
+	}
+	if resp := postEncoded(t, addr, "gzip", plain); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("corrupt gzip = %d, want 400", resp.StatusCode)
+	}
+	if resp := postEncoded(t, addr, "gzip", nil); resp.StatusCode != 200 {
+		t.Errorf("empty gzip body = %d, want 200", resp.StatusCode)
+	}
+
+	// A small payload expanding past the cap fails while read
+	bomb := gzipped(bytes.Repeat([]byte("a"), 1<<20))
+	if resp := postEncoded(t, addr, "gzip", bomb); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("%d compressed bytes expanding to 1MB = %d, want the cap hit", len(bomb), resp.StatusCode)
+	}
+	if resp := postEncoded(t, addr, "gzip", gzipped(bytes.Repeat([]byte("a"), 64))); resp.StatusCode != 200 {
+		t.Errorf("body of exactly the cap = %d", resp.StatusCode)
+	}
+}
+
+func TestDecompressRequestReadJson(t *testing.T) {
+	r := newTestRouter()
+	r.Use(DecompressRequest(0))
+	r.Post("/echo", func(req *Request, w *Writer) {
+		var v struct{ Items []string }
+		if err := req.ReadJson(&v); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(strings.Join(v.Items, ",")))
+	})
+	addr := serveRouter(t, r)
+
+	resp := postEncoded(t, addr, "gzip", gzipped([]byte(`{"items":["a","b"]}`)))
+	if got := bodyString(t, resp); got != "a,b" {
+		t.Errorf("ReadJson of a gzip body = %d %q", resp.StatusCode, got)
+	}
+}
@@ -0,0 +1,214 @@
+package gouter
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// devPollInterval is how often dev mode checks the asset manifest for changes
+const devPollInterval = 500 * time.Millisecond
+
+// ErrNoTemplates is returned by RenderTemplate when the router has no
+// templates loaded or none with the requested name
+var ErrNoTemplates = errors.New("template not found")
+
+// devState is the runtime switch of DevMode and its polling goroutine
+type devState struct {
+	on   atomic.Bool
+	mu   sync.Mutex
+	stop chan struct{} // Closed to end the poller, nil while off
+}
+
+// DevMode switches development behavior at runtime
+// While on:
+//   - RenderTemplate parses the template files again on every call
+//   - File, ServerStatic and ServerFiles read files on every request and
+//     answer with Cache-Control: no-store instead of ETag and Last-Modified
+//   - the asset manifest is reloaded when its file changes
+//   - responses carry X-Dev-Mode: on
+//
+// Turning it off stops the manifest polling; nothing runs while it is off
+func (r *Router) DevMode(on bool) {
+	r.lazyInit()
+
+	r.dev.mu.Lock()
+	defer r.dev.mu.Unlock()
+	if r.dev.on.Load() == on {
+		return
+	}
+	r.dev.on.Store(on)
+
+	if !on {
+		close(r.dev.stop)
+		r.dev.stop = nil
+		return
+	}
+	r.dev.stop = make(chan struct{})
+	stop := r.dev.stop
+	spawn("devmode", func() { r.pollAssets(stop) })
+}
+
+// pollAssets reloads the asset manifest when its modification time changes
+func (r *Router) pollAssets(stop chan struct{}) {
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := r.assets.reloadIfChanged(); err != nil {
+				log.Warn("asset manifest reload failed: " + err.Error())
+			}
+		}
+	}
+}
+
+// templateSet holds the templates loaded by Router.TemplateFiles
+type templateSet struct {
+	patterns []string
+	funcs    template.FuncMap
+
+	mu   sync.RWMutex
+	tmpl *template.Template
+}
+
+// TemplateFiles loads HTML templates matching glob patterns for RenderTemplate
+// Templates can call asset "name" to resolve a path through the asset
+// manifest (see AssetManifest)
+func (r *Router) TemplateFiles(patterns ...string) error {
+	r.lazyInit()
+
+	set := &templateSet{
+		patterns: patterns,
+		funcs:    template.FuncMap{"asset": r.Asset},
+	}
+	tmpl, err := set.parse()
+	if err != nil {
+		return err
+	}
+	set.tmpl = tmpl
+	r.templates = set
+	return nil
+}
+
+// parse reads the template files from disk
+func (s *templateSet) parse() (*template.Template, error) {
+	tmpl := template.New("").Funcs(s.funcs)
+	for _, pattern := range s.patterns {
+		var err error
+		if tmpl, err = tmpl.ParseGlob(pattern); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// get returns the templates, parsed again when reload is set
+func (s *templateSet) get(reload bool) (*template.Template, error) {
+	if reload {
+		tmpl, err := s.parse()
+		if err != nil {
+			return nil, err
+		}
+		s.mu.Lock()
+		s.tmpl = tmpl
+		s.mu.Unlock()
+		return tmpl, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tmpl, nil
+}
+
+// RenderTemplate renders a template loaded by TemplateFiles, as Render does
+// Returns ErrNoTemplates when no template has that name
+func (w *Writer) RenderTemplate(name string, data any) error {
+	if w.templates == nil {
+		return ErrNoTemplates
+	}
+	tmpl, err := w.templates.get(w.dev)
+	if err != nil {
+		return err
+	}
+	if tmpl = tmpl.Lookup(name); tmpl == nil {
+		return ErrNoTemplates
+	}
+	return w.Render(tmpl, data)
+}
+
+// assetManifest maps logical asset names to built file paths
+type assetManifest struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	entries map[string]string
+}
+
+// AssetManifest loads a JSON object mapping asset names to their built
+// paths (e.g., {"app.js": "/static/app.3f9a.js"}), as written by bundlers
+// Asset and the asset template function resolve names through it
+func (r *Router) AssetManifest(path string) error {
+	m := &assetManifest{path: path}
+	if err := m.reloadIfChanged(); err != nil {
+		return err
+	}
+	r.assets = m
+	return nil
+}
+
+// Asset returns the built path of an asset, or name when the manifest does
+// not list it
+func (r *Router) Asset(name string) string {
+	if r.assets == nil {
+		return name
+	}
+	r.assets.mu.RLock()
+	defer r.assets.mu.RUnlock()
+	if p, ok := r.assets.entries[name]; ok {
+		return p
+	}
+	return name
+}
+
+// reloadIfChanged reads the manifest when its modification time changed
+// A nil manifest has nothing to reload
+func (m *assetManifest) reloadIfChanged() error {
+	if m == nil {
+		return nil
+	}
+
+	stat, err := os.Stat(m.path)
+	if err != nil {
+		return err
+	}
+	m.mu.RLock()
+	unchanged := stat.ModTime().Equal(m.modTime)
+	m.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		return err
+	}
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.entries, m.modTime = entries, stat.ModTime()
+	m.mu.Unlock()
+	return nil
+}
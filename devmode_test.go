@@ -0,0 +1,144 @@
+package gouter
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+// devPollers counts the running DevMode manifest pollers
+func devPollers() int {
+	var buf bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buf, 1)
+	return strings.Count(buf.String(), `"devmode"`)
+}
+
+// touch writes content to path with a modification time moved by offset
+func touch(t *testing.T, path, content string, offset time.Duration) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mod := time.Now().Add(offset)
+	if err := os.Chtimes(path, mod, mod); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDevModeReloadsTemplates(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	manifest := filepath.Join(dir, "manifest.json")
+	touch(t, page, `{{define "page"}}v1 {{.}} {{asset "app.js"}}{{end}}`, 0)
+	touch(t, manifest, `{"app.js": "/static/app.111.js"}`, 0)
+
+	r := newTestRouter()
+	if err := r.TemplateFiles(filepath.Join(dir, "*.html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.AssetManifest(manifest); err != nil {
+		t.Fatal(err)
+	}
+	r.Get("/", func(req *Request, w *Writer) {
+		if err := w.RenderTemplate("page", "home"); err != nil {
+			Error(w, err, 500)
+		}
+	})
+	r.Get("/missing", func(req *Request, w *Writer) {
+		if err := w.RenderTemplate("nope", nil); !errors.Is(err, ErrNoTemplates) {
+			t.Errorf("unknown template = %v, want ErrNoTemplates", err)
+		}
+	})
+	addr := serveRouter(t, r)
+	render := func() (string, string) {
+		resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		return bodyString(t, resp), resp.Header.Get("X-Dev-Mode")
+	}
+	rawExchange(t, addr, "GET /missing HTTP/1.1\r\nHost: x\r\n\r\n", 1)
+
+	// Off: templates stay as loaded and nothing polls
+	touch(t, page, `{{define "page"}}v2 {{.}} {{asset "app.js"}}{{end}}`, time.Second)
+	if body, dev := render(); body != "v1 home /static/app.111.js" || dev != "" {
+		t.Errorf("production render = %q (X-Dev-Mode %q), want the loaded template", body, dev)
+	}
+	if n := devPollers(); n != 0 {
+		t.Errorf("%d pollers running with DevMode off", n)
+	}
+
+	r.DevMode(true)
+	r.DevMode(true)
+	if n := devPollers(); n != 1 {
+		t.Errorf("%d pollers running with DevMode on, want 1", n)
+	}
+	if body, dev := render(); body != "v2 home /static/app.111.js" || dev != "on" {
+		t.Errorf("dev render = %q (X-Dev-Mode %q), want the edited template", body, dev)
+	}
+	touch(t, manifest, `{"app.js": "/static/app.222.js"}`, time.Hour)
+	deadline := time.Now().Add(3 * time.Second)
+	for r.Asset("app.js") != "/static/app.222.js" && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if body, _ := render(); body != "v2 home /static/app.222.js" {
+		t.Errorf("render after a manifest change = %q, want the new asset path", body)
+	}
+
+	// A broken edit fails the render instead of serving the old template
+	touch(t, page, `{{define "page"}}v3 {{.}`, 2*time.Second)
+	resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != 500 {
+		t.Errorf("broken template = %d, want 500", resp.StatusCode)
+	}
+
+	r.DevMode(false)
+	touch(t, page, `{{define "page"}}v4 {{.}}{{end}}`, 3*time.Second)
+	if body, dev := render(); body != "v2 home /static/app.222.js" || dev != "" {
+		t.Errorf("render after DevMode(false) = %q (X-Dev-Mode %q), want the last good template", body, dev)
+	}
+	eventually(t, "poller stops", func() bool { return devPollers() == 0 })
+}
+
+func TestDevModeFiles(t *testing.T) {
+	dir := t.TempDir()
+	small, large := filepath.Join(dir, "small.css"), filepath.Join(dir, "large.bin")
+	touch(t, small, "body{color:red}", 0)
+	touch(t, large, strings.Repeat("a", 100<<10), 0)
+
+	r := newTestRouter()
+	for path, file := range map[string]string{"/small.css": small, "/large.bin": large} {
+		if _, err := r.File(path, file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ServerStatic(r, "/static", dir)
+	addr := serveRouter(t, r)
+	get := func(path string) (string, http.Header) {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		return bodyString(t, resp), resp.Header
+	}
+
+	touch(t, small, "body{color:blue}", time.Second)
+	if body, h := get("/small.css"); body != "body{color:red}" || h.Get("ETag") == "" || h.Get("Cache-Control") == "no-store" {
+		t.Errorf("production file = %q %v, want the loaded copy with an ETag", body, h)
+	}
+	if _, h := get("/large.bin"); h.Get("ETag") == "" || h.Get("Last-Modified") == "" {
+		t.Errorf("production large file headers = %v, want validators", h)
+	}
+
+	r.DevMode(true)
+	defer r.DevMode(false)
+	for _, path := range []string{"/small.css", "/large.bin", "/static/small.css"} {
+		body, h := get(path)
+		if h.Get("Cache-Control") != "no-store" || h.Get("ETag") != "" || h.Get("Last-Modified") != "" || h.Get("X-Dev-Mode") != "on" {
+			t.Errorf("dev %s headers = %v, want no-store without validators", path, h)
+		}
+		if path != "/large.bin" && body != "body{color:blue}" {
+			t.Errorf("dev %s = %q, want the file as on disk", path, body)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package gouter
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// StatusError wraps err so typed handlers (H, H1, H2) answer with code
+// Other errors returned by typed handlers become a bodyless 500
+func StatusError(code int, err error) error {
+	return &httpError{code, err}
+}
+
+// H adapts a handler returning a value into a Handler writing it as JSON
+func H[R any](fn func(r *Request, w *Writer) (R, error)) Handler {
+	return func(r *Request, w *Writer) {
+		res, err := fn(r, w)
+		writeResult(w, res, err)
+	}
+}
+
+// H1 adapts a handler taking one injected argument
+// See H2 for how arguments are bound
+func H1[A, R any](fn func(r *Request, w *Writer, a A) (R, error)) Handler {
+	bindA := newArgBinder(reflect.TypeOf((*A)(nil)).Elem(), 0)
+
+	return func(r *Request, w *Writer) {
+		a, err := bindA(r)
+		if err != nil {
+			Error(w, err, http.StatusBadRequest)
+			return
+		}
+		res, err := fn(r, w, a.Interface().(A))
+		writeResult(w, res, err)
+	}
+}
+
+// H2 adapts a handler taking two injected arguments, e.g.
+//
+//	H2(func(r *Request, w *Writer, id int, body UpdateReq) (Resp, error))
+//
+// Arguments are bound by type:
+//   - Scalars (string, integers, floats, bool) and encoding.TextUnmarshaler
+//     types (e.g., UUIDs) take the route parameters in pattern order
+//   - Structs with `param:"name"` tagged fields get those parameters by name
+//   - Anything else is decoded from the JSON body
+//
+// Conversion failures answer 400 naming the parameter. The argument types
+// are inspected once, when H2 is called
+func H2[A, B, R any](fn func(r *Request, w *Writer, a A, b B) (R, error)) Handler {
+	typeA := reflect.TypeOf((*A)(nil)).Elem()
+	bindA := newArgBinder(typeA, 0)
+	next := 0
+	if isParamScalar(typeA) {
+		next = 1
+	}
+	bindB := newArgBinder(reflect.TypeOf((*B)(nil)).Elem(), next)
+
+	return func(r *Request, w *Writer) {
+		a, err := bindA(r)
+		if err != nil {
+			Error(w, err, http.StatusBadRequest)
+			return
+		}
+		b, err := bindB(r)
+		if err != nil {
+			Error(w, err, http.StatusBadRequest)
+			return
+		}
+		res, err := fn(r, w, a.Interface().(A), b.Interface().(B))
+		writeResult(w, res, err)
+	}
+}
+
+// writeResult writes a typed handler result, or its error status
+func writeResult(w *Writer, res any, err error) {
+	if err != nil {
+		var he *httpError
+		if errors.As(err, &he) {
+			Error(w, he.err, he.code)
+			return
+		}
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := w.WriteJson(res); err != nil {
+		log.Error(err)
+	}
+}
+
+// argBinder produces the value of one injected handler argument
+type argBinder func(r *Request) (reflect.Value, error)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isParamScalar reports whether t is bound from a single route parameter
+func isParamScalar(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// newArgBinder inspects an argument type and returns its binder
+// position is the index of the route parameter used by scalar arguments
+func newArgBinder(t reflect.Type, position int) argBinder {
+	if isParamScalar(t) {
+		return func(r *Request) (reflect.Value, error) {
+			if r.route == nil || position >= len(r.route.Parameters) {
+				return reflect.Value{}, fmt.Errorf("route has no parameter #%d", position+1)
+			}
+			name := r.route.Parameters[position].Name
+			return convertParam(t, name, r.Params.Get(name))
+		}
+	}
+
+	if t.Kind() == reflect.Struct {
+		if fields := paramFields(t); len(fields) > 0 {
+			return func(r *Request) (reflect.Value, error) {
+				v := reflect.New(t).Elem()
+				for _, f := range fields {
+					raw := r.Params.Get(f.name)
+					if raw == "" {
+						continue
+					}
+					fv, err := convertParam(f.typ, f.name, raw)
+					if err != nil {
+						return reflect.Value{}, err
+					}
+					v.Field(f.index).Set(fv)
+				}
+				return v, nil
+			}
+		}
+	}
+
+	return func(r *Request) (reflect.Value, error) {
+		v := reflect.New(t)
+		if err := r.ReadJson(v.Interface()); err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid JSON body: %w", err)
+		}
+		return v.Elem(), nil
+	}
+}
+
+// paramField is a struct field bound to a route parameter
+type paramField struct {
+	index int
+	name  string
+	typ   reflect.Type
+}
+
+// paramFields lists the `param` tagged scalar fields of a struct
+func paramFields(t reflect.Type) []paramField {
+	var fields []paramField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, ok := f.Tag.Lookup("param")
+		if !ok || !f.IsExported() || !isParamScalar(f.Type) {
+			continue
+		}
+		fields = append(fields, paramField{index: i, name: name, typ: f.Type})
+	}
+	return fields
+}
+
+// convertParam parses a raw parameter into a value of type t
+func convertParam(t reflect.Type, name, raw string) (reflect.Value, error) {
+	v := reflect.New(t)
+
+	if u, ok := v.Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(raw)); err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid parameter %q: %w", name, err)
+		}
+		return v.Elem(), nil
+	}
+
+	e := v.Elem()
+	var err error
+	switch t.Kind() {
+	case reflect.String:
+		e.SetString(raw)
+	case reflect.Bool:
+		var b bool
+		b, err = strconv.ParseBool(raw)
+		e.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		n, err = strconv.ParseInt(raw, 10, t.Bits())
+		e.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		n, err = strconv.ParseUint(raw, 10, t.Bits())
+		e.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		f, err = strconv.ParseFloat(raw, t.Bits())
+		e.SetFloat(f)
+	}
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("invalid parameter %q: expected %s, got %q", name, t.Kind(), raw)
+	}
+	return e, nil
+}
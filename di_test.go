@@ -0,0 +1,105 @@
+package gouter
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// testUUID parses the canonical 8-4-4-4-12 hex form
+type testUUID [16]byte
+
+func (u *testUUID) UnmarshalText(b []byte) error {
+	s := strings.ReplaceAll(string(b), "-", "")
+	if len(b) != 36 || len(s) != 32 {
+		return errors.New("malformed uuid")
+	}
+	_, err := hex.Decode(u[:], []byte(s))
+	return err
+}
+
+type updateReq struct {
+	Name string `json:"name"`
+}
+
+type itemParams struct {
+	Org  string `param:"org"`
+	ID   int    `param:"id"`
+	Skip string // Untagged fields are left alone
+}
+
+// call sends a request to addr and returns the status and body
+func call(t *testing.T, addr, method, path, body string) (int, string) {
+	t.Helper()
+	raw := method + " " + path + " HTTP/1.1\r\nHost: x\r\n"
+	if body != "" {
+		raw += "Content-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n"
+	}
+	resp := rawExchange(t, addr, raw+"\r\n"+body, 1)[0]
+	return resp.StatusCode, strings.TrimSpace(bodyString(t, resp))
+}
+
+func TestTypedHandlerInjection(t *testing.T) {
+	r := newTestRouter()
+	r.Put("/users/:id", H2(func(r *Request, w *Writer, id int, body updateReq) (string, error) {
+		return fmt.Sprintf("%d:%s", id, body.Name), nil
+	}))
+	r.Get("/tags/:tag/:page", H2(func(r *Request, w *Writer, tag string, page uint8) (string, error) {
+		return tag + "#" + strconv.Itoa(int(page)), nil
+	}))
+	r.Get("/objects/:uuid", H1(func(r *Request, w *Writer, id testUUID) (string, error) {
+		return hex.EncodeToString(id[:4]), nil
+	}))
+	r.Get("/orgs/:org/items/:id", H1(func(r *Request, w *Writer, p itemParams) (itemParams, error) {
+		return p, nil
+	}))
+	r.Get("/gone/:id", H1(func(r *Request, w *Writer, id int) (string, error) {
+		return "", StatusError(http.StatusGone, fmt.Errorf("item %d was deleted", id))
+	}))
+	r.Get("/broken", H(func(r *Request, w *Writer) (string, error) {
+		return "", errors.New("database down")
+	}))
+	addr := serveRouter(t, r)
+
+	tests := []struct {
+		method, path, body string
+		code               int
+		want               string
+	}{
+		{"PUT", "/users/42", `{"name":"ana"}`, 200, `"42:ana"`},
+		{"GET", "/tags/go/3", "", 200, `"go#3"`},
+		{"GET", "/objects/0123abcd-0000-4000-8000-000000000000", "", 200, `"0123abcd"`},
+		{"GET", "/orgs/acme/items/7", "", 200, `{"Org":"acme","ID":7,"Skip":""}`},
+		{"GET", "/gone/9", "", 410, "item 9 was deleted"},
+		{"GET", "/broken", "", 500, "internal server error"},
+
+		// Conversion failures name the parameter
+		{"PUT", "/users/abc", `{"name":"ana"}`, 400, `invalid parameter "id": expected int, got "abc"`},
+		{"GET", "/tags/go/300", "", 400, `invalid parameter "page": expected uint8, got "300"`},
+		{"GET", "/objects/not-a-uuid", "", 400, `invalid parameter "uuid": malformed uuid`},
+		{"GET", "/orgs/acme/items/x", "", 400, `invalid parameter "id": expected int, got "x"`},
+		{"PUT", "/users/1", `{"name":`, 400, "invalid JSON body"},
+	}
+	for _, tt := range tests {
+		code, body := call(t, addr, tt.method, tt.path, tt.body)
+		if code != tt.code || !strings.HasPrefix(body, tt.want) {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, code, body, tt.code, tt.want)
+		}
+	}
+}
+
+func BenchmarkTypedHandlerBinding(b *testing.B) {
+	bind := newArgBinder(reflect.TypeOf(0), 0)
+	req := &Request{Params: Params{"id": "12345"}, route: &RouteInfo{Parameters: []ParamInfo{{Name: "id"}}}}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := bind(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
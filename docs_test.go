@@ -0,0 +1,121 @@
+package gouter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// occupiedPort holds a loopback port for the rest of the test
+func occupiedPort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// docsRouter returns a router whose docs are configured to use port
+func docsRouter(port int, configure func(d *Doc)) *Router {
+	r := NewRouter()
+	r.Update(func(d *Doc) {
+		d.Addrs = "127.0.0.1"
+		d.Port = strconv.Itoa(port)
+		configure(d)
+	})
+	r.Get("/", func(req *Request, w *Writer) {})
+	return r
+}
+
+// serveUntilReady serves r and returns once requests are answered, or
+// returns the Serve error if it stopped first
+func serveUntilReady(t *testing.T, r *Router) error {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Router: r}
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(100 * time.Millisecond):
+	}
+	if resp := rawExchange(t, l.Addr().String(), "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 200 {
+		t.Errorf("app status = %d with docs unavailable", resp.StatusCode)
+	}
+	return nil
+}
+
+func TestDocsPortTakenBestEffort(t *testing.T) {
+	port := occupiedPort(t)
+	r := docsRouter(port, func(d *Doc) {})
+
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatalf("Serve = %v, want the app to start without docs", err)
+	}
+	if addr, ok := r.DocsAddr(); ok {
+		t.Errorf("DocsAddr = %q, true; want false when the port is taken", addr)
+	}
+}
+
+func TestDocsPortTakenFailOnError(t *testing.T) {
+	port := occupiedPort(t)
+	r := docsRouter(port, func(d *Doc) { d.FailOnError = true })
+
+	err := serveUntilReady(t, r)
+	var opErr *net.OpError
+	if err == nil || !errors.As(err, &opErr) {
+		t.Errorf("Serve = %v, want the listen error", err)
+	}
+	if _, ok := r.DocsAddr(); ok {
+		t.Error("DocsAddr reports docs that failed to start")
+	}
+}
+
+func TestDocsPortTakenAutoPort(t *testing.T) {
+	port := occupiedPort(t)
+	r := docsRouter(port, func(d *Doc) { d.AutoPort = true })
+
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	addr, ok := r.DocsAddr()
+	if !ok {
+		t.Fatal("DocsAddr = false, want the next free port")
+	}
+	_, p, _ := net.SplitHostPort(addr)
+	got, _ := strconv.Atoi(p)
+	if got <= port || got > port+docAutoPortRange {
+		t.Errorf("docs port = %d, want one of %d-%d", got, port+1, port+docAutoPortRange)
+	}
+	if resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 200 {
+		t.Errorf("docs on the chosen port = %d", resp.StatusCode)
+	}
+}
+
+func TestDocsAddrSettledBeforeServing(t *testing.T) {
+	r := docsRouter(0, func(d *Doc) {})
+	if _, ok := r.DocsAddr(); ok {
+		t.Error("DocsAddr set before serving")
+	}
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := r.DocsAddr(); !ok || addr == "127.0.0.1:0" {
+		t.Errorf("DocsAddr = %q %v, want the bound address", addr, ok)
+	}
+}
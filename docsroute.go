@@ -0,0 +1,36 @@
+package gouter
+
+import (
+	"net/http"
+	"strings"
+)
+
+// MountDocs serves the documentation below prefix on the router itself,
+// for deployments that expose a single port
+// The docs page, /openapi.json and, when enabled, /swagger and "Try it"
+// are served as on the doc server, which MountDocs turns off; set
+// Doc.Active again to keep both. The route is hidden from the docs, and
+// middlewares added to it with Use (e.g., an auth check) protect it
+func (r *Router) MountDocs(prefix string) *RouteInfo {
+	r.lazyInit()
+	base := strings.TrimRight("/"+strings.Trim(prefix, "/"), "/")
+	r.docConfig.Active = false
+
+	handler := func(req *Request, w *Writer) {
+		// Links in the docs page are relative to the page, so it must be
+		// served with a trailing slash
+		if req.path == base {
+			w.SetHeader("Location", base+"/")
+			w.WriteHeader(http.StatusMovedPermanently)
+			return
+		}
+		sub := "/" + strings.TrimPrefix(strings.TrimPrefix(req.path, base), "/")
+		r.serveDocs(req, sub, w)
+	}
+
+	info := r.addRoute(base+"/*", traceLayer("handler", withGuards(handler)), nil, callerLocation(1))
+	if info != nil {
+		info.Hide()
+	}
+	return info
+}
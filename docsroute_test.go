@@ -0,0 +1,98 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMountDocs(t *testing.T) {
+	r := docsRouter(0, func(d *Doc) {
+		d.Title = "Shop API"
+		d.SwaggerUI = true
+		d.TryIt = true
+	})
+	r.Get("/items", func(req *Request, w *Writer) { w.Write([]byte("items")) }).SetDescription("List items")
+	r.MountDocs("/docs/").Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			if req.Headers.Get("Authorization") != "Bearer admin" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(req, w)
+		}
+	})
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	if addr, ok := r.DocsAddr(); ok {
+		t.Errorf("doc server listening on %s after MountDocs", addr)
+	}
+	addr := r.wsBase()[len("ws://"):]
+
+	get := func(path string, authorized bool) *http.Response {
+		raw := "GET " + path + " HTTP/1.1\r\nHost: x\r\n"
+		if authorized {
+			raw += "Authorization: Bearer admin\r\n"
+		}
+		return rawExchange(t, addr, raw+"\r\n", 1)[0]
+	}
+
+	// The middleware added to the docs route guards every path below it
+	for _, path := range []string{"/docs", "/docs/", "/docs/openapi.json", "/docs/swagger"} {
+		if resp := get(path, false); resp.StatusCode != 401 {
+			t.Errorf("GET %s without credentials = %d, want 401", path, resp.StatusCode)
+		}
+	}
+	if resp := get("/items", false); resp.StatusCode != 200 {
+		t.Errorf("app route = %d", resp.StatusCode)
+	}
+
+	// Relative links of the page need the trailing slash
+	if resp := get("/docs", true); resp.StatusCode != 301 || resp.Header.Get("Location") != "/docs/" {
+		t.Errorf("GET /docs = %d Location %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	page := bodyString(t, get("/docs/", true))
+	if !strings.Contains(page, "Shop API") || !strings.Contains(page, "List items") || strings.Contains(page, "/docs/*") {
+		t.Errorf("docs page lacks the app routes or shows its own")
+	}
+	if !strings.Contains(bodyString(t, get("/docs/swagger", true)), "SwaggerUIBundle") {
+		t.Error("/docs/swagger is not Swagger UI")
+	}
+
+	var spec openAPISpec
+	resp := get("/docs/openapi.json", true)
+	if err := json.Unmarshal([]byte(bodyString(t, resp)), &spec); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Paths["/items"]; !ok || len(spec.Paths) != 2 {
+		t.Errorf("openapi paths = %v, want / and /items only", spec.Paths)
+	}
+
+	// Try it runs below the prefix too
+	body := `{"method":"GET","path":"/items"}`
+	raw := "POST /docs/try HTTP/1.1\r\nHost: x\r\nAuthorization: Bearer admin\r\nContent-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	var out tryItResponse
+	json.Unmarshal([]byte(bodyString(t, rawExchange(t, addr, raw, 1)[0])), &out)
+	if out.Status != 200 || out.Body != "items" {
+		t.Errorf("Try it below the prefix = %+v", out)
+	}
+}
+
+func TestMountDocsKeepsDocServer(t *testing.T) {
+	r := docsRouter(0, func(d *Doc) {})
+	r.MountDocs("/")
+	r.Update(func(d *Doc) { d.Active = true })
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	addr, ok := r.DocsAddr()
+	if !ok {
+		t.Fatal("doc server off with Doc.Active set again")
+	}
+	if resp := rawExchange(t, addr, "GET /openapi.json HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 200 {
+		t.Errorf("doc server openapi.json = %d", resp.StatusCode)
+	}
+}
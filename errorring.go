@@ -0,0 +1,197 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults for RecordErrors
+const (
+	defaultErrorRingSize     = 50
+	defaultErrorRingBodySize = 4096
+)
+
+// DefaultRedactedHeaders lists the request headers whose values are hidden
+// in captured diagnostics
+var DefaultRedactedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization", "X-Api-Key"}
+
+// redactedValue replaces the value of redacted headers
+const redactedValue = "[REDACTED]"
+
+// ErrorRingConfig configures the retention of 5xx responses
+type ErrorRingConfig struct {
+	Size         int      // Responses kept, oldest dropped first (default: 50)
+	MaxBodyBytes int      // Captured request and response body size (default: 4096)
+	Redact       []string // Headers whose values are hidden (default: DefaultRedactedHeaders)
+}
+
+// ErrorRecord is a retained 5xx response
+type ErrorRecord struct {
+	Time           time.Time `json:"time"`
+	Method         string    `json:"method"`
+	Path           string    `json:"path"`
+	Route          string    `json:"route,omitempty"`
+	Status         int       `json:"status"`
+	RequestID      string    `json:"requestId,omitempty"` // X-Request-Id of the request or response
+	RequestHeaders Headers   `json:"requestHeaders"`
+	RequestBody    string    `json:"requestBody,omitempty"`  // Request body, truncated
+	ResponseBody   string    `json:"responseBody,omitempty"` // Buffered response body, truncated; empty when streamed
+	Stack          string    `json:"stack,omitempty"`        // Goroutine stack when the handler panicked
+}
+
+// errorRing is a bounded buffer of error records
+type errorRing struct {
+	cfg     ErrorRingConfig
+	redact  map[string]bool
+	mu      sync.Mutex
+	records []ErrorRecord
+	next    int // Slot overwritten by the next record once full
+}
+
+// RecordErrors starts retaining the last 5xx responses for RecentErrors
+// Recording is off by default; when off requests pay a single nil check
+func (r *Router) RecordErrors(cfg ErrorRingConfig) {
+	if cfg.Size <= 0 {
+		cfg.Size = defaultErrorRingSize
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = defaultErrorRingBodySize
+	}
+	if cfg.Redact == nil {
+		cfg.Redact = DefaultRedactedHeaders
+	}
+
+	ring := &errorRing{cfg: cfg, redact: make(map[string]bool)}
+	for _, h := range cfg.Redact {
+		ring.redact[strings.ToLower(h)] = true
+	}
+	r.errorRing = ring
+}
+
+// RecentErrors returns the retained 5xx responses, newest first
+func (r *Router) RecentErrors() []ErrorRecord {
+	ring := r.errorRing
+	if ring == nil {
+		return nil
+	}
+
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	out := make([]ErrorRecord, 0, len(ring.records))
+	for i := 0; i < len(ring.records); i++ {
+		idx := (ring.next - 1 - i + 2*len(ring.records)) % len(ring.records)
+		out = append(out, ring.records[idx])
+	}
+	return out
+}
+
+// ErrRecentErrorsNoAuth is returned by RecentErrorsRoute when no auth middleware is given
+var ErrRecentErrorsNoAuth = errors.New("recent errors route requires an auth middleware")
+
+// RecentErrorsRoute registers an admin route listing RecentErrors as JSON
+// The route is hidden from the docs and wrapped by auth, which must reject
+// unauthorized callers since request bodies are exposed
+func (r *Router) RecentErrorsRoute(path string, auth Middleware) (*RouteInfo, error) {
+	if auth == nil {
+		return nil, ErrRecentErrorsNoAuth
+	}
+
+	handler := func(req *Request, w *Writer) {
+		list := r.RecentErrors()
+		if list == nil {
+			list = []ErrorRecord{}
+		}
+		w.WriteJson(list)
+	}
+
+	info := r.addRoute(path, traceLayer("recent-errors", auth(handler)), nil, callerLocation(1), "GET")
+	if info == nil {
+		return nil, errors.New("recent errors route: path already registered: " + path)
+	}
+	return info.Hide(), nil
+}
+
+// add stores a record, replacing the oldest one when full
+func (ring *errorRing) add(rec ErrorRecord) {
+	ring.mu.Lock()
+	defer ring.mu.Unlock()
+
+	if len(ring.records) < ring.cfg.Size {
+		ring.records = append(ring.records, rec)
+		ring.next = len(ring.records) % ring.cfg.Size
+		return
+	}
+	ring.records[ring.next] = rec
+	ring.next = (ring.next + 1) % ring.cfg.Size
+}
+
+// capture records a finished request answered with a 5xx status
+func (ring *errorRing) capture(req *Request, w *Writer, stack []byte, now time.Time) {
+	rec := ErrorRecord{
+		Time:           now,
+		Method:         req.Method,
+		Path:           req.path,
+		Status:         w.code,
+		RequestHeaders: make(Headers, len(req.Headers)),
+		Stack:          string(stack),
+	}
+	if req.route != nil {
+		rec.Route = req.route.Path
+	}
+
+	rec.RequestID = req.Headers.Get("X-Request-Id")
+	if rec.RequestID == "" {
+		rec.RequestID = w.Headers.Get("X-Request-Id")
+	}
+
+	for k, v := range req.Headers {
+		if ring.redact[k] {
+			v = redactedValue
+		}
+		rec.RequestHeaders[k] = v
+	}
+
+	if c, ok := req.Body.(*captureReader); ok {
+		rec.RequestBody = string(c.buf)
+	}
+	if !w.headersSent || len(w.body) > 0 {
+		rec.ResponseBody = string(truncate(w.body, ring.cfg.MaxBodyBytes))
+	}
+
+	ring.add(rec)
+}
+
+// truncate returns at most n bytes of b
+func truncate(b []byte, n int) []byte {
+	if len(b) > n {
+		return b[:n]
+	}
+	return b
+}
+
+// captureReader keeps the first max bytes read from a request body
+type captureReader struct {
+	r   io.Reader
+	buf []byte
+	max int
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if room := c.max - len(c.buf); room > 0 {
+		c.buf = append(c.buf, truncate(p[:n], room)...)
+	}
+	return n, err
+}
+
+// watch captures the request body as the handler reads it
+func (ring *errorRing) watch(req *Request) {
+	if req.Body != http.NoBody && req.Body != nil {
+		req.Body = &captureReader{r: req.Body, max: ring.cfg.MaxBodyBytes}
+	}
+}
@@ -0,0 +1,125 @@
+package gouter
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestRecentErrors(t *testing.T) {
+	r := newTestRouter()
+	r.RecordErrors(ErrorRingConfig{Size: 2, MaxBodyBytes: 8})
+	r.Post("/orders/:id", func(req *Request, w *Writer) {
+		io.ReadAll(req.Body)
+		w.Headers.Add("X-Request-Id", "resp-1")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("database unavailable"))
+	})
+	r.Post("/panic", func(req *Request, w *Writer) {
+		io.ReadAll(req.Body)
+		panic("boom")
+	})
+	r.Get("/missing", func(req *Request, w *Writer) { w.WriteHeader(http.StatusNotFound) })
+	r.Get("/ok", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	addr := serveRouter(t, r)
+
+	send := func(path, headers, body string) int {
+		raw := "POST " + path + " HTTP/1.1\r\nHost: x\r\n" + headers + "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+		return rawExchange(t, addr, raw, 1)[0].StatusCode
+	}
+
+	if got := r.RecentErrors(); len(got) != 0 {
+		t.Fatalf("RecentErrors before any failure = %v", got)
+	}
+	send("/orders/7", "Authorization: Bearer secret\r\nCookie: sid=abc\r\nX-Trace: keep\r\n", `{"item":"0123456789"}`)
+	send("/panic", "X-Request-Id: req-2\r\n", "payload")
+	rawExchange(t, addr, "GET /missing HTTP/1.1\r\nHost: x\r\n\r\nGET /ok HTTP/1.1\r\nHost: x\r\n\r\n", 2)
+
+	got := r.RecentErrors()
+	if len(got) != 2 {
+		t.Fatalf("RecentErrors = %d records, want the two 5xx only", len(got))
+	}
+	panicked, failed := got[0], got[1]
+
+	if failed.Status != 500 || failed.Route != "/orders/:id" || failed.Path != "/orders/7" || failed.Method != "POST" {
+		t.Errorf("handler 500 = %s %s route %s status %d", failed.Method, failed.Path, failed.Route, failed.Status)
+	}
+	if failed.RequestID != "resp-1" {
+		t.Errorf("RequestID = %q, want the response X-Request-Id", failed.RequestID)
+	}
+	if failed.RequestBody != `{"item":` || failed.ResponseBody != "database" {
+		t.Errorf("bodies = %q / %q, want both truncated to 8 bytes", failed.RequestBody, failed.ResponseBody)
+	}
+	h := failed.RequestHeaders
+	if h.Get("Authorization") != redactedValue || h.Get("Cookie") != redactedValue || h.Get("X-Trace") != "keep" {
+		t.Errorf("request headers = %v, want credentials redacted and the rest kept", h)
+	}
+	if failed.Stack != "" {
+		t.Error("handler-returned 500 has a stack")
+	}
+
+	if panicked.Status != 500 || panicked.RequestID != "req-2" || panicked.RequestBody != "payload" {
+		t.Errorf("panic record = %+v", panicked)
+	}
+	if !strings.Contains(panicked.Stack, "TestRecentErrors") {
+		t.Errorf("panic stack does not point at the handler:\n%s", panicked.Stack)
+	}
+
+	// The ring keeps the newest records only
+	send("/orders/8", "", "")
+	got = r.RecentErrors()
+	if len(got) != 2 || got[0].Path != "/orders/8" || got[1].Path != "/panic" {
+		t.Errorf("after overflow = %s, %s, want /orders/8 then /panic", got[0].Path, got[1].Path)
+	}
+}
+
+func TestRecentErrorsRoute(t *testing.T) {
+	r := newTestRouter()
+	if _, err := r.RecentErrorsRoute("/debug/errors", nil); !errors.Is(err, ErrRecentErrorsNoAuth) {
+		t.Errorf("RecentErrorsRoute without auth = %v, want ErrRecentErrorsNoAuth", err)
+	}
+	if got := r.RecentErrors(); got != nil {
+		t.Errorf("RecentErrors while disabled = %v, want nil", got)
+	}
+
+	r.RecordErrors(ErrorRingConfig{})
+	admin := func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			if req.Headers.Get("X-Admin") != "yes" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(req, w)
+		}
+	}
+	info, err := r.RecentErrorsRoute("/debug/errors", admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Hidden {
+		t.Error("recent errors route is listed in the docs")
+	}
+	r.Get("/fail", func(req *Request, w *Writer) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	addr := serveRouter(t, r)
+
+	resps := rawExchange(t, addr, "GET /fail HTTP/1.1\r\nHost: x\r\nAuthorization: Bearer secret\r\n\r\n"+
+		"GET /debug/errors HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"GET /debug/errors HTTP/1.1\r\nHost: x\r\nX-Admin: yes\r\n\r\n", 3)
+	if resps[1].StatusCode != http.StatusUnauthorized {
+		t.Errorf("without credentials = %d, want 401", resps[1].StatusCode)
+	}
+	body := bodyString(t, resps[2])
+	var list []ErrorRecord
+	if err := json.Unmarshal([]byte(body), &list); err != nil || len(list) != 1 {
+		t.Fatalf("listing = %s (%v), want one record", body, err)
+	}
+	if list[0].Status != http.StatusBadGateway || strings.Contains(body, "secret") {
+		t.Errorf("listing = %s, want the 502 with credentials redacted", body)
+	}
+}
@@ -0,0 +1,398 @@
+package gouter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// ErrorFormat identifies the representation of a synthesized error body
+type ErrorFormat string
+
+const (
+	ErrorText ErrorFormat = "text" // text/plain (default for unknown clients)
+	ErrorJSON ErrorFormat = "json" // application/json
+	ErrorHTML ErrorFormat = "html" // text/html
+)
+
+// ErrorData is the data made available to error templates
+type ErrorData struct {
+	Status    int    // HTTP status code
+	Message   string // Lowercase status text (e.g., "not found"), or its ErrorMessages translation
+	Path      string // Requested path, empty when the request could not be parsed
+	RequestID string // X-Request-Id of the request or response, if any
+	Lang      string // Language of Message, empty for the built-in English text
+}
+
+// errorTemplate is satisfied by both html/template and text/template
+type errorTemplate interface {
+	Execute(w io.Writer, data any) error
+}
+
+// httpError carries the status code a failed request should be answered with
+type httpError struct {
+	code int
+	err  error
+}
+
+func (e *httpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *httpError) Unwrap() error {
+	return e.err
+}
+
+// errorContentTypes maps each format to its Content-Type header
+var errorContentTypes = map[ErrorFormat]string{
+	ErrorText: "text/plain; charset=utf-8",
+	ErrorJSON: "application/json; charset=utf-8",
+	ErrorHTML: "text/html; charset=utf-8",
+}
+
+// defaultErrorPage is the built-in HTML error body, themed like the docs UI
+var defaultErrorPage = htmltemplate.Must(htmltemplate.New("error").Parse(`<!DOCTYPE html>
+<html lang="{{or .Lang "en"}}">
+<head>
+    <meta charset="UTF-8">
+    <title>{{.Status}} {{.Message}}</title>
+    <style>
+        ` + docsThemeCSS + `
+        body {
+            font-family: 'Inter', sans-serif;
+            background-color: var(--bg-dark);
+            color: var(--text-primary);
+            display: flex;
+            align-items: center;
+            justify-content: center;
+            min-height: 100vh;
+            margin: 0;
+        }
+
+        .error-card {
+            background-color: var(--bg-panel);
+            border: 1px solid var(--border);
+            border-radius: 10px;
+            padding: 40px 60px;
+            text-align: center;
+        }
+
+        .error-card h1 {
+            color: var(--accent);
+            font-size: 48px;
+            margin: 0 0 10px 0;
+        }
+
+        .error-card p {
+            color: var(--text-secondary);
+        }
+    </style>
+</head>
+<body>
+    <div class="error-card">
+        <h1>{{.Status}}</h1>
+        <p>{{.Message}}</p>
+    </div>
+</body>
+</html>
+`))
+
+// ErrorTemplate overrides the body used for synthesized errors of a format
+// Args:
+//   - format: Representation to override (ErrorText, ErrorJSON or ErrorHTML)
+//   - text: Template source executed with ErrorData
+//
+// HTML templates are parsed with html/template, others with text/template
+func (r *Router) ErrorTemplate(format ErrorFormat, text string) error {
+	if _, ok := errorContentTypes[format]; !ok {
+		return errors.New("unknown error format: " + string(format))
+	}
+
+	var (
+		tmpl errorTemplate
+		err  error
+	)
+	if format == ErrorHTML {
+		tmpl, err = htmltemplate.New(string(format)).Parse(text)
+	} else {
+		tmpl, err = template.New(string(format)).Parse(text)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.errorTemplates == nil {
+		r.errorTemplates = make(map[ErrorFormat]errorTemplate)
+	}
+	r.errorTemplates[format] = tmpl
+	return nil
+}
+
+// ErrorPageTemplates loads HTML error pages from fsys
+// Files named after a status ("404.html") or a status class ("5xx.html")
+// are parsed with html/template and executed with ErrorData. A status uses
+// its own page, then its class page, then the ErrorTemplate override for
+// ErrorHTML, then the built-in page
+func (r *Router) ErrorPageTemplates(fsys fs.FS) error {
+	names, err := fs.Glob(fsys, "*.html")
+	if err != nil {
+		return err
+	}
+
+	pages := make(map[string]errorTemplate)
+	for _, name := range names {
+		key := strings.TrimSuffix(name, ".html")
+		if !errorPageName.MatchString(key) {
+			continue
+		}
+		tmpl, err := htmltemplate.ParseFS(fsys, name)
+		if err != nil {
+			return err
+		}
+		pages[strings.ToLower(key)] = tmpl
+	}
+
+	r.errorPages = pages
+	return nil
+}
+
+// errorPageName matches the page names loaded by ErrorPageTemplates
+var errorPageName = regexp.MustCompile(`^[1-5]([0-9]{2}|[xX]{2})$`)
+
+// ErrorMessages sets translated status messages for synthesized errors
+// Args:
+//   - catalog: Messages by language tag (e.g., "pt-BR", "pt") and status
+//
+// The language is negotiated from Accept-Language; a regional tag falls back
+// to its base language. Statuses missing from the catalog keep the English text
+func (r *Router) ErrorMessages(catalog map[string]map[int]string) {
+	r.errorMessages = make(map[string]languageMessages, len(catalog))
+	for lang, messages := range catalog {
+		r.errorMessages[strings.ToLower(lang)] = languageMessages{tag: lang, messages: messages}
+	}
+}
+
+// languageMessages are the ErrorMessages of one language
+type languageMessages struct {
+	tag      string // Language tag as given to ErrorMessages
+	messages map[int]string
+}
+
+// localizedMessage returns the catalog message for a status in the
+// preferred language of an Accept-Language header
+func localizedMessage(catalog map[string]languageMessages, acceptLanguage string, code int) (string, string, bool) {
+	for _, lang := range acceptedLanguages(acceptLanguage) {
+		candidates := []string{lang}
+		if base, _, ok := strings.Cut(lang, "-"); ok {
+			candidates = append(candidates, base)
+		}
+		for _, c := range candidates {
+			if msg, ok := catalog[c].messages[code]; ok {
+				return msg, catalog[c].tag, true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// acceptedLanguages lists the lowercase tags of an Accept-Language header,
+// most preferred first; q=0 and "*" are left out
+func acceptedLanguages(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var langs []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			langs = append(langs, weighted{tag, q})
+		}
+	}
+
+	sort.SliceStable(langs, func(i, j int) bool {
+		return langs[i].q > langs[j].q
+	})
+	tags := make([]string, len(langs))
+	for i, l := range langs {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// errorPage returns the template for a status: its own page, its class page,
+// then the router-wide override for the format
+func (r *Router) errorPage(format ErrorFormat, code int) (errorTemplate, bool) {
+	if format == ErrorHTML && r.errorPages != nil {
+		status := strconv.Itoa(code)
+		if tmpl, ok := r.errorPages[status]; ok {
+			return tmpl, true
+		}
+		if tmpl, ok := r.errorPages[status[:1]+"xx"]; ok {
+			return tmpl, true
+		}
+	}
+	tmpl, ok := r.errorTemplates[format]
+	return tmpl, ok
+}
+
+// writeError fills the writer with the default body for an error status
+// The representation is negotiated from the request Accept header and the
+// message language from Accept-Language; req may be nil when the request
+// could not be parsed, in which case English plain text is used
+func writeError(rt *Router, req *Request, w *Writer, code int) {
+	var accept, path, requestID string
+	if req != nil {
+		accept = req.Headers.Get("Accept")
+		path = req.path
+		requestID = req.Headers.Get("X-Request-Id")
+	}
+	if requestID == "" {
+		requestID = w.Headers.Get("X-Request-Id")
+	}
+
+	format := negotiateErrorFormat(accept)
+	data := ErrorData{
+		Status:    code,
+		Message:   strings.ToLower(http.StatusText(code)),
+		Path:      path,
+		RequestID: requestID,
+	}
+	localized := rt != nil && req != nil && rt.errorMessages != nil
+	if localized {
+		if msg, lang, ok := localizedMessage(rt.errorMessages, req.Headers.Get("Accept-Language"), code); ok {
+			data.Message, data.Lang = msg, lang
+		}
+	}
+
+	// Templates render into a local buffer: the body is synthesized by the
+	// framework, so it bypasses the Writer locking and response limits
+	var buf bytes.Buffer
+	rendered := false
+	if rt != nil {
+		if tmpl, ok := rt.errorPage(format, code); ok {
+			rendered = tmpl.Execute(&buf, data) == nil
+		}
+	}
+
+	if !rendered {
+		buf.Reset()
+		switch format {
+		case ErrorJSON:
+			b, _ := json.Marshal(struct {
+				Error  string `json:"error"`
+				Status int    `json:"status"`
+			}{data.Message, data.Status})
+			buf.Write(b)
+		case ErrorHTML:
+			defaultErrorPage.Execute(&buf, data)
+		default:
+			buf.WriteString(data.Message)
+		}
+	}
+
+	w.code = code
+	w.body = append(w.body[:0], buf.Bytes()...)
+	w.Headers.Add("Content-Type", errorContentTypes[format])
+	w.Headers.Add("Content-Length", strconv.Itoa(len(w.body)))
+	// English bodies depend on Accept-Language too once a catalog is set
+	if localized {
+		w.Headers.Add("Vary", "Accept-Language")
+	}
+	if data.Lang != "" {
+		w.Headers.Add("Content-Language", data.Lang)
+	}
+}
+
+// negotiateErrorFormat picks the error representation preferred by an Accept header
+// Ties (including "*/*" and a missing header) resolve to plain text
+func negotiateErrorFormat(accept string) ErrorFormat {
+	if accept == "" {
+		return ErrorText
+	}
+
+	candidates := []struct {
+		format    ErrorFormat
+		mediaType string
+	}{
+		{ErrorText, "text/plain"},
+		{ErrorJSON, "application/json"},
+		{ErrorHTML, "text/html"},
+	}
+
+	best, bestQ := ErrorText, 0.0
+	for _, c := range candidates {
+		q := acceptQuality(accept, c.mediaType)
+		if q > bestQ {
+			best, bestQ = c.format, q
+		}
+	}
+
+	return best
+}
+
+// acceptQuality returns the q-value an Accept header assigns to a media type
+// The most specific matching range wins, as described in RFC 7231 section 5.3.2
+func acceptQuality(accept, mediaType string) float64 {
+	mainType, _, _ := strings.Cut(mediaType, "/")
+
+	quality, specificity := 0.0, -1
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		rng := strings.ToLower(strings.TrimSpace(fields[0]))
+
+		var spec int
+		switch {
+		case rng == mediaType:
+			spec = 2
+		case rng == mainType+"/*":
+			spec = 1
+		case rng == "*/*":
+			spec = 0
+		default:
+			continue
+		}
+
+		if spec < specificity {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(key) == "q" {
+				if v, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		quality, specificity = q, spec
+	}
+
+	return quality
+}
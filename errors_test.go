@@ -0,0 +1,215 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// errorRouter serves /ok for GET only and /panic, which panics
+func errorRouter(t *testing.T) (*Router, string) {
+	r := newTestRouter()
+	r.Get("/ok", func(req *Request, w *Writer) {
+		w.Write([]byte("ok"))
+	})
+	r.Get("/panic", func(req *Request, w *Writer) {
+		panic("boom")
+	})
+	return r, serveRouter(t, r)
+}
+
+// getError requests path with an Accept header and checks the
+// representation headers shared by every format
+func getError(t *testing.T, addr, method, path, accept string) (*http.Response, string) {
+	t.Helper()
+	raw := method + " " + path + " HTTP/1.1\r\nHost: x\r\n"
+	if accept != "" {
+		raw += "Accept: " + accept + "\r\n"
+	}
+	resp := rawExchange(t, addr, raw+"\r\n", 1)[0]
+	body := bodyString(t, resp)
+	if got := resp.Header.Get("Content-Length"); got != strconv.Itoa(len(body)) {
+		t.Errorf("%s %s: Content-Length = %s, body has %d bytes", method, path, got, len(body))
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasSuffix(ct, "; charset=utf-8") {
+		t.Errorf("%s %s: Content-Type = %q, want a utf-8 charset", method, path, ct)
+	}
+	return resp, body
+}
+
+func TestErrorBodyNegotiation(t *testing.T) {
+	_, addr := errorRouter(t)
+
+	cases := []struct {
+		method, path string
+		code         int
+		message      string
+	}{
+		{"GET", "/missing", 404, "not found"},
+		{"POST", "/ok", 405, "method not allowed"},
+		{"GET", "/panic", 500, "internal server error"},
+	}
+	for _, c := range cases {
+		resp, body := getError(t, addr, c.method, c.path, "application/json")
+		if resp.StatusCode != c.code || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			t.Errorf("%s %s JSON: %d %q", c.method, c.path, resp.StatusCode, resp.Header.Get("Content-Type"))
+		}
+		var got struct {
+			Error  string `json:"error"`
+			Status int    `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(body), &got); err != nil || got.Error != c.message || got.Status != c.code {
+			t.Errorf("%s %s JSON body = %q, want error %q status %d", c.method, c.path, body, c.message, c.code)
+		}
+
+		resp, body = getError(t, addr, c.method, c.path, "text/html,application/xhtml+xml;q=0.9")
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") ||
+			!strings.HasPrefix(body, "<!DOCTYPE html>") || !strings.Contains(body, "<p>"+c.message+"</p>") {
+			t.Errorf("%s %s HTML: %q %q", c.method, c.path, resp.Header.Get("Content-Type"), body)
+		}
+
+		for _, accept := range []string{"", "*/*", "text/plain", "image/png"} {
+			resp, body = getError(t, addr, c.method, c.path, accept)
+			if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") || body != c.message {
+				t.Errorf("%s %s Accept %q: %q %q, want plain %q", c.method, c.path, accept, resp.Header.Get("Content-Type"), body, c.message)
+			}
+		}
+	}
+}
+
+func TestErrorBodyParseFailure(t *testing.T) {
+	_, addr := errorRouter(t)
+
+	resp := rawExchange(t, addr, "GET /ok HTTP/1.1\r\nHost: x\r\nBad Header\r\nAccept: application/json\r\n\r\n", 1)[0]
+	body := bodyString(t, resp)
+	if resp.StatusCode != http.StatusBadRequest || body != "bad request" {
+		t.Errorf("malformed request = %d %q, want plain 400 text", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Length"); got != strconv.Itoa(len(body)) {
+		t.Errorf("Content-Length = %s, body has %d bytes", got, len(body))
+	}
+}
+
+func TestErrorTemplateOverride(t *testing.T) {
+	r, addr := errorRouter(t)
+	if err := r.ErrorTemplate(ErrorJSON, `{"code":{{.Status}},"path":"{{.Path}}"}`); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ErrorTemplate(ErrorHTML, `<b>{{.Message}} ü</b>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.ErrorTemplate("xml", ""); err == nil {
+		t.Error("ErrorTemplate accepted an unknown format")
+	}
+
+	if _, body := getError(t, addr, "GET", "/missing", "application/json"); body != `{"code":404,"path":"/missing"}` {
+		t.Errorf("JSON override = %q", body)
+	}
+	// Content-Length counts bytes, not runes
+	if _, body := getError(t, addr, "GET", "/missing", "text/html"); body != "<b>not found ü</b>" {
+		t.Errorf("HTML override = %q", body)
+	}
+	if _, body := getError(t, addr, "GET", "/missing", "text/plain"); body != "not found" {
+		t.Errorf("text without override = %q", body)
+	}
+}
+
+func TestNegotiateErrorFormat(t *testing.T) {
+	tests := map[string]ErrorFormat{
+		"":                                  ErrorText,
+		"*/*":                               ErrorText,
+		"application/json":                  ErrorJSON,
+		"application/*":                     ErrorJSON,
+		"text/html, */*;q=0.8":              ErrorHTML,
+		"text/*":                            ErrorText,
+		"application/json;q=0.5, text/html": ErrorHTML,
+		"text/html;q=0, application/json":   ErrorJSON,
+		"image/webp":                        ErrorText,
+	}
+	for accept, want := range tests {
+		if got := negotiateErrorFormat(accept); got != want {
+			t.Errorf("negotiateErrorFormat(%q) = %s, want %s", accept, got, want)
+		}
+	}
+}
+
+func TestLocalizedErrorMessages(t *testing.T) {
+	r, addr := errorRouter(t)
+	r.ErrorMessages(map[string]map[int]string{
+		"pt-BR": {404: "não encontrado"},
+		"pt":    {405: "método não permitido"},
+		"es":    {404: "no encontrado"},
+	})
+
+	get := func(method, path, accept, lang string) (*http.Response, string) {
+		raw := method + " " + path + " HTTP/1.1\r\nHost: x\r\nAccept: " + accept + "\r\n"
+		if lang != "" {
+			raw += "Accept-Language: " + lang + "\r\n"
+		}
+		resp := rawExchange(t, addr, raw+"\r\n", 1)[0]
+		return resp, bodyString(t, resp)
+	}
+
+	cases := []struct {
+		method, path, accept, lang string
+		want, contentLanguage      string
+	}{
+		{"GET", "/missing", "text/plain", "pt-BR", "não encontrado", "pt-BR"},
+		{"GET", "/missing", "application/json", "pt-br,en;q=0.8", `{"error":"não encontrado","status":404}`, "pt-BR"},
+		{"POST", "/ok", "text/plain", "pt-BR", "método não permitido", "pt"},
+		{"GET", "/missing", "text/plain", "en;q=0.5, es", "no encontrado", "es"},
+		{"GET", "/missing", "text/plain", "es;q=0, de", "not found", ""},
+		{"GET", "/missing", "text/plain", "", "not found", ""},
+		{"GET", "/panic", "text/plain", "pt-BR", "internal server error", ""},
+	}
+	for _, c := range cases {
+		resp, body := get(c.method, c.path, c.accept, c.lang)
+		if body != c.want {
+			t.Errorf("%s %s in %q = %q, want %q", c.method, c.path, c.lang, body, c.want)
+		}
+		if got := resp.Header.Get("Content-Language"); got != c.contentLanguage {
+			t.Errorf("%s %s in %q: Content-Language = %q, want %q", c.method, c.path, c.lang, got, c.contentLanguage)
+		}
+		// English answers vary too, or caches would serve them to pt-BR clients
+		if !strings.Contains(resp.Header.Get("Vary"), "Accept-Language") {
+			t.Errorf("%s %s in %q: Vary = %q, want Accept-Language", c.method, c.path, c.lang, resp.Header.Get("Vary"))
+		}
+	}
+}
+
+func TestErrorPageTemplates(t *testing.T) {
+	r, addr := errorRouter(t)
+	r.ErrorMessages(map[string]map[int]string{"pt-BR": {404: "não encontrado"}})
+	err := r.ErrorPageTemplates(fstest.MapFS{
+		"404.html":   {Data: []byte(`<p lang="{{.Lang}}">{{.Status}} {{.Message}} {{.Path}} {{.RequestID}}</p>`)},
+		"5xx.html":   {Data: []byte(`<p>server {{.Status}}</p>`)},
+		"notes.html": {Data: []byte(`{{ broken`)},
+	})
+	if err != nil {
+		t.Fatalf("ErrorPageTemplates: %v", err)
+	}
+
+	raw := "GET /missing HTTP/1.1\r\nHost: x\r\nAccept: text/html\r\nAccept-Language: pt-BR\r\nX-Request-Id: req-9\r\n\r\n"
+	resp := rawExchange(t, addr, raw, 1)[0]
+	if body := bodyString(t, resp); body != `<p lang="pt-BR">404 não encontrado /missing req-9</p>` {
+		t.Errorf("status page = %q", body)
+	}
+	if _, body := getError(t, addr, "GET", "/panic", "text/html"); body != "<p>server 500</p>" {
+		t.Errorf("class page = %q", body)
+	}
+	// No 405 or 4xx page: the built-in page is used
+	if _, body := getError(t, addr, "POST", "/ok", "text/html"); !strings.Contains(body, "method not allowed") || !strings.HasPrefix(body, "<!DOCTYPE html>") {
+		t.Errorf("fallback page = %q", body)
+	}
+	// Pages only apply to HTML
+	if _, body := getError(t, addr, "GET", "/missing", "text/plain"); body != "not found" {
+		t.Errorf("plain text with pages = %q", body)
+	}
+
+	if err := r.ErrorPageTemplates(fstest.MapFS{"404.html": {Data: []byte(`{{ broken`)}}); err == nil {
+		t.Error("ErrorPageTemplates accepted a broken page")
+	}
+}
@@ -0,0 +1,58 @@
+package gouter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// jsonBufferPool recycles the buffers used to encode ETag-tracked JSON
+var jsonBufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// JSONWithETag encodes v as JSON and answers conditional requests
+// Args:
+//   - r: Request whose If-None-Match is evaluated
+//   - code: Status of the full response; only 2xx responses are revalidated
+//   - v: Data structure to serialize
+//   - maxAge: Cache-Control max-age, 0 sends "no-cache" (always revalidate)
+//
+// The weak ETag is a hash of the encoded JSON, so it identifies the
+// uncompressed representation regardless of any later content coding.
+// A matching If-None-Match answers 304 without a body
+// Returns error if serialization or writing fails
+func (w *Writer) JSONWithETag(r *Request, code int, v any, maxAge time.Duration) error {
+	buf := jsonBufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer jsonBufferPool.Put(buf)
+
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:8]) + `"`
+
+	w.Headers.Add("ETag", etag)
+	if maxAge > 0 {
+		w.Headers.Add("Cache-Control", "max-age="+strconv.Itoa(int(maxAge/time.Second)))
+	} else {
+		w.Headers.Add("Cache-Control", "no-cache")
+	}
+
+	if code >= 200 && code < 300 && etagMatches(r.Headers.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	w.Headers.Add("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
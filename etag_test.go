@@ -0,0 +1,81 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSONWithETag(t *testing.T) {
+	type item struct {
+		ID   int    `json:"id"`
+		Name string `json:"name"`
+	}
+	current := item{1, "first"}
+
+	r := newTestRouter()
+	r.Get("/item", func(req *Request, w *Writer) {
+		w.JSONWithETag(req, http.StatusOK, current, 0)
+	})
+	r.Get("/cached", func(req *Request, w *Writer) {
+		w.JSONWithETag(req, http.StatusOK, current, time.Minute)
+	})
+	r.Get("/missing", func(req *Request, w *Writer) {
+		w.JSONWithETag(req, http.StatusNotFound, current, 0)
+	})
+	addr := serveRouter(t, r)
+
+	get := func(path, ifNoneMatch string) *http.Response {
+		raw := "GET " + path + " HTTP/1.1\r\nHost: x\r\n"
+		if ifNoneMatch != "" {
+			raw += "If-None-Match: " + ifNoneMatch + "\r\n"
+		}
+		return rawExchange(t, addr, raw+"\r\n", 1)[0]
+	}
+
+	first := get("/item", "")
+	etag := first.Header.Get("ETag")
+	var got item
+	if err := json.Unmarshal([]byte(bodyString(t, first)), &got); err != nil || got != current {
+		t.Fatalf("body decodes to %+v (%v), want %+v", got, err, current)
+	}
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Errorf("ETag = %q, want a weak validator", etag)
+	}
+	if cc := first.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Errorf("Cache-Control = %q, want no-cache without maxAge", cc)
+	}
+	if ct := first.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	for _, header := range []string{etag, strings.TrimPrefix(etag, "W/"), `"other", ` + etag, "*"} {
+		resp := get("/item", header)
+		if body := bodyString(t, resp); resp.StatusCode != http.StatusNotModified || body != "" {
+			t.Errorf("If-None-Match %s: %d with %q, want 304 without a body", header, resp.StatusCode, body)
+		}
+		if resp.Header.Get("ETag") != etag {
+			t.Errorf("If-None-Match %s: 304 ETag = %q, want %q", header, resp.Header.Get("ETag"), etag)
+		}
+	}
+
+	current.Name = "renamed"
+	changed := get("/item", etag)
+	if changed.StatusCode != http.StatusOK || bodyString(t, changed) == "" {
+		t.Errorf("stale ETag after the change: %d, want the full 200", changed.StatusCode)
+	}
+	if changed.Header.Get("ETag") == etag {
+		t.Error("ETag did not change with the struct")
+	}
+
+	if cc := get("/cached", "").Header.Get("Cache-Control"); cc != "max-age=60" {
+		t.Errorf("Cache-Control = %q, want max-age=60", cc)
+	}
+
+	missing := get("/missing", "*")
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("non-2xx with If-None-Match: %d, want the 404 unchanged", missing.StatusCode)
+	}
+}
@@ -0,0 +1,165 @@
+package gouter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ExampleRequest is the request half of a route example
+type ExampleRequest struct {
+	Path    string            // Concrete path to request, defaults to the route path
+	Headers map[string]string // Request headers
+	Body    string            // Request body
+}
+
+// ExampleResponse is what a route example expects back
+type ExampleResponse struct {
+	Status      int      // Expected status code, 0 to skip the check
+	Headers     []string // Headers that must be present
+	BodyPattern string   // Regular expression the body must match, empty to skip
+}
+
+// RouteExample is a named request and its expected response
+type RouteExample struct {
+	Name     string
+	Request  ExampleRequest
+	Response ExampleResponse
+
+	pattern *regexp.Regexp // Compiled BodyPattern
+	err     error          // BodyPattern compile error, reported by RunExamples
+}
+
+// ExampleResult is the outcome of one route example
+type ExampleResult struct {
+	Method   string   // Route method
+	Route    string   // Route path pattern
+	Name     string   // Example name
+	Failures []string // Mismatches, empty when the example passed
+}
+
+// Passed reports whether the response matched every expectation
+func (e ExampleResult) Passed() bool {
+	return len(e.Failures) == 0
+}
+
+// Example attaches a sample request and its expected response to the route
+// Examples are shown in the docs and checked by Router.RunExamples
+func (r *RouteInfo) Example(name string, req ExampleRequest, resp ExampleResponse) *RouteInfo {
+	ex := RouteExample{Name: name, Request: req, Response: resp}
+	if resp.BodyPattern != "" {
+		ex.pattern, ex.err = regexp.Compile(resp.BodyPattern)
+	}
+	r.Examples = append(r.Examples, ex)
+	return r
+}
+
+// RunExamples sends every route example through an in-memory connection
+// and compares the responses with the expectations
+// Returns the results in route order and an error when any example failed
+func (r *Router) RunExamples() ([]ExampleResult, error) {
+	var (
+		results []ExampleResult
+		failed  int
+	)
+
+	for _, doc := range r.docs {
+		for _, ex := range doc.Examples {
+			result := ExampleResult{Method: doc.Method, Route: doc.Path, Name: ex.Name}
+			result.Failures = r.runExample(doc, ex)
+			if !result.Passed() {
+				failed++
+			}
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Route < results[j].Route
+	})
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d examples failed", failed, len(results))
+	}
+	return results, nil
+}
+
+// runExample executes one example and lists its mismatches
+func (r *Router) runExample(doc *RouteInfo, ex RouteExample) []string {
+	if ex.err != nil {
+		return []string{"invalid body pattern: " + ex.err.Error()}
+	}
+
+	path := ex.Request.Path
+	if path == "" {
+		path = doc.Path
+	}
+	headers := ex.Request.Headers
+	if doc.Host != "" && !strings.Contains(doc.Host, ":") {
+		headers = withHost(headers, doc.Host)
+	}
+
+	resp, body, err := r.record(buildRequest(doc.Method, path, headers, []byte(ex.Request.Body)))
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var failures []string
+	if want := ex.Response.Status; want != 0 && resp.StatusCode != want {
+		failures = append(failures, "status "+strconv.Itoa(resp.StatusCode)+", want "+strconv.Itoa(want))
+	}
+	for _, h := range ex.Response.Headers {
+		if resp.Header.Get(h) == "" {
+			failures = append(failures, "missing header "+h)
+		}
+	}
+	if ex.pattern != nil && !ex.pattern.Match(body) {
+		failures = append(failures, "body does not match "+ex.Response.BodyPattern)
+	}
+	return failures
+}
+
+// withHost returns a copy of headers carrying a Host header
+func withHost(headers map[string]string, host string) map[string]string {
+	out := map[string]string{"Host": host}
+	for k, v := range headers {
+		out[k] = v
+	}
+	return out
+}
+
+// exampleCurl renders an example as a curl command for the docs
+func exampleCurl(base, method, routePath string, ex RouteExample) string {
+	path := ex.Request.Path
+	if path == "" {
+		path = routePath
+	}
+
+	var b strings.Builder
+	b.WriteString("curl -X " + method + " '" + base + path + "'")
+
+	keys := make([]string, 0, len(ex.Request.Headers))
+	for k := range ex.Request.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		b.WriteString(" \\\n  -H '" + k + ": " + ex.Request.Headers[k] + "'")
+	}
+	if ex.Request.Body != "" {
+		b.WriteString(" \\\n  -d '" + strings.ReplaceAll(ex.Request.Body, "'", `'\''`) + "'")
+	}
+	return b.String()
+}
+
+// httpBase returns the HTTP base URL of the served application for the
+// docs, or "" when it is not known yet
+func (r *Router) httpBase() string {
+	base := r.wsBase()
+	if strings.HasPrefix(base, "ws") {
+		return "http" + strings.TrimPrefix(base, "ws")
+	}
+	return base
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/Murilinho145SG/gouter/examples/fullapp/client"
+)
+
+// update rewrites client/client.go from the router
+var update = flag.Bool("update", false, "regenerate client/client.go")
+
+// TestClientUpToDate checks the committed client against the router
+func TestClientUpToDate(t *testing.T) {
+	src, err := newRouter(newStore(), t.TempDir(), t.TempDir()).GenerateGoClient("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *update {
+		if err := os.WriteFile("client/client.go", src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	committed, err := os.ReadFile("client/client.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, committed) {
+		t.Errorf("client/client.go is stale, run go generate")
+	}
+}
+
+// TestClientRoundTrip drives the app through the generated client
+func TestClientRoundTrip(t *testing.T) {
+	base, _ := startApp(t)
+	c := client.NewClient(base, nil)
+	ctx := context.Background()
+
+	raw, err := c.GetHealth(ctx)
+	if err != nil || !bytes.Contains(raw, []byte(`"ok"`)) {
+		t.Fatalf("GetHealth = %s, %v", raw, err)
+	}
+
+	raw, err = c.PostAPIUsers(ctx, map[string]string{"name": "ada"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var created User
+	if err := json.Unmarshal(raw, &created); err != nil || created.Name != "ada" {
+		t.Fatalf("PostAPIUsers = %s, %v", raw, err)
+	}
+
+	raw, err = c.GetAPIUsersByID(ctx, created.ID)
+	var got User
+	if err != nil || json.Unmarshal(raw, &got) != nil || got != created {
+		t.Errorf("GetAPIUsersByID(%d) = %s, %v, want %+v", created.ID, raw, err, created)
+	}
+
+	raw, err = c.GetAPIUsers(ctx)
+	var all []User
+	if err != nil || json.Unmarshal(raw, &all) != nil || len(all) != 1 {
+		t.Errorf("GetAPIUsers = %s, %v, want one user", raw, err)
+	}
+
+	var apiErr *client.Error
+	if _, err := c.GetAPIUsersByID(ctx, 999); !errors.As(err, &apiErr) || apiErr.Status != http.StatusNotFound {
+		t.Errorf("GetAPIUsersByID(999) error = %v, want a 404 Error", err)
+	}
+	if _, err := c.PostAPIUsers(ctx, map[string]string{}); !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		t.Errorf("PostAPIUsers without a name error = %v, want a 400 Error", err)
+	}
+}
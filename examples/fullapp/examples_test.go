@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// TestExamplesBuild compiles every example program
+func TestExamplesBuild(t *testing.T) {
+	dirs, err := filepath.Glob("../*/main.go")
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("no examples found: %v", err)
+	}
+	for _, main := range dirs {
+		dir := filepath.Dir(main)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			cmd := exec.Command("go", "build", "-o", os.DevNull, ".")
+			cmd.Dir = dir
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("go build: %v\n%s", err, out)
+			}
+		})
+	}
+}
+
+// startApp serves the fullapp router on a port-0 listener until the test
+// ends, and returns its base URL and upload directory
+func startApp(t *testing.T) (string, string) {
+	t.Helper()
+	staticDir, uploadDir := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(staticDir, "hello.txt"), []byte("static hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &gouter.Server{Router: newRouter(newStore(), staticDir, uploadDir)}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(l)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := srv.Shutdown(ctx); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	})
+	return "http://" + l.Addr().String(), uploadDir
+}
+
+// call sends a request and returns the response with its body read
+func call(t *testing.T, method, url, contentType string, body io.Reader) (*http.Response, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, b
+}
+
+func TestFullApp(t *testing.T) {
+	base, uploadDir := startApp(t)
+
+	t.Run("health", func(t *testing.T) {
+		resp, body := call(t, "GET", base+"/health", "", nil)
+		if resp.StatusCode != 200 || !strings.Contains(string(body), `"ok"`) {
+			t.Fatalf("GET /health = %d %s", resp.StatusCode, body)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "100" {
+			t.Errorf("X-RateLimit-Limit = %q, want 100", resp.Header.Get("X-RateLimit-Limit"))
+		}
+	})
+
+	t.Run("cors", func(t *testing.T) {
+		req, _ := http.NewRequest("OPTIONS", base+"/api/users", nil)
+		req.Header.Set("Origin", "https://example.com")
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.Header.Get("Access-Control-Allow-Origin") == "" {
+			t.Errorf("preflight without Access-Control-Allow-Origin: %v", resp.Header)
+		}
+	})
+
+	t.Run("users", func(t *testing.T) {
+		resp, body := call(t, "POST", base+"/api/users", "application/json", strings.NewReader(`{"name":"ada"}`))
+		if resp.StatusCode != 201 {
+			t.Fatalf("POST /api/users = %d %s", resp.StatusCode, body)
+		}
+		var created User
+		if err := json.Unmarshal(body, &created); err != nil || created.Name != "ada" {
+			t.Fatalf("created user = %s (%v)", body, err)
+		}
+
+		if resp, body := call(t, "POST", base+"/api/users", "application/json", strings.NewReader(`{}`)); resp.StatusCode != 400 {
+			t.Errorf("POST invalid user = %d %s, want 400", resp.StatusCode, body)
+		}
+
+		_, body = call(t, "GET", base+"/api/users", "", nil)
+		var users []User
+		if err := json.Unmarshal(body, &users); err != nil || len(users) != 1 || users[0] != created {
+			t.Errorf("GET /api/users = %s (%v)", body, err)
+		}
+
+		_, body = call(t, "GET", base+"/api/users/1", "", nil)
+		var got User
+		if err := json.Unmarshal(body, &got); err != nil || got != created {
+			t.Errorf("GET /api/users/1 = %s (%v)", body, err)
+		}
+		for path, code := range map[string]int{"/api/users/99": 404, "/api/users/x": 400} {
+			if resp, _ := call(t, "GET", base+path, "", nil); resp.StatusCode != code {
+				t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, code)
+			}
+		}
+	})
+
+	t.Run("upload", func(t *testing.T) {
+		var buf bytes.Buffer
+		mw := multipart.NewWriter(&buf)
+		mw.WriteField("title", "notes")
+		fw, _ := mw.CreateFormFile("file", "notes.txt")
+		fw.Write([]byte("uploaded content"))
+		mw.Close()
+
+		resp, body := call(t, "POST", base+"/api/upload", mw.FormDataContentType(), &buf)
+		if resp.StatusCode != 201 || !strings.Contains(string(body), `"notes"`) {
+			t.Fatalf("POST /api/upload = %d %s", resp.StatusCode, body)
+		}
+		saved, err := os.ReadFile(filepath.Join(uploadDir, "notes.txt"))
+		if err != nil || string(saved) != "uploaded content" {
+			t.Errorf("saved file = %q (%v)", saved, err)
+		}
+	})
+
+	t.Run("static", func(t *testing.T) {
+		resp, body := call(t, "GET", base+"/static/hello.txt", "", nil)
+		if resp.StatusCode != 200 || string(body) != "static hello" {
+			t.Errorf("GET /static/hello.txt = %d %q", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("docs", func(t *testing.T) {
+		resp, body := call(t, "GET", base+"/docs/openapi.json", "", nil)
+		if resp.StatusCode != 200 || !strings.Contains(string(body), "/api/users") {
+			t.Errorf("GET /docs/openapi.json = %d %.200s", resp.StatusCode, body)
+		}
+	})
+
+	t.Run("websocket", func(t *testing.T) {
+		if got := wsEcho(t, strings.TrimPrefix(base, "http://"), "ping"); got != "ping" {
+			t.Errorf("websocket echo = %q, want %q", got, "ping")
+		}
+	})
+}
+
+// wsEcho opens a websocket to /ws, sends msg as a text frame and returns
+// the payload of the first frame received
+func wsEcho(t *testing.T, addr, msg string) string {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+
+	io.WriteString(c, "GET /ws HTTP/1.1\r\nHost: "+addr+"\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("upgrade status = %d", resp.StatusCode)
+	}
+
+	// Client frames are masked; a zero mask keeps the payload as is
+	frame := append([]byte{0x81, 0x80 | byte(len(msg)), 0, 0, 0, 0}, msg...)
+	if _, err := c.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(br, head); err != nil {
+		t.Fatal(err)
+	}
+	payload := make([]byte, head[1]&0x7f)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return string(payload)
+}
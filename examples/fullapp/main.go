@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+	"github.com/Murilinho145SG/gouter/log"
+	"github.com/Murilinho145SG/gouter/ratelimit"
+)
+
+// User is the resource managed by the REST endpoints
+type User struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// UploadForm binds the multipart fields of the upload endpoint
+type UploadForm struct {
+	Title string             `gouter:"title"`
+	File  *gouter.FileUpload `gouter:"file"`
+}
+
+// store keeps users in memory for the example
+type store struct {
+	mu     sync.Mutex
+	users  map[int]User
+	nextID int
+}
+
+func newStore() *store {
+	return &store{users: make(map[int]User), nextID: 1}
+}
+
+func (s *store) add(name string) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u := User{ID: s.nextID, Name: name}
+	s.users[u.ID] = u
+	s.nextID++
+	return u
+}
+
+func (s *store) get(id int) (User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	return u, ok
+}
+
+func (s *store) list() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]User, 0, len(s.users))
+	for i := 1; i < s.nextID; i++ {
+		if u, ok := s.users[i]; ok {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// newRouter wires every demonstrated feature into a single router
+// The client package is generated from it:
+//
+//go:generate go test -run TestClientUpToDate -update .
+func newRouter(s *store, staticDir, uploadDir string) *gouter.Router {
+	r := gouter.NewRouter()
+	r.MountDocs("/docs")
+
+	r.Use(gouter.CORS(gouter.CORSConfig{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         10 * time.Minute,
+	}))
+	r.Use(ratelimit.Middleware(ratelimit.Config{Rate: ratelimit.Rate{Requests: 100, Per: time.Minute}}))
+	r.Use(gouter.Logger())
+
+	r.Route("/health", func(r *gouter.Request, w *gouter.Writer) {
+		w.WriteJson(map[string]string{"status": "ok"})
+	}).SetDescription("Liveness probe")
+
+	r.Group("/api", func(g *gouter.Group) {
+		g.Route("/users", func(r *gouter.Request, w *gouter.Writer) {
+			if r.Method == "POST" {
+				var body struct {
+					Name string `json:"name"`
+				}
+				if err := r.ReadJson(&body); err != nil || body.Name == "" {
+					gouter.Error(w, errors.New("invalid user payload"), 400)
+					return
+				}
+				w.WriteHeader(201)
+				w.WriteJson(s.add(body.Name))
+				return
+			}
+			w.WriteJson(s.list())
+		}, "GET", "POST").SetDescription("List users (GET) or create one (POST)")
+
+		g.Route("/users/:id", func(r *gouter.Request, w *gouter.Writer) {
+			id, err := strconv.Atoi(r.Params.Get("id"))
+			if err != nil {
+				gouter.Error(w, errors.New("invalid id"), 400)
+				return
+			}
+			u, ok := s.get(id)
+			if !ok {
+				gouter.Error(w, errors.New("user not found"), 404)
+				return
+			}
+			w.WriteJson(u)
+		}).SetDescription("Get a single user").SetParam("id", "int", "User identifier")
+
+		g.Route("/upload", func(r *gouter.Request, w *gouter.Writer) {
+			var form UploadForm
+			if err := r.ParseMultipart(&form); err != nil {
+				gouter.Error(w, err, 400)
+				return
+			}
+			if form.File == nil {
+				gouter.Error(w, errors.New("missing file"), 400)
+				return
+			}
+
+			f, err := form.File.Save(filepath.Join(uploadDir, filepath.Base(form.File.Filename)))
+			if err != nil {
+				gouter.Error(w, err, 500)
+				return
+			}
+			defer f.Close()
+
+			w.WriteHeader(201)
+			w.WriteJson(map[string]string{"title": form.Title, "file": form.File.Filename})
+		}, "POST").SetDescription("Upload a file with a title")
+	})
+
+	r.WebSocket("/ws", func(ws *gouter.WebSocket, r *gouter.Request) {
+		for {
+			msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			if msg == nil {
+				continue
+			}
+			if err := ws.WriteMessage(msg); err != nil {
+				return
+			}
+		}
+	}, gouter.WebSocketConfig{}).SetDescription("Websocket echo")
+
+	gouter.ServerStatic(r, "/static", staticDir)
+
+	return r
+}
+
+func main() {
+	staticDir := envOr("STATIC_DIR", "./public")
+	uploadDir := envOr("UPLOAD_DIR", os.TempDir())
+
+	srv := &gouter.Server{Router: newRouter(newStore(), staticDir, uploadDir)}
+	l, err := net.Listen("tcp", envOr("ADDR", "0.0.0.0:8080"))
+	if err != nil {
+		panic(err)
+	}
+	log.System("Listening on " + l.Addr().String() + ", docs at /docs/")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		// Stop accepting connections and let running requests finish
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := srv.Shutdown(shutdownCtx); err != nil {
+			log.System("Shutdown timed out with requests still running")
+		}
+	}()
+
+	// Serve returns once the listener is closed, Shutdown once the
+	// requests are done
+	if err := srv.Serve(l); err != nil && !errors.Is(err, net.ErrClosed) {
+		panic(err)
+	}
+	<-drained
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+func main() {
+	r := gouter.NewRouter()
+	events := make(chan string)
+
+	go func() {
+		for i := 0; ; i++ {
+			time.Sleep(5 * time.Second)
+			events <- fmt.Sprintf("event %d", i)
+		}
+	}()
+
+	// Long poll: wait for the next event, giving up when the client leaves
+	r.Route("/poll", func(r *gouter.Request, w *gouter.Writer) {
+		select {
+		case e := <-events:
+			w.Write([]byte(e))
+		case <-w.CloseNotify():
+		case <-time.After(30 * time.Second):
+			w.WriteHeader(204)
+		}
+	})
+
+	// Server-sent events: stream until the client disconnects
+	r.Route("/events", func(r *gouter.Request, w *gouter.Writer) {
+		w.Headers.Add("Content-Type", "text/event-stream")
+		w.Headers.Add("Cache-Control", "no-cache")
+		if err := w.WriteHeaders(); err != nil {
+			return
+		}
+
+		closed := w.CloseNotify()
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-closed:
+				return
+			case t := <-ticker.C:
+				fmt.Fprintf(w, "data: %s\n\n", t.Format(time.RFC3339))
+			}
+		}
+	})
+
+	if err := gouter.Run("0.0.0.0:8080", r); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,79 @@
+package gouter
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRunExamples(t *testing.T) {
+	r := newTestRouter()
+	r.Post("/users", func(req *Request, w *Writer) {
+		w.Headers.Add("Location", "/users/1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":1,"name":"ana"}`))
+	}).Example("create basic user", ExampleRequest{
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"name":"ana"}`,
+	}, ExampleResponse{Status: 201, Headers: []string{"Location"}, BodyPattern: `"id":`}).
+		Example("outdated contract", ExampleRequest{Body: `{"name":"ana"}`},
+			ExampleResponse{Status: 200, Headers: []string{"X-Total"}, BodyPattern: `"email":`})
+	r.Get("/users/:id", func(req *Request, w *Writer) {
+		w.Write([]byte(`{"id":` + req.Params.Get("id") + `}`))
+	}).Example("fetch user", ExampleRequest{Path: "/users/7"}, ExampleResponse{Status: 200, BodyPattern: `"id":7`}).
+		Example("broken pattern", ExampleRequest{Path: "/users/7"}, ExampleResponse{BodyPattern: `(`})
+	r.HostRoute("api.example.com", "/status", func(req *Request, w *Writer) {
+		w.Write([]byte("api"))
+	}).Example("host scoped", ExampleRequest{}, ExampleResponse{Status: 200, BodyPattern: "^api$"})
+
+	results, err := r.RunExamples()
+	if err == nil || !strings.Contains(err.Error(), "2 of 5 examples failed") {
+		t.Errorf("RunExamples error = %v, want 2 of 5 failed", err)
+	}
+
+	byName := make(map[string]ExampleResult)
+	for _, res := range results {
+		byName[res.Name] = res
+	}
+	for _, name := range []string{"create basic user", "fetch user", "host scoped"} {
+		if res, ok := byName[name]; !ok || !res.Passed() {
+			t.Errorf("%q = %+v, want passed", name, res)
+		}
+	}
+
+	outdated := byName["outdated contract"]
+	want := []string{"status 201, want 200", "missing header X-Total", `body does not match "email":`}
+	if strings.Join(outdated.Failures, "|") != strings.Join(want, "|") {
+		t.Errorf("outdated contract failures = %q, want %q", outdated.Failures, want)
+	}
+	if outdated.Method != "POST" || outdated.Route != "/users" {
+		t.Errorf("result names %s %s, want POST /users", outdated.Method, outdated.Route)
+	}
+	if f := byName["broken pattern"].Failures; len(f) != 1 || !strings.HasPrefix(f[0], "invalid body pattern") {
+		t.Errorf("broken pattern failures = %q, want the compile error", f)
+	}
+
+	if results[0].Route > results[len(results)-1].Route {
+		t.Error("results are not in route order")
+	}
+
+	rec := &Writer{Headers: make(Headers)}
+	r.serveDocs(&Request{Headers: make(Headers)}, "/", rec)
+	page := string(rec.body)
+	for _, want := range []string{"create basic user", "expects 201", "curl -X POST", "-H &#39;Content-Type: application/json&#39;", `-d &#39;{&#34;name&#34;:&#34;ana&#34;}&#39;`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("docs page lacks %q", want)
+		}
+	}
+}
+
+func TestRunExamplesAllPassing(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/ping", func(req *Request, w *Writer) { w.Write([]byte("pong")) }).
+		Example("ping", ExampleRequest{}, ExampleResponse{Status: 200, BodyPattern: "pong"})
+
+	results, err := r.RunExamples()
+	if err != nil || len(results) != 1 || !results[0].Passed() {
+		t.Errorf("RunExamples = %+v, %v, want one passing example", results, err)
+	}
+}
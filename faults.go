@@ -0,0 +1,243 @@
+package gouter
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// FaultsEnv is the environment variable that must be "1" for
+// Router.FaultInjector to enable anything
+const FaultsEnv = "GOUTER_FAULTS"
+
+// ErrFaultsDisabled is returned by Router.FaultInjector without both opt-ins
+var ErrFaultsDisabled = errors.New("fault injection needs FaultConfig.Enable and " + FaultsEnv + "=1")
+
+// FaultConfig configures a FaultInjector
+// Rates are probabilities between 0 and 1, drawn independently per request
+type FaultConfig struct {
+	Enable bool // Explicit opt-in; the FaultsEnv variable must be set as well
+
+	Routes             []string      // Route patterns affected, or path prefixes ending in "*"; empty for all
+	ErrorRate          float64       // Requests answered Status without running the handler
+	Status             int           // Synthetic error status (default: 503)
+	LatencyJitter      time.Duration // Requests are delayed by a random time up to this
+	DropConnectionRate float64       // Responses cut halfway through by closing the connection
+
+	// AdminPath registers AdminHandler at this route, which is never
+	// faulted so the rates can always be turned down; empty for none.
+	// Only read by Router.FaultInjector
+	AdminPath string
+}
+
+// FaultCounts counts the faults injected so far
+type FaultCounts struct {
+	Delayed int64 `json:"delayed"`
+	Errors  int64 `json:"errors"`
+	Dropped int64 `json:"dropped"`
+}
+
+// FaultInjector makes matching requests slow or failing, to test how
+// clients cope. The configuration can change at runtime (see AdminHandler)
+type FaultInjector struct {
+	mu    sync.Mutex
+	cfg   FaultConfig
+	admin string // Route of AdminHandler, left out of the faults
+
+	delayed, errors, dropped atomic.Int64
+}
+
+// FaultInjector installs a fault injection middleware on the router
+// Args:
+//   - cfg: Faults to inject
+//
+// Returns ErrFaultsDisabled unless cfg.Enable is set and the FaultsEnv
+// environment variable is "1", so a config shipped by mistake does nothing
+// in production. Every injected fault is logged as a warning
+func (r *Router) FaultInjector(cfg FaultConfig) (*FaultInjector, error) {
+	if !cfg.Enable || os.Getenv(FaultsEnv) != "1" {
+		return nil, ErrFaultsDisabled
+	}
+
+	f := &FaultInjector{admin: cfg.AdminPath}
+	f.Configure(cfg)
+	r.Use(f.middleware)
+	if f.admin != "" {
+		r.Route(f.admin, f.AdminHandler)
+	}
+	log.Warn("fault injection enabled:", f.describe())
+	return f, nil
+}
+
+// Configure replaces the faults to inject; Enable and AdminPath are ignored
+func (f *FaultInjector) Configure(cfg FaultConfig) {
+	if cfg.Status == 0 {
+		cfg.Status = http.StatusServiceUnavailable
+	}
+	cfg.Enable = true
+	cfg.AdminPath = f.admin
+
+	f.mu.Lock()
+	f.cfg = cfg
+	f.mu.Unlock()
+}
+
+// Config returns the faults being injected
+func (f *FaultInjector) Config() FaultConfig {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.cfg
+}
+
+// Counts returns how many faults were injected
+func (f *FaultInjector) Counts() FaultCounts {
+	return FaultCounts{
+		Delayed: f.delayed.Load(),
+		Errors:  f.errors.Load(),
+		Dropped: f.dropped.Load(),
+	}
+}
+
+// faultState is the JSON form of the injector used by AdminHandler
+type faultState struct {
+	Routes             []string    `json:"routes"`
+	ErrorRate          float64     `json:"error_rate"`
+	Status             int         `json:"status"`
+	LatencyJitter      string      `json:"latency_jitter"`
+	DropConnectionRate float64     `json:"drop_connection_rate"`
+	Counts             FaultCounts `json:"counts"`
+}
+
+// AdminHandler answers the configuration and counts as JSON; PUT and POST
+// replace the configuration with the JSON body, in the same shape
+// It is meant for an internal route, registered with FaultConfig.AdminPath
+// so the faults spare it
+func (f *FaultInjector) AdminHandler(r *Request, w *Writer) {
+	if r.Method == "PUT" || r.Method == "POST" {
+		var s faultState
+		if err := r.ReadJson(&s); err != nil {
+			Error(w, fmt.Errorf("invalid fault config: %w", err), http.StatusBadRequest)
+			return
+		}
+		cfg := FaultConfig{Routes: s.Routes, ErrorRate: s.ErrorRate, Status: s.Status, DropConnectionRate: s.DropConnectionRate}
+		if s.LatencyJitter != "" {
+			d, err := time.ParseDuration(s.LatencyJitter)
+			if err != nil {
+				Error(w, fmt.Errorf("invalid latency_jitter: %w", err), http.StatusBadRequest)
+				return
+			}
+			cfg.LatencyJitter = d
+		}
+		f.Configure(cfg)
+		log.Warn("fault injection changed:", f.describe())
+	}
+
+	cfg := f.Config()
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteJson(faultState{
+		Routes:             cfg.Routes,
+		ErrorRate:          cfg.ErrorRate,
+		Status:             cfg.Status,
+		LatencyJitter:      cfg.LatencyJitter.String(),
+		DropConnectionRate: cfg.DropConnectionRate,
+		Counts:             f.Counts(),
+	})
+}
+
+// describe summarizes the configuration for logs
+func (f *FaultInjector) describe() string {
+	cfg := f.Config()
+	routes := "all routes"
+	if len(cfg.Routes) > 0 {
+		routes = strings.Join(cfg.Routes, ", ")
+	}
+	return fmt.Sprintf("%s: error rate %g (status %d), latency jitter %s, drop rate %g",
+		routes, cfg.ErrorRate, cfg.Status, cfg.LatencyJitter, cfg.DropConnectionRate)
+}
+
+// middleware injects the configured faults into matching requests
+func (f *FaultInjector) middleware(next Handler) Handler {
+	return func(r *Request, w *Writer) {
+		cfg := f.Config()
+		if !cfg.matches(r) || (r.route != nil && f.admin != "" && r.route.Path == f.admin) {
+			next(r, w)
+			return
+		}
+
+		if cfg.LatencyJitter > 0 {
+			d := rand.N(cfg.LatencyJitter)
+			f.delayed.Add(1)
+			log.Warn("fault injection: delaying", r.Method, r.path, "by", d)
+			<-r.Clock().After(d)
+		}
+
+		if faultHit(cfg.ErrorRate) {
+			f.errors.Add(1)
+			log.Warn("fault injection: answering", r.Method, r.path, "with", cfg.Status)
+			Error(w, errors.New("injected fault"), cfg.Status)
+			return
+		}
+
+		next(r, w)
+
+		if faultHit(cfg.DropConnectionRate) {
+			f.dropped.Add(1)
+			log.Warn("fault injection: dropping the connection of", r.Method, r.path)
+			w.drop()
+		}
+	}
+}
+
+// matches reports whether the faults apply to the request
+func (cfg *FaultConfig) matches(r *Request) bool {
+	if len(cfg.Routes) == 0 {
+		return true
+	}
+	for _, route := range cfg.Routes {
+		if prefix, ok := strings.CutSuffix(route, "*"); ok && strings.HasPrefix(r.path, prefix) {
+			return true
+		}
+		if r.route != nil && r.route.Path == route {
+			return true
+		}
+	}
+	return false
+}
+
+// faultHit draws a request with probability rate
+func faultHit(rate float64) bool {
+	return rate > 0 && rand.Float64() < rate
+}
+
+// drop sends the headers and half of the buffered body, then closes the
+// connection, so the client sees a response cut short
+// Responses without a body to cut are closed before the headers
+func (w *Writer) drop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done || w.hijacked {
+		return
+	}
+
+	if !w.headersSent && len(w.body) > 0 {
+		if w.Headers.Get("Content-Length") == "" {
+			w.Headers.Add("Content-Length", fmt.Sprint(len(w.body)))
+		}
+		if err := w.writeHeaders(); err == nil && !w.noBody {
+			w.c.Write(w.body[:len(w.body)/2])
+		}
+	}
+	if w.notifier != nil {
+		w.notifier.stop()
+	}
+	w.c.Close()
+	w.hijacked = true
+}
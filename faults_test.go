@@ -0,0 +1,193 @@
+package gouter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// delayClock records the delays waited for and returns at once
+type delayClock struct {
+	Clock
+	mu     sync.Mutex
+	delays []time.Duration
+}
+
+func (c *delayClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	c.delays = append(c.delays, d)
+	c.mu.Unlock()
+	ch := make(chan time.Time, 1)
+	ch <- c.Now()
+	return ch
+}
+
+func TestFaultInjectorOptIn(t *testing.T) {
+	cases := []struct {
+		name   string
+		env    string
+		enable bool
+	}{
+		{"no opt-in", "", false},
+		{"config only", "", true},
+		{"environment only", "1", false},
+		{"other environment value", "true", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(FaultsEnv, tc.env)
+			r := newTestRouter()
+			f, err := r.FaultInjector(FaultConfig{Enable: tc.enable, ErrorRate: 1, AdminPath: "/admin/faults"})
+			if !errors.Is(err, ErrFaultsDisabled) || f != nil {
+				t.Fatalf("FaultInjector = %v, %v; want ErrFaultsDisabled", f, err)
+			}
+			r.Get("/a", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+			addr := serveRouter(t, r)
+			if resp := rawExchange(t, addr, "GET /a HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 200 {
+				t.Errorf("GET /a = %d, want the handler answer", resp.StatusCode)
+			}
+			if resp := rawExchange(t, addr, "GET /admin/faults HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 404 {
+				t.Errorf("admin route = %d, want none registered", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestFaultInjectorModes(t *testing.T) {
+	t.Setenv(FaultsEnv, "1")
+	var f *FaultInjector
+	var calls atomic.Int32
+	clock := &delayClock{Clock: SystemClock}
+
+	out := captureServe(t, func(t *testing.T) {
+		r := newTestRouter()
+		r.SetClockSource(clock)
+		var err error
+		f, err = r.FaultInjector(FaultConfig{Enable: true, ErrorRate: 1, Status: 502, AdminPath: "/admin/faults"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := func(req *Request, w *Writer) {
+			calls.Add(1)
+			w.Write([]byte("hello world!"))
+		}
+		r.Get("/a", handler)
+		r.Get("/api/items", handler)
+		r.Get("/empty", func(req *Request, w *Writer) { calls.Add(1) })
+		addr := serveRouter(t, r)
+
+		get := func(path string) *http.Response {
+			return rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		}
+		configure := func(body string) int {
+			resp := rawExchange(t, addr, "PUT /admin/faults HTTP/1.1\r\nHost: x\r\nContent-Length: "+
+				strconv.Itoa(len(body))+"\r\n\r\n"+body, 1)[0]
+			return resp.StatusCode
+		}
+
+		// Errors: the handler does not run
+		if resp := get("/a"); resp.StatusCode != 502 || bodyString(t, resp) != "injected fault" {
+			t.Errorf("error mode = %d", resp.StatusCode)
+		}
+		if n := calls.Load(); n != 0 {
+			t.Errorf("handler ran %d times under a 100%% error rate", n)
+		}
+
+		// The admin route is spared, so the rates can be turned down
+		if code := configure(`{"latency_jitter":"40ms"}`); code != 200 {
+			t.Fatalf("admin PUT = %d under a 100%% error rate", code)
+		}
+		if resp := get("/a"); resp.StatusCode != 200 || bodyString(t, resp) != "hello world!" {
+			t.Errorf("latency mode = %d", resp.StatusCode)
+		}
+		clock.mu.Lock()
+		delays := append([]time.Duration(nil), clock.delays...)
+		clock.mu.Unlock()
+		if len(delays) != 1 || delays[0] < 0 || delays[0] >= 40*time.Millisecond {
+			t.Errorf("delays = %v, want one below the jitter", delays)
+		}
+
+		// Drops: the client reads half the declared body, then EOF
+		if code := configure(`{"drop_connection_rate":1}`); code != 200 {
+			t.Fatalf("admin PUT = %d", code)
+		}
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.SetDeadline(time.Now().Add(3 * time.Second))
+		io.WriteString(c, "GET /a HTTP/1.1\r\nHost: x\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if resp.ContentLength != 12 || string(body) != "hello " || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("dropped response = length %d, %q, %v; want 6 of 12 bytes and a cut", resp.ContentLength, body, err)
+		}
+
+		// Without a body to cut the connection closes before the headers
+		c2, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c2.Close()
+		c2.SetDeadline(time.Now().Add(3 * time.Second))
+		io.WriteString(c2, "GET /empty HTTP/1.1\r\nHost: x\r\n\r\n")
+		if resp, err := http.ReadResponse(bufio.NewReader(c2), nil); err == nil {
+			t.Errorf("dropped empty response = %d, want the connection closed", resp.StatusCode)
+		}
+
+		// Route patterns and prefixes scope the faults; the default status is 503
+		if code := configure(`{"routes":["/api*"],"error_rate":1}`); code != 200 {
+			t.Fatalf("admin PUT = %d", code)
+		}
+		if resp := get("/api/items"); resp.StatusCode != 503 {
+			t.Errorf("prefix route = %d, want 503", resp.StatusCode)
+		}
+		if resp := get("/a"); resp.StatusCode != 200 {
+			t.Errorf("unmatched route = %d, want 200", resp.StatusCode)
+		}
+		if code := configure(`{"routes":["/a"],"error_rate":1}`); code != 200 {
+			t.Fatalf("admin PUT = %d", code)
+		}
+		if resp := get("/a"); resp.StatusCode != 503 {
+			t.Errorf("pattern route = %d, want 503", resp.StatusCode)
+		}
+		if code := configure(`{"latency_jitter":"soon"}`); code != 400 {
+			t.Errorf("invalid jitter = %d, want 400", code)
+		}
+
+		var state faultState
+		if err := json.Unmarshal([]byte(bodyString(t, get("/admin/faults"))), &state); err != nil {
+			t.Fatal(err)
+		}
+		// Each PUT replaces the whole configuration, so only one request was delayed
+		want := FaultCounts{Delayed: 1, Errors: 3, Dropped: 2}
+		if state.Counts != want || f.Counts() != want || state.Status != 503 || len(state.Routes) != 1 {
+			t.Errorf("admin state = %+v, counts %+v; want counts %+v", state, f.Counts(), want)
+		}
+	})
+
+	for _, line := range []string{
+		"fault injection enabled: all routes: error rate 1 (status 502)",
+		"fault injection: answering GET /a with 502",
+		"fault injection: delaying GET /a by",
+		"fault injection: dropping the connection of GET /empty",
+		"fault injection changed: /api*: error rate 1 (status 503)",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("log lacks %q:\n%s", line, out)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package gouter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// fixtureMemoryLimit is the largest file File keeps in memory; bigger files
+// are streamed from disk on every request
+const fixtureMemoryLimit = 64 << 10
+
+// Static registers a route answering with a fixed status, content type and body
+// The response carries Content-Length and a content-based ETag, and
+// If-None-Match revalidations of 2xx responses are answered with 304
+func (r *Router) Static(path string, status int, contentType string, body []byte) *RouteInfo {
+	handler := staticHandler(status, contentType, body)
+	info := r.addRoute(path, traceLayer("handler", handler), nil, callerLocation(1), "GET", "HEAD")
+	if info == nil {
+		return nil
+	}
+	return info.SetDescription(fmt.Sprintf("Static %d %s response (%d bytes)", status, contentType, len(body)))
+}
+
+// File registers a route serving a single file
+// Files up to 64KB are read once at registration and served from memory;
+// larger ones are streamed from disk with an ETag and Last-Modified derived
+// from their size and modification time. In DevMode every file is read from
+// disk without validators
+// Returns an error if the file cannot be read at registration
+func (r *Router) File(path, filename string) (*RouteInfo, error) {
+	stat, err := os.Stat(filename)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", filename)
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	handler := fileHandler(filename, contentType)
+	if stat.Size() <= fixtureMemoryLimit {
+		body, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		// DevMode reads the file again on every request
+		memory, disk := staticHandler(http.StatusOK, contentType, body), handler
+		handler = func(r *Request, w *Writer) {
+			if w.dev {
+				disk(r, w)
+				return
+			}
+			memory(r, w)
+		}
+	}
+
+	info := r.addRoute(path, traceLayer("handler", handler), nil, callerLocation(1), "GET", "HEAD")
+	if info == nil {
+		return nil, fmt.Errorf("path already registered: %s", path)
+	}
+	r.staticRoots = append(r.staticRoots, filename)
+	return info.SetDescription("Serves file " + filename + " (" + contentType + ")"), nil
+}
+
+// staticHandler serves an in-memory body with its precomputed ETag
+func staticHandler(status int, contentType string, body []byte) Handler {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+	length := strconv.Itoa(len(body))
+
+	return func(r *Request, w *Writer) {
+		if !fixtureMethod(r, w) {
+			return
+		}
+
+		w.Headers.Add("ETag", etag)
+		if status >= 200 && status < 300 && etagMatches(r.Headers.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Headers.Add("Content-Type", contentType)
+		w.Headers.Add("Content-Length", length)
+		w.WriteHeader(status)
+		if r.Method != "HEAD" {
+			w.Write(body)
+		}
+	}
+}
+
+// fileHandler streams a file from disk, revalidating with ETag or Last-Modified
+func fileHandler(filename, contentType string) Handler {
+	return func(r *Request, w *Writer) {
+		if !fixtureMethod(r, w) {
+			return
+		}
+
+		file, err := os.Open(filename)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		defer file.Close()
+
+		stat, err := file.Stat()
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if w.dev {
+			w.Headers.Add("Cache-Control", "no-store")
+		} else {
+			modified := stat.ModTime().UTC().Truncate(time.Second)
+			etag := `"` + strconv.FormatInt(stat.Size(), 16) + "-" + strconv.FormatInt(stat.ModTime().UnixNano(), 16) + `"`
+			w.Headers.Add("ETag", etag)
+			w.Headers.Add("Last-Modified", httpdate.Format(modified))
+
+			if notModified(r, etag, modified) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+
+		w.Headers.Add("Content-Type", contentType)
+		if err := sendFile(r, w, file, stat.Size()); err != nil && !isClosedConnectionError(err) {
+			log.Error(fmt.Errorf("error copying file: %w", err))
+		}
+	}
+}
+
+// fixtureMethod allows GET and HEAD, answering other methods with 405
+func fixtureMethod(r *Request, w *Writer) bool {
+	if r.Method == "GET" || r.Method == "HEAD" {
+		return true
+	}
+	w.Headers.Add("Allow", "GET, HEAD")
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	return false
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since
+// when the request carries no entity tags
+func notModified(r *Request, etag string, modified time.Time) bool {
+	if inm := r.Headers.Get("If-None-Match"); inm != "" {
+		return etagMatches(inm, etag)
+	}
+
+	ims := r.Headers.Get("If-Modified-Since")
+	if ims == "" {
+		return false
+	}
+	t, err := httpdate.Parse(ims)
+	if err != nil {
+		return false
+	}
+	return !modified.After(t)
+}
+
+// etagMatches reports whether an If-None-Match list matches etag
+// Uses the weak comparison required for If-None-Match
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package gouter
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStaticRoute(t *testing.T) {
+	r := newTestRouter()
+	info := r.Static("/version", http.StatusCreated, "application/json", []byte(`{"v":"1.2.3"}`))
+	r.Static("/down", http.StatusServiceUnavailable, "text/html", []byte("<p>maintenance</p>"))
+	addr := serveRouter(t, r)
+
+	if info == nil || info.Description != "Static 201 application/json response (13 bytes)" {
+		t.Errorf("docs description = %v", info)
+	}
+
+	resp := fetch(t, addr, "GET", "/version")
+	etag := resp.Header.Get("ETag")
+	if resp.StatusCode != 201 || bodyString(t, resp) != `{"v":"1.2.3"}` ||
+		resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get("Content-Length") != "13" ||
+		!strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		t.Fatalf("GET /version = %d %v", resp.StatusCode, resp.Header)
+	}
+
+	resp = fetch(t, addr, "HEAD", "/version")
+	if resp.StatusCode != 201 || resp.Header.Get("Content-Length") != "13" || resp.Header.Get("ETag") != etag {
+		t.Errorf("HEAD /version = %d %v", resp.StatusCode, resp.Header)
+	}
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag, "*"} {
+		resp := fetch(t, addr, "GET", "/version", "If-None-Match: "+inm)
+		if resp.StatusCode != http.StatusNotModified || bodyString(t, resp) != "" || resp.Header.Get("ETag") != etag {
+			t.Errorf("If-None-Match %s = %d, want 304 with the ETag", inm, resp.StatusCode)
+		}
+	}
+	if resp := fetch(t, addr, "GET", "/version", `If-None-Match: "other"`); resp.StatusCode != 201 {
+		t.Errorf("stale If-None-Match = %d, want 201", resp.StatusCode)
+	}
+
+	// Error pages are never revalidated
+	down := fetch(t, addr, "GET", "/down")
+	resp = fetch(t, addr, "GET", "/down", "If-None-Match: "+down.Header.Get("ETag"))
+	if resp.StatusCode != 503 || bodyString(t, resp) != "<p>maintenance</p>" {
+		t.Errorf("revalidated 503 = %d, want the page again", resp.StatusCode)
+	}
+
+	if resp := fetch(t, addr, "POST", "/version"); resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /version = %d, want 405", resp.StatusCode)
+	}
+}
+
+func TestFileRoute(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "terms.html")
+	os.WriteFile(small, []byte("<h1>terms</h1>"), 0o644)
+	large := filepath.Join(dir, "big.txt")
+	os.WriteFile(large, []byte(strings.Repeat("x", fixtureMemoryLimit+1)), 0o644)
+
+	r := newTestRouter()
+	info, err := r.File("/terms", small)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.File("/big", large); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.File("/dir", dir); err == nil {
+		t.Error("File accepted a directory")
+	}
+	if _, err := r.File("/missing", filepath.Join(dir, "missing")); err == nil {
+		t.Error("File accepted a missing file")
+	}
+	if _, err := r.File("/terms", small); err == nil {
+		t.Error("File registered a path twice")
+	}
+	if !strings.HasPrefix(info.Description, "Serves file ") || !strings.Contains(info.Description, "text/html") {
+		t.Errorf("docs description = %q", info.Description)
+	}
+	addr := serveRouter(t, r)
+
+	resp := fetch(t, addr, "GET", "/terms")
+	if bodyString(t, resp) != "<h1>terms</h1>" || resp.Header.Get("Content-Length") != "14" ||
+		!strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("small file = %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := fetch(t, addr, "GET", "/terms", "If-None-Match: "+resp.Header.Get("ETag")); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("small file revalidation = %d, want 304", resp.StatusCode)
+	}
+
+	resp = fetch(t, addr, "GET", "/big")
+	etag, modified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if len(bodyString(t, resp)) != fixtureMemoryLimit+1 || etag == "" || modified == "" {
+		t.Fatalf("large file = %d %v", resp.StatusCode, resp.Header)
+	}
+	if resp := fetch(t, addr, "GET", "/big", "If-None-Match: "+etag); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("large file If-None-Match = %d, want 304", resp.StatusCode)
+	}
+	if resp := fetch(t, addr, "GET", "/big", "If-Modified-Since: "+modified); resp.StatusCode != http.StatusNotModified {
+		t.Errorf("large file If-Modified-Since = %d, want 304", resp.StatusCode)
+	}
+	// If-None-Match takes precedence over If-Modified-Since
+	if resp := fetch(t, addr, "GET", "/big", `If-None-Match: "old"`, "If-Modified-Since: "+modified); resp.StatusCode != 200 {
+		t.Errorf("stale ETag with a current date = %d, want 200", resp.StatusCode)
+	}
+
+	// A changed file gets new validators
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(large, later, later)
+	if resp := fetch(t, addr, "GET", "/big", "If-None-Match: "+etag); resp.StatusCode != 200 || resp.Header.Get("ETag") == etag {
+		t.Errorf("modified file = %d ETag %s, want 200 with a new ETag", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
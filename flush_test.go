@@ -0,0 +1,128 @@
+package gouter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlushStreamsIncrementally(t *testing.T) {
+	next := make(chan struct{})
+	r := newTestRouter()
+	r.Get("/events", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Type", "text/event-stream")
+		// Flush with nothing buffered sends the headers on their own
+		if err := w.Flush(); err != nil {
+			t.Errorf("first flush: %v", err)
+		}
+		w.SetHeader("X-Late", "ignored")
+		for i := 0; i < 3; i++ {
+			<-next
+			w.Write([]byte("data: " + string(rune('a'+i)) + "\n\n"))
+			// Later flushes are no-ops: writes already reach the client
+			if err := w.Flush(); err != nil {
+				t.Errorf("flush %d: %v", i, err)
+			}
+		}
+	})
+	addr := serveRouter(t, r)
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(c, "GET /events HTTP/1.1\r\nHost: x\r\n\r\nGET /events HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(c)
+
+	for round := 0; round < 2; round++ {
+		// Headers arrive before the handler produced any event
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if framing(resp) != "chunked" || resp.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("headers = %v, want a chunked event stream", resp.Header)
+		}
+		if resp.Header.Get("X-Late") != "" {
+			t.Error("header set after Flush was sent")
+		}
+		// Each event is readable while the handler still waits for the next
+		events := bufio.NewReader(resp.Body)
+		for i := 0; i < 3; i++ {
+			next <- struct{}{}
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := "data: " + string(rune('a'+i)) + "\n"; line != want {
+				t.Fatalf("event %d = %q, want %q", i, line, want)
+			}
+			events.ReadString('\n')
+		}
+		// The terminating chunk keeps the connection usable
+		if rest, err := io.ReadAll(events); err != nil || len(rest) != 0 {
+			t.Fatalf("after the last event: %q, %v", rest, err)
+		}
+	}
+}
+
+func TestFlushFraming(t *testing.T) {
+	done := make(chan *Writer, 1)
+	r := newTestRouter()
+	r.Get("/declared", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Length", "11")
+		w.Write([]byte("hello"))
+		w.Flush()
+		w.Write([]byte(" world"))
+	})
+	r.Get("/after", func(req *Request, w *Writer) {
+		w.Write([]byte("body"))
+		done <- w
+	})
+	addr := serveRouter(t, r)
+
+	// A declared length is kept: the body is streamed as is, not chunked
+	raw := "GET /declared HTTP/1.1\r\nHost: x\r\n\r\nGET /after HTTP/1.1\r\nHost: x\r\n\r\n"
+	resps := rawExchange(t, addr, raw, 2)
+	if got := bodyString(t, resps[0]); framing(resps[0]) != "length" || got != "hello world" {
+		t.Errorf("declared length = %s %q, want the body unchunked", framing(resps[0]), got)
+	}
+	if got := bodyString(t, resps[1]); got != "body" {
+		t.Errorf("next response = %q", got)
+	}
+
+	// Once the handler returned the response belongs to the server
+	w := <-done
+	eventually(t, "the response to complete", func() bool {
+		return errors.Is(w.Flush(), ErrResponseDone)
+	})
+}
+
+func TestFlushBoundsMemory(t *testing.T) {
+	const parts, size = 256, 4 << 10
+	r := newTestRouter()
+	r.Get("/export", func(req *Request, w *Writer) {
+		part := []byte(strings.Repeat("x", size))
+		for i := 0; i < parts; i++ {
+			if _, err := w.Write(part); err != nil {
+				t.Errorf("part %d: %v", i, err)
+				return
+			}
+			w.Flush()
+		}
+	})
+	// A budget smaller than the export only holds if nothing stays buffered
+	addr := serveTest(t, &Server{Router: r, MaxMemoryPerRequest: 64 << 10})
+
+	resp := rawExchange(t, addr, "GET /export HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if n := len(bodyString(t, resp)); resp.StatusCode != 200 || n != parts*size {
+		t.Errorf("export = %d with %d bytes, want 200 with %d", resp.StatusCode, n, parts*size)
+	}
+}
@@ -0,0 +1,85 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+)
+
+// maxFormBytes caps the urlencoded body read by ParseForm, on top of
+// ParserConfig.MaxBodyBytes
+const maxFormBytes = 10 << 20
+
+// ParseForm parses the query string and, for POST, PUT and PATCH requests
+// with Content-Type application/x-www-form-urlencoded, the body
+// Body values come before query values of the same name in Form. It runs
+// once; later calls return nil. Malformed bodies return a 400 StatusError
+// and bodies over 10MB a 413. Multipart bodies are left to ParseMultipart
+// and MultipartReader
+func (r *Request) ParseForm() error {
+	r.pool.checkReleased("Request", 1)
+	if r.form != nil {
+		return nil
+	}
+
+	var err error
+	r.postForm = make(url.Values)
+	if r.Method == "POST" || r.Method == "PUT" || r.Method == "PATCH" {
+		err = r.parsePostForm()
+	}
+
+	r.form = make(url.Values)
+	for k, v := range r.postForm {
+		r.form[k] = append(r.form[k], v...)
+	}
+	for k, v := range r.Query() {
+		r.form[k] = append(r.form[k], v...)
+	}
+	return err
+}
+
+// parsePostForm reads an urlencoded body into postForm
+func (r *Request) parsePostForm() error {
+	ct, _, _ := mime.ParseMediaType(r.Headers.Get("Content-Type"))
+	if ct != "application/x-www-form-urlencoded" || r.Body == nil {
+		return nil
+	}
+
+	body, err := r.readAllBudget(io.LimitReader(r.Body, maxFormBytes+1))
+	switch {
+	case errors.Is(err, ErrBodyTooLarge), err == nil && len(body) > maxFormBytes:
+		return StatusError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+	case err != nil:
+		return err
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return StatusError(http.StatusBadRequest, err)
+	}
+	r.postForm = values
+	return nil
+}
+
+// Form returns the query and urlencoded body values, parsing them on
+// first use
+func (r *Request) Form() url.Values {
+	r.ParseForm()
+	return r.form
+}
+
+// FormValue returns the first value of key from the body or the query
+// string, or "" when absent. Parse errors are ignored; call ParseForm to
+// see them
+func (r *Request) FormValue(key string) string {
+	return r.Form().Get(key)
+}
+
+// PostFormValue returns the first value of key from the urlencoded body
+// only, or ""
+func (r *Request) PostFormValue(key string) string {
+	r.ParseForm()
+	return r.postForm.Get(key)
+}
@@ -0,0 +1,110 @@
+package gouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// formRequest sends method target with a body of the given Content-Type
+func formRequest(t *testing.T, addr, method, target, contentType, body string) *http.Response {
+	t.Helper()
+	raw := method + " " + target + " HTTP/1.1\r\nHost: x\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n"
+	if contentType != "" {
+		raw += "Content-Type: " + contentType + "\r\n"
+	}
+	return rawExchange(t, addr, raw+"\r\n"+body, 1)[0]
+}
+
+// formStatus returns the status carried by a ParseForm error
+func formStatus(err error) int {
+	var he *httpError
+	if errors.As(err, &he) {
+		return he.code
+	}
+	return 0
+}
+
+func TestParseForm(t *testing.T) {
+	r := newTestRouter()
+	r.Route("/form", func(req *Request, w *Writer) {
+		err := req.ParseForm()
+		fmt.Fprintf(w, "%s|%s|%v|%q|%v", req.FormValue("name"), req.PostFormValue("name"), req.Form()["name"], req.FormValue("page"), formStatus(err))
+		if req.ParseForm() != nil {
+			t.Error("second ParseForm returned an error")
+		}
+	})
+	addr := serveRouter(t, r)
+
+	const urlencoded = "application/x-www-form-urlencoded"
+	for _, tc := range []struct {
+		method, target, contentType, body string
+		want                              string
+	}{
+		// Body values come before query values of the same name
+		{"POST", "/form?name=query&page=2", urlencoded, "name=body+value&name=%C3%A9", `body value|body value|[body value é query]|"2"|0`},
+		{"PUT", "/form", urlencoded + "; charset=UTF-8", "name=put", `put|put|[put]|""|0`},
+		{"PATCH", "/form", urlencoded, "name=patch", `patch|patch|[patch]|""|0`},
+		// Other methods and content types only read the query string
+		{"GET", "/form?name=query", urlencoded, "name=body", `query||[query]|""|0`},
+		{"DELETE", "/form?name=query", urlencoded, "name=body", `query||[query]|""|0`},
+		{"POST", "/form?name=query", "application/json", `{"name":"json"}`, `query||[query]|""|0`},
+		{"POST", "/form?name=query", "multipart/form-data; boundary=x", "--x--", `query||[query]|""|0`},
+		{"POST", "/form?page=1", urlencoded, "", `||[]|"1"|0`},
+		{"POST", "/form?name=query", urlencoded, "name=%zz", `query||[query]|""|400`},
+	} {
+		resp := formRequest(t, addr, tc.method, tc.target, tc.contentType, tc.body)
+		if got := bodyString(t, resp); got != tc.want {
+			t.Errorf("%s %s %q = %s, want %s", tc.method, tc.target, tc.body, got, tc.want)
+		}
+	}
+}
+
+func TestParseFormLimits(t *testing.T) {
+	r := newTestRouter()
+	r.Post("/small", func(req *Request, w *Writer) {
+		err := req.ParseForm()
+		w.WriteHeader(max(formStatus(err), http.StatusOK))
+		if err != nil && !errors.Is(err, ErrBodyTooLarge) {
+			t.Errorf("ParseForm = %v, want ErrBodyTooLarge", err)
+		}
+	}).SetMaxBodyBytes(16)
+	r.Post("/big", func(req *Request, w *Writer) {
+		w.WriteHeader(max(formStatus(req.ParseForm()), http.StatusOK))
+	})
+	addr := serveRouter(t, r)
+
+	const urlencoded = "application/x-www-form-urlencoded"
+	if resp := formRequest(t, addr, "POST", "/small", urlencoded, "a=1"); resp.StatusCode != 200 {
+		t.Errorf("body under the route limit = %d", resp.StatusCode)
+	}
+	if resp := formRequest(t, addr, "POST", "/small", urlencoded, "a="+strings.Repeat("x", 32)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("body over the route limit = %d, want 413", resp.StatusCode)
+	}
+	if resp := formRequest(t, addr, "POST", "/big", urlencoded, "a="+strings.Repeat("x", maxFormBytes)); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("body over 10MB = %d, want 413", resp.StatusCode)
+	}
+}
+
+func TestFormThroughContext(t *testing.T) {
+	r := newTestRouter()
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			req.ParseForm()
+			next(req.WithContext(req.Context()), w)
+		}
+	})
+	r.Post("/form", func(req *Request, w *Writer) {
+		w.Write([]byte(req.FormValue("name")))
+	})
+	addr := serveRouter(t, r)
+
+	// The body was consumed by the middleware; the copy keeps its values
+	resp := formRequest(t, addr, "POST", "/form", "application/x-www-form-urlencoded", "name=kept")
+	if got := bodyString(t, resp); got != "kept" {
+		t.Errorf("FormValue after WithContext = %q", got)
+	}
+}
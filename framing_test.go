@@ -0,0 +1,192 @@
+package gouter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// framing describes how a response body is delimited on the wire
+func framing(resp *http.Response) string {
+	switch {
+	case len(resp.TransferEncoding) > 0:
+		return strings.Join(resp.TransferEncoding, ",")
+	case resp.ContentLength >= 0:
+		return "length"
+	case resp.Close:
+		return "close"
+	}
+	return "none"
+}
+
+func TestResponseFraming(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "small.txt"), []byte("static file"), 0o644)
+	os.WriteFile(filepath.Join(dir, "large.txt"), []byte(strings.Repeat("L", 200<<10)), 0o644)
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("proxied "))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("stream"))
+	}))
+	t.Cleanup(upstream.Close)
+	pool, err := ProxyPool([]Upstream{{URL: upstream.URL}}, PoolOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+
+	r := newTestRouter()
+	r.Get("/buffered", func(req *Request, w *Writer) { w.Write([]byte("buffered")) })
+	r.Get("/flushed", func(req *Request, w *Writer) {
+		w.Write([]byte("part one, "))
+		w.Flush()
+		w.Write([]byte("part two"))
+	})
+	r.Get("/headers-first", func(req *Request, w *Writer) {
+		w.WriteHeaders()
+		w.Write([]byte("after headers"))
+	})
+	r.Get("/large", func(req *Request, w *Writer) {
+		for i := 0; i < 64; i++ {
+			w.Write([]byte(strings.Repeat("x", 1000)))
+		}
+	})
+	r.Get("/declared", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Length", "8")
+		w.WriteHeaders()
+		w.Write([]byte("declared"))
+	})
+	r.Get("/empty", func(req *Request, w *Writer) { w.WriteHeader(http.StatusNoContent) })
+	r.Get("/unframed", func(req *Request, w *Writer) {
+		w.SetHeader("Transfer-Encoding", "gzip")
+		w.WriteHeaders()
+		w.Write([]byte("raw"))
+	})
+	r.Get("/cut", func(req *Request, w *Writer) {
+		w.Write([]byte("half"))
+		w.Flush()
+		panic("stream broke")
+	})
+	r.File("/file/small", filepath.Join(dir, "small.txt"))
+	r.File("/file/large", filepath.Join(dir, "large.txt"))
+	ServerStatic(r, "/static", dir)
+	r.Route("/proxy/*", pool.Handler)
+	addr := serveTest(t, &Server{Router: r, MaxBufferedResponseBytes: 16 << 10})
+
+	cases := []struct {
+		target, framing, body string
+		size                  int
+	}{
+		{"/buffered", "length", "buffered", 0},
+		{"/flushed", "chunked", "part one, part two", 0},
+		{"/headers-first", "chunked", "after headers", 0},
+		{"/large", "chunked", "", 64000},
+		{"/declared", "length", "declared", 0},
+		{"/file/small", "length", "static file", 0},
+		{"/file/large", "length", "", 200 << 10},
+		{"/static/small.txt", "length", "static file", 0},
+		{"/static/large.txt", "length", "", 200 << 10},
+		{"/proxy/x", "length", "proxied stream", 0},
+	}
+	// Every response on one connection: each must end where its framing says
+	var raw strings.Builder
+	for _, c := range cases {
+		raw.WriteString("GET " + c.target + " HTTP/1.1\r\nHost: x\r\n\r\n")
+	}
+	for i, resp := range rawExchange(t, addr, raw.String(), len(cases)) {
+		c := cases[i]
+		body := bodyString(t, resp)
+		if got := framing(resp); got != c.framing || resp.Close {
+			t.Errorf("%s framed by %s (close %v), want %s on a kept connection", c.target, got, resp.Close, c.framing)
+		}
+		if (c.size == 0 && body != c.body) || (c.size > 0 && len(body) != c.size) {
+			t.Errorf("%s body = %d bytes, want %q or %d bytes", c.target, len(body), c.body, c.size)
+		}
+	}
+
+	// No framing for bodiless responses, and no chunks sent for HEAD
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "GET /empty HTTP/1.1\r\nHost: x\r\n\r\nHEAD /flushed HTTP/1.1\r\nHost: x\r\n\r\nGET /buffered HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(c)
+	var resp []*http.Response
+	for _, method := range []string{"GET", "HEAD", "GET"} {
+		next, err := http.ReadResponse(br, &http.Request{Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp = append(resp, next)
+		io.ReadAll(next.Body)
+	}
+	if resp[0].StatusCode != 204 || resp[0].Header.Get("Content-Length") != "" || resp[0].Header.Get("Transfer-Encoding") != "" {
+		t.Errorf("204 headers = %v, want no framing", resp[0].Header)
+	}
+	if resp[2].StatusCode != 200 || resp[2].ContentLength != int64(len("buffered")) {
+		t.Errorf("response after a streamed HEAD = %d, want the next response intact", resp[2].StatusCode)
+	}
+
+	// HTTP/1.0 clients cannot decode chunks: the connection delimits the body
+	resp = rawExchange(t, addr, "GET /flushed HTTP/1.0\r\nHost: x\r\n\r\n", 1)
+	if len(resp[0].TransferEncoding) > 0 || !resp[0].Close || bodyString(t, resp[0]) != "part one, part two" {
+		t.Errorf("HTTP/1.0 stream framed by %s (close %v)", framing(resp[0]), resp[0].Close)
+	}
+
+	// A body the Writer cannot frame closes the connection
+	uc, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer uc.Close()
+	uc.SetDeadline(time.Now().Add(3 * time.Second))
+	io.WriteString(uc, "GET /unframed HTTP/1.1\r\nHost: x\r\n\r\n")
+	out, err := io.ReadAll(uc)
+	if err != nil || !strings.Contains(strings.ToLower(string(out)), "connection: close") || !strings.HasSuffix(string(out), "\r\n\r\nraw") {
+		t.Errorf("unframed response = %q (%v), want the connection closed after the body", out, err)
+	}
+
+	// A stream cut by a panic must not look complete
+	c, err = net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "GET /cut HTTP/1.1\r\nHost: x\r\n\r\n")
+	cut, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(cut.Body); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("reading a cut stream = %v, want unexpected EOF", err)
+	}
+}
+
+func TestDocsResponseFraming(t *testing.T) {
+	r := NewRouter()
+	r.Get("/users", func(req *Request, w *Writer) {})
+	for _, path := range []string{"/", "/openapi.json"} {
+		client, server := net.Pipe()
+		go handleDocRequest(server, r)
+		go io.WriteString(client, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n")
+		resp, err := http.ReadResponse(bufio.NewReader(client), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		if framing(resp) != "length" || err != nil || int64(len(body)) != resp.ContentLength {
+			t.Errorf("docs %s framed by %s with %d of %d bytes (%v)", path, framing(resp), len(body), resp.ContentLength, err)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,30 @@
+package gouter
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"runtime/pprof"
+)
+
+// GoroutineLabel is the pprof label key set on every goroutine gouter
+// starts; its value names the spawn point (e.g., "conn", "task"), so
+// goroutine profiles and leak checks can tell them apart
+const GoroutineLabel = "gouter"
+
+// spawn runs fn in a new goroutine labeled name
+// The labels are set around the go statement, so the goroutine carries
+// them from creation on, before it first runs
+func spawn(name string, fn func()) {
+	pprof.Do(context.Background(), pprof.Labels(GoroutineLabel, name), func(context.Context) {
+		go fn()
+	})
+}
+
+// funcName returns the name of fn for goroutine labels
+func funcName(fn any) string {
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "unknown"
+}
@@ -0,0 +1,119 @@
+package gouter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGroupPrefixNormalization(t *testing.T) {
+	tests := []struct {
+		prefix, path, want string
+	}{
+		{"/auth", "/login", "/auth/login"},
+		{"/auth/", "/login", "/auth/login"},
+		{"auth", "login", "/auth/login"},
+		{"auth/", "/login", "/auth/login"},
+		{"//auth//", "//login", "/auth/login"},
+		{"/api/v1/", "users/:id", "/api/v1/users/:id"},
+		{"/api//v1", "/files/*path", "/api/v1/files/*path"},
+		{"/auth", "", "/auth"},
+		{"/auth", "/", "/auth"},
+	}
+	for _, tt := range tests {
+		r := newTestRouter()
+		var info *RouteInfo
+		err := r.Group(tt.prefix, func(g *Group) {
+			info = g.Route(tt.path, func(req *Request, w *Writer) {
+				w.Write([]byte("ok"))
+			}, "GET")
+		})
+		if err != nil {
+			t.Errorf("Group(%q): %v", tt.prefix, err)
+			continue
+		}
+		if info == nil || info.Path != tt.want {
+			t.Errorf("Group(%q).Route(%q) docs path = %v, want %q", tt.prefix, tt.path, info, tt.want)
+			continue
+		}
+
+		addr := serveRouter(t, r)
+		target := tt.want
+		if target == "/api/v1/users/:id" {
+			target = "/api/v1/users/7"
+		} else if target == "/api/v1/files/*path" {
+			target = "/api/v1/files/a/b"
+		}
+		if resp := rawExchange(t, addr, "GET "+target+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 200 {
+			t.Errorf("Group(%q).Route(%q): GET %s = %d", tt.prefix, tt.path, target, resp.StatusCode)
+		}
+	}
+}
+
+func TestNestedGroupPrefixes(t *testing.T) {
+	r := newTestRouter()
+	var info *RouteInfo
+	r.Group("/api/", func(g *Group) {
+		g.Group("v2//", func(v2 *Group) {
+			v2.Group("/admin", func(admin *Group) {
+				info = admin.Route("//users/", func(req *Request, w *Writer) {}, "GET")
+			})
+		})
+	})
+	if info == nil || info.Path != "/api/v2/admin/users/" {
+		t.Fatalf("nested route = %v", info)
+	}
+	addr := serveRouter(t, r)
+	if resp := rawExchange(t, addr, "GET /api/v2/admin/users HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 200 {
+		t.Errorf("nested route status = %d", resp.StatusCode)
+	}
+}
+
+func TestGroupRejectsEmptyPrefix(t *testing.T) {
+	for _, prefix := range []string{"", "/", "//", "///"} {
+		r := newTestRouter()
+		called := false
+		err := r.Group(prefix, func(g *Group) { called = true })
+		if !errors.Is(err, ErrEmptyGroupPrefix) || called {
+			t.Errorf("Group(%q) = %v, called %v; want ErrEmptyGroupPrefix without calling fn", prefix, err, called)
+		}
+
+		r.Group("/outer", func(g *Group) {
+			if err := g.Group(prefix, func(*Group) { called = true }); !errors.Is(err, ErrEmptyGroupPrefix) || called {
+				t.Errorf("nested Group(%q) = %v, called %v", prefix, err, called)
+			}
+		})
+	}
+
+	var g Group
+	if err := g.Group("/x", func(*Group) {}); !errors.Is(err, ErrNoRouter) {
+		t.Errorf("zero-value Group.Group = %v, want ErrNoRouter", err)
+	}
+	if info := g.Route("/x", func(req *Request, w *Writer) {}); info != nil {
+		t.Error("zero-value Group registered a route")
+	}
+}
+
+func TestMountPrefixNormalization(t *testing.T) {
+	child := newTestRouter()
+	child.Get("/", func(req *Request, w *Writer) { w.Write([]byte("root")) })
+	child.Get("//items", func(req *Request, w *Writer) { w.Write([]byte("items")) })
+
+	r := newTestRouter()
+	if err := r.Mount("child//", child); err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, doc := range r.docs {
+		paths = append(paths, doc.Path)
+	}
+	if len(paths) != 2 || paths[0] != "/child" || paths[1] != "/child/items" {
+		t.Errorf("mounted docs paths = %q, want [/child /child/items]", paths)
+	}
+
+	addr := serveRouter(t, r)
+	for path, want := range map[string]string{"/child": "root", "/child/items": "items"} {
+		if resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; bodyString(t, resp) != want {
+			t.Errorf("GET %s = %d, want %q", path, resp.StatusCode, want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package gtest
+
+import (
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+var epoch = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// fired reports whether ch holds a value, returning it
+func fired(ch <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-ch:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	after := clock.After(time.Second)
+	timer := clock.NewTimer(2 * time.Second)
+	if n := clock.Timers(); n != 2 {
+		t.Fatalf("waiting timers = %d, want 2", n)
+	}
+
+	clock.Advance(999 * time.Millisecond)
+	if _, ok := fired(after); ok {
+		t.Fatal("After fired before its deadline")
+	}
+	clock.Advance(time.Millisecond)
+	if at, ok := fired(after); !ok || !at.Equal(epoch.Add(time.Second)) {
+		t.Fatalf("After = %v, %v; want it fired at %v", at, ok, epoch.Add(time.Second))
+	}
+	if !clock.Now().Equal(epoch.Add(time.Second)) {
+		t.Fatalf("Now = %v", clock.Now())
+	}
+
+	// Reset moves the deadline from the fake now, not from the first arming
+	if !timer.Reset(3 * time.Second) {
+		t.Fatal("Reset of a waiting timer returned false")
+	}
+	clock.Advance(2 * time.Second)
+	if _, ok := fired(timer.C()); ok {
+		t.Fatal("timer fired at its old deadline after Reset")
+	}
+	clock.Advance(time.Second)
+	if _, ok := fired(timer.C()); !ok {
+		t.Fatal("timer did not fire at its reset deadline")
+	}
+	if timer.Stop() {
+		t.Error("Stop of a fired timer returned true")
+	}
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("waiting timers = %d after all fired, want 0", n)
+	}
+}
+
+func TestFakeClockStopAndSet(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	stopped := clock.NewTimer(time.Minute)
+	if !stopped.Stop() {
+		t.Fatal("Stop of a waiting timer returned false")
+	}
+	if stopped.Stop() {
+		t.Error("second Stop returned true")
+	}
+	clock.Advance(time.Hour)
+	if _, ok := fired(stopped.C()); ok {
+		t.Error("stopped timer fired")
+	}
+
+	// A stopped timer can be rearmed; Reset reports it was not active
+	if stopped.Reset(time.Minute) {
+		t.Error("Reset of a stopped timer returned true")
+	}
+
+	// Moving backwards fires nothing, and the timer still waits for its
+	// absolute deadline
+	clock.Set(epoch)
+	if _, ok := fired(stopped.C()); ok {
+		t.Error("timer fired when the clock moved backwards")
+	}
+	clock.Set(epoch.Add(time.Hour + time.Minute))
+	if _, ok := fired(stopped.C()); !ok {
+		t.Error("timer did not fire when Set passed its deadline")
+	}
+
+	// Non-positive durations fire at once, without waiting on Advance
+	for _, d := range []time.Duration{0, -time.Second} {
+		if _, ok := fired(clock.After(d)); !ok {
+			t.Errorf("After(%v) did not fire immediately", d)
+		}
+	}
+	if n := clock.Timers(); n != 0 {
+		t.Errorf("waiting timers = %d, want 0", n)
+	}
+}
+
+func TestSequentialIDs(t *testing.T) {
+	ids := &SequentialIDs{Prefix: "req-"}
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := ids.NewID()
+			if err != nil {
+				t.Error(err)
+			}
+			mu.Lock()
+			seen[id] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 50 || !seen["req-1"] || !seen["req-50"] {
+		t.Errorf("ids = %v, want req-1 to req-50 without repeats", seen)
+	}
+	if id, _ := ids.NewID(); id != "req-51" {
+		t.Errorf("next id = %q, want req-51", id)
+	}
+}
+
+func TestRouterUsesFakes(t *testing.T) {
+	clock := NewFakeClock(epoch)
+	r := newRouter()
+	r.SetClockSource(clock)
+	r.SetIDGenerator(&SequentialIDs{Prefix: "n"})
+
+	breaker := gouter.NewCircuitBreaker(gouter.BreakerConfig{MinRequests: 1, Cooldown: time.Minute})
+	r.Use(breaker.Middleware())
+	r.Use(gouter.CSPNonce("script-src 'nonce-{nonce}'"))
+	r.Route("/now", func(req *gouter.Request, w *gouter.Writer) {
+		w.Write([]byte(req.Clock().Now().Format(time.RFC3339) + " " + req.CSPNonce()))
+	})
+	var failing atomic.Bool
+	r.Route("/flaky", func(req *gouter.Request, w *gouter.Writer) {
+		if failing.Load() {
+			w.WriteHeader(500)
+		}
+	})
+
+	// Strict mode stamps every response with a Date from the router clock
+	s := &gouter.Server{Router: r}
+	s.StrictHTTP(true)
+	base, shutdown := serve(t, s)
+	defer shutdown()
+
+	resp, err := http.Get(base + "/now")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Content-Security-Policy"); got != "script-src 'nonce-n1'" {
+		t.Errorf("CSP = %q, want the first sequential nonce", got)
+	}
+	if got := resp.Header.Get("Date"); got != "Sun, 01 Mar 2026 12:00:00 GMT" {
+		t.Errorf("Date = %q, want the fake time", got)
+	}
+	clock.Advance(90 * time.Second)
+	if got := get(t, http.DefaultClient, base+"/now"); got != "2026-03-01T12:01:30Z n2" {
+		t.Errorf("body = %q", got)
+	}
+
+	// The breaker reads the request Clock, so its cooldown ends on Advance
+	failing.Store(true)
+	status := func() int {
+		resp, err := http.Get(base + "/flaky")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := status(); code != 500 {
+		t.Fatalf("first failure = %d", code)
+	}
+	resp, err = http.Get(base + "/flaky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 503 || resp.Header.Get("Retry-After") != "60" {
+		t.Fatalf("open circuit = %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	clock.Advance(59 * time.Second)
+	if code := status(); code != 503 {
+		t.Errorf("before cooldown = %d, want 503", code)
+	}
+	failing.Store(false)
+	clock.Advance(time.Second)
+	if code := status(); code != 200 {
+		t.Errorf("half-open trial = %d, want 200", code)
+	}
+	for _, c := range breaker.Circuits() {
+		if c.Route == "/flaky" && c.State != gouter.BreakerClosed {
+			t.Errorf("circuit = %+v, want the route closed again", c)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package gtest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// serve starts s on a port-0 listener
+// Returns the base URL and a function shutting the server down
+func serve(t *testing.T, s *gouter.Server) (string, func()) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Serve(l)
+	return "http://" + l.Addr().String(), func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := s.Shutdown(ctx); err != nil {
+			t.Errorf("shutdown: %v", err)
+		}
+	}
+}
+
+// newRouter returns a router without the documentation server
+func newRouter() *gouter.Router {
+	r := gouter.NewRouter()
+	r.Update(func(d *gouter.Doc) { d.Active = false })
+	return r
+}
+
+// get fetches url and returns the body
+func get(t *testing.T, c *http.Client, url string) string {
+	t.Helper()
+	resp, err := c.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(resp.Body)
+	return string(b)
+}
+
+// dialWebSocket opens a websocket to url and returns the connection after
+// the handshake, with its reader
+func dialWebSocket(t *testing.T, url string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	c, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	fmt.Fprintf(c, "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(c)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("websocket handshake: %v %v", resp, err)
+	}
+	return c, br
+}
+
+// TestConformanceNoLeaks runs each goroutine-spawning feature, stops it the
+// documented way and checks no gouter goroutine outlives it
+func TestConformanceNoLeaks(t *testing.T) {
+	t.Run("keep-alive and streaming", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			r := newRouter()
+			r.Get("/stream", func(req *gouter.Request, w *gouter.Writer) {
+				<-time.After(time.Millisecond)
+				if req.Context().Err() != nil {
+					return
+				}
+				w.Write([]byte("part"))
+				w.Flush()
+				w.CloseNotify()
+				w.Write([]byte(" end"))
+			})
+			base, shutdown := serve(t, &gouter.Server{Router: r})
+			c := &http.Client{}
+			for i := 0; i < 3; i++ {
+				if got := get(t, c, base+"/stream"); got != "part end" {
+					t.Errorf("stream = %q", got)
+				}
+			}
+			// The client keeps its connection idle until Shutdown closes it
+			shutdown()
+			c.CloseIdleConnections()
+		})
+	})
+
+	t.Run("websocket hub", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			hub := gouter.NewHub(gouter.QueueConfig{Size: 8})
+			joined := make(chan struct{})
+			r := newRouter()
+			r.WebSocket("/ws", func(ws *gouter.WebSocket, req *gouter.Request) {
+				hub.Join(ws)
+				defer hub.Leave(ws)
+				close(joined)
+				for {
+					if _, err := ws.ReadMessage(); err != nil {
+						return
+					}
+				}
+			}, gouter.WebSocketConfig{})
+			base, shutdown := serve(t, &gouter.Server{Router: r})
+
+			c, br := dialWebSocket(t, base)
+			defer c.Close()
+			<-joined
+			hub.Broadcast([]byte("hi"))
+			head := make([]byte, 4)
+			if _, err := io.ReadFull(br, head); err != nil || string(head) != "\x81\x02hi" {
+				t.Fatalf("broadcast frame %q: %v", head, err)
+			}
+
+			// The client answers the going-away close frame by hanging up
+			done := make(chan struct{})
+			go func() {
+				shutdown()
+				close(done)
+			}()
+			if _, err := io.ReadFull(br, head[:2]); err != nil || head[0] != 0x88 {
+				t.Errorf("frame after Shutdown %x: %v, want a close frame", head[:2], err)
+			}
+			c.Close()
+			<-done
+		})
+	})
+
+	t.Run("tasks", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			_, shutdown := serve(t, &gouter.Server{Router: newRouter()})
+			gouter.Go(func(ctx context.Context) { <-ctx.Done() })
+			shutdown()
+		})
+	})
+
+	t.Run("docs server", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			r := gouter.NewRouter()
+			r.Update(func(d *gouter.Doc) {
+				d.Port = "0"
+				d.Addrs = "127.0.0.1"
+			})
+			r.Get("/ping", func(req *gouter.Request, w *gouter.Writer) {})
+			_, shutdown := serve(t, &gouter.Server{Router: r})
+			var addr string
+			for deadline := time.Now().Add(time.Second); addr == "" && time.Now().Before(deadline); {
+				addr, _ = r.DocsAddr()
+				time.Sleep(time.Millisecond)
+			}
+			if addr == "" {
+				t.Fatal("documentation server did not start")
+			}
+			c := &http.Client{}
+			get(t, c, "http://"+addr+"/")
+			shutdown()
+			c.CloseIdleConnections()
+		})
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("upstream"))
+		}))
+		defer upstream.Close()
+
+		VerifyNoLeaks(t, func() {
+			pool, err := gouter.ProxyPool([]gouter.Upstream{{URL: upstream.URL}}, gouter.PoolOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			r := newRouter()
+			r.Route("/*", pool.Handler)
+			base, shutdown := serve(t, &gouter.Server{Router: r})
+			c := &http.Client{}
+			if got := get(t, c, base+"/x"); got != "upstream" {
+				t.Errorf("proxied body = %q", got)
+			}
+			shutdown()
+			pool.Close()
+			c.CloseIdleConnections()
+		})
+	})
+
+	t.Run("net/http adapter and recorder", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			r := newRouter()
+			r.Get("/ok", func(req *gouter.Request, w *gouter.Writer) { w.Write([]byte("ok")) })
+			srv := httptest.NewServer(gouter.ToHTTPHandler(r))
+			if got := get(t, srv.Client(), srv.URL+"/ok"); got != "ok" {
+				t.Errorf("adapter body = %q", got)
+			}
+			srv.Close()
+			if err := r.SelfTest("/ok"); err != nil {
+				t.Error(err)
+			}
+		})
+	})
+
+	t.Run("upload inspectors", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			seen := func(part gouter.UploadPartInfo, r io.Reader) error {
+				_, err := io.Copy(io.Discard, r)
+				return err
+			}
+			r := newRouter()
+			r.UploadInspector(gouter.ChainUploadInspectors(seen, seen))
+			r.Post("/upload", func(req *gouter.Request, w *gouter.Writer) {
+				var form struct {
+					File *gouter.FileUpload `gouter:"file"`
+				}
+				if err := req.ParseMultipart(&form); err != nil || form.File == nil {
+					w.WriteHeader(http.StatusBadRequest)
+				}
+			})
+			base, shutdown := serve(t, &gouter.Server{Router: r})
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			fw, _ := mw.CreateFormFile("file", "a.txt")
+			fw.Write([]byte("contents"))
+			mw.Close()
+			c := &http.Client{}
+			resp, err := c.Post(base+"/upload", mw.FormDataContentType(), &body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("upload = %d", resp.StatusCode)
+			}
+			shutdown()
+			c.CloseIdleConnections()
+		})
+	})
+
+	t.Run("dev mode and reload", func(t *testing.T) {
+		VerifyNoLeaks(t, func() {
+			r := newRouter()
+			r.DevMode(true)
+			r.DevMode(false)
+			stop := (&gouter.Server{Router: r}).EnableSIGHUPReload()
+			stop()
+		})
+	})
+}
+
+// recordingTB captures the failures reported by VerifyNoLeaks
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestVerifyNoLeaksReportsLabeledGoroutines(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	tb := &recordingTB{TB: t}
+	VerifyNoLeaks(tb, func() {
+		gouter.Go(func(ctx context.Context) { <-release })
+	})
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "task") {
+		t.Errorf("reported %q, want the leaked task", tb.errors)
+	}
+}
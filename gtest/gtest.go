@@ -0,0 +1,235 @@
+/*
+Package gtest provides deterministic time and identifier sources for tests.
+
+Features:
+- FakeClock, a gouter.Clock that only moves when told to
+- Timers and After channels firing as the fake time passes them
+- SequentialIDs, a gouter.IDGenerator returning predictable identifiers
+- VerifyNoLeaks, failing tests that leave gouter goroutines running
+*/
+package gtest
+
+import (
+	"bytes"
+	"regexp"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// FakeClock is a gouter.Clock whose time only changes through Advance and Set
+// It is safe for concurrent use
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock creates a clock stopped at start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the fake time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer creates a timer firing once the fake time reaches Now()+d
+func (c *FakeClock) NewTimer(d time.Duration) gouter.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, ch: make(chan time.Time, 1)}
+	t.schedule(c.now.Add(d))
+	return t
+}
+
+// After returns a channel receiving the fake time once d has passed
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Advance moves the time forward by d, firing the timers it passes
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.set(c.now.Add(d))
+	c.mu.Unlock()
+}
+
+// Set moves the time to t, firing the timers it passes
+// Moving backwards fires nothing
+func (c *FakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	c.set(t)
+	c.mu.Unlock()
+}
+
+// Timers returns how many timers are waiting, e.g. to know that the code
+// under test is blocked on the clock before advancing it
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// set moves the time and fires due timers; the caller holds c.mu
+func (c *FakeClock) set(t time.Time) {
+	c.now = t
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(t) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.active = false
+		select {
+		case timer.ch <- t:
+		default:
+		}
+	}
+	clear(c.timers[len(pending):])
+	c.timers = pending
+}
+
+// fakeTimer is a timer of a FakeClock
+type fakeTimer struct {
+	clock  *FakeClock
+	ch     chan time.Time
+	at     time.Time
+	active bool
+}
+
+// schedule arms the timer; the caller holds the clock lock
+func (t *fakeTimer) schedule(at time.Time) {
+	t.at = at
+	t.active = true
+	if !at.After(t.clock.now) {
+		t.active = false
+		select {
+		case t.ch <- t.clock.now:
+		default:
+		}
+		return
+	}
+	t.clock.timers = append(t.clock.timers, t)
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop disarms the timer; false when it already fired or was stopped
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.unschedule()
+}
+
+// Reset rearms the timer for d from the fake time; false when it had
+// already fired or was stopped
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	wasActive := t.unschedule()
+	t.schedule(t.clock.now.Add(d))
+	return wasActive
+}
+
+// unschedule removes the timer from its clock; the caller holds the lock
+func (t *fakeTimer) unschedule() bool {
+	if !t.active {
+		return false
+	}
+	t.active = false
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// SequentialIDs is a gouter.IDGenerator returning Prefix followed by 1, 2, 3…
+type SequentialIDs struct {
+	Prefix string
+	n      atomic.Int64
+}
+
+// NewID returns the next identifier
+func (g *SequentialIDs) NewID() (string, error) {
+	return g.Prefix + strconv.FormatInt(g.n.Add(1), 10), nil
+}
+
+// leakWait is how long VerifyNoLeaks lets goroutines wind down
+const leakWait = 2 * time.Second
+
+// gouterLabel finds the gouter label of a goroutine profile record
+var gouterLabel = regexp.MustCompile(`"` + gouter.GoroutineLabel + `":"([^"]*)"`)
+
+// VerifyNoLeaks runs fn and fails t when goroutines started by gouter during
+// fn, e.g. connections, tunnels or Go tasks, are still running after it
+// returns. They get a moment to finish, so fn should stop what it started
+// (Server.Shutdown, Pool.Close) but need not wait for every goroutine
+func VerifyNoLeaks(t testing.TB, fn func()) {
+	t.Helper()
+
+	before := gouterGoroutines()
+	fn()
+
+	deadline := time.Now().Add(leakWait)
+	for {
+		leaked := leakedGoroutines(before, gouterGoroutines())
+		if len(leaked) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("gouter goroutines still running:\n\n%s", strings.Join(leaked, "\n\n"))
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// gouterGoroutines counts the running gouter goroutines by label and stack
+func gouterGoroutines() map[string]int {
+	var buf bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&buf, 1)
+
+	counts := make(map[string]int)
+	for _, record := range strings.Split(buf.String(), "\n\n") {
+		m := gouterLabel.FindStringSubmatch(record)
+		if m == nil {
+			continue
+		}
+		// "N @ 0x… 0x…" heads each record: N goroutines share the stack
+		head, stack, _ := strings.Cut(record, "\n")
+		n, err := strconv.Atoi(strings.Fields(head)[0])
+		if err != nil {
+			continue
+		}
+		counts[m[1]+"\n"+stack] += n
+	}
+	return counts
+}
+
+// leakedGoroutines describes the goroutines of after missing from before
+func leakedGoroutines(before, after map[string]int) []string {
+	var leaked []string
+	for key, n := range after {
+		if extra := n - before[key]; extra > 0 {
+			label, stack, _ := strings.Cut(key, "\n")
+			leaked = append(leaked, strconv.Itoa(extra)+" × "+label+"\n"+stack)
+		}
+	}
+	return leaked
+}
@@ -0,0 +1,92 @@
+package gouter
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// ErrNotFound makes a guard answer 404, e.g. when the entity for :id is missing
+var ErrNotFound = errors.New("not found")
+
+// GuardFunc loads data for a route before its handler runs
+// A non-nil error skips the handler: ErrNotFound answers 404, errors built
+// with StatusError answer their code and anything else answers 500
+type GuardFunc func(r *Request) (any, error)
+
+// routeGuard is a guard and the request value key receiving its result
+type routeGuard struct {
+	key string // Empty to use the route name
+	fn  GuardFunc
+}
+
+// Guard adds a guard storing its result under the route name
+// (see SetName) in the request values
+// Guards run in the order they were added, after the route middlewares
+func (r *RouteInfo) Guard(fn GuardFunc) *RouteInfo {
+	return r.GuardAs("", fn)
+}
+
+// GuardAs adds a guard storing its result under key in the request values
+func (r *RouteInfo) GuardAs(key string, fn GuardFunc) *RouteInfo {
+	r.guards = append(r.guards, routeGuard{key: key, fn: fn})
+	return r
+}
+
+// SetValue stores a request-scoped value
+func (r *Request) SetValue(key string, v any) {
+	r.pool.checkReleased("Request", 1)
+	if r.values == nil {
+		r.values = make(map[string]any)
+	}
+	r.values[key] = v
+}
+
+// Value returns a request-scoped value, or nil when it is not set
+func (r *Request) Value(key string) any {
+	r.pool.checkReleased("Request", 1)
+	return r.values[key]
+}
+
+// withGuards runs the guards of the matched route before the handler
+// Guards are read at request time, so Guard may be called after registration
+func withGuards(next Handler) Handler {
+	return func(r *Request, w *Writer) {
+		if r.route == nil || len(r.route.guards) == 0 {
+			next(r, w)
+			return
+		}
+
+		for _, g := range r.route.guards {
+			key := g.key
+			if key == "" {
+				key = r.route.HandlerName
+			}
+
+			v, err := g.fn(r)
+			if err != nil {
+				TraceEvent(r, "guard "+key+" failed")
+				writeGuardError(w, err)
+				return
+			}
+			r.SetValue(key, v)
+		}
+
+		next(r, w)
+	}
+}
+
+// writeGuardError maps a guard error to its response status
+func writeGuardError(w *Writer, err error) {
+	var he *httpError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.As(err, &he):
+		Error(w, he.err, he.code)
+	default:
+		log.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,94 @@
+package gouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+type record struct {
+	ID    string
+	Owner string
+}
+
+func TestGuardLoadsRecord(t *testing.T) {
+	records := map[string]*record{"7": {ID: "7", Owner: "ana"}}
+	loadRecord := func(r *Request) (any, error) {
+		rec, ok := records[r.Params.Get("id")]
+		if !ok {
+			return nil, ErrNotFound
+		}
+		return rec, nil
+	}
+
+	r := newTestRouter()
+	r.Get("/records/:id", func(req *Request, w *Writer) {
+		rec := req.Value("getRecord").(*record)
+		w.Write([]byte(rec.ID + ":" + rec.Owner))
+	}).SetName("getRecord").Guard(loadRecord)
+
+	// Guards compose in order: later ones see the values of earlier ones
+	var order []string
+	r.Get("/records/:id/owner", func(req *Request, w *Writer) {
+		order = append(order, "handler")
+		w.Write([]byte(req.Value("owner").(string)))
+	}).GuardAs("record", func(r *Request) (any, error) {
+		order = append(order, "record")
+		return loadRecord(r)
+	}).GuardAs("owner", func(r *Request) (any, error) {
+		order = append(order, "owner")
+		return r.Value("record").(*record).Owner, nil
+	})
+	addr := serveRouter(t, r)
+
+	if code, body := call(t, addr, "GET", "/records/7", ""); code != 200 || body != "7:ana" {
+		t.Errorf("GET /records/7 = %d %q, want the loaded record", code, body)
+	}
+	if code, body := call(t, addr, "GET", "/records/7/owner", ""); code != 200 || body != "ana" {
+		t.Errorf("GET /records/7/owner = %d %q", code, body)
+	}
+	if fmt.Sprint(order) != "[record owner handler]" {
+		t.Errorf("run order = %v, want guards in order before the handler", order)
+	}
+}
+
+func TestGuardErrorSkipsHandler(t *testing.T) {
+	r := newTestRouter()
+	ran := false
+	handler := func(req *Request, w *Writer) { ran = true }
+	r.Get("/missing", handler).Guard(func(r *Request) (any, error) {
+		return nil, fmt.Errorf("record 9: %w", ErrNotFound)
+	})
+	r.Get("/forbidden", handler).Guard(func(r *Request) (any, error) {
+		return nil, StatusError(http.StatusForbidden, errors.New("not your record"))
+	})
+	r.Get("/broken", handler).Guard(func(r *Request) (any, error) {
+		return nil, errors.New("database down")
+	})
+	second := false
+	r.Get("/first-fails", handler).Guard(func(r *Request) (any, error) {
+		return nil, ErrNotFound
+	}).Guard(func(r *Request) (any, error) {
+		second = true
+		return nil, nil
+	})
+	addr := serveRouter(t, r)
+
+	for path, want := range map[string]struct {
+		code int
+		body string
+	}{
+		"/missing":     {404, "not found"},
+		"/forbidden":   {403, "not your record"},
+		"/broken":      {500, "internal server error"},
+		"/first-fails": {404, "not found"},
+	} {
+		if code, body := call(t, addr, "GET", path, ""); code != want.code || body != want.body {
+			t.Errorf("GET %s = %d %q, want %d %q", path, code, body, want.code, want.body)
+		}
+	}
+	if ran || second {
+		t.Errorf("handler ran %v, later guard ran %v after a guard error", ran, second)
+	}
+}
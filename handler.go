@@ -12,7 +12,13 @@ package gouter
 
 import (
 	"errors"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/Murilinho145SG/gouter/log"
 )
@@ -27,26 +33,153 @@ type handlerList map[string]Handler
 type Middleware func(handler Handler) Handler
 
 // router manages routes, middleware, and documentation
+// The zero value is ready to use and behaves like NewRouter
 type Router struct {
-	handlerList handlerList  // Map of registered routes
-	mws         []Middleware // List of global middlewares
-	docs        []*RouteInfo // Route documentation store
+	handlerList handlerList             // Map of registered routes
+	tree        routeNode               // Route patterns by segment, for lookups
+	exact       map[string]exactRoute   // Static routes, matched before any segmentation
+	mws         []middlewareEntry       // Global middlewares in registration order
+	infos       map[string]*RouteInfo   // Route metadata keyed like handlerList
+	methods     map[string]*methodTable // Method dispatch of routes registered with methods
+	docs        []*RouteInfo            // Route documentation store
 	docConfig   *Doc
+	docsMu      sync.Mutex // Guards docsAddr and serveURL
+	docsAddr    string     // Bound documentation address, empty when not serving
+	serveURL    string     // Websocket base URL of the served application, for the docs
+
+	observers        []Observer                    // Request and connection metrics hooks
+	inflight         inflightRegistry              // Requests currently being served
+	tracing          bool                          // Trace every request
+	traceOnHeader    bool                          // Trace requests sending X-Gouter-Trace
+	clk              Clock                         // Time source, SystemClock when nil
+	ids              IDGenerator                   // CSP nonces and Request.NewID, RandomIDs when nil
+	selfTest         selfTest                      // Startup self-test configuration and results
+	parserConfig     *ParserConfig                 // Request parsing limits and strictness
+	errorTemplates   map[ErrorFormat]errorTemplate // Overrides for synthesized error bodies
+	errorPages       map[string]errorTemplate      // HTML pages by status ("404") or class ("4xx")
+	errorMessages    map[string]languageMessages   // Translated status messages by lowercase language tag
+	soft             softLimits                    // Early-warning thresholds and counters
+	staticRoots      []string                      // Directories and files served from disk, checked on reload
+	hosts            []*hostScope                  // Host-scoped route sets, literal hosts first
+	parent           *Router                       // Router owning this host scope, nil for top-level routers
+	host             string                        // Host pattern of this scope
+	errorRing        *errorRing                    // Retained 5xx responses, nil unless RecordErrors was called
+	bufSizer         bufferSizer                   // Observed header sizes driving the read buffer size
+	methodOverride   bool                          // Let POST requests ask for PUT, PATCH or DELETE
+	redirectSlash    bool                          // Redirect paths differing from their route by a trailing slash
+	redirectFixed    bool                          // Redirect paths matching a route once cleaned or case-folded
+	methodNotFound   bool                          // Answer 404 instead of 405 to methods a path has no route for
+	noAutoOptions    bool                          // Answer OPTIONS like other methods without a route
+	surrogateHeaders []string                      // Headers carrying surrogate keys, nil for Surrogate-Key
+	uploadInspector  UploadInspector               // Checks files received by ParseMultipart and ReceiveFile
+	dev              devState                      // DevMode switch and asset polling
+	templates        *templateSet                  // Templates loaded by TemplateFiles
+	assets           *assetManifest                // Asset manifest loaded by AssetManifest
+	initOnce         sync.Once                     // Fills the fields of a zero-value Router
+
+	chainMu sync.Mutex    // Guards middleware lists, chains and their docs
+	mwGen   atomic.Uint64 // Bumped by Use, so route chains rebuild
+	chains  []*routeChain // Chains of the registered routes
+}
+
+// exactRoute is a static route served by the exact-match fast path
+type exactRoute struct {
+	handler Handler
+	info    *RouteInfo
 }
 
 // RouteInfo contains documentation metadata for a route
 type RouteInfo struct {
-	Method      string      // HTTP method (GET, POST, etc.)
-	Path        string      // Route path pattern
-	Description string      // Human-readable description
-	Parameters  []ParamInfo // List of path parameters
+	Method       string         // HTTP method (GET, POST, etc.)
+	Path         string         // Route path pattern
+	Description  string         // Human-readable description
+	Parameters   []ParamInfo    // List of path parameters
+	QueryParams  []ParamInfo    // Documented query parameters (see Paginated)
+	RequestBody  *BodyDoc       // Documented JSON request body (see SetRequestBody)
+	Responses    []BodyDoc      // Documented responses by status (see SetResponse)
+	Hidden       bool           // Excluded from the documentation UI
+	HandlerName  string         // Registered name, or file:line of registration
+	Middlewares  []string       // Names of the middlewares wrapping the handler, outermost first
+	Protocol     string         // "websocket" for upgrade routes, empty for plain HTTP
+	Host         string         // Host pattern the route is scoped to, empty for any host
+	Subprotocols []string       // Websocket subprotocols offered by the route
+	Examples     []RouteExample // Sample requests shown in the docs and run by RunExamples
+	Scopes       []string       // Scopes a caller needs (see RequireScopes)
+	Deprecation  string         // Deprecation note, empty while the route is current
+	Version      string         // API version tag (e.g., "v2")
+
+	methods      []string      // Methods the route was registered for, Method being the first
+	cors         *CORSConfig   // Route-level CORS policy layered over the global one
+	availability *availability // Time window and header gating
+	guards       []routeGuard  // Data loaders run before the handler
+	debugRate    float64       // Fraction of requests dumped to the log (see DebugSample)
+	chain        *routeChain   // Middleware chain of the route (see Use)
+	maxBody      int64         // Body limit replacing ParserConfig.MaxBodyBytes, 0 inherits it
 }
 
 // ParamInfo describes a path parameter
 type ParamInfo struct {
-	Name        string // Parameter name (e.g., "id")
-	Type        string // Expected data type
-	Description string // Parameter description
+	Name        string `json:"name"`                  // Parameter name (e.g., "id")
+	Type        string `json:"type,omitempty"`        // Expected data type
+	Description string `json:"description,omitempty"` // Parameter description
+}
+
+// MiddlewareOption configures how a middleware is registered
+type MiddlewareOption func(m *middlewareEntry)
+
+// middlewareEntry is a registered middleware with its settings
+type middlewareEntry struct {
+	mw       Middleware
+	name     string
+	priority int
+}
+
+// MiddlewareName labels a middleware in route table exports
+// Without it the name of the middleware function is used
+func MiddlewareName(name string) MiddlewareOption {
+	return func(m *middlewareEntry) {
+		m.name = name
+	}
+}
+
+// Priority moves a middleware within the chain of the routes it applies to
+// Higher priorities run first (outermost), wherever the middleware was
+// registered; equal priorities keep the default order. The default is 0,
+// so e.g. Priority(10) on a group middleware runs it before global ones
+func Priority(n int) MiddlewareOption {
+	return func(m *middlewareEntry) {
+		m.priority = n
+	}
+}
+
+// newMiddlewareEntry resolves the settings of a middleware from its options
+func newMiddlewareEntry(mw Middleware, opts []MiddlewareOption) middlewareEntry {
+	entry := middlewareEntry{mw: mw}
+	for _, opt := range opts {
+		opt(&entry)
+	}
+	if entry.name != "" {
+		return entry
+	}
+
+	entry.name = "unknown"
+	if fn := runtime.FuncForPC(reflect.ValueOf(mw).Pointer()); fn != nil {
+		entry.name = fn.Name()
+		if idx := strings.LastIndex(entry.name, "/"); idx != -1 {
+			entry.name = entry.name[idx+1:]
+		}
+	}
+	return entry
+}
+
+// outermostFirst returns the middlewares of one registration site in
+// execution order: the last registered middleware is the outermost one
+func outermostFirst(mws []middlewareEntry) []middlewareEntry {
+	chain := make([]middlewareEntry, 0, len(mws))
+	for i := len(mws) - 1; i >= 0; i-- {
+		chain = append(chain, mws[i])
+	}
+	return chain
 }
 
 // SetDescription sets the route description and returns modified RouteInfo
@@ -55,6 +188,18 @@ func (r *RouteInfo) SetDescription(desc string) *RouteInfo {
 	return r
 }
 
+// SetName sets the handler name used in route table exports
+func (r *RouteInfo) SetName(name string) *RouteInfo {
+	r.HandlerName = name
+	return r
+}
+
+// Hide excludes the route from the documentation UI
+func (r *RouteInfo) Hide() *RouteInfo {
+	r.Hidden = true
+	return r
+}
+
 // SetParam updates parameter metadata and returns modified RouteInfo
 func (r *RouteInfo) SetParam(paramName, ty, desc string) *RouteInfo {
 	for i, param := range r.Parameters {
@@ -69,20 +214,61 @@ func (r *RouteInfo) SetParam(paramName, ty, desc string) *RouteInfo {
 // NewRouter creates and returns a new router instance
 func NewRouter() *Router {
 	return &Router{
-		handlerList: make(handlerList),
-		docConfig: &Doc{
-			Active: true,
-			Port:   "7665",
-			Addrs:  "localhost",
-		},
+		handlerList:  make(handlerList),
+		exact:        make(map[string]exactRoute),
+		infos:        make(map[string]*RouteInfo),
+		methods:      make(map[string]*methodTable),
+		docConfig:    defaultDocConfig(),
+		parserConfig: defaultParserConfig(),
+	}
+}
+
+// defaultDocConfig serves the documentation on localhost:7665
+func defaultDocConfig() *Doc {
+	return &Doc{
+		Active: true,
+		Port:   "7665",
+		Addrs:  "localhost",
 	}
 }
 
+// lazyInit gives a zero-value Router the defaults of NewRouter
+// Registration and serving call it first, so `var r Router` works
+func (r *Router) lazyInit() {
+	r.initOnce.Do(func() {
+		if r.handlerList == nil {
+			r.handlerList = make(handlerList)
+		}
+		if r.exact == nil {
+			r.exact = make(map[string]exactRoute)
+		}
+		if r.infos == nil {
+			r.infos = make(map[string]*RouteInfo)
+		}
+		if r.methods == nil {
+			r.methods = make(map[string]*methodTable)
+		}
+		if r.docConfig == nil {
+			r.docConfig = defaultDocConfig()
+		}
+		if r.parserConfig == nil {
+			r.parserConfig = defaultParserConfig()
+		}
+	})
+}
+
 // Update doc configuration with logic
 func (r *Router) Update(callback func(d *Doc)) {
+	r.lazyInit()
 	callback(r.docConfig)
 }
 
+// UpdateParser changes request parsing limits and strictness flags
+func (r *Router) UpdateParser(callback func(p *ParserConfig)) {
+	r.lazyInit()
+	callback(r.parserConfig)
+}
+
 // parseRoute matches incoming requests to registered routes
 // Returns the appropriate handler or nil if no match found
 func (r *Router) parseRoute(req *Request) (Handler, string) {
@@ -90,121 +276,323 @@ func (r *Router) parseRoute(req *Request) (Handler, string) {
 		return nil, ""
 	}
 
-	routes := r.handlerList
+	// Host-scoped routes take precedence over the global ones
+	if len(r.hosts) > 0 {
+		if handler, basePath := r.parseHostRoute(req); handler != nil {
+			return handler, basePath
+		}
+	}
 
-	// Check for exact match
-	if err := routes.hasRoute(req.Path().reqPath); err == nil {
-		return routes.getHandler(req.Path().reqPath), req.Path().reqPath
+	// Static routes are matched on the raw path, before any segmentation
+	if e, ok := r.exact[req.path]; ok {
+		req.route = r.methodRoute(e.info, req.Method)
+		return e.handler, req.path
 	}
 
-	var originalPath string
+	// Everything else walks the route tree; parameters are bound only once
+	// the whole route matched
+	params := make(map[string]string)
+	pattern := r.tree.lookup(req.path, params)
+	if pattern == "" {
+		if h := r.fixedPathRedirect(req.path); h != nil {
+			return h, req.path
+		}
+		return nil, ""
+	}
+	if h := r.redirectFor(req.path, pattern); h != nil {
+		return h, req.path
+	}
+	for name, value := range params {
+		req.Params.add(name, value)
+	}
+	req.route = r.methodRoute(r.infos[pattern], req.Method)
 
-	// Check for wildcard and parameterized routes
-	for k := range routes {
-		// Handle wildcard routes (e.g., /static/*)
-		if strings.HasSuffix(k, "/*") {
-			baseRoute := strings.TrimSuffix(k, "/*")
-			if strings.HasPrefix(req.Path().reqPath, baseRoute) {
-				return routes.getHandler(k), baseRoute
-			}
+	basePath := pattern
+	if base, _, ok := splitCatchAll(pattern); ok {
+		basePath = base
+	}
+	return r.handlerList.getHandler(pattern), basePath
+}
+
+// methodRoute returns the route registered for method on the path of info
+// when the path is split by method, so per-route settings read before the
+// method dispatch (e.g. the body limit) are those of the request method
+// Methods without a route keep info
+func (r *Router) methodRoute(info *RouteInfo, method string) *RouteInfo {
+	if info == nil {
+		return nil
+	}
+	if t := r.methods[info.Path]; t != nil {
+		if e, ok := t.lookup(method); ok {
+			return e.info
 		}
+	}
+	return info
+}
 
-		// Split path segments for parameter matching
-		partsReq := strings.Split(strings.Trim(req.Path().reqPath, "/"), "/")
-		parts := strings.Split(strings.Trim(k, "/"), "/")
+// Route registers a new handler for a specific path
+// methods: Optional HTTP methods the handler answers; without them it answers
+// every method and is documented as GET. A path may be registered once per
+// method, and other methods get 405 with an Allow header. GET also answers
+// HEAD. See Get, Post, Put, Patch and Delete
+// Returns RouteInfo for documentation purposes
+func (r *Router) Route(path string, handler Handler, methods ...string) *RouteInfo {
+	return r.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), methods...)
+}
 
-		if len(parts) != len(partsReq) {
-			continue
+// callerLocation returns "file:line" of the function skip frames above the caller
+func callerLocation(skip int) string {
+	_, file, line, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return ""
+	}
+	return filepath.Base(file) + ":" + strconv.Itoa(line)
+}
+
+// addRoute registers a handler wrapped by the global and group middlewares
+// group is the innermost group of the route, nil outside groups, and
+// location is where the route was registered in user code
+func (r *Router) addRoute(path string, handler Handler, group *Group, location string, methods ...string) *RouteInfo {
+	r.lazyInit()
+	methods = normalizeMethods(methods)
+
+	if err := r.checkRoute(path, location, methods...); err != nil {
+		log.Warn(err.Error())
+		return nil
+	}
+
+	var table *methodTable
+	if len(methods) > 0 {
+		if table = r.methods[path]; table == nil {
+			table = r.newMethodTable(path, group, location, nil)
 		}
+	}
+	handler, doc := r.prepareRoute(path, handler, group, location, methods...)
+	r.insertRoute(path, handler, doc, table, methods)
+	return doc
+}
 
-		var matched = true
-		var currentPath string
-
-		// Match path segments
-		for i := 0; i < len(parts); i++ {
-			part := parts[i]
-			partReq := partsReq[i]
-
-			// Handle parameter segments (e.g., :id)
-			if strings.HasPrefix(part, ":") {
-				paramName := strings.TrimPrefix(part, ":")
-				req.Params.add(paramName, partReq)
-				currentPath += "/" + part
-			} else if part == partReq {
-				if part != "" {
-					currentPath += "/" + part
-				}
-			} else {
-				matched = false
-				break
+// checkRoute reports why path cannot be registered for methods, or nil
+func (r *Router) checkRoute(path, location string, methods ...string) error {
+	// Check for existing route; paths split by method take more methods
+	if r.handlerList[path] != nil {
+		t := r.methods[path]
+		if t == nil || len(methods) == 0 {
+			return &routeError{"This path [" + path + "] already exists (registered at " + location + ").", ErrRouteConflict}
+		}
+		for _, m := range methods {
+			if _, ok := t.routes[m]; ok {
+				return &routeError{"This path [" + path + "] already has a " + m + " handler (registered at " + location + ").", ErrRouteConflict}
 			}
 		}
+	}
 
-		if matched && len(parts) == len(partsReq) {
-			originalPath = currentPath
-			break
+	// Adjacent parameters have no literal to split on
+	for _, part := range strings.Split(path, "/") {
+		if strings.Contains(part, ":") && ambiguousSegment(parseSegment(part)) {
+			return &routeError{"This path [" + path + "] has adjacent parameters in one segment (registered at " + location + ").", nil}
 		}
 	}
 
-	return routes.getHandler(originalPath), originalPath
+	// A wildcard is the whole last segment, and a prefix takes one wildcard
+	if strings.Contains(path, "*") {
+		base, name, ok := splitCatchAll(path)
+		if !ok || strings.Contains(base, "*") || !isParamName(name) {
+			return &routeError{"This path [" + path + "] has a misplaced wildcard; it must be the last segment, \"*\" or \"*name\" (registered at " + location + ").", nil}
+		}
+		for other := range r.handlerList {
+			if b, _, ok := splitCatchAll(other); ok && other != path && strings.Trim(b, "/") == strings.Trim(base, "/") {
+				return &routeError{"This path [" + path + "] has the same prefix as the wildcard route [" + other + "] (registered at " + location + ").", ErrRouteConflict}
+			}
+		}
+	}
+	return nil
 }
 
-// Route registers a new handler for a specific path
-// methods: Optional HTTP method specification (defaults to GET)
-// Returns RouteInfo for documentation purposes
-func (r *Router) Route(path string, handler Handler, methods ...string) *RouteInfo {
-	// Check for existing route
-	if r.handlerList[path] != nil {
-		log.WarnE(2, "This path ["+path+"] already exists.")
-		return nil
-	}
+// routeError is a rejected registration; kind is the sentinel it matches
+type routeError struct {
+	msg  string
+	kind error
+}
+
+func (e *routeError) Error() string {
+	return e.msg
+}
+
+func (e *routeError) Unwrap() error {
+	return e.kind
+}
 
-	// Apply middleware chain
-	for _, mw := range r.mws {
-		handler = mw(handler)
+// docsOwner returns the router keeping the docs of r: its owner for host scopes
+func (r *Router) docsOwner() *Router {
+	if r.parent != nil {
+		return r.parent
 	}
+	return r
+}
 
-	r.handlerList[path] = handler
+// prepareRoute wraps handler in its middleware chain and builds its docs
+// entry, without registering either
+func (r *Router) prepareRoute(path string, handler Handler, group *Group, location string, methods ...string) (Handler, *RouteInfo) {
+	// Host scopes share the middlewares and docs of the owning router
+	top := r.docsOwner()
+
+	// The chain is composed on the first request, so later Use calls apply
+	chain := newRouteChain(top, group, handler)
+	handler = chain.serve
 
 	// Create documentation entry
-	doc := RouteInfo{
+	doc := &RouteInfo{
 		Path:   path,
 		Method: "GET", // Default method
+		Host:   r.host,
 	}
 
+	// Availability checks run first so unavailable routes behave as missing
+	handler = availabilityGuard(top, doc, handler)
+
 	if len(methods) > 0 {
-		doc.Method = methods[0]
+		doc.Method, doc.methods = methods[0], methods
 	}
 
+	// Identify the handler by where it was registered
+	doc.HandlerName = location
+
+	doc.chain = chain
+	chain.docs = []*RouteInfo{doc}
+	top.chainMu.Lock()
+	doc.Middlewares = chain.names()
+	top.chainMu.Unlock()
+
 	doc.Parameters = []ParamInfo{}
 
-	// Extract parameters from path
+	// Host parameters are bound like path parameters
+	if r.host != "" {
+		for _, label := range strings.Split(r.host, ".") {
+			for _, paramName := range segmentParams(label) {
+				doc.Parameters = append(doc.Parameters, ParamInfo{Name: paramName})
+			}
+		}
+	}
+
+	// Extract parameters from path, including several per segment
 	parts := strings.Split(path, "/")
 	for _, part := range parts {
-		if strings.HasPrefix(part, ":") {
-			paramName := strings.TrimPrefix(part, ":")
+		for _, paramName := range segmentParams(part) {
 			doc.Parameters = append(doc.Parameters, ParamInfo{
 				Name: paramName,
 			})
 		}
 	}
+	if _, name, ok := splitCatchAll(path); ok && name != "" {
+		doc.Parameters = append(doc.Parameters, ParamInfo{Name: name})
+	}
+
+	return handler, doc
+}
+
+// insertRoute adds a prepared route to the live table and the docs
+// Routes registered with methods go in table, the method dispatch of path
+func (r *Router) insertRoute(path string, handler Handler, doc *RouteInfo, table *methodTable, methods []string) {
+	top := r.docsOwner()
+	top.docs = append(top.docs, doc)
+	if doc.chain != nil {
+		top.chainMu.Lock()
+		top.chains = append(top.chains, doc.chain)
+		top.chainMu.Unlock()
+	}
 
-	r.docs = append(r.docs, &doc)
+	if table != nil {
+		table.add(handler, doc, methods)
+		if r.handlerList[path] != nil {
+			return
+		}
+		r.methods[path] = table
+		handler = table.serve
+	}
+	r.handlerList[path] = handler
+	r.infos[path] = doc
+	r.tree.insert(path)
 
-	return &doc
+	// Patterns without parameters or wildcards also go in the fast path
+	if !strings.ContainsAny(path, ":*") {
+		r.exact[path] = exactRoute{handler: handler, info: doc}
+	}
 }
 
 // Use adds middleware to the global middleware chain
-func (r *Router) Use(mw Middleware) {
-	r.mws = append(r.mws, mw)
+// It applies to every route, including those registered before: chains are
+// composed again on the next request. The default order of a route chain,
+// outermost first, is:
+//   - availability checks (see AvailableAfter)
+//   - global middlewares, the last registered one outermost
+//   - group middlewares, outer groups first, the last registered one outermost
+//   - route middlewares (see RouteInfo.Use), the last given one outermost
+//   - guards (see Guard), then the handler
+//
+// Priority reorders the global, group and route middlewares; see MiddlewareChain
+func (r *Router) Use(mw Middleware, opts ...MiddlewareOption) {
+	r.chainMu.Lock()
+	defer r.chainMu.Unlock()
+	r.mws = append(r.mws, newMiddlewareEntry(mw, opts))
+	r.middlewaresChanged()
 }
 
-// hasRoute checks if a path exists in the handler list
-func (h handlerList) hasRoute(path string) error {
-	if h[path] == nil {
-		return errors.New("path is not founded")
+// MiddlewareChain returns the effective chain of a route, outermost first
+// path is a route pattern or a request path matched like an incoming request
+// Built-in layers are reported as "availability" and "guard:<key>" when the
+// route uses them, and the handler as "handler". Returns nil when no route
+// matches or the route is registered for another method
+func (r *Router) MiddlewareChain(path, method string) []string {
+	info := r.infos[path]
+	if info == nil {
+		req := newRequest()
+		req.path = path
+		if h, _ := r.parseRoute(req); h == nil {
+			return nil
+		}
+		info = req.route
 	}
-	return nil
+	if info == nil {
+		return nil
+	}
+	if t := r.methods[info.Path]; t != nil && method != "" {
+		e, ok := t.lookup(strings.ToUpper(method))
+		if !ok {
+			return nil
+		}
+		info = e.info
+	}
+
+	var chain []string
+	if info.availability != nil {
+		chain = append(chain, "availability")
+	}
+	chain = append(chain, info.Middlewares...)
+	for _, g := range info.guards {
+		key := g.key
+		if key == "" {
+			key = info.HandlerName
+		}
+		chain = append(chain, "guard:"+key)
+	}
+	return append(chain, "handler")
+}
+
+// visibleDocs returns the documented routes that are not hidden
+func (r *Router) visibleDocs() []*RouteInfo {
+	routes := make([]*RouteInfo, 0, len(r.docs))
+	now := r.clock()
+	for _, doc := range r.docs {
+		if doc.Hidden {
+			continue
+		}
+		if a := doc.availability; a != nil && a.hideDocs && !a.inWindow(now) {
+			continue
+		}
+		routes = append(routes, doc)
+	}
+	return routes
 }
 
 // getHandler retrieves the handler for a specific path
@@ -212,41 +600,142 @@ func (h handlerList) getHandler(path string) Handler {
 	return h[path]
 }
 
+// ErrEmptyGroupPrefix is returned when a group prefix normalizes to nothing
+var ErrEmptyGroupPrefix = errors.New("group prefix is empty after normalization")
+
+// ErrRouteConflict is wrapped by the errors of paths that are already registered
+var ErrRouteConflict = errors.New("route conflict")
+
+// ErrNoRouter is returned when a Group or Server has no Router to work on,
+// e.g. a zero-value Group instead of one passed to a GroupFunc
+var ErrNoRouter = errors.New("no router")
+
 // Group creates a route group with common configuration
-func (r *Router) Group(path string, handler GroupFunc) {
-	handler(newGroup(r, path))
+// The prefix is normalized: repeated slashes are collapsed, a leading slash is
+// added and trailing slashes are removed, so "auth/" and "/auth" are equivalent.
+// Returns ErrEmptyGroupPrefix (without calling handler) for "" or "/"
+func (r *Router) Group(path string, handler GroupFunc) error {
+	prefix, err := normalizeGroupPrefix(path)
+	if err != nil {
+		log.WarnE(3, err.Error()+": ["+path+"]")
+		return err
+	}
+
+	handler(newGroup(r, nil, prefix))
+	return nil
 }
 
 // Group represents a set of routes with shared configuration
 type Group struct {
-	router    *Router      // Parent router
-	pathGroup string       // Group path prefix
-	mw        []Middleware // Group-specific middleware
+	router    *Router           // Parent router
+	parent    *Group            // Enclosing group for nested groups
+	pathGroup string            // Normalized group path prefix
+	mw        []middlewareEntry // Group-specific middleware in registration order
 }
 
 // GroupFunc defines the function signature for group configuration
 type GroupFunc func(g *Group)
 
 // newGroup creates a new route group instance
-func newGroup(router *Router, pathGroup string) *Group {
+func newGroup(router *Router, parent *Group, pathGroup string) *Group {
 	return &Group{
 		router:    router,
+		parent:    parent,
 		pathGroup: pathGroup,
 	}
 }
 
+// normalizeGroupPrefix cleans a group prefix into the "/segment" form
+func normalizeGroupPrefix(path string) (string, error) {
+	prefix := strings.TrimRight(collapseSlashes("/"+path), "/")
+	if prefix == "" {
+		return "", ErrEmptyGroupPrefix
+	}
+	return prefix, nil
+}
+
+// collapseSlashes replaces runs of slashes with a single slash
+func collapseSlashes(path string) string {
+	if !strings.Contains(path, "//") {
+		return path
+	}
+
+	var b strings.Builder
+	b.Grow(len(path))
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' && i > 0 && path[i-1] == '/' {
+			continue
+		}
+		b.WriteByte(path[i])
+	}
+	return b.String()
+}
+
+// joinRoutePath appends a route path to a normalized group prefix
+// An empty path, or "/" under a prefix, registers the prefix itself
+func joinRoutePath(prefix, path string) string {
+	if path == "" || prefix != "" && strings.Trim(path, "/") == "" {
+		return prefix
+	}
+	return collapseSlashes(prefix + "/" + path)
+}
+
+// Group creates a nested group whose prefix and middlewares extend this group's
+// Returns ErrNoRouter for a zero-value Group
+func (g *Group) Group(path string, handler GroupFunc) error {
+	if g.router == nil {
+		log.WarnE(3, ErrNoRouter.Error()+": group ["+path+"] is not attached to a Router")
+		return ErrNoRouter
+	}
+
+	prefix, err := normalizeGroupPrefix(path)
+	if err != nil {
+		log.WarnE(3, err.Error()+": ["+path+"]")
+		return err
+	}
+
+	handler(newGroup(g.router, g, prefix))
+	return nil
+}
+
 // Route registers a route within the group
+// Groups only exist inside a GroupFunc: on a zero-value Group the route is
+// not registered, a warning names ErrNoRouter and nil is returned
 func (g *Group) Route(path string, handler Handler, methods ...string) *RouteInfo {
-	// Apply group middleware
-	for _, mw := range g.mw {
-		handler = mw(handler)
+	return g.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), methods...)
+}
+
+// addRoute prepends the group prefix, then hands the route to the enclosing
+// group or router; inner is the innermost group of the route, g when nil,
+// whose middlewares and those of its parents wrap the handler
+func (g *Group) addRoute(path string, handler Handler, inner *Group, location string, methods ...string) *RouteInfo {
+	if inner == nil {
+		inner = g
 	}
 
 	// Register route with group prefix
-	return g.router.Route(g.pathGroup+path, handler, methods...)
+	path = joinRoutePath(g.pathGroup, path)
+	if g.router == nil {
+		log.Warn(ErrNoRouter.Error() + ": route [" + path + "] (registered at " + location + ") is not attached to a Router")
+		return nil
+	}
+	if g.parent != nil {
+		return g.parent.addRoute(path, handler, inner, location, methods...)
+	}
+	return g.router.addRoute(path, handler, inner, location, methods...)
 }
 
 // Use adds middleware to the group's middleware chain
-func (g *Group) Use(mw Middleware) {
-	g.mw = append(g.mw, mw)
+// Like Router.Use it applies to routes registered before as well; see
+// Router.Use for the default order
+func (g *Group) Use(mw Middleware, opts ...MiddlewareOption) {
+	if g.router == nil {
+		g.mw = append(g.mw, newMiddlewareEntry(mw, opts))
+		return
+	}
+	top := g.router.docsOwner()
+	top.chainMu.Lock()
+	defer top.chainMu.Unlock()
+	g.mw = append(g.mw, newMiddlewareEntry(mw, opts))
+	top.middlewaresChanged()
 }
@@ -0,0 +1,213 @@
+package gouter
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// routingRouter has static routes overlapping parameter and wildcard ones
+func routingRouter() *Router {
+	r := newTestRouter()
+	reply := func(name string) Handler {
+		return func(req *Request, w *Writer) {
+			w.Write([]byte(name + " " + req.Params.Get("id") + req.Params.Get("page") + req.Params.Get("rest")))
+		}
+	}
+	r.Get("/", reply("root"))
+	r.Get("/healthz", reply("healthz"))
+	r.Get("/:page", reply("page"))
+	r.Get("/users/me", reply("me"))
+	r.Get("/users/:id", reply("user"))
+	r.Get("/users/:id/avatar", reply("avatar"))
+	r.Route("/files/*rest", reply("files"))
+	r.Get("/files/index", reply("index"))
+	r.HostRoute("api.example.com", "/healthz", reply("api-healthz"))
+	return r
+}
+
+func TestExactRoutePrecedence(t *testing.T) {
+	addr := serveRouter(t, routingRouter())
+	cases := []struct{ host, path, want string }{
+		{"x", "/", "root "},
+		{"x", "/healthz", "healthz "},
+		{"x", "/metrics", "page metrics"},
+		{"x", "/users/me", "me "},
+		{"x", "/users/42", "user 42"},
+		{"x", "/users/me/avatar", "avatar me"},
+		{"x", "/files/index", "index "},
+		{"x", "/files/a/b", "files a/b"},
+		{"api.example.com", "/healthz", "api-healthz "},
+		{"api.example.com", "/users/me", "me "},
+	}
+	for _, tc := range cases {
+		resp := rawExchange(t, addr, "GET "+tc.path+" HTTP/1.1\r\nHost: "+tc.host+"\r\n\r\n", 1)[0]
+		if got := bodyString(t, resp); got != tc.want {
+			t.Errorf("GET %s%s = %q, want %q", tc.host, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestExactRouteAllocatesNothing(t *testing.T) {
+	r := routingRouter()
+	// Host scopes are checked first, whatever the Host header looks like
+	for _, host := range []string{"", "example.com", "example.com:8080", "[::1]", "[::1]:8080", "www.api.example.com"} {
+		req := newRequest()
+		req.path = "/healthz"
+		req.Headers.Add("Host", host)
+		allocs := testing.AllocsPerRun(1000, func() {
+			if h, _ := r.parseRoute(req); h == nil {
+				t.Fatal("no route for /healthz")
+			}
+		})
+		if allocs != 0 {
+			t.Errorf("exact route lookup with Host %q allocated %v times, want 0", host, allocs)
+		}
+	}
+}
+
+// benchmarkRoute measures matching path against routingRouter
+func benchmarkRoute(b *testing.B, path string) {
+	r := routingRouter()
+	req := newRequest()
+	req.path = path
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if h, _ := r.parseRoute(req); h == nil {
+			b.Fatalf("no route for %s", path)
+		}
+	}
+}
+
+func BenchmarkRouteExact(b *testing.B)    { benchmarkRoute(b, "/healthz") }
+func BenchmarkRouteParam(b *testing.B)    { benchmarkRoute(b, "/users/42/avatar") }
+func BenchmarkRouteWildcard(b *testing.B) { benchmarkRoute(b, "/files/a/b/c") }
+
+func TestMiddlewareChainMatchesExecution(t *testing.T) {
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, name)
+	}
+	layer := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *Request, w *Writer) {
+				record(name)
+				next(req, w)
+			}
+		}
+	}
+
+	r := newTestRouter()
+	r.Use(layer("first"), MiddlewareName("first"))
+	r.Use(layer("second"), MiddlewareName("second"))
+	r.Use(layer("late"), MiddlewareName("late"), Priority(-1))
+	r.Group("/api", func(g *Group) {
+		g.Use(layer("outer"), MiddlewareName("outer"))
+		g.Group("/v1", func(g *Group) {
+			g.Use(layer("inner"), MiddlewareName("inner"))
+			g.Use(layer("urgent"), MiddlewareName("urgent"), Priority(10))
+			g.Post("/items/:id", func(req *Request, w *Writer) {
+				record("handler")
+			}).GuardAs("item", func(req *Request) (any, error) {
+				record("guard:item")
+				return req.Params.Get("id"), nil
+			})
+		})
+	})
+	r.Get("/sale", func(req *Request, w *Writer) {}).AvailableAfter(time.Unix(0, 0))
+	addr := serveRouter(t, r)
+
+	want := []string{"urgent", "second", "first", "outer", "inner", "late", "guard:item", "handler"}
+	for _, path := range []string{"/api/v1/items/:id", "/api/v1/items/7"} {
+		if got := r.MiddlewareChain(path, "post"); !reflect.DeepEqual(got, want) {
+			t.Errorf("MiddlewareChain(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	resp := rawExchange(t, addr, "POST /api/v1/items/7 HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n", 1)[0]
+	if resp.StatusCode != 200 {
+		t.Fatalf("status %d, want 200", resp.StatusCode)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("execution order = %s, want the reported chain %s", strings.Join(ran, " > "), strings.Join(want, " > "))
+	}
+
+	if got := r.MiddlewareChain("/api/v1/items/7", "GET"); got != nil {
+		t.Errorf("chain for an unregistered method = %v, want nil", got)
+	}
+	sale := []string{"availability", "second", "first", "late", "handler"}
+	if got := r.MiddlewareChain("/sale", "GET"); !reflect.DeepEqual(got, sale) {
+		t.Errorf("chain of a scheduled route = %v, want %v", got, sale)
+	}
+	if got := r.MiddlewareChain("/nowhere", ""); got != nil {
+		t.Errorf("chain for an unknown path = %v, want nil", got)
+	}
+}
+
+func TestZeroValueRouter(t *testing.T) {
+	var r Router
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			w.Headers.Add("X-Mw", "1")
+			next(req, w)
+		}
+	})
+	r.Route("/a", func(req *Request, w *Writer) { w.Write([]byte("a")) })
+	if err := r.Group("/g", func(g *Group) {
+		g.Get("/b", func(req *Request, w *Writer) { w.Write([]byte("b")) })
+	}); err != nil {
+		t.Fatalf("Group on a zero-value Router: %v", err)
+	}
+	r.Update(func(d *Doc) { d.Active = false })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- Serve(l, &r) }()
+
+	resps := rawExchange(t, l.Addr().String(), "GET /a HTTP/1.1\r\nHost: x\r\n\r\nGET /g/b HTTP/1.1\r\nHost: x\r\n\r\nGET /c HTTP/1.1\r\nHost: x\r\n\r\n", 3)
+	for i, want := range []string{"a", "b"} {
+		if got := bodyString(t, resps[i]); got != want || resps[i].Header.Get("X-Mw") != "1" {
+			t.Errorf("response %d = %q X-Mw %q, want %q through the middleware", i+1, got, resps[i].Header.Get("X-Mw"), want)
+		}
+	}
+	if resps[2].StatusCode != 404 {
+		t.Errorf("unknown path = %d, want 404", resps[2].StatusCode)
+	}
+	l.Close()
+	if err := <-served; !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Serve after closing the listener = %v, want net.ErrClosed", err)
+	}
+}
+
+func TestNoRouter(t *testing.T) {
+	if err := Run("127.0.0.1:0", nil); !errors.Is(err, ErrNoRouter) {
+		t.Errorf("Run without a Router = %v, want ErrNoRouter", err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if err := (&Server{}).Serve(l); !errors.Is(err, ErrNoRouter) {
+		t.Errorf("Server.Serve without a Router = %v, want ErrNoRouter", err)
+	}
+	if err := (&Server{Addr: "127.0.0.1:0"}).ListenAndServe(); !errors.Is(err, ErrNoRouter) {
+		t.Errorf("Server.ListenAndServe without a Router = %v, want ErrNoRouter", err)
+	}
+	if err := (&Server{Addr: "127.0.0.1:0"}).ListenAndServeTLS("cert.pem", "key.pem"); !errors.Is(err, ErrNoRouter) {
+		t.Errorf("Server.ListenAndServeTLS without a Router = %v, want ErrNoRouter", err)
+	}
+}
@@ -0,0 +1,231 @@
+package gouter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// defaultHandshakeTimeout bounds TLS handshakes when Server.HandshakeTimeout is zero
+const defaultHandshakeTimeout = 5 * time.Second
+
+// handshakeLogLimit is the number of client IPs remembered for rate-limited
+// reports; older entries are dropped past it
+const handshakeLogLimit = 4096
+
+// helloNames holds the SNI name of each ClientHello until its handshake
+// ends, keyed by the raw connection
+var helloNames sync.Map
+
+// recordServerName makes config remember the SNI name of each ClientHello,
+// so failed handshakes still report it
+func recordServerName(config *tls.Config) {
+	next := config.GetConfigForClient
+	config.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+		helloNames.Store(hello.Conn, hello.ServerName)
+		if next != nil {
+			return next(hello)
+		}
+		return nil, nil
+	}
+}
+
+// HandshakeError describes a failed TLS handshake
+type HandshakeError struct {
+	RemoteAddr string // Client address
+	ServerName string // SNI name the client asked for, empty if it sent none or it is unknown
+	Timeout    bool   // The client did not finish within the handshake timeout
+	Err        error
+}
+
+func (e *HandshakeError) Error() string {
+	msg := "TLS handshake from " + e.RemoteAddr
+	if e.ServerName != "" {
+		msg += " for " + e.ServerName
+	}
+	if e.Timeout {
+		return msg + " timed out"
+	}
+	return msg + " failed: " + e.Err.Error()
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// HandshakeStats describes a finished TLS handshake
+type HandshakeStats struct {
+	RemoteAddr string        // Client address
+	ServerName string        // SNI name the client asked for
+	Version    uint16        // Negotiated TLS version, 0 on failure
+	Duration   time.Duration // Time spent in the handshake
+	Err        error         // *HandshakeError on failure
+}
+
+// HandshakeObserver is implemented by observers that also want TLS
+// handshake events; register it with Router.Observe
+type HandshakeObserver interface {
+	HandshakeDone(s HandshakeStats)
+}
+
+// HandshakeCounts counts the TLS handshakes of a Server
+type HandshakeCounts struct {
+	Succeeded int64
+	Failed    int64 // Failures other than timeouts
+	TimedOut  int64
+}
+
+// Handshakes returns the TLS handshake counters of the server
+func (s *Server) Handshakes() HandshakeCounts {
+	return s.handshakes.counts()
+}
+
+// handshakeReporter counts handshakes and reports failures, at most once
+// per interval for each client IP
+type handshakeReporter struct {
+	succeeded, failed, timedOut atomic.Int64
+
+	mu       sync.Mutex
+	timeout  time.Duration
+	interval time.Duration
+	onError  func(error)
+	reported map[string]time.Time // Last report per client IP
+}
+
+// configure applies the Server settings
+func (h *handshakeReporter) configure(timeout, interval time.Duration, onError func(error)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeout, h.interval, h.onError = timeout, interval, onError
+}
+
+func (h *handshakeReporter) counts() HandshakeCounts {
+	return HandshakeCounts{
+		Succeeded: h.succeeded.Load(),
+		Failed:    h.failed.Load(),
+		TimedOut:  h.timedOut.Load(),
+	}
+}
+
+// handshake completes the TLS handshake of a new connection
+// Returns false when the connection failed and must be closed
+// A nil reporter (Router.RunTLS) uses the defaults and logs every failure
+func (h *handshakeReporter) handshake(tc *tls.Conn, r *Router) bool {
+	timeout, interval, onError := defaultHandshakeTimeout, time.Duration(0), func(err error) { log.Error(err) }
+	if h != nil {
+		h.mu.Lock()
+		if h.timeout > 0 {
+			timeout = h.timeout
+		}
+		interval = h.interval
+		if h.onError != nil {
+			onError = h.onError
+		}
+		h.mu.Unlock()
+	}
+
+	start := time.Now()
+	tc.SetDeadline(start.Add(timeout))
+	err := tc.Handshake()
+	tc.SetDeadline(time.Time{})
+
+	// Listeners built by the Server record the name before the handshake
+	// can fail; others only know it on success
+	state := tc.ConnectionState()
+	stats := HandshakeStats{
+		RemoteAddr: tc.RemoteAddr().String(),
+		ServerName: state.ServerName,
+		Duration:   time.Since(start),
+	}
+	if name, ok := helloNames.LoadAndDelete(tc.NetConn()); ok && stats.ServerName == "" {
+		stats.ServerName = name.(string)
+	}
+	if err == nil {
+		stats.Version = state.Version
+		if h != nil {
+			h.succeeded.Add(1)
+		}
+		r.notifyHandshake(stats)
+		return true
+	}
+
+	var ne net.Error
+	herr := &HandshakeError{
+		RemoteAddr: stats.RemoteAddr,
+		ServerName: stats.ServerName,
+		Timeout:    errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &ne) && ne.Timeout()),
+		Err:        err,
+	}
+	stats.Err = herr
+	if h != nil {
+		if herr.Timeout {
+			h.timedOut.Add(1)
+		} else {
+			h.failed.Add(1)
+		}
+	}
+	r.notifyHandshake(stats)
+
+	if h.shouldReport(stats.RemoteAddr, interval) {
+		onError(herr)
+	}
+	return false
+}
+
+// shouldReport rate-limits failure reports per client IP
+func (h *handshakeReporter) shouldReport(addr string, interval time.Duration) bool {
+	if h == nil || interval <= 0 {
+		return true
+	}
+	ip, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		ip = addr
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if last, ok := h.reported[ip]; ok && now.Sub(last) < interval {
+		return false
+	}
+	if h.reported == nil {
+		h.reported = make(map[string]time.Time)
+	}
+	if len(h.reported) >= handshakeLogLimit {
+		for k, last := range h.reported {
+			if now.Sub(last) >= interval {
+				delete(h.reported, k)
+			}
+		}
+		// Every entry is recent: forget an arbitrary one
+		if len(h.reported) >= handshakeLogLimit {
+			for k := range h.reported {
+				delete(h.reported, k)
+				break
+			}
+		}
+	}
+	h.reported[ip] = now
+	return true
+}
+
+// notifyHandshake passes a handshake to the observers that want it
+func (r *Router) notifyHandshake(s HandshakeStats) {
+	for _, o := range r.observers {
+		if ho, ok := o.(HandshakeObserver); ok {
+			ho.HandshakeDone(s)
+		}
+	}
+}
+
+// String formats the counters for logs
+func (c HandshakeCounts) String() string {
+	return fmt.Sprintf("%d succeeded, %d failed, %d timed out", c.Succeeded, c.Failed, c.TimedOut)
+}
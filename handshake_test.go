@@ -0,0 +1,214 @@
+package gouter
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// handshakeEvents records the reported errors and observed handshakes
+type handshakeEvents struct {
+	mu       sync.Mutex
+	reports  []error
+	observed []HandshakeStats
+}
+
+func (e *handshakeEvents) report(err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.reports = append(e.reports, err)
+}
+
+func (e *handshakeEvents) RequestDone(RequestTiming) {}
+func (e *handshakeEvents) ConnClosed(ConnStats)      {}
+
+func (e *handshakeEvents) HandshakeDone(s HandshakeStats) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.observed = append(e.observed, s)
+}
+
+func (e *handshakeEvents) snapshot() ([]error, []HandshakeStats) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]error(nil), e.reports...), append([]HandshakeStats(nil), e.observed...)
+}
+
+// serveHandshakes serves s over TLS with a fresh certificate, reporting to
+// a new handshakeEvents
+// Readiness is probed with a full handshake, counted as Succeeded but left
+// out of the events
+func serveHandshakes(t *testing.T, s *Server) (string, *handshakeEvents) {
+	t.Helper()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "handshake")
+
+	events := &handshakeEvents{}
+	s.Router = newTestRouter()
+	s.Router.Observe(events)
+	s.Router.Get("/", func(r *Request, w *Writer) {})
+	s.ErrorHandler = events.report
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Addr = l.Addr().String()
+	l.Close()
+	go s.ListenAndServeTLS(certFile, keyFile)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	eventually(t, "the TLS listener", func() bool {
+		c, err := tls.Dial("tcp", s.Addr, &tls.Config{InsecureSkipVerify: true})
+		if err == nil {
+			c.Close()
+		}
+		return err == nil
+	})
+	eventually(t, "the probe handshake", func() bool {
+		_, observed := events.snapshot()
+		return len(observed) == 1
+	})
+
+	events.mu.Lock()
+	events.reports, events.observed = nil, nil
+	events.mu.Unlock()
+	return s.Addr, events
+}
+
+func TestHandshakeTimeout(t *testing.T) {
+	s := &Server{HandshakeTimeout: 300 * time.Millisecond, HandshakeLogInterval: time.Minute}
+	addr, events := serveHandshakes(t, s)
+
+	// Clients that connect and never say hello
+	start := time.Now()
+	var silent []net.Conn
+	for i := 0; i < 3; i++ {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		silent = append(silent, c)
+	}
+
+	// They do not hold up a client that does handshake
+	c, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true, ServerName: "fast.example.test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= s.HandshakeTimeout {
+		t.Errorf("handshake behind silent clients took %v", elapsed)
+	}
+	c.Close()
+
+	for i, c := range silent {
+		c.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if _, err := c.Read(make([]byte, 1)); err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Fatalf("silent client %d: read = %v, want the server to close", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < s.HandshakeTimeout || elapsed > 2*time.Second {
+		t.Errorf("silent clients closed after %v, want about %v", elapsed, s.HandshakeTimeout)
+	}
+
+	eventually(t, "the handshake counts", func() bool {
+		return s.Handshakes() == HandshakeCounts{Succeeded: 2, TimedOut: 3}
+	})
+
+	// Three timeouts from one IP inside the interval make a single report
+	reports, observed := events.snapshot()
+	if len(reports) != 1 {
+		t.Fatalf("reports = %v, want one", reports)
+	}
+	var he *HandshakeError
+	if !errors.As(reports[0], &he) || !he.Timeout || he.ServerName != "" {
+		t.Errorf("report = %#v, want a timeout without SNI", reports[0])
+	}
+	if host, _, _ := net.SplitHostPort(he.RemoteAddr); host != "127.0.0.1" {
+		t.Errorf("report address = %q", he.RemoteAddr)
+	}
+
+	// Observers see every handshake, reported or not
+	var ok, failed int
+	for _, st := range observed {
+		if st.Err == nil {
+			ok++
+			if st.ServerName != "fast.example.test" || st.Version != tls.VersionTLS13 {
+				t.Errorf("success = %+v", st)
+			}
+		} else {
+			failed++
+		}
+	}
+	if ok != 1 || failed != 3 {
+		t.Errorf("observed %d successes and %d failures, want 1 and 3", ok, failed)
+	}
+}
+
+func TestHandshakeFailureReportsSNI(t *testing.T) {
+	s := &Server{}
+	addr, events := serveHandshakes(t, s)
+
+	// The client distrusts the self-signed certificate and aborts; without
+	// a log interval each failure is reported
+	for i := 0; i < 2; i++ {
+		c, err := tls.Dial("tcp", addr, &tls.Config{ServerName: "api.example.test"})
+		if err == nil {
+			c.Close()
+			t.Fatal("handshake with an untrusted certificate succeeded")
+		}
+	}
+
+	eventually(t, "two reports", func() bool {
+		reports, _ := events.snapshot()
+		return len(reports) == 2
+	})
+	reports, observed := events.snapshot()
+	for _, err := range reports {
+		var he *HandshakeError
+		if !errors.As(err, &he) || he.Timeout || he.ServerName != "api.example.test" || he.Err == nil {
+			t.Errorf("report = %#v, want a failure for api.example.test", err)
+		}
+	}
+	if got := s.Handshakes(); got != (HandshakeCounts{Succeeded: 1, Failed: 2}) {
+		t.Errorf("counts = %v", got)
+	}
+	if len(observed) != 2 || observed[0].Version != 0 || observed[0].Err == nil {
+		t.Errorf("observed = %+v", observed)
+	}
+}
+
+func TestHandshakeReportLimiter(t *testing.T) {
+	var h handshakeReporter
+	if !h.shouldReport("10.0.0.1:1000", time.Hour) {
+		t.Fatal("first failure not reported")
+	}
+	if h.shouldReport("10.0.0.1:2000", time.Hour) {
+		t.Error("second failure from the same IP reported")
+	}
+	if !h.shouldReport("10.0.0.2:1000", time.Hour) {
+		t.Error("failure from another IP not reported")
+	}
+	if !h.shouldReport("10.0.0.1:3000", 0) {
+		t.Error("failure not reported without an interval")
+	}
+
+	// The remembered IPs stay bounded
+	for i := 0; i < handshakeLogLimit+10; i++ {
+		h.shouldReport(net.IPv4(10, 1, byte(i>>8), byte(i)).String()+":1", time.Hour)
+	}
+	if n := len(h.reported); n > handshakeLogLimit {
+		t.Errorf("remembered %d IPs, limit %d", n, handshakeLogLimit)
+	}
+}
@@ -0,0 +1,150 @@
+package gouter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Hardening lists every setting applied by the hardened presets
+// Start from DefaultHardening, override individual fields, then build the
+// Router and Server from it; the values stay inspectable for audits
+//
+// Panic recovery is not listed: every router turns handler panics into 500
+// responses. Forwarding headers (X-Forwarded-For, Forwarded) are never used
+// for Request.RemoteIP, so there is no trusted-proxy setting to turn off
+type Hardening struct {
+	MaxHeaderBytes int           // Parser limit for request line plus headers
+	MaxHeaderCount int           // Parser limit for header lines
+	HeaderTimeout  time.Duration // Deadline for receiving the header block
+	MaxBodyBytes   int64         // Parser limit for the request body
+
+	ReadTimeout   time.Duration // Server deadline for reading a whole request
+	WriteTimeout  time.Duration // Server deadline for writing the response
+	IdleTimeout   time.Duration // Server limit for idle connections
+	MinTLSVersion uint16        // Lowest TLS version accepted by the server
+
+	SecurityHeaders      map[string]string // Headers added to every response
+	SuppressServerHeader bool              // Remove any Server header set by handlers
+	RejectMethods        []string          // Methods answered with 405 (e.g., TRACE)
+	Docs                 bool              // Keep the documentation server enabled
+}
+
+// DefaultHardening returns the settings used by NewHardenedRouter and HardenedServer
+func DefaultHardening() Hardening {
+	return Hardening{
+		MaxHeaderBytes: 64 << 10,
+		MaxHeaderCount: 50,
+		HeaderTimeout:  5 * time.Second,
+		MaxBodyBytes:   10 << 20,
+
+		ReadTimeout:   30 * time.Second,
+		WriteTimeout:  30 * time.Second,
+		IdleTimeout:   60 * time.Second,
+		MinTLSVersion: tls.VersionTLS12,
+
+		SecurityHeaders:      DefaultSecurityHeaders(),
+		SuppressServerHeader: true,
+		RejectMethods:        []string{"TRACE", "TRACK"},
+		Docs:                 false,
+	}
+}
+
+// DefaultSecurityHeaders returns the headers added by the hardened preset
+func DefaultSecurityHeaders() map[string]string {
+	return map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "no-referrer",
+		"Content-Security-Policy":   "default-src 'self'; frame-ancestors 'none'",
+		"Strict-Transport-Security": "max-age=63072000; includeSubDomains",
+	}
+}
+
+// NewHardenedRouter creates a router with the DefaultHardening settings
+func NewHardenedRouter() *Router {
+	return DefaultHardening().NewRouter()
+}
+
+// HardenedServer creates a server for r with the DefaultHardening settings
+func HardenedServer(addr string, r *Router) *Server {
+	return DefaultHardening().NewServer(addr, r)
+}
+
+// NewRouter creates a router with the parser limits, docs setting and
+// middlewares of h
+func (h Hardening) NewRouter() *Router {
+	r := NewRouter()
+	h.Apply(r)
+	return r
+}
+
+// Apply configures an existing router with the router-level settings of h
+// The middlewares wrap every route, including those registered before
+func (h Hardening) Apply(r *Router) {
+	r.UpdateParser(func(p *ParserConfig) {
+		p.MaxHeaderBytes = h.MaxHeaderBytes
+		p.MaxHeaderCount = h.MaxHeaderCount
+		p.HeaderTimeout = h.HeaderTimeout
+		p.MaxBodyBytes = h.MaxBodyBytes
+		p.AllowObsFold = false
+	})
+	r.Update(func(d *Doc) {
+		d.Active = h.Docs
+	})
+
+	if len(h.RejectMethods) > 0 {
+		r.Use(RejectMethods(h.RejectMethods...), MiddlewareName("RejectMethods"))
+	}
+	if len(h.SecurityHeaders) > 0 || h.SuppressServerHeader {
+		r.Use(SecurityHeaders(h.SecurityHeaders, h.SuppressServerHeader), MiddlewareName("SecurityHeaders"))
+	}
+}
+
+// NewServer creates a server for r with the connection-level settings of h
+func (h Hardening) NewServer(addr string, r *Router) *Server {
+	return &Server{
+		Addr:         addr,
+		Router:       r,
+		TLSConfig:    &tls.Config{MinVersion: h.MinTLSVersion},
+		ReadTimeout:  h.ReadTimeout,
+		WriteTimeout: h.WriteTimeout,
+		IdleTimeout:  h.IdleTimeout,
+	}
+}
+
+// SecurityHeaders creates a middleware adding headers to every response
+// Headers already set by the handler are kept; with suppressServer, a Server
+// header set by the handler is removed
+func SecurityHeaders(headers map[string]string, suppressServer bool) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			next(r, w)
+
+			for k, v := range headers {
+				if w.Headers.Get(k) == "" {
+					w.Headers.Add(k, v)
+				}
+			}
+			if suppressServer {
+				delete(w.Headers, "server")
+			}
+		}
+	}
+}
+
+// RejectMethods creates a middleware answering the given methods with 405
+func RejectMethods(methods ...string) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			for _, m := range methods {
+				if strings.EqualFold(r.Method, m) {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+					return
+				}
+			}
+			next(r, w)
+		}
+	}
+}
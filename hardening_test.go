@@ -0,0 +1,99 @@
+package gouter
+
+import (
+	"crypto/tls"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHardeningAppliesEveryField(t *testing.T) {
+	// Values differ from the defaults of NewRouter and Server
+	h := Hardening{
+		MaxHeaderBytes:       12345,
+		MaxHeaderCount:       7,
+		HeaderTimeout:        3 * time.Second,
+		MaxBodyBytes:         4321,
+		ReadTimeout:          11 * time.Second,
+		WriteTimeout:         12 * time.Second,
+		IdleTimeout:          13 * time.Second,
+		MinTLSVersion:        tls.VersionTLS13,
+		SecurityHeaders:      map[string]string{"X-Frame-Options": "DENY"},
+		SuppressServerHeader: true,
+		RejectMethods:        []string{"TRACE"},
+		Docs:                 true,
+	}
+
+	// Registered before Apply, which must still wrap it
+	r := NewRouter()
+	r.Route("/", func(req *Request, w *Writer) {
+		w.SetHeader("Server", "leaky/1.0")
+		w.Write([]byte("ok"))
+	})
+	h.Apply(r)
+	r.Update(func(d *Doc) { d.Active = false })
+	s := h.NewServer("127.0.0.1:0", r)
+
+	resps := rawExchange(t, serveTest(t, s), "GET / HTTP/1.1\r\nHost: x\r\n\r\nTRACE / HTTP/1.1\r\nHost: x\r\n\r\n", 2)
+	get, trace := resps[0], resps[1]
+
+	p := r.parserConfig
+	checks := map[string]bool{
+		"MaxHeaderBytes":       p.MaxHeaderBytes == h.MaxHeaderBytes,
+		"MaxHeaderCount":       p.MaxHeaderCount == h.MaxHeaderCount,
+		"HeaderTimeout":        p.HeaderTimeout == h.HeaderTimeout,
+		"MaxBodyBytes":         p.MaxBodyBytes == h.MaxBodyBytes,
+		"ReadTimeout":          s.ReadTimeout == h.ReadTimeout,
+		"WriteTimeout":         s.WriteTimeout == h.WriteTimeout,
+		"IdleTimeout":          s.IdleTimeout == h.IdleTimeout,
+		"MinTLSVersion":        s.TLSConfig != nil && s.TLSConfig.MinVersion == h.MinTLSVersion,
+		"SecurityHeaders":      get.Header.Get("X-Frame-Options") == "DENY",
+		"SuppressServerHeader": get.Header.Get("Server") == "",
+		"RejectMethods":        trace.StatusCode == http.StatusMethodNotAllowed,
+	}
+
+	// Docs is checked before the test turned the doc server off again
+	docs := NewRouter()
+	h.Apply(docs)
+	checks["Docs"] = docs.docConfig.Active
+	docs.Update(func(d *Doc) { d.Active = false })
+
+	fields := reflect.TypeOf(h)
+	for i := 0; i < fields.NumField(); i++ {
+		name := fields.Field(i).Name
+		ok, checked := checks[name]
+		switch {
+		case !checked:
+			t.Errorf("Hardening.%s has no check in this test", name)
+		case !ok:
+			t.Errorf("Hardening.%s was not applied", name)
+		}
+	}
+	if p.AllowObsFold {
+		t.Errorf("Apply left obsolete line folding allowed")
+	}
+	if get.StatusCode != http.StatusOK || bodyString(t, get) != "ok" {
+		t.Errorf("GET / = %d, want 200 ok", get.StatusCode)
+	}
+}
+
+func TestSecurityHeadersKeepHandlerValues(t *testing.T) {
+	r := newTestRouter()
+	r.Use(SecurityHeaders(map[string]string{"X-Frame-Options": "DENY", "Referrer-Policy": "no-referrer"}, false))
+	r.Route("/", func(req *Request, w *Writer) {
+		w.SetHeader("X-Frame-Options", "SAMEORIGIN")
+		w.SetHeader("Server", "app")
+	})
+
+	resp := rawExchange(t, serveRouter(t, r), "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := resp.Header.Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("X-Frame-Options = %q, want the handler value", got)
+	}
+	if got := resp.Header.Get("Referrer-Policy"); got != "no-referrer" {
+		t.Errorf("Referrer-Policy = %q, want no-referrer", got)
+	}
+	if got := resp.Header.Get("Server"); got != "app" {
+		t.Errorf("Server = %q, want it kept without suppressServer", got)
+	}
+}
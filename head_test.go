@@ -0,0 +1,143 @@
+package gouter
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// getThenHead sends GET and HEAD for path on one connection, followed by a
+// GET of /marker whose intact answer shows HEAD sent no body bytes
+func getThenHead(t *testing.T, addr, path string) (get, head *http.Response, body string) {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	io.WriteString(c, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"HEAD "+path+" HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"GET /marker HTTP/1.1\r\nHost: x\r\n\r\n")
+	br := bufio.NewReader(c)
+	var resps []*http.Response
+	var bodies []string
+	for _, m := range []string{"GET", "HEAD", "GET"} {
+		resp, err := http.ReadResponse(br, &http.Request{Method: m})
+		if err != nil {
+			t.Fatalf("%s %s: %v", m, path, err)
+		}
+		b, _ := io.ReadAll(resp.Body)
+		resps, bodies = append(resps, resp), append(bodies, string(b))
+	}
+	if bodies[2] != "marker" {
+		t.Errorf("HEAD %s: the next response read %q, want marker; a body leaked", path, bodies[2])
+	}
+	return resps[0], resps[1], bodies[0]
+}
+
+func TestHeadMatchesGet(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "small.txt"), []byte("static file"), 0o644)
+	os.WriteFile(filepath.Join(dir, "large.bin"), []byte(strings.Repeat("L", 256<<10)), 0o644)
+	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+	os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("a"), 0o644)
+
+	r := newTestRouter()
+	r.Get("/marker", func(req *Request, w *Writer) { w.Write([]byte("marker")) })
+	r.Get("/text", func(req *Request, w *Writer) {
+		w.SetHeader("ETag", `"v1"`)
+		w.Write([]byte("hello head"))
+	})
+	r.Get("/json", func(req *Request, w *Writer) { w.WriteJson(map[string]int{"n": 42}) })
+	r.Route("/any", func(req *Request, w *Writer) { w.Write([]byte("any method")) })
+	r.Get("/declared", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Length", "8")
+		w.WriteHeaders()
+		w.Write([]byte("declared"))
+	})
+	r.Get("/listing", func(req *Request, w *Writer) { ListenFiles(w, req, dir) })
+	r.File("/file", filepath.Join(dir, "large.bin"))
+	ServerStatic(r, "/static", dir)
+	addr := serveRouter(t, r)
+
+	for _, path := range []string{"/text", "/json", "/any", "/declared", "/listing", "/file", "/static/small.txt", "/static/large.bin", "/static/sub"} {
+		get, head, body := getThenHead(t, addr, path)
+		if get.StatusCode != 200 || head.StatusCode != 200 {
+			t.Errorf("%s: GET %d, HEAD %d", path, get.StatusCode, head.StatusCode)
+			continue
+		}
+		if get.ContentLength != int64(len(body)) || head.ContentLength != get.ContentLength {
+			t.Errorf("%s: Content-Length GET %d (body %d), HEAD %d", path, get.ContentLength, len(body), head.ContentLength)
+		}
+		for _, h := range []string{"Content-Type", "ETag", "Last-Modified"} {
+			if head.Header.Get(h) != get.Header.Get(h) {
+				t.Errorf("%s: HEAD %s = %q, GET %q", path, h, head.Header.Get(h), get.Header.Get(h))
+			}
+		}
+	}
+
+	// Static mounts list HEAD among the methods they answer
+	resp := rawExchange(t, addr, "POST /static/small.txt HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n", 1)[0]
+	if resp.StatusCode != 405 || resp.Header.Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Errorf("POST on a static file = %d, Allow %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+}
+
+func TestHeadStreamed(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/marker", func(req *Request, w *Writer) { w.Write([]byte("marker")) })
+	r.Get("/stream", func(req *Request, w *Writer) {
+		w.Write([]byte("first"))
+		w.Flush()
+		w.Write([]byte("second"))
+	})
+	addr := serveRouter(t, r)
+
+	// Without a known length HEAD announces the framing of GET, and no chunks
+	get, head, body := getThenHead(t, addr, "/stream")
+	if body != "firstsecond" || framing(get) != "chunked" || framing(head) != "chunked" {
+		t.Errorf("GET %s %q, HEAD %s", framing(get), body, framing(head))
+	}
+}
+
+func TestHeadDocs(t *testing.T) {
+	r := docsRouter(0, func(d *Doc) {})
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := r.DocsAddr()
+
+	// The docs server closes each connection, so the raw bytes end the answer
+	exchange := func(method string) (string, string) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		c.SetDeadline(time.Now().Add(3 * time.Second))
+		io.WriteString(c, method+" / HTTP/1.1\r\nHost: x\r\n\r\n")
+		out, _ := io.ReadAll(c)
+		head, body, _ := strings.Cut(string(out), "\r\n\r\n")
+		// Header order is not fixed
+		lines := strings.Split(head, "\r\n")
+		slices.Sort(lines[1:])
+		return strings.Join(lines, "\r\n"), body
+	}
+	getHead, getBody := exchange("GET")
+	headHead, headBody := exchange("HEAD")
+	if getBody == "" || headBody != "" {
+		t.Errorf("docs GET body %d bytes, HEAD body %q", len(getBody), headBody)
+	}
+	// Same status line and headers, Content-Length of the page included
+	if headHead != getHead || !strings.Contains(strings.ToLower(headHead), "content-length: ") {
+		t.Errorf("docs GET headers %q, HEAD headers %q", getHead, headHead)
+	}
+}
@@ -0,0 +1,97 @@
+package gouter
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// aliasRouter echoes the content type and body of POST /echo
+func aliasRouter(aliases map[string]string) *Router {
+	r := newTestRouter()
+	r.UpdateParser(func(p *ParserConfig) { p.HeaderAliases = aliases })
+	r.Post("/echo", func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.Write([]byte(req.Headers.Get("content-type") + "|" + req.Headers.Get("x_custom") + "|" + string(body)))
+	})
+	return r
+}
+
+func TestHeaderAliases(t *testing.T) {
+	const warning = "renamed by HeaderAliases"
+	out := captureServe(t, func(t *testing.T) {
+		addr := serveRouter(t, aliasRouter(DefaultHeaderAliases))
+
+		// Both pipelined bodies are framed by the underscore length; one
+		// warning covers the connection
+		resps := rawExchange(t, addr, "POST /echo HTTP/1.1\r\nHost: x\r\nContent_Length: 5\r\nCONTENT_TYPE: text/plain\r\n\r\nhello"+
+			"POST /echo HTTP/1.1\r\nHost: x\r\ncontent_length: 3\r\n\r\nabc", 2)
+		for i, want := range []string{"text/plain||hello", "||abc"} {
+			if got := bodyString(t, resps[i]); resps[i].StatusCode != 200 || got != want {
+				t.Errorf("request %d = %d %q, want %q", i+1, resps[i].StatusCode, got, want)
+			}
+		}
+		rawExchange(t, addr, "POST /echo HTTP/1.1\r\nHost: x\r\nUser_Agent: legacy\r\nContent-Length: 0\r\n\r\n", 1)
+
+		cases := []struct {
+			name    string
+			headers string
+		}{
+			{"unknown underscore name", "X_Custom: 1\r\nContent-Length: 0\r\n"},
+			{"alias conflicting with the header", "Content_Length: 5\r\nContent-Length: 6\r\n"},
+			{"alias next to chunked", "Content_Length: 3\r\nTransfer-Encoding: chunked\r\n"},
+			{"invalid name character", "Bad@Name: 1\r\nContent-Length: 0\r\n"},
+			{"space in the name", "Bad Name: 1\r\nContent-Length: 0\r\n"},
+		}
+		for _, tc := range cases {
+			resp := rawExchange(t, addr, "POST /echo HTTP/1.1\r\nHost: x\r\n"+tc.headers+"\r\nabcdef", 1)[0]
+			if resp.StatusCode != 400 || !resp.Close {
+				t.Errorf("%s: status %d, close %v; want a closing 400", tc.name, resp.StatusCode, resp.Close)
+			}
+		}
+	})
+	// Two accepted connections and the two rejected ones with an alias
+	if n := strings.Count(out, warning); n != 4 {
+		t.Errorf("logged %d rename warnings, want one per connection:\n%s", n, out)
+	}
+	if !strings.Contains(out, "header content_length from 127.0.0.1:") {
+		t.Errorf("warning does not name the header and client:\n%s", out)
+	}
+}
+
+func TestHeaderAliasesOptIn(t *testing.T) {
+	out := captureServe(t, func(t *testing.T) {
+		addr := serveRouter(t, aliasRouter(nil))
+		resp := rawExchange(t, addr, "POST /echo HTTP/1.1\r\nHost: x\r\nX_Custom: 1\r\nContent-Length: 2\r\n\r\nok", 1)[0]
+		if got := bodyString(t, resp); resp.StatusCode != 200 || got != "|1|ok" {
+			t.Errorf("without aliases = %d %q, want the underscore header kept", resp.StatusCode, got)
+		}
+	})
+	if strings.Contains(out, "HeaderAliases") {
+		t.Errorf("warning without aliases:\n%s", out)
+	}
+}
+
+func TestHeaderAliasesAllocations(t *testing.T) {
+	headers := []byte("POST /echo HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\nContent-Type: text/plain\r\nAccept: */*")
+	aliased := []byte("POST /echo HTTP/1.1\r\nHost: x\r\nContent_Length: 5\r\nContent_Type: text/plain\r\nAccept: */*")
+	parse := func(cfg *ParserConfig, b []byte) float64 {
+		req := &Request{Headers: make(Headers)}
+		return testing.AllocsPerRun(100, func() {
+			clear(req.Headers)
+			if _, err := req.parser(b, cfg); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	plain := parse(defaultParserConfig(), headers)
+	cfg := defaultParserConfig()
+	cfg.HeaderAliases = DefaultHeaderAliases
+	if got := parse(cfg, headers); got != plain {
+		t.Errorf("aliases on, standard names: %v allocs, want %v", got, plain)
+	}
+	if got := parse(cfg, aliased); got != plain {
+		t.Errorf("aliases on, underscore names: %v allocs, want %v", got, plain)
+	}
+}
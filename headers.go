@@ -0,0 +1,130 @@
+package gouter
+
+import "strings"
+
+// HeaderMerge decides which value wins when two header sets define the
+// same single-valued header; list-valued headers always accumulate
+type HeaderMerge int
+
+const (
+	MergeOverride HeaderMerge = iota // The merged-in value replaces the existing one
+	MergeKeep                        // The existing value is kept
+)
+
+// listHeaders are the headers whose values accumulate instead of being
+// replaced, with the separator joining them. Set-Cookie values cannot be
+// comma-joined, so each one is sent on its own line
+var listHeaders = map[string]string{
+	"vary":       ", ",
+	"link":       ", ",
+	"set-cookie": "\n",
+}
+
+// Values returns the values of a header, one per Add call for list-valued
+// headers (Vary, Link, Set-Cookie), or a single value
+func (h Headers) Values(key string) []string {
+	key = strings.ToLower(key)
+	v, ok := h[key]
+	if !ok {
+		return nil
+	}
+	if sep, list := listHeaders[key]; list {
+		return strings.Split(v, sep)
+	}
+	return []string{v}
+}
+
+// Merge copies the headers of other into h
+// Args:
+//   - other: Headers to merge in
+//   - policy: Winner for single-valued headers present in both
+//
+// List-valued headers (Vary, Link, Set-Cookie) append the values of other
+// under either policy, skipping Vary and Link tokens h already has
+//
+// Layers of a response merge in this order, so a handler overrides the
+// middlewares of its router or group, which override those of a router
+// it is mounted into (see Mount and DefaultHeaders):
+//
+//	header kind     parent  child  handler  result
+//	single-valued   A       B      C        C
+//	single-valued   A       B      -        B
+//	Vary            Origin  Accept -        Origin, Accept
+//	Set-Cookie      a=1     b=2    c=3      a=1, b=2 and c=3 on separate lines
+func (h Headers) Merge(other Headers, policy HeaderMerge) Headers {
+	for k, v := range other {
+		k = strings.ToLower(k)
+		if _, list := listHeaders[k]; list {
+			h.appendValue(k, v)
+			continue
+		}
+		if _, exists := h[k]; exists && policy == MergeKeep {
+			continue
+		}
+		h[k] = v
+	}
+	return h
+}
+
+// appendValue adds the values of v to a list-valued header
+func (h Headers) appendValue(key, v string) {
+	sep := listHeaders[key]
+	prev, ok := h[key]
+	if !ok || prev == "" {
+		h[key] = v
+		return
+	}
+	if key == "set-cookie" {
+		h[key] = prev + sep + v
+		return
+	}
+
+	values := prev
+	for _, token := range strings.Split(v, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" || hasToken(prev, token) {
+			continue
+		}
+		values += sep + token
+	}
+	h[key] = values
+}
+
+// hasToken reports whether a comma-separated list holds token, ignoring case
+func hasToken(list, token string) bool {
+	for _, t := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(t), token) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeHeaderLines renders headers as "Name: value" lines, one line per
+// Set-Cookie value
+func writeHeaderLines(b *strings.Builder, h Headers) {
+	for k, v := range h {
+		if k == "set-cookie" {
+			for _, cookie := range strings.Split(v, "\n") {
+				b.WriteString(k + ": " + cookie + "\r\n")
+			}
+			continue
+		}
+		b.WriteString(k + ": " + v + "\r\n")
+	}
+}
+
+// DefaultHeaders creates a middleware adding headers to every response
+// Headers set by the middlewares and handler it wraps replace them, except
+// list-valued ones, which accumulate (see Headers.Merge)
+func DefaultHeaders(h Headers) Middleware {
+	defaults := make(Headers, len(h))
+	defaults.Merge(h, MergeOverride)
+
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			w.Headers.Merge(defaults, MergeOverride)
+			next(r, w)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// update rewrites golden files with the current output
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// golden compares got with the file testdata/name, rewriting it with -update
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := "testdata/" + name
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (run go test -update to accept it):\n%s", path, got)
+	}
+}
+
+// newTestRouter returns a router without the documentation server, so
+// tests do not compete for its port
+func newTestRouter() *Router {
+	r := NewRouter()
+	r.Update(func(d *Doc) { d.Active = false })
+	return r
+}
+
+// serveTest serves s on a port-0 listener until the test ends
+// Returns the listener address
+func serveTest(t testing.TB, s *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return serveListener(t, s, l)
+}
+
+// serveListener serves s on l until the test ends
+// Returns the listener address
+func serveListener(t testing.TB, s *Server, l net.Listener) string {
+	go s.Serve(l)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	return l.Addr().String()
+}
+
+// serveRouter serves r with default Server settings
+func serveRouter(t testing.TB, r *Router) string {
+	t.Helper()
+	return serveTest(t, &Server{Router: r})
+}
+
+// rawExchange writes raw to addr and reads n responses from the connection
+func rawExchange(t testing.TB, addr, raw string, n int) []*http.Response {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	if _, err := io.WriteString(c, raw); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(c)
+	var out []*http.Response
+	for i := 0; i < n; i++ {
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatalf("response %d: %v", i+1, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body = io.NopCloser(strings.NewReader(string(body)))
+		out = append(out, resp)
+	}
+	return out
+}
+
+// fetch sends a bodiless method request for path with extra header lines
+// and reads the response as a client that sent method would, so HEAD
+// responses announce a length without a body
+func fetch(t testing.TB, addr, method, path string, headers ...string) *http.Response {
+	t.Helper()
+	raw := method + " " + path + " HTTP/1.1\r\nHost: x\r\n"
+	for _, h := range headers {
+		raw += h + "\r\n"
+	}
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	if _, err := io.WriteString(c, raw+"\r\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(c), &http.Request{Method: method})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body = io.NopCloser(strings.NewReader(string(body)))
+	return resp
+}
+
+// bodyString reads the whole body of resp
+func bodyString(t testing.TB, resp *http.Response) string {
+	t.Helper()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// eventually polls cond until it holds or a second passed
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
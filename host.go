@@ -0,0 +1,134 @@
+package gouter
+
+import (
+	"net"
+	"strings"
+)
+
+// hostScope is a set of routes served only to a matching Host header
+type hostScope struct {
+	pattern string           // Host pattern as registered (e.g., ":tenant.example.com")
+	labels  [][]segmentToken // Parsed dot-separated labels
+	literal bool             // No parameters, preferred over parameterized scopes
+	routes  *Router          // Routes of the scope, registered through the parent
+}
+
+// HostRoute registers a handler served only for requests whose Host matches
+// Args:
+//   - host: Host pattern; labels may capture parameters like path segments
+//     (e.g., ":tenant.example.com" binds r.Params "tenant")
+//   - path: Route path pattern, matched as in Route
+//   - handler: Request handler
+//   - methods: Optional HTTP method specification (defaults to GET)
+//
+// Ports in the Host header are ignored and literal hosts take precedence
+// over parameterized ones. Requests matching no host route fall back to the
+// routes registered without a host
+func (r *Router) HostRoute(host, path string, handler Handler, methods ...string) *RouteInfo {
+	scope := r.hostScope(host)
+	return scope.routes.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), methods...)
+}
+
+// Host scopes a group of routes to a host pattern (see HostRoute)
+// Groups nested in fn extend the scope
+func (r *Router) Host(host string, fn GroupFunc) {
+	fn(newGroup(r.hostScope(host).routes, nil, ""))
+}
+
+// hostScope returns the scope for a pattern, creating it if needed
+func (r *Router) hostScope(host string) *hostScope {
+	host = strings.ToLower(host)
+	for _, s := range r.hosts {
+		if s.pattern == host {
+			return s
+		}
+	}
+
+	scope := &hostScope{
+		pattern: host,
+		literal: !strings.Contains(host, ":"),
+		routes:  NewRouter(),
+	}
+	scope.routes.parent = r
+	scope.routes.host = host
+	for _, label := range strings.Split(host, ".") {
+		scope.labels = append(scope.labels, parseSegment(label))
+	}
+
+	// Literal scopes are tried first; registration order breaks ties
+	i := len(r.hosts)
+	if scope.literal {
+		i = 0
+		for i < len(r.hosts) && r.hosts[i].literal {
+			i++
+		}
+	}
+	r.hosts = append(r.hosts, nil)
+	copy(r.hosts[i+1:], r.hosts[i:])
+	r.hosts[i] = scope
+	return scope
+}
+
+// noHostParams is the empty, read-only result of matching a literal scope
+var noHostParams = map[string]string{}
+
+// matchHost binds the host parameters of a scope
+// Returns nil when the host does not match; literal scopes and hosts with
+// another number of labels are told apart without allocating
+func (s *hostScope) matchHost(host string) map[string]string {
+	if s.literal {
+		if host != s.pattern {
+			return nil
+		}
+		return noHostParams
+	}
+	if strings.Count(host, ".")+1 != len(s.labels) {
+		return nil
+	}
+	labels := strings.Split(host, ".")
+
+	params := make(map[string]string)
+	for i, tokens := range s.labels {
+		if !matchSegment(tokens, labels[i], params) {
+			return nil
+		}
+	}
+	return params
+}
+
+// parseHostRoute matches the request against the host scopes
+// Returns a nil handler when no scope serves the request
+func (r *Router) parseHostRoute(req *Request) (Handler, string) {
+	host := requestHost(req)
+	if host == "" {
+		return nil, ""
+	}
+
+	for _, s := range r.hosts {
+		params := s.matchHost(host)
+		if params == nil {
+			continue
+		}
+		handler, basePath := s.routes.parseRoute(req)
+		if handler == nil {
+			continue
+		}
+		for name, value := range params {
+			req.Params.add(name, value)
+		}
+		return handler, basePath
+	}
+	return nil, ""
+}
+
+// requestHost returns the lowercase Host header without its port
+// SplitHostPort only sees hosts with a port, its error allocates
+func requestHost(req *Request) string {
+	host := strings.ToLower(req.Headers["host"])
+	if strings.LastIndexByte(host, ':') > strings.LastIndexByte(host, ']') {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	return strings.TrimSuffix(host, ".")
+}
@@ -0,0 +1,73 @@
+package gouter
+
+import (
+	"testing"
+)
+
+func TestHostRoutes(t *testing.T) {
+	reply := func(name string) Handler {
+		return func(req *Request, w *Writer) {
+			w.Write([]byte(name + " " + req.Params.Get("tenant") + req.Params.Get("id")))
+		}
+	}
+
+	r := newTestRouter()
+	tenant := r.HostRoute(":tenant.example.com", "/dashboard", reply("dashboard"))
+	r.HostRoute(":tenant.example.com", "/items/:id", reply("item"))
+	r.HostRoute("admin.example.com", "/dashboard", reply("admin"))
+	r.Host(":tenant.shop.io", func(g *Group) {
+		g.Group("/api", func(g *Group) {
+			g.Group("/v1", func(g *Group) {
+				g.Get("/orders/:id", reply("order"))
+			})
+		})
+	})
+	r.Get("/dashboard", reply("global"))
+	addr := serveRouter(t, r)
+
+	cases := []struct{ host, path, want string }{
+		{"acme.example.com", "/dashboard", "dashboard acme"},
+		{"acme.example.com:8080", "/dashboard", "dashboard acme"},
+		{"ACME.Example.com.", "/dashboard", "dashboard acme"},
+		{"acme.example.com", "/items/7", "item acme7"},
+		{"admin.example.com", "/dashboard", "admin "},
+		{"admin.example.com:443", "/dashboard", "admin "},
+		{"acme.shop.io", "/api/v1/orders/9", "order acme9"},
+		{"example.com", "/dashboard", "global "},
+		{"a.b.example.com", "/dashboard", "global "},
+		{"other.org", "/dashboard", "global "},
+		{"", "/dashboard", "global "},
+	}
+	for _, tc := range cases {
+		raw := "GET " + tc.path + " HTTP/1.1\r\n"
+		if tc.host != "" {
+			raw += "Host: " + tc.host + "\r\n"
+		}
+		resp := rawExchange(t, addr, raw+"\r\n", 1)[0]
+		if got := bodyString(t, resp); resp.StatusCode != 200 || got != tc.want {
+			t.Errorf("Host %q %s = %d %q, want %q", tc.host, tc.path, resp.StatusCode, got, tc.want)
+		}
+	}
+
+	// A host scope without the path falls back to the global routes
+	resp := rawExchange(t, addr, "GET /items/7 HTTP/1.1\r\nHost: other.org\r\n\r\n", 1)[0]
+	if resp.StatusCode != 404 {
+		t.Errorf("scoped path on an unscoped host = %d, want 404", resp.StatusCode)
+	}
+
+	if tenant.Host != ":tenant.example.com" {
+		t.Errorf("RouteInfo.Host = %q, want the host pattern", tenant.Host)
+	}
+	if len(tenant.Parameters) != 1 || tenant.Parameters[0].Name != "tenant" {
+		t.Errorf("Parameters = %+v, want the tenant host parameter", tenant.Parameters)
+	}
+	hosts := make(map[string]bool)
+	for _, doc := range r.visibleDocs() {
+		hosts[doc.Host+doc.Path] = true
+	}
+	for _, want := range []string{":tenant.example.com/dashboard", "admin.example.com/dashboard", ":tenant.shop.io/api/v1/orders/:id", "/dashboard"} {
+		if !hosts[want] {
+			t.Errorf("docs miss %s, have %v", want, hosts)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package gouter
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+)
+
+// h2Server serves the TLS connections of a Server that negotiated HTTP/2
+// They are handed to a net/http server, which speaks the protocol, running
+// the router like ToHTTPHandler
+type h2Server struct {
+	once sync.Once
+	l    *h2Listener
+
+	mu   sync.Mutex
+	done map[net.Conn]chan struct{} // Closed when net/http closes the connection
+}
+
+// start launches the net/http server; closing its listener, as Shutdown
+// does, shuts it down gracefully with a GOAWAY to the clients
+func (h *h2Server) start(r *Router, opts connOptions) {
+	h.l = &h2Listener{conns: make(chan net.Conn), closed: make(chan struct{})}
+	h.done = make(map[net.Conn]chan struct{})
+
+	// net/http applies the connection timeouts; requests keep the others
+	reqOpts := opts
+	reqOpts.readTimeout, reqOpts.writeTimeout, reqOpts.idleTimeout = 0, 0, 0
+	reqOpts.tracker, reqOpts.handshakes, reqOpts.http2 = nil, nil, nil
+
+	srv := &http.Server{
+		Handler:      httpHandler(r, reqOpts),
+		ReadTimeout:  opts.readTimeout,
+		WriteTimeout: opts.writeTimeout,
+		IdleTimeout:  opts.idleTimeout,
+		ConnState:    h.connState,
+	}
+	spawn("http2", func() { srv.Serve(h.l) })
+	spawn("http2", func() {
+		<-h.l.closed
+		srv.Shutdown(context.Background())
+	})
+	if !opts.tracker.addListener(h.l) {
+		h.l.Close()
+	}
+}
+
+// serve hands tc to the net/http server and waits until it is closed, so
+// the connection keeps its slot and shutdown tracking meanwhile
+func (h *h2Server) serve(tc *tls.Conn) {
+	done := make(chan struct{})
+	h.mu.Lock()
+	h.done[tc] = done
+	h.mu.Unlock()
+
+	select {
+	case h.l.conns <- tc:
+		<-done
+	case <-h.l.closed:
+		h.mu.Lock()
+		delete(h.done, tc)
+		h.mu.Unlock()
+	}
+}
+
+// connState releases serve once net/http is done with a connection
+func (h *h2Server) connState(c net.Conn, st http.ConnState) {
+	if st != http.StateClosed && st != http.StateHijacked {
+		return
+	}
+	h.mu.Lock()
+	done, ok := h.done[c]
+	delete(h.done, c)
+	h.mu.Unlock()
+	if ok {
+		close(done)
+	}
+}
+
+// h2Listener feeds the connections accepted by the Server to net/http
+type h2Listener struct {
+	conns     chan net.Conn
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (l *h2Listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closed:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *h2Listener) Close() error {
+	l.closeOnce.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *h2Listener) Addr() net.Addr {
+	return remoteAddr("http2")
+}
+
+// offerHTTP2 adds "h2" to the ALPN protocols of config, keeping HTTP/1.1
+// for clients without HTTP/2
+func offerHTTP2(config *tls.Config) {
+	if !slices.Contains(config.NextProtos, "h2") {
+		config.NextProtos = append([]string{"h2"}, config.NextProtos...)
+	}
+	if !slices.Contains(config.NextProtos, "http/1.1") {
+		config.NextProtos = append(config.NextProtos, "http/1.1")
+	}
+}
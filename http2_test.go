@@ -0,0 +1,178 @@
+package gouter
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// h2Client returns a client negotiating HTTP/2 when offered, and the
+// count of TCP connections it opened
+func h2Client() (*http.Client, *atomic.Int32) {
+	dials := new(atomic.Int32)
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			ForceAttemptHTTP2: true,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				dials.Add(1)
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		},
+	}, dials
+}
+
+// serveH2Test serves r over TLS with EnableHTTP2 set to enable
+func serveH2Test(t *testing.T, r *Router, enable bool) (*Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "h2")
+	s := &Server{Router: r, EnableHTTP2: enable}
+	return s, "https://" + serveTLSTest(t, s, certFile, keyFile)
+}
+
+func TestHTTP2OverTLS(t *testing.T) {
+	const parallel = 4
+	arrived := new(atomic.Int32)
+	all := make(chan struct{})
+	r := newTestRouter()
+	r.Post("/echo/:id", func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.Write([]byte(req.Params.Get("id") + ":" + string(body)))
+	})
+	// Answers once every request is in flight, which takes multiplexing
+	r.Get("/together", func(req *Request, w *Writer) {
+		if arrived.Add(1) == parallel {
+			close(all)
+		}
+		select {
+		case <-all:
+		case <-time.After(3 * time.Second):
+			w.WriteHeader(http.StatusGatewayTimeout)
+		}
+	})
+	_, base := serveH2Test(t, r, true)
+	client, dials := h2Client()
+
+	resp, err := client.Post(base+"/echo/7", "text/plain", strings.NewReader("over h2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodyString(t, resp); resp.ProtoMajor != 2 || got != "7:over h2" {
+		t.Fatalf("POST = %s %q, want HTTP/2", resp.Proto, got)
+	}
+
+	codes := make(chan int, parallel)
+	for i := 0; i < parallel; i++ {
+		go func() {
+			resp, err := client.Get(base + "/together")
+			if err != nil {
+				codes <- 0
+				return
+			}
+			resp.Body.Close()
+			codes <- resp.StatusCode
+		}()
+	}
+	for i := 0; i < parallel; i++ {
+		if code := <-codes; code != 200 {
+			t.Errorf("concurrent request = %d", code)
+		}
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("%d connections opened, want every request on one", n)
+	}
+}
+
+func TestHTTP2KeepsHTTP1(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) { w.Write([]byte(req.Version)) })
+
+	// Clients without HTTP/2 share the port
+	_, base := serveH2Test(t, r, true)
+	c, err := tls.Dial("tcp", strings.TrimPrefix(base, "https://"), &tls.Config{InsecureSkipVerify: true, NextProtos: []string{"http/1.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if p := c.ConnectionState().NegotiatedProtocol; p != "http/1.1" {
+		t.Errorf("negotiated %q, want http/1.1", p)
+	}
+	if code := getOverTLS(t, c, bufio.NewReader(c)); code != 200 {
+		t.Errorf("HTTP/1.1 over the HTTP/2 server = %d", code)
+	}
+
+	// HTTP/2 is not offered unless enabled
+	_, base = serveH2Test(t, r, false)
+	client, _ := h2Client()
+	resp, err := client.Get(base + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodyString(t, resp); resp.ProtoMajor != 1 || got != "HTTP/1.1" {
+		t.Errorf("without EnableHTTP2 = %s %q", resp.Proto, got)
+	}
+}
+
+func TestHTTP2Shutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r := newTestRouter()
+	r.Get("/slow", func(req *Request, w *Writer) {
+		close(started)
+		<-release
+		w.Write([]byte("finished"))
+	})
+	s, base := serveH2Test(t, r, true)
+	client, _ := h2Client()
+
+	type result struct {
+		proto, body string
+		err         error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := client.Get(base + "/slow")
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		done <- result{proto: resp.Proto, body: string(body)}
+	}()
+	<-started
+
+	// The in-flight stream completes before Shutdown returns
+	type shutdown struct {
+		report ShutdownReport
+		err    error
+	}
+	shut := make(chan shutdown, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		report, err := s.Shutdown(ctx)
+		shut <- shutdown{report, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	if res := <-done; res.err != nil || res.proto != "HTTP/2.0" || res.body != "finished" {
+		t.Errorf("in-flight request = %+v", res)
+	}
+	if res := <-shut; res.err != nil || res.report.ForceClosed != 0 {
+		t.Errorf("Shutdown = %+v, %v; want the HTTP/2 connection drained", res.report, res.err)
+	}
+}
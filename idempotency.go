@@ -0,0 +1,229 @@
+package gouter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// maxIdempotencyKey is the longest Idempotency-Key accepted
+const maxIdempotencyKey = 255
+
+// maxIdempotencyRecords is the record count above which a
+// MemoryIdempotencyStore sweeps expired records
+const maxIdempotencyRecords = 10000
+
+// IdempotencyReplayHeaders are the response headers stored with an
+// idempotent response and sent again on replay
+var IdempotencyReplayHeaders = []string{
+	"Content-Type", "Content-Language", "Content-Location", "Location",
+	"ETag", "Last-Modified", "Cache-Control",
+}
+
+// IdempotencyRecord is the state of one Idempotency-Key
+type IdempotencyRecord struct {
+	Fingerprint string            // Hash of the request body the key was first used with
+	Done        bool              // The response is stored; false while the first request runs
+	Status      int               // Response status
+	Headers     map[string]string // Response headers listed in IdempotencyReplayHeaders
+	Body        []byte            // Response body
+}
+
+// IdempotencyStore keeps idempotency records, e.g. in Redis so replicas
+// share them. Implementations must be safe for concurrent use
+type IdempotencyStore interface {
+	// Begin reserves key with a pending record for up to ttl
+	// Returns the existing record instead when key is already known
+	Begin(key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error)
+
+	// Complete stores the response of a reserved key for ttl
+	Complete(key string, rec IdempotencyRecord, ttl time.Duration) error
+
+	// Release drops the reservation of key so it can be retried
+	Release(key string) error
+}
+
+// Idempotency creates a middleware replaying the response of POST and PATCH
+// requests sent again with the same Idempotency-Key header
+// Args:
+//   - store: Record storage, NewMemoryIdempotencyStore for a single process
+//   - ttl: Time a response is replayed for
+//
+// Records are keyed by the header value and the matched route, and bound
+// to a hash of the request body: a key reused with another body is
+// answered 409, and so is a retry while the first request still runs, with
+// Retry-After. Replays carry Idempotent-Replayed: true. Responses of 5xx,
+// panics and streamed responses are not stored, so the key can be retried
+func Idempotency(store IdempotencyStore, ttl time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			idemKey := r.Headers.Get("Idempotency-Key")
+			if idemKey == "" || (r.Method != "POST" && r.Method != "PATCH") {
+				next(r, w)
+				return
+			}
+			if len(idemKey) > maxIdempotencyKey {
+				Error(w, errors.New("Idempotency-Key is too long"), http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				Error(w, err, http.StatusBadRequest)
+				return
+			}
+			r.Body = bytes.NewReader(body)
+			sum := sha256.Sum256(body)
+			fingerprint := hex.EncodeToString(sum[:])
+
+			route := r.path
+			if r.route != nil {
+				route = r.route.Path
+			}
+			key := route + " " + idemKey
+
+			rec, err := store.Begin(key, fingerprint, ttl)
+			if err != nil {
+				log.Error(fmt.Errorf("idempotency store failed: %w", err))
+				Error(w, errors.New("service unavailable"), http.StatusServiceUnavailable)
+				return
+			}
+			switch {
+			case rec == nil:
+			case rec.Fingerprint != fingerprint:
+				Error(w, errors.New("Idempotency-Key was used with another request body"), http.StatusConflict)
+				return
+			case !rec.Done:
+				w.Headers.Add("Retry-After", "1")
+				Error(w, errors.New("a request with this Idempotency-Key is in progress"), http.StatusConflict)
+				return
+			default:
+				w.Headers.Merge(rec.Headers, MergeOverride)
+				w.Headers.Add("Idempotent-Replayed", "true")
+				w.WriteHeader(rec.Status)
+				w.Write(rec.Body)
+				return
+			}
+
+			completed := false
+			defer func() {
+				if completed {
+					return
+				}
+				if err := store.Release(key); err != nil {
+					log.Error(fmt.Errorf("idempotency store failed: %w", err))
+				}
+			}()
+
+			next(r, w)
+
+			w.mu.Lock()
+			if w.headersSent || w.code >= 500 {
+				w.mu.Unlock()
+				return
+			}
+			done := IdempotencyRecord{
+				Fingerprint: fingerprint,
+				Done:        true,
+				Status:      w.code,
+				Headers:     make(map[string]string),
+				Body:        append([]byte(nil), w.body...),
+			}
+			if done.Status == 0 {
+				done.Status = http.StatusOK
+			}
+			for _, h := range IdempotencyReplayHeaders {
+				if v := w.Headers.Get(h); v != "" {
+					done.Headers[h] = v
+				}
+			}
+			w.mu.Unlock()
+
+			if err := store.Complete(key, done, ttl); err != nil {
+				log.Error(fmt.Errorf("idempotency store failed: %w", err))
+				return
+			}
+			completed = true
+		}
+	}
+}
+
+// MemoryIdempotencyStore is a per-process IdempotencyStore
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]*memoryIdempotencyRecord
+	clock   Clock
+}
+
+// memoryIdempotencyRecord is a record with its expiry
+type memoryIdempotencyRecord struct {
+	rec     IdempotencyRecord
+	expires time.Time
+}
+
+// NewMemoryIdempotencyStore creates an empty store
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]*memoryIdempotencyRecord),
+		clock:   SystemClock,
+	}
+}
+
+// SetClock replaces the time source of the store; call it before use
+func (s *MemoryIdempotencyStore) SetClock(c Clock) {
+	s.clock = c
+}
+
+// Begin reserves key unless a live record exists for it
+func (s *MemoryIdempotencyStore) Begin(key, fingerprint string, ttl time.Duration) (*IdempotencyRecord, error) {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.records[key]; ok && now.Before(e.expires) {
+		rec := e.rec
+		return &rec, nil
+	}
+	if len(s.records) >= maxIdempotencyRecords {
+		s.evictLocked(now)
+	}
+	s.records[key] = &memoryIdempotencyRecord{
+		rec:     IdempotencyRecord{Fingerprint: fingerprint},
+		expires: now.Add(ttl),
+	}
+	return nil, nil
+}
+
+// Complete stores the response of key
+func (s *MemoryIdempotencyStore) Complete(key string, rec IdempotencyRecord, ttl time.Duration) error {
+	now := s.clock.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[key] = &memoryIdempotencyRecord{rec: rec, expires: now.Add(ttl)}
+	return nil
+}
+
+// Release forgets key
+func (s *MemoryIdempotencyStore) Release(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// evictLocked drops expired records; the caller holds s.mu
+func (s *MemoryIdempotencyStore) evictLocked(now time.Time) {
+	for k, e := range s.records {
+		if !now.Before(e.expires) {
+			delete(s.records, k)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package gouter_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+	"github.com/Murilinho145SG/gouter/gtest"
+)
+
+// idempotentServer serves handler at POST /payments behind Idempotency
+func idempotentServer(t *testing.T, store gouter.IdempotencyStore, handler gouter.Handler) string {
+	t.Helper()
+	r := gouter.NewRouter()
+	r.Update(func(d *gouter.Doc) { d.Active = false })
+	r.Use(gouter.Idempotency(store, time.Minute))
+	r.Post("/payments", handler)
+	r.Post("/refunds", handler)
+	srv := httptest.NewServer(gouter.ToHTTPHandler(r))
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+// post sends body to url with an Idempotency-Key and returns the response
+// with its body
+func post(t *testing.T, url, key, body string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest("POST", url, strings.NewReader(body))
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+// counted returns a handler creating a payment, and its call counter
+func counted() (gouter.Handler, *atomic.Int32) {
+	calls := new(atomic.Int32)
+	return func(r *gouter.Request, w *gouter.Writer) {
+		id := strconv.Itoa(int(calls.Add(1)))
+		w.SetHeader("Location", "/payments/"+id)
+		w.SetHeader("X-Not-Replayed", "1")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("payment " + id))
+	}, calls
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	handler, calls := counted()
+	base := idempotentServer(t, gouter.NewMemoryIdempotencyStore(), handler)
+
+	first, firstBody := post(t, base+"/payments", "k1", `{"amount":10}`)
+	retry, retryBody := post(t, base+"/payments", "k1", `{"amount":10}`)
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times, want 1", calls.Load())
+	}
+	if retry.StatusCode != http.StatusCreated || retryBody != firstBody || retry.Header.Get("Location") != first.Header.Get("Location") {
+		t.Errorf("replay = %d %q at %q, want %d %q at %q", retry.StatusCode, retryBody, retry.Header.Get("Location"),
+			first.StatusCode, firstBody, first.Header.Get("Location"))
+	}
+	if retry.Header.Get("Idempotent-Replayed") != "true" || first.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("Idempotent-Replayed on the first response %q and the replay %q", first.Header.Get("Idempotent-Replayed"), retry.Header.Get("Idempotent-Replayed"))
+	}
+	if retry.Header.Get("X-Not-Replayed") != "" {
+		t.Errorf("replay carries a header outside IdempotencyReplayHeaders")
+	}
+
+	// Keys are scoped to the route, and requests without one always run
+	post(t, base+"/refunds", "k1", `{"amount":10}`)
+	post(t, base+"/payments", "", `{"amount":10}`)
+	post(t, base+"/payments", "", `{"amount":10}`)
+	if calls.Load() != 4 {
+		t.Errorf("handler ran %d times, want 4", calls.Load())
+	}
+}
+
+func TestIdempotencyConflictingBody(t *testing.T) {
+	handler, calls := counted()
+	base := idempotentServer(t, gouter.NewMemoryIdempotencyStore(), handler)
+
+	post(t, base+"/payments", "k1", `{"amount":10}`)
+	resp, _ := post(t, base+"/payments", "k1", `{"amount":99}`)
+	if resp.StatusCode != http.StatusConflict || calls.Load() != 1 {
+		t.Errorf("reused key with another body = %d after %d runs, want 409 after 1", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestIdempotencyConcurrentDuplicates(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	var calls atomic.Int32
+	base := idempotentServer(t, gouter.NewMemoryIdempotencyStore(), func(r *gouter.Request, w *gouter.Writer) {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		w.Write([]byte("done"))
+	})
+
+	first := make(chan string, 1)
+	go func() {
+		_, body := post(t, base+"/payments", "k1", "{}")
+		first <- body
+	}()
+	<-started
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, _ := post(t, base+"/payments", "k1", "{}")
+			if resp.StatusCode != http.StatusConflict || resp.Header.Get("Retry-After") == "" {
+				t.Errorf("duplicate in flight = %d, Retry-After %q, want 409 with Retry-After", resp.StatusCode, resp.Header.Get("Retry-After"))
+			}
+		}()
+	}
+	wg.Wait()
+	close(release)
+
+	if body := <-first; body != "done" {
+		t.Errorf("first request = %q", body)
+	}
+	if resp, body := post(t, base+"/payments", "k1", "{}"); body != "done" || resp.Header.Get("Idempotent-Replayed") != "true" {
+		t.Errorf("retry after completion = %d %q, want the replay", resp.StatusCode, body)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("handler ran %d times, want 1", calls.Load())
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	clock := gtest.NewFakeClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := gouter.NewMemoryIdempotencyStore()
+	store.SetClock(clock)
+	handler, calls := counted()
+	base := idempotentServer(t, store, handler)
+
+	post(t, base+"/payments", "k1", "{}")
+	clock.Advance(time.Minute - time.Second)
+	post(t, base+"/payments", "k1", "{}")
+	if calls.Load() != 1 {
+		t.Fatalf("handler ran %d times within the ttl, want 1", calls.Load())
+	}
+
+	clock.Advance(time.Second)
+	resp, _ := post(t, base+"/payments", "k1", "{}")
+	if calls.Load() != 2 || resp.Header.Get("Idempotent-Replayed") != "" {
+		t.Errorf("after the ttl the handler ran %d times, replayed %q, want a second run", calls.Load(), resp.Header.Get("Idempotent-Replayed"))
+	}
+}
+
+func TestIdempotencyServerErrorsAreRetried(t *testing.T) {
+	var calls atomic.Int32
+	base := idempotentServer(t, gouter.NewMemoryIdempotencyStore(), func(r *gouter.Request, w *gouter.Writer) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	})
+
+	post(t, base+"/payments", "k1", "{}")
+	if resp, body := post(t, base+"/payments", "k1", "{}"); resp.StatusCode != http.StatusOK || body != "ok" {
+		t.Errorf("retry after a 502 = %d %q, want the handler to run again", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,194 @@
+package gouter
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// inflightShards is the number of independently locked registry shards
+const inflightShards = 16
+
+// InflightInfo describes a request that is currently being served
+type InflightInfo struct {
+	ID        uint64        `json:"id"`        // Registry sequence number
+	Method    string        `json:"method"`    // HTTP method
+	Path      string        `json:"path"`      // Requested path
+	Route     string        `json:"route"`     // Matched route pattern, empty when unmatched
+	ClientIP  string        `json:"client_ip"` // Remote address without port
+	Start     time.Time     `json:"start"`     // When the request started being served
+	Elapsed   time.Duration `json:"elapsed"`   // Time spent so far
+	BytesIn   int64         `json:"bytes_in"`  // Bytes read for this request so far
+	BytesOut  int64         `json:"bytes_out"` // Bytes written for this request so far
+	Goroutine int64         `json:"goroutine"` // ID of the goroutine serving it, for matching stack dumps
+}
+
+// inflightEntry is a registered request with the counters needed to
+// compute its progress
+type inflightEntry struct {
+	info     InflightInfo
+	conn     *countingConn
+	inStart  int64
+	outStart int64
+}
+
+// inflightShard is one locked slice of the registry
+type inflightShard struct {
+	mu      sync.Mutex
+	entries map[uint64]*inflightEntry
+}
+
+// inflightRegistry tracks the requests being served
+// Requests are spread across shards by ID so concurrent requests rarely
+// contend; adding and removing are O(1)
+type inflightRegistry struct {
+	enabled atomic.Bool // Requests are registered, see TrackInflight
+	seq     atomic.Uint64
+	shards  [inflightShards]inflightShard
+}
+
+// add registers a request and returns the ID used to remove it
+func (reg *inflightRegistry) add(e *inflightEntry) uint64 {
+	id := reg.seq.Add(1)
+	e.info.ID = id
+
+	s := &reg.shards[id%inflightShards]
+	s.mu.Lock()
+	if s.entries == nil {
+		s.entries = make(map[uint64]*inflightEntry)
+	}
+	s.entries[id] = e
+	s.mu.Unlock()
+	return id
+}
+
+// remove unregisters a finished request
+func (reg *inflightRegistry) remove(id uint64) {
+	s := &reg.shards[id%inflightShards]
+	s.mu.Lock()
+	delete(s.entries, id)
+	s.mu.Unlock()
+}
+
+// snapshot returns the registered requests, oldest first
+func (reg *inflightRegistry) snapshot() []InflightInfo {
+	now := time.Now()
+	var out []InflightInfo
+	for i := range reg.shards {
+		s := &reg.shards[i]
+		s.mu.Lock()
+		for _, e := range s.entries {
+			info := e.info
+			info.Elapsed = now.Sub(info.Start)
+			info.BytesIn = e.conn.in.Load() - e.inStart
+			info.BytesOut = e.conn.out.Load() - e.outStart
+			out = append(out, info)
+		}
+		s.mu.Unlock()
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// goroutineID parses the current goroutine ID from its stack header
+// Only meant for diagnostics; returns 0 if the header cannot be parsed
+func goroutineID() int64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseInt(string(b), 10, 64)
+	return id
+}
+
+// TrackInflight records the requests being served for InflightRequests
+// Tracking costs an allocation and a stack read per request, so it is off
+// until enabled here or by InflightRoute
+func (r *Router) TrackInflight(enabled bool) {
+	r.inflight.enabled.Store(enabled)
+}
+
+// InflightRequests lists the requests currently being served, oldest first
+// Use it when a server seems stuck to see which handlers are still running.
+// Only requests started while TrackInflight is on are listed
+func (r *Router) InflightRequests() []InflightInfo {
+	return r.inflight.snapshot()
+}
+
+// InflightRequests lists the requests currently being served by the router
+// Returns nil when the server has no Router
+func (s *Server) InflightRequests() []InflightInfo {
+	if s.Router == nil {
+		return nil
+	}
+	return s.Router.InflightRequests()
+}
+
+// ErrInflightNoAuth is returned by InflightRoute when no auth middleware is given
+var ErrInflightNoAuth = errors.New("inflight route requires an auth middleware")
+
+// InflightRoute registers an admin route listing the in-flight requests,
+// and turns on TrackInflight
+// The listing is JSON, or an HTML table for clients preferring text/html.
+// The route is hidden from the docs and wrapped by auth, which must reject
+// unauthorized callers since paths and client IPs are exposed
+func (r *Router) InflightRoute(path string, auth Middleware) (*RouteInfo, error) {
+	if auth == nil {
+		return nil, ErrInflightNoAuth
+	}
+
+	handler := func(req *Request, w *Writer) {
+		list := r.InflightRequests()
+		if negotiateErrorFormat(req.Headers.Get("Accept")) == ErrorHTML {
+			w.Headers.Add("Content-Type", errorContentTypes[ErrorHTML])
+			if err := inflightPage.Execute(w, list); err != nil {
+				Error(w, err, http.StatusInternalServerError)
+			}
+			return
+		}
+		if list == nil {
+			list = []InflightInfo{}
+		}
+		w.WriteJson(list)
+	}
+
+	info := r.addRoute(path, traceLayer("inflight", auth(handler)), nil, callerLocation(1), "GET")
+	if info == nil {
+		return nil, errors.New("inflight route: path already registered: " + path)
+	}
+	r.TrackInflight(true)
+	return info.Hide(), nil
+}
+
+// inflightPage renders the in-flight listing for browsers
+var inflightPage = htmltemplate.Must(htmltemplate.New("inflight").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>In-flight requests</title>
+<style>
+` + docsThemeCSS + `
+body { font-family: 'Inter', sans-serif; background-color: var(--bg-dark); color: var(--text-primary); }
+table { border-collapse: collapse; font-family: monospace; }
+th, td { padding: 4px 10px; border-bottom: 1px solid var(--border); text-align: left; }
+</style>
+</head>
+<body>
+<h1>In-flight requests ({{len .}})</h1>
+<table>
+<tr><th>ID</th><th>Method</th><th>Path</th><th>Route</th><th>Client</th><th>Elapsed</th><th>In</th><th>Out</th><th>Goroutine</th></tr>
+{{range .}}<tr><td>{{.ID}}</td><td>{{.Method}}</td><td>{{.Path}}</td><td>{{.Route}}</td><td>{{.ClientIP}}</td><td>{{.Elapsed}}</td><td>{{.BytesIn}}</td><td>{{.BytesOut}}</td><td>{{.Goroutine}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
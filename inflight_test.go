@@ -0,0 +1,116 @@
+package gouter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// slowRoute registers GET /slow, which blocks until release is closed, and
+// returns a channel closed once a request entered it
+func slowRoute(r *Router, release chan struct{}) <-chan struct{} {
+	entered := make(chan struct{}, 1)
+	r.Get("/slow", func(req *Request, w *Writer) {
+		entered <- struct{}{}
+		<-release
+		w.Write([]byte("done"))
+	})
+	return entered
+}
+
+func TestInflightRequests(t *testing.T) {
+	r := newTestRouter()
+	r.TrackInflight(true)
+	release := make(chan struct{})
+	entered := slowRoute(r, release)
+	addr := serveRouter(t, r)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+
+	list := r.InflightRequests()
+	if len(list) != 1 {
+		t.Fatalf("in-flight requests = %+v, want the slow one", list)
+	}
+	if info := list[0]; info.Method != "GET" || info.Path != "/slow" || info.Route != "/slow" ||
+		info.ClientIP != "127.0.0.1" || info.Goroutine == 0 || info.BytesIn == 0 {
+		t.Errorf("in-flight info = %+v", info)
+	}
+
+	close(release)
+	<-done
+	eventually(t, "the finished request to leave the listing", func() bool { return len(r.InflightRequests()) == 0 })
+}
+
+func TestInflightRequestsOffByDefault(t *testing.T) {
+	r := newTestRouter()
+	release := make(chan struct{})
+	entered := slowRoute(r, release)
+	addr := serveRouter(t, r)
+
+	go func() {
+		if resp, err := http.Get("http://" + addr + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-entered
+	defer close(release)
+
+	if list := r.InflightRequests(); len(list) != 0 {
+		t.Errorf("in-flight requests without TrackInflight = %+v, want none", list)
+	}
+}
+
+func TestInflightRequestsWithoutRouter(t *testing.T) {
+	var s Server
+	if list := s.InflightRequests(); list != nil {
+		t.Errorf("in-flight requests of a Server without Router = %+v, want nil", list)
+	}
+}
+
+func TestInflightRoute(t *testing.T) {
+	r := newTestRouter()
+	if _, err := r.InflightRoute("/admin/inflight", nil); !errors.Is(err, ErrInflightNoAuth) {
+		t.Fatalf("InflightRoute without auth = %v, want ErrInflightNoAuth", err)
+	}
+
+	auth := func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			if req.Headers.Get("Authorization") != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(req, w)
+		}
+	}
+	info, err := r.InflightRoute("/admin/inflight", auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Hidden {
+		t.Errorf("admin route is not hidden from the docs")
+	}
+	addr := serveRouter(t, r)
+
+	resps := rawExchange(t, addr, "GET /admin/inflight HTTP/1.1\r\nHost: x\r\n\r\n"+
+		"GET /admin/inflight HTTP/1.1\r\nHost: x\r\nAuthorization: secret\r\n\r\n", 2)
+	if resps[0].StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want 401", resps[0].StatusCode)
+	}
+
+	// The listing includes the admin request itself
+	var list []InflightInfo
+	if err := json.NewDecoder(resps[1].Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].Path != "/admin/inflight" {
+		t.Errorf("listing = %+v, want the admin request", list)
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Package httpdate parses and formats the dates of HTTP headers (RFC 7231
+section 7.1.1.1): Date, Last-Modified, If-Modified-Since, Expires and
+Retry-After.
+
+Input is accepted in the three formats the RFC requires recipients to
+understand; output is always IMF-fixdate in GMT.
+*/
+package httpdate
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts of the accepted formats, preferred first
+const (
+	IMFFixdate = "Mon, 02 Jan 2006 15:04:05 GMT"  // Sun, 06 Nov 1994 08:49:37 GMT
+	RFC850     = "Monday, 02-Jan-06 15:04:05 GMT" // Sunday, 06-Nov-94 08:49:37 GMT
+	ASCTime    = "Mon Jan _2 15:04:05 2006"       // Sun Nov  6 08:49:37 1994
+)
+
+// ErrInvalidDate is returned for values in none of the accepted formats
+var ErrInvalidDate = errors.New("invalid HTTP date")
+
+// Parse reads an HTTP date in any of the accepted formats
+// Two-digit RFC 850 years more than 50 years in the future are taken as the
+// most recent past year with the same digits. The result is in UTC
+func Parse(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	if t, err := time.Parse(IMFFixdate, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(RFC850, s); err == nil {
+		if t.After(time.Now().AddDate(50, 0, 0)) {
+			t = t.AddDate(-100, 0, 0)
+		}
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(ASCTime, s); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, ErrInvalidDate
+}
+
+// Format writes t as IMF-fixdate in GMT, dropping sub-second precision
+func Format(t time.Time) string {
+	return t.UTC().Format(IMFFixdate)
+}
+
+// RetryAfter writes a Retry-After delay as delta-seconds, rounded up
+// Negative delays are written as 0
+func RetryAfter(d time.Duration) string {
+	return strconv.Itoa(int(max(math.Ceil(d.Seconds()), 0)))
+}
+
+// RetryAt writes a Retry-After value in the date form
+func RetryAt(t time.Time) string {
+	return Format(t)
+}
+
+// ParseRetryAfter reads a Retry-After value in either form into the delay
+// left from now; dates in the past give 0
+func ParseRetryAfter(s string, now time.Time) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.ParseUint(s, 10, 31); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	t, err := Parse(s)
+	if err != nil {
+		return 0, err
+	}
+	return max(t.Sub(now), 0), nil
+}
@@ -0,0 +1,103 @@
+package httpdate
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	// The example of RFC 7231 section 7.1.1.1 in each format
+	want := time.Date(1994, time.November, 6, 8, 49, 37, 0, time.UTC)
+	for _, s := range []string{
+		"Sun, 06 Nov 1994 08:49:37 GMT",
+		"Sunday, 06-Nov-94 08:49:37 GMT",
+		"Sun Nov  6 08:49:37 1994",
+		"  Sun, 06 Nov 1994 08:49:37 GMT ",
+	} {
+		got, err := Parse(s)
+		if err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("Parse(%q) = %v, %v, want %v", s, got, err, want)
+		}
+		if f := Format(got); f != "Sun, 06 Nov 1994 08:49:37 GMT" {
+			t.Errorf("Format(Parse(%q)) = %q", s, f)
+		}
+	}
+
+	for _, s := range []string{
+		"",
+		"yesterday",
+		"Sun, 06 Nov 1994 08:49:37 PST",
+		"Sun, 06 Nov 1994 08:49:37 +0000",
+		"1994-11-06T08:49:37Z",
+		"Sun, 6 Nov 1994 08:49:37",
+	} {
+		if _, err := Parse(s); !errors.Is(err, ErrInvalidDate) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalidDate", s, err)
+		}
+	}
+
+	// Two-digit years far in the future belong to the previous century
+	future := time.Now().UTC().AddDate(60, 0, 0)
+	got, err := Parse(future.Format(RFC850))
+	if err != nil || got.Year() != future.Year()-100 {
+		t.Errorf("Parse of a year 60 years ahead = %v, %v, want year %d", got, err, future.Year()-100)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	zone := time.FixedZone("BRT", -3*3600)
+	for _, in := range []time.Time{
+		time.Date(2026, time.February, 28, 23, 59, 59, 999_999_999, zone),
+		time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Unix(0, 0),
+	} {
+		s := Format(in)
+		parsed, err := Parse(s)
+		if err != nil {
+			t.Fatalf("Parse(Format(%v)) failed: %v", in, err)
+		}
+		if !parsed.Equal(in.Truncate(time.Second)) || Format(parsed) != s {
+			t.Errorf("round trip of %v gave %v (%q then %q)", in, parsed, s, Format(parsed))
+		}
+	}
+	if got := Format(time.Date(2026, time.March, 1, 9, 0, 0, 0, time.FixedZone("CET", 3600))); got != "Sun, 01 Mar 2026 08:00:00 GMT" {
+		t.Errorf("Format in CET = %q, want GMT", got)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                  "0",
+		-5 * time.Second:   "0",
+		time.Millisecond:   "1",
+		30 * time.Second:   "30",
+		90*time.Second + 1: "91",
+		2 * time.Hour:      "7200",
+	} {
+		if got := RetryAfter(d); got != want {
+			t.Errorf("RetryAfter(%v) = %q, want %q", d, got, want)
+		}
+	}
+
+	now := time.Date(2026, time.May, 4, 12, 0, 0, 0, time.UTC)
+	if got := RetryAt(now.Add(time.Hour)); got != "Mon, 04 May 2026 13:00:00 GMT" {
+		t.Errorf("RetryAt = %q", got)
+	}
+	for s, want := range map[string]time.Duration{
+		"120":                            2 * time.Minute,
+		" 0 ":                            0,
+		"Mon, 04 May 2026 12:10:00 GMT":  10 * time.Minute,
+		"Monday, 04-May-26 12:00:30 GMT": 30 * time.Second,
+		"Mon, 04 May 2026 11:00:00 GMT":  0,
+	} {
+		if got, err := ParseRetryAfter(s, now); err != nil || got != want {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	for _, s := range []string{"-1", "1.5", "soon"} {
+		if _, err := ParseRetryAfter(s, now); err == nil {
+			t.Errorf("ParseRetryAfter(%q) succeeded", s)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package gouter
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// ErrAddrInUse is returned when the listen address is taken by another socket
+var ErrAddrInUse = errors.New("address already in use")
+
+// ErrPermission is returned when the process may not bind the address,
+// typically a port below 1024 without privileges
+var ErrPermission = errors.New("permission denied")
+
+// ErrInvalidAddr is returned when the listen address is not host:port
+var ErrInvalidAddr = errors.New("invalid listen address")
+
+// listen opens a TCP listener, turning common failures into actionable errors
+func listen(addr string) (net.Listener, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("%w %q: expected \":8080\", \"127.0.0.1:8080\" or \"[::1]:8080\": %w", ErrInvalidAddr, addr, err)
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, listenError(addr, err)
+	}
+	return l, nil
+}
+
+// listenError explains why binding addr failed
+func listenError(addr string, err error) error {
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	switch {
+	case errors.Is(err, syscall.EADDRINUSE):
+		holder := ""
+		if pid, name := portHolder(port); pid > 0 {
+			holder = fmt.Sprintf(" (held by pid %d %s)", pid, name)
+		}
+		return fmt.Errorf("%w: %s%s; stop the other process or pick another port: %w", ErrAddrInUse, addr, holder, err)
+	case errors.Is(err, syscall.EACCES):
+		hint := ""
+		if port > 0 && port < 1024 {
+			hint = "; ports below 1024 need root or CAP_NET_BIND_SERVICE (setcap 'cap_net_bind_service=+ep' <binary>), or listen above 1024 behind a proxy"
+		}
+		return fmt.Errorf("%w: %s%s: %w", ErrPermission, addr, hint, err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,73 @@
+package gouter
+
+import (
+	"errors"
+	"net"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestListenAddrInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	addr := l.Addr().String()
+
+	err = Run(addr, newTestRouter())
+	if !errors.Is(err, ErrAddrInUse) || !errors.Is(err, syscall.EADDRINUSE) {
+		t.Fatalf("Run on a taken port = %v, want ErrAddrInUse wrapping EADDRINUSE", err)
+	}
+	if !strings.Contains(err.Error(), addr) || !strings.Contains(err.Error(), "pick another port") {
+		t.Errorf("error %q does not name the address and a way out", err)
+	}
+	if runtime.GOOS == "linux" {
+		if want := "held by pid " + strconv.Itoa(os.Getpid()); !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not name the holder (%s)", err, want)
+		}
+	}
+
+	s := &Server{Addr: addr, Router: newTestRouter()}
+	if err := s.ListenAndServe(); !errors.Is(err, ErrAddrInUse) {
+		t.Errorf("Server.ListenAndServe = %v, want ErrAddrInUse", err)
+	}
+}
+
+func TestListenInvalidAddr(t *testing.T) {
+	for _, addr := range []string{"8080", "localhost", "::1:8080", "[::1]"} {
+		_, err := listen(addr)
+		if !errors.Is(err, ErrInvalidAddr) {
+			t.Errorf("listen(%q) = %v, want ErrInvalidAddr", addr, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), `"[::1]:8080"`) {
+			t.Errorf("listen(%q) error %q does not show the IPv6 form", addr, err)
+		}
+	}
+}
+
+func TestListenPermissionHint(t *testing.T) {
+	denied := &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EACCES)}
+
+	err := listenError(":80", denied)
+	if !errors.Is(err, ErrPermission) || !errors.Is(err, syscall.EACCES) {
+		t.Fatalf("listenError = %v, want ErrPermission wrapping EACCES", err)
+	}
+	if !strings.Contains(err.Error(), "CAP_NET_BIND_SERVICE") {
+		t.Errorf("privileged port error %q has no hint", err)
+	}
+
+	if err := listenError(":8080", denied); !errors.Is(err, ErrPermission) || strings.Contains(err.Error(), "below 1024") {
+		t.Errorf("unprivileged port error = %v, want ErrPermission without the port hint", err)
+	}
+
+	other := errors.New("boom")
+	if err := listenError(":8080", other); err != other {
+		t.Errorf("unknown error = %v, want it unchanged", err)
+	}
+}
@@ -0,0 +1,155 @@
+package gouter
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// clientManifestVersion identifies the layout of client manifests
+// Bump it only with a change to clientRoute or clientManifest
+const clientManifestVersion = 1
+
+// ClientManifestOptions selects the routes of a client manifest
+type ClientManifestOptions struct {
+	PathPrefix        string // Only include routes under this prefix (e.g., "/api")
+	IncludeWebSockets bool   // Include websocket routes, left out by default
+}
+
+// clientManifest is the document produced by ExportClientManifest
+type clientManifest struct {
+	Schema int           `json:"schema"`
+	Routes []clientRoute `json:"routes"`
+}
+
+// clientRoute is the client view of a route: no handler, middleware or
+// host details
+type clientRoute struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Params      []string `json:"params,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	Deprecation string   `json:"deprecation,omitempty"`
+	Version     string   `json:"version,omitempty"`
+	WebSocket   bool     `json:"websocket,omitempty"`
+}
+
+// RequireScopes declares the scopes a caller needs for the route
+// The declaration feeds the docs, ExportTable and ExportClientManifest; auth
+// middlewares enforce it through Request.RequiredScopes
+func (r *RouteInfo) RequireScopes(scopes ...string) *RouteInfo {
+	r.Scopes = append(r.Scopes, scopes...)
+	return r
+}
+
+// Deprecate marks the route deprecated with a note for its callers
+func (r *RouteInfo) Deprecate(note string) *RouteInfo {
+	r.Deprecation = note
+	return r
+}
+
+// SetVersion tags the route with an API version (e.g., "v2")
+func (r *RouteInfo) SetVersion(tag string) *RouteInfo {
+	r.Version = tag
+	return r
+}
+
+// RequiredScopes returns the scopes declared for the matched route
+func (r *Request) RequiredScopes() []string {
+	if r.route == nil {
+		return nil
+	}
+	return r.route.Scopes
+}
+
+// ExportClientManifest serializes the routes a frontend may call
+// Hidden routes are left out, and so is server-only metadata such as
+// handlers, middlewares and hosts. Output is sorted like ExportTable and
+// carries a schema version; see ClientManifestTypeScript for its type
+func (r *Router) ExportClientManifest(opts ClientManifestOptions) ([]byte, error) {
+	manifest := clientManifest{Schema: clientManifestVersion, Routes: []clientRoute{}}
+
+	for _, doc := range r.docs {
+		if doc.Hidden || !strings.HasPrefix(doc.Path, opts.PathPrefix) {
+			continue
+		}
+		if doc.Protocol == "websocket" && !opts.IncludeWebSockets {
+			continue
+		}
+
+		var params []string
+		for _, p := range doc.Parameters {
+			params = append(params, p.Name)
+		}
+		// A route split by method is listed once per method
+		for _, method := range r.docMethods(doc) {
+			manifest.Routes = append(manifest.Routes, clientRoute{
+				Method:      method,
+				Path:        doc.Path,
+				Params:      params,
+				Scopes:      doc.Scopes,
+				Deprecation: doc.Deprecation,
+				Version:     doc.Version,
+				WebSocket:   doc.Protocol == "websocket",
+			})
+		}
+	}
+
+	sort.Slice(manifest.Routes, func(i, j int) bool {
+		a, b := manifest.Routes[i], manifest.Routes[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Method < b.Method
+	})
+
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// ClientManifestTypeScript returns a .d.ts declaring the manifest shape
+// It is derived from the same types as ExportClientManifest, so the two
+// cannot drift apart
+func ClientManifestTypeScript() string {
+	var b strings.Builder
+	b.WriteString("// Generated by gouter: client manifest schema " + strconv.Itoa(clientManifestVersion) + "\n\n")
+	writeTSInterface(&b, "ClientRoute", reflect.TypeOf(clientRoute{}), nil)
+	b.WriteString("\n")
+	writeTSInterface(&b, "ClientManifest", reflect.TypeOf(clientManifest{}), map[string]string{
+		"schema": strconv.Itoa(clientManifestVersion),
+		"routes": "ClientRoute[]",
+	})
+	return b.String()
+}
+
+// writeTSInterface declares a struct as a TypeScript interface, using the
+// JSON names; override gives the type of specific fields
+func writeTSInterface(b *strings.Builder, name string, t reflect.Type, override map[string]string) {
+	b.WriteString("export interface " + name + " {\n")
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		field, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+
+		ty, ok := override[field]
+		if !ok {
+			switch f.Type.Kind() {
+			case reflect.String:
+				ty = "string"
+			case reflect.Bool:
+				ty = "boolean"
+			case reflect.Int:
+				ty = "number"
+			case reflect.Slice:
+				ty = "string[]"
+			}
+		}
+
+		optional := ""
+		if opts == "omitempty" {
+			optional = "?"
+		}
+		b.WriteString("  " + field + optional + ": " + ty + ";\n")
+	}
+	b.WriteString("}\n")
+}
@@ -0,0 +1,74 @@
+package gouter
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// manifestRouter has visible, hidden, websocket and out-of-prefix routes
+func manifestRouter() *Router {
+	r := newTestRouter()
+	noop := func(req *Request, w *Writer) {}
+	r.Route("/api/users", noop, "GET", "POST").RequireScopes("users:read")
+	r.Get("/api/users/:id", noop).RequireScopes("users:read").SetVersion("v2")
+	r.Delete("/api/users/:id", noop).RequireScopes("users:write", "admin").Deprecate("use /api/v2/users/:id")
+	r.Get("/api/internal/stats", noop).Hide()
+	r.WebSocket("/api/events", func(ws *WebSocket, req *Request) {}, WebSocketConfig{})
+	r.Get("/health", noop)
+	return r
+}
+
+func TestClientManifestGolden(t *testing.T) {
+	data, err := manifestRouter().ExportClientManifest(ClientManifestOptions{PathPrefix: "/api", IncludeWebSockets: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "client_manifest.golden.json", append(data, '\n'))
+
+	if strings.Contains(string(data), "/internal") || strings.Contains(string(data), "/health") {
+		t.Errorf("manifest lists hidden or out-of-prefix routes:\n%s", data)
+	}
+	for _, field := range []string{`"handler"`, `"middlewares"`, `"host"`} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("manifest carries server-only field %s", field)
+		}
+	}
+}
+
+func TestClientManifestDefaults(t *testing.T) {
+	data, err := manifestRouter().ExportClientManifest(ClientManifestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m struct {
+		Schema int
+		Routes []struct{ Method, Path string }
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatal(err)
+	}
+	var routes []string
+	for _, r := range m.Routes {
+		routes = append(routes, r.Method+" "+r.Path)
+	}
+	want := "GET /api/users POST /api/users DELETE /api/users/:id GET /api/users/:id GET /health"
+	if got := strings.Join(routes, " "); got != want || m.Schema != clientManifestVersion {
+		t.Errorf("schema %d with routes %q, want %d with %q", m.Schema, got, clientManifestVersion, want)
+	}
+}
+
+// TestClientManifestSchema pins the manifest shape to its schema version:
+// changing clientRoute or clientManifest fails here until the version is
+// bumped and a golden file for the new version is added with -update
+func TestClientManifestSchema(t *testing.T) {
+	name := "client_manifest_v" + strconv.Itoa(clientManifestVersion) + ".d.ts"
+	if !*update {
+		if _, err := os.Stat("testdata/" + name); err != nil {
+			t.Fatalf("no golden file for schema %d: %v", clientManifestVersion, err)
+		}
+	}
+	golden(t, name, []byte(ClientManifestTypeScript()))
+}
@@ -0,0 +1,168 @@
+package gouter
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// allowedMethodsKey holds the methods of a path while its 405 answer runs
+const allowedMethodsKey = "gouter.allowedMethods"
+
+// methodTable dispatches the requests of a path registered with methods
+type methodTable struct {
+	top      *Router               // Router whose HandleMethodNotAllowed and HandleOPTIONS apply
+	routes   map[string]exactRoute // Handler and docs entry by method
+	allow    []string              // Allow header values in registration order
+	fallback Handler               // 405 answer, wrapped like the routes of the path
+}
+
+// newMethodTable creates the method dispatch of path
+// inner answers methods without a route, the 405 of the table when nil; it
+// is wrapped in the middlewares of the route so e.g. CORS preflights and
+// request logs still see these requests
+func (r *Router) newMethodTable(path string, group *Group, location string, inner Handler) *methodTable {
+	t := &methodTable{top: r.docsOwner(), routes: make(map[string]exactRoute)}
+	if inner == nil {
+		inner = t.notAllowed
+	}
+	t.fallback, _ = r.prepareRoute(path, inner, group, location)
+	return t
+}
+
+// add registers the handler of methods; GET also answers HEAD unless HEAD
+// has a handler of its own
+func (t *methodTable) add(handler Handler, doc *RouteInfo, methods []string) {
+	for _, m := range methods {
+		t.routes[m] = exactRoute{handler: handler, info: doc}
+		if !slices.Contains(t.allow, m) {
+			t.allow = append(t.allow, m)
+		}
+		if m == "GET" && !slices.Contains(t.allow, "HEAD") {
+			t.allow = append(t.allow, "HEAD")
+		}
+	}
+}
+
+// lookup returns the route of a request method
+func (t *methodTable) lookup(method string) (exactRoute, bool) {
+	e, ok := t.routes[method]
+	if !ok && method == "HEAD" {
+		e, ok = t.routes["GET"]
+	}
+	return e, ok
+}
+
+// serve runs the handler of the request method
+func (t *methodTable) serve(r *Request, w *Writer) {
+	e, ok := t.lookup(r.Method)
+	if !ok {
+		// CORS preflights land here and answer with the methods of the path
+		r.SetValue(allowedMethodsKey, t.allow)
+		t.fallback(r, w)
+		return
+	}
+	r.route = e.info
+	e.handler(r, w)
+}
+
+// notAllowed answers a method the path has no route for: OPTIONS with the
+// methods of the path, anything else with 405, unless the router turned
+// these answers off
+func (t *methodTable) notAllowed(r *Request, w *Writer) {
+	allow := t.allow
+	if !t.top.noAutoOptions {
+		allow = append(slices.Clip(allow), "OPTIONS")
+	}
+
+	switch {
+	case r.Method == "OPTIONS" && !t.top.noAutoOptions:
+		w.SetHeader("Allow", strings.Join(allow, ", "))
+		w.WriteHeader(http.StatusNoContent)
+	case t.top.methodNotFound:
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.SetHeader("Allow", strings.Join(allow, ", "))
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleMethodNotAllowed sets how paths registered with methods answer the
+// other methods: 405 with an Allow header when on, 404 as for an unknown
+// path when off. Enabled by default
+func (r *Router) HandleMethodNotAllowed(on bool) {
+	r.methodNotFound = !on
+}
+
+// HandleOPTIONS makes paths registered with methods answer OPTIONS with 204
+// and an Allow header listing their methods, unless OPTIONS has a route of
+// its own. CORS preflights are still answered by the CORS middleware.
+// Enabled by default
+func (r *Router) HandleOPTIONS(on bool) {
+	r.noAutoOptions = !on
+}
+
+// normalizeMethods upper-cases methods and drops repeats
+func normalizeMethods(methods []string) []string {
+	if len(methods) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(methods))
+	for _, m := range methods {
+		m = strings.ToUpper(strings.TrimSpace(m))
+		if m != "" && !slices.Contains(out, m) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// Get registers a handler for GET (and HEAD) requests to path
+func (r *Router) Get(path string, handler Handler) *RouteInfo {
+	return r.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "GET")
+}
+
+// Post registers a handler for POST requests to path
+func (r *Router) Post(path string, handler Handler) *RouteInfo {
+	return r.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "POST")
+}
+
+// Put registers a handler for PUT requests to path
+func (r *Router) Put(path string, handler Handler) *RouteInfo {
+	return r.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "PUT")
+}
+
+// Patch registers a handler for PATCH requests to path
+func (r *Router) Patch(path string, handler Handler) *RouteInfo {
+	return r.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "PATCH")
+}
+
+// Delete registers a handler for DELETE requests to path
+func (r *Router) Delete(path string, handler Handler) *RouteInfo {
+	return r.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "DELETE")
+}
+
+// Get registers a handler for GET (and HEAD) requests within the group
+func (g *Group) Get(path string, handler Handler) *RouteInfo {
+	return g.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "GET")
+}
+
+// Post registers a handler for POST requests within the group
+func (g *Group) Post(path string, handler Handler) *RouteInfo {
+	return g.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "POST")
+}
+
+// Put registers a handler for PUT requests within the group
+func (g *Group) Put(path string, handler Handler) *RouteInfo {
+	return g.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "PUT")
+}
+
+// Patch registers a handler for PATCH requests within the group
+func (g *Group) Patch(path string, handler Handler) *RouteInfo {
+	return g.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "PATCH")
+}
+
+// Delete registers a handler for DELETE requests within the group
+func (g *Group) Delete(path string, handler Handler) *RouteInfo {
+	return g.addRoute(path, traceLayer("handler", withGuards(handler)), nil, callerLocation(1), "DELETE")
+}
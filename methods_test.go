@@ -0,0 +1,199 @@
+package gouter
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// methodEcho answers with the name of the handler and the request method
+func methodEcho(name string) Handler {
+	return func(r *Request, w *Writer) {
+		w.Write([]byte(name + " " + r.Method + " " + r.Params.Get("id")))
+	}
+}
+
+// methodExchange sends a bodiless request and returns the status, Allow
+// header and body; HEAD responses are read without a body
+func methodExchange(t *testing.T, addr, method, path string) (int, string, string) {
+	t.Helper()
+	resp := fetch(t, addr, method, path, "Content-Length: 0")
+	return resp.StatusCode, resp.Header.Get("Allow"), bodyString(t, resp)
+}
+
+func TestMethodDispatch(t *testing.T) {
+	var seen atomic.Int32
+	r := newTestRouter()
+	// The middleware sees every request, 405 answers included
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			seen.Add(1)
+			next(req, w)
+		}
+	})
+	r.Get("/users", methodEcho("list"))
+	r.Post("/users", methodEcho("create"))
+	r.Get("/users/:id", methodEcho("show"))
+	r.Put("/users/:id", methodEcho("replace"))
+	r.Patch("/users/:id", methodEcho("update"))
+	r.Delete("/users/:id", methodEcho("remove"))
+	r.Route("/reports", methodEcho("report"), "post", " Put ")
+	r.Get("/files", methodEcho("files"))
+	r.Route("/files", methodEcho("head"), "HEAD")
+	r.Route("/any", methodEcho("any"))
+	addr := serveRouter(t, r)
+
+	cases := []struct {
+		method, path string
+		status       int
+		allow, body  string
+	}{
+		{"GET", "/users", 200, "", "list GET "},
+		{"POST", "/users", 200, "", "create POST "},
+		{"DELETE", "/users", 405, "GET, HEAD, POST, OPTIONS", ""},
+		{"OPTIONS", "/users", 204, "GET, HEAD, POST, OPTIONS", ""},
+		{"GET", "/users/7", 200, "", "show GET 7"},
+		{"PUT", "/users/7", 200, "", "replace PUT 7"},
+		{"PATCH", "/users/7", 200, "", "update PATCH 7"},
+		{"DELETE", "/users/7", 200, "", "remove DELETE 7"},
+		{"POST", "/users/7", 405, "GET, HEAD, PUT, PATCH, DELETE, OPTIONS", ""},
+		{"POST", "/reports", 200, "", "report POST "},
+		{"PUT", "/reports", 200, "", "report PUT "},
+		{"GET", "/reports", 405, "POST, PUT, OPTIONS", ""},
+		{"HEAD", "/users", 200, "", ""},
+		{"GET", "/files", 200, "", "files GET "},
+		{"PURGE", "/any", 200, "", "any PURGE "},
+		{"GET", "/missing", 404, "", ""},
+	}
+	for _, tc := range cases {
+		status, allow, body := methodExchange(t, addr, tc.method, tc.path)
+		if status != tc.status || allow != tc.allow || (tc.body != "" && body != tc.body) {
+			t.Errorf("%s %s = %d, Allow %q, %q; want %d, Allow %q, %q", tc.method, tc.path, status, allow, body, tc.status, tc.allow, tc.body)
+		}
+	}
+	// Unknown paths have no route, so no middleware chain
+	if n := int(seen.Load()); n != len(cases)-1 {
+		t.Errorf("middleware saw %d requests, want all %d to routed paths", n, len(cases)-1)
+	}
+
+	// HEAD answers with the GET length, unless HEAD has a route of its own
+	if resp := fetch(t, addr, "HEAD", "/users"); resp.ContentLength != int64(len("list HEAD ")) {
+		t.Errorf("HEAD /users Content-Length = %d, want the GET handler answer", resp.ContentLength)
+	}
+	if resp := fetch(t, addr, "HEAD", "/files"); resp.ContentLength != int64(len("head HEAD ")) {
+		t.Errorf("HEAD /files Content-Length = %d, want the HEAD handler answer", resp.ContentLength)
+	}
+}
+
+func TestMethodNotAllowedSwitches(t *testing.T) {
+	r := newTestRouter()
+	r.HandleMethodNotAllowed(false)
+	r.HandleOPTIONS(false)
+	r.Get("/items", methodEcho("list"))
+	r.Route("/items", methodEcho("options"), "OPTIONS")
+	r.Post("/orders", methodEcho("create"))
+	addr := serveRouter(t, r)
+
+	if status, allow, _ := methodExchange(t, addr, "DELETE", "/items"); status != 404 || allow != "" {
+		t.Errorf("405 off: DELETE = %d, Allow %q; want a plain 404", status, allow)
+	}
+	if status, _, body := methodExchange(t, addr, "OPTIONS", "/items"); status != 200 || body != "options OPTIONS " {
+		t.Errorf("OPTIONS route = %d %q, want its handler", status, body)
+	}
+	if status, _, _ := methodExchange(t, addr, "OPTIONS", "/orders"); status != 404 {
+		t.Errorf("automatic OPTIONS off = %d, want 404", status)
+	}
+
+	r2 := newTestRouter()
+	r2.HandleOPTIONS(false)
+	r2.Post("/orders", methodEcho("create"))
+	addr2 := serveRouter(t, r2)
+	if status, allow, _ := methodExchange(t, addr2, "OPTIONS", "/orders"); status != 405 || allow != "POST" {
+		t.Errorf("automatic OPTIONS off = %d, Allow %q; want 405 without OPTIONS listed", status, allow)
+	}
+}
+
+func TestGroupMethods(t *testing.T) {
+	r := newTestRouter()
+	err := r.Group("/api", func(g *Group) {
+		g.Get("/items/:id", methodEcho("show"))
+		g.Delete("/items/:id", methodEcho("remove"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := serveRouter(t, r)
+
+	if status, _, body := methodExchange(t, addr, "DELETE", "/api/items/3"); status != 200 || body != "remove DELETE 3" {
+		t.Errorf("DELETE = %d %q", status, body)
+	}
+	status, allow, _ := methodExchange(t, addr, "PUT", "/api/items/3")
+	if status != http.StatusMethodNotAllowed || !strings.HasPrefix(allow, "GET, HEAD, DELETE") {
+		t.Errorf("PUT = %d, Allow %q", status, allow)
+	}
+}
+
+func TestMethodSwitchesAtRequestTime(t *testing.T) {
+	var seen atomic.Int32
+	r := newTestRouter()
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			seen.Add(1)
+			next(req, w)
+		}
+	})
+	r.Post("/orders", methodEcho("create"))
+	r.HostRoute("api.example", "/orders", methodEcho("api"), "PUT")
+	addr := serveRouter(t, r)
+
+	onHost := func(method string) (int, string) {
+		raw := method + " /orders HTTP/1.1\r\nHost: api.example\r\nContent-Length: 0\r\n\r\n"
+		resp := rawExchange(t, addr, raw, 1)[0]
+		return resp.StatusCode, resp.Header.Get("Allow")
+	}
+
+	if status, allow, _ := methodExchange(t, addr, "OPTIONS", "/orders"); status != 204 || allow != "POST, OPTIONS" {
+		t.Errorf("OPTIONS = %d, Allow %q", status, allow)
+	}
+	if status, allow := onHost("OPTIONS"); status != 204 || allow != "PUT, OPTIONS" {
+		t.Errorf("OPTIONS on the host = %d, Allow %q", status, allow)
+	}
+
+	// Switches flipped after registration apply to the next request, host
+	// routes included
+	r.HandleOPTIONS(false)
+	r.HandleMethodNotAllowed(false)
+	if status, allow, _ := methodExchange(t, addr, "OPTIONS", "/orders"); status != 404 || allow != "" {
+		t.Errorf("OPTIONS with both off = %d, Allow %q; want 404", status, allow)
+	}
+	if status, allow := onHost("DELETE"); status != 404 || allow != "" {
+		t.Errorf("DELETE on the host with 405 off = %d, Allow %q; want 404", status, allow)
+	}
+	r.HandleMethodNotAllowed(true)
+	if status, allow := onHost("DELETE"); status != 405 || allow != "PUT" {
+		t.Errorf("DELETE on the host = %d, Allow %q; want 405 without OPTIONS", status, allow)
+	}
+
+	// The automatic answers run inside the middlewares of the path
+	if n := seen.Load(); n != 5 {
+		t.Errorf("middleware saw %d requests, want all 5", n)
+	}
+}
+
+func TestAutoOptionsWithCORS(t *testing.T) {
+	r := newTestRouter()
+	r.Use(CORS(CORSConfig{AllowedOrigins: []string{"https://app.example"}}))
+	r.Get("/items", methodEcho("list"))
+	r.Put("/items", methodEcho("replace"))
+	addr := serveRouter(t, r)
+
+	// A preflight is answered by CORS, a plain OPTIONS by the router
+	resp := preflight(t, addr, "/items", "https://app.example")
+	if resp.StatusCode != 204 || resp.Header.Get("Access-Control-Allow-Methods") != "GET, HEAD, PUT" || resp.Header.Get("Allow") != "" {
+		t.Errorf("preflight = %d %v", resp.StatusCode, resp.Header)
+	}
+	if status, allow, _ := methodExchange(t, addr, "OPTIONS", "/items"); status != 204 || allow != "GET, HEAD, PUT, OPTIONS" {
+		t.Errorf("OPTIONS = %d, Allow %q", status, allow)
+	}
+}
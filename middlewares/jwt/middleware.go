@@ -0,0 +1,90 @@
+/*
+Package jwt provides a middleware verifying JSON Web Tokens sent as bearer
+tokens.
+
+Features:
+- HS256 (shared secret) and RS256 (RSA public key) signatures
+- exp and nbf checks with a configurable leeway
+- Claims available from the request values and the request context
+*/
+package jwt
+
+import (
+	"context"
+	"crypto/rsa"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// ValueKey is the request value holding the Claims of a verified token
+const ValueKey = "claims"
+
+// contextKey keys the Claims in the request context
+type contextKey struct{}
+
+// Config configures the JWT middleware
+type Config struct {
+	Secret    []byte         // HS256 key, HS256 tokens are refused when empty
+	PublicKey *rsa.PublicKey // RS256 key, RS256 tokens are refused when nil
+	Leeway    time.Duration  // Clock skew tolerated on exp and nbf
+	Realm     string         // Realm of the 401 challenge, "Restricted" when empty
+}
+
+// Middleware requires a valid token in the Authorization header
+// Missing, malformed, badly signed, expired and not yet valid tokens get
+// 401 with a Bearer challenge. The claims are then available through
+// FromRequest, r.Value(ValueKey) and FromContext(r.Context())
+func Middleware(cfg Config) gouter.Middleware {
+	realm := cfg.Realm
+	if realm == "" {
+		realm = "Restricted"
+	}
+	challenge := `Bearer realm=` + strconv.Quote(realm)
+
+	return func(next gouter.Handler) gouter.Handler {
+		return func(r *gouter.Request, w *gouter.Writer) {
+			token, ok := bearerToken(r.Headers.Get("Authorization"))
+			if !ok {
+				w.SetHeader("WWW-Authenticate", challenge)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			claims, err := Parse(token, cfg, r.Clock().Now())
+			if err != nil {
+				w.SetHeader("WWW-Authenticate", challenge+`, error="invalid_token", error_description=`+strconv.Quote(err.Error()))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			r.SetValue(ValueKey, claims)
+			next(r.WithContext(context.WithValue(r.Context(), contextKey{}, claims)), w)
+		}
+	}
+}
+
+// FromRequest returns the claims verified by Middleware, or nil
+func FromRequest(r *gouter.Request) Claims {
+	claims, _ := r.Value(ValueKey).(Claims)
+	return claims
+}
+
+// FromContext returns the claims verified by Middleware from a request
+// context, or false
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(Claims)
+	return claims, ok
+}
+
+// bearerToken returns the token of a Bearer Authorization header
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,85 @@
+package jwt
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+	"github.com/Murilinho145SG/gouter/gtest"
+)
+
+// guarded serves /me behind Middleware(cfg) on a router reading clock
+func guarded(cfg Config, clock gouter.Clock) http.Handler {
+	r := gouter.NewRouter()
+	r.Update(func(d *gouter.Doc) { d.Active = false })
+	r.SetClockSource(clock)
+	r.Use(Middleware(cfg))
+	r.Get("/me", func(req *gouter.Request, w *gouter.Writer) {
+		fromCtx, ok := FromContext(req.Context())
+		fromValue, _ := req.Value(ValueKey).(Claims)
+		fmt.Fprint(w, FromRequest(req).Subject(), " ", fromCtx.Subject(), " ", ok, " ", fromValue.Subject())
+	})
+	return gouter.ToHTTPHandler(r)
+}
+
+// getMe sends GET /me with an optional Authorization header
+func getMe(h http.Handler, authorization string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/me", nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware(t *testing.T) {
+	clock := gtest.NewFakeClock(now)
+	h := guarded(Config{Secret: secret, Realm: "api"}, clock)
+	token := signHS256(secret, map[string]any{"sub": "ana", "exp": now.Unix() + 60})
+
+	rec := getMe(h, "Bearer "+token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("valid token = %d %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+	if got := rec.Body.String(); got != "ana ana true ana" {
+		t.Errorf("handler saw %q, want the claims from the helper, context and value", got)
+	}
+	if rec := getMe(h, "bearer  "+token+" "); rec.Code != http.StatusOK {
+		t.Errorf("lowercase scheme with spaces = %d", rec.Code)
+	}
+
+	for _, authorization := range []string{"", "Bearer", "Bearer   ", "Basic " + token, token} {
+		rec := getMe(h, authorization)
+		if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != `Bearer realm="api"` {
+			t.Errorf("Authorization %q = %d, WWW-Authenticate %q", authorization, rec.Code, rec.Header().Get("WWW-Authenticate"))
+		}
+		if strings.Contains(rec.Body.String(), "ana") {
+			t.Errorf("Authorization %q reached the handler", authorization)
+		}
+	}
+
+	// Expiry follows the router clock
+	clock.Advance(time.Minute)
+	rec = getMe(h, "Bearer "+token)
+	want := `Bearer realm="api", error="invalid_token", error_description="jwt: token expired"`
+	if rec.Code != http.StatusUnauthorized || rec.Header().Get("WWW-Authenticate") != want {
+		t.Errorf("expired token = %d, WWW-Authenticate %q", rec.Code, rec.Header().Get("WWW-Authenticate"))
+	}
+
+	rec = getMe(h, "Bearer "+signHS256([]byte("other"), map[string]any{"sub": "ana"}))
+	if got := rec.Header().Get("WWW-Authenticate"); !strings.HasSuffix(got, `error_description="jwt: invalid signature"`) {
+		t.Errorf("badly signed token challenge = %q", got)
+	}
+}
+
+func TestMiddlewareDefaultRealm(t *testing.T) {
+	h := guarded(Config{Secret: secret}, gtest.NewFakeClock(now))
+	if got := getMe(h, "").Header().Get("WWW-Authenticate"); got != `Bearer realm="Restricted"` {
+		t.Errorf("WWW-Authenticate = %q", got)
+	}
+}
@@ -0,0 +1,132 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Errors returned by Parse
+var (
+	ErrMalformed   = errors.New("jwt: malformed token")
+	ErrAlgorithm   = errors.New("jwt: unsupported or unconfigured algorithm")
+	ErrSignature   = errors.New("jwt: invalid signature")
+	ErrExpired     = errors.New("jwt: token expired")
+	ErrNotYetValid = errors.New("jwt: token not valid yet")
+)
+
+// Claims is the decoded payload of a token
+// Numbers are float64, as decoded by encoding/json
+type Claims map[string]any
+
+// Subject returns the "sub" claim, or ""
+func (c Claims) Subject() string {
+	s, _ := c["sub"].(string)
+	return s
+}
+
+// time returns a NumericDate claim (seconds since the epoch)
+func (c Claims) time(name string) (time.Time, bool, error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false, ErrMalformed
+	}
+	sec := int64(n)
+	return time.Unix(sec, int64((n-float64(sec))*1e9)), true, nil
+}
+
+// header is the JOSE header of a token
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+}
+
+// Parse verifies a compact serialized token against cfg and returns its
+// claims
+// The algorithm is only accepted when cfg holds its key: HS256 needs
+// Secret, RS256 needs PublicKey, so a token cannot pick a weaker one.
+// exp and nbf are checked at now, within Leeway
+func Parse(token string, cfg Config, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	if err := verify(h.Alg, parts[0]+"."+parts[1], sig, cfg); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if claims == nil {
+		return nil, ErrMalformed
+	}
+
+	exp, ok, err := claims.time("exp")
+	if err != nil {
+		return nil, err
+	}
+	if ok && !now.Before(exp.Add(cfg.Leeway)) {
+		return nil, ErrExpired
+	}
+	nbf, ok, err := claims.time("nbf")
+	if err != nil {
+		return nil, err
+	}
+	if ok && now.Add(cfg.Leeway).Before(nbf) {
+		return nil, ErrNotYetValid
+	}
+	return claims, nil
+}
+
+// verify checks the signature of the signing input with the key of alg
+func verify(alg, input string, sig []byte, cfg Config) error {
+	switch {
+	case alg == "HS256" && len(cfg.Secret) > 0:
+		mac := hmac.New(sha256.New, cfg.Secret)
+		mac.Write([]byte(input))
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return ErrSignature
+		}
+		return nil
+	case alg == "RS256" && cfg.PublicKey != nil:
+		sum := sha256.Sum256([]byte(input))
+		if rsa.VerifyPKCS1v15(cfg.PublicKey, crypto.SHA256, sum[:], sig) != nil {
+			return ErrSignature
+		}
+		return nil
+	}
+	return ErrAlgorithm
+}
+
+// decodeSegment decodes a base64url JSON segment into v
+func decodeSegment(seg string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformed
+	}
+	if json.Unmarshal(b, v) != nil {
+		return ErrMalformed
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	secret = []byte("shared-secret")
+	now    = time.Unix(1_700_000_000, 0)
+)
+
+// segment encodes v as a base64url JSON segment
+func segment(v any) string {
+	b, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// signHS256 builds a token for claims signed with key
+func signHS256(key []byte, claims any) string {
+	input := segment(header{Alg: "HS256", Typ: "JWT"}) + "." + segment(claims)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// signRS256 builds a token for claims signed with key
+func signRS256(t *testing.T, key *rsa.PrivateKey, claims any) string {
+	t.Helper()
+	input := segment(header{Alg: "RS256", Typ: "JWT"}) + "." + segment(claims)
+	sum := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestParseHS256(t *testing.T) {
+	cfg := Config{Secret: secret}
+	claims, err := Parse(signHS256(secret, map[string]any{"sub": "ana", "role": "admin", "exp": now.Unix() + 60}), cfg, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Subject() != "ana" || claims["role"] != "admin" || claims["exp"] != float64(now.Unix()+60) {
+		t.Errorf("claims = %v", claims)
+	}
+
+	valid := signHS256(secret, map[string]any{"sub": "ana"})
+	parts := strings.Split(valid, ".")
+	for name, tc := range map[string]struct {
+		token string
+		want  error
+	}{
+		"other key":         {signHS256([]byte("other"), map[string]any{"sub": "ana"}), ErrSignature},
+		"tampered payload":  {parts[0] + "." + segment(map[string]any{"sub": "root"}) + "." + parts[2], ErrSignature},
+		"two segments":      {parts[0] + "." + parts[1], ErrMalformed},
+		"bad signature b64": {parts[0] + "." + parts[1] + ".!!", ErrMalformed},
+		"bad header":        {"e30x." + parts[1] + "." + parts[2], ErrMalformed},
+		"alg none":          {segment(header{Alg: "none"}) + "." + parts[1] + ".", ErrAlgorithm},
+		"null payload":      {signHS256(secret, nil), ErrMalformed},
+		"array payload":     {signHS256(secret, []int{1}), ErrMalformed},
+		"string exp":        {signHS256(secret, map[string]any{"exp": "tomorrow"}), ErrMalformed},
+	} {
+		if _, err := Parse(tc.token, cfg, now); !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+
+	// Without a secret, HS256 tokens are refused rather than checked
+	// against an empty key
+	if _, err := Parse(signHS256(nil, map[string]any{}), Config{}, now); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("HS256 without a secret: err = %v", err)
+	}
+}
+
+func TestParseRS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{PublicKey: &key.PublicKey}
+
+	claims, err := Parse(signRS256(t, key, map[string]any{"sub": "svc"}), cfg, now)
+	if err != nil || claims.Subject() != "svc" {
+		t.Fatalf("claims = %v, err = %v", claims, err)
+	}
+	if _, err := Parse(signRS256(t, other, map[string]any{"sub": "svc"}), cfg, now); !errors.Is(err, ErrSignature) {
+		t.Errorf("other key: err = %v", err)
+	}
+
+	// A token cannot switch to HS256 and be checked with the public key as
+	// the HMAC secret
+	der, _ := json.Marshal(key.PublicKey)
+	if _, err := Parse(signHS256(der, map[string]any{"sub": "root"}), cfg, now); !errors.Is(err, ErrAlgorithm) {
+		t.Errorf("HS256 token with only an RSA key: err = %v", err)
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	at := func(d time.Duration) float64 { return float64(now.Add(d).Unix()) }
+	for name, tc := range map[string]struct {
+		claims map[string]any
+		leeway time.Duration
+		want   error
+	}{
+		"exp ahead":             {map[string]any{"exp": at(time.Second)}, 0, nil},
+		"exp now":               {map[string]any{"exp": at(0)}, 0, ErrExpired},
+		"exp passed":            {map[string]any{"exp": at(-time.Minute)}, 0, ErrExpired},
+		"exp within leeway":     {map[string]any{"exp": at(-time.Minute)}, 2 * time.Minute, nil},
+		"fractional exp":        {map[string]any{"exp": at(0) + 0.5}, 0, nil},
+		"nbf now":               {map[string]any{"nbf": at(0)}, 0, nil},
+		"nbf ahead":             {map[string]any{"nbf": at(time.Minute)}, 0, ErrNotYetValid},
+		"nbf within leeway":     {map[string]any{"nbf": at(time.Minute)}, time.Minute, nil},
+		"expired and not valid": {map[string]any{"exp": at(-time.Hour), "nbf": at(time.Hour)}, 0, ErrExpired},
+	} {
+		_, err := Parse(signHS256(secret, tc.claims), Config{Secret: secret, Leeway: tc.leeway}, now)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: err = %v, want %v", name, err, tc.want)
+		}
+	}
+}
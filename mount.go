@@ -0,0 +1,121 @@
+package gouter
+
+import "github.com/Murilinho145SG/gouter/log"
+
+// Mount serves the routes of child under prefix
+// Args:
+//   - prefix: Path prefix of the mounted routes (e.g., "/billing")
+//   - child: Router with its routes already registered
+//
+// Each mounted route keeps the middlewares of child and is wrapped in the
+// global middlewares of r, like routes registered on r. The child works on
+// its own headers: those set by the middlewares of r are merged under them
+// with Headers.Merge when they are sent or the child returns, so r sets
+// defaults the child and its handlers override, while list-valued headers
+// accumulate. Middlewares of the child, a ResponseCache for instance, do
+// not see the headers of r.
+// Routes registered on child afterwards, its host scopes and its
+// documentation server are not mounted
+// Returns the first registration error, e.g. a route conflict
+func (r *Router) Mount(prefix string, child *Router) error {
+	r.lazyInit()
+	location := callerLocation(1)
+
+	normalized, err := normalizeGroupPrefix(prefix)
+	if err != nil {
+		log.WarnE(3, err.Error()+": ["+prefix+"]")
+		return err
+	}
+	prefix = normalized
+
+	for _, doc := range child.docs {
+		handler := child.handlerList[doc.Path]
+		if doc.Host != "" || handler == nil {
+			continue
+		}
+
+		// Routes split by method are mounted one method set at a time
+		var methods []string
+		childTable := child.methods[doc.Path]
+		if childTable != nil {
+			for _, m := range childTable.allow {
+				if e, ok := childTable.routes[m]; ok && e.info == doc {
+					methods = append(methods, m)
+				}
+			}
+			if len(methods) == 0 {
+				continue
+			}
+			handler = childTable.routes[methods[0]].handler
+		}
+
+		path := prefix
+		if doc.Path != "/" {
+			path = joinRoutePath(prefix, doc.Path)
+		}
+		if err := r.checkRoute(path, location, methods...); err != nil {
+			log.Warn(err.Error())
+			return err
+		}
+
+		// The docs of the child route carry over, with the middlewares of
+		// both routers and the parameters of the prefix
+		var table *methodTable
+		if childTable != nil {
+			if table = r.methods[path]; table == nil {
+				table = r.newMethodTable(path, nil, location, childTable.fallback)
+			}
+		}
+		wrapped, info := r.prepareRoute(path, mountLayer(handler), nil, location, doc.Method)
+		mounted := *doc
+		mounted.Path = path
+		mounted.Middlewares = append(info.Middlewares, doc.Middlewares...)
+		mounted.chain = info.chain
+		info.chain.docs = []*RouteInfo{&mounted}
+		info.chain.tail = doc.Middlewares
+		mounted.Parameters = info.Parameters
+		for i, p := range mounted.Parameters {
+			for _, cp := range doc.Parameters {
+				if cp.Name == p.Name {
+					mounted.Parameters[i] = cp
+				}
+			}
+		}
+		// The child handler already checks the availability of its route
+		mounted.availability = nil
+		r.insertRoute(path, wrapped, &mounted, table, methods)
+	}
+	return nil
+}
+
+// mountLayer runs a mounted router on its own headers
+// The headers set so far become parent headers, merged under those of the
+// child once they are sent or the child returns; nested mounts stack
+func mountLayer(child Handler) Handler {
+	return func(r *Request, w *Writer) {
+		w.mu.Lock()
+		if !w.headersSent {
+			parent := make(Headers, len(w.parentHeaders)+len(w.Headers))
+			w.parentHeaders = parent.Merge(w.parentHeaders, MergeOverride).Merge(w.Headers, MergeOverride)
+			w.Headers = make(Headers)
+		}
+		w.mu.Unlock()
+
+		defer func() {
+			w.mu.Lock()
+			w.mergeParentHeaders()
+			w.mu.Unlock()
+		}()
+		child(r, w)
+	}
+}
+
+// mergeParentHeaders puts the headers set outside a mounted router under
+// those of the response; the caller holds w.mu
+func (w *Writer) mergeParentHeaders() {
+	if w.parentHeaders == nil {
+		return
+	}
+	w.Headers = w.parentHeaders.Merge(w.Headers, MergeOverride)
+	w.parentHeaders = nil
+}
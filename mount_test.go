@@ -0,0 +1,133 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mountedRouter mounts child under /child of a router using parent
+func mountedRouter(t *testing.T, parent Middleware, child *Router) string {
+	t.Helper()
+	r := newTestRouter()
+	r.Use(parent)
+	if err := r.Mount("/child", child); err != nil {
+		t.Fatal(err)
+	}
+	return serveRouter(t, r)
+}
+
+func TestMountHeaderPrecedence(t *testing.T) {
+	child := newTestRouter()
+	child.Use(DefaultHeaders(Headers{"Cache-Control": "private", "Vary": "Accept", "Set-Cookie": "child=1"}))
+	handler := func(req *Request, w *Writer) {
+		w.SetHeader("X-Handler", "1")
+		w.SetHeader("Vary", "Accept-Language, Origin")
+		w.SetHeader("Set-Cookie", "handler=1")
+		if req.Params.Get("mode") == "stream" {
+			w.Flush()
+		}
+		w.Write([]byte("ok"))
+	}
+	child.Get("/:mode", handler)
+
+	addr := mountedRouter(t, DefaultHeaders(Headers{
+		"Cache-Control": "public",
+		"X-Parent":      "1",
+		"Vary":          "Origin",
+		"Set-Cookie":    "parent=1",
+	}), child)
+
+	// Streamed headers leave before the child returns
+	for _, mode := range []string{"buffered", "stream"} {
+		resp := rawExchange(t, addr, "GET /child/"+mode+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		h := resp.Header
+		if h.Get("Cache-Control") != "private" || h.Get("X-Parent") != "1" || h.Get("X-Handler") != "1" {
+			t.Errorf("%s: single-valued headers %v, want the child value and both others", mode, h)
+		}
+		if got := h.Get("Vary"); got != "Origin, Accept, Accept-Language" {
+			t.Errorf("%s: Vary = %q, want Origin, Accept, Accept-Language", mode, got)
+		}
+		if got := h.Values("Set-Cookie"); !slices.Equal(got, []string{"parent=1", "child=1", "handler=1"}) {
+			t.Errorf("%s: Set-Cookie = %q, want every layer once", mode, got)
+		}
+	}
+}
+
+func TestMountHandlerOverridesChild(t *testing.T) {
+	child := newTestRouter()
+	child.Use(DefaultHeaders(Headers{"Cache-Control": "private"}))
+	child.Get("/", func(req *Request, w *Writer) {
+		w.SetHeader("Cache-Control", "no-store")
+	})
+	addr := mountedRouter(t, DefaultHeaders(Headers{"Cache-Control": "public"}), child)
+
+	resp := rawExchange(t, addr, "GET /child HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := resp.Header.Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want the handler value", got)
+	}
+}
+
+func TestMountedCacheIgnoresParentCookies(t *testing.T) {
+	calls := 0
+	cache := NewResponseCache(time.Minute)
+	child := newTestRouter()
+	child.Use(cache.Middleware())
+	child.Get("/page", func(req *Request, w *Writer) {
+		calls++
+		w.Write([]byte("page"))
+	})
+	addr := mountedRouter(t, DefaultHeaders(Headers{"Set-Cookie": "session=1", "Vary": "Origin"}), child)
+
+	raw := "GET /child/page HTTP/1.1\r\nHost: x\r\n\r\n"
+	resps := rawExchange(t, addr, raw+raw, 2)
+	if calls != 1 || resps[1].Header.Get("X-Cache") != "HIT" {
+		t.Errorf("handler ran %d times, second X-Cache %q, want one run and a HIT", calls, resps[1].Header.Get("X-Cache"))
+	}
+	for i, resp := range resps {
+		if resp.Header.Get("Set-Cookie") != "session=1" || resp.Header.Get("Vary") != "Origin" {
+			t.Errorf("response %d lacks the parent headers: %v", i+1, resp.Header)
+		}
+	}
+}
+
+func TestMountNestedRouters(t *testing.T) {
+	inner := newTestRouter()
+	inner.Use(DefaultHeaders(Headers{"Vary": "Accept", "X-Layer": "inner"}))
+	inner.Get("/leaf", func(req *Request, w *Writer) {})
+	middle := newTestRouter()
+	middle.Use(DefaultHeaders(Headers{"Vary": "Cookie", "X-Layer": "middle"}))
+	if err := middle.Mount("/inner", inner); err != nil {
+		t.Fatal(err)
+	}
+	addr := mountedRouter(t, DefaultHeaders(Headers{"Vary": "Origin", "X-Layer": "outer"}), middle)
+
+	resp := rawExchange(t, addr, "GET /child/inner/leaf HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := resp.Header.Get("Vary"); got != "Origin, Cookie, Accept" {
+		t.Errorf("Vary = %q, want the tokens of every router, outer first", got)
+	}
+	if got := resp.Header.Get("X-Layer"); got != "inner" {
+		t.Errorf("X-Layer = %q, want the innermost router", got)
+	}
+}
+
+func TestMountWarnsWithGivenPrefix(t *testing.T) {
+	stdout := os.Stdout
+	rd, wr, _ := os.Pipe()
+	os.Stdout = wr
+	err := newTestRouter().Mount("//", newTestRouter())
+	os.Stdout = stdout
+	wr.Close()
+	out, _ := io.ReadAll(rd)
+
+	if !errors.Is(err, ErrEmptyGroupPrefix) {
+		t.Errorf("Mount(\"//\") = %v, want ErrEmptyGroupPrefix", err)
+	}
+	if !strings.Contains(string(out), "[//]") {
+		t.Errorf("warning %q does not name the prefix given", out)
+	}
+}
@@ -0,0 +1,216 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// FileUpload represents a file being uploaded via multipart/form-data
+type FileUpload struct {
+	File     *os.File // The temporary file object
+	Filename string   // Original filename from the client
+	r        *Request // Pointer to the parent request (used for cleanup)
+}
+
+// newFileUpload creates a new instance of FileUpload
+func newFileUpload(file *os.File, filename string) *FileUpload {
+	return &FileUpload{
+		File:     file,
+		Filename: filename,
+	}
+}
+
+// Save writes the uploaded file to a specified directory
+func (fu *FileUpload) Save(dir string) (*os.File, error) {
+	defer fu.r.Cleanup() // Clean up temporary files when done
+
+	f, err := os.Create(dir) // Create the destination file
+	if err != nil {
+		return nil, err
+	}
+
+	// Copy the contents of the uploaded file to the destination
+	_, err = io.Copy(f, fu.File)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// PartReader iterates over the parts of a multipart/form-data body
+// Parts are streamed from the connection; nothing is buffered or spooled
+type PartReader struct {
+	mr *multipart.Reader
+}
+
+// Part is a single field or file of a multipart body
+// Its content must be read before calling NextPart again
+type Part struct {
+	FormName string // Name from the Content-Disposition header
+	FileName string // Client filename, empty for plain fields
+	p        *multipart.Part
+}
+
+// Header returns a header of the part (e.g., "Content-Type")
+func (p *Part) Header(key string) string {
+	return p.p.Header.Get(key)
+}
+
+// Read implements io.Reader over the part content
+func (p *Part) Read(b []byte) (int, error) {
+	return p.p.Read(b)
+}
+
+// IsFile reports whether the part carries a filename
+func (p *Part) IsFile() bool {
+	return p.FileName != ""
+}
+
+// MultipartReader returns an iterator over the parts of a multipart/form-data body
+// Use it instead of ParseMultipart to decide per part how to store the content
+func (r *Request) MultipartReader() (*PartReader, error) {
+	contentType := r.Headers.Get("Content-Type")
+	if !strings.Contains(contentType, "multipart/form-data") {
+		return nil, errors.New("invalid header")
+	}
+
+	// Parse boundary parameter from Content-Type header
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	boundary := params["boundary"]
+	if boundary == "" {
+		return nil, errors.New("boundary not found")
+	}
+
+	return &PartReader{mr: multipart.NewReader(r.Body, boundary)}, nil
+}
+
+// NextPart returns the next part, or io.EOF once all parts were read
+func (pr *PartReader) NextPart() (*Part, error) {
+	p, err := pr.mr.NextPart()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Part{
+		FormName: p.FormName(),
+		FileName: p.FileName(),
+		p:        p,
+	}, nil
+}
+
+// parseStruct binds a multipart part into the struct field tagged with its name
+func (r *Request) parseStruct(v interface{}, part *Part) error {
+	val := reflect.ValueOf(v)
+
+	// Ensure v is a pointer to a struct
+	if val.Kind() != reflect.Ptr {
+		return errors.New("is need ptr")
+	}
+
+	val = val.Elem()
+	if val.Kind() != reflect.Struct {
+		return errors.New("is need struct")
+	}
+
+	// Loop through the struct fields to match the form-data tag
+	for i := 0; i < val.NumField(); i++ {
+		f := val.Type().Field(i)
+		field := val.Field(i)
+
+		// Check if the field has a `gouter` tag matching the part name
+		tag, ok := f.Tag.Lookup("gouter")
+		if !ok || part.FormName != tag {
+			continue
+		}
+
+		// Files are streamed into a temporary file
+		if part.IsFile() && field.Type() == reflect.TypeOf((*FileUpload)(nil)) {
+			tempFile, err := os.CreateTemp("", "upload-*.tmp")
+			if err != nil {
+				return err
+			}
+			r.tempFiles = append(r.tempFiles, tempFile)
+
+			info := UploadPartInfo{
+				FormName:    part.FormName,
+				Filename:    part.FileName,
+				ContentType: part.Header("Content-Type"),
+			}
+			if err := r.spoolUpload(tempFile, part, info); err != nil {
+				return err
+			}
+
+			if _, err := tempFile.Seek(0, 0); err != nil {
+				return err
+			}
+
+			tmpFileU := newFileUpload(tempFile, part.FileName)
+			tmpFileU.r = r
+			field.Set(reflect.ValueOf(tmpFileU))
+			return nil
+		}
+
+		// Set string content directly if field is of string type
+		if field.Kind() == reflect.String {
+			content, err := r.readAllBudget(part)
+			if err != nil {
+				return err
+			}
+			field.SetString(string(content))
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// Cleanup removes all temporary uploaded files
+func (r *Request) Cleanup() {
+	for _, f := range r.tempFiles {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	r.tempFiles = nil
+}
+
+// ParseMultipart processes a multipart/form-data body and populates the provided struct
+// Fields are matched by their `gouter` tag; file parts are spooled to temporary
+// files removed by Cleanup, and checked by the router UploadInspector. A
+// rejected file returns ErrUploadRejected in a 422 StatusError after
+// removing every file spooled so far
+func (r *Request) ParseMultipart(v interface{}) (err error) {
+	defer func() {
+		if errors.Is(err, ErrUploadRejected) {
+			r.Cleanup()
+		}
+	}()
+
+	pr, err := r.MultipartReader()
+	if err != nil {
+		return err
+	}
+
+	for {
+		part, err := pr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := r.parseStruct(v, part); err != nil {
+			return err
+		}
+	}
+}
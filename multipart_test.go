@@ -0,0 +1,206 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// multipartFixture builds a body with a field and two files
+func multipartFixture(t *testing.T) (string, []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "holiday")
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", `form-data; name="photo"; filename="beach.jpg"`)
+	h.Set("Content-Type", "image/jpeg")
+	p, _ := mw.CreatePart(h)
+	p.Write(bytes.Repeat([]byte{0xff}, 10000))
+
+	p, _ = mw.CreateFormFile("notes", "notes.txt")
+	io.WriteString(p, "sunny\n")
+	mw.Close()
+	return mw.FormDataContentType(), buf.Bytes()
+}
+
+// postMultipart posts body to path and returns the response body
+func postMultipart(t *testing.T, addr, path, contentType string, body []byte) (int, string) {
+	t.Helper()
+	raw := "POST " + path + " HTTP/1.1\r\nHost: x\r\nContent-Type: " + contentType +
+		"\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + string(body)
+	resp := rawExchange(t, addr, raw, 1)[0]
+	return resp.StatusCode, bodyString(t, resp)
+}
+
+func TestMultipartReader(t *testing.T) {
+	r := newTestRouter()
+	r.Post("/parts", func(req *Request, w *Writer) {
+		pr, err := req.MultipartReader()
+		if err != nil {
+			w.WriteHeader(400)
+			w.Write([]byte(err.Error()))
+			return
+		}
+		for {
+			part, err := pr.NextPart()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				w.Write([]byte("error: " + err.Error()))
+				return
+			}
+			n, _ := io.Copy(io.Discard, part)
+			w.Write([]byte(part.FormName + "|" + part.FileName + "|" + part.Header("Content-Type") + "|" +
+				strconv.FormatBool(part.IsFile()) + "|" + strconv.FormatInt(n, 10) + "\n"))
+		}
+	})
+	r.Post("/small", func(req *Request, w *Writer) {
+		pr, err := req.MultipartReader()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for {
+			part, err := pr.NextPart()
+			if err != nil {
+				w.Write([]byte(err.Error()))
+				return
+			}
+			if _, err := io.Copy(io.Discard, part); err != nil {
+				w.Write([]byte(err.Error()))
+				return
+			}
+		}
+	}).SetMaxBodyBytes(1000)
+	addr := serveRouter(t, r)
+
+	contentType, body := multipartFixture(t)
+	_, got := postMultipart(t, addr, "/parts", contentType, body)
+	want := "title|||false|7\n" +
+		"photo|beach.jpg|image/jpeg|true|10000\n" +
+		"notes|notes.txt|application/octet-stream|true|6\n"
+	if got != want {
+		t.Errorf("parts:\n%s\nwant:\n%s", got, want)
+	}
+
+	if code, _ := postMultipart(t, addr, "/small", contentType, body); code != 413 {
+		t.Errorf("over-limit Content-Length = %d, want 413", code)
+	}
+	// Parts stream from the body, so its limit applies while reading
+	raw := "POST /small HTTP/1.1\r\nHost: x\r\nContent-Type: " + contentType +
+		"\r\nTransfer-Encoding: chunked\r\n\r\n" + strconv.FormatInt(int64(len(body)), 16) + "\r\n" + string(body) + "\r\n0\r\n\r\n"
+	if got := bodyString(t, rawExchange(t, addr, raw, 1)[0]); !strings.Contains(got, ErrBodyTooLarge.Error()) {
+		t.Errorf("over-limit chunked body = %q, want %v", got, ErrBodyTooLarge)
+	}
+
+	if code, got := postMultipart(t, addr, "/parts", "application/json", []byte("{}")); code != 400 || got == "" {
+		t.Errorf("non-multipart body = %d %q, want 400 with an error", code, got)
+	}
+	if code, _ := postMultipart(t, addr, "/parts", "multipart/form-data", body); code != 400 {
+		t.Errorf("missing boundary = %d, want 400", code)
+	}
+}
+
+func TestParseMultipartUsesPartReader(t *testing.T) {
+	type upload struct {
+		Title string      `gouter:"title"`
+		Photo *FileUpload `gouter:"photo"`
+		Notes *FileUpload `gouter:"notes"`
+	}
+
+	var tempNames []string
+	r := newTestRouter()
+	r.Post("/upload", func(req *Request, w *Writer) {
+		var u upload
+		if err := req.ParseMultipart(&u); err != nil {
+			t.Error(err)
+			return
+		}
+		defer req.Cleanup()
+		notes, _ := io.ReadAll(u.Notes.File)
+		photo, _ := io.ReadAll(u.Photo.File)
+		tempNames = append(tempNames, u.Photo.File.Name(), u.Notes.File.Name())
+		w.Write([]byte(u.Title + "|" + u.Photo.Filename + ":" + strconv.Itoa(len(photo)) + "|" + u.Notes.Filename + ":" + string(notes)))
+	})
+	addr := serveRouter(t, r)
+
+	contentType, body := multipartFixture(t)
+	if _, got := postMultipart(t, addr, "/upload", contentType, body); got != "holiday|beach.jpg:10000|notes.txt:sunny\n" {
+		t.Errorf("bound upload = %q", got)
+	}
+	for _, name := range tempNames {
+		if _, err := os.Stat(name); err == nil {
+			t.Errorf("temporary file %s left after Cleanup", name)
+		}
+	}
+}
+
+func TestParseMultipartSpoolsIncrementally(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	r := newTestRouter()
+	r.Post("/upload", func(req *Request, w *Writer) {
+		var u struct {
+			Video *FileUpload `gouter:"video"`
+		}
+		if err := req.ParseMultipart(&u); err != nil {
+			t.Error(err)
+			return
+		}
+		defer req.Cleanup()
+		n, _ := io.Copy(io.Discard, u.Video.File)
+		w.Write([]byte(strconv.FormatInt(n, 10)))
+	})
+	// Far less memory than the upload: file parts must not be buffered
+	addr := serveTest(t, &Server{Router: r, MaxMemoryPerRequest: 64 << 10})
+
+	const size = 4 << 20
+	head := "--b\r\nContent-Disposition: form-data; name=\"video\"; filename=\"clip.mp4\"\r\n\r\n"
+	tail := "\r\n--b--\r\n"
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	io.WriteString(c, "POST /upload HTTP/1.1\r\nHost: x\r\nContent-Type: multipart/form-data; boundary=b\r\nContent-Length: "+
+		strconv.Itoa(len(head)+size+len(tail))+"\r\n\r\n"+head)
+	c.Write(bytes.Repeat([]byte("v"), size/2))
+
+	// The first half reaches the temporary file before the rest is sent
+	spooled := func() int64 {
+		entries, _ := os.ReadDir(dir)
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil && strings.HasPrefix(e.Name(), "upload-") {
+				return info.Size()
+			}
+		}
+		return 0
+	}
+	eventually(t, "half the upload spooled while the body streams", func() bool { return spooled() >= size/4 })
+
+	c.Write(bytes.Repeat([]byte("v"), size/2))
+	io.WriteString(c, tail)
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodyString(t, resp); resp.StatusCode != 200 || got != strconv.Itoa(size) {
+		t.Errorf("upload = %d %q, want %d bytes", resp.StatusCode, got, size)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d temporary files left after Cleanup", len(entries))
+	}
+}
@@ -0,0 +1,201 @@
+package gouter
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WrapHTTPHandler adapts a net/http handler, e.g. net/http/pprof or a
+// Prometheus handler, into a Handler
+// The handler sees the request target unchanged, so mount it where it
+// expects to be or strip the prefix with http.StripPrefix. Its response
+// goes through the Writer, which supports http.Flusher and http.Hijacker
+func WrapHTTPHandler(h http.Handler) Handler {
+	return func(r *Request, w *Writer) {
+		req, err := newHTTPRequest(r)
+		if err != nil {
+			Error(w, err, http.StatusBadRequest)
+			return
+		}
+		hw := &httpResponseWriter{w: w, header: make(http.Header)}
+		h.ServeHTTP(hw, req)
+		// Headers set by a handler that wrote nothing are still sent
+		hw.WriteHeader(http.StatusOK)
+	}
+}
+
+// newHTTPRequest converts r into a server-side net/http request
+func newHTTPRequest(r *Request) (*http.Request, error) {
+	target := r.path
+	if r.rawQuery != "" {
+		target += "?" + r.rawQuery
+	}
+	var body io.Reader = r.Body
+	if body == nil {
+		body = http.NoBody
+	}
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range r.Headers {
+		for _, v := range r.Headers.Values(k) {
+			req.Header.Add(k, v)
+		}
+	}
+	req.Host = r.Headers.Get("Host")
+	req.Header.Del("Host")
+	req.RequestURI = target
+	req.RemoteAddr = r.RemoteAddrs
+	if major, minor, ok := http.ParseHTTPVersion(r.Version); ok {
+		req.Proto, req.ProtoMajor, req.ProtoMinor = r.Version, major, minor
+	}
+	req.ContentLength = -1
+	if cl := r.Headers.Get("Content-Length"); cl != "" {
+		req.ContentLength, _ = strconv.ParseInt(cl, 10, 64)
+	} else if r.Headers.Get("Transfer-Encoding") == "" {
+		req.ContentLength = 0
+	}
+	return req, nil
+}
+
+// httpResponseWriter implements http.ResponseWriter over a Writer
+type httpResponseWriter struct {
+	w           *Writer
+	header      http.Header
+	wroteHeader bool
+}
+
+func (hw *httpResponseWriter) Header() http.Header {
+	return hw.header
+}
+
+// WriteHeader copies the headers to the Writer along with the status;
+// calls after the first are ignored, as in net/http
+func (hw *httpResponseWriter) WriteHeader(code int) {
+	if hw.wroteHeader {
+		return
+	}
+	hw.wroteHeader = true
+	for k, values := range hw.header {
+		if _, list := listHeaders[strings.ToLower(k)]; list {
+			for _, v := range values {
+				hw.w.SetHeader(k, v)
+			}
+			continue
+		}
+		hw.w.SetHeader(k, strings.Join(values, ", "))
+	}
+	hw.w.WriteHeader(code)
+}
+
+func (hw *httpResponseWriter) Write(p []byte) (int, error) {
+	hw.WriteHeader(http.StatusOK)
+	return hw.w.Write(p)
+}
+
+// Flush implements http.Flusher
+func (hw *httpResponseWriter) Flush() {
+	hw.WriteHeader(http.StatusOK)
+	hw.w.Flush()
+}
+
+// Hijack implements http.Hijacker
+func (hw *httpResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, br, err := hw.w.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, bufio.NewReadWriter(br, bufio.NewWriter(c)), nil
+}
+
+// ToHTTPHandler adapts a Router into a net/http handler, to run it under
+// http.Server (e.g., for HTTP/2) or mount it in a net/http mux
+// Each request is streamed through the router over an in-memory
+// connection, as ServeConn does, with the client address preserved. The
+// router sees a plain connection, so TLS-dependent features do not apply
+func ToHTTPHandler(r *Router) http.Handler {
+	return httpHandler(r, connOptions{})
+}
+
+// httpHandler serves net/http requests through handleConn with opts
+func httpHandler(r *Router, opts connOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		client, server := net.Pipe()
+		defer client.Close()
+		spawn("nethttp", func() { handleConn(&addrConn{Conn: server, remote: remoteAddr(req.RemoteAddr)}, r, opts) })
+
+		// A canceled request unblocks the pipe
+		stop := context.AfterFunc(req.Context(), func() { client.Close() })
+		defer stop()
+
+		out := req.Clone(req.Context())
+		out.Close = true
+		out.RequestURI = ""
+		// Write in the background, the router may answer before the body ends
+		spawn("nethttp", func() { out.Write(client) })
+
+		resp, err := http.ReadResponse(bufio.NewReader(client), out)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		for k, values := range resp.Header {
+			if k == "Connection" {
+				continue
+			}
+			w.Header()[k] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		copyFlushing(w, resp.Body)
+	})
+}
+
+// copyFlushing copies body to w, flushing after each read so streamed
+// responses reach the client as they are produced
+func copyFlushing(w http.ResponseWriter, body io.Reader) {
+	flusher, _ := w.(http.Flusher)
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := body.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				panic(http.ErrAbortHandler)
+			}
+			return
+		}
+	}
+}
+
+// addrConn reports the address of the net/http client as its remote address
+type addrConn struct {
+	net.Conn
+	remote net.Addr
+}
+
+func (c *addrConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// remoteAddr is a net.Addr from an address string
+type remoteAddr string
+
+func (a remoteAddr) Network() string { return "tcp" }
+func (a remoteAddr) String() string  { return string(a) }
@@ -0,0 +1,168 @@
+package gouter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrapHTTPHandler(t *testing.T) {
+	r := newTestRouter()
+	r.Route("/std/*", WrapHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Add("Set-Cookie", "a=1")
+		w.Header().Add("Set-Cookie", "b=2")
+		w.WriteHeader(http.StatusAccepted)
+		// Headers changed after WriteHeader are not sent, as in net/http
+		w.Header().Set("X-Late", "1")
+		fmt.Fprintf(w, "%s %s %s q=%s host=%s accept=%v len=%d remote=%t body=%s",
+			req.Method, req.URL.Path, req.Proto, req.URL.Query().Get("q"), req.Host,
+			req.Header.Values("Accept"), req.ContentLength, strings.HasPrefix(req.RemoteAddr, "127.0.0.1:"), body)
+	})))
+	r.Route("/strip/*", WrapHTTPHandler(http.StripPrefix("/strip", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	}))))
+	r.Route("/headers-only", WrapHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("X-Only", "yes")
+	})))
+	r.Route("/debug/pprof/*", WrapHTTPHandler(http.HandlerFunc(pprof.Index)))
+	addr := serveRouter(t, r)
+
+	raw := "POST /std/items?q=go HTTP/1.1\r\nHost: api.example\r\nAccept: text/plain\r\nAccept: application/json\r\nContent-Length: 5\r\n\r\nhello"
+	resp := rawExchange(t, addr, raw, 1)[0]
+	want := "POST /std/items HTTP/1.1 q=go host=api.example accept=[text/plain, application/json] len=5 remote=true body=hello"
+	if got := bodyString(t, resp); resp.StatusCode != http.StatusAccepted || got != want {
+		t.Errorf("wrapped handler = %d %q, want %q", resp.StatusCode, got, want)
+	}
+	if got := resp.Header.Values("Set-Cookie"); len(got) != 2 || got[0] != "a=1" || got[1] != "b=2" {
+		t.Errorf("Set-Cookie = %v, want both cookies", got)
+	}
+	if resp.Header.Get("Content-Type") != "text/plain" || resp.Header.Get("X-Late") != "" {
+		t.Errorf("headers = %v", resp.Header)
+	}
+
+	resp = rawExchange(t, addr, "GET /strip/inner/path HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := bodyString(t, resp); got != "/inner/path" {
+		t.Errorf("StripPrefix path = %q", got)
+	}
+	resp = rawExchange(t, addr, "GET /headers-only HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != 200 || resp.Header.Get("X-Only") != "yes" {
+		t.Errorf("handler writing nothing = %d, X-Only %q", resp.StatusCode, resp.Header.Get("X-Only"))
+	}
+	resp = rawExchange(t, addr, "GET /debug/pprof/ HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := bodyString(t, resp); resp.StatusCode != 200 || !strings.Contains(got, "goroutine") {
+		t.Errorf("pprof index = %d", resp.StatusCode)
+	}
+}
+
+func TestWrapHTTPHandlerFlush(t *testing.T) {
+	release := make(chan struct{})
+	r := newTestRouter()
+	r.Get("/events", WrapHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		io.WriteString(w, "data: one\n\n")
+		w.(http.Flusher).Flush()
+		<-release
+		io.WriteString(w, "data: two\n\n")
+	})))
+	addr := serveRouter(t, r)
+
+	resp, err := http.Get("http://" + addr + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	if line, err := br.ReadString('\n'); err != nil || line != "data: one\n" {
+		t.Fatalf("first event = %q, %v; want it before the handler returns", line, err)
+	}
+	close(release)
+	if rest, _ := io.ReadAll(br); string(rest) != "\ndata: two\n\n" {
+		t.Errorf("rest of the stream = %q", rest)
+	}
+}
+
+func TestToHTTPHandler(t *testing.T) {
+	release := make(chan struct{})
+	r := newTestRouter()
+	r.Post("/users/:id", func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.SetHeader("X-Remote", req.RemoteIP())
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, "%s %s %s", req.Params.Get("id"), req.Query().Get("v"), body)
+	})
+	r.Get("/stream", func(req *Request, w *Writer) {
+		w.Write([]byte("first\n"))
+		w.Flush()
+		<-release
+		w.Write([]byte("second\n"))
+	})
+	srv := httptest.NewServer(ToHTTPHandler(r))
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/users/42?v=2", "text/plain", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodyString(t, resp); resp.StatusCode != http.StatusCreated || got != "42 2 payload" {
+		t.Errorf("POST through http.Server = %d %q", resp.StatusCode, got)
+	}
+	if got := resp.Header.Get("X-Remote"); got != "127.0.0.1" {
+		t.Errorf("RemoteIP = %q, want the net/http client address", got)
+	}
+	if resp.Header.Get("Connection") == "close" || resp.Close {
+		t.Error("the in-memory connection's close leaked to the client")
+	}
+
+	resp, err = http.Get(srv.URL + "/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown route = %d", resp.StatusCode)
+	}
+
+	// Streamed responses are flushed through to the net/http client
+	resp, err = http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	br := bufio.NewReader(resp.Body)
+	done := make(chan string, 1)
+	go func() {
+		line, _ := br.ReadString('\n')
+		done <- line
+	}()
+	select {
+	case line := <-done:
+		if line != "first\n" {
+			t.Errorf("first line = %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("flushed line not received while the handler runs")
+	}
+	close(release)
+	if rest, _ := io.ReadAll(br); string(rest) != "second\n" {
+		t.Errorf("rest of the stream = %q", rest)
+	}
+}
+
+func TestToHTTPHandlerHead(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/page", func(req *Request, w *Writer) { w.Write([]byte("content")) })
+	h := ToHTTPHandler(r)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("HEAD", "/page", nil))
+	if rec.Code != 200 || rec.Body.Len() != 0 || rec.Header().Get("Content-Length") != "7" {
+		t.Errorf("HEAD = %d, %d body bytes, Content-Length %q", rec.Code, rec.Body.Len(), rec.Header().Get("Content-Length"))
+	}
+}
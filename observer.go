@@ -0,0 +1,84 @@
+package gouter
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// RequestTiming describes a completed request
+type RequestTiming struct {
+	Method   string        // HTTP method
+	Path     string        // Requested path
+	Route    string        // Matched route pattern, empty when unmatched
+	Status   int           // Response status code
+	Start    time.Time     // When the request started being served
+	Duration time.Duration // Time until the response was written
+	BytesIn  int64         // Bytes read from the connection for this request
+	BytesOut int64         // Bytes written to the connection for this request
+}
+
+// ConnStats describes a closed connection
+type ConnStats struct {
+	RemoteAddr string        // Client address
+	Requests   int           // Requests served on the connection
+	Duration   time.Duration // Connection lifetime
+	BytesIn    int64         // Total bytes read
+	BytesOut   int64         // Total bytes written
+}
+
+// Observer receives metrics about served requests and connections
+// Byte counts are plaintext HTTP bytes: on TLS connections the counter sits
+// above the TLS layer, so handshake and record overhead are not included
+type Observer interface {
+	RequestDone(t RequestTiming)
+	ConnClosed(s ConnStats)
+}
+
+// Observe registers an observer notified after every request and connection
+func (r *Router) Observe(o Observer) {
+	r.observers = append(r.observers, o)
+}
+
+// countingConn counts the bytes read from and written to a connection
+type countingConn struct {
+	net.Conn
+	in  atomic.Int64
+	out atomic.Int64
+
+	aliasWarned bool // A HeaderAliases rename was logged for this connection
+}
+
+// newCountingConn wraps c unless it already counts its bytes
+func newCountingConn(c net.Conn) *countingConn {
+	if cc, ok := c.(*countingConn); ok {
+		return cc
+	}
+	return &countingConn{Conn: c}
+}
+
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.in.Add(int64(n))
+	return n, err
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.out.Add(int64(n))
+	return n, err
+}
+
+// notifyRequest reports a completed request to every observer
+func (r *Router) notifyRequest(t RequestTiming) {
+	for _, o := range r.observers {
+		o.RequestDone(t)
+	}
+}
+
+// notifyConn reports a closed connection to every observer
+func (r *Router) notifyConn(s ConnStats) {
+	for _, o := range r.observers {
+		o.ConnClosed(s)
+	}
+}
@@ -0,0 +1,253 @@
+package gouter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// openAPIVersion is the version of the documents built by OpenAPI
+const openAPIVersion = "3.0.3"
+
+// defaultDocsTitle names the docs page and the OpenAPI document when
+// Doc.Title is empty
+const defaultDocsTitle = "Gouter Documentation"
+
+// openAPIDocument is the root of an OpenAPI document
+type openAPIDocument struct {
+	OpenAPI string                                  `json:"openapi"`
+	Info    openAPIInfo                             `json:"info"`
+	Servers []openAPIServer                         `json:"servers,omitempty"`
+	Paths   map[string]map[string]*openAPIOperation `json:"paths"`
+}
+
+type openAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+// openAPIOperation is one method of a path
+type openAPIOperation struct {
+	OperationID string                     `json:"operationId,omitempty"`
+	Summary     string                     `json:"summary,omitempty"`
+	Description string                     `json:"description,omitempty"`
+	Tags        []string                   `json:"tags,omitempty"`
+	Deprecated  bool                       `json:"deprecated,omitempty"`
+	Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]openAPIResponse `json:"responses"`
+}
+
+type openAPIParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Required    bool           `json:"required"`
+	Description string         `json:"description,omitempty"`
+	Schema      map[string]any `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema  *Schema         `json:"schema"`
+	Example json.RawMessage `json:"example,omitempty"`
+}
+
+// openAPIContent describes a JSON body, nil when it has no schema
+func openAPIContent(b BodyDoc) map[string]openAPIMediaType {
+	if b.Schema == nil {
+		return nil
+	}
+	return map[string]openAPIMediaType{
+		"application/json": {Schema: b.Schema, Example: json.RawMessage(b.Example)},
+	}
+}
+
+// OpenAPI returns the OpenAPI 3.0 document of the routes shown in the docs
+// Path parameters, query parameters (see Paginated), body schemas (see
+// SetRequestBody and SetResponse), descriptions, version tags, deprecation
+// notes and the statuses of route examples carry over; a route registered
+// for several methods gives one operation per method.
+// Websocket routes are left out, having no OpenAPI form. The doc server
+// serves it at /openapi.json
+func (r *Router) OpenAPI() ([]byte, error) {
+	r.lazyInit()
+	doc := openAPIDocument{
+		OpenAPI: openAPIVersion,
+		Info:    openAPIInfo{Title: r.docsTitle(), Version: "1.0.0"},
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	if base := r.httpBase(); base != "" {
+		doc.Servers = []openAPIServer{{URL: base}}
+	}
+
+	for _, route := range r.visibleDocs() {
+		if route.Protocol == "websocket" {
+			continue
+		}
+		path := openAPIPath(route.Path)
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		// A route registered for several methods is one operation per method,
+		// with operation IDs kept unique
+		methods := route.methods
+		if len(methods) == 0 {
+			methods = []string{route.Method}
+		}
+		for _, m := range methods {
+			op := openAPIOperationOf(route)
+			if len(methods) > 1 && op.OperationID != "" {
+				op.OperationID += "_" + strings.ToLower(m)
+			}
+			doc.Paths[path][strings.ToLower(m)] = op
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// docsTitle returns the title of the docs page and OpenAPI document
+func (r *Router) docsTitle() string {
+	if r.docConfig != nil && r.docConfig.Title != "" {
+		return r.docConfig.Title
+	}
+	return defaultDocsTitle
+}
+
+// openAPIOperationOf describes one route
+func openAPIOperationOf(route *RouteInfo) *openAPIOperation {
+	op := &openAPIOperation{
+		Summary:   route.Description,
+		Responses: make(map[string]openAPIResponse),
+	}
+	// Unnamed handlers are identified by file:line, not worth an operation ID
+	if !strings.Contains(route.HandlerName, ".go:") {
+		op.OperationID = route.HandlerName
+	}
+	if route.Version != "" {
+		op.Tags = []string{route.Version}
+	}
+	if route.Deprecation != "" {
+		op.Deprecated = true
+		op.Description = route.Deprecation
+	}
+
+	// Host parameters have no place in the path template
+	path := openAPIPath(route.Path)
+	for _, p := range route.Parameters {
+		if !strings.Contains(path, "{"+p.Name+"}") {
+			continue
+		}
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:        p.Name,
+			In:          "path",
+			Required:    true,
+			Description: p.Description,
+			Schema:      openAPISchemaType(p.Type),
+		})
+	}
+	if _, name, ok := splitCatchAll(route.Path); ok && name == "" {
+		op.Parameters = append(op.Parameters, openAPIParameter{Name: "path", In: "path", Required: true, Schema: openAPISchemaType("")})
+	}
+	for _, p := range route.QueryParams {
+		op.Parameters = append(op.Parameters, openAPIParameter{
+			Name:        p.Name,
+			In:          "query",
+			Description: p.Description,
+			Schema:      openAPISchemaType(p.Type),
+		})
+	}
+
+	if route.RequestBody != nil && route.RequestBody.Schema != nil {
+		op.RequestBody = &openAPIRequestBody{Required: true, Content: openAPIContent(*route.RequestBody)}
+	}
+	for _, b := range route.Responses {
+		op.Responses[strconv.Itoa(b.Status)] = openAPIResponse{Description: b.StatusText(), Content: openAPIContent(b)}
+	}
+	for _, ex := range route.Examples {
+		if ex.Response.Status == 0 {
+			continue
+		}
+		code := strconv.Itoa(ex.Response.Status)
+		if _, ok := op.Responses[code]; !ok {
+			op.Responses[code] = openAPIResponse{Description: ex.Name}
+		}
+	}
+	if len(op.Responses) == 0 {
+		op.Responses["default"] = openAPIResponse{Description: "Response"}
+	}
+	return op
+}
+
+// openAPIPath converts a route pattern to an OpenAPI path template
+// ("/files/:name.:ext" becomes "/files/{name}.{ext}", "/static/*" becomes
+// "/static/{path}" and "/files/*name" becomes "/files/{name}")
+func openAPIPath(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	for i, seg := range segs {
+		switch {
+		case seg == "*":
+			segs[i] = "{path}"
+		case strings.HasPrefix(seg, "*"):
+			segs[i] = "{" + seg[1:] + "}"
+		case strings.Contains(seg, ":"):
+			var b strings.Builder
+			for _, t := range parseSegment(seg) {
+				if t.param != "" {
+					b.WriteString("{" + t.param + "}")
+				} else {
+					b.WriteString(t.lit)
+				}
+			}
+			segs[i] = b.String()
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+// openAPISchemaType maps a ParamInfo type to a JSON schema
+// Unknown and empty types are strings
+func openAPISchemaType(typ string) map[string]any {
+	switch strings.ToLower(typ) {
+	case "int", "int32", "int64", "integer", "uint":
+		return map[string]any{"type": "integer"}
+	case "float", "float32", "float64", "number":
+		return map[string]any{"type": "number"}
+	case "bool", "boolean":
+		return map[string]any{"type": "boolean"}
+	case "uuid", "date", "date-time", "email", "uri":
+		return map[string]any{"type": "string", "format": strings.ToLower(typ)}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// swaggerUIPage loads Swagger UI from unpkg.com and points it at the OpenAPI
+// document next to it, so it also works below a path prefix
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <title>Swagger UI</title>
+    <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+    <div id="swagger-ui"></div>
+    <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+    </script>
+</body>
+</html>
+`
@@ -0,0 +1,177 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// openAPISpec is the part of an OpenAPI document the tests read
+type openAPISpec struct {
+	OpenAPI string `json:"openapi"`
+	Info    struct {
+		Title string `json:"title"`
+	} `json:"info"`
+	Servers []struct {
+		URL string `json:"url"`
+	} `json:"servers"`
+	Paths map[string]map[string]struct {
+		OperationID string   `json:"operationId"`
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Tags        []string `json:"tags"`
+		Deprecated  bool     `json:"deprecated"`
+		Parameters  []struct {
+			Name     string         `json:"name"`
+			In       string         `json:"in"`
+			Required bool           `json:"required"`
+			Schema   map[string]any `json:"schema"`
+		} `json:"parameters"`
+		RequestBody *struct {
+			Content map[string]struct {
+				Schema  Schema          `json:"schema"`
+				Example json.RawMessage `json:"example"`
+			} `json:"content"`
+		} `json:"requestBody"`
+		Responses map[string]struct {
+			Description string         `json:"description"`
+			Content     map[string]any `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+}
+
+func parseOpenAPI(t *testing.T, doc []byte) openAPISpec {
+	t.Helper()
+	var spec openAPISpec
+	if err := json.Unmarshal(doc, &spec); err != nil {
+		t.Fatalf("%v in %s", err, doc)
+	}
+	return spec
+}
+
+type openAPIOrder struct {
+	Item  string `json:"item"`
+	Count int    `json:"count,omitempty"`
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	noop := func(req *Request, w *Writer) {}
+	r := newTestRouter()
+	r.Get("/orders", noop).SetName("listOrders").SetDescription("List orders").Paginated(PageDefaults{})
+	r.Post("/orders", noop).SetName("createOrder").
+		SetRequestBody(openAPIOrder{Item: "book", Count: 2}).
+		SetResponse(http.StatusCreated, openAPIOrder{}).
+		SetResponse(http.StatusBadRequest, nil)
+	r.Get("/orders/:id", noop).SetParam("id", "int", "Order number").SetVersion("v2").
+		Example("missing", ExampleRequest{}, ExampleResponse{Status: 404})
+	r.Route("/files/:name.:ext", noop, "GET", "PUT").SetName("file")
+	r.Get("/static/*", noop)
+	r.Get("/docs/*page", noop).Deprecate("Use /static")
+	r.Get("/internal", noop).Hide()
+	r.HostRoute(":tenant.shop.io", "/cart/:id", noop, "GET")
+
+	doc, err := r.OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := parseOpenAPI(t, doc)
+	if spec.OpenAPI != "3.0.3" || spec.Info.Title != "Gouter Documentation" || len(spec.Servers) != 0 {
+		t.Errorf("header = %s %q servers %v", spec.OpenAPI, spec.Info.Title, spec.Servers)
+	}
+
+	var paths []string
+	for p, ops := range spec.Paths {
+		for m := range ops {
+			paths = append(paths, m+" "+p)
+		}
+	}
+	want := []string{"get /orders", "post /orders", "get /orders/{id}", "get /files/{name}.{ext}", "put /files/{name}.{ext}", "get /static/{path}", "get /docs/{page}", "get /cart/{id}"}
+	slices.Sort(paths)
+	slices.Sort(want)
+	if !slices.Equal(paths, want) {
+		t.Errorf("operations = %v, want %v", paths, want)
+	}
+
+	list := spec.Paths["/orders"]["get"]
+	if list.OperationID != "listOrders" || list.Summary != "List orders" || len(list.Parameters) != 2 ||
+		list.Parameters[0].Name != "limit" || list.Parameters[0].In != "query" || list.Parameters[0].Schema["type"] != "integer" {
+		t.Errorf("GET /orders = %+v", list)
+	}
+	if _, ok := list.Responses["default"]; !ok || len(list.Responses) != 1 {
+		t.Errorf("undocumented responses = %v, want a default entry", list.Responses)
+	}
+
+	create := spec.Paths["/orders"]["post"]
+	body := create.RequestBody.Content["application/json"]
+	if body.Schema.Type != "object" || !reflect.DeepEqual(body.Schema.Required, []string{"item"}) || string(body.Example) == "" {
+		t.Errorf("request body = %+v", body)
+	}
+	if len(create.Responses) != 2 || create.Responses["201"].Content == nil || create.Responses["400"].Content != nil {
+		t.Errorf("responses = %+v, want 201 with a body and 400 without", create.Responses)
+	}
+
+	show := spec.Paths["/orders/{id}"]["get"]
+	if len(show.Parameters) != 1 || !show.Parameters[0].Required || show.Parameters[0].In != "path" || show.Parameters[0].Schema["type"] != "integer" {
+		t.Errorf("path parameter = %+v", show.Parameters)
+	}
+	if !reflect.DeepEqual(show.Tags, []string{"v2"}) || show.Responses["404"].Description != "missing" {
+		t.Errorf("GET /orders/{id} = %+v", show)
+	}
+	// File and line of registration make a poor operation ID
+	if show.OperationID != "" {
+		t.Errorf("operationId = %q for an unnamed handler", show.OperationID)
+	}
+
+	// One operation per method, with distinct IDs
+	get, put := spec.Paths["/files/{name}.{ext}"]["get"], spec.Paths["/files/{name}.{ext}"]["put"]
+	if get.OperationID != "file_get" || put.OperationID != "file_put" || len(put.Parameters) != 2 {
+		t.Errorf("multi-method route = %+v / %+v", get, put)
+	}
+
+	if p := spec.Paths["/static/{path}"]["get"].Parameters; len(p) != 1 || p[0].Name != "path" {
+		t.Errorf("wildcard parameters = %+v", p)
+	}
+	docs := spec.Paths["/docs/{page}"]["get"]
+	if !docs.Deprecated || docs.Description != "Use /static" || len(docs.Parameters) != 1 || docs.Parameters[0].Name != "page" {
+		t.Errorf("named wildcard route = %+v", docs)
+	}
+	// The host parameter has no place in the path template
+	if p := spec.Paths["/cart/{id}"]["get"].Parameters; len(p) != 1 || p[0].Name != "id" {
+		t.Errorf("host route parameters = %+v", p)
+	}
+}
+
+func TestOpenAPIServed(t *testing.T) {
+	for _, swagger := range []bool{false, true} {
+		r := docsRouter(0, func(d *Doc) {
+			d.Title = "Shop API"
+			d.SwaggerUI = swagger
+		})
+		if err := serveUntilReady(t, r); err != nil {
+			t.Fatal(err)
+		}
+		addr, _ := r.DocsAddr()
+
+		resp := rawExchange(t, addr, "GET /openapi.json HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		spec := parseOpenAPI(t, []byte(bodyString(t, resp)))
+		if resp.Header.Get("Content-Type") != "application/json" || spec.Info.Title != "Shop API" {
+			t.Errorf("openapi.json = %q, title %q", resp.Header.Get("Content-Type"), spec.Info.Title)
+		}
+		// The served application is the server of the document
+		if len(spec.Servers) != 1 || !strings.HasPrefix(spec.Servers[0].URL, "http://127.0.0.1:") {
+			t.Errorf("servers = %v", spec.Servers)
+		}
+
+		page := bodyString(t, rawExchange(t, addr, "GET /swagger HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0])
+		if got := strings.Contains(page, "SwaggerUIBundle"); got != swagger {
+			t.Errorf("SwaggerUI %v: /swagger serves the Swagger page = %v", swagger, got)
+		}
+		index := bodyString(t, rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0])
+		if !strings.Contains(index, "Shop API") || strings.Contains(index, `href="swagger"`) != swagger {
+			t.Errorf("SwaggerUI %v: docs page links = %v", swagger, strings.Contains(index, `href="swagger"`))
+		}
+	}
+}
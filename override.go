@@ -0,0 +1,79 @@
+package gouter
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// maxOverrideFormBytes bounds the form body read to find a _method field
+// Larger bodies are left untouched and only the header override applies
+const maxOverrideFormBytes = 64 << 10
+
+// overridableMethods are the methods a POST may be rewritten to
+var overridableMethods = map[string]bool{
+	"PUT":    true,
+	"PATCH":  true,
+	"DELETE": true,
+}
+
+// AllowMethodOverride lets POST requests reach PUT, PATCH and DELETE routes,
+// for HTML forms that can only send GET and POST
+// The method is taken from the X-HTTP-Method-Override header, or else from
+// the _method field of an application/x-www-form-urlencoded body. Other
+// methods and other targets are ignored. Disabled by default
+func (r *Router) AllowMethodOverride(allow bool) {
+	r.methodOverride = allow
+}
+
+// OriginalMethod returns the method sent by the client, which differs from
+// Method when AllowMethodOverride rewrote it
+func (r *Request) OriginalMethod() string {
+	if r.originalMethod != "" {
+		return r.originalMethod
+	}
+	return r.Method
+}
+
+// overrideMethod rewrites the method of a POST asking for an override
+func overrideMethod(req *Request) {
+	if req.Method != "POST" {
+		return
+	}
+
+	method := req.Headers.Get("X-HTTP-Method-Override")
+	if method == "" {
+		method = formMethod(req)
+	}
+	method = strings.ToUpper(strings.TrimSpace(method))
+	if !overridableMethods[method] {
+		return
+	}
+
+	req.originalMethod = req.Method
+	req.Method = method
+	TraceEvent(req, "method override POST -> "+method)
+}
+
+// formMethod returns the _method field of a urlencoded form body
+// The bytes read are put back in front of the body for the handler
+func formMethod(req *Request) string {
+	mediaType, _, _ := mime.ParseMediaType(req.Headers.Get("Content-Type"))
+	if mediaType != "application/x-www-form-urlencoded" || req.Body == nil {
+		return ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxOverrideFormBytes+1))
+	req.Body = io.MultiReader(bytes.NewReader(body), req.Body)
+	if err != nil || len(body) > maxOverrideFormBytes {
+		return ""
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get("_method")
+}
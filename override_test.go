@@ -0,0 +1,70 @@
+package gouter
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestMethodOverride(t *testing.T) {
+	echo := func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.Write([]byte(req.Method + " " + req.OriginalMethod() + " " + string(body)))
+	}
+	newRouter := func(allow bool) *Router {
+		r := newTestRouter()
+		r.AllowMethodOverride(allow)
+		r.Get("/items", echo)
+		r.Post("/items", echo)
+		r.Put("/items/:id", echo)
+		r.Delete("/items/:id", echo)
+		return r
+	}
+	addr := serveRouter(t, newRouter(true))
+	disabled := serveRouter(t, newRouter(false))
+
+	send := func(addr, method, path, headers, body string) (int, string) {
+		raw := method + " " + path + " HTTP/1.1\r\nHost: x\r\n" + headers + "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+		resp := rawExchange(t, addr, raw, 1)[0]
+		return resp.StatusCode, bodyString(t, resp)
+	}
+	form := "Content-Type: application/x-www-form-urlencoded\r\n"
+
+	cases := []struct {
+		name, method, path, headers, body string
+		code                              int
+		want                              string
+	}{
+		{"header", "POST", "/items/1", "X-HTTP-Method-Override: put\r\n", "{}", 200, "PUT POST {}"},
+		{"form field", "POST", "/items/1", form, "name=a&_method=DELETE", 200, "DELETE POST name=a&_method=DELETE"},
+		{"header wins over form", "POST", "/items/1", form + "X-HTTP-Method-Override: PUT\r\n", "_method=DELETE", 200, "PUT POST _method=DELETE"},
+		{"form needs its content type", "POST", "/items/1", "Content-Type: text/plain\r\n", "_method=PUT", 405, ""},
+		{"GET is never overridden", "GET", "/items", "X-HTTP-Method-Override: DELETE\r\n", "", 200, "GET GET "},
+		{"unsafe target ignored", "POST", "/items", "X-HTTP-Method-Override: CONNECT\r\n", "x", 200, "POST POST x"},
+		{"GET target ignored", "POST", "/items", "X-HTTP-Method-Override: GET\r\n", "x", 200, "POST POST x"},
+	}
+	for _, c := range cases {
+		code, body := send(addr, c.method, c.path, c.headers, c.body)
+		if code != c.code || c.want != "" && body != c.want {
+			t.Errorf("%s: %d %q, want %d %q", c.name, code, body, c.code, c.want)
+		}
+	}
+
+	if code, _ := send(disabled, "POST", "/items/1", "X-HTTP-Method-Override: PUT\r\n", ""); code != 405 {
+		t.Errorf("override while disabled = %d, want 405", code)
+	}
+
+	r := newRouter(true)
+	rec := &Writer{Headers: make(Headers)}
+	r.serveDocs(&Request{Headers: make(Headers)}, "/", rec)
+	page := string(rec.body)
+	if n := strings.Count(page, "X-HTTP-Method-Override: "); n != 2 {
+		t.Errorf("docs note the override on %d routes, want the PUT and DELETE ones", n)
+	}
+	rec = &Writer{Headers: make(Headers)}
+	newRouter(false).serveDocs(&Request{Headers: make(Headers)}, "/", rec)
+	if strings.Contains(string(rec.body), "X-HTTP-Method-Override") {
+		t.Error("docs mention the override while it is disabled")
+	}
+}
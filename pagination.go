@@ -0,0 +1,214 @@
+package gouter
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidCursor is returned by Paginate for cursors that do not decode
+// or whose signature does not match
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorMACSize is the length of the truncated HMAC-SHA256 of signed cursors
+const cursorMACSize = 16
+
+// PageDefaults configures Paginate
+// Zero values use: Limit 20, MinLimit 1, MaxLimit 100
+type PageDefaults struct {
+	Limit     int    // Page size when the request has no limit
+	MinLimit  int    // Smaller limits are raised to this
+	MaxLimit  int    // Larger limits are lowered to this
+	Cursor    bool   // Use opaque cursors instead of limit/offset
+	CursorKey []byte // Signs cursors with HMAC-SHA256 so clients cannot forge them
+}
+
+// Page is the position requested by a list endpoint
+type Page struct {
+	Limit  int    // Number of items to return
+	Offset int64  // Items to skip, in offset mode
+	After  string // Position decoded from the cursor, empty for the first page
+	Next   string // Position after the last item returned, set by the handler in cursor mode
+
+	cursor bool
+	key    []byte
+	path   string
+	query  url.Values
+}
+
+// withDefaults fills the zero fields of d
+func (d PageDefaults) withDefaults() PageDefaults {
+	if d.MinLimit <= 0 {
+		d.MinLimit = 1
+	}
+	if d.MaxLimit <= 0 {
+		d.MaxLimit = 100
+	}
+	if d.Limit <= 0 {
+		d.Limit = 20
+	}
+	return d
+}
+
+// Paginate reads the page requested by the limit and offset (or cursor)
+// query parameters
+// Limits outside [MinLimit, MaxLimit] are clamped. A non-numeric limit or
+// offset, a negative offset and an invalid cursor return a 400 StatusError
+func Paginate(r *Request, defaults PageDefaults) (Page, error) {
+	d := defaults.withDefaults()
+	query := r.Query()
+	p := Page{
+		Limit:  d.Limit,
+		cursor: d.Cursor,
+		key:    d.CursorKey,
+		path:   r.path,
+		query:  query,
+	}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil {
+			return Page{}, StatusError(http.StatusBadRequest, errors.New("invalid limit: "+raw))
+		}
+		p.Limit = limit
+	}
+	p.Limit = min(max(p.Limit, d.MinLimit), d.MaxLimit)
+
+	if d.Cursor {
+		if raw := query.Get("cursor"); raw != "" {
+			after, err := decodeCursor(raw, d.CursorKey)
+			if err != nil {
+				return Page{}, StatusError(http.StatusBadRequest, err)
+			}
+			p.After = after
+		}
+		return p, nil
+	}
+
+	if raw := query.Get("offset"); raw != "" {
+		offset, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || offset < 0 {
+			return Page{}, StatusError(http.StatusBadRequest, errors.New("invalid offset: "+raw))
+		}
+		p.Offset = offset
+	}
+	return p, nil
+}
+
+// Paginated documents the query parameters read by Paginate with defaults
+func (r *RouteInfo) Paginated(defaults PageDefaults) *RouteInfo {
+	d := defaults.withDefaults()
+	limit := "Page size, " + strconv.Itoa(d.MinLimit) + " to " + strconv.Itoa(d.MaxLimit) +
+		" (default " + strconv.Itoa(d.Limit) + ")"
+	r.QueryParams = append(r.QueryParams, ParamInfo{Name: "limit", Type: "integer", Description: limit})
+	if d.Cursor {
+		r.QueryParams = append(r.QueryParams, ParamInfo{Name: "cursor", Type: "string", Description: "next_cursor of the previous page"})
+	} else {
+		r.QueryParams = append(r.QueryParams, ParamInfo{Name: "offset", Type: "integer", Description: "Items to skip (default 0)"})
+	}
+	return r
+}
+
+// WritePage writes one page of a list as JSON
+// Args:
+//   - items: Items of the page
+//   - p: Page returned by Paginate; in cursor mode p.Next is the position
+//     of the following page, empty on the last one
+//   - total: Number of items in the whole list, ignored in cursor mode
+//
+// The envelope holds items, limit and either total and offset, or
+// next_cursor. Link headers (RFC 5988) point to the next and previous pages,
+// keeping the other query parameters
+func (w *Writer) WritePage(items any, p Page, total int64) error {
+	if items == nil {
+		items = []any{}
+	}
+
+	var links []string
+	if p.cursor {
+		var next string
+		if p.Next != "" {
+			next = encodeCursor(p.Next, p.key)
+			links = append(links, p.link("next", "cursor", next))
+		}
+		if len(links) > 0 {
+			w.SetHeader("Link", strings.Join(links, ", "))
+		}
+		return w.WriteJson(struct {
+			Items      any    `json:"items"`
+			NextCursor string `json:"next_cursor,omitempty"`
+			Limit      int    `json:"limit"`
+		}{items, next, p.Limit})
+	}
+
+	if next := p.Offset + int64(p.Limit); next < total {
+		links = append(links, p.link("next", "offset", strconv.FormatInt(next, 10)))
+	}
+	if p.Offset > 0 {
+		prev := max(p.Offset-int64(p.Limit), 0)
+		links = append(links, p.link("prev", "offset", strconv.FormatInt(prev, 10)))
+	}
+	if len(links) > 0 {
+		w.SetHeader("Link", strings.Join(links, ", "))
+	}
+	return w.WriteJson(struct {
+		Items  any   `json:"items"`
+		Total  int64 `json:"total"`
+		Limit  int   `json:"limit"`
+		Offset int64 `json:"offset"`
+	}{items, total, p.Limit, p.Offset})
+}
+
+// link builds a Link header entry for the current path with one query
+// parameter replaced and limit set to the page size
+func (p Page) link(rel, param, value string) string {
+	query := url.Values{}
+	for k, v := range p.query {
+		query[k] = v
+	}
+	query.Set("limit", strconv.Itoa(p.Limit))
+	query.Set(param, value)
+	return "<" + p.path + "?" + query.Encode() + `>; rel="` + rel + `"`
+}
+
+// encodeCursor wraps a position into an opaque cursor, signed when key is set
+func encodeCursor(position string, key []byte) string {
+	cursor := base64.RawURLEncoding.EncodeToString([]byte(position))
+	if key == nil {
+		return cursor
+	}
+	return cursor + "." + base64.RawURLEncoding.EncodeToString(cursorMAC(position, key))
+}
+
+// decodeCursor returns the position of a cursor, checking its signature
+// when key is set
+func decodeCursor(cursor string, key []byte) (string, error) {
+	payload, sig, signed := strings.Cut(cursor, ".")
+	if signed != (key != nil) {
+		return "", ErrInvalidCursor
+	}
+
+	position, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	if key != nil {
+		mac, err := base64.RawURLEncoding.DecodeString(sig)
+		if err != nil || !hmac.Equal(mac, cursorMAC(string(position), key)) {
+			return "", ErrInvalidCursor
+		}
+	}
+	return string(position), nil
+}
+
+// cursorMAC signs a cursor position
+func cursorMAC(position string, key []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(position))
+	return h.Sum(nil)[:cursorMACSize]
+}
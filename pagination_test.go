@@ -0,0 +1,157 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// paginationRouter lists the numbers 0 to 44 by offset at /items and by
+// signed cursor at /feed
+func paginationRouter(t *testing.T) (*Router, string) {
+	const total = 45
+	offsets := PageDefaults{Limit: 10, MaxLimit: 25}
+	cursors := PageDefaults{Limit: 10, Cursor: true, CursorKey: []byte("secret")}
+
+	r := newTestRouter()
+	r.Get("/items", func(req *Request, w *Writer) {
+		p, err := Paginate(req, offsets)
+		if err != nil {
+			Error(w, err, http.StatusBadRequest)
+			return
+		}
+		items := []int{}
+		for i := p.Offset; i < min(p.Offset+int64(p.Limit), total); i++ {
+			items = append(items, int(i))
+		}
+		w.WritePage(items, p, total)
+	}).Paginated(offsets)
+	r.Get("/feed", func(req *Request, w *Writer) {
+		p, err := Paginate(req, cursors)
+		if err != nil {
+			Error(w, err, http.StatusBadRequest)
+			return
+		}
+		start, _ := strconv.Atoi(p.After)
+		items := []int{}
+		for i := start; i < min(start+p.Limit, total); i++ {
+			items = append(items, i)
+		}
+		if end := start + p.Limit; end < total {
+			p.Next = strconv.Itoa(end)
+		}
+		w.WritePage(items, p, 0)
+	}).Paginated(cursors)
+	return r, serveRouter(t, r)
+}
+
+// pageEnvelope is the JSON written by WritePage in both modes
+type pageEnvelope struct {
+	Items      []int  `json:"items"`
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int64  `json:"offset"`
+	NextCursor string `json:"next_cursor"`
+}
+
+func getPage(t *testing.T, addr, target string) (*http.Response, pageEnvelope) {
+	t.Helper()
+	resp := rawExchange(t, addr, "GET "+target+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	var page pageEnvelope
+	if resp.StatusCode == 200 {
+		if err := json.Unmarshal([]byte(bodyString(t, resp)), &page); err != nil {
+			t.Fatalf("%s: %v", target, err)
+		}
+	}
+	return resp, page
+}
+
+func TestPaginateOffsets(t *testing.T) {
+	_, addr := paginationRouter(t)
+
+	cases := []struct {
+		target      string
+		limit       int
+		first, size int
+		link        string
+	}{
+		{"/items", 10, 0, 10, `</items?limit=10&offset=10>; rel="next"`},
+		{"/items?limit=10&offset=20&sort=name", 10, 20, 10,
+			`</items?limit=10&offset=30&sort=name>; rel="next", </items?limit=10&offset=10&sort=name>; rel="prev"`},
+		{"/items?offset=40", 10, 40, 5, `</items?limit=10&offset=30>; rel="prev"`},
+		{"/items?offset=5", 10, 5, 10, `</items?limit=10&offset=15>; rel="next", </items?limit=10&offset=0>; rel="prev"`},
+		{"/items?limit=1000", 25, 0, 25, `</items?limit=25&offset=25>; rel="next"`},
+		{"/items?limit=0", 1, 0, 1, `</items?limit=1&offset=1>; rel="next"`},
+		{"/items?limit=-3&offset=44", 1, 44, 1, `</items?limit=1&offset=43>; rel="prev"`},
+	}
+	for _, c := range cases {
+		resp, page := getPage(t, addr, c.target)
+		if resp.StatusCode != 200 || page.Limit != c.limit || page.Total != 45 || len(page.Items) != c.size || page.Items[0] != c.first {
+			t.Errorf("%s = %d %+v, want %d items from %d with limit %d", c.target, resp.StatusCode, page, c.size, c.first, c.limit)
+		}
+		if got := resp.Header.Get("Link"); got != c.link {
+			t.Errorf("%s: Link = %q, want %q", c.target, got, c.link)
+		}
+	}
+
+	for _, target := range []string{"/items?limit=ten", "/items?offset=-1", "/items?offset=x"} {
+		if resp, _ := getPage(t, addr, target); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", target, resp.StatusCode)
+		}
+	}
+}
+
+func TestPaginateCursors(t *testing.T) {
+	r, addr := paginationRouter(t)
+
+	var seen []int
+	target := "/feed?limit=20"
+	for pages := 0; target != ""; pages++ {
+		if pages > 3 {
+			t.Fatal("cursor never ran out")
+		}
+		resp, page := getPage(t, addr, target)
+		if resp.StatusCode != 200 {
+			t.Fatalf("%s = %d", target, resp.StatusCode)
+		}
+		seen = append(seen, page.Items...)
+		target = ""
+		if page.NextCursor != "" {
+			link := resp.Header.Get("Link")
+			target = "/feed?cursor=" + page.NextCursor + "&limit=20"
+			if link != "<"+target+`>; rel="next"` {
+				t.Errorf("Link = %q, want the next cursor", link)
+			}
+		}
+	}
+	if len(seen) != 45 || seen[44] != 44 {
+		t.Errorf("followed cursors through %d items, want all 45", len(seen))
+	}
+
+	_, first := getPage(t, addr, "/feed")
+	payload, sig, _ := strings.Cut(first.NextCursor, ".")
+	forged := encodeCursor("40", nil)
+	for name, cursor := range map[string]string{
+		"tampered payload":  forged + "." + sig,
+		"missing signature": payload,
+		"unsigned forgery":  forged,
+		"bad signature":     payload + ".AAAA",
+		"not base64":        "!!!." + sig,
+	} {
+		if resp, _ := getPage(t, addr, "/feed?cursor="+cursor); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s cursor = %d, want 400", name, resp.StatusCode)
+		}
+	}
+
+	params := map[string]string{}
+	for _, doc := range r.visibleDocs() {
+		for _, p := range doc.QueryParams {
+			params[doc.Path+" "+p.Name] = p.Description
+		}
+	}
+	if params["/items limit"] != "Page size, 1 to 25 (default 10)" || params["/items offset"] == "" || params["/feed cursor"] == "" {
+		t.Errorf("documented query params = %v", params)
+	}
+}
@@ -0,0 +1,85 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// Limits of a single FuzzParserConn input
+const (
+	fuzzWatchdog   = 5 * time.Second
+	fuzzAllocBytes = 32 << 20
+	fuzzMaxBody    = 1 << 20
+)
+
+// fuzzConn is a connection reading a fixed input, then EOF
+type fuzzConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *fuzzConn) Read(p []byte) (int, error)       { return c.r.Read(p) }
+func (c *fuzzConn) Write(p []byte) (int, error)      { return len(p), nil }
+func (c *fuzzConn) Close() error                     { return nil }
+func (c *fuzzConn) RemoteAddr() net.Addr             { return remoteAddr("fuzz:1") }
+func (c *fuzzConn) SetDeadline(time.Time) error      { return nil }
+func (c *fuzzConn) SetReadDeadline(time.Time) error  { return nil }
+func (c *fuzzConn) SetWriteDeadline(time.Time) error { return nil }
+
+// FuzzParserConn feeds raw connections to the request parser, reading every
+// request and its body as serveRequest does until the parser gives up
+// Each input must end with a 4xx/5xx rejection or a connection error, and
+// must neither panic, run past the watchdog, nor allocate past the cap
+// The seed corpus is in testdata/fuzz/FuzzParserConn
+func FuzzParserConn(f *testing.F) {
+	f.Add([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n"), false)
+	f.Fuzz(func(t *testing.T, data []byte, strict bool) {
+		cfg := defaultParserConfig()
+		cfg.MaxBodyBytes = fuzzMaxBody
+
+		watchdog := time.AfterFunc(fuzzWatchdog, func() {
+			panic("parserConn still running after " + fuzzWatchdog.String())
+		})
+		defer watchdog.Stop()
+
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		err := fuzzParse(data, cfg, strict)
+		runtime.ReadMemStats(&after)
+
+		if n := after.TotalAlloc - before.TotalAlloc; n > fuzzAllocBytes {
+			t.Errorf("parsing %d bytes allocated %d bytes", len(data), n)
+		}
+		var he *httpError
+		if errors.As(err, &he) && (he.code < 400 || he.code > 599) {
+			t.Errorf("rejected with status %d: %v", he.code, err)
+		}
+	})
+}
+
+// fuzzParse parses requests from data until an error, which it returns
+func fuzzParse(data []byte, cfg *ParserConfig, strict bool) error {
+	c := &fuzzConn{r: bytes.NewReader(data)}
+	br := bufio.NewReaderSize(c, 512)
+	for {
+		req, err := parserConn(c, br, cfg, defaultMaxURILength, strict)
+		if err != nil {
+			return err
+		}
+		if err := limitBody(req, cfg.MaxBodyBytes); err != nil {
+			releaseRequest(req)
+			return err
+		}
+		_, err = io.Copy(io.Discard, req.Body)
+		releaseRequest(req)
+		if err != nil {
+			return err
+		}
+	}
+}
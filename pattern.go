@@ -0,0 +1,128 @@
+package gouter
+
+import "strings"
+
+// segmentToken is a literal or a parameter within a path segment
+type segmentToken struct {
+	param string // Parameter name, empty for literals
+	lit   string // Literal text, used when param is empty
+}
+
+// parseSegment splits a pattern segment into literals and parameters
+//
+// Rules:
+//   - A segment starting with ':' and holding no other ':' is one parameter
+//     named by the rest of the segment (e.g., ":id", ":user-id")
+//   - Otherwise each ':' starts a parameter whose name runs over letters,
+//     digits and '_', and the text between parameters is literal
+//     (e.g., ":name.:ext", ":lat,:lng", "v:major")
+func parseSegment(seg string) []segmentToken {
+	if strings.HasPrefix(seg, ":") && strings.Count(seg, ":") == 1 {
+		return []segmentToken{{param: seg[1:]}}
+	}
+
+	var tokens []segmentToken
+	for seg != "" {
+		i := strings.IndexByte(seg, ':')
+		if i == -1 {
+			tokens = append(tokens, segmentToken{lit: seg})
+			break
+		}
+		if i > 0 {
+			tokens = append(tokens, segmentToken{lit: seg[:i]})
+			seg = seg[i:]
+		}
+
+		end := 1
+		for end < len(seg) && isParamNameByte(seg[end]) {
+			end++
+		}
+		tokens = append(tokens, segmentToken{param: seg[1:end]})
+		seg = seg[end:]
+	}
+	return tokens
+}
+
+// isParamName reports whether name is made of parameter name bytes only
+func isParamName(name string) bool {
+	for i := 0; i < len(name); i++ {
+		if !isParamNameByte(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// isParamNameByte reports whether b may appear in an embedded parameter name
+func isParamNameByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// segmentParams returns the parameter names of a pattern segment
+func segmentParams(seg string) []string {
+	if !strings.Contains(seg, ":") {
+		return nil
+	}
+	var names []string
+	for _, t := range parseSegment(seg) {
+		if t.param != "" {
+			names = append(names, t.param)
+		}
+	}
+	return names
+}
+
+// ambiguousSegment reports whether a segment has two parameters with no
+// literal between them, which can never be split deterministically
+func ambiguousSegment(tokens []segmentToken) bool {
+	for i := 1; i < len(tokens); i++ {
+		if tokens[i].param != "" && tokens[i-1].param != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegment binds the parameters of a parsed segment against value
+//
+// Every parameter must capture at least one byte and literals must appear
+// verbatim. When a parameter is followed by a literal, the parameter is
+// greedy: the split happens at the last occurrence of the literal that lets
+// the rest of the segment match, so the right-hand parameter gets the
+// shortest possible value ("report.tar.gz" against ":name.:ext" binds
+// name="report.tar" and ext="gz")
+func matchSegment(tokens []segmentToken, value string, params map[string]string) bool {
+	if len(tokens) == 0 {
+		return value == ""
+	}
+
+	t := tokens[0]
+	if t.param == "" {
+		if !strings.HasPrefix(value, t.lit) {
+			return false
+		}
+		return matchSegment(tokens[1:], value[len(t.lit):], params)
+	}
+
+	// A trailing parameter takes the rest of the segment
+	if len(tokens) == 1 {
+		if value == "" {
+			return false
+		}
+		params[t.param] = value
+		return true
+	}
+
+	next := tokens[1]
+	if next.param != "" {
+		return false
+	}
+
+	for i := strings.LastIndex(value, next.lit); i > 0; i = strings.LastIndex(value[:i], next.lit) {
+		if matchSegment(tokens[1:], value[i:], params) {
+			params[t.param] = value[:i]
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package gouter
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchSegment(t *testing.T) {
+	tests := []struct {
+		pattern, value string
+		want           map[string]string // nil when the segment must not match
+	}{
+		{":name.:ext", "report.pdf", map[string]string{"name": "report", "ext": "pdf"}},
+		{":name.:ext", "report.tar.gz", map[string]string{"name": "report.tar", "ext": "gz"}},
+		{":name.:ext", ".bashrc", nil},
+		{":name.:ext", "report.", nil},
+		{":name.:ext", "report", nil},
+		{":lat,:lng", "-23.55,-46.63", map[string]string{"lat": "-23.55", "lng": "-46.63"}},
+		{":lat,:lng", "1,2,3", map[string]string{"lat": "1,2", "lng": "3"}},
+		{"v:major.:minor", "v1.2", map[string]string{"major": "1", "minor": "2"}},
+		{"v:major.:minor", "x1.2", nil},
+		{":a-:b.json", "x-y-z.json", map[string]string{"a": "x-y", "b": "z"}},
+		{":a-:b.json", "x-y.xml", nil},
+		{"img_:id", "img_42", map[string]string{"id": "42"}},
+	}
+	for _, tt := range tests {
+		params := map[string]string{}
+		ok := matchSegment(parseSegment(tt.pattern), tt.value, params)
+		if tt.want == nil {
+			if ok {
+				t.Errorf("%s matched %q with %v", tt.pattern, tt.value, params)
+			}
+			continue
+		}
+		if !ok || !reflect.DeepEqual(params, tt.want) {
+			t.Errorf("%s against %q = %v %v, want %v", tt.pattern, tt.value, ok, params, tt.want)
+		}
+	}
+}
+
+func TestMultiParamSegmentRoutes(t *testing.T) {
+	r := newTestRouter()
+	files := r.Get("/files/:name.:ext", func(req *Request, w *Writer) {
+		w.Write([]byte(req.Params.Get("name") + "|" + req.Params.Get("ext")))
+	})
+	r.Get("/geo/:lat,:lng", func(req *Request, w *Writer) {
+		w.Write([]byte(req.Params.Get("lat") + "|" + req.Params.Get("lng")))
+	})
+	if r.Get("/bad/:a:b", func(req *Request, w *Writer) {}) != nil {
+		t.Error("adjacent parameters were registered")
+	}
+	addr := serveRouter(t, r)
+
+	var names []string
+	for _, p := range files.Parameters {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"name", "ext"}) {
+		t.Errorf("docs parameters = %v, want [name ext]", names)
+	}
+
+	for path, want := range map[string]string{
+		"/files/report.pdf":    "report|pdf",
+		"/files/my.report.pdf": "my.report|pdf",
+		"/geo/-23.5,-46.6":     "-23.5|-46.6",
+	} {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		if got := bodyString(t, resp); resp.StatusCode != 200 || got != want {
+			t.Errorf("GET %s = %d %q, want %q", path, resp.StatusCode, got, want)
+		}
+	}
+	for _, path := range []string{"/files/report", "/geo/-23.5"} {
+		if resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]; resp.StatusCode != 404 {
+			t.Errorf("GET %s without the separator = %d, want 404", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestParseSegment(t *testing.T) {
+	tests := map[string][]segmentToken{
+		":id":        {{param: "id"}},
+		"img_:id":    {{lit: "img_"}, {param: "id"}},
+		":name.:ext": {{param: "name"}, {lit: "."}, {param: "ext"}},
+		"v:major.x":  {{lit: "v"}, {param: "major"}, {lit: ".x"}},
+		"a:b-c:d":    {{lit: "a"}, {param: "b"}, {lit: "-c"}, {param: "d"}},
+	}
+	for seg, want := range tests {
+		if got := parseSegment(seg); !reflect.DeepEqual(got, want) {
+			t.Errorf("parseSegment(%q) = %+v, want %+v", seg, got, want)
+		}
+	}
+}
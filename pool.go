@@ -0,0 +1,125 @@
+package gouter
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// maxPooledBodyBytes caps the response buffers kept in bodyPool; larger
+// buffers are dropped so one big response does not pin its memory
+const maxPooledBodyBytes = 64 << 10
+
+// requestPool recycles the Requests of serveRequest, and bodyPool the
+// response buffers of its Writers
+var (
+	requestPool = sync.Pool{New: func() any { return newRequest() }}
+	bodyPool    = sync.Pool{New: func() any { return new([]byte) }}
+)
+
+// poolState marks a pooled object released, for the gouterdebug build
+type poolState struct {
+	released atomic.Bool
+}
+
+// acquireRequest returns an empty Request from the pool
+func acquireRequest() *Request {
+	return requestPool.Get().(*Request)
+}
+
+// releaseRequest resets a Request and returns it to the pool
+// With the gouterdebug build tag the object is poisoned instead of reused,
+// and later use is logged with the offending caller
+func releaseRequest(r *Request) {
+	if poolDebug {
+		r.pool.released.Store(true)
+		return
+	}
+
+	headers, params, values := r.Headers, r.Params, r.values
+	clear(headers)
+	clear(params)
+	clear(values)
+	*r = Request{Headers: headers, Params: params, values: values}
+	requestPool.Put(r)
+}
+
+// acquireWriter returns an empty Writer for c with a pooled body buffer
+// Writers themselves are not recycled: a goroutine that outlived its
+// handler may still hold one, and must keep getting ErrResponseDone instead
+// of writing into a later response
+func acquireWriter(c net.Conn) *Writer {
+	w := newWriter(c)
+	w.body = (*bodyPool.Get().(*[]byte))[:0]
+	return w
+}
+
+// releaseWriter takes the body buffer back from a completed Writer
+// With the gouterdebug build tag the buffer is left in place and late
+// calls, which fail with ErrResponseDone either way, are logged
+func releaseWriter(w *Writer) {
+	if poolDebug {
+		w.pool.released.Store(true)
+		return
+	}
+
+	w.mu.Lock()
+	body := w.body[:0]
+	w.body, w.done = nil, true
+	w.mu.Unlock()
+	if cap(body) == 0 || cap(body) > maxPooledBodyBytes {
+		return
+	}
+	bodyPool.Put(&body)
+}
+
+// checkReleased logs a use of a released pooled object
+// Only active with the gouterdebug build tag; skip counts the frames between
+// the user code and this call
+func (p *poolState) checkReleased(kind string, skip int) {
+	if poolDebug && p.released.Load() {
+		log.WarnE(skip+3, kind+" used after its response was sent (retain requests with Request.Clone)")
+	}
+}
+
+// Clone returns a copy of the request that stays valid after the handler
+// returns
+// Requests are pooled and reused for later requests once the response is
+// sent, so goroutines outliving the handler must work on a clone. The clone
+// has no body; uploaded files spooled by ParseMultipart stay with it
+func (r *Request) Clone() *Request {
+	r.pool.checkReleased("Request", 1)
+
+	c := &Request{
+		Method:         r.Method,
+		path:           r.path,
+		rawQuery:       r.rawQuery,
+		basePath:       r.basePath,
+		Headers:        make(Headers, len(r.Headers)),
+		Version:        r.Version,
+		Body:           http.NoBody,
+		Params:         make(Params, len(r.Params)),
+		RemoteAddrs:    r.RemoteAddrs,
+		tempFiles:      append([]*os.File(nil), r.tempFiles...),
+		route:          r.route,
+		headerBytes:    r.headerBytes,
+		originalMethod: r.originalMethod,
+	}
+	for k, v := range r.Headers {
+		c.Headers[k] = v
+	}
+	for k, v := range r.Params {
+		c.Params[k] = v
+	}
+	if len(r.values) > 0 {
+		c.values = make(map[string]any, len(r.values))
+		for k, v := range r.values {
+			c.values[k] = v
+		}
+	}
+	return c
+}
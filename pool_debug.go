@@ -0,0 +1,6 @@
+//go:build gouterdebug
+
+package gouter
+
+// poolDebug poisons released Requests and Writers instead of reusing them
+const poolDebug = true
@@ -0,0 +1,7 @@
+//go:build !gouterdebug
+
+package gouter
+
+// poolDebug poisons released Requests and Writers instead of reusing them
+// Enabled with the gouterdebug build tag
+const poolDebug = false
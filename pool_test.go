@@ -0,0 +1,149 @@
+package gouter
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// keepAliveConn is a client connection sending requests one at a time
+type keepAliveConn struct {
+	t  testing.TB
+	c  net.Conn
+	br *bufio.Reader
+}
+
+func dialKeepAlive(t testing.TB, addr string) *keepAliveConn {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return &keepAliveConn{t: t, c: c, br: bufio.NewReader(c)}
+}
+
+// get sends a GET for path and returns the response with its body read
+func (k *keepAliveConn) get(path string) (*http.Response, string) {
+	k.t.Helper()
+	k.c.SetDeadline(time.Now().Add(3 * time.Second))
+	if _, err := io.WriteString(k.c, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n"); err != nil {
+		k.t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(k.br, nil)
+	if err != nil {
+		k.t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		k.t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestLateWriteDoesNotReachNextResponse(t *testing.T) {
+	release := make(chan struct{})
+	lateErr := make(chan error, 1)
+
+	r := newTestRouter()
+	r.Get("/first", func(req *Request, w *Writer) {
+		go func() {
+			<-release
+			_, err := w.Write([]byte("late"))
+			w.SetHeader("X-Late", "1")
+			lateErr <- err
+		}()
+		w.Write([]byte("first"))
+	})
+	r.Get("/second", func(req *Request, w *Writer) {
+		w.Write([]byte("second"))
+	})
+	k := dialKeepAlive(t, serveRouter(t, r))
+
+	if _, body := k.get("/first"); body != "first" {
+		t.Fatalf("first body = %q", body)
+	}
+	close(release)
+	if err := <-lateErr; !errors.Is(err, ErrResponseDone) {
+		t.Errorf("late Write error = %v, want ErrResponseDone", err)
+	}
+
+	resp, body := k.get("/second")
+	if body != "second" || resp.Header.Get("X-Late") != "" {
+		t.Errorf("second response = %q with X-Late %q, want %q alone", body, resp.Header.Get("X-Late"), "second")
+	}
+}
+
+func TestLateWriteDuringNextRequest(t *testing.T) {
+	// The late goroutine races the next request on the same connection;
+	// run with -race to check the released Writer is not shared
+	r := newTestRouter()
+	r.Get("/spawn", func(req *Request, w *Writer) {
+		go func() {
+			for i := 0; i < 100; i++ {
+				w.Write([]byte("late"))
+			}
+		}()
+	})
+	r.Get("/echo", func(req *Request, w *Writer) {
+		w.Write([]byte("echo"))
+	})
+	k := dialKeepAlive(t, serveRouter(t, r))
+
+	for i := 0; i < 20; i++ {
+		k.get("/spawn")
+		if _, body := k.get("/echo"); body != "echo" {
+			t.Fatalf("request %d body = %q, want %q", i, body, "echo")
+		}
+	}
+}
+
+func TestKeepAliveStartsClean(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/set/:id", func(req *Request, w *Writer) {
+		if v := req.Value("seen"); v != nil {
+			t.Errorf("request value leaked from a previous request: %v", v)
+		}
+		req.SetValue("seen", req.Params["id"])
+		w.SetHeader("X-Id", req.Params["id"])
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(req.Params["id"]))
+	})
+	r.Get("/plain", func(req *Request, w *Writer) {
+		if len(req.Params) != 0 {
+			t.Errorf("params leaked from a previous request: %v", req.Params)
+		}
+		w.Write([]byte("plain"))
+	})
+	k := dialKeepAlive(t, serveRouter(t, r))
+
+	for _, id := range []string{"1", "2"} {
+		resp, body := k.get("/set/" + id)
+		if resp.StatusCode != http.StatusCreated || body != id || resp.Header.Get("X-Id") != id {
+			t.Errorf("/set/%s = %d %q X-Id %q", id, resp.StatusCode, body, resp.Header.Get("X-Id"))
+		}
+		resp, body = k.get("/plain")
+		if resp.StatusCode != http.StatusOK || body != "plain" || resp.Header.Get("X-Id") != "" {
+			t.Errorf("/plain = %d %q X-Id %q", resp.StatusCode, body, resp.Header.Get("X-Id"))
+		}
+	}
+}
+
+func BenchmarkKeepAliveRequest(b *testing.B) {
+	r := newTestRouter()
+	r.Get("/users/:id", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Type", "text/plain")
+		w.Write([]byte(req.Params["id"]))
+	})
+	k := dialKeepAlive(b, serveRouter(b, r))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k.get("/users/42")
+	}
+}
@@ -0,0 +1,72 @@
+//go:build linux
+
+package gouter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// tcpListenState is the /proc/net/tcp state of a listening socket
+const tcpListenState = "0A"
+
+// portHolder finds the process listening on a TCP port through /proc
+// Best effort: returns 0 when the socket or its process is not visible,
+// e.g. when it belongs to another user
+func portHolder(port int) (int, string) {
+	if port <= 0 {
+		return 0, ""
+	}
+
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/tcp", "/proc/net/tcp6"} {
+		listeningInodes(table, port, inodes)
+	}
+	if len(inodes) == 0 {
+		return 0, ""
+	}
+
+	procs, _ := filepath.Glob("/proc/[0-9]*/fd/*")
+	for _, fd := range procs {
+		link, err := os.Readlink(fd)
+		if err != nil || !strings.HasPrefix(link, "socket:[") {
+			continue
+		}
+		if !inodes[strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]")] {
+			continue
+		}
+
+		pidDir := filepath.Dir(filepath.Dir(fd))
+		pid, _ := strconv.Atoi(filepath.Base(pidDir))
+		comm, _ := os.ReadFile(filepath.Join(pidDir, "comm"))
+		return pid, strings.TrimSpace(string(comm))
+	}
+	return 0, ""
+}
+
+// listeningInodes adds the inodes of sockets listening on port from a
+// /proc/net/tcp style table
+func listeningInodes(table string, port int, inodes map[string]bool) {
+	f, err := os.Open(table)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	suffix := fmt.Sprintf(":%04X", port)
+	scanner := bufio.NewScanner(f)
+	scanner.Scan() // Header line
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListenState {
+			continue
+		}
+		if strings.HasSuffix(fields[1], suffix) {
+			inodes[fields[9]] = true
+		}
+	}
+}
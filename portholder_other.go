@@ -0,0 +1,8 @@
+//go:build !linux
+
+package gouter
+
+// portHolder is not implemented on this platform
+func portHolder(port int) (int, string) {
+	return 0, ""
+}
@@ -0,0 +1,434 @@
+package gouter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Upstream is a backend of a ProxyPool
+type Upstream struct {
+	URL    string // Base URL requests are forwarded to (e.g., "http://10.0.0.2:8080")
+	Weight int    // Relative share of the traffic, 1 when zero
+}
+
+// PoolPolicy selects the upstream serving each request
+type PoolPolicy int
+
+const (
+	RoundRobin   PoolPolicy = iota // Smooth weighted round robin
+	LeastLatency                   // Lowest average latency, scaled by weight
+)
+
+// PoolOptions configures a ProxyPool
+// Zero values use the defaults given in each field
+type PoolOptions struct {
+	HealthPath string        // Path probed with GET on every upstream, empty disables probes
+	Interval   time.Duration // Time between health probes (default: 10s)
+	Timeout    time.Duration // Limit for the response headers of a proxied request, or a probe (default: 30s)
+	// IdleTimeout ends response bodies, tunnels and event streams silent
+	// for that long (default: 5m); Timeout does not apply to them once the
+	// upstream answered
+	IdleTimeout time.Duration
+	Policy      PoolPolicy // Upstream selection policy
+
+	// FailureThreshold consecutive 5xx responses or transport errors eject
+	// an upstream for Cooldown (defaults: 3 and 30s)
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	Observer UpstreamObserver // Notified when an upstream changes state, may be nil
+	Clock    Clock            // Time source of ejections and probes, SystemClock when nil
+}
+
+// UpstreamStatus describes an upstream of a ProxyPool
+type UpstreamStatus struct {
+	URL          string        `json:"url"`
+	Weight       int           `json:"weight"`
+	Healthy      bool          `json:"healthy"`       // Last health probe passed (true without probes)
+	EjectedUntil time.Time     `json:"ejected_until"` // End of the cooldown after passive failures
+	Latency      time.Duration `json:"latency"`       // Moving average of response times
+	Failures     int           `json:"consecutive_failures"`
+	Requests     int64         `json:"requests"`
+	Errors       int64         `json:"errors"`
+
+	Tunnels        int   `json:"tunnels"`          // Upgraded connections currently open
+	TunnelBytesIn  int64 `json:"tunnel_bytes_in"`  // Bytes sent by clients over closed tunnels
+	TunnelBytesOut int64 `json:"tunnel_bytes_out"` // Bytes sent by the upstream over closed tunnels
+}
+
+// Available reports whether the upstream may receive traffic at t
+func (s UpstreamStatus) Available(t time.Time) bool {
+	return s.Healthy && !t.Before(s.EjectedUntil)
+}
+
+// UpstreamObserver receives the state changes of pool upstreams: ejection,
+// recovery and health probe results
+type UpstreamObserver interface {
+	UpstreamChanged(s UpstreamStatus)
+}
+
+// ErrNoUpstream is answered with 503 when every upstream is ejected or unhealthy
+var ErrNoUpstream = errors.New("no upstream available")
+
+// hopHeaders are connection-specific and not forwarded by the proxy
+var hopHeaders = []string{
+	"connection", "keep-alive", "proxy-authenticate", "proxy-authorization",
+	"te", "trailer", "transfer-encoding", "upgrade",
+}
+
+// Pool is a reverse proxy spreading requests over weighted upstreams
+type Pool struct {
+	opts   PoolOptions
+	client *http.Client
+	stop   chan struct{}
+	once   sync.Once
+
+	mu        sync.Mutex
+	upstreams []*upstream
+}
+
+// upstream is the live state of an Upstream
+type upstream struct {
+	url     *url.URL
+	status  UpstreamStatus
+	current int // Smooth round robin counter
+}
+
+// ProxyPool creates a reverse proxy over upstreams
+// Args:
+//   - upstreams: Backends with their weights
+//   - opts: Health checking, selection and ejection settings
+//
+// Returns an error for an empty list or an invalid URL. Health probes run
+// until Close or a Server.Shutdown
+func ProxyPool(upstreams []Upstream, opts PoolOptions) (*Pool, error) {
+	if len(upstreams) == 0 {
+		return nil, errors.New("proxy pool: no upstreams")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	if opts.IdleTimeout <= 0 {
+		opts.IdleTimeout = 5 * time.Minute
+	}
+	if opts.Clock == nil {
+		opts.Clock = SystemClock
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+
+	p := &Pool{
+		opts: opts,
+		client: &http.Client{
+			// Redirects are the client's business
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		stop: make(chan struct{}),
+	}
+	for _, u := range upstreams {
+		parsed, err := url.Parse(strings.TrimRight(u.URL, "/"))
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			return nil, errors.New("proxy pool: invalid upstream URL: " + u.URL)
+		}
+		weight := max(u.Weight, 1)
+		p.upstreams = append(p.upstreams, &upstream{
+			url:    parsed,
+			status: UpstreamStatus{URL: parsed.String(), Weight: weight, Healthy: true},
+		})
+	}
+
+	if opts.HealthPath != "" {
+		Go(p.probeLoop)
+	}
+	return p, nil
+}
+
+// Close stops the health probes
+func (p *Pool) Close() {
+	p.once.Do(func() { close(p.stop) })
+}
+
+// Status returns the state of every upstream
+func (p *Pool) Status() []UpstreamStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	status := make([]UpstreamStatus, len(p.upstreams))
+	for i, u := range p.upstreams {
+		status[i] = u.status
+	}
+	return status
+}
+
+// AdminHandler answers the pool Status as JSON
+// It is meant for an internal route, e.g. r.Route("/admin/upstreams", pool.AdminHandler)
+func (p *Pool) AdminHandler(r *Request, w *Writer) {
+	w.SetHeader("Content-Type", "application/json")
+	w.WriteJson(p.Status())
+}
+
+// Handler forwards the request to an upstream chosen by the pool policy
+// The request path and query are appended to the upstream URL. Upgrade
+// requests (e.g., websockets) become a tunnel when the upstream switches
+// protocols, and event streams are relayed as each event arrives
+func (p *Pool) Handler(r *Request, w *Writer) {
+	u := p.pick(p.opts.Clock.Now())
+	if u == nil {
+		Error(w, ErrNoUpstream, http.StatusServiceUnavailable)
+		return
+	}
+	if isProxyUpgrade(r) {
+		p.upgrade(r, w, u)
+		return
+	}
+
+	// The timer ends the request when the response headers take longer than
+	// Timeout, then when the body stays silent for IdleTimeout. The request
+	// context also ends it when the client goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	idle := time.AfterFunc(p.opts.Timeout, cancel)
+	defer idle.Stop()
+
+	start := p.opts.Clock.Now()
+	resp, err := p.client.Do(p.outgoing(ctx, u, r))
+	if err != nil {
+		// A client that left is not a failure of the upstream
+		if r.Context().Err() == nil {
+			p.report(u, p.since(start), false)
+		}
+		Error(w, errors.New("bad gateway"), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	p.relay(w, resp)
+	if isEventStream(resp) {
+		p.report(u, p.since(start), resp.StatusCode < 500)
+		p.stream(w, resp.Body, idle)
+		return
+	}
+	// Only the upstream side counts: the client may go away mid-copy, and a
+	// read cut by the cancellation above is not an upstream error
+	idle.Reset(p.opts.IdleTimeout)
+	body := &upstreamBody{r: resp.Body, idle: idle, timeout: p.opts.IdleTimeout}
+	io.Copy(w, body)
+	p.report(u, p.since(start), (body.err == nil || ctx.Err() != nil) && resp.StatusCode < 500)
+}
+
+// upstreamBody records the read error of an upstream response body and
+// pushes the idle timer back on each read
+type upstreamBody struct {
+	r       io.Reader
+	err     error
+	idle    *time.Timer
+	timeout time.Duration
+}
+
+func (b *upstreamBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	if b.idle != nil {
+		b.idle.Reset(b.timeout)
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		b.err = err
+	}
+	return n, err
+}
+
+// since returns the time elapsed on the pool Clock
+func (p *Pool) since(t time.Time) time.Duration {
+	return p.opts.Clock.Now().Sub(t)
+}
+
+// relay copies the status and headers of an upstream response
+func (p *Pool) relay(w *Writer, resp *http.Response) {
+	// The Writer sets its own framing headers
+	for k, v := range resp.Header {
+		k = strings.ToLower(k)
+		if k == "content-length" || containsString(hopHeaders, k) {
+			continue
+		}
+		// List-valued headers such as Set-Cookie keep one value per line
+		if _, list := listHeaders[k]; list {
+			for _, value := range v {
+				w.SetHeader(k, value)
+			}
+			continue
+		}
+		w.SetHeader(k, strings.Join(v, ", "))
+	}
+	w.WriteHeader(resp.StatusCode)
+}
+
+// outgoing builds the upstream request for r
+func (p *Pool) outgoing(ctx context.Context, u *upstream, r *Request) *http.Request {
+	target := *u.url
+	target.Path += r.path
+	target.RawQuery = r.rawQuery
+
+	var body io.Reader
+	if r.Body != nil && r.Body != http.NoBody {
+		body = r.Body
+	}
+	out, _ := http.NewRequestWithContext(ctx, r.Method, target.String(), body)
+	for k, v := range r.Headers {
+		if k != "host" && !containsString(hopHeaders, k) {
+			out.Header.Set(k, v)
+		}
+	}
+	if cl, err := strconv.ParseInt(r.Headers.Get("Content-Length"), 10, 64); err == nil {
+		out.ContentLength = cl
+	}
+
+	out.Host = r.Headers.Get("Host")
+	if ip := r.RemoteIP(); ip != "" {
+		if prior := r.Headers.Get("X-Forwarded-For"); prior != "" {
+			ip = prior + ", " + ip
+		}
+		out.Header.Set("X-Forwarded-For", ip)
+	}
+	return out
+}
+
+// pick selects an available upstream, or nil when there is none
+func (p *Pool) pick(now time.Time) *upstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var (
+		best  *upstream
+		total int
+	)
+	for _, u := range p.upstreams {
+		if !u.status.Available(now) {
+			continue
+		}
+		switch p.opts.Policy {
+		case LeastLatency:
+			// Averages decay so slow upstreams are eventually retried, and
+			// upstreams without samples yet are tried first
+			u.status.Latency -= u.status.Latency / 64
+			if best == nil || u.status.Latency*time.Duration(best.status.Weight) < best.status.Latency*time.Duration(u.status.Weight) {
+				best = u
+			}
+		default:
+			u.current += u.status.Weight
+			total += u.status.Weight
+			if best == nil || u.current > best.current {
+				best = u
+			}
+		}
+	}
+
+	if best != nil {
+		best.current -= total
+		best.status.Requests++
+	}
+	return best
+}
+
+// report records the outcome of a proxied request: failures past the
+// threshold eject the upstream for the cooldown
+func (p *Pool) report(u *upstream, elapsed time.Duration, ok bool) {
+	p.mu.Lock()
+	s := &u.status
+	if s.Latency == 0 {
+		s.Latency = elapsed
+	} else {
+		s.Latency = (s.Latency*4 + elapsed) / 5
+	}
+
+	changed := false
+	if ok {
+		changed = s.Failures >= p.opts.FailureThreshold
+		s.Failures = 0
+	} else {
+		s.Errors++
+		s.Failures++
+		// A failure right after a cooldown ejects the upstream again
+		now := p.opts.Clock.Now()
+		if s.Failures >= p.opts.FailureThreshold && !now.Before(s.EjectedUntil) {
+			s.EjectedUntil = now.Add(p.opts.Cooldown)
+			changed = true
+		}
+	}
+	status := *s
+	p.mu.Unlock()
+
+	if changed {
+		p.notify(status)
+	}
+}
+
+// probeLoop runs the health probes until Close or shutdown
+func (p *Pool) probeLoop(ctx context.Context) {
+	for {
+		p.probe(ctx)
+		select {
+		case <-p.opts.Clock.After(p.opts.Interval):
+		case <-p.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// probe checks every upstream once
+// Passive ejections are left to their cooldown: an upstream may pass its
+// health check while failing real requests
+func (p *Pool) probe(ctx context.Context) {
+	for _, u := range p.upstreams {
+		healthy := p.check(ctx, u)
+
+		p.mu.Lock()
+		changed := u.status.Healthy != healthy
+		u.status.Healthy = healthy
+		status := u.status
+		p.mu.Unlock()
+
+		if changed {
+			p.notify(status)
+		}
+	}
+}
+
+// check sends one health probe, passing on a 2xx or 3xx answer
+func (p *Pool) check(ctx context.Context, u *upstream) bool {
+	ctx, cancel := context.WithTimeout(ctx, p.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.url.String()+p.opts.HealthPath, nil)
+	if err != nil {
+		return false
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return false
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode < 400
+}
+
+// notify reports an upstream state change to the observer
+func (p *Pool) notify(s UpstreamStatus) {
+	if p.opts.Observer != nil {
+		p.opts.Observer.UpstreamChanged(s)
+	}
+}
@@ -0,0 +1,501 @@
+package gouter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// failConn is a client connection that fails every write
+type failConn struct{ net.Conn }
+
+func (failConn) Write(p []byte) (int, error)      { return 0, errors.New("client gone") }
+func (failConn) Close() error                     { return nil }
+func (failConn) SetWriteDeadline(time.Time) error { return nil }
+
+// testPool returns a pool over a single upstream running handler
+func testPool(t *testing.T, handler http.HandlerFunc) *Pool {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+	p, err := ProxyPool([]Upstream{{URL: upstream.URL}}, PoolOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	return p
+}
+
+func TestPoolIgnoresClientWriteErrors(t *testing.T) {
+	p := testPool(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 64<<10)))
+	})
+
+	// A small buffer makes the Writer stream, so the body reaches the
+	// failing client connection while it is copied
+	w := newWriter(failConn{})
+	w.maxBuffered = 1024
+	req := newRequest()
+	req.Method, req.path = "GET", "/"
+	p.Handler(req, w)
+
+	if st := p.Status()[0]; st.Errors != 0 || st.Failures != 0 {
+		t.Errorf("client write error counted against the upstream: %+v", st)
+	}
+}
+
+func TestPoolCountsUpstreamReadErrors(t *testing.T) {
+	p := testPool(t, func(w http.ResponseWriter, r *http.Request) {
+		// Promise more than is sent, then drop the connection
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte("partial"))
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+
+	r := newTestRouter()
+	r.Route("/*", p.Handler)
+	addr := serveRouter(t, r)
+	if resp, err := http.Get("http://" + addr + "/x"); err == nil {
+		resp.Body.Close()
+	}
+
+	if st := p.Status()[0]; st.Errors != 1 {
+		t.Errorf("truncated upstream body counted %d errors, want 1", st.Errors)
+	}
+}
+
+// poolAddr serves a pool over a single upstream running handler
+func poolAddr(t *testing.T, opts PoolOptions, handler http.HandlerFunc) (*Pool, string) {
+	t.Helper()
+	upstream := httptest.NewServer(handler)
+	t.Cleanup(upstream.Close)
+	p, err := ProxyPool([]Upstream{{URL: upstream.URL}}, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	r := newTestRouter()
+	r.Route("/*", p.Handler)
+	return p, serveRouter(t, r)
+}
+
+func TestPoolTimeoutsOnBodies(t *testing.T) {
+	opts := PoolOptions{Timeout: 100 * time.Millisecond, IdleTimeout: 150 * time.Millisecond}
+	p, addr := poolAddr(t, opts, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow-headers":
+			time.Sleep(300 * time.Millisecond)
+		case "/slow-body":
+			// Takes longer than Timeout in all, but never idles
+			for i := 0; i < 6; i++ {
+				io.WriteString(w, "chunk;")
+				w.(http.Flusher).Flush()
+				time.Sleep(50 * time.Millisecond)
+			}
+		case "/stall":
+			io.WriteString(w, "start;")
+			w.(http.Flusher).Flush()
+			time.Sleep(400 * time.Millisecond)
+			io.WriteString(w, "late")
+		}
+	})
+	get := func(path string) (int, string) {
+		resp := rawExchange(t, addr, "GET "+path+" HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	// Timeout only bounds the wait for the headers
+	if code, body := get("/slow-body"); code != 200 || body != strings.Repeat("chunk;", 6) {
+		t.Errorf("slow body = %d %q, want it whole", code, body)
+	}
+	if st := p.Status()[0]; st.Errors != 0 {
+		t.Errorf("slow body counted %d errors", st.Errors)
+	}
+	if code, _ := get("/slow-headers"); code != http.StatusBadGateway {
+		t.Errorf("slow headers = %d, want 502", code)
+	}
+	if st := p.Status()[0]; st.Errors != 1 {
+		t.Errorf("slow headers counted %d errors, want 1", st.Errors)
+	}
+
+	// A body silent past IdleTimeout is cut by the pool itself
+	if _, body := get("/stall"); strings.Contains(body, "late") {
+		t.Errorf("stalled body = %q, want it cut at IdleTimeout", body)
+	}
+	if st := p.Status()[0]; st.Errors != 1 {
+		t.Errorf("idle cut counted as an upstream error: %d errors", st.Errors)
+	}
+}
+
+func TestPoolCancelsWithClient(t *testing.T) {
+	canceled := make(chan struct{})
+	p := testPool(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	})
+	returned := make(chan struct{})
+	r := newTestRouter()
+	r.Route("/*", func(req *Request, w *Writer) {
+		defer close(returned)
+		p.Handler(req, w)
+	})
+	addr := serveRouter(t, r)
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(c, "GET /wait HTTP/1.1\r\nHost: x\r\n\r\n")
+	time.Sleep(100 * time.Millisecond)
+	c.Close()
+
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream request still running after the client left")
+	}
+	<-returned
+	if st := p.Status()[0]; st.Errors != 0 || st.Failures != 0 {
+		t.Errorf("client disconnect counted against the upstream: %+v", st)
+	}
+}
+
+// backend is a test upstream answering its name, or 500 while failing
+type backend struct {
+	name    string
+	url     string
+	hits    atomic.Int64
+	failing atomic.Bool
+	sick    atomic.Bool // Health probes answer 503
+	delay   time.Duration
+}
+
+func newBackend(t *testing.T, name string) *backend {
+	b := &backend{name: name}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			if b.sick.Load() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			return
+		}
+		b.hits.Add(1)
+		time.Sleep(b.delay)
+		if b.failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		io.WriteString(w, b.name)
+	}))
+	t.Cleanup(srv.Close)
+	b.url = srv.URL
+	return b
+}
+
+// upstreamEvents records the state changes reported by a pool
+type upstreamEvents struct {
+	mu     sync.Mutex
+	events []UpstreamStatus
+}
+
+func (o *upstreamEvents) UpstreamChanged(s UpstreamStatus) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, s)
+}
+
+func (o *upstreamEvents) list() []UpstreamStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]UpstreamStatus(nil), o.events...)
+}
+
+// servePool serves the pool on every path and its status on /admin/upstreams
+func servePool(t *testing.T, backends []*backend, weights []int, opts PoolOptions) (*Pool, func() (int, string)) {
+	t.Helper()
+	upstreams := make([]Upstream, len(backends))
+	for i, b := range backends {
+		upstreams[i] = Upstream{URL: b.url, Weight: weights[i]}
+	}
+	p, err := ProxyPool(upstreams, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+
+	r := newTestRouter()
+	r.Get("/admin/upstreams", p.AdminHandler)
+	r.Route("/*", p.Handler)
+	addr := serveRouter(t, r)
+	return p, func() (int, string) {
+		resp := rawExchange(t, addr, "GET /work HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+		return resp.StatusCode, bodyString(t, resp)
+	}
+}
+
+// hitCounts sends n requests and counts the answers of each backend
+func hitCounts(n int, get func() (int, string)) map[string]int {
+	counts := map[string]int{}
+	for i := 0; i < n; i++ {
+		code, body := get()
+		counts[body+" "+http.StatusText(code)]++
+	}
+	return counts
+}
+
+func TestProxyPoolWeights(t *testing.T) {
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	p, get := servePool(t, []*backend{a, b}, []int{3, 0}, PoolOptions{})
+
+	// Smooth round robin spreads an exact 3:1 share
+	counts := hitCounts(40, get)
+	if counts["a OK"] != 30 || counts["b OK"] != 10 {
+		t.Errorf("weights 3 and 1 split 40 requests as %v", counts)
+	}
+	if st := p.Status(); st[0].Requests != 30 || st[1].Requests != 10 || st[1].Weight != 1 {
+		t.Errorf("status = %+v, want the requests counted and weight 0 read as 1", st)
+	}
+
+	for _, bad := range [][]Upstream{nil, {{URL: "not a url"}}, {{URL: "http://"}}} {
+		if _, err := ProxyPool(bad, PoolOptions{}); err == nil {
+			t.Errorf("ProxyPool(%v) succeeded", bad)
+		}
+	}
+}
+
+func TestProxyPoolPassiveEjection(t *testing.T) {
+	a, flaky := newBackend(t, "a"), newBackend(t, "flaky")
+	seen := &upstreamEvents{}
+	p, get := servePool(t, []*backend{a, flaky}, []int{1, 1}, PoolOptions{
+		FailureThreshold: 2,
+		Cooldown:         300 * time.Millisecond,
+		Observer:         seen,
+	})
+
+	// Failures are relayed until the threshold ejects the upstream; the
+	// traffic then shifts to the healthy one
+	flaky.failing.Store(true)
+	counts := hitCounts(4, get)
+	if counts["a OK"] != 2 || counts["flaky Internal Server Error"] != 2 {
+		t.Errorf("before ejection = %v, want alternating answers", counts)
+	}
+	if counts := hitCounts(6, get); counts["a OK"] != 6 {
+		t.Errorf("after ejection = %v, want every request on a", counts)
+	}
+	events := seen.list()
+	if len(events) != 1 || events[0].URL != flaky.url || events[0].Failures != 2 || events[0].Available(time.Now()) {
+		t.Fatalf("events = %+v, want the ejection of the flaky upstream", events)
+	}
+
+	// The admin endpoint shows the ejection
+	var status []UpstreamStatus
+	_, body := servePoolAdmin(t, p)
+	if err := json.Unmarshal([]byte(body), &status); err != nil || len(status) != 2 || status[1].Errors != 2 || status[1].EjectedUntil.IsZero() {
+		t.Errorf("admin status = %s (%v)", body, err)
+	}
+
+	// Once the cooldown is over the recovered upstream takes traffic again
+	flaky.failing.Store(false)
+	time.Sleep(350 * time.Millisecond)
+	if counts := hitCounts(4, get); counts["flaky OK"] != 2 {
+		t.Errorf("after the cooldown = %v, want traffic back on flaky", counts)
+	}
+	events = seen.list()
+	if len(events) != 2 || events[1].Failures != 0 {
+		t.Errorf("events = %+v, want the recovery reported", events)
+	}
+}
+
+// servePoolAdmin answers the admin handler of p directly
+func servePoolAdmin(t *testing.T, p *Pool) (int, string) {
+	rec := &Writer{Headers: make(Headers)}
+	p.AdminHandler(&Request{Headers: make(Headers)}, rec)
+	return rec.code, string(rec.body)
+}
+
+func TestProxyPoolHealthProbes(t *testing.T) {
+	a, b := newBackend(t, "a"), newBackend(t, "b")
+	seen := &upstreamEvents{}
+	_, get := servePool(t, []*backend{a, b}, []int{1, 1}, PoolOptions{
+		HealthPath: "/health",
+		Interval:   10 * time.Millisecond,
+		Observer:   seen,
+	})
+
+	b.sick.Store(true)
+	eventually(t, "probe marks b unhealthy", func() bool { return len(seen.list()) == 1 })
+	if counts := hitCounts(4, get); counts["a OK"] != 4 {
+		t.Errorf("with b unhealthy = %v, want every request on a", counts)
+	}
+
+	a.sick.Store(true)
+	eventually(t, "probe marks a unhealthy", func() bool { return len(seen.list()) == 2 })
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("without healthy upstreams = %d, want 503", code)
+	}
+
+	a.sick.Store(false)
+	b.sick.Store(false)
+	eventually(t, "probes recover both", func() bool { return len(seen.list()) == 4 })
+	if counts := hitCounts(4, get); counts["a OK"] != 2 || counts["b OK"] != 2 {
+		t.Errorf("after recovery = %v, want both upstreams used", counts)
+	}
+}
+
+func TestProxyPoolLeastLatency(t *testing.T) {
+	slow, fast := newBackend(t, "slow"), newBackend(t, "fast")
+	slow.delay = 20 * time.Millisecond
+	_, get := servePool(t, []*backend{slow, fast}, []int{1, 1}, PoolOptions{Policy: LeastLatency})
+
+	counts := hitCounts(30, get)
+	if counts["fast OK"] < 25 || counts["slow OK"] == 0 {
+		t.Errorf("least latency split = %v, want most requests on fast", counts)
+	}
+}
+
+// writeClientFrame sends a masked websocket text frame
+func writeClientFrame(t *testing.T, c net.Conn, msg string) {
+	t.Helper()
+	mask := []byte{1, 2, 3, 4}
+	frame := []byte{0x81, 0x80 | byte(len(msg))}
+	frame = append(frame, mask...)
+	for i := 0; i < len(msg); i++ {
+		frame = append(frame, msg[i]^mask[i%4])
+	}
+	if _, err := c.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readServerFrame reads one short unmasked frame and returns its opcode
+// and payload
+func readServerFrame(br *bufio.Reader) (byte, string, error) {
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(br, head); err != nil {
+		return 0, "", err
+	}
+	payload := make([]byte, head[1]&0x7f)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		return 0, "", err
+	}
+	return head[0] & 0x0f, string(payload), nil
+}
+
+func TestProxyPoolTunnels(t *testing.T) {
+	gate := make(chan struct{})
+	backend := newTestRouter()
+	backend.WebSocket("/ws", func(ws *WebSocket, req *Request) {
+		defer ws.Close()
+		for {
+			msg, err := ws.ReadMessage()
+			if err != nil || string(msg) == "bye" {
+				return
+			}
+			ws.WriteMessage([]byte("echo: " + string(msg)))
+		}
+	}, WebSocketConfig{})
+	backend.Get("/events", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Type", "text/event-stream")
+		w.Write([]byte("data: 1\n\n"))
+		w.Flush()
+		<-gate
+		w.Write([]byte("data: 2\n\n"))
+	})
+	backend.Get("/plain", func(req *Request, w *Writer) { w.Write([]byte("no upgrade here")) })
+	upstream := "http://" + serveRouter(t, backend)
+
+	p, err := ProxyPool([]Upstream{{URL: upstream}}, PoolOptions{IdleTimeout: 300 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(p.Close)
+	front := newTestRouter()
+	front.Route("/*", p.Handler)
+	addr := serveRouter(t, front)
+
+	dial := func(raw string) (net.Conn, *bufio.Reader, *http.Response) {
+		t.Helper()
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		c.SetDeadline(time.Now().Add(3 * time.Second))
+		io.WriteString(c, raw)
+		br := bufio.NewReader(c)
+		resp, err := http.ReadResponse(br, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c, br, resp
+	}
+
+	// Messages go both ways through the tunnel
+	c, br, resp := dial(wsHandshake + "Sec-WebSocket-Version: 13\r\n\r\n")
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("proxied handshake = %d %v, want the upstream 101", resp.StatusCode, resp.Header)
+	}
+	for _, msg := range []string{"hello", "world"} {
+		writeClientFrame(t, c, msg)
+		if op, got, err := readServerFrame(br); err != nil || op != 1 || got != "echo: "+msg {
+			t.Fatalf("echo of %q = %d %q %v", msg, op, got, err)
+		}
+	}
+	if st := p.Status()[0]; st.Tunnels != 1 {
+		t.Errorf("open tunnels = %d, want 1", st.Tunnels)
+	}
+
+	// The upstream closing ends the client connection too
+	writeClientFrame(t, c, "bye")
+	for {
+		if _, _, err := readServerFrame(br); err != nil {
+			break
+		}
+	}
+	eventually(t, "tunnel closed", func() bool { return p.Status()[0].Tunnels == 0 })
+	// Three masked frames in, two echoes out
+	if st := p.Status()[0]; st.TunnelBytesIn != 31 || st.TunnelBytesOut < 26 {
+		t.Errorf("tunnel bytes = %d in, %d out, want 31 in and at least 26 out", st.TunnelBytesIn, st.TunnelBytesOut)
+	}
+
+	// A silent tunnel is closed after the IdleTimeout
+	_, br, _ = dial(wsHandshake + "Sec-WebSocket-Version: 13\r\n\r\n")
+	opened := time.Now()
+	if _, err := br.ReadByte(); err == nil {
+		t.Error("idle tunnel sent data")
+	}
+	if d := time.Since(opened); d < 250*time.Millisecond || d > 2*time.Second {
+		t.Errorf("idle tunnel closed after %v, want the 300ms IdleTimeout", d)
+	}
+
+	// An upstream refusing the switch is relayed as a plain response
+	_, _, resp = dial("GET /plain HTTP/1.1\r\nHost: x\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	if body := bodyString(t, resp); resp.StatusCode != 200 || body != "no upgrade here" {
+		t.Errorf("refused upgrade = %d %q", resp.StatusCode, body)
+	}
+
+	// Events are relayed as they arrive, not when the stream ends
+	_, _, resp = dial("GET /events HTTP/1.1\r\nHost: x\r\n\r\n")
+	events := bufio.NewReader(resp.Body)
+	if line, err := events.ReadString('\n'); err != nil || line != "data: 1\n" {
+		t.Fatalf("first event = %q %v, want it before the stream ends", line, err)
+	}
+	close(gate)
+	rest, _ := io.ReadAll(events)
+	if string(rest) != "\ndata: 2\n\n" {
+		t.Errorf("rest of the stream = %q", rest)
+	}
+}
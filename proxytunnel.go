@@ -0,0 +1,193 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// isProxyUpgrade reports whether the request asks to switch protocols
+// Unlike isUpgradeRequest any protocol is accepted, the upstream decides
+func isProxyUpgrade(r *Request) bool {
+	if r.Headers.Get("Upgrade") == "" {
+		return false
+	}
+	for _, token := range strings.Split(r.Headers.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// upgrade forwards an Upgrade request; when the upstream answers 101 the
+// client connection is taken over and bytes are copied both ways until
+// either side closes or stays idle past the IdleTimeout
+func (p *Pool) upgrade(r *Request, w *Writer, u *upstream) {
+	start := p.opts.Clock.Now()
+	upConn, err := p.dial(u)
+	if err != nil {
+		p.report(u, p.since(start), false)
+		Error(w, errors.New("bad gateway"), http.StatusBadGateway)
+		return
+	}
+	defer upConn.Close()
+
+	out := p.outgoing(context.Background(), u, r)
+	out.Header.Set("Connection", "Upgrade")
+	out.Header.Set("Upgrade", r.Headers.Get("Upgrade"))
+
+	upConn.SetDeadline(time.Now().Add(p.opts.Timeout))
+	upReader := bufio.NewReader(upConn)
+	var resp *http.Response
+	if err = out.Write(upConn); err == nil {
+		resp, err = http.ReadResponse(upReader, out)
+	}
+	if err != nil {
+		p.report(u, p.since(start), false)
+		Error(w, errors.New("bad gateway"), http.StatusBadGateway)
+		return
+	}
+	upConn.SetDeadline(time.Time{})
+
+	// The upstream refused the switch: relay its answer as usual
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		p.relay(w, resp)
+		_, err = io.Copy(w, resp.Body)
+		p.report(u, p.since(start), err == nil && resp.StatusCode < 500)
+		return
+	}
+
+	var head bytes.Buffer
+	head.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	resp.Header.Write(&head)
+	head.WriteString("\r\n")
+	if err := w.hijack(head.Bytes()); err != nil {
+		p.report(u, p.since(start), true)
+		return
+	}
+	p.report(u, p.since(start), true)
+
+	p.mu.Lock()
+	u.status.Tunnels++
+	p.mu.Unlock()
+
+	// Bytes the parsers read ahead belong to the tunnel
+	var wg sync.WaitGroup
+	var in, outBytes atomic.Int64
+	wg.Add(2)
+	spawn("proxy-tunnel", func() {
+		defer wg.Done()
+		p.pipe(upConn, w.c, w.br, &in)
+	})
+	spawn("proxy-tunnel", func() {
+		defer wg.Done()
+		p.pipe(w.c, upConn, upReader, &outBytes)
+	})
+	wg.Wait()
+
+	p.mu.Lock()
+	u.status.Tunnels--
+	u.status.TunnelBytesIn += in.Load()
+	u.status.TunnelBytesOut += outBytes.Load()
+	p.mu.Unlock()
+}
+
+// pipe copies src to dst until either fails or src stays idle for the
+// IdleTimeout, then closes both so the opposite copy ends as well
+func (p *Pool) pipe(dst, srcConn net.Conn, src io.Reader, count *atomic.Int64) {
+	defer dst.Close()
+	defer srcConn.Close()
+
+	buf := make([]byte, 32<<10)
+	for {
+		srcConn.SetReadDeadline(time.Now().Add(p.opts.IdleTimeout))
+		n, err := src.Read(buf)
+		if n > 0 {
+			dst.SetWriteDeadline(time.Now().Add(p.opts.IdleTimeout))
+			if _, werr := dst.Write(buf[:n]); werr != nil {
+				return
+			}
+			count.Add(int64(n))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// dial opens a connection to the upstream, with TLS for https URLs
+func (p *Pool) dial(u *upstream) (net.Conn, error) {
+	host := u.url.Host
+	dialer := &net.Dialer{Timeout: p.opts.Timeout}
+	if u.url.Scheme == "https" {
+		if u.url.Port() == "" {
+			host += ":443"
+		}
+		return tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.url.Hostname()})
+	}
+	if u.url.Port() == "" {
+		host += ":80"
+	}
+	return dialer.Dial("tcp", host)
+}
+
+// hijack writes head to the connection and takes it over from the server
+// Returns ErrResponseDone when a response was already started
+func (w *Writer) hijack(head []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done || w.headersSent {
+		return ErrResponseDone
+	}
+	// The tunnel reads the connection from now on
+	if w.notifier != nil {
+		w.notifier.stop()
+	}
+	if _, err := w.c.Write(head); err != nil {
+		return err
+	}
+	w.hijacked = true
+	return nil
+}
+
+// stream relays an event stream as it arrives; the upstream may stay
+// silent for up to the IdleTimeout between events
+func (p *Pool) stream(w *Writer, body io.Reader, idle *time.Timer) error {
+	if err := w.WriteHeaders(); err != nil {
+		return err
+	}
+	idle.Reset(p.opts.IdleTimeout)
+	buf := make([]byte, 4<<10)
+	for {
+		n, err := body.Read(buf)
+		idle.Reset(p.opts.IdleTimeout)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// isEventStream reports whether the upstream answered with server-sent events
+func isEventStream(resp *http.Response) bool {
+	ct, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+	return strings.EqualFold(strings.TrimSpace(ct), "text/event-stream")
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// FailurePolicy decides what happens to requests when the store fails
+type FailurePolicy int
+
+const (
+	FailClosed FailurePolicy = iota // Reject with 503 (default)
+	FailOpen                        // Let the request through unlimited
+)
+
+// Config configures the rate limiting middleware
+type Config struct {
+	Rate      Rate                           // Allowed requests per period for each key
+	Store     Store                          // Backend, defaults to a new MemoryStore
+	Key       func(r *gouter.Request) string // Client identity, defaults to the remote IP
+	OnFailure FailurePolicy                  // Behavior when Store returns an error
+	Clock     gouter.Clock                   // Time source of the default store, gouter.SystemClock when nil
+}
+
+// Middleware limits requests per key, answering 429 with Retry-After
+// Allowed responses carry X-RateLimit-Limit and X-RateLimit-Remaining;
+// with a MemoryStore the limit is the bucket size, Rate.Burst when set
+func Middleware(cfg Config) gouter.Middleware {
+	if cfg.Store == nil {
+		store := NewMemoryStore()
+		if cfg.Clock != nil {
+			store.SetClock(cfg.Clock)
+		}
+		cfg.Store = store
+	}
+	if cfg.Key == nil {
+		cfg.Key = func(r *gouter.Request) string { return r.RemoteIP() }
+	}
+	limit := strconv.Itoa(cfg.Rate.Requests)
+	if _, ok := cfg.Store.(*MemoryStore); ok {
+		limit = strconv.Itoa(cfg.Rate.capacity())
+	}
+
+	return func(next gouter.Handler) gouter.Handler {
+		return func(r *gouter.Request, w *gouter.Writer) {
+			d, err := cfg.Store.Allow(cfg.Key(r), cfg.Rate)
+			if err != nil {
+				log.Error(fmt.Errorf("rate limit store failed: %w", err))
+				if cfg.OnFailure == FailOpen {
+					next(r, w)
+					return
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			w.Headers.Add("X-RateLimit-Limit", limit)
+			w.Headers.Add("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			if !d.Allowed {
+				w.Headers.Add("Retry-After", httpdate.RetryAfter(max(d.ResetAfter, time.Second)))
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+
+			next(r, w)
+		}
+	}
+}
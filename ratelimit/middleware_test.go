@@ -0,0 +1,202 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+	"github.com/Murilinho145SG/gouter/gtest"
+)
+
+// fakeStore records the keys it is asked about and can simulate an outage
+type fakeStore struct {
+	mu       sync.Mutex
+	keys     []string
+	down     bool
+	decision Decision
+}
+
+func (s *fakeStore) Allow(key string, limit Rate) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append(s.keys, key)
+	if s.down {
+		return Decision{}, errors.New("store unreachable")
+	}
+	return s.decision, nil
+}
+
+// limitedHandler serves r behind the middleware as an http.Handler
+func limitedHandler(cfg Config) http.Handler {
+	r := gouter.NewRouter()
+	r.Update(func(d *gouter.Doc) { d.Active = false })
+	r.Use(Middleware(cfg))
+	r.Get("/", func(req *gouter.Request, w *gouter.Writer) {
+		w.Write([]byte("ok"))
+	})
+	return gouter.ToHTTPHandler(r)
+}
+
+// get sends a GET / with the given API key header
+func get(h http.Handler, apiKey string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Api-Key", apiKey)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestDefaultKeyIgnoresPort(t *testing.T) {
+	r := gouter.NewRouter()
+	r.Update(func(d *gouter.Doc) { d.Active = false })
+	r.Use(Middleware(Config{Rate: Rate{Requests: 1, Per: time.Minute}}))
+	r.Get("/", func(req *gouter.Request, w *gouter.Writer) {})
+	h := gouter.ToHTTPHandler(r)
+
+	do := func(remote string) int {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remote
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do("[2001:db8::1]:5000"); code != http.StatusOK {
+		t.Fatalf("first request = %d, want 200", code)
+	}
+	// Same IPv6 client from another source port shares the bucket
+	if code := do("[2001:db8::1]:5001"); code != http.StatusTooManyRequests {
+		t.Errorf("same IP, other port = %d, want 429", code)
+	}
+	if code := do("[2001:db8::2]:5000"); code != http.StatusOK {
+		t.Errorf("other IP = %d, want 200", code)
+	}
+}
+
+func TestMiddlewareUsesStore(t *testing.T) {
+	store := &fakeStore{decision: Decision{Allowed: true, Remaining: 4}}
+	h := limitedHandler(Config{
+		Rate:  Rate{Requests: 5, Per: time.Minute},
+		Store: store,
+		Key:   func(r *gouter.Request) string { return "api:" + r.Headers.Get("X-Api-Key") },
+	})
+
+	rec := get(h, "alpha")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("allowed request = %d, want 200", rec.Code)
+	}
+	if got := rec.Header().Get("X-RateLimit-Limit"); got != "5" {
+		t.Errorf("X-RateLimit-Limit = %q, want 5", got)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "4" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 4", got)
+	}
+
+	store.decision = Decision{Allowed: false, ResetAfter: 1500 * time.Millisecond}
+	rec = get(h, "beta")
+	if rec.Code != http.StatusTooManyRequests || rec.Body.String() == "ok" {
+		t.Errorf("denied request = %d %q, want 429 without reaching the handler", rec.Code, rec.Body)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "2" {
+		t.Errorf("Retry-After = %q, want the reset rounded up to 2", got)
+	}
+
+	store.decision = Decision{Allowed: false}
+	if got := get(h, "beta").Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After without a reset = %q, want at least 1", got)
+	}
+
+	if want := []string{"api:alpha", "api:beta", "api:beta"}; len(store.keys) != 3 || store.keys[0] != want[0] || store.keys[2] != want[2] {
+		t.Errorf("store keys = %v, want %v", store.keys, want)
+	}
+}
+
+func TestMiddlewareStoreOutage(t *testing.T) {
+	for _, tc := range []struct {
+		policy FailurePolicy
+		want   int
+	}{
+		{FailClosed, http.StatusServiceUnavailable},
+		{FailOpen, http.StatusOK},
+	} {
+		store := &fakeStore{down: true}
+		h := limitedHandler(Config{Rate: Rate{Requests: 1, Per: time.Minute}, Store: store, OnFailure: tc.policy})
+
+		for i := 0; i < 3; i++ {
+			rec := get(h, "k")
+			if rec.Code != tc.want {
+				t.Errorf("policy %d, request %d during the outage = %d, want %d", tc.policy, i+1, rec.Code, tc.want)
+			}
+			if rec.Header().Get("X-RateLimit-Limit") != "" {
+				t.Errorf("policy %d: limit headers sent without a decision", tc.policy)
+			}
+		}
+
+		// Recovery applies the store decisions again
+		store.down = false
+		store.decision = Decision{Allowed: false}
+		if rec := get(h, "k"); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("policy %d after recovery = %d, want 429", tc.policy, rec.Code)
+		}
+	}
+}
+
+func TestMiddlewareClock(t *testing.T) {
+	clock := gtest.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	h := limitedHandler(Config{Rate: Rate{Requests: 1, Per: time.Minute}, Key: func(*gouter.Request) string { return "k" }, Clock: clock})
+
+	if rec := get(h, ""); rec.Code != http.StatusOK {
+		t.Fatalf("first request = %d", rec.Code)
+	}
+	rec := get(h, "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "60" {
+		t.Fatalf("second request = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// The default store refills on the fake clock only
+	clock.Advance(59 * time.Second)
+	if rec := get(h, ""); rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Errorf("before refill = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	clock.Advance(time.Second)
+	if rec := get(h, ""); rec.Code != http.StatusOK {
+		t.Errorf("after refill = %d, want 200", rec.Code)
+	}
+}
+
+func TestMiddlewareBurst(t *testing.T) {
+	clock := gtest.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	h := limitedHandler(Config{Rate: PerSecond(2, 5), Clock: clock})
+
+	for i := 0; i < 5; i++ {
+		rec := get(h, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("burst request %d = %d", i+1, rec.Code)
+		}
+		// Remaining counts down from the bucket size the limit reports
+		if rec.Header().Get("X-RateLimit-Limit") != "5" || rec.Header().Get("X-RateLimit-Remaining") != strconv.Itoa(4-i) {
+			t.Errorf("burst request %d: limit %q, remaining %q", i+1, rec.Header().Get("X-RateLimit-Limit"), rec.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+	rec := get(h, "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over the burst = %d, Retry-After %q", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// After the burst, the rate alone applies: 2 requests a second
+	clock.Advance(time.Second)
+	codes := []int{get(h, "").Code, get(h, "").Code, get(h, "").Code}
+	if codes[0] != 200 || codes[1] != 200 || codes[2] != 429 {
+		t.Errorf("one second later = %v, want two requests through", codes)
+	}
+
+	// Other stores keep reporting Requests, as Burst does not apply to them
+	counted := limitedHandler(Config{Rate: PerSecond(2, 5), Store: NewCounterStore(newFakeCounter(), "rl:")})
+	if got := get(counted, "").Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Errorf("CounterStore X-RateLimit-Limit = %q, want 2", got)
+	}
+}
@@ -0,0 +1,189 @@
+/*
+Package ratelimit provides the rate limiting middleware and its storage backends.
+
+Features:
+- Store interface so limits can be shared across replicas
+- In-memory token bucket store with a configurable burst (the default)
+- Fixed-window store over any Incr/Expire client (e.g., Redis)
+- Configurable fail-open or fail-closed behavior on store errors
+*/
+package ratelimit
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter"
+)
+
+// Rate is a number of requests allowed per period
+type Rate struct {
+	Requests int           // Requests allowed in each period
+	Per      time.Duration // Length of the period
+	Burst    int           // Requests a MemoryStore lets through at once, Requests when zero
+}
+
+// PerSecond returns a rate of rps requests per second with bursts of up
+// to burst requests
+func PerSecond(rps, burst int) Rate {
+	return Rate{Requests: rps, Per: time.Second, Burst: burst}
+}
+
+// capacity returns the bucket size of the rate
+func (r Rate) capacity() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+	return r.Requests
+}
+
+// Decision is the outcome of a rate limit check
+type Decision struct {
+	Allowed    bool          // Whether the request may proceed
+	Remaining  int           // Requests left in the current period
+	ResetAfter time.Duration // Time until the limit is fully or partially restored
+}
+
+// Store decides whether a request identified by key fits within limit
+// Implementations must be safe for concurrent use
+type Store interface {
+	Allow(key string, limit Rate) (Decision, error)
+}
+
+// maxIdleBuckets is the bucket count above which full buckets are evicted
+const maxIdleBuckets = 10000
+
+// MemoryStore is a per-process token bucket store
+// Each key gets a bucket of limit.Burst tokens (limit.Requests when unset)
+// refilled at limit.Requests tokens per limit.Per, so short bursts are
+// allowed up to the bucket size
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+// bucket is the token state of one key
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// SetClock replaces the time source of the store, e.g. with a fake in tests
+func (s *MemoryStore) SetClock(c gouter.Clock) {
+	s.mu.Lock()
+	s.now = c.Now
+	s.mu.Unlock()
+}
+
+// Allow takes a token from the bucket of key
+func (s *MemoryStore) Allow(key string, limit Rate) (Decision, error) {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	capacity := float64(limit.capacity())
+	perToken := limit.Per / time.Duration(limit.Requests)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= maxIdleBuckets {
+			s.evictFull(now, limit)
+		}
+		b = &bucket{tokens: capacity, last: now}
+		s.buckets[key] = b
+	}
+
+	// Refill for the time elapsed since the last check
+	b.tokens += float64(now.Sub(b.last)) / float64(perToken)
+	if b.tokens > capacity {
+		b.tokens = capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(perToken))
+		return Decision{Allowed: false, Remaining: 0, ResetAfter: wait}, nil
+	}
+
+	b.tokens--
+	return Decision{Allowed: true, Remaining: int(b.tokens), ResetAfter: perToken}, nil
+}
+
+// evictFull removes the buckets that refilled completely, i.e. idle keys
+func (s *MemoryStore) evictFull(now time.Time, limit Rate) {
+	refill := limit.Per / time.Duration(limit.Requests) * time.Duration(limit.capacity())
+	for key, b := range s.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// Counter is the subset of a key-value client needed by CounterStore
+// Redis clients map directly: Incr to INCR and Expire to EXPIRE
+type Counter interface {
+	Incr(key string) (int64, error)             // Increments key, creating it at 1
+	Expire(key string, ttl time.Duration) error // Sets the time to live of key
+}
+
+// CounterStore is a fixed-window store backed by a shared Counter
+// Each period gets its own counter key, so replicas sharing the Counter
+// enforce one combined limit. Rate.Burst does not apply to windows
+type CounterStore struct {
+	client Counter
+	prefix string
+	now    func() time.Time
+}
+
+// NewCounterStore creates a store keeping its counters under prefix
+func NewCounterStore(client Counter, prefix string) *CounterStore {
+	return &CounterStore{client: client, prefix: prefix, now: time.Now}
+}
+
+// SetClock replaces the time source of the store; call it before use
+func (s *CounterStore) SetClock(c gouter.Clock) {
+	s.now = c.Now
+}
+
+// Allow increments the counter of the current window for key
+func (s *CounterStore) Allow(key string, limit Rate) (Decision, error) {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return Decision{Allowed: true}, nil
+	}
+
+	now := s.now()
+	window := now.UnixNano() / int64(limit.Per)
+	resetAfter := time.Duration((window+1)*int64(limit.Per) - now.UnixNano())
+	counterKey := s.prefix + key + ":" + strconv.FormatInt(window, 10)
+
+	n, err := s.client.Incr(counterKey)
+	if err != nil {
+		return Decision{}, err
+	}
+	// The first hit of a window sets its expiry
+	if n == 1 {
+		if err := s.client.Expire(counterKey, limit.Per); err != nil {
+			return Decision{}, err
+		}
+	}
+
+	remaining := limit.Requests - int(n)
+	if remaining < 0 {
+		return Decision{Allowed: false, ResetAfter: resetAfter}, nil
+	}
+	return Decision{Allowed: true, Remaining: remaining, ResetAfter: resetAfter}, nil
+}
@@ -0,0 +1,141 @@
+package ratelimit
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/gtest"
+)
+
+// fakeCounter is an in-memory Counter recording expiries
+type fakeCounter struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	expires map[string]time.Duration
+	fail    error
+}
+
+func newFakeCounter() *fakeCounter {
+	return &fakeCounter{counts: make(map[string]int64), expires: make(map[string]time.Duration)}
+}
+
+func (c *fakeCounter) Incr(key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.fail != nil {
+		return 0, c.fail
+	}
+	c.counts[key]++
+	return c.counts[key], nil
+}
+
+func (c *fakeCounter) Expire(key string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[key] = ttl
+	return nil
+}
+
+func TestMemoryStoreBurstAndRefill(t *testing.T) {
+	clock := gtest.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	store.SetClock(clock)
+	rate := Rate{Requests: 2, Per: time.Second, Burst: 4}
+
+	for i := 0; i < 4; i++ {
+		if d, _ := store.Allow("k", rate); !d.Allowed || d.Remaining != 3-i {
+			t.Fatalf("burst request %d = %+v, want allowed with %d left", i+1, d, 3-i)
+		}
+	}
+	d, _ := store.Allow("k", rate)
+	if d.Allowed || d.ResetAfter != 500*time.Millisecond {
+		t.Errorf("over the burst = %+v, want denied for 500ms", d)
+	}
+	if d, _ := store.Allow("other", rate); !d.Allowed {
+		t.Error("another key shares the bucket")
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if d, _ := store.Allow("k", rate); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("after one refill interval = %+v, want one token", d)
+	}
+	clock.Advance(time.Hour)
+	if d, _ := store.Allow("k", rate); d.Remaining != 3 {
+		t.Errorf("after idling = %+v, want the bucket capped at the burst", d)
+	}
+	if d, _ := store.Allow("k", Rate{}); !d.Allowed {
+		t.Error("zero rate limited the request")
+	}
+}
+
+func TestCounterStoreWindows(t *testing.T) {
+	clock := gtest.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	client := newFakeCounter()
+	store := NewCounterStore(client, "rl:")
+	store.SetClock(clock)
+	rate := Rate{Requests: 2, Per: time.Minute, Burst: 10}
+
+	clock.Advance(15 * time.Second)
+	for i := 0; i < 2; i++ {
+		if d, err := store.Allow("k", rate); err != nil || !d.Allowed {
+			t.Fatalf("request %d = %+v, %v, want allowed", i+1, d, err)
+		}
+	}
+	d, _ := store.Allow("k", rate)
+	if d.Allowed || d.ResetAfter != 45*time.Second {
+		t.Errorf("third request = %+v, want denied until the window ends in 45s", d)
+	}
+	if len(client.expires) != 1 {
+		t.Errorf("expiries = %v, want one per window", client.expires)
+	}
+	for key, ttl := range client.expires {
+		if ttl != time.Minute || key[:5] != "rl:k:" {
+			t.Errorf("expiry %s = %v, want the prefixed key for one period", key, ttl)
+		}
+	}
+
+	clock.Advance(45 * time.Second)
+	if d, _ := store.Allow("k", rate); !d.Allowed || d.Remaining != 1 {
+		t.Errorf("next window = %+v, want a fresh count", d)
+	}
+
+	outage := errors.New("connection refused")
+	client.fail = outage
+	if _, err := store.Allow("k", rate); !errors.Is(err, outage) {
+		t.Errorf("Allow during an outage = %v, want the client error", err)
+	}
+}
+
+func TestMemoryStoreEvictsRefilledBuckets(t *testing.T) {
+	clock := gtest.NewFakeClock(time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStore()
+	store.SetClock(clock)
+	rate := Rate{Requests: 2, Per: time.Second, Burst: 4}
+
+	for i := 0; i < 4; i++ {
+		store.Allow("k", rate)
+	}
+	for i := 1; i < maxIdleBuckets; i++ {
+		store.Allow("idle-"+strconv.Itoa(i), rate)
+	}
+
+	// Per has passed but a 4-token bucket takes 2s to refill, so nothing
+	// is evicted and "k" keeps its partial refill
+	clock.Advance(1500 * time.Millisecond)
+	store.Allow("new", rate)
+	if d, _ := store.Allow("k", rate); d.Remaining != 2 {
+		t.Errorf("k after 1.5s = %+v, want 3 tokens refilled and one taken", d)
+	}
+	if n := len(store.buckets); n != maxIdleBuckets+1 {
+		t.Errorf("buckets = %d, want none evicted", n)
+	}
+
+	clock.Advance(2 * time.Second)
+	store.Allow("newer", rate)
+	if n := len(store.buckets); n != 1 {
+		t.Errorf("buckets = %d after every bucket refilled, want only the new one", n)
+	}
+}
@@ -0,0 +1,74 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// recordTimeout bounds a single in-memory round trip
+const recordTimeout = 10 * time.Second
+
+// ServeConn serves a single already-accepted connection with the router
+// It is the building block used by Serve and by the in-memory recorder, and can
+// be used directly with net.Pipe to exercise a router without a listener
+func ServeConn(c net.Conn, r *Router) {
+	handleConn(c, r, connOptions{})
+}
+
+// record sends a raw HTTP request through the router over an in-memory pipe
+// and returns the parsed response with its body fully read
+func (r *Router) record(raw []byte) (*http.Response, []byte, error) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	spawn("recorder", func() { ServeConn(server, r) })
+
+	client.SetDeadline(time.Now().Add(recordTimeout))
+
+	// Write in the background since the server may answer before reading
+	// the whole request, and pipes are unbuffered
+	spawn("recorder", func() { client.Write(raw) })
+
+	// Answers to HEAD announce a length but carry no body
+	method, _, _ := bytes.Cut(raw, []byte(" "))
+	resp, err := http.ReadResponse(bufio.NewReader(client), &http.Request{Method: string(method)})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read in-memory response: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp, body, fmt.Errorf("failed to read in-memory response body: %w", err)
+	}
+
+	return resp, body, nil
+}
+
+// buildRequest renders a minimal HTTP/1.1 request for the in-memory recorder
+func buildRequest(method, path string, headers map[string]string, body []byte) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, path)
+	host := false
+	for k := range headers {
+		host = host || strings.EqualFold(k, "Host")
+	}
+	if !host {
+		buf.WriteString("Host: gouter.local\r\n")
+	}
+	for k, v := range headers {
+		fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+	}
+	if len(body) > 0 {
+		fmt.Fprintf(&buf, "Content-Length: %d\r\n", len(body))
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body)
+	return buf.Bytes()
+}
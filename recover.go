@@ -0,0 +1,33 @@
+package gouter
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// panicStackKey holds the stack of a panic caught by Recover, for RecordErrors
+const panicStackKey = "gouter.panicStack"
+
+// Recover creates a middleware answering 500 when the rest of the chain
+// panics, with the panic and its stack trace logged
+// The server already does this around every route; Recover catches the
+// panic inside the chain instead, so the middlewares registered after it
+// (e.g. Logger) see the 500. A response already being streamed is cut
+// short and its connection closed
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					stack := debug.Stack()
+					log.Error(fmt.Errorf("panic serving %s: %v", displayPath(r.path), rec), "\n"+string(stack))
+					r.SetValue(panicStackKey, stack)
+					w.failed()
+				}
+			}()
+			next(r, w)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package gouter
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRecover(t *testing.T) {
+	var access syncBuffer
+	out := captureServe(t, func(t *testing.T) {
+		r := newTestRouter()
+		r.RecordErrors(ErrorRingConfig{})
+		r.Use(Recover())
+		// Registered after Recover, so it wraps it and sees the 500
+		r.Use(Logger(LoggerFormat(LogJSON), LoggerOutput(&access)))
+		r.Get("/boom", func(req *Request, w *Writer) {
+			w.SetHeader("X-Partial", "yes")
+			w.Write([]byte("half written"))
+			panic("recovered boom")
+		})
+		r.Get("/ok", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+		addr := serveRouter(t, r)
+
+		// The connection survives the panic and serves the next request
+		raw := "GET /boom HTTP/1.1\r\nHost: x\r\n\r\nGET /ok HTTP/1.1\r\nHost: x\r\n\r\n"
+		resps := rawExchange(t, addr, raw, 2)
+		if resps[0].StatusCode != 500 || resps[0].Close || resps[0].Header.Get("X-Partial") != "" {
+			t.Errorf("panic answered %d (close %v, headers %v), want a clean 500", resps[0].StatusCode, resps[0].Close, resps[0].Header)
+		}
+		if body := bodyString(t, resps[0]); strings.Contains(body, "half") {
+			t.Errorf("500 body = %q, want the partial body dropped", body)
+		}
+		if got := bodyString(t, resps[1]); got != "ok" {
+			t.Errorf("next response = %q", got)
+		}
+
+		eventually(t, "the access line", func() bool { return access.lines()[0] != "" })
+		var e AccessLogEntry
+		if err := json.Unmarshal([]byte(access.lines()[0]), &e); err != nil || e.Status != 500 {
+			t.Errorf("access entry = %+v (%v), want status 500", e, err)
+		}
+		errs := r.RecentErrors()
+		if len(errs) != 1 || !strings.Contains(errs[0].Stack, "TestRecover") {
+			t.Errorf("recorded errors = %+v, want the stack caught by Recover", errs)
+		}
+	})
+
+	// One log entry with the stack, not a second one from the server
+	if n := strings.Count(out, "panic serving /boom: recovered boom"); n != 1 {
+		t.Errorf("panic logged %d times, want once:\n%s", n, out)
+	}
+	if !strings.Contains(out, "recover_test.go") {
+		t.Errorf("log lacks the stack of the handler:\n%s", out)
+	}
+}
+
+func TestRecoverOutsideChain(t *testing.T) {
+	// A middleware outside Recover that panics is still answered by the server
+	r := newTestRouter()
+	r.Use(Recover())
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			if req.Headers.Get("X-Fail") != "" {
+				panic("middleware boom")
+			}
+			next(req, w)
+		}
+	})
+	r.Get("/ok", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	addr := serveRouter(t, r)
+
+	raw := "GET /ok HTTP/1.1\r\nHost: x\r\nX-Fail: 1\r\n\r\nGET /ok HTTP/1.1\r\nHost: x\r\n\r\n"
+	resps := rawExchange(t, addr, raw, 2)
+	if resps[0].StatusCode != 500 || resps[1].StatusCode != 200 {
+		t.Errorf("statuses = %d, %d; want 500 then 200 on the same connection", resps[0].StatusCode, resps[1].StatusCode)
+	}
+}
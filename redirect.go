@@ -0,0 +1,161 @@
+package gouter
+
+import (
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+)
+
+// RedirectTrailingSlash redirects requests whose path only differs from
+// their route by a trailing slash, e.g. /users/ to /users, instead of
+// serving them as the route. Wildcard routes keep the slash as part of the
+// wildcard. Disabled by default
+func (r *Router) RedirectTrailingSlash(on bool) {
+	r.redirectSlash = on
+}
+
+// RedirectFixedPath redirects requests whose path only matches a route once
+// cleaned (repeated slashes, "." and ".." segments) or compared without case,
+// e.g. //Users/../users to /users. Disabled by default: repeated slashes
+// are then served as the route, the other paths answer 404
+func (r *Router) RedirectFixedPath(on bool) {
+	r.redirectFixed = on
+}
+
+// redirectHandler answers a redirect to target, keeping the query
+// GET and HEAD get 301; other methods get 308, which tells the client to
+// repeat the method and body
+func redirectHandler(target string) Handler {
+	return func(r *Request, w *Writer) {
+		code := http.StatusMovedPermanently
+		if r.Method != "GET" && r.Method != "HEAD" {
+			code = http.StatusPermanentRedirect
+		}
+		if r.rawQuery != "" {
+			target += "?" + r.rawQuery
+		}
+		w.SetHeader("Location", target)
+		w.WriteHeader(code)
+	}
+}
+
+// canonicalPath returns the path of a request as spelled by its route:
+// single leading slash, trailing slash only if the pattern has one
+// Paths matching a wildcard are returned with their trailing slash as sent
+func canonicalPath(reqPath, pattern string) string {
+	rest := strings.Trim(reqPath, "/")
+	slash := strings.HasSuffix(pattern, "/")
+	if _, _, ok := splitCatchAll(pattern); ok {
+		slash = strings.HasSuffix(reqPath, "/")
+	}
+	if rest != "" && slash {
+		return "/" + rest + "/"
+	}
+	return "/" + rest
+}
+
+// redirectFor returns the redirect of a request matching pattern through the
+// tree, or nil when it is served as is
+func (r *Router) redirectFor(reqPath, pattern string) Handler {
+	top := r.docsOwner()
+	target := canonicalPath(reqPath, pattern)
+	if target == reqPath {
+		return nil
+	}
+
+	// Only a trailing slash added or removed is a trailing slash redirect;
+	// anything else was a malformed path the tree tolerated
+	if strings.TrimSuffix(target, "/") == strings.TrimSuffix(reqPath, "/") {
+		if top.redirectSlash {
+			return redirectHandler(target)
+		}
+		return nil
+	}
+	if top.redirectFixed {
+		return redirectHandler(target)
+	}
+	return nil
+}
+
+// fixedPathRedirect returns a redirect to the route a request matches once
+// its path is cleaned or compared without case, or nil
+func (r *Router) fixedPathRedirect(reqPath string) Handler {
+	if !r.docsOwner().redirectFixed {
+		return nil
+	}
+
+	cleaned := path.Clean("/" + reqPath)
+	if strings.HasSuffix(reqPath, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	if p := r.tree.lookup(cleaned, make(map[string]string)); p != "" {
+		return r.redirectOrSelf(cleaned, p)
+	}
+	if fixed, ok := r.tree.fixCase(cleaned); ok {
+		return r.redirectOrSelf(fixed, r.tree.lookup(fixed, make(map[string]string)))
+	}
+	return nil
+}
+
+// redirectOrSelf redirects to fixed, with the trailing slash of pattern when
+// RedirectTrailingSlash is on
+func (r *Router) redirectOrSelf(fixed, pattern string) Handler {
+	if r.docsOwner().redirectSlash {
+		fixed = canonicalPath(fixed, pattern)
+	}
+	return redirectHandler(fixed)
+}
+
+// fixCase returns path with its literal segments spelled as registered when
+// it only matches a route without case; parameter values are kept as sent
+func (n *routeNode) fixCase(path string) (string, bool) {
+	rest := strings.Trim(path, "/")
+	segs, ok := n.matchFold(rest, rest == "", nil)
+	if !ok {
+		return "", false
+	}
+	fixed := "/" + strings.Join(segs, "/")
+	if strings.HasSuffix(path, "/") && fixed != "/" {
+		fixed += "/"
+	}
+	return fixed, true
+}
+
+// matchFold walks the tree like match, comparing literal segments without
+// case, and returns the segments of the matching path
+func (n *routeNode) matchFold(rest string, end bool, segs []string) ([]string, bool) {
+	if end {
+		return segs, n.route != "" || n.catchAll != ""
+	}
+
+	seg, next, found := strings.Cut(rest, "/")
+	if c := n.static[seg]; c != nil {
+		if s, ok := c.matchFold(next, !found, append(segs, seg)); ok {
+			return s, true
+		}
+	}
+	// Sorted so the fix does not depend on map order when several
+	// spellings of a segment are registered
+	for _, key := range slices.Sorted(maps.Keys(n.static)) {
+		if key == seg || !strings.EqualFold(key, seg) {
+			continue
+		}
+		if s, ok := n.static[key].matchFold(next, !found, append(segs, key)); ok {
+			return s, true
+		}
+	}
+	for _, e := range n.params {
+		if !matchSegment(e.tokens, seg, make(map[string]string)) {
+			continue
+		}
+		if s, ok := e.node.matchFold(next, !found, append(segs, seg)); ok {
+			return s, true
+		}
+	}
+	if n.catchAll != "" {
+		return append(segs, rest), true
+	}
+	return nil, false
+}
@@ -0,0 +1,90 @@
+package gouter
+
+import "testing"
+
+// slashRouter serves a route without and one with a trailing slash, a
+// parameter route and a wildcard
+func slashRouter(t *testing.T, slash, fixed bool) string {
+	r := newTestRouter()
+	r.RedirectTrailingSlash(slash)
+	r.RedirectFixedPath(fixed)
+	r.Route("/users", func(req *Request, w *Writer) { w.Write([]byte("users")) }, "GET", "POST")
+	r.Get("/dir/", func(req *Request, w *Writer) { w.Write([]byte("dir")) })
+	r.Get("/users/:name", func(req *Request, w *Writer) { w.Write([]byte("user " + req.Params.Get("name"))) })
+	r.Get("/files/*", func(req *Request, w *Writer) { w.Write([]byte("files")) })
+	return serveRouter(t, r)
+}
+
+// redirectCase is a request and the answer it should get: a redirect when
+// location is set, the body otherwise
+type redirectCase struct {
+	method, target string
+	code           int
+	location, body string
+}
+
+func checkRedirects(t *testing.T, addr string, cases []redirectCase) {
+	t.Helper()
+	for _, c := range cases {
+		raw := c.method + " " + c.target + " HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n"
+		resp := rawExchange(t, addr, raw, 1)[0]
+		body := bodyString(t, resp)
+		if resp.StatusCode != c.code || resp.Header.Get("Location") != c.location || (c.body != "" && body != c.body) {
+			t.Errorf("%s %s = %d Location %q body %q; want %d %q %q", c.method, c.target, resp.StatusCode, resp.Header.Get("Location"), body, c.code, c.location, c.body)
+		}
+	}
+}
+
+func TestRedirectTrailingSlash(t *testing.T) {
+	checkRedirects(t, slashRouter(t, true, false), []redirectCase{
+		{"GET", "/users", 200, "", "users"},
+		{"GET", "/users/", 301, "/users", ""},
+		{"HEAD", "/users/", 301, "/users", ""},
+		// Other methods must be repeated with their body
+		{"POST", "/users/", 308, "/users", ""},
+		{"GET", "/users/?page=2&sort=name", 301, "/users?page=2&sort=name", ""},
+		{"GET", "/dir", 301, "/dir/", ""},
+		{"GET", "/users/bob/", 301, "/users/bob", ""},
+		// A wildcard keeps the slash as part of its value
+		{"GET", "/files/a/", 200, "", "files"},
+		// Other fixes are left to RedirectFixedPath
+		{"GET", "//users", 200, "", "users"},
+		{"GET", "/USERS", 404, "", ""},
+	})
+}
+
+func TestRedirectFixedPath(t *testing.T) {
+	checkRedirects(t, slashRouter(t, false, true), []redirectCase{
+		{"GET", "//users", 301, "/users", ""},
+		{"GET", "/./users", 301, "/users", ""},
+		{"GET", "/dir/../users", 301, "/users", ""},
+		{"GET", "/USERS", 301, "/users", ""},
+		// Parameter values keep their case
+		{"GET", "/Users/Bob", 301, "/users/Bob", ""},
+		{"POST", "/Users?x=1", 308, "/users?x=1", ""},
+		{"GET", "//files/a", 301, "/files/a", ""},
+		// The trailing slash is kept as sent without RedirectTrailingSlash
+		{"GET", "/USERS/", 301, "/users/", ""},
+		{"GET", "/nothing/../here", 404, "", ""},
+	})
+}
+
+func TestRedirectBoth(t *testing.T) {
+	// One hop to the canonical path, not one redirect per fix
+	checkRedirects(t, slashRouter(t, true, true), []redirectCase{
+		{"GET", "/USERS/", 301, "/users", ""},
+		{"GET", "//DIR", 301, "/dir/", ""},
+		{"GET", "/users//bob/", 301, "/users/bob", ""},
+	})
+}
+
+func TestRedirectOffByDefault(t *testing.T) {
+	checkRedirects(t, slashRouter(t, false, false), []redirectCase{
+		// The tree serves the route for a trailing slash without redirecting
+		{"GET", "/users/", 200, "", "users"},
+		{"GET", "/dir", 200, "", "dir"},
+		{"GET", "/USERS", 404, "", ""},
+		{"GET", "/dir/../users", 404, "", ""},
+		{"GET", "//users", 200, "", "users"},
+	})
+}
@@ -0,0 +1,137 @@
+package gouter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// reloadState holds what Server.Reload re-reads
+type reloadState struct {
+	mu       sync.Mutex // Serializes reloads and hook registration
+	certFile string
+	keyFile  string
+	cert     atomic.Pointer[tls.Certificate] // Served to new TLS handshakes
+	hooks    []func() error
+}
+
+// setCertificate records the files of the served certificate
+func (s *reloadState) setCertificate(certFile, keyFile string, cert *tls.Certificate) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certFile, s.keyFile = certFile, keyFile
+	s.cert.Store(cert)
+}
+
+// getCertificate implements tls.Config.GetCertificate
+func (s *reloadState) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := s.cert.Load()
+	if cert == nil {
+		return nil, errors.New("no certificate loaded")
+	}
+	return cert, nil
+}
+
+// OnReload registers a hook run by Reload, in registration order
+// Hooks should validate before changing state: they run after the built-in
+// checks passed, and any hook error makes Reload keep the old certificate
+func (s *Server) OnReload(hook func() error) {
+	s.reload.mu.Lock()
+	defer s.reload.mu.Unlock()
+	s.reload.hooks = append(s.reload.hooks, hook)
+}
+
+// Reload re-reads the server configuration from disk
+//
+// Steps:
+//   - Loads the certificate and key given to ListenAndServeTLS again
+//   - Checks that static roots (ServerStatic, Router.File) still exist;
+//     small files served by Router.File keep their registration-time content
+//   - Runs the OnReload hooks
+//
+// The new certificate is only applied when every step succeeds, and only
+// affects new connections. Returns the joined errors of the failed steps
+func (s *Server) Reload() error {
+	s.reload.mu.Lock()
+	defer s.reload.mu.Unlock()
+
+	var (
+		errs  []error
+		cert  *tls.Certificate
+		roots []string
+	)
+
+	if s.reload.certFile != "" {
+		c, err := tls.LoadX509KeyPair(s.reload.certFile, s.reload.keyFile)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load TLS certificate: %w", err))
+		} else {
+			cert = &c
+		}
+	}
+
+	if s.Router != nil {
+		roots = s.Router.staticRoots
+	}
+	for _, root := range roots {
+		if _, err := os.Stat(root); err != nil {
+			errs = append(errs, fmt.Errorf("static root unavailable: %w", err))
+		}
+	}
+
+	// Hooks only run once the built-in checks passed
+	if len(errs) == 0 {
+		for i, hook := range s.reload.hooks {
+			if err := hook(); err != nil {
+				errs = append(errs, fmt.Errorf("reload hook #%d: %w", i+1, err))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		err := errors.Join(errs...)
+		log.Error(fmt.Errorf("reload refused: %w", err))
+		return err
+	}
+
+	if cert != nil {
+		s.reload.cert.Store(cert)
+	}
+	log.Info("Reloaded: certificate", cert != nil, "static roots", len(roots), "hooks", len(s.reload.hooks))
+	return nil
+}
+
+// EnableSIGHUPReload calls Reload whenever the process receives SIGHUP
+// On platforms without SIGHUP it has no effect; call Reload directly
+// Returns a function that stops listening for the signal
+func (s *Server) EnableSIGHUPReload() (stop func()) {
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, syscall.SIGHUP)
+
+	spawn("sighup", func() {
+		for {
+			select {
+			case <-ch:
+				s.Reload()
+			case <-done:
+				return
+			}
+		}
+	})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package gouter
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for commonName to certFile and keyFile
+func writeCert(t *testing.T, certFile, keyFile, commonName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveTLSTest runs s.ListenAndServeTLS on a free port until the test ends
+// Returns the address once it accepts connections
+func serveTLSTest(t *testing.T, s *Server, certFile, keyFile string) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Addr = l.Addr().String()
+	l.Close()
+
+	go s.ListenAndServeTLS(certFile, keyFile)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		s.Shutdown(ctx)
+	})
+	eventually(t, "the TLS listener", func() bool {
+		c, err := net.Dial("tcp", s.Addr)
+		if err == nil {
+			c.Close()
+		}
+		return err == nil
+	})
+	return s.Addr
+}
+
+// presentedName dials addr and returns the common name of the served certificate
+func presentedName(t *testing.T, addr string) (*tls.Conn, string) {
+	t.Helper()
+	c, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, c.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+// getOverTLS sends a keep-alive GET / on c and returns the status
+func getOverTLS(t *testing.T, c *tls.Conn, br *bufio.Reader) int {
+	t.Helper()
+	c.SetDeadline(time.Now().Add(3 * time.Second))
+	if _, err := c.Write([]byte("GET / HTTP/1.1\r\nHost: x\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bodyString(t, resp)
+	return resp.StatusCode
+}
+
+func TestReloadSwapsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "first")
+
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	s := &Server{Router: r}
+	addr := serveTLSTest(t, s, certFile, keyFile)
+
+	old, name := presentedName(t, addr)
+	if name != "first" {
+		t.Fatalf("initial certificate %q, want first", name)
+	}
+	oldReader := bufio.NewReader(old)
+	if code := getOverTLS(t, old, oldReader); code != 200 {
+		t.Fatalf("status %d, want 200", code)
+	}
+
+	writeCert(t, certFile, keyFile, "second")
+	if err := s.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, name := presentedName(t, addr); name != "second" {
+		t.Errorf("new connection after Reload presents %q, want second", name)
+	}
+	if code := getOverTLS(t, old, oldReader); code != 200 {
+		t.Errorf("existing connection after Reload: status %d, want 200", code)
+	}
+	if name := old.ConnectionState().PeerCertificates[0].Subject.CommonName; name != "first" {
+		t.Errorf("existing connection now reports %q, want first", name)
+	}
+}
+
+func TestReloadRefusesPartialFailure(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeCert(t, certFile, keyFile, "first")
+	static := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(static, []byte("page"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTestRouter()
+	if _, err := r.File("/page", static); err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{Router: r}
+	var calls []string
+	hookErr := errors.New("config invalid")
+	failHook := false
+	s.OnReload(func() error {
+		calls = append(calls, "first")
+		return nil
+	})
+	s.OnReload(func() error {
+		calls = append(calls, "second")
+		if failHook {
+			return hookErr
+		}
+		return nil
+	})
+	addr := serveTLSTest(t, s, certFile, keyFile)
+
+	// A broken certificate is refused and the hooks do not run
+	os.WriteFile(certFile, []byte("not a certificate"), 0o600)
+	if err := s.Reload(); err == nil || !strings.Contains(err.Error(), "TLS certificate") {
+		t.Errorf("Reload with a broken certificate = %v, want a certificate error", err)
+	}
+	if len(calls) != 0 {
+		t.Errorf("hooks ran after a failed check: %v", calls)
+	}
+
+	// A failing hook keeps the old certificate even though the new one loads
+	writeCert(t, certFile, keyFile, "second")
+	failHook = true
+	if err := s.Reload(); !errors.Is(err, hookErr) {
+		t.Errorf("Reload with a failing hook = %v, want the hook error", err)
+	}
+	if strings.Join(calls, ",") != "first,second" {
+		t.Errorf("hooks ran as %v, want registration order", calls)
+	}
+	if _, name := presentedName(t, addr); name != "first" {
+		t.Errorf("certificate after a refused reload = %q, want first", name)
+	}
+
+	// A missing static root is refused as well
+	failHook = false
+	os.Remove(static)
+	if err := s.Reload(); err == nil || !strings.Contains(err.Error(), "static root") {
+		t.Errorf("Reload with a missing static root = %v, want a static root error", err)
+	}
+	if _, name := presentedName(t, addr); name != "first" {
+		t.Errorf("certificate after a refused reload = %q, want first", name)
+	}
+}
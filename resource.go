@@ -0,0 +1,231 @@
+package gouter
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// Controller actions detected by Resource; a controller implements any subset
+type (
+	// ResourceIndexer lists the collection: GET /things
+	ResourceIndexer interface{ Index(r *Request, w *Writer) }
+	// ResourceShower shows one member: GET /things/:id
+	ResourceShower interface{ Show(r *Request, w *Writer) }
+	// ResourceCreator creates a member: POST /things
+	ResourceCreator interface{ Create(r *Request, w *Writer) }
+	// ResourceUpdater updates a member: PUT or PATCH /things/:id
+	ResourceUpdater interface{ Update(r *Request, w *Writer) }
+	// ResourceDeleter deletes a member: DELETE /things/:id
+	ResourceDeleter interface{ Delete(r *Request, w *Writer) }
+)
+
+// Resource holds the docs entries of the actions registered by Router.Resource
+// Entries of actions the controller does not implement are nil
+type Resource struct {
+	Index  *RouteInfo
+	Show   *RouteInfo
+	Create *RouteInfo
+	Update *RouteInfo // PUT entry; PATCH requests share its guards and settings
+	Delete *RouteInfo
+}
+
+// resourceAction is one controller method bound to its docs entry
+type resourceAction struct {
+	handler     Handler
+	info        **RouteInfo // Entry of the action, filled once the path is registered
+	description string      // Docs description
+}
+
+// resourcePath dispatches the requests of one resource path by method
+type resourcePath struct {
+	actions map[string]resourceAction
+	allow   string // Allow header sent with 405 responses
+}
+
+// Resource registers the conventional CRUD routes of a controller
+// Args:
+//   - path: Collection path (e.g., "/users" or "/users/:uid/posts")
+//   - controller: Value implementing any of ResourceIndexer, ResourceShower,
+//     ResourceCreator, ResourceUpdater and ResourceDeleter
+//
+// Members are addressed by path+"/:id", so parameters of nested collections
+// need other names. Methods the controller lacks answer 405
+// Returns nil when nothing was registered
+func (r *Router) Resource(path string, controller any) *Resource {
+	location := callerLocation(1)
+	return registerResource(path, controller, func(p string, h Handler) (*RouteInfo, *Router) {
+		return r.addRoute(p, h, nil, location), r.docsOwner()
+	})
+}
+
+// Resource registers the CRUD routes of a controller within the group
+// See Router.Resource
+func (g *Group) Resource(path string, controller any) *Resource {
+	location := callerLocation(1)
+	return registerResource(joinRoutePath("", path), controller, func(p string, h Handler) (*RouteInfo, *Router) {
+		info := g.addRoute(p, h, nil, location)
+		if info == nil {
+			return nil, nil
+		}
+		return info, g.router.docsOwner()
+	})
+}
+
+// resourceAdder registers a path and reports the router owning its docs
+type resourceAdder func(path string, h Handler) (*RouteInfo, *Router)
+
+// registerResource detects the actions of controller and registers the
+// collection and member paths through add
+func registerResource(path string, controller any, add resourceAdder) *Resource {
+	path = strings.TrimRight(path, "/")
+	for _, part := range strings.Split(path, "/") {
+		if slices.Contains(segmentParams(part), "id") {
+			log.WarnE(4, "resource ["+path+"] already binds :id; name the parent parameter differently")
+			return nil
+		}
+	}
+
+	plural, noun := resourceNouns(path)
+	res := &Resource{}
+	collection := map[string]resourceAction{}
+	member := map[string]resourceAction{}
+
+	if c, ok := controller.(ResourceIndexer); ok {
+		collection["GET"] = resourceAction{c.Index, &res.Index, "List " + plural}
+	}
+	if c, ok := controller.(ResourceCreator); ok {
+		collection["POST"] = resourceAction{c.Create, &res.Create, "Create a " + noun}
+	}
+	if c, ok := controller.(ResourceShower); ok {
+		member["GET"] = resourceAction{c.Show, &res.Show, "Show a " + noun}
+	}
+	if c, ok := controller.(ResourceUpdater); ok {
+		member["PUT"] = resourceAction{c.Update, &res.Update, "Update a " + noun}
+		member["PATCH"] = resourceAction{c.Update, &res.Update, "Update a " + noun}
+	}
+	if c, ok := controller.(ResourceDeleter); ok {
+		member["DELETE"] = resourceAction{c.Delete, &res.Delete, "Delete a " + noun}
+	}
+
+	if len(collection) == 0 && len(member) == 0 {
+		log.WarnE(4, "resource ["+path+"] controller implements no action")
+		return nil
+	}
+
+	registered := registerResourcePath(path, collection, add, noun)
+	if registerResourcePath(path+"/:id", member, add, noun) {
+		registered = true
+	}
+	if !registered {
+		return nil
+	}
+	return res
+}
+
+// registerResourcePath registers one path dispatching to actions, with a
+// described docs entry per method
+func registerResourcePath(path string, actions map[string]resourceAction, add resourceAdder, noun string) bool {
+	if len(actions) == 0 {
+		return false
+	}
+
+	methods := make([]string, 0, len(actions)+1)
+	for m := range actions {
+		methods = append(methods, m)
+	}
+	slices.SortFunc(methods, compareResourceMethods)
+
+	allow := methods
+	if _, ok := actions["GET"]; ok {
+		allow = append([]string{"HEAD"}, methods...)
+		slices.SortFunc(allow, compareResourceMethods)
+	}
+	rp := &resourcePath{actions: make(map[string]resourceAction, len(actions)), allow: strings.Join(allow, ", ")}
+
+	info, owner := add(path, rp.serve)
+	if info == nil {
+		return false
+	}
+
+	// The registered entry documents the first method, the others get copies
+	for i, m := range methods {
+		doc := info
+		if i > 0 {
+			doc = &RouteInfo{
+				Path:        info.Path,
+				Host:        info.Host,
+				HandlerName: info.HandlerName,
+				Parameters:  slices.Clone(info.Parameters),
+				Middlewares: slices.Clone(info.Middlewares),
+				chain:       info.chain,
+			}
+			owner.docs = append(owner.docs, doc)
+			if c := info.chain; c != nil {
+				owner.chainMu.Lock()
+				c.docs = append(c.docs, doc)
+				owner.chainMu.Unlock()
+			}
+		}
+		a := actions[m]
+		doc.Method = m
+		doc.Description = a.description
+		doc.SetParam("id", "string", noun+" identifier")
+
+		// PATCH comes after PUT and shares its entry
+		if *a.info == nil {
+			*a.info = doc
+		}
+		rp.actions[m] = resourceAction{traceLayer("handler", withGuards(a.handler)), a.info, a.description}
+	}
+	return true
+}
+
+// serve runs the action of the request method, answering 405 when the
+// controller does not implement it
+func (rp *resourcePath) serve(r *Request, w *Writer) {
+	method := r.Method
+	if method == "HEAD" {
+		method = "GET"
+	}
+
+	a, ok := rp.actions[method]
+	if !ok {
+		w.SetHeader("Allow", rp.allow)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Guards and settings are read from the entry of the action
+	r.route = *a.info
+	a.handler(r, w)
+}
+
+// compareResourceMethods sorts methods the way CRUD tables usually list them
+func compareResourceMethods(a, b string) int {
+	const order = "GET HEAD POST PUT PATCH DELETE"
+	return strings.Index(order, a) - strings.Index(order, b)
+}
+
+// resourceNouns returns the collection name of a path and its singular
+// ("/users/:uid/posts" → "posts", "post")
+func resourceNouns(path string) (string, string) {
+	parts := strings.Split(path, "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		p := parts[i]
+		if p == "" || strings.ContainsAny(p, ":*") {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(p, "ies"):
+			return p, strings.TrimSuffix(p, "ies") + "y"
+		case strings.HasSuffix(p, "ss"):
+			return p, p
+		default:
+			return p, strings.TrimSuffix(p, "s")
+		}
+	}
+	return "resources", "resource"
+}
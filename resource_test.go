@@ -0,0 +1,117 @@
+package gouter
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// readOnlyUsers implements only the read actions
+type readOnlyUsers struct{}
+
+func (readOnlyUsers) Index(r *Request, w *Writer) { w.Write([]byte("users")) }
+func (readOnlyUsers) Show(r *Request, w *Writer)  { w.Write([]byte("user " + r.Params.Get("id"))) }
+
+// userPosts implements every action of a nested resource
+type userPosts struct{}
+
+func (userPosts) Index(r *Request, w *Writer) { w.Write([]byte("posts of " + r.Params.Get("uid"))) }
+func (userPosts) Show(r *Request, w *Writer) {
+	w.Write([]byte("post " + r.Params.Get("id") + " of " + r.Params.Get("uid")))
+}
+func (userPosts) Create(r *Request, w *Writer) {
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("created for " + r.Params.Get("uid")))
+}
+func (userPosts) Update(r *Request, w *Writer) {
+	w.Write([]byte(r.Method + " post " + r.Params.Get("id") + " of " + r.Params.Get("uid")))
+}
+func (userPosts) Delete(r *Request, w *Writer) { w.WriteHeader(http.StatusNoContent) }
+
+func TestResource(t *testing.T) {
+	r := newTestRouter()
+	users := r.Resource("/users", readOnlyUsers{})
+	posts := r.Resource("/users/:uid/posts/", userPosts{})
+	if users == nil || users.Index == nil || users.Show == nil || users.Create != nil || users.Update != nil || users.Delete != nil {
+		t.Fatalf("read-only resource = %+v, want only Index and Show", users)
+	}
+	if posts == nil || posts.Update == nil || posts.Update.Method != "PUT" {
+		t.Fatalf("nested resource = %+v, want every action with Update on PUT", posts)
+	}
+	addr := serveRouter(t, r)
+
+	cases := []struct {
+		method, path string
+		code         int
+		body, allow  string
+	}{
+		{"GET", "/users", 200, "users", ""},
+		{"GET", "/users/7", 200, "user 7", ""},
+		{"POST", "/users", 405, "", "GET, HEAD"},
+		{"PUT", "/users/7", 405, "", "GET, HEAD"},
+		{"DELETE", "/users/7", 405, "", "GET, HEAD"},
+		{"GET", "/users/3/posts", 200, "posts of 3", ""},
+		{"GET", "/users/3/posts/9", 200, "post 9 of 3", ""},
+		{"POST", "/users/3/posts", 201, "created for 3", ""},
+		{"PUT", "/users/3/posts/9", 200, "PUT post 9 of 3", ""},
+		{"PATCH", "/users/3/posts/9", 200, "PATCH post 9 of 3", ""},
+		{"DELETE", "/users/3/posts/9", 204, "", ""},
+		{"DELETE", "/users/3/posts", 405, "", "GET, HEAD, POST"},
+	}
+	for _, c := range cases {
+		resp := rawExchange(t, addr, c.method+" "+c.path+" HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n", 1)[0]
+		body := bodyString(t, resp)
+		if resp.StatusCode != c.code || (c.code != 405 && body != c.body) {
+			t.Errorf("%s %s = %d %q, want %d %q", c.method, c.path, resp.StatusCode, body, c.code, c.body)
+		}
+		if got := resp.Header.Get("Allow"); c.allow != "" && got != c.allow {
+			t.Errorf("%s %s: Allow = %q, want %q", c.method, c.path, got, c.allow)
+		}
+	}
+
+	var entries []string
+	params := map[string]string{}
+	for _, doc := range r.visibleDocs() {
+		entries = append(entries, doc.Method+" "+doc.Path+" "+doc.Description)
+		for _, p := range doc.Parameters {
+			params[doc.Method+" "+doc.Path+" "+p.Name] = p.Description
+		}
+	}
+	want := []string{
+		"GET /users List users",
+		"GET /users/:id Show a user",
+		"GET /users/:uid/posts List posts",
+		"POST /users/:uid/posts Create a post",
+		"GET /users/:uid/posts/:id Show a post",
+		"PUT /users/:uid/posts/:id Update a post",
+		"PATCH /users/:uid/posts/:id Update a post",
+		"DELETE /users/:uid/posts/:id Delete a post",
+	}
+	slices.Sort(entries)
+	slices.Sort(want)
+	if !slices.Equal(entries, want) {
+		t.Errorf("docs entries = %q, want %q", entries, want)
+	}
+	if params["DELETE /users/:uid/posts/:id id"] != "post identifier" {
+		t.Errorf("member parameters = %v, want id described on every method", params)
+	}
+}
+
+func TestResourceRejects(t *testing.T) {
+	r := newTestRouter()
+	if res := r.Resource("/posts/:id/comments", userPosts{}); res != nil {
+		t.Errorf("parent bound to :id registered %+v", res)
+	}
+	if res := r.Resource("/empty", struct{}{}); res != nil {
+		t.Errorf("controller without actions registered %+v", res)
+	}
+	if n := len(r.visibleDocs()); n != 0 {
+		t.Errorf("rejected resources left %d docs entries", n)
+	}
+
+	var res *Resource
+	r.Group("/api", func(g *Group) { res = g.Resource("/tags", readOnlyUsers{}) })
+	if res == nil || res.Show.Path != "/api/tags/:id" {
+		t.Errorf("group resource = %+v, want the group prefix", res)
+	}
+}
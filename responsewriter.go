@@ -0,0 +1,179 @@
+package gouter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// ResponseWriter is the response API of Writer, for code that produces or
+// inspects responses without depending on the concrete type (e.g., helpers
+// shared with a net/http adapter, or test fakes)
+// Handlers still receive *Writer. Middlewares wrap a buffered response by
+// calling next and then reading Status and Body, and replacing the body
+// with SetBody; a streamed response is gone by then, so to compress or
+// meter every response they install a body filter with FilterBody first
+type ResponseWriter interface {
+	Write(p []byte) (int, error)
+	WriteHeader(statusCode int)
+	SetHeader(key, value string) error
+	DelHeader(key string) error
+	Flush() error
+	Status() int
+	BytesWritten() int64
+	Body() []byte
+	SetBody(b []byte) error
+	FilterBody(wrap func(dst io.Writer) io.WriteCloser) error
+	Hijack() (net.Conn, *bufio.Reader, error)
+}
+
+// Status returns the response status, 200 when none was set yet
+func (w *Writer) Status() int {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status()
+}
+
+// BytesWritten returns the size of the body written so far, buffered or
+// already streamed; HEAD responses count none
+func (w *Writer) BytesWritten() int64 {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.bodyBytes()
+}
+
+// Body returns the buffered body, not yet sent to the client
+// It is empty once the response is streamed (see Flush). The slice is only
+// valid until the next write
+func (w *Writer) Body() []byte {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.body
+}
+
+// SetBody replaces the buffered body, e.g. with a compressed version; set
+// the matching headers (Content-Encoding, Content-Length) alongside
+// Returns ErrResponseDone once the headers were sent
+func (w *Writer) SetBody(b []byte) error {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done || w.headersSent || w.hijacked {
+		return ErrResponseDone
+	}
+	// Charged before the old body is released, like a write, since both are
+	// held while copying
+	if err := w.budget.charge(len(b)); err != nil {
+		return err
+	}
+	w.budget.release(len(w.body))
+	w.body = append(w.body[:0], b...)
+	return nil
+}
+
+// FilterBody passes the rest of the body through the writer returned by
+// wrap, e.g. a gzip.Writer, before it is buffered or streamed; dst takes
+// the filtered bytes and is only valid within the filter's Write, Close and
+// Flush (called by Writer.Flush when the filter has one). Filters are closed
+// once the handler returns, the last one installed first, as it sees the
+// handler's bytes first
+// The Content-Length of a filtered response is recomputed (or the body is
+// chunked), so set only the headers the filter changes. wrap is called with
+// the response locked and must not use the Writer. Returns ErrResponseDone
+// once the headers were sent or body bytes were written
+func (w *Writer) FilterBody(wrap func(dst io.Writer) io.WriteCloser) error {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done || w.headersSent || w.hijacked || len(w.body) > 0 {
+		return ErrResponseDone
+	}
+	var dst io.Writer = bodySink{w}
+	if n := len(w.filters); n > 0 {
+		dst = w.filters[n-1]
+	}
+	w.filters = append(w.filters, wrap(dst))
+	w.filtered = true
+	return nil
+}
+
+// bodySink is the destination of the innermost body filter
+type bodySink struct{ w *Writer }
+
+// Write buffers or streams filtered bytes; the filter runs with w.mu held
+func (s bodySink) Write(p []byte) (int, error) { return s.w.sendLocked(p) }
+
+// flushFilters flushes the body filters that can be, outermost first so
+// their output reaches the inner ones; the caller holds w.mu
+func (w *Writer) flushFilters() error {
+	for i := len(w.filters) - 1; i >= 0; i-- {
+		if f, ok := w.filters[i].(interface{ Flush() error }); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// closeFilters closes the body filters once the handler returned; the
+// caller holds w.mu. What they emit for a status without a body (e.g. an
+// empty gzip stream on a 304) is dropped
+func (w *Writer) closeFilters() {
+	if len(w.filters) == 0 || w.hijacked {
+		return
+	}
+	for i := len(w.filters) - 1; i >= 0; i-- {
+		if err := w.filters[i].Close(); err != nil {
+			log.Error(fmt.Errorf("response body filter: %w", err))
+		}
+	}
+	w.filters = nil
+	if !w.headersSent && !statusAllowsBody(w.code) {
+		w.budget.release(len(w.body))
+		w.body = w.body[:0]
+	}
+}
+
+// dropFilteredLength removes the Content-Length set by the handler once a
+// filter may have changed the body size; the caller holds w.mu
+func (w *Writer) dropFilteredLength() {
+	if w.filtered {
+		delete(w.Headers, "content-length")
+	}
+}
+
+// Hijack takes over the connection, for protocols other than HTTP
+// The reader holds the bytes the client sent after the request. Nothing of
+// the buffered response is sent, and the request deadlines are cleared.
+// The connection is closed once the handler returns, so serve it from
+// there. Returns ErrResponseDone when a response was already started
+func (w *Writer) Hijack() (net.Conn, *bufio.Reader, error) {
+	w.pool.checkReleased("Writer", 1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done || w.headersSent || w.hijacked {
+		return nil, nil, ErrResponseDone
+	}
+	if w.notifier != nil {
+		w.notifier.stop()
+	}
+	w.c.SetDeadline(time.Time{})
+	w.hijacked = true
+
+	br := w.br
+	if br == nil {
+		br = bufio.NewReader(w.c)
+	}
+	return w.c, br, nil
+}
@@ -0,0 +1,286 @@
+package gouter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var _ ResponseWriter = (*Writer)(nil)
+
+// sizeLog records the status and size a middleware saw after next
+type sizeLog struct {
+	status int
+	bytes  int64
+}
+
+func TestWriterStatusAndSize(t *testing.T) {
+	seen := make(chan sizeLog, 1)
+	r := newTestRouter()
+	r.Use(func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			next(req, w)
+			seen <- sizeLog{w.Status(), w.BytesWritten()}
+		}
+	})
+	r.Get("/default", func(req *Request, w *Writer) {
+		if w.Status() != 200 || w.BytesWritten() != 0 {
+			t.Errorf("fresh writer: status %d, %d bytes", w.Status(), w.BytesWritten())
+		}
+	})
+	r.Get("/created", func(req *Request, w *Writer) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("made"))
+		w.Write([]byte(" it"))
+	})
+	r.Get("/streamed", func(req *Request, w *Writer) {
+		w.Write([]byte("first "))
+		w.Flush()
+		if len(w.Body()) != 0 {
+			t.Errorf("Body after Flush = %q, want it sent", w.Body())
+		}
+		w.Write([]byte("second"))
+		w.Flush()
+	})
+	r.Get("/missing", func(req *Request, w *Writer) { w.WriteHeader(http.StatusNotFound) })
+	addr := serveRouter(t, r)
+
+	for _, tc := range []struct {
+		method, path string
+		want         sizeLog
+	}{
+		{"GET", "/default", sizeLog{200, 0}},
+		{"GET", "/created", sizeLog{201, 7}},
+		{"GET", "/streamed", sizeLog{200, 12}},
+		{"HEAD", "/created", sizeLog{201, 0}},
+		{"GET", "/missing", sizeLog{404, 0}},
+	} {
+		fetch(t, addr, tc.method, tc.path)
+		if got := <-seen; got != tc.want {
+			t.Errorf("%s %s: middleware saw %+v, want %+v", tc.method, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestWriterSetBody(t *testing.T) {
+	r := newTestRouter()
+	// Rewrites the body once the handler is done, as a compressor would
+	upper := func(next Handler) Handler {
+		return func(req *Request, w *Writer) {
+			next(req, w)
+			if err := w.SetBody(bytes.ToUpper(w.Body())); err != nil {
+				w.SetHeader("X-SetBody", err.Error())
+			}
+		}
+	}
+	r.Get("/text", func(req *Request, w *Writer) {
+		w.Write([]byte("quiet words"))
+	}).Use(upper)
+	r.Get("/streamed", func(req *Request, w *Writer) {
+		w.Write([]byte("sent"))
+		w.Flush()
+	}).Use(upper)
+	addr := serveRouter(t, r)
+
+	resp := rawExchange(t, addr, "GET /text HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := bodyString(t, resp); got != "QUIET WORDS" || resp.ContentLength != int64(len(got)) {
+		t.Errorf("rewritten body = %q, Content-Length %d", got, resp.ContentLength)
+	}
+	// The streamed response cannot change; SetBody says so
+	resp = rawExchange(t, addr, "GET /streamed HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := bodyString(t, resp); got != "sent" || resp.Header.Get("X-SetBody") != "" {
+		t.Errorf("streamed body = %q, headers %v", got, resp.Header)
+	}
+}
+
+func TestWriterHijack(t *testing.T) {
+	errs := make(chan error, 2)
+	r := newTestRouter()
+	r.Get("/hijack", func(req *Request, w *Writer) {
+		w.Write([]byte("never sent"))
+		c, br, err := w.Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, _, again := w.Hijack()
+		errs <- again
+		errs <- w.SetBody([]byte("x"))
+
+		// Bytes the client sent after the request are in br
+		line, _ := br.ReadString('\n')
+		io.WriteString(c, "echo: "+line)
+	})
+	r.Get("/flushed", func(req *Request, w *Writer) {
+		w.Flush()
+		_, _, err := w.Hijack()
+		w.Write([]byte(strconv.FormatBool(errors.Is(err, ErrResponseDone))))
+	})
+	addr := serveRouter(t, r)
+
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(c, "GET /hijack HTTP/1.1\r\nHost: x\r\n\r\nping\n")
+
+	// Only the hijacker writes, and the connection closes once it returns
+	got, err := io.ReadAll(c)
+	if err != nil || string(got) != "echo: ping\n" {
+		t.Errorf("hijacked connection = %q, %v", got, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-errs; !errors.Is(err, ErrResponseDone) {
+			t.Errorf("call after Hijack = %v, want ErrResponseDone", err)
+		}
+	}
+
+	resp := rawExchange(t, addr, "GET /flushed HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := bodyString(t, resp); !strings.HasSuffix(got, "true") {
+		t.Errorf("Hijack after Flush = %q, want ErrResponseDone", got)
+	}
+}
+
+// gzipBody installs a gzip body filter, as a compressing middleware would
+func gzipBody(next Handler) Handler {
+	return func(req *Request, w *Writer) {
+		w.SetHeader("Content-Encoding", "gzip")
+		w.FilterBody(func(dst io.Writer) io.WriteCloser { return gzip.NewWriter(dst) })
+		next(req, w)
+	}
+}
+
+// gunzip decodes a gzip response body
+func gunzip(t *testing.T, resp *http.Response) string {
+	t.Helper()
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip body: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip body: %v", err)
+	}
+	return string(b)
+}
+
+// prefixer writes a tag before the first bytes it filters
+type prefixer struct {
+	tag    string
+	dst    io.Writer
+	tagged bool
+}
+
+func (p *prefixer) Write(b []byte) (int, error) {
+	if !p.tagged {
+		p.tagged = true
+		if _, err := io.WriteString(p.dst, p.tag); err != nil {
+			return 0, err
+		}
+	}
+	return p.dst.Write(b)
+}
+
+func (p *prefixer) Close() error { return nil }
+
+func TestWriterFilterBody(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/buffered", func(req *Request, w *Writer) {
+		w.SetHeader("Content-Length", "11")
+		w.Write([]byte("quiet words"))
+	}).Use(gzipBody)
+	r.Get("/streamed", func(req *Request, w *Writer) {
+		w.Write([]byte("first "))
+		w.Flush()
+		w.Write([]byte("second"))
+	}).Use(gzipBody)
+	r.Get("/json", func(req *Request, w *Writer) {
+		w.WriteJson(map[string]int{"n": 1})
+	}).Use(gzipBody)
+	r.Get("/unchanged", func(req *Request, w *Writer) {
+		w.WriteHeader(http.StatusNotModified)
+	}).Use(gzipBody)
+	addr := serveRouter(t, r)
+
+	// The handler's Content-Length is replaced by the compressed size
+	resp := rawExchange(t, addr, "GET /buffered HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.ContentLength == 11 || resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Errorf("buffered: Content-Length %d, headers %v", resp.ContentLength, resp.Header)
+	}
+	if got := gunzip(t, resp); got != "quiet words" {
+		t.Errorf("buffered body = %q", got)
+	}
+
+	// Flush pushes what the compressor holds; the body is chunked
+	resp = rawExchange(t, addr, "GET /streamed HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if len(resp.TransferEncoding) == 0 || resp.TransferEncoding[0] != "chunked" {
+		t.Errorf("streamed: Transfer-Encoding %v, want chunked", resp.TransferEncoding)
+	}
+	if got := gunzip(t, resp); got != "first second" {
+		t.Errorf("streamed body = %q", got)
+	}
+
+	resp = rawExchange(t, addr, "GET /json HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if got := gunzip(t, resp); !strings.Contains(got, `"n":1`) {
+		t.Errorf("json body = %q", got)
+	}
+
+	// A status without a body gets none, not an empty gzip stream
+	resp = rawExchange(t, addr, "GET /unchanged HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != http.StatusNotModified || resp.ContentLength > 0 {
+		t.Errorf("304: status %d, Content-Length %d", resp.StatusCode, resp.ContentLength)
+	}
+}
+
+func TestWriterFilterBodyOrder(t *testing.T) {
+	metered := make(chan int64, 1)
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) {
+		w.FilterBody(func(dst io.Writer) io.WriteCloser { return &prefixer{tag: "<outer>", dst: dst} })
+		w.FilterBody(func(dst io.Writer) io.WriteCloser { return &prefixer{tag: "<inner>", dst: dst} })
+		w.Write([]byte("body"))
+		metered <- w.BytesWritten()
+		if err := w.FilterBody(func(dst io.Writer) io.WriteCloser { return &prefixer{tag: "<late>", dst: dst} }); !errors.Is(err, ErrResponseDone) {
+			t.Errorf("FilterBody after a write = %v, want ErrResponseDone", err)
+		}
+	})
+	addr := serveRouter(t, r)
+
+	// The last filter installed sees the handler's bytes first
+	resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	want := "<outer><inner>body"
+	if got := bodyString(t, resp); got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+	// BytesWritten counts what the filters emitted
+	if n := <-metered; n != int64(len(want)) {
+		t.Errorf("BytesWritten = %d, want %d", n, len(want))
+	}
+}
+
+func TestWriterSetBodyBudget(t *testing.T) {
+	setErr := make(chan error, 1)
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) {
+		w.Write([]byte("small"))
+		setErr <- w.SetBody(bytes.Repeat([]byte("x"), 8192))
+	})
+	addr := serveTest(t, &Server{Router: r, MaxMemoryPerRequest: 4096})
+
+	resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if err := <-setErr; !errors.Is(err, ErrMemoryBudget) {
+		t.Errorf("SetBody over the budget = %v, want ErrMemoryBudget", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", resp.StatusCode)
+	}
+}
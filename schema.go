@@ -0,0 +1,290 @@
+package gouter
+
+import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is the JSON schema of a body, in the OpenAPI 3.0 dialect
+type Schema struct {
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+
+	order []string // Property names in struct field order, for Fields
+}
+
+// BodyDoc documents the JSON body of a request or response
+type BodyDoc struct {
+	Status  int     // Response status, 0 for a request body
+	Schema  *Schema // nil for responses without a body
+	Example string  // The value given, encoded as indented JSON
+}
+
+// SchemaField is one row of the field table of a body in the docs
+type SchemaField struct {
+	Name        string // Dotted path, "[]" marking array items (e.g., "items[].id")
+	Type        string
+	Required    bool
+	Description string
+}
+
+// SetRequestBody documents the JSON body the route expects, from a value of
+// its Go type
+// Fields are named by their json tag; those with omitempty or of pointer
+// type are optional, and a `doc` tag describes them. A populated value
+// doubles as the example payload
+func (r *RouteInfo) SetRequestBody(v any) *RouteInfo {
+	r.RequestBody = newBodyDoc(0, v)
+	return r
+}
+
+// SetResponse documents a response status and its JSON body, read from v
+// like SetRequestBody; nil v documents a status without a body
+func (r *RouteInfo) SetResponse(code int, v any) *RouteInfo {
+	doc := *newBodyDoc(code, v)
+	for i := range r.Responses {
+		if r.Responses[i].Status == code {
+			r.Responses[i] = doc
+			return r
+		}
+	}
+	r.Responses = append(r.Responses, doc)
+	sort.Slice(r.Responses, func(i, j int) bool {
+		return r.Responses[i].Status < r.Responses[j].Status
+	})
+	return r
+}
+
+// newBodyDoc builds the schema and example of v
+func newBodyDoc(status int, v any) *BodyDoc {
+	doc := &BodyDoc{Status: status}
+	if v == nil {
+		return doc
+	}
+	doc.Schema = SchemaOf(v)
+
+	val := reflect.ValueOf(v)
+	// A nil pointer still shows the shape of its type
+	if val.Kind() == reflect.Pointer && val.IsNil() {
+		val = reflect.New(val.Type().Elem())
+	}
+	if b, err := json.MarshalIndent(val.Interface(), "", "  "); err == nil {
+		doc.Example = string(b)
+	}
+	return doc
+}
+
+// SchemaOf returns the JSON schema of the Go type of v
+func SchemaOf(v any) *Schema {
+	return schemaOf(reflect.TypeOf(v), make(map[reflect.Type]bool))
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage(nil))
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schemaOf builds the schema of t; seen holds the structs being expanded, so
+// recursive types stop at a plain object
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Pointer {
+		s := schemaOf(t.Elem(), seen)
+		s.Nullable = true
+		return s
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType, t.Implements(jsonMarshalerType):
+		// Encoded by its own code: any JSON value
+		return &Schema{}
+	case t.Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: schemaOf(t.Elem(), seen)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: schemaOf(t.Elem(), seen)}
+	case reflect.Struct:
+		if seen[t] {
+			return &Schema{Type: "object"}
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		addStructFields(s, t, seen, nil)
+		return s
+	}
+	return &Schema{}
+}
+
+// addStructFields adds the JSON fields of struct t to s, flattening
+// embedded structs without a json name like encoding/json does: the fields
+// of the outer struct hide the promoted ones of the same name, listed in
+// shadowed
+func addStructFields(s *Schema, t reflect.Type, seen map[reflect.Type]bool, shadowed map[string]bool) {
+	own := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := jsonFieldName(t.Field(i)); ok {
+			own[name] = true
+		}
+	}
+	for name := range shadowed {
+		own[name] = true
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		ft := f.Type
+		if f.Anonymous && name == "" {
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addStructFields(s, ft, seen, own)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if shadowed[name] {
+			continue
+		}
+
+		fs := schemaOf(ft, seen)
+		if strings.Contains(opts, "string") && fs.Type != "object" && fs.Type != "array" {
+			fs = &Schema{Type: "string", Nullable: fs.Nullable}
+		}
+		fs.Description = f.Tag.Get("doc")
+		if _, dup := s.Properties[name]; !dup {
+			s.order = append(s.order, name)
+		}
+		s.Properties[name] = fs
+		if !strings.Contains(opts, "omitempty") && ft.Kind() != reflect.Pointer {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// jsonFieldName returns the JSON name of a field that is not flattened
+// into its struct; ok is false for skipped and flattened fields
+func jsonFieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	name, _, _ := strings.Cut(tag, ",")
+	if tag == "-" || !f.IsExported() {
+		return "", false
+	}
+	if f.Anonymous && name == "" {
+		ft := f.Type
+		if ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		if ft.Kind() == reflect.Struct {
+			return "", false
+		}
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// typeName describes s in a field table (e.g., "string (date-time)", "array of integer")
+func (s *Schema) typeName() string {
+	switch {
+	case s == nil || s.Type == "":
+		return "any"
+	case s.Type == "array":
+		return "array of " + s.Items.typeName()
+	case s.Type == "object" && s.AdditionalProperties != nil:
+		return "map of " + s.AdditionalProperties.typeName()
+	case s.Format != "":
+		return s.Type + " (" + s.Format + ")"
+	}
+	return s.Type
+}
+
+// Fields lists the fields of the schema for the docs, nested ones under
+// their dotted path
+func (s *Schema) Fields() []SchemaField {
+	var fields []SchemaField
+	s.appendFields(&fields, "", 0)
+	return fields
+}
+
+// maxSchemaFieldDepth bounds how deep Fields descends into nested objects
+const maxSchemaFieldDepth = 4
+
+func (s *Schema) appendFields(fields *[]SchemaField, prefix string, depth int) {
+	if s == nil || depth > maxSchemaFieldDepth {
+		return
+	}
+	if s.Type == "array" {
+		s.Items.appendFields(fields, prefix+"[]", depth+1)
+		return
+	}
+
+	for _, name := range s.order {
+		p := s.Properties[name]
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		*fields = append(*fields, SchemaField{
+			Name:        path,
+			Type:        p.typeName(),
+			Required:    slices.Contains(s.Required, name),
+			Description: p.Description,
+		})
+		p.appendFields(fields, path, depth+1)
+	}
+}
+
+// StatusText returns the reason phrase of the documented status
+func (b BodyDoc) StatusText() string {
+	return http.StatusText(b.Status)
+}
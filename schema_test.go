@@ -0,0 +1,212 @@
+package gouter
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaAudit struct {
+	CreatedBy string    `json:"created_by" doc:"Who created it"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type schemaTag struct {
+	Name string `json:"name"`
+}
+
+type schemaNode struct {
+	Value    int           `json:"value"`
+	Children []*schemaNode `json:"children,omitempty"`
+}
+
+type schemaOrder struct {
+	ID       int64           `json:"id,string" doc:"Order number"`
+	Item     string          `json:"item"`
+	Count    int             `json:"count,omitempty"`
+	Price    float64         `json:"price"`
+	Paid     bool            `json:"paid"`
+	Note     *string         `json:"note"`
+	Blob     []byte          `json:"blob,omitempty"`
+	Raw      json.RawMessage `json:"raw,omitempty"`
+	Tags     []schemaTag     `json:"tags"`
+	Meta     map[string]int  `json:"meta"`
+	Tree     schemaNode      `json:"tree"`
+	Ignored  string          `json:"-"`
+	Untagged string
+	internal string
+	Any      any               `json:"any,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	// The outer field hides the promoted one of the same name
+	CreatedBy int `json:"created_by,omitempty"`
+	schemaAudit
+}
+
+func TestSchemaOf(t *testing.T) {
+	s := SchemaOf(schemaOrder{})
+	if s.Type != "object" {
+		t.Fatalf("type = %q", s.Type)
+	}
+	types := map[string]string{}
+	for name, p := range s.Properties {
+		types[name] = p.typeName()
+	}
+	want := map[string]string{
+		"id":         "string",
+		"item":       "string",
+		"count":      "integer",
+		"price":      "number",
+		"paid":       "boolean",
+		"note":       "string",
+		"blob":       "string (byte)",
+		"raw":        "any",
+		"tags":       "array of object",
+		"meta":       "map of integer",
+		"tree":       "object",
+		"Untagged":   "string",
+		"any":        "any",
+		"labels":     "map of string",
+		"created_by": "integer",
+		"created_at": "string (date-time)",
+	}
+	if !reflect.DeepEqual(types, want) {
+		t.Errorf("property types = %v, want %v", types, want)
+	}
+	if !s.Properties["note"].Nullable || s.Properties["id"].Description != "Order number" {
+		t.Errorf("note nullable %v, id description %q", s.Properties["note"].Nullable, s.Properties["id"].Description)
+	}
+	// Optional: omitempty and pointers; the outer created_by is omitempty
+	wantRequired := []string{"id", "item", "price", "paid", "tags", "meta", "tree", "Untagged", "created_at"}
+	if !reflect.DeepEqual(s.Required, wantRequired) {
+		t.Errorf("required = %v, want %v", s.Required, wantRequired)
+	}
+
+	// The schema agrees with what encoding/json produces
+	b, _ := json.Marshal(schemaOrder{CreatedBy: 7, schemaAudit: schemaAudit{CreatedBy: "hidden"}})
+	var encoded map[string]any
+	json.Unmarshal(b, &encoded)
+	for name := range encoded {
+		if s.Properties[name] == nil {
+			t.Errorf("encoded field %q missing from the schema", name)
+		}
+	}
+	if encoded["created_by"] != float64(7) {
+		t.Errorf("created_by encoded as %v", encoded["created_by"])
+	}
+
+	// Recursive types stop at a plain object
+	children := s.Properties["tree"].Properties["children"]
+	if children.Type != "array" || children.Items.Type != "object" || children.Items.Properties != nil || !children.Items.Nullable {
+		t.Errorf("recursive children = %+v", children)
+	}
+}
+
+func TestSchemaFields(t *testing.T) {
+	fields := SchemaOf([]schemaOrder{}).Fields()
+	rows := map[string]SchemaField{}
+	var order []string
+	for _, f := range fields {
+		rows[f.Name] = f
+		order = append(order, f.Name)
+	}
+	// Fields follow the struct order, nested ones under their path
+	if order[0] != "[].id" || order[1] != "[].item" {
+		t.Errorf("first fields = %v", order[:2])
+	}
+	for name, typ := range map[string]string{
+		"[].tags[].name":       "string",
+		"[].tree.children":     "array of object",
+		"[].tree.value":        "integer",
+		"[].created_at":        "string (date-time)",
+		"[].meta":              "map of integer",
+		"[].tree.children[]":   "",
+		"[].created_by":        "integer",
+		"[].id":                "string",
+		"[].tags":              "array of object",
+		"[].note":              "string",
+		"[].tree":              "object",
+		"[].blob":              "string (byte)",
+		"[].raw":               "any",
+		"[].labels":            "map of string",
+		"[].Untagged":          "string",
+		"[].any":               "any",
+		"[].paid":              "boolean",
+		"[].price":             "number",
+		"[].count":             "integer",
+		"[].item":              "string",
+		"[].tree.children[].x": "",
+	} {
+		f, ok := rows[name]
+		if typ == "" {
+			if ok {
+				t.Errorf("field %q listed", name)
+			}
+			continue
+		}
+		if !ok || f.Type != typ {
+			t.Errorf("field %q = %+v, want type %q", name, f, typ)
+		}
+	}
+	if !rows["[].item"].Required || rows["[].note"].Required || rows["[].id"].Description != "Order number" {
+		t.Errorf("item %+v, note %+v, id %+v", rows["[].item"], rows["[].note"], rows["[].id"])
+	}
+}
+
+func TestBodyDocs(t *testing.T) {
+	r := newTestRouter()
+	route := r.Post("/orders", func(req *Request, w *Writer) {}).
+		SetRequestBody(schemaTag{Name: "first"}).
+		SetResponse(500, nil).
+		SetResponse(201, (*schemaTag)(nil)).
+		SetResponse(200, schemaTag{}).
+		SetResponse(201, schemaTag{Name: "created"})
+
+	if route.RequestBody.Status != 0 || route.RequestBody.Example != "{\n  \"name\": \"first\"\n}" {
+		t.Errorf("request body = %+v", route.RequestBody)
+	}
+	var statuses []int
+	for _, b := range route.Responses {
+		statuses = append(statuses, b.Status)
+	}
+	if !reflect.DeepEqual(statuses, []int{200, 201, 500}) {
+		t.Errorf("response statuses = %v, want sorted and replaced", statuses)
+	}
+	if created := route.Responses[1]; !strings.Contains(created.Example, "created") || created.StatusText() != "Created" {
+		t.Errorf("201 = %+v, want the later declaration", created)
+	}
+	if route.Responses[2].Schema != nil || route.Responses[2].Example != "" {
+		t.Errorf("500 without a body = %+v", route.Responses[2])
+	}
+
+	// A nil pointer still documents its type, with a zero example
+	doc := newBodyDoc(200, (*schemaTag)(nil))
+	if doc.Schema.Properties["name"] == nil || doc.Example != "{\n  \"name\": \"\"\n}" {
+		t.Errorf("nil pointer body = %+v", doc)
+	}
+}
+
+func TestBodyDocsPage(t *testing.T) {
+	r := docsRouter(0, func(d *Doc) {})
+	r.Post("/orders", func(req *Request, w *Writer) {}).
+		SetRequestBody(schemaOrder{Item: "book"}).
+		SetResponse(201, schemaTag{}).
+		SetResponse(204, nil)
+	r.Post("/raw", func(req *Request, w *Writer) {}).SetRequestBody(nil)
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := r.DocsAddr()
+
+	resp := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	page := bodyString(t, resp)
+	for _, want := range []string{"Request Body", "tags[].name", "string (date-time)", "Order number", "&#34;item&#34;: &#34;book&#34;", "201 Created", "204 No Content"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("docs page lacks %q", want)
+		}
+	}
+	if resp.StatusCode != 200 || !strings.HasSuffix(strings.TrimSpace(page), "</html>") {
+		t.Errorf("docs page = %d, ends %q", resp.StatusCode, page[max(0, len(page)-40):])
+	}
+}
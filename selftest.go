@@ -0,0 +1,97 @@
+package gouter
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// SelfTestResult records the outcome of one self-test request
+type SelfTestResult struct {
+	Path    string        // Requested path
+	Status  int           // Response status code, 0 if no response was read
+	Latency time.Duration // Time taken by the round trip
+	Err     error         // Transport error or failure reason
+}
+
+// selfTest stores the startup self-test configuration and last results
+type selfTest struct {
+	mu      sync.Mutex
+	paths   []string
+	results []SelfTestResult
+}
+
+// SelfTestOnStart enables a self-test of the given GET routes when serving starts
+// Serve, Run and RunTLS return the self-test error instead of accepting traffic
+func (r *Router) SelfTestOnStart(paths ...string) {
+	r.selfTest.mu.Lock()
+	defer r.selfTest.mu.Unlock()
+	r.selfTest.paths = append(r.selfTest.paths, paths...)
+}
+
+// SelfTestResults returns the results of the last self-test run
+// Readiness checks can use it to report why an instance is not ready
+func (r *Router) SelfTestResults() []SelfTestResult {
+	r.selfTest.mu.Lock()
+	defer r.selfTest.mu.Unlock()
+	return append([]SelfTestResult(nil), r.selfTest.results...)
+}
+
+// SelfTest sends a GET request to each path through an in-memory connection
+// Returns an error listing every path that answered 5xx, panicked or failed to
+// respond; results are logged and kept for SelfTestResults
+func (r *Router) SelfTest(paths ...string) error {
+	results := make([]SelfTestResult, 0, len(paths))
+	var errs []error
+
+	for _, path := range paths {
+		start := time.Now()
+		resp, _, err := r.record(buildRequest("GET", path, nil, nil))
+		result := SelfTestResult{
+			Path:    path,
+			Latency: time.Since(start),
+			Err:     err,
+		}
+
+		if resp != nil {
+			result.Status = resp.StatusCode
+			if resp.StatusCode >= 500 && result.Err == nil {
+				result.Err = errors.New("status " + strconv.Itoa(resp.StatusCode))
+			}
+		}
+
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, result.Err))
+			log.Error("Self-test failed:", path, result.Err, result.Latency)
+		} else {
+			log.System("Self-test passed:", path, result.Status, result.Latency)
+		}
+
+		results = append(results, result)
+	}
+
+	r.selfTest.mu.Lock()
+	r.selfTest.results = results
+	r.selfTest.mu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("self-test failed: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// runStartupSelfTest runs the self-test configured with SelfTestOnStart, if any
+func (r *Router) runStartupSelfTest() error {
+	r.selfTest.mu.Lock()
+	paths := append([]string(nil), r.selfTest.paths...)
+	r.selfTest.mu.Unlock()
+
+	if len(paths) == 0 {
+		return nil
+	}
+	return r.SelfTest(paths...)
+}
@@ -0,0 +1,94 @@
+package gouter
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+)
+
+// selfTestRouter serves a healthy route and several broken ones
+func selfTestRouter() *Router {
+	r := newTestRouter()
+	r.Get("/ok", func(req *Request, w *Writer) {
+		w.Write([]byte("ok"))
+	})
+	r.Get("/missing-page", func(req *Request, w *Writer) {
+		w.WriteHeader(404)
+	})
+	r.Get("/nil", func(req *Request, w *Writer) {
+		var cfg *CORSConfig
+		w.Write([]byte(cfg.AllowedOrigins[0]))
+	})
+	r.Get("/unavailable", func(req *Request, w *Writer) {
+		w.WriteHeader(503)
+	})
+	return r
+}
+
+func TestSelfTest(t *testing.T) {
+	r := selfTestRouter()
+
+	if err := r.SelfTest("/ok", "/missing-page"); err != nil {
+		t.Errorf("SelfTest of healthy routes = %v, want nil", err)
+	}
+
+	err := r.SelfTest("/ok", "/nil", "/unavailable")
+	if err == nil {
+		t.Fatal("SelfTest passed with broken routes")
+	}
+	msg := err.Error()
+	for _, want := range []string{"/nil: status 500", "/unavailable: status 503"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error %q does not report %q", msg, want)
+		}
+	}
+	if strings.Contains(msg, "/ok") {
+		t.Errorf("error %q reports the healthy route", msg)
+	}
+
+	results := r.SelfTestResults()
+	if len(results) != 3 {
+		t.Fatalf("SelfTestResults = %+v, want one result per path", results)
+	}
+	for i, want := range []struct {
+		path   string
+		status int
+		failed bool
+	}{{"/ok", 200, false}, {"/nil", 500, true}, {"/unavailable", 503, true}} {
+		got := results[i]
+		if got.Path != want.path || got.Status != want.status || (got.Err != nil) != want.failed || got.Latency <= 0 {
+			t.Errorf("result %d = %+v, want %s %d failed=%v", i, got, want.path, want.status, want.failed)
+		}
+	}
+}
+
+func TestSelfTestOnStartBlocksServe(t *testing.T) {
+	r := selfTestRouter()
+	r.SelfTestOnStart("/ok", "/nil")
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	err = (&Server{Router: r}).Serve(l)
+	if err == nil || errors.Is(err, net.ErrClosed) || !strings.Contains(err.Error(), "/nil") {
+		t.Errorf("Serve = %v, want the self-test failure", err)
+	}
+	if results := r.SelfTestResults(); len(results) != 2 {
+		t.Errorf("SelfTestResults after startup = %+v", results)
+	}
+}
+
+func TestSelfTestOnStartPasses(t *testing.T) {
+	r := selfTestRouter()
+	r.SelfTestOnStart("/ok")
+	addr := serveRouter(t, r)
+
+	resp := rawExchange(t, addr, "GET /ok HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != 200 {
+		t.Errorf("status after a passing self-test = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,149 @@
+package gouter
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/Murilinho145SG/gouter/internal/httpdate"
+)
+
+// sendfileBytes counts the file bytes sent without a userspace copy
+var sendfileBytes atomic.Int64
+
+// SendfileBytes returns how many bytes of static files were handed to the
+// kernel on plain TCP connections (sendfile on Linux) since the process
+// started; TLS connections and other wrappers copy through userspace
+func SendfileBytes() int64 {
+	return sendfileBytes.Load()
+}
+
+// sendFile answers r with f, which is size bytes long, or with the single
+// byte range r asks for. A list of ranges gets the whole file, and a range
+// starting past the end gets 416. If-Range is compared with the ETag or
+// Last-Modified header the caller already set
+// The caller sets the other headers; sendFile writes them with the status
+func sendFile(r *Request, w *Writer, f *os.File, size int64) error {
+	w.Headers.Add("Accept-Ranges", "bytes")
+
+	start, n, status := int64(0), size, http.StatusOK
+	if header := r.Headers.Get("Range"); header != "" && ifRange(r, w) {
+		start, n, status = parseRange(header, size)
+	}
+
+	switch status {
+	case http.StatusRequestedRangeNotSatisfiable:
+		w.Headers.Add("Content-Range", "bytes */"+strconv.FormatInt(size, 10))
+		w.WriteHeader(status)
+		return nil
+	case http.StatusPartialContent:
+		w.Headers.Add("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+n-1, size))
+		if _, err := f.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	w.Headers.Add("Content-Length", strconv.FormatInt(n, 10))
+	w.WriteHeader(status)
+	if err := w.WriteHeaders(); err != nil {
+		return err
+	}
+	_, err := w.writeFile(f, n)
+	return err
+}
+
+// ifRange reports whether the Range header of r applies to the response
+// An If-Range validator that does not match strongly asks for the whole file
+func ifRange(r *Request, w *Writer) bool {
+	cond := r.Headers.Get("If-Range")
+	if cond == "" {
+		return true
+	}
+	if strings.HasPrefix(cond, `"`) {
+		return cond == w.Headers.Get("ETag")
+	}
+	modified := w.Headers.Get("Last-Modified")
+	t, err := httpdate.Parse(cond)
+	if err != nil || modified == "" {
+		return false
+	}
+	m, err := httpdate.Parse(modified)
+	return err == nil && t.Equal(m)
+}
+
+// parseRange reads a Range header for a body of size bytes
+// Returns the first byte and length of the range with 206, 416 when it
+// cannot be satisfied, or 200 when the header is ignored: malformed, not in
+// bytes, or a list of ranges
+func parseRange(header string, size int64) (start, n int64, status int) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return 0, size, http.StatusOK
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, http.StatusOK
+	}
+
+	if first == "" {
+		// Suffix range: the last bytes of the body
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix < 0 {
+			return 0, size, http.StatusOK
+		}
+		if suffix == 0 || size == 0 {
+			return 0, 0, http.StatusRequestedRangeNotSatisfiable
+		}
+		suffix = min(suffix, size)
+		return size - suffix, suffix, http.StatusPartialContent
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 {
+		return 0, size, http.StatusOK
+	}
+	end := size - 1
+	if last != "" {
+		if end, err = strconv.ParseInt(last, 10, 64); err != nil || end < start {
+			return 0, size, http.StatusOK
+		}
+		end = min(end, size-1)
+	}
+	if start >= size {
+		return 0, 0, http.StatusRequestedRangeNotSatisfiable
+	}
+	return start, end - start + 1, http.StatusPartialContent
+}
+
+// writeFile sends n bytes of f, from its current offset, after the headers
+// The file goes straight to the socket when the connection is plain TCP,
+// otherwise it is copied through a buffer. HEAD responses send nothing
+func (w *Writer) writeFile(f *os.File, n int64) (int64, error) {
+	if w.noBody {
+		return 0, nil
+	}
+
+	body := &io.LimitedReader{R: f, N: n}
+	if w.chunked {
+		return io.Copy(struct{ io.Writer }{w}, body)
+	}
+	if cc, ok := w.c.(*countingConn); ok {
+		if tcp, ok := cc.Conn.(*net.TCPConn); ok {
+			written, err := tcp.ReadFrom(body)
+			cc.out.Add(written)
+			w.streamed += written
+			sendfileBytes.Add(written)
+			return written, err
+		}
+	}
+
+	// Neither side may pick its own zero-copy path: f must be read through body
+	written, err := io.Copy(struct{ io.Writer }{w.c}, body)
+	w.streamed += written
+	return written, err
+}
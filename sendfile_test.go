@@ -0,0 +1,187 @@
+package gouter
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// wrappedListener hides the *net.TCPConn of accepted connections, as TLS
+// does, so files are copied through a buffer
+type wrappedListener struct{ net.Listener }
+
+func (l wrappedListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return struct{ net.Conn }{c}, nil
+}
+
+// staticServer serves a file of size random bytes under /static
+// Returns the address and the file contents
+func staticServer(tb testing.TB, size int, buffered bool) (string, []byte) {
+	tb.Helper()
+	data := make([]byte, size)
+	rand.Read(data)
+	dir := tb.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "blob.bin"), data, 0o644); err != nil {
+		tb.Fatal(err)
+	}
+
+	r := newTestRouter()
+	ServerStatic(r, "/static", dir)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if buffered {
+		l = wrappedListener{l}
+	}
+	return serveListener(tb, &Server{Router: r}, l), data
+}
+
+func TestSendFileChecksums(t *testing.T) {
+	const size = 1 << 20
+	cases := []struct {
+		rng         string
+		status      int
+		first, last int
+	}{
+		{"", http.StatusOK, 0, size - 1},
+		{"bytes=100-65635", http.StatusPartialContent, 100, 65635},
+		{"bytes=1000-", http.StatusPartialContent, 1000, size - 1},
+		{"bytes=-4096", http.StatusPartialContent, size - 4096, size - 1},
+		{"bytes=10-99999999", http.StatusPartialContent, 10, size - 1},
+		{"bytes=0-0,5-9", http.StatusOK, 0, size - 1},
+	}
+
+	for _, path := range []string{"sendfile", "buffered"} {
+		t.Run(path, func(t *testing.T) {
+			addr, data := staticServer(t, size, path == "buffered")
+			before := SendfileBytes()
+			for _, tc := range cases {
+				req, _ := http.NewRequest("GET", "http://"+addr+"/static/blob.bin", nil)
+				if tc.rng != "" {
+					req.Header.Set("Range", tc.rng)
+				}
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				h := sha256.New()
+				n, err := io.Copy(h, resp.Body)
+				resp.Body.Close()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := sha256.Sum256(data[tc.first : tc.last+1])
+				if resp.StatusCode != tc.status || string(h.Sum(nil)) != string(want[:]) {
+					t.Errorf("Range %q: %d with %d bytes, want %d with bytes %d-%d", tc.rng, resp.StatusCode, n, tc.status, tc.first, tc.last)
+				}
+				if tc.status == http.StatusPartialContent {
+					wantRange := "bytes " + strconv.Itoa(tc.first) + "-" + strconv.Itoa(tc.last) + "/" + strconv.Itoa(size)
+					if got := resp.Header.Get("Content-Range"); got != wantRange {
+						t.Errorf("Range %q: Content-Range %q, want %q", tc.rng, got, wantRange)
+					}
+				}
+			}
+
+			sent := SendfileBytes() - before
+			if path == "sendfile" && sent == 0 {
+				t.Errorf("no bytes went through sendfile on a plain TCP connection")
+			}
+			if path == "buffered" && sent != 0 {
+				t.Errorf("%d bytes went through sendfile on a wrapped connection", sent)
+			}
+		})
+	}
+}
+
+func TestSendFileUnsatisfiableRange(t *testing.T) {
+	addr, _ := staticServer(t, 100, false)
+	resps := rawExchange(t, addr, "GET /static/blob.bin HTTP/1.1\r\nHost: x\r\nRange: bytes=100-\r\n\r\n"+
+		"HEAD /static/blob.bin HTTP/1.1\r\nHost: x\r\nRange: bytes=-10\r\n\r\n", 2)
+
+	get, head := resps[0], resps[1]
+	if get.StatusCode != http.StatusRequestedRangeNotSatisfiable || get.Header.Get("Content-Range") != "bytes */100" {
+		t.Errorf("range past the end = %d %q, want 416 bytes */100", get.StatusCode, get.Header.Get("Content-Range"))
+	}
+	if head.StatusCode != http.StatusPartialContent || head.Header.Get("Content-Length") != "10" || bodyString(t, head) != "" {
+		t.Errorf("HEAD with a range = %d, length %q, want 206 with 10 and no body", head.StatusCode, head.Header.Get("Content-Length"))
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	cases := []struct {
+		header   string
+		start, n int64
+		status   int
+	}{
+		{"bytes=0-9", 0, 10, http.StatusPartialContent},
+		{"bytes=90-", 90, 10, http.StatusPartialContent},
+		{"bytes=-500", 0, 100, http.StatusPartialContent},
+		{"bytes=-0", 0, 0, http.StatusRequestedRangeNotSatisfiable},
+		{"bytes=100-200", 0, 0, http.StatusRequestedRangeNotSatisfiable},
+		{"bytes=9-5", 0, 100, http.StatusOK},
+		{"bytes=a-5", 0, 100, http.StatusOK},
+		{"bytes=5", 0, 100, http.StatusOK},
+		{"items=0-9", 0, 100, http.StatusOK},
+		{"bytes=0-1,3-4", 0, 100, http.StatusOK},
+	}
+	for _, tc := range cases {
+		start, n, status := parseRange(tc.header, 100)
+		if start != tc.start || n != tc.n || status != tc.status {
+			t.Errorf("parseRange(%q) = %d, %d, %d, want %d, %d, %d", tc.header, start, n, status, tc.start, tc.n, tc.status)
+		}
+	}
+}
+
+func TestFileIfRange(t *testing.T) {
+	// Larger than fixtureMemoryLimit, so File streams it from disk
+	data := []byte(strings.Repeat("0123456789", fixtureMemoryLimit/10+1))
+	path := filepath.Join(t.TempDir(), "data.txt")
+	os.WriteFile(path, data, 0o644)
+	r := newTestRouter()
+	r.File("/data", path)
+	addr := serveRouter(t, r)
+
+	etag := rawExchange(t, addr, "GET /data HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0].Header.Get("ETag")
+	resps := rawExchange(t, addr, "GET /data HTTP/1.1\r\nHost: x\r\nRange: bytes=2-4\r\nIf-Range: "+etag+"\r\n\r\n"+
+		"GET /data HTTP/1.1\r\nHost: x\r\nRange: bytes=2-4\r\nIf-Range: \"stale\"\r\n\r\n", 2)
+
+	if got := bodyString(t, resps[0]); resps[0].StatusCode != http.StatusPartialContent || got != "234" {
+		t.Errorf("matching If-Range = %d %q, want 206 234", resps[0].StatusCode, got)
+	}
+	if got := bodyString(t, resps[1]); resps[1].StatusCode != http.StatusOK || got != string(data) {
+		t.Errorf("stale If-Range = %d with %d bytes, want the whole file", resps[1].StatusCode, len(got))
+	}
+}
+
+func BenchmarkServeFile(b *testing.B) {
+	const size = 100 << 20
+	for _, path := range []string{"sendfile", "buffered"} {
+		b.Run(path, func(b *testing.B) {
+			addr, _ := staticServer(b, size, path == "buffered")
+			url := "http://" + addr + "/static/blob.bin"
+			b.SetBytes(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				resp, err := http.Get(url)
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Copy(io.Discard, resp.Body)
+				resp.Body.Close()
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package gouter
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// Server serves a Router with connection-level settings
+// The zero value of each field keeps the behavior of Run and Serve, except
+// Router: serving without one fails with ErrNoRouter
+type Server struct {
+	Addr         string        // Address to listen on (e.g., ":8080")
+	Router       *Router       // Router handling the requests
+	TLSConfig    *tls.Config   // TLS settings used by ListenAndServeTLS
+	ReadTimeout  time.Duration // Deadline for reading a whole request, counted from its start
+	WriteTimeout time.Duration // Deadline for writing the response, counted from the end of the headers
+	IdleTimeout  time.Duration // Limit for a connection waiting for its next request (default: parser HeaderTimeout)
+
+	// MaxURILength caps the request-target (path and query) and answers
+	// longer ones with 414 before the headers are read. 0 uses 8KB and a
+	// negative value disables the check
+	MaxURILength int
+
+	// MaxMemoryPerRequest caps the approximate memory a request may buffer:
+	// parsed headers, bodies read into memory (ReceiveFile, multipart fields)
+	// and the buffered response. Going over answers 503 and makes the
+	// failing read or write return ErrMemoryBudget. 0 disables the check
+	MaxMemoryPerRequest int64
+
+	// MaxBufferedResponseBytes switches a response to streaming once its
+	// buffered body would grow past this size; such responses have no
+	// Content-Length and are sent chunked. 0 disables the switch
+	MaxBufferedResponseBytes int
+
+	// MinReadBufferSize and MaxReadBufferSize bound the per-connection read
+	// buffer, sized from the header blocks seen so far (see
+	// Router.ReadBufferSize). Defaults: 1KB and 64KB
+	MinReadBufferSize int
+	MaxReadBufferSize int
+
+	// HandshakeTimeout bounds the TLS handshake of each connection, which
+	// runs on the connection goroutine (default: 5s)
+	HandshakeTimeout time.Duration
+
+	// ErrorHandler receives connection-level errors: failed TLS handshakes,
+	// as *HandshakeError with the client address and SNI name. nil logs them
+	ErrorHandler func(err error)
+
+	// HandshakeLogInterval reports handshake failures from one client IP at
+	// most once per interval, hiding scanner noise. 0 reports every failure
+	HandshakeLogInterval time.Duration
+
+	// MaxConcurrentConnections caps the connections served at once. A
+	// connection accepted past it gets a 503 and is closed, at once or after
+	// ConnectionQueueTimeout. 0 means no limit
+	MaxConcurrentConnections int
+
+	// ConnectionQueueTimeout is how long the accept loop waits for a slot
+	// for a connection over the limit before it gets a 503 and is closed. 0
+	// rejects at once. The loop accepts nothing while it waits, so only one
+	// connection per listener waits; later clients stay in the kernel backlog
+	ConnectionQueueTimeout time.Duration
+
+	// EnableHTTP2 offers HTTP/2 through ALPN in ListenAndServeTLS. HTTP/2
+	// connections are served by net/http running the router, as with
+	// ToHTTPHandler; Serve uses it for TLS listeners whose config lists "h2"
+	EnableHTTP2 bool
+
+	strict      bool              // Strict RFC parsing, set by StrictHTTP
+	handshakes  handshakeReporter // TLS handshake counters and report limiter
+	reload      reloadState       // Certificate files and hooks used by Reload
+	trackerOnce sync.Once         // Creates track
+	track       *connTracker      // Listeners and connections closed by Shutdown
+	limiter     connLimiter       // MaxConcurrentConnections slots and counters
+	http2       h2Server          // Serves HTTP/2 connections when EnableHTTP2 is set
+}
+
+// connOptions carries the Server settings down to each connection
+type connOptions struct {
+	readTimeout  time.Duration
+	writeTimeout time.Duration
+	idleTimeout  time.Duration
+
+	maxURILength        int
+	maxRequestMemory    int64
+	maxBufferedResponse int
+
+	minReadBuffer int
+	maxReadBuffer int
+
+	strict bool // Strict RFC parsing (see Server.StrictHTTP)
+
+	tracker    *connTracker       // Shutdown registry, nil outside Server
+	handshakes *handshakeReporter // TLS handshake settings and counters, nil outside Server
+	http2      *h2Server          // HTTP/2 server, nil unless Server.EnableHTTP2
+}
+
+// options returns the per-connection settings of the server
+func (s *Server) options() connOptions {
+	s.handshakes.configure(s.HandshakeTimeout, s.HandshakeLogInterval, s.ErrorHandler)
+	return connOptions{
+		readTimeout:  s.ReadTimeout,
+		writeTimeout: s.WriteTimeout,
+		idleTimeout:  s.IdleTimeout,
+
+		maxURILength:        s.MaxURILength,
+		maxRequestMemory:    s.MaxMemoryPerRequest,
+		maxBufferedResponse: s.MaxBufferedResponseBytes,
+
+		minReadBuffer: s.MinReadBufferSize,
+		maxReadBuffer: s.MaxReadBufferSize,
+
+		strict: s.strict,
+
+		tracker:    s.tracker(),
+		handshakes: &s.handshakes,
+	}
+}
+
+// ListenAndServe listens on s.Addr and serves plain HTTP
+func (s *Server) ListenAndServe() error {
+	if s.Router == nil {
+		return ErrNoRouter
+	}
+
+	l, err := listen(s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to create listener: %w", err)
+	}
+	defer l.Close()
+
+	return s.Serve(l)
+}
+
+// ListenAndServeTLS listens on s.Addr and serves HTTPS
+// Args:
+//   - certFile: Path to SSL certificate file
+//   - keyFile: Path to private key file
+//
+// The handshake runs on the connection goroutine, bounded by
+// HandshakeTimeout, so slow clients do not hold up the accept loop
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	if s.Router == nil {
+		return ErrNoRouter
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	s.reload.setCertificate(certFile, keyFile, &cert)
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.TLSConfig != nil {
+		config = s.TLSConfig.Clone()
+	}
+	// Resolved per handshake so Reload affects new connections only
+	if config.GetCertificate == nil {
+		config.GetCertificate = s.reload.getCertificate
+	} else {
+		config.Certificates = append(config.Certificates, cert)
+	}
+	if s.EnableHTTP2 {
+		offerHTTP2(config)
+	}
+	recordServerName(config)
+
+	l, err := listen(s.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to create listener: %w", err)
+	}
+	defer l.Close()
+
+	return s.serve(tls.NewListener(l, config), true)
+}
+
+// Serve accepts connections on l until it is closed
+// Returns an error wrapping net.ErrClosed once the listener is closed
+func (s *Server) Serve(l net.Listener) error {
+	return s.serve(l, false)
+}
+
+// serve runs the accept loop; secure tells the docs to link wss:// URLs
+func (s *Server) serve(l net.Listener, secure bool) error {
+	r := s.Router
+	if r == nil {
+		return ErrNoRouter
+	}
+	r.lazyInit()
+	r.setServeAddr(l.Addr(), secure)
+
+	// Exercise configured routes before accepting public traffic
+	if err := r.runStartupSelfTest(); err != nil {
+		return err
+	}
+
+	opts := s.options()
+	if s.EnableHTTP2 {
+		s.http2.once.Do(func() { s.http2.start(r, opts) })
+		opts.http2 = &s.http2
+	}
+	if r.docConfig.Active {
+		docs, err := startDoc(r)
+		if err != nil {
+			return err
+		}
+		// Shutdown closes the documentation server with the main listener
+		if docs != nil && !opts.tracker.addListener(docs) {
+			docs.Close()
+		}
+	}
+
+	if !opts.tracker.addListener(l) {
+		l.Close()
+		return fmt.Errorf("server stopped: %w", net.ErrClosed)
+	}
+	s.limiter.configure(s.MaxConcurrentConnections)
+	var backoff acceptBackoff
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return fmt.Errorf("server stopped: %w", err)
+			}
+			log.Error(fmt.Errorf("connection accept error: %w", err))
+			backoff.wait()
+			continue
+		}
+		backoff.reset()
+		if !s.limiter.acquire(s.ConnectionQueueTimeout) {
+			rejectConn(conn)
+			continue
+		}
+		spawn("conn", func() {
+			defer s.limiter.release()
+			handleConn(conn, r, opts)
+		})
+	}
+}
+
+// acceptBackoff spaces out Accept retries after errors such as running out
+// of file descriptors, as net/http does: 5ms, doubling up to 1s, until an
+// Accept succeeds
+type acceptBackoff struct {
+	delay time.Duration
+	sleep func(time.Duration) // time.Sleep when nil, replaced in tests
+}
+
+// wait sleeps before the next Accept
+func (b *acceptBackoff) wait() {
+	if b.delay == 0 {
+		b.delay = 5 * time.Millisecond
+	} else if b.delay *= 2; b.delay > time.Second {
+		b.delay = time.Second
+	}
+	if b.sleep != nil {
+		b.sleep(b.delay)
+		return
+	}
+	time.Sleep(b.delay)
+}
+
+// reset starts the next run of errors over from 5ms
+func (b *acceptBackoff) reset() {
+	b.delay = 0
+}
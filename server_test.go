@@ -0,0 +1,71 @@
+package gouter
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAcceptBackoff(t *testing.T) {
+	var slept []time.Duration
+	b := acceptBackoff{sleep: func(d time.Duration) { slept = append(slept, d) }}
+	for i := 0; i < 10; i++ {
+		b.wait()
+	}
+	ms := time.Millisecond
+	want := []time.Duration{5 * ms, 10 * ms, 20 * ms, 40 * ms, 80 * ms, 160 * ms, 320 * ms, 640 * ms, time.Second, time.Second}
+	if !reflect.DeepEqual(slept, want) {
+		t.Errorf("delays = %v, want %v", slept, want)
+	}
+
+	// A successful Accept starts over
+	b.reset()
+	b.wait()
+	if got := slept[len(slept)-1]; got != 5*ms {
+		t.Errorf("delay after reset = %v, want 5ms", got)
+	}
+}
+
+// flakyListener fails its first Accepts, as a process out of file
+// descriptors would
+type flakyListener struct {
+	net.Listener
+	failures atomic.Int32
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	if l.failures.Add(-1) >= 0 {
+		return nil, errors.New("accept: too many open files")
+	}
+	return l.Listener.Accept()
+}
+
+func TestServeBacksOffAcceptErrors(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := &flakyListener{Listener: inner}
+	l.failures.Store(3)
+	go (&Server{Router: r}).Serve(l)
+	t.Cleanup(func() { l.Close() })
+
+	// Three failures wait 5, 10 and 20ms before the next Accept
+	start := time.Now()
+	resp, err := http.Get("http://" + inner.Addr().String() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodyString(t, resp); got != "ok" {
+		t.Errorf("body = %q, want ok", got)
+	}
+	if d := time.Since(start); d < 30*time.Millisecond {
+		t.Errorf("served after %v, want the accept errors spaced out", d)
+	}
+}
@@ -0,0 +1,333 @@
+package gouter
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// shutdownPollInterval is how often Shutdown checks for drained connections
+const shutdownPollInterval = 10 * time.Millisecond
+
+// closeGoingAway is the websocket close code sent on shutdown
+const closeGoingAway = 1001
+
+// ShutdownReport describes what a Shutdown did
+type ShutdownReport struct {
+	Drained          int           // Connections that finished on their own
+	ForceClosed      int           // Connections still busy when the context expired
+	WebSocketsClosed int           // Websockets sent a 1001 (going away) close frame
+	TasksFinished    int           // Background tasks (see Go) that returned in time
+	TasksAbandoned   int           // Background tasks still running when the context expired
+	DrainDuration    time.Duration // Time spent waiting for connections
+	TasksDuration    time.Duration // Time spent waiting for background tasks
+	Total            time.Duration // Whole shutdown
+}
+
+// connState is the shutdown-relevant state of a tracked connection
+type connState int
+
+const (
+	connIdle   connState = iota // Waiting for a request
+	connActive                  // Serving a request, or hijacked
+)
+
+// connTracker registers the connections and websockets of a Server
+type connTracker struct {
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]connState
+	sockets   map[*WebSocket]struct{}
+	shutdown  bool
+	drained   int // Connections closed gracefully since shutdown began
+}
+
+// newConnTracker creates an empty registry
+func newConnTracker() *connTracker {
+	return &connTracker{
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]connState),
+		sockets:   make(map[*WebSocket]struct{}),
+	}
+}
+
+// tracker returns the registry of the server, creating it if needed
+func (s *Server) tracker() *connTracker {
+	s.trackerOnce.Do(func() {
+		s.track = newConnTracker()
+	})
+	return s.track
+}
+
+// addListener registers a listener; false when the server is shutting down
+func (t *connTracker) addListener(l net.Listener) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.shutdown {
+		return false
+	}
+	t.listeners[l] = struct{}{}
+	return true
+}
+
+// add registers a new connection; false when the server is shutting down
+// A nil tracker (Router.Run, Router.RunTLS) accepts everything
+func (t *connTracker) add(c net.Conn) bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.shutdown {
+		return false
+	}
+	t.conns[c] = connIdle
+	return true
+}
+
+// setState records a connection state change
+// Returns false when an idle connection should close because of a shutdown
+func (t *connTracker) setState(c net.Conn, st connState) bool {
+	if t == nil {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.conns[c]; !ok {
+		return false
+	}
+	if t.shutdown && st == connIdle {
+		return false
+	}
+	t.conns[c] = st
+	return true
+}
+
+// remove unregisters a connection that is closing
+func (t *connTracker) remove(c net.Conn) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.conns[c]; !ok {
+		return
+	}
+	delete(t.conns, c)
+	if t.shutdown {
+		t.drained++
+	}
+}
+
+// closing reports whether a shutdown has begun
+func (t *connTracker) closing() bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shutdown
+}
+
+// addSocket registers an upgraded websocket
+func (t *connTracker) addSocket(ws *WebSocket) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sockets[ws] = struct{}{}
+}
+
+// removeSocket unregisters a closed websocket
+func (t *connTracker) removeSocket(ws *WebSocket) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.sockets, ws)
+}
+
+// Shutdown stops the server gracefully
+//
+// Phases:
+//   - Closes the listeners and idle keep-alive connections, and cancels
+//     background tasks started with Go so they wind down meanwhile
+//   - Sends websockets a 1001 (going away) close frame
+//   - Waits for in-flight requests; busy connections get Connection: close
+//   - Waits for the background tasks
+//
+// When ctx expires, the remaining connections are closed and remaining
+// tasks abandoned; the report counts both and ctx.Err() is returned
+func (s *Server) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	start := time.Now()
+	t := s.tracker()
+	var report ShutdownReport
+
+	t.mu.Lock()
+	t.shutdown = true
+	for l := range t.listeners {
+		l.Close()
+	}
+	for c, st := range t.conns {
+		if st == connIdle {
+			c.Close()
+		}
+	}
+	sockets := make([]*WebSocket, 0, len(t.sockets))
+	for ws := range t.sockets {
+		sockets = append(sockets, ws)
+	}
+	t.mu.Unlock()
+	running := tasks.cancel()
+
+	for _, ws := range sockets {
+		if ws.closeGoingAway() == nil {
+			report.WebSocketsClosed++
+		}
+	}
+
+	var err error
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for err == nil {
+		t.mu.Lock()
+		remaining := len(t.conns)
+		t.mu.Unlock()
+		if remaining == 0 {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	t.mu.Lock()
+	for c := range t.conns {
+		c.Close()
+		delete(t.conns, c)
+		report.ForceClosed++
+	}
+	report.Drained = t.drained
+	t.mu.Unlock()
+	report.DrainDuration = time.Since(start)
+
+	tasksStart := time.Now()
+	report.TasksFinished, report.TasksAbandoned = tasks.wait(ctx, running)
+	report.TasksDuration = time.Since(tasksStart)
+	report.Total = time.Since(start)
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	log.System("Shutdown:", report.Drained, "drained,", report.ForceClosed, "force-closed,",
+		report.WebSocketsClosed, "websockets closed,", report.TasksFinished, "tasks finished,",
+		report.TasksAbandoned, "abandoned in", report.Total)
+	return report, err
+}
+
+// closeGoingAway sends a 1001 close frame
+func (ws *WebSocket) closeGoingAway() error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+
+	frame := []byte{0x88, 2, 0, 0}
+	binary.BigEndian.PutUint16(frame[2:], closeGoingAway)
+	ws.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+// taskTracker tracks the background tasks started with Go
+type taskTracker struct {
+	mu      sync.Mutex
+	ctx     context.Context // Context of the current tasks, nil after a shutdown
+	stop    context.CancelFunc
+	running int
+	done    chan struct{} // Signaled whenever a task returns
+}
+
+// tasks is the process-wide task registry
+var tasks taskTracker
+
+// Go runs fn in a goroutine tracked by Server.Shutdown, labeled with
+// GoroutineLabel "task" and the name of fn in goroutine profiles
+// ctx is canceled when a shutdown begins; fn should return promptly then.
+// Tasks are process-wide: the first Server to shut down cancels them all,
+// and tasks started afterwards get a new context
+func Go(fn func(ctx context.Context)) {
+	tasks.mu.Lock()
+	if tasks.ctx == nil {
+		tasks.ctx, tasks.stop = context.WithCancel(context.Background())
+	}
+	if tasks.done == nil {
+		tasks.done = make(chan struct{}, 1)
+	}
+	ctx := tasks.ctx
+	tasks.running++
+	tasks.mu.Unlock()
+
+	labels := pprof.Labels(GoroutineLabel, "task", "task", funcName(fn))
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		go func() {
+			defer func() {
+				tasks.mu.Lock()
+				tasks.running--
+				tasks.mu.Unlock()
+				select {
+				case tasks.done <- struct{}{}:
+				default:
+				}
+			}()
+			fn(ctx)
+		}()
+	})
+}
+
+// cancel cancels the running tasks and returns how many there are
+func (t *taskTracker) cancel() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stop == nil {
+		return 0
+	}
+	t.stop()
+	t.ctx, t.stop = nil, nil
+	return t.running
+}
+
+// wait waits for the canceled tasks until ctx expires
+// Returns how many of the initial tasks finished and how many were still running
+func (t *taskTracker) wait(ctx context.Context, initial int) (finished, abandoned int) {
+	if initial == 0 {
+		return 0, 0
+	}
+	t.mu.Lock()
+	done := t.done
+	t.mu.Unlock()
+
+	for {
+		t.mu.Lock()
+		running := min(t.running, initial)
+		t.mu.Unlock()
+		if running == 0 {
+			return initial, 0
+		}
+
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return initial - running, running
+		}
+	}
+}
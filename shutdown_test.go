@@ -0,0 +1,97 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdownReport(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+
+	r := newTestRouter()
+	r.Get("/slow", func(req *Request, w *Writer) {
+		started <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})
+	r.WebSocket("/ws", func(ws *WebSocket, req *Request) {
+		started <- struct{}{}
+		<-release // Stuck: never reads the close frame
+	}, WebSocketConfig{})
+	s := &Server{Router: r}
+	addr := serveTest(t, s)
+	t.Cleanup(func() { close(release) })
+
+	dial := func(raw string) (net.Conn, *bufio.Reader) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		c.SetDeadline(time.Now().Add(3 * time.Second))
+		io.WriteString(c, raw)
+		return c, bufio.NewReader(c)
+	}
+
+	slow, slowReader := dial("GET /slow HTTP/1.1\r\nHost: x\r\n\r\n")
+	ws, wsReader := dial(wsHandshake + "Sec-WebSocket-Version: 13\r\n\r\n")
+	if resp, err := http.ReadResponse(wsReader, nil); err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("websocket handshake: %v", err)
+	}
+	<-started
+	<-started
+
+	finishing := make(chan bool, 1)
+	Go(func(ctx context.Context) {
+		<-ctx.Done()
+		finishing <- true
+	})
+	Go(func(ctx context.Context) {
+		<-release // Ignores cancellation
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	report, err := s.Shutdown(ctx)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Shutdown = %v, want the deadline error", err)
+	}
+
+	if report.Drained != 1 || report.ForceClosed != 1 || report.WebSocketsClosed != 1 {
+		t.Errorf("connections: %d drained, %d force-closed, %d websockets, want 1 each", report.Drained, report.ForceClosed, report.WebSocketsClosed)
+	}
+	if report.TasksFinished != 1 || report.TasksAbandoned != 1 {
+		t.Errorf("tasks: %d finished, %d abandoned, want 1 each", report.TasksFinished, report.TasksAbandoned)
+	}
+	if report.DrainDuration < 300*time.Millisecond || report.Total < report.DrainDuration+report.TasksDuration {
+		t.Errorf("durations: drain %v, tasks %v, total %v", report.DrainDuration, report.TasksDuration, report.Total)
+	}
+	if !<-finishing {
+		t.Error("canceled task did not finish")
+	}
+
+	resp, err := http.ReadResponse(slowReader, nil)
+	if err != nil || bodyString(t, resp) != "done" || !resp.Close {
+		t.Errorf("in-flight request: %v, want its response with Connection: close", err)
+	}
+	frame := make([]byte, 4)
+	if _, err := io.ReadFull(wsReader, frame); err != nil || !bytes.Equal(frame, []byte{0x88, 2, 0x03, 0xe9}) {
+		t.Errorf("websocket got %x (%v), want a 1001 close frame", frame, err)
+	}
+	slow.Close()
+	ws.Close()
+
+	// Tasks started after a shutdown get a live context
+	alive := make(chan bool, 1)
+	Go(func(ctx context.Context) { alive <- ctx.Err() == nil })
+	if !<-alive {
+		t.Error("task started after the shutdown was already canceled")
+	}
+}
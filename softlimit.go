@@ -0,0 +1,129 @@
+package gouter
+
+import (
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+// defaultSoftLogInterval is the minimum gap between two warnings of one kind
+const defaultSoftLogInterval = 10 * time.Second
+
+// Soft limit kinds reported in SoftLimitEvent.Limit
+const (
+	SoftLimitConnections = "connections"  // Open connections
+	SoftLimitBody        = "body"         // Declared request body size
+	SoftLimitHeaders     = "header_bytes" // Request line plus header block size
+)
+
+// SoftLimits configures early warnings below the hard limits
+// Crossing a soft limit never affects the request: it logs a rate-limited
+// warning and notifies observers implementing SoftLimitObserver
+type SoftLimits struct {
+	Connections  int           // Open connections that trigger a warning, 0 disables
+	BodyFraction float64       // Share of ParserConfig.MaxBodyBytes a declared body may reach (e.g., 0.5), 0 disables
+	HeaderBytes  int           // Header block size that triggers a warning (e.g., 256 << 10), 0 disables
+	LogInterval  time.Duration // Minimum gap between warnings of one kind (default: 10s)
+}
+
+// SoftLimitEvent describes a request or connection crossing a soft limit
+type SoftLimitEvent struct {
+	Limit     string // One of the SoftLimit* kinds
+	Value     int64  // Observed value
+	Threshold int64  // Configured soft limit
+}
+
+// SoftLimitObserver is implemented by observers that also want soft limit
+// events; they are delivered for every crossing, unlike the rate-limited logs
+type SoftLimitObserver interface {
+	SoftLimitReached(e SoftLimitEvent)
+}
+
+// softKind indexes the per-kind counters of softLimits
+type softKind int
+
+const (
+	softConnections softKind = iota
+	softBody
+	softHeaders
+)
+
+// softLimits holds the configured thresholds and their counters
+type softLimits struct {
+	cfg     SoftLimits
+	conns   atomic.Int64 // Currently open connections
+	counts  [3]atomic.Int64
+	lastLog [3]atomic.Int64 // Unix nanoseconds of the last warning per kind
+}
+
+// softLimitKinds names each softKind
+var softLimitKinds = [3]string{SoftLimitConnections, SoftLimitBody, SoftLimitHeaders}
+
+// SetSoftLimits configures the soft thresholds checked while serving
+func (r *Router) SetSoftLimits(l SoftLimits) {
+	if l.LogInterval <= 0 {
+		l.LogInterval = defaultSoftLogInterval
+	}
+	r.soft.cfg = l
+}
+
+// SoftLimitCounts returns how many times each soft limit was crossed
+func (r *Router) SoftLimitCounts() map[string]int64 {
+	counts := make(map[string]int64, len(softLimitKinds))
+	for i, kind := range softLimitKinds {
+		counts[kind] = r.soft.counts[i].Load()
+	}
+	return counts
+}
+
+// connOpened counts a new connection and checks the connection threshold
+func (r *Router) connOpened() {
+	n := r.soft.conns.Add(1)
+	if limit := r.soft.cfg.Connections; limit > 0 && n >= int64(limit) {
+		r.softLimitReached(softConnections, n, int64(limit))
+	}
+}
+
+// connClosed stops counting a connection
+func (r *Router) connClosed() {
+	r.soft.conns.Add(-1)
+}
+
+// checkRequestSoftLimits checks the header and body thresholds of a parsed request
+func (r *Router) checkRequestSoftLimits(req *Request) {
+	cfg := r.soft.cfg
+
+	if cfg.HeaderBytes > 0 && req.headerBytes >= cfg.HeaderBytes {
+		r.softLimitReached(softHeaders, int64(req.headerBytes), int64(cfg.HeaderBytes))
+	}
+
+	if cfg.BodyFraction > 0 && r.parserConfig.MaxBodyBytes > 0 {
+		threshold := int64(cfg.BodyFraction * float64(r.parserConfig.MaxBodyBytes))
+		size, err := strconv.ParseInt(req.Headers.Get("Content-Length"), 10, 64)
+		if err == nil && size > threshold {
+			r.softLimitReached(softBody, size, threshold)
+		}
+	}
+}
+
+// softLimitReached counts a crossing, notifies observers and logs at most
+// once per LogInterval for the kind
+func (r *Router) softLimitReached(kind softKind, value, threshold int64) {
+	r.soft.counts[kind].Add(1)
+
+	e := SoftLimitEvent{Limit: softLimitKinds[kind], Value: value, Threshold: threshold}
+	for _, o := range r.observers {
+		if so, ok := o.(SoftLimitObserver); ok {
+			so.SoftLimitReached(e)
+		}
+	}
+
+	now := r.clock().UnixNano()
+	last := r.soft.lastLog[kind].Load()
+	if now-last < int64(r.soft.cfg.LogInterval) || !r.soft.lastLog[kind].CompareAndSwap(last, now) {
+		return
+	}
+	log.Warn("Soft limit", e.Limit, "reached:", value, "threshold", threshold)
+}
@@ -0,0 +1,134 @@
+package gouter
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// softEvents records the soft limit events delivered to an observer
+type softEvents struct {
+	mu     sync.Mutex
+	events []SoftLimitEvent
+}
+
+func (o *softEvents) RequestDone(RequestTiming) {}
+func (o *softEvents) ConnClosed(ConnStats)      {}
+
+func (o *softEvents) SoftLimitReached(e SoftLimitEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.events = append(o.events, e)
+}
+
+func (o *softEvents) len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.events)
+}
+
+// softWarnings runs fn with stdout captured and counts the soft limit warnings
+func softWarnings(t *testing.T, fn func()) int {
+	t.Helper()
+	stdout := os.Stdout
+	rd, wr, _ := os.Pipe()
+	os.Stdout = wr
+	fn()
+	os.Stdout = stdout
+	wr.Close()
+	out, _ := io.ReadAll(rd)
+	return strings.Count(string(out), "Soft limit")
+}
+
+func TestSoftLimitLogOncePerInterval(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := newTestRouter()
+	r.SetClock(func() time.Time { return now })
+	r.SetSoftLimits(SoftLimits{HeaderBytes: 100, BodyFraction: 0.5, LogInterval: time.Minute})
+	r.UpdateParser(func(p *ParserConfig) { p.MaxBodyBytes = 1000 })
+	seen := &softEvents{}
+	r.Observe(seen)
+
+	big := &Request{Headers: Headers{"content-length": "600"}, headerBytes: 200}
+	small := &Request{Headers: Headers{"content-length": "500"}, headerBytes: 99}
+
+	logs := softWarnings(t, func() {
+		for i := 0; i < 5; i++ {
+			r.checkRequestSoftLimits(big)
+			r.checkRequestSoftLimits(small)
+		}
+	})
+	if logs != 2 {
+		t.Errorf("%d warnings within the interval, want one per kind", logs)
+	}
+	counts := r.SoftLimitCounts()
+	if counts[SoftLimitHeaders] != 5 || counts[SoftLimitBody] != 5 || counts[SoftLimitConnections] != 0 {
+		t.Errorf("counts = %v, want 5 header and 5 body crossings", counts)
+	}
+	if n := seen.len(); n != 10 {
+		t.Errorf("observer got %d events, want every crossing", n)
+	}
+	if e := seen.events[0]; e.Limit != SoftLimitHeaders || e.Value != 200 || e.Threshold != 100 {
+		t.Errorf("first event = %+v, want header_bytes 200 over 100", e)
+	}
+	if e := seen.events[1]; e.Limit != SoftLimitBody || e.Value != 600 || e.Threshold != 500 {
+		t.Errorf("second event = %+v, want body 600 over 500", e)
+	}
+
+	now = now.Add(59 * time.Second)
+	if logs := softWarnings(t, func() { r.checkRequestSoftLimits(big) }); logs != 0 {
+		t.Errorf("%d warnings before the interval elapsed, want none", logs)
+	}
+	now = now.Add(time.Second)
+	if logs := softWarnings(t, func() { r.checkRequestSoftLimits(big) }); logs != 2 {
+		t.Errorf("%d warnings once the interval elapsed, want one per kind", logs)
+	}
+	if counts := r.SoftLimitCounts(); counts[SoftLimitHeaders] != 7 {
+		t.Errorf("header count = %d, want 7", counts[SoftLimitHeaders])
+	}
+}
+
+func TestSoftLimitConnections(t *testing.T) {
+	r := newTestRouter()
+	r.SetSoftLimits(SoftLimits{Connections: 2})
+
+	logs := softWarnings(t, func() {
+		r.connOpened()
+		r.connOpened()
+		r.connOpened()
+		r.connClosed()
+		r.connClosed()
+		r.connOpened()
+	})
+	if logs != 1 {
+		t.Errorf("%d warnings, want one within the default interval", logs)
+	}
+	if n := r.SoftLimitCounts()[SoftLimitConnections]; n != 3 {
+		t.Errorf("connection count = %d, want crossings at 2, 3 and 2 again", n)
+	}
+}
+
+func TestSoftLimitOverTheWire(t *testing.T) {
+	r := newTestRouter()
+	r.SetSoftLimits(SoftLimits{HeaderBytes: 64, LogInterval: time.Hour})
+	seen := &softEvents{}
+	r.Observe(seen)
+	r.Get("/", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	addr := serveRouter(t, r)
+
+	// Only the padded request crosses the threshold, and it is still served
+	padded := "GET / HTTP/1.1\r\nHost: x\r\nX-Pad: " + strings.Repeat("p", 64) + "\r\n\r\n"
+	resps := rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n"+padded+padded, 3)
+	for i, resp := range resps {
+		if got := bodyString(t, resp); resp.StatusCode != 200 || got != "ok" {
+			t.Errorf("response %d = %d %q, want 200 ok", i+1, resp.StatusCode, got)
+		}
+	}
+	eventually(t, "two soft limit events", func() bool { return seen.len() == 2 })
+	if n := r.SoftLimitCounts()[SoftLimitHeaders]; n != 2 {
+		t.Errorf("header count = %d, want 2", n)
+	}
+}
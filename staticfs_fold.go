@@ -0,0 +1,7 @@
+//go:build windows || darwin
+
+package gouter
+
+// caseInsensitiveFS is set where the default filesystems ignore case, so
+// static paths are matched against the names stored on disk
+const caseInsensitiveFS = true
@@ -0,0 +1,6 @@
+//go:build !windows && !darwin
+
+package gouter
+
+// caseInsensitiveFS is set where the default filesystems ignore case
+const caseInsensitiveFS = false
@@ -0,0 +1,104 @@
+package gouter
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// StaticOptions restricts what ServerStaticOptions serves
+type StaticOptions struct {
+	// DenyPatterns are path.Match globs checked against every name in the
+	// request path and against the path below the root (e.g., "*.env" or
+	// "private/*"). On case-insensitive filesystems they are checked against
+	// the names as stored on disk, whatever case the request used
+	DenyPatterns []string
+	HideDotfiles bool // Refuse names starting with "."
+}
+
+// resolveStaticPath maps the part of a URL path below a static mount to a
+// file under root, with forward slashes as the only separator
+// Returns the filesystem path, or the status answering the request
+func resolveStaticPath(root, urlPath string, opts *StaticOptions) (string, int) {
+	decoded, err := url.PathUnescape(urlPath)
+	if err != nil {
+		return "", http.StatusBadRequest
+	}
+	// A backslash separates directories on Windows only; refusing it keeps
+	// a mount serving the same files on every platform
+	if strings.ContainsAny(decoded, "\\\x00") {
+		return "", http.StatusBadRequest
+	}
+
+	// Rooted before cleaning, ".." cannot climb above the mount
+	rel := strings.TrimPrefix(path.Clean("/"+decoded), "/")
+	if rel == "" {
+		return root, 0
+	}
+
+	names := strings.Split(rel, "/")
+	if caseInsensitiveFS {
+		var ok bool
+		if names, ok = onDiskNames(root, names); !ok {
+			return "", http.StatusNotFound
+		}
+		rel = strings.Join(names, "/")
+	}
+	if opts.denies(names) {
+		return "", http.StatusNotFound
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), 0
+}
+
+// denies reports whether any name, or the path they form, is refused
+func (o *StaticOptions) denies(names []string) bool {
+	for i, name := range names {
+		if o.HideDotfiles && strings.HasPrefix(name, ".") {
+			return true
+		}
+		prefix := strings.Join(names[:i+1], "/")
+		for _, pattern := range o.DenyPatterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+			if ok, _ := path.Match(pattern, prefix); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// onDiskNames replaces each name by the directory entry it opens on a
+// case-insensitive filesystem, preferring an exact match
+// Names no entry lists (short 8.3 names, trailing dots, alternate data
+// streams) would open a file under another name, so they resolve to nothing
+func onDiskNames(root string, names []string) ([]string, bool) {
+	resolved := make([]string, len(names))
+	dir := root
+	for i, name := range names {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, false
+		}
+		found := ""
+		for _, e := range entries {
+			if e.Name() == name {
+				found = name
+				break
+			}
+			if found == "" && strings.EqualFold(e.Name(), name) {
+				found = e.Name()
+			}
+		}
+		if found == "" {
+			return nil, false
+		}
+		resolved[i] = found
+		dir = filepath.Join(dir, found)
+	}
+	return resolved, true
+}
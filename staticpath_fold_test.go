@@ -0,0 +1,31 @@
+//go:build windows || darwin
+
+package gouter
+
+import "testing"
+
+func TestStaticCaseVariations(t *testing.T) {
+	addr := guardedStatic(t)
+	cases := []struct {
+		target string
+		status int
+	}{
+		{"/static/PUBLIC.TXT", 200},
+		{"/static/DOCS/readme.md", 200},
+
+		// Deny rules match the names stored on disk
+		{"/static/SECRET.ENV", 404},
+		{"/static/Secret.Env", 404},
+		{"/static/PRIVATE/key.pem", 404},
+		{"/static/Private/KEY.PEM", 404},
+		{"/static/.GIT/config", 404},
+		{"/static/docs/../SECRET.env", 404},
+	}
+	for _, tc := range cases {
+		resp := sendTarget(t, addr, tc.target)
+		bodyString(t, resp)
+		if resp.StatusCode != tc.status {
+			t.Errorf("GET %s = %d, want %d", tc.target, resp.StatusCode, tc.status)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package gouter
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// staticTree writes files (slash-separated names to contents) under a fresh
+// mount root; the root sits in a directory that also holds outside.txt
+func staticTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	parent := t.TempDir()
+	if err := os.WriteFile(filepath.Join(parent, "outside.txt"), []byte("outside"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(parent, "root")
+	for name, body := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// guardedStatic serves a tree under /static with the deny rules of the tests
+func guardedStatic(t *testing.T) string {
+	t.Helper()
+	root := staticTree(t, map[string]string{
+		"public.txt":      "public",
+		"secret.env":      "secret",
+		".git/config":     "secret",
+		"private/key.pem": "secret",
+		"docs/Readme.md":  "readme",
+	})
+	r := newTestRouter()
+	ServerStaticOptions(r, "/static", root, StaticOptions{
+		DenyPatterns: []string{"*.env", "private/*"},
+		HideDotfiles: true,
+	})
+	return serveRouter(t, r)
+}
+
+func TestStaticPathResolution(t *testing.T) {
+	addr := guardedStatic(t)
+	cases := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/static/public.txt", 200, "public"},
+		{"/static/%70ublic.txt", 200, "public"},
+		{"/static/docs/Readme.md", 200, "readme"},
+		{"/static/docs/./../public.txt", 200, "public"},
+
+		// Encoded separators and NUL never reach the filesystem
+		{"/static/..%5Coutside.txt", 400, ""},
+		{"/static/docs%5CReadme.md", 400, ""},
+		{"/static/public.txt%00.md", 400, ""},
+		{"/static/bad%zzescape", 400, ""},
+
+		// ".." stops at the mount, decoded or not
+		{"/static/%2e%2e/outside.txt", 404, ""},
+		{"/static/docs/%2E%2E/%2E%2E/outside.txt", 404, ""},
+
+		// Denied names answer as if they did not exist
+		{"/static/secret.env", 404, ""},
+		{"/static/.git/config", 404, ""},
+		{"/static/private/key.pem", 404, ""},
+		{"/static/docs/../secret.env", 404, ""},
+	}
+	for _, tc := range cases {
+		resp := sendTarget(t, addr, tc.target)
+		body := bodyString(t, resp)
+		if resp.StatusCode != tc.status {
+			t.Errorf("GET %s = %d, want %d", tc.target, resp.StatusCode, tc.status)
+			continue
+		}
+		if tc.body != "" && body != tc.body {
+			t.Errorf("GET %s body = %q, want %q", tc.target, body, tc.body)
+		}
+	}
+}
+
+func TestStaticListingHidesDenied(t *testing.T) {
+	addr := guardedStatic(t)
+	cases := []struct {
+		target string
+		shown  []string
+		hidden []string
+	}{
+		{"/static/", []string{"public.txt", "docs/", "private/"}, []string{"secret.env", ".git"}},
+		{"/static/private", nil, []string{"key.pem"}},
+		{"/static/docs", []string{"Readme.md"}, nil},
+	}
+	for _, tc := range cases {
+		resp := sendTarget(t, addr, tc.target)
+		body := bodyString(t, resp)
+		if resp.StatusCode != 200 {
+			t.Errorf("GET %s = %d, want a listing", tc.target, resp.StatusCode)
+			continue
+		}
+		for _, name := range tc.shown {
+			if !strings.Contains(body, name) {
+				t.Errorf("GET %s lists no %s:\n%s", tc.target, name, body)
+			}
+		}
+		for _, name := range tc.hidden {
+			if strings.Contains(body, name) {
+				t.Errorf("GET %s lists the denied %s", tc.target, name)
+			}
+		}
+	}
+}
+
+func TestStaticOnDiskNames(t *testing.T) {
+	root := staticTree(t, map[string]string{
+		"Docs/README.md": "readme",
+		"secret.env":     "secret",
+	})
+
+	names, ok := onDiskNames(root, []string{"docs", "readme.MD"})
+	if !ok || !slices.Equal(names, []string{"Docs", "README.md"}) {
+		t.Errorf("onDiskNames = %q, %v; want the stored case", names, ok)
+	}
+	if _, ok := onDiskNames(root, []string{"Docs", "missing.md"}); ok {
+		t.Error("a name without a directory entry resolved")
+	}
+	if _, ok := onDiskNames(root, []string{"secret.env", "x"}); ok {
+		t.Error("a name below a file resolved")
+	}
+
+	// Deny rules see the stored name, so a case variation cannot dodge them
+	opts := &StaticOptions{DenyPatterns: []string{"*.env"}}
+	if opts.denies([]string{"SECRET.ENV"}) {
+		t.Fatal("the request name alone should not match the lowercase glob")
+	}
+	names, ok = onDiskNames(root, []string{"SECRET.ENV"})
+	if !ok || !opts.denies(names) {
+		t.Errorf("onDiskNames = %q, %v; want a denied secret.env", names, ok)
+	}
+
+	// An exact entry wins over a case-folded one where both can exist
+	if !caseInsensitiveFS {
+		if err := os.WriteFile(filepath.Join(root, "SECRET.ENV"), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"secret.env", "SECRET.ENV"} {
+			if names, _ := onDiskNames(root, []string{name}); !slices.Equal(names, []string{name}) {
+				t.Errorf("onDiskNames(%q) = %q, want the exact entry", name, names)
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package gouter
+
+import "testing"
+
+func TestStaticWindowsAliases(t *testing.T) {
+	addr := guardedStatic(t)
+
+	// Names Windows opens under another name are listed by no directory
+	// entry, so they cannot reach a denied file
+	for _, target := range []string{
+		"/static/secret.env.",
+		"/static/secret.env%20",
+		"/static/secret.env::$DATA",
+		"/static/SECRET~1.ENV",
+		"/static/PRIVATE~1/key.pem",
+		"/static/public.txt.",
+	} {
+		resp := sendTarget(t, addr, target)
+		bodyString(t, resp)
+		if resp.StatusCode != 404 {
+			t.Errorf("GET %s = %d, want 404", target, resp.StatusCode)
+		}
+	}
+
+	// Drive letters and UNC prefixes are plain names below the mount
+	for _, target := range []string{"/static/C:/Windows/win.ini", "/static//server/share/x"} {
+		resp := sendTarget(t, addr, target)
+		bodyString(t, resp)
+		if resp.StatusCode == 200 {
+			t.Errorf("GET %s served a file outside the mount", target)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package gouter
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// StrictHTTP switches the server to strict RFC 9110/9112 parsing
+// Strict mode rejects with 400 (505 for other HTTP major versions) what the
+// default mode tolerates for old or sloppy clients:
+//   - obsolete line folding, even with ParserConfig.AllowObsFold
+//   - control characters other than tab in header values
+//   - malformed HTTP versions and request targets
+//   - HTTP/1.1 requests without Host
+//   - Content-Length values other than plain digits
+//   - Transfer-Encoding in HTTP/1.0 requests
+//
+// ParserConfig.HeaderAliases is ignored, so header names are kept as sent,
+// and responses also carry a Date header. Call it before Serve; see
+// ConformanceReport for the resulting list of requirements
+func (s *Server) StrictHTTP(on bool) {
+	s.strict = on
+}
+
+// ConformanceCheck is one protocol requirement and whether the server meets it
+type ConformanceCheck struct {
+	Requirement string // What the RFC asks for
+	Reference   string // RFC section
+	Satisfied   bool   // Met by the current configuration
+	Note        string // How it is met or why it is relaxed
+}
+
+// ConformanceReport lists the protocol requirements a Server meets
+type ConformanceReport []ConformanceCheck
+
+// ConformanceReport describes which RFC 9110/9112 requirements the current
+// configuration enforces and which it relaxes
+func (s *Server) ConformanceReport() ConformanceReport {
+	cfg := &ParserConfig{}
+	if s.Router != nil && s.Router.parserConfig != nil {
+		cfg = s.Router.parserConfig
+	}
+	strict := s.strict
+	relaxed := "relaxed outside StrictHTTP"
+
+	check := func(req, ref string, ok bool, met, why string) ConformanceCheck {
+		c := ConformanceCheck{Requirement: req, Reference: ref, Satisfied: ok, Note: met}
+		if !ok {
+			c.Note = why
+		}
+		return c
+	}
+
+	return ConformanceReport{
+		check("Reject messages with both Transfer-Encoding and Content-Length", "RFC 9112 6.1", true, "always enforced", ""),
+		check("Reject conflicting Content-Length values", "RFC 9112 6.3", true, "always enforced", ""),
+		check("Reject whitespace between field name and colon", "RFC 9112 5.1", true, "always enforced", ""),
+		check("Reject bare CR and LF in the request line and fields", "RFC 9112 2.2", true, "always enforced", ""),
+		check("Reject more than one Host field", "RFC 9112 3.2", true, "always enforced", ""),
+		check("Frame every response with Content-Length, chunked encoding or close", "RFC 9112 6.3", true, "always enforced", ""),
+		check("Reject obsolete line folding", "RFC 9112 5.2", strict || !cfg.AllowObsFold,
+			"rejected with 400", "ParserConfig.AllowObsFold unfolds it"),
+		check("Field names are tokens, without renaming", "RFC 9110 5.1", strict || cfg.HeaderAliases == nil,
+			"names are kept as sent", "ParserConfig.HeaderAliases renames underscore names"),
+		check("Field values hold no control characters but tab", "RFC 9110 5.5", strict,
+			"rejected with 400", relaxed),
+		check("HTTP-version is HTTP/DIGIT.DIGIT, major 1", "RFC 9112 2.3", strict,
+			"malformed versions get 400, other majors 505", relaxed),
+		check("Request-target is in origin, absolute or asterisk form", "RFC 9112 3.2", strict,
+			"other targets get 400", relaxed),
+		check("Reject HTTP/1.1 requests without Host", "RFC 9112 3.2", strict,
+			"rejected with 400", relaxed),
+		check("Content-Length is 1*DIGIT", "RFC 9110 8.6", strict,
+			"signs and spaces are rejected", relaxed),
+		check("Treat Transfer-Encoding in HTTP/1.0 as faulty framing", "RFC 9112 6.1", strict,
+			"rejected with 400", relaxed),
+		check("Send Date in responses", "RFC 9110 6.6.1", strict,
+			"IMF-fixdate from the router Clock", relaxed),
+		check("Accept the three HTTP-date formats, send IMF-fixdate", "RFC 9110 5.6.7", true, "always enforced", ""),
+	}
+}
+
+// String formats the report as one line per requirement
+func (c ConformanceReport) String() string {
+	var b strings.Builder
+	for _, check := range c {
+		mark := "ok     "
+		if !check.Satisfied {
+			mark = "RELAXED"
+		}
+		fmt.Fprintf(&b, "%s  %-14s %s (%s)\n", mark, check.Reference, check.Requirement, check.Note)
+	}
+	return b.String()
+}
+
+// strictParserConfig returns cfg without the tolerances strict mode refuses
+func strictParserConfig(cfg *ParserConfig) *ParserConfig {
+	c := *cfg
+	c.AllowObsFold = false
+	c.HeaderAliases = nil
+	return &c
+}
+
+// checkStrict applies the strict mode checks the parser does not make
+func checkStrict(req *Request) error {
+	v := req.Version
+	if len(v) != 8 || !strings.HasPrefix(v, "HTTP/") || !isDigit(v[5]) || v[6] != '.' || !isDigit(v[7]) {
+		return &httpError{http.StatusBadRequest, errors.New("malformed HTTP version: " + v)}
+	}
+	if v[5] != '1' {
+		return &httpError{http.StatusHTTPVersionNotSupported, errors.New("unsupported HTTP version: " + v)}
+	}
+
+	switch {
+	case strings.HasPrefix(req.path, "/"):
+	case req.path == "*" && req.Method == "OPTIONS":
+	case strings.HasPrefix(req.path, "http://") || strings.HasPrefix(req.path, "https://"):
+	default:
+		return &httpError{http.StatusBadRequest, errors.New("invalid request target")}
+	}
+
+	if _, ok := req.Headers["host"]; !ok && v == "HTTP/1.1" {
+		return &httpError{http.StatusBadRequest, errors.New("missing host header")}
+	}
+
+	for name, value := range req.Headers {
+		for i := 0; i < len(value); i++ {
+			if c := value[i]; (c < ' ' && c != '\t') || c == 0x7f {
+				return &httpError{http.StatusBadRequest, errors.New("invalid character in header " + name)}
+			}
+		}
+	}
+
+	if cl, ok := req.Headers["content-length"]; ok {
+		for _, part := range strings.Split(cl, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" || strings.IndexFunc(part, func(r rune) bool { return r < '0' || r > '9' }) != -1 {
+				return &httpError{http.StatusBadRequest, errors.New("invalid content-length: " + cl)}
+			}
+		}
+	}
+
+	if _, ok := req.Headers["transfer-encoding"]; ok && v == "HTTP/1.0" {
+		return &httpError{http.StatusBadRequest, errors.New("transfer-encoding in an HTTP/1.0 request")}
+	}
+	return nil
+}
+
+// isDigit reports whether c is an ASCII digit
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
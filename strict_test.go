@@ -0,0 +1,128 @@
+package gouter
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// strictServer serves an echo route with the tolerances of the lenient
+// mode turned on, in strict mode or not
+func strictServer(t *testing.T, strict bool) (*Server, string) {
+	t.Helper()
+	r := newTestRouter()
+	r.UpdateParser(func(p *ParserConfig) {
+		p.AllowObsFold = true
+		p.HeaderAliases = DefaultHeaderAliases
+	})
+	r.Route("/echo", func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.Write(body)
+	})
+	s := &Server{Router: r}
+	s.StrictHTTP(strict)
+	return s, serveTest(t, s)
+}
+
+// conformanceCheck finds a requirement of the report by its text
+func conformanceCheck(t *testing.T, report ConformanceReport, requirement string) ConformanceCheck {
+	t.Helper()
+	for _, c := range report {
+		if c.Requirement == requirement {
+			return c
+		}
+	}
+	t.Fatalf("report has no %q", requirement)
+	return ConformanceCheck{}
+}
+
+func TestStrictHTTPMatrix(t *testing.T) {
+	lenient, lenientAddr := strictServer(t, false)
+	strict, strictAddr := strictServer(t, true)
+	lenientReport, strictReport := lenient.ConformanceReport(), strict.ConformanceReport()
+
+	// Each request is refused (or, for renaming, changed) only where the
+	// report claims the requirement is met
+	cases := []struct {
+		requirement string
+		raw         string
+		lenient     string // Status and body in the lenient mode
+		strict      string
+	}{
+		{"Reject obsolete line folding", "GET /echo HTTP/1.1\r\nHost: x\r\nX-A: 1\r\n 2\r\n\r\n", "200 ", "400"},
+		{"Field names are tokens, without renaming", "POST /echo HTTP/1.1\r\nHost: x\r\nContent_Length: 2\r\n\r\nok", "200 ok", "200 "},
+		{"Field values hold no control characters but tab", "GET /echo HTTP/1.1\r\nHost: x\r\nX-A: a\x01b\r\n\r\n", "200 ", "400"},
+		{"HTTP-version is HTTP/DIGIT.DIGIT, major 1", "GET /echo HTTP/1.10\r\nHost: x\r\n\r\n", "200 ", "400"},
+		{"HTTP-version is HTTP/DIGIT.DIGIT, major 1", "GET /echo HTTP/2.0\r\nHost: x\r\n\r\n", "200 ", "505"},
+		{"Request-target is in origin, absolute or asterisk form", "GET echo HTTP/1.1\r\nHost: x\r\n\r\n", "200 ", "400"},
+		{"Reject HTTP/1.1 requests without Host", "GET /echo HTTP/1.1\r\n\r\n", "200 ", "400"},
+		{"Content-Length is 1*DIGIT", "POST /echo HTTP/1.1\r\nHost: x\r\nContent-Length: +2\r\n\r\nok", "200 ok", "400"},
+		{"Treat Transfer-Encoding in HTTP/1.0 as faulty framing", "POST /echo HTTP/1.0\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\n\r\n", "200 ok", "400"},
+
+		{"Reject messages with both Transfer-Encoding and Content-Length", "POST /echo HTTP/1.1\r\nHost: x\r\nContent-Length: 2\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\n\r\n", "400", "400"},
+		{"Reject conflicting Content-Length values", "POST /echo HTTP/1.1\r\nHost: x\r\nContent-Length: 2\r\nContent-Length: 3\r\n\r\nokk", "400", "400"},
+		{"Reject whitespace between field name and colon", "GET /echo HTTP/1.1\r\nHost : x\r\n\r\n", "400", "400"},
+		{"Reject bare CR and LF in the request line and fields", "GET /echo HTTP/1.1\r\nHost: x\r\nX-A: 1\nX-B: 2\r\n\r\n", "400", "400"},
+		{"Reject more than one Host field", "GET /echo HTTP/1.1\r\nHost: x\r\nHost: y\r\n\r\n", "400", "400"},
+	}
+	outcome := func(addr, raw string) string {
+		resp := rawExchange(t, addr, raw, 1)[0]
+		if resp.StatusCode >= 400 {
+			return strconv.Itoa(resp.StatusCode)
+		}
+		return strconv.Itoa(resp.StatusCode) + " " + bodyString(t, resp)
+	}
+	for _, tc := range cases {
+		name := strings.SplitN(tc.raw, "\r\n", 2)[0]
+		if got := outcome(lenientAddr, tc.raw); got != tc.lenient {
+			t.Errorf("lenient %q (%s) = %q, want %q", name, tc.requirement, got, tc.lenient)
+		}
+		if got := outcome(strictAddr, tc.raw); got != tc.strict {
+			t.Errorf("strict %q (%s) = %q, want %q", name, tc.requirement, got, tc.strict)
+		}
+
+		differs := tc.lenient != tc.strict
+		if lc, sc := conformanceCheck(t, lenientReport, tc.requirement), conformanceCheck(t, strictReport, tc.requirement); lc.Satisfied == differs || !sc.Satisfied {
+			t.Errorf("%s: report says lenient %v, strict %v; outcomes differ: %v", tc.requirement, lc.Satisfied, sc.Satisfied, differs)
+		}
+	}
+
+	// Only strict responses carry Date
+	lenientDate := rawExchange(t, lenientAddr, "GET /echo HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0].Header.Get("Date")
+	strictDate := rawExchange(t, strictAddr, "GET /echo HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0].Header.Get("Date")
+	if lenientDate != "" || !strings.HasSuffix(strictDate, " GMT") {
+		t.Errorf("Date lenient %q, strict %q", lenientDate, strictDate)
+	}
+	if conformanceCheck(t, lenientReport, "Send Date in responses").Satisfied || !conformanceCheck(t, strictReport, "Send Date in responses").Satisfied {
+		t.Error("report disagrees with the Date headers")
+	}
+
+	// Strict mode meets every requirement
+	for _, c := range strictReport {
+		if !c.Satisfied {
+			t.Errorf("strict mode relaxes %q: %s", c.Requirement, c.Note)
+		}
+	}
+}
+
+func TestConformanceReportFollowsConfig(t *testing.T) {
+	r := newTestRouter()
+	s := &Server{Router: r}
+	report := s.ConformanceReport()
+	if c := conformanceCheck(t, report, "Reject obsolete line folding"); !c.Satisfied {
+		t.Errorf("default config relaxes obs-fold: %+v", c)
+	}
+	if c := conformanceCheck(t, report, "Reject HTTP/1.1 requests without Host"); c.Satisfied || c.Note != "relaxed outside StrictHTTP" {
+		t.Errorf("default mode check = %+v", c)
+	}
+
+	r.UpdateParser(func(p *ParserConfig) { p.AllowObsFold = true })
+	text := s.ConformanceReport().String()
+	if !strings.Contains(text, "RELAXED  RFC 9112 5.2   Reject obsolete line folding (ParserConfig.AllowObsFold unfolds it)\n") {
+		t.Errorf("report:\n%s", text)
+	}
+	if n := strings.Count(text, "\n"); n != len(report) {
+		t.Errorf("report has %d lines for %d checks", n, len(report))
+	}
+}
@@ -0,0 +1,142 @@
+package gouter
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// defaultSurrogateHeaders are sent by SurrogateKeys unless the router
+// configures others with SurrogateKeyHeaders
+var defaultSurrogateHeaders = []string{"Surrogate-Key"}
+
+// Purger invalidates responses cached at the edge (CDN, Varnish) by
+// surrogate key, typically through the provider API client
+type Purger interface {
+	Purge(keys []string) error
+}
+
+// PurgerFunc adapts a function to the Purger interface
+type PurgerFunc func(keys []string) error
+
+func (f PurgerFunc) Purge(keys []string) error {
+	return f(keys)
+}
+
+// purgeRegistry holds what Purge invalidates, process-wide
+var purgeRegistry struct {
+	mu      sync.Mutex
+	purgers map[*Router]Purger
+	caches  []*ResponseCache
+}
+
+// SurrogateKeyHeaders sets the response headers carrying surrogate keys
+// Keys are space-separated, except in Cache-Tag which takes a comma list
+// Default: Surrogate-Key
+func (r *Router) SurrogateKeyHeaders(names ...string) {
+	r.surrogateHeaders = append([]string(nil), names...)
+}
+
+// SetPurger registers the edge purger called by Purge
+// A nil purger removes the router's one
+func (r *Router) SetPurger(p Purger) {
+	purgeRegistry.mu.Lock()
+	defer purgeRegistry.mu.Unlock()
+
+	if p == nil {
+		delete(purgeRegistry.purgers, r)
+		return
+	}
+	if purgeRegistry.purgers == nil {
+		purgeRegistry.purgers = make(map[*Router]Purger)
+	}
+	purgeRegistry.purgers[r] = p
+}
+
+// Purge invalidates every response tagged with one of keys, in the local
+// ResponseCaches and through the purgers of every router (see SetPurger)
+// Keys are sanitized and deduplicated as in SurrogateKeys
+// Returns the purger errors joined
+func Purge(keys ...string) error {
+	keys = appendSurrogateKeys(nil, keys)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	purgeRegistry.mu.Lock()
+	caches := append([]*ResponseCache(nil), purgeRegistry.caches...)
+	purgers := make([]Purger, 0, len(purgeRegistry.purgers))
+	for _, p := range purgeRegistry.purgers {
+		purgers = append(purgers, p)
+	}
+	purgeRegistry.mu.Unlock()
+
+	for _, c := range caches {
+		c.Invalidate(keys...)
+	}
+
+	var errs []error
+	for _, p := range purgers {
+		if err := p.Purge(keys); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SurrogateKeys tags the response for edge cache invalidation
+// Keys accumulate across calls. Whitespace, commas and control characters
+// are removed from each key, and empty or repeated keys are dropped
+func (w *Writer) SurrogateKeys(keys ...string) {
+	w.pool.checkReleased("Writer", 1)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.done || w.headersSent {
+		return
+	}
+	w.surrogateKeys = appendSurrogateKeys(w.surrogateKeys, keys)
+	if len(w.surrogateKeys) == 0 {
+		return
+	}
+
+	names := w.surrogateHeaders
+	if names == nil {
+		names = defaultSurrogateHeaders
+	}
+	for _, name := range names {
+		sep := " "
+		if strings.EqualFold(name, "Cache-Tag") {
+			sep = ","
+		}
+		w.Headers.Add(name, strings.Join(w.surrogateKeys, sep))
+	}
+}
+
+// appendSurrogateKeys appends the sanitized keys missing from dst
+func appendSurrogateKeys(dst, keys []string) []string {
+	for _, k := range keys {
+		k = strings.Map(func(c rune) rune {
+			if c <= ' ' || c == ',' || c == 0x7f {
+				return -1
+			}
+			return c
+		}, k)
+		if k == "" || containsString(dst, k) {
+			continue
+		}
+		dst = append(dst, k)
+	}
+	return dst
+}
+
+// containsString reports whether list holds s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
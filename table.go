@@ -0,0 +1,245 @@
+package gouter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// routeTableVersion identifies the layout of exported route tables
+const routeTableVersion = 1
+
+// TableEntry is the canonical representation of a route in an exported table
+type TableEntry struct {
+	Method      string      `json:"method"`
+	Path        string      `json:"path"`
+	Handler     string      `json:"handler"`
+	Description string      `json:"description,omitempty"`
+	Parameters  []ParamInfo `json:"parameters,omitempty"`
+	Middlewares []string    `json:"middlewares,omitempty"`
+	Hidden      bool        `json:"hidden,omitempty"`
+	Protocol    string      `json:"protocol,omitempty"`
+	Host        string      `json:"host,omitempty"`
+	Scopes      []string    `json:"scopes,omitempty"`
+	Deprecation string      `json:"deprecation,omitempty"`
+	Version     string      `json:"version,omitempty"`
+}
+
+// routeTable is the document produced by ExportTable
+type routeTable struct {
+	Version int          `json:"version"`
+	Routes  []TableEntry `json:"routes"`
+}
+
+// ChangeKind describes how a route differs between two tables
+type ChangeKind string
+
+const (
+	RouteAdded   ChangeKind = "added"
+	RouteRemoved ChangeKind = "removed"
+	RouteChanged ChangeKind = "changed"
+)
+
+// Change is a single difference reported by DiffTables
+type Change struct {
+	Kind   ChangeKind // Added, removed or changed
+	Method string     // HTTP method of the route
+	Path   string     // Route path pattern
+	Host   string     // Host pattern, empty for routes served on any host
+	Fields []string   // Names of the changed fields (RouteChanged only)
+}
+
+// String formats the change as a single human-readable line
+func (c Change) String() string {
+	if c.Kind == RouteChanged {
+		return fmt.Sprintf("%s %s %s%s %v", c.Kind, c.Method, c.Host, c.Path, c.Fields)
+	}
+	return fmt.Sprintf("%s %s %s%s", c.Kind, c.Method, c.Host, c.Path)
+}
+
+// ExportTable serializes the route table to canonical JSON
+// Routes are sorted by path then method so identical configurations always
+// produce identical bytes, suitable for diffing between deployments
+func (r *Router) ExportTable() ([]byte, error) {
+	table := routeTable{
+		Version: routeTableVersion,
+		Routes:  make([]TableEntry, 0, len(r.docs)),
+	}
+
+	for _, doc := range r.docs {
+		// Routes split by method list each method they serve
+		for _, method := range r.docMethods(doc) {
+			table.Routes = append(table.Routes, tableEntry(doc, method))
+		}
+	}
+
+	sort.Slice(table.Routes, func(i, j int) bool {
+		a, b := table.Routes[i], table.Routes[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Host < b.Host
+	})
+
+	return json.MarshalIndent(table, "", "  ")
+}
+
+// tableEntry is the exported form of doc served with method
+func tableEntry(doc *RouteInfo, method string) TableEntry {
+	return TableEntry{
+		Method:      method,
+		Path:        doc.Path,
+		Handler:     doc.HandlerName,
+		Description: doc.Description,
+		Parameters:  doc.Parameters,
+		Middlewares: doc.Middlewares,
+		Hidden:      doc.Hidden,
+		Protocol:    doc.Protocol,
+		Host:        doc.Host,
+		Scopes:      doc.Scopes,
+		Deprecation: doc.Deprecation,
+		Version:     doc.Version,
+	}
+}
+
+// ParseTable decodes a route table produced by ExportTable
+func ParseTable(data []byte) ([]TableEntry, error) {
+	var table routeTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("invalid route table: %w", err)
+	}
+
+	if table.Version != routeTableVersion {
+		return nil, fmt.Errorf("unsupported route table version %d", table.Version)
+	}
+
+	return table.Routes, nil
+}
+
+// DiffTables computes the routes added, removed or changed between two exports
+// Changes are ordered by path, method then host
+func DiffTables(old, new []byte) ([]Change, error) {
+	oldRoutes, err := ParseTable(old)
+	if err != nil {
+		return nil, fmt.Errorf("old table: %w", err)
+	}
+
+	newRoutes, err := ParseTable(new)
+	if err != nil {
+		return nil, fmt.Errorf("new table: %w", err)
+	}
+
+	key := func(e TableEntry) string {
+		return e.Method + " " + e.Host + e.Path
+	}
+
+	oldByKey := make(map[string]TableEntry, len(oldRoutes))
+	for _, e := range oldRoutes {
+		oldByKey[key(e)] = e
+	}
+
+	newByKey := make(map[string]TableEntry, len(newRoutes))
+	for _, e := range newRoutes {
+		newByKey[key(e)] = e
+	}
+
+	var changes []Change
+	for k, n := range newByKey {
+		o, ok := oldByKey[k]
+		if !ok {
+			changes = append(changes, Change{Kind: RouteAdded, Method: n.Method, Path: n.Path, Host: n.Host})
+			continue
+		}
+
+		if fields := changedFields(o, n); len(fields) > 0 {
+			changes = append(changes, Change{Kind: RouteChanged, Method: n.Method, Path: n.Path, Host: n.Host, Fields: fields})
+		}
+	}
+
+	for k, o := range oldByKey {
+		if _, ok := newByKey[k]; !ok {
+			changes = append(changes, Change{Kind: RouteRemoved, Method: o.Method, Path: o.Path, Host: o.Host})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Method != b.Method {
+			return a.Method < b.Method
+		}
+		return a.Host < b.Host
+	})
+
+	return changes, nil
+}
+
+// changedFields lists the JSON names of the fields that differ between two entries
+func changedFields(a, b TableEntry) []string {
+	var fields []string
+
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			name := t.Field(i).Name
+			if tag := t.Field(i).Tag.Get("json"); tag != "" {
+				name, _, _ = strings.Cut(tag, ",")
+			}
+			fields = append(fields, name)
+		}
+	}
+
+	return fields
+}
+
+// DocIssue is a documentation gap reported by LintTable
+type DocIssue struct {
+	Method  string // HTTP method of the route
+	Path    string // Route path pattern
+	Message string // What is missing
+}
+
+// String formats the issue as a single human-readable line
+func (i DocIssue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Method, i.Path, i.Message)
+}
+
+// registrationLocation matches the file:line name given to unnamed handlers
+var registrationLocation = regexp.MustCompile(`\.go:\d+$`)
+
+// LintTable reports visible routes with incomplete documentation: no
+// description, no handler name (see SetName) or undescribed parameters
+func LintTable(entries []TableEntry) []DocIssue {
+	var issues []DocIssue
+	for _, e := range entries {
+		if e.Hidden {
+			continue
+		}
+
+		report := func(msg string) {
+			issues = append(issues, DocIssue{Method: e.Method, Path: e.Path, Message: msg})
+		}
+
+		if e.Description == "" {
+			report("missing description")
+		}
+		if registrationLocation.MatchString(e.Handler) {
+			report("unnamed handler " + e.Handler)
+		}
+		for _, p := range e.Parameters {
+			if p.Description == "" {
+				report("parameter " + p.Name + " has no description")
+			}
+		}
+	}
+	return issues
+}
@@ -0,0 +1,111 @@
+package gouter
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffTables(t *testing.T) {
+	noop := func(req *Request, w *Writer) {}
+	audit := func(next Handler) Handler { return next }
+
+	old := newTestRouter()
+	old.Route("/users", noop, "GET").SetName("listUsers")
+	old.Get("/users/:id", noop).SetName("getUser")
+	old.Get("/legacy", noop).SetName("legacy")
+	old.Get("/health", noop).SetName("health")
+
+	next := newTestRouter()
+	next.Use(audit, MiddlewareName("audit"))
+	next.Route("/users", noop, "GET", "POST").SetName("listUsers")
+	next.Get("/users/:id", noop).SetName("getUser").SetDescription("Fetch a user")
+	next.Get("/health", noop).SetName("health").Hide()
+	next.HostRoute("api.example.com", "/health", noop, "GET").SetName("health")
+
+	oldData, err := old.ExportTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	newData, err := next.ExportTable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := DiffTables(oldData, newData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"changed GET /health [middlewares hidden]",
+		"added GET api.example.com/health",
+		"removed GET /legacy",
+		"changed GET /users [middlewares]",
+		"added POST /users",
+		"changed GET /users/:id [description middlewares]",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("changes:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if changes, _ := DiffTables(newData, newData); len(changes) != 0 {
+		t.Errorf("identical tables differ: %v", changes)
+	}
+}
+
+func TestExportTableCanonical(t *testing.T) {
+	build := func(reverse bool) []byte {
+		r := newTestRouter()
+		paths := []string{"/a", "/b/:id", "/c"}
+		if reverse {
+			paths = []string{"/c", "/b/:id", "/a"}
+		}
+		for _, p := range paths {
+			r.Get(p, func(req *Request, w *Writer) {}).SetName("h" + p)
+		}
+		data, err := r.ExportTable()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	if a, b := build(false), build(true); !bytes.Equal(a, b) {
+		t.Errorf("registration order changed the export:\n%s\n%s", a, b)
+	}
+
+	entries, err := ParseTable(build(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[1].Path != "/b/:id" || entries[1].Handler != "h/b/:id" ||
+		len(entries[1].Parameters) != 1 || entries[1].Parameters[0].Name != "id" {
+		t.Errorf("parsed entries = %+v", entries)
+	}
+}
+
+func TestExportTableHandlerLocation(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/x", func(req *Request, w *Writer) {})
+	data, _ := r.ExportTable()
+	entries, err := ParseTable(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(entries[0].Handler, "table_test.go:") {
+		t.Errorf("unnamed handler = %q, want its registration file:line", entries[0].Handler)
+	}
+}
+
+func TestParseTableRejectsOtherVersions(t *testing.T) {
+	if _, err := ParseTable([]byte(`{"version":2,"routes":[]}`)); err == nil {
+		t.Error("ParseTable accepted version 2")
+	}
+	if _, err := DiffTables([]byte(`{`), []byte(`{"version":1}`)); err == nil || !strings.HasPrefix(err.Error(), "old table") {
+		t.Errorf("DiffTables error = %v, want it to name the old table", err)
+	}
+}
@@ -0,0 +1,16 @@
+// Generated by gouter: client manifest schema 1
+
+export interface ClientRoute {
+  method: string;
+  path: string;
+  params?: string[];
+  scopes?: string[];
+  deprecation?: string;
+  version?: string;
+  websocket?: boolean;
+}
+
+export interface ClientManifest {
+  schema: 1;
+  routes: ClientRoute[];
+}
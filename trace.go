@@ -0,0 +1,170 @@
+package gouter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Murilinho145SG/gouter/log"
+)
+
+const (
+	// traceHeader enables tracing per request and carries the trace back
+	traceHeader = "X-Gouter-Trace"
+	// maxTraceHeaderBytes caps the size of the trace response header
+	maxTraceHeaderBytes = 4096
+)
+
+// TraceStep is a single entry of a request trace
+type TraceStep struct {
+	Kind    string        // "route", "enter", "exit" or "event"
+	Name    string        // Route pattern, middleware name or event note
+	At      time.Duration // Offset from the start of the request
+	Elapsed time.Duration // Time spent inside the layer (exit only)
+	Status  int           // Status after the layer, when it changed (exit only)
+	Headers []string      // Response headers added by the layer (exit only)
+}
+
+// String formats the step compactly for logs and the trace header
+func (s TraceStep) String() string {
+	switch s.Kind {
+	case "enter":
+		return ">" + s.Name
+	case "exit":
+		out := "<" + s.Name + " " + s.Elapsed.String()
+		if s.Status != 0 {
+			out += fmt.Sprintf(" status=%d", s.Status)
+		}
+		for _, h := range s.Headers {
+			out += " +" + h
+		}
+		return out
+	default:
+		return s.Kind + "=" + s.Name
+	}
+}
+
+// requestTrace accumulates the steps of a traced request
+type requestTrace struct {
+	start time.Time
+	steps []TraceStep
+}
+
+// add appends a step stamped with its offset from the request start
+func (t *requestTrace) add(step TraceStep) {
+	step.At = time.Since(t.start)
+	t.steps = append(t.steps, step)
+}
+
+// TraceRequests enables tracing of every request
+// Traces are logged at debug level and returned in the X-Gouter-Trace header,
+// so this is meant for development only
+func (r *Router) TraceRequests(enabled bool) {
+	r.tracing = enabled
+}
+
+// AllowTraceHeader enables tracing of requests sending an X-Gouter-Trace header
+// Never enable it in production: any client could then read the trace
+func (r *Router) AllowTraceHeader(enabled bool) {
+	r.traceOnHeader = enabled
+}
+
+// TraceEvent adds a custom annotation to the request trace
+// It does nothing when the request is not traced. Control characters of
+// note are replaced by spaces in the log and the X-Gouter-Trace header
+func TraceEvent(r *Request, note string) {
+	if r.trace == nil {
+		return
+	}
+	r.trace.add(TraceStep{Kind: "event", Name: note})
+}
+
+// Trace returns the steps recorded so far, or nil when the request is not traced
+func (r *Request) Trace() []TraceStep {
+	if r.trace == nil {
+		return nil
+	}
+	return append([]TraceStep(nil), r.trace.steps...)
+}
+
+// startTrace attaches a trace to the request when tracing applies to it
+func (r *Router) startTrace(req *Request) {
+	if r.tracing || (r.traceOnHeader && req.Headers.Get(traceHeader) != "") {
+		req.trace = &requestTrace{start: time.Now()}
+	}
+}
+
+// finishTrace logs the trace and exposes it in a size-capped response header
+func finishTrace(req *Request, w *Writer) {
+	if req.trace == nil {
+		return
+	}
+
+	parts := make([]string, 0, len(req.trace.steps))
+	for _, step := range req.trace.steps {
+		parts = append(parts, step.String())
+	}
+	summary := stripControl(strings.Join(parts, ", "))
+	log.Debug("Trace", req.Method, displayPath(req.path), summary)
+
+	if len(summary) > maxTraceHeaderBytes {
+		summary = summary[:maxTraceHeaderBytes-3] + "..."
+	}
+	w.Headers.Add(traceHeader, summary)
+}
+
+// stripControl replaces control characters with spaces, so notes taken
+// from the request (e.g., a path) cannot split the log line or the
+// trace header
+func stripControl(s string) string {
+	return strings.Map(func(c rune) rune {
+		if (c < ' ' && c != '\t') || c == 0x7f {
+			return ' '
+		}
+		return c
+	}, s)
+}
+
+// traceLayer wraps one middleware layer so traced requests record its
+// enter/exit, duration, status change and added headers
+// Untraced requests only pay a nil check
+func traceLayer(name string, layer Handler) Handler {
+	return func(r *Request, w *Writer) {
+		if r.trace == nil {
+			layer(r, w)
+			return
+		}
+
+		before := make(map[string]bool, len(w.Headers))
+		for k := range w.Headers {
+			before[k] = true
+		}
+		status := w.code
+
+		r.trace.add(TraceStep{Kind: "enter", Name: name})
+		start := time.Now()
+		layer(r, w)
+
+		step := TraceStep{Kind: "exit", Name: name, Elapsed: time.Since(start)}
+		if w.code != status {
+			step.Status = w.code
+		}
+		for k := range w.Headers {
+			if !before[k] {
+				step.Headers = append(step.Headers, k)
+			}
+		}
+		sort.Strings(step.Headers)
+		r.trace.add(step)
+	}
+}
+
+// applyMiddlewares wraps a handler with a chain listed outermost first
+// Each layer, and the handler itself, is traceable by name
+func applyMiddlewares(handler Handler, chain []middlewareEntry) Handler {
+	for i := len(chain) - 1; i >= 0; i-- {
+		handler = traceLayer(chain[i].name, chain[i].mw(handler))
+	}
+	return handler
+}
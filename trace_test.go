@@ -0,0 +1,84 @@
+package gouter
+
+import (
+	"strings"
+	"testing"
+)
+
+// tagHeader returns a middleware setting header name on the response
+func tagHeader(name string) Middleware {
+	return func(next Handler) Handler {
+		return func(r *Request, w *Writer) {
+			w.Headers.Add(name, "1")
+			next(r, w)
+		}
+	}
+}
+
+func TestTraceOrdering(t *testing.T) {
+	r := newTestRouter()
+	r.TraceRequests(true)
+	// The last registered middleware is the outermost
+	r.Use(tagHeader("X-Inner"), MiddlewareName("inner"))
+	r.Use(tagHeader("X-Outer"), MiddlewareName("outer"))
+	r.Get("/items/:id", func(req *Request, w *Writer) {
+		TraceEvent(req, "cache miss")
+		w.WriteHeader(202)
+	})
+
+	resp := rawExchange(t, serveRouter(t, r), "GET /items/1 HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	trace := resp.Header.Get("X-Gouter-Trace")
+	steps := strings.Split(trace, ", ")
+
+	want := []string{"route=", ">outer", ">inner", ">handler", "event=cache miss", "<handler", "<inner", "<outer"}
+	i := 0
+	for _, step := range steps {
+		if i < len(want) && strings.HasPrefix(step, want[i]) {
+			i++
+		}
+	}
+	if i != len(want) {
+		t.Fatalf("trace %q misses %q in order", trace, want[i])
+	}
+	for _, step := range steps {
+		if strings.HasPrefix(step, "<handler") && !strings.Contains(step, "status=202") {
+			t.Errorf("handler exit %q without its status change", step)
+		}
+		if strings.HasPrefix(step, "<outer") && !strings.Contains(step, "+x-outer") {
+			t.Errorf("outer exit %q without its added header", step)
+		}
+	}
+}
+
+func TestTraceEventCannotInjectHeaders(t *testing.T) {
+	r := newTestRouter()
+	r.TraceRequests(true)
+	r.Get("/", func(req *Request, w *Writer) {
+		TraceEvent(req, "note\r\nX-Injected: 1\r\n\r\nbody")
+	})
+
+	resp := rawExchange(t, serveRouter(t, r), "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if v := resp.Header.Get("X-Injected"); v != "" {
+		t.Errorf("trace note injected X-Injected: %q", v)
+	}
+	if trace := resp.Header.Get("X-Gouter-Trace"); !strings.Contains(trace, "note  X-Injected: 1    body") {
+		t.Errorf("trace header = %q, want the note with its control bytes replaced", trace)
+	}
+}
+
+func TestTraceDisabledAllocatesNothing(t *testing.T) {
+	r := newTestRouter()
+	h := traceLayer("mw", func(req *Request, w *Writer) {
+		TraceEvent(req, "event")
+	})
+	req, w := newRequest(), newWriter(discardConn{})
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.startTrace(req)
+		h(req, w)
+		finishTrace(req, w)
+	})
+	if allocs != 0 {
+		t.Errorf("untraced request allocated %v times, want 0", allocs)
+	}
+}
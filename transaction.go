@@ -0,0 +1,136 @@
+package gouter
+
+import (
+	"errors"
+	"strings"
+)
+
+// RouteTx stages the routes of a Router.Transaction
+type RouteTx struct {
+	router *Router
+	staged []stagedRoute
+	paths  map[string]map[string]string // Location of each staged path by method, "" for any method
+	errs   []error
+}
+
+// stagedRoute is a prepared route waiting for the commit
+type stagedRoute struct {
+	path     string
+	location string
+	methods  []string
+	handler  Handler
+	doc      *RouteInfo
+}
+
+// Transaction registers the routes added by fn all at once
+// Args:
+//   - fn: Stages routes with tx.Route; returning an error discards them
+//
+// Staged routes are checked against the live routes and each other. They
+// reach the route table and the docs only when fn returns nil and nothing
+// conflicts; otherwise none is registered and the returned error joins fn's
+// error with every conflict (see ErrRouteConflict)
+func (r *Router) Transaction(fn func(tx *RouteTx) error) error {
+	r.lazyInit()
+
+	tx := &RouteTx{router: r, paths: make(map[string]map[string]string)}
+	if err := fn(tx); err != nil {
+		tx.errs = append([]error{err}, tx.errs...)
+	}
+
+	// fn may have registered routes directly, so check again before committing
+	if len(tx.errs) == 0 {
+		for _, s := range tx.staged {
+			if err := r.checkRoute(s.path, s.location, s.methods...); err != nil {
+				tx.errs = append(tx.errs, err)
+			}
+		}
+	}
+	if len(tx.errs) > 0 {
+		return errors.Join(tx.errs...)
+	}
+
+	for _, s := range tx.staged {
+		var table *methodTable
+		if len(s.methods) > 0 {
+			if table = r.methods[s.path]; table == nil {
+				table = r.newMethodTable(s.path, nil, s.location, nil)
+			}
+		}
+		r.insertRoute(s.path, s.handler, s.doc, table, s.methods)
+	}
+	return nil
+}
+
+// Route stages a route; see Router.Route
+// A conflicting route is reported by Transaction and returns a detached
+// entry, so chained calls such as SetDescription stay harmless
+func (tx *RouteTx) Route(path string, handler Handler, methods ...string) *RouteInfo {
+	location := callerLocation(1)
+	r := tx.router
+	methods = normalizeMethods(methods)
+
+	err := r.checkRoute(path, location, methods...)
+	if prev := tx.stagedAt(path, methods); prev != "" && err == nil {
+		err = &routeError{"This path [" + path + "] is staged twice (at " + prev + " and " + location + ").", ErrRouteConflict}
+	}
+	if other := tx.stagedWildcard(path); other != "" && err == nil {
+		err = &routeError{"This path [" + path + "] has the same prefix as the staged wildcard route [" + other + "] (staged at " + location + ").", ErrRouteConflict}
+	}
+	if err != nil {
+		tx.errs = append(tx.errs, err)
+		return &RouteInfo{Path: path}
+	}
+
+	h, doc := r.prepareRoute(path, traceLayer("handler", withGuards(handler)), nil, location, methods...)
+	if tx.paths[path] == nil {
+		tx.paths[path] = make(map[string]string)
+	}
+	keys := methods
+	if len(keys) == 0 {
+		keys = []string{""}
+	}
+	for _, m := range keys {
+		tx.paths[path][m] = location
+	}
+	tx.staged = append(tx.staged, stagedRoute{path: path, location: location, methods: methods, handler: h, doc: doc})
+	return doc
+}
+
+// stagedAt returns where path was already staged for one of methods, or for
+// any method, and "" when it was not
+func (tx *RouteTx) stagedAt(path string, methods []string) string {
+	staged := tx.paths[path]
+	if len(staged) == 0 {
+		return ""
+	}
+	if loc, ok := staged[""]; ok {
+		return loc
+	}
+	if len(methods) == 0 {
+		for _, loc := range staged {
+			return loc
+		}
+	}
+	for _, m := range methods {
+		if loc, ok := staged[m]; ok {
+			return loc
+		}
+	}
+	return ""
+}
+
+// stagedWildcard returns another staged wildcard route with the prefix of
+// path, and "" when there is none or path has no wildcard
+func (tx *RouteTx) stagedWildcard(path string) string {
+	base, _, ok := splitCatchAll(path)
+	if !ok {
+		return ""
+	}
+	for other := range tx.paths {
+		if b, _, ok := splitCatchAll(other); ok && other != path && strings.Trim(b, "/") == strings.Trim(base, "/") {
+			return other
+		}
+	}
+	return ""
+}
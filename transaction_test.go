@@ -0,0 +1,124 @@
+package gouter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	ok := func(body string) Handler {
+		return func(r *Request, w *Writer) { w.Write([]byte(body)) }
+	}
+	r := newTestRouter()
+	r.Route("/plugin/status", ok("live"))
+
+	errPlugin := errors.New("plugin setup failed")
+	failures := []struct {
+		name  string
+		stage func(tx *RouteTx) error
+		want  []error
+		added int // Docs entries of routes registered outside the transaction
+	}{
+		{"conflict with a live route", func(tx *RouteTx) error {
+			tx.Route("/plugin/a", ok("a"), "GET")
+			tx.Route("/plugin/b", ok("b"))
+			tx.Route("/plugin/status", ok("status"), "GET")
+			return nil
+		}, []error{ErrRouteConflict}, 0},
+		{"path staged twice", func(tx *RouteTx) error {
+			tx.Route("/plugin/a", ok("a"), "GET", "POST")
+			tx.Route("/plugin/b", ok("b"))
+			tx.Route("/plugin/a", ok("again"), "POST")
+			return nil
+		}, []error{ErrRouteConflict}, 0},
+		{"wildcards with one prefix", func(tx *RouteTx) error {
+			tx.Route("/plugin/files/*", ok("files"))
+			tx.Route("/plugin/b", ok("b"))
+			tx.Route("/plugin/files/*name", ok("named"))
+			return nil
+		}, []error{ErrRouteConflict}, 0},
+		{"error from the plugin", func(tx *RouteTx) error {
+			tx.Route("/plugin/a", ok("a"))
+			tx.Route("/plugin/b", ok("b")).SetDescription("b")
+			tx.Route("/plugin/status", ok("status"))
+			return errPlugin
+		}, []error{errPlugin, ErrRouteConflict}, 0},
+		{"live route added while staging", func(tx *RouteTx) error {
+			tx.Route("/plugin/a", ok("a"))
+			tx.Route("/plugin/c", ok("c"), "GET").SetDescription("detached")
+			tx.Route("/plugin/d", ok("d"))
+			r.Get("/plugin/c", ok("live c"))
+			return nil
+		}, []error{ErrRouteConflict}, 1},
+	}
+	for _, c := range failures {
+		docs := len(r.visibleDocs())
+		err := r.Transaction(c.stage)
+		for _, want := range c.want {
+			if !errors.Is(err, want) {
+				t.Errorf("%s: error %v does not match %v", c.name, err, want)
+			}
+		}
+		if n := len(r.visibleDocs()); n != docs+c.added {
+			t.Errorf("%s: docs went from %d to %d entries", c.name, docs, n)
+		}
+	}
+
+	// Every conflict is reported at once
+	err := r.Transaction(func(tx *RouteTx) error {
+		tx.Route("/plugin/status", ok("x"))
+		tx.Route("/plugin/c", ok("x"))
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "[/plugin/status]") || !strings.Contains(err.Error(), "[/plugin/c]") {
+		t.Errorf("combined report = %v, want both conflicts", err)
+	}
+
+	err = r.Transaction(func(tx *RouteTx) error {
+		tx.Route("/plugin/a", ok("get a"), "GET")
+		tx.Route("/plugin/a", ok("post a"), "POST")
+		tx.Route("/plugin/status", ok("post status"), "POST")
+		tx.Route("/plugin/files/*name", ok("files")).SetDescription("Plugin files")
+		return nil
+	})
+	if err == nil || !errors.Is(err, ErrRouteConflict) {
+		t.Fatalf("POST on a path registered for every method = %v, want a conflict", err)
+	}
+
+	err = r.Transaction(func(tx *RouteTx) error {
+		tx.Route("/plugin/a", ok("get a"), "GET")
+		tx.Route("/plugin/a", ok("post a"), "POST")
+		tx.Route("/plugin/files/*name", ok("files")).SetDescription("Plugin files")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("committing transaction: %v", err)
+	}
+	addr := serveRouter(t, r)
+
+	for _, c := range []struct{ method, path, code, body string }{
+		{"GET", "/plugin/a", "200", "get a"},
+		{"POST", "/plugin/a", "200", "post a"},
+		{"GET", "/plugin/files/x.txt", "200", "files"},
+		{"GET", "/plugin/status", "200", "live"},
+		{"GET", "/plugin/c", "200", "live c"},
+		{"GET", "/plugin/b", "404", ""},
+		{"GET", "/plugin/d", "404", ""},
+	} {
+		resp := rawExchange(t, addr, c.method+" "+c.path+" HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n", 1)[0]
+		body := bodyString(t, resp)
+		if resp.Status[:3] != c.code || (c.code == "200" && body != c.body) {
+			t.Errorf("%s %s = %s %q, want %s %q", c.method, c.path, resp.Status, body, c.code, c.body)
+		}
+	}
+
+	var entries []string
+	for _, doc := range r.visibleDocs() {
+		entries = append(entries, doc.Method+" "+doc.Path+" "+doc.Description)
+	}
+	if got := strings.Join(entries, "|"); !strings.Contains(got, "GET /plugin/files/*name Plugin files") ||
+		!strings.Contains(got, "POST /plugin/a") || strings.Contains(got, "detached") {
+		t.Errorf("docs = %q, want the committed routes only", got)
+	}
+}
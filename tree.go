@@ -0,0 +1,127 @@
+package gouter
+
+import "strings"
+
+// routeNode is a node of the route tree, one level per path segment
+// Lookups walk the request path once, trying at each level the literal
+// child, then parameter children in registration order, then the wildcard,
+// and backtrack when a branch matches no route
+type routeNode struct {
+	static    map[string]*routeNode // Children by literal segment
+	params    []*paramEdge          // Children whose segment holds parameters
+	route     string                // Pattern ending at this node, empty if none
+	catchAll  string                // "<prefix>/*" pattern whose prefix ends here
+	catchName string                // Parameter bound to the rest of the path by catchAll
+}
+
+// paramEdge is a child reached through a segment with parameters
+type paramEdge struct {
+	seg    string         // Pattern segment, e.g. ":id" or ":name.:ext"
+	tokens []segmentToken // Parsed seg
+	node   *routeNode
+}
+
+// splitCatchAll splits a wildcard pattern, "/files/*" or "/files/*path",
+// into its prefix and parameter name; ok is false for other patterns
+func splitCatchAll(pattern string) (base, name string, ok bool) {
+	i := strings.LastIndex(pattern, "/*")
+	if i < 0 || strings.Contains(pattern[i+2:], "/") {
+		return "", "", false
+	}
+	return pattern[:i], pattern[i+2:], true
+}
+
+// insert adds a route pattern to the tree
+// Patterns ending in "/*" match their prefix and everything below it; a name
+// after the star receives the rest of the path
+func (n *routeNode) insert(pattern string) {
+	base, name, catchAll := splitCatchAll(pattern)
+	if !catchAll {
+		base = pattern
+	}
+
+	node := n
+	if rest := strings.Trim(base, "/"); rest != "" {
+		for _, seg := range strings.Split(rest, "/") {
+			node = node.child(seg)
+		}
+	}
+	if catchAll {
+		node.catchAll, node.catchName = pattern, name
+	} else {
+		node.route = pattern
+	}
+}
+
+// child returns the child for a pattern segment, creating it
+func (n *routeNode) child(seg string) *routeNode {
+	if !strings.Contains(seg, ":") {
+		if c := n.static[seg]; c != nil {
+			return c
+		}
+		if n.static == nil {
+			n.static = make(map[string]*routeNode)
+		}
+		c := &routeNode{}
+		n.static[seg] = c
+		return c
+	}
+
+	for _, e := range n.params {
+		if e.seg == seg {
+			return e.node
+		}
+	}
+	e := &paramEdge{seg: seg, tokens: parseSegment(seg), node: &routeNode{}}
+	n.params = append(n.params, e)
+	return e.node
+}
+
+// lookup returns the pattern matching a request path, binding its
+// parameters into params, or "" when no route matches
+// Leading and trailing slashes of the path are ignored
+func (n *routeNode) lookup(path string, params map[string]string) string {
+	rest := strings.Trim(path, "/")
+	return n.match(rest, rest == "", params)
+}
+
+// match walks the remaining segments; end is true once none are left
+// Parameters bound by a branch that fails deeper are removed again
+func (n *routeNode) match(rest string, end bool, params map[string]string) string {
+	if end {
+		if n.route != "" {
+			return n.route
+		}
+		return n.matchCatchAll("", params)
+	}
+
+	seg, next, found := strings.Cut(rest, "/")
+	if c := n.static[seg]; c != nil {
+		if p := c.match(next, !found, params); p != "" {
+			return p
+		}
+	}
+	for _, e := range n.params {
+		if !matchSegment(e.tokens, seg, params) {
+			continue
+		}
+		if p := e.node.match(next, !found, params); p != "" {
+			return p
+		}
+		for _, t := range e.tokens {
+			if t.param != "" {
+				delete(params, t.param)
+			}
+		}
+	}
+	return n.matchCatchAll(rest, params)
+}
+
+// matchCatchAll returns the wildcard pattern of n, binding its parameter to
+// rest, the remaining path without its leading slash
+func (n *routeNode) matchCatchAll(rest string, params map[string]string) string {
+	if n.catchAll != "" && n.catchName != "" {
+		params[n.catchName] = rest
+	}
+	return n.catchAll
+}
@@ -0,0 +1,91 @@
+package gouter
+
+import (
+	"maps"
+	"strconv"
+	"testing"
+)
+
+func TestRouteTreeLookup(t *testing.T) {
+	var root routeNode
+	for _, p := range []string{
+		"/",
+		"/users/me/settings",
+		"/users/:id/avatar",
+		"/users/:id",
+		"/a/:x/b",
+		"/a/:y/c",
+		"/a/:x/:z/d",
+		"/files/:name.:ext",
+		"/files/*rest",
+		"/static/*",
+		"/docs/:section/*page",
+	} {
+		root.insert(p)
+	}
+
+	cases := []struct {
+		path    string
+		pattern string
+		params  map[string]string
+	}{
+		{"/", "/", map[string]string{}},
+		{"/users/me/settings", "/users/me/settings", map[string]string{}},
+		// The literal branch fails deeper, the parameter one matches
+		{"/users/me/avatar", "/users/:id/avatar", map[string]string{"id": "me"}},
+		{"/users/42/", "/users/:id", map[string]string{"id": "42"}},
+		// Bindings of failed branches are dropped
+		{"/a/1/c", "/a/:y/c", map[string]string{"y": "1"}},
+		{"/a/1/2/d", "/a/:x/:z/d", map[string]string{"x": "1", "z": "2"}},
+		{"/a/1/2/e", "", map[string]string{}},
+		{"/files/report.pdf", "/files/:name.:ext", map[string]string{"name": "report", "ext": "pdf"}},
+		{"/files/a/b.txt", "/files/*rest", map[string]string{"rest": "a/b.txt"}},
+		{"/files", "/files/*rest", map[string]string{"rest": ""}},
+		{"/static/css/site.css", "/static/*", map[string]string{}},
+		{"/docs/api/v1/intro", "/docs/:section/*page", map[string]string{"section": "api", "page": "v1/intro"}},
+		{"/nothing/here", "", map[string]string{}},
+	}
+	for _, tc := range cases {
+		params := make(map[string]string)
+		if got := root.lookup(tc.path, params); got != tc.pattern || !maps.Equal(params, tc.params) {
+			t.Errorf("lookup(%q) = %q %v, want %q %v", tc.path, got, params, tc.pattern, tc.params)
+		}
+	}
+}
+
+func TestRouteTreeParamOrder(t *testing.T) {
+	// Parameter children are tried in registration order
+	var root routeNode
+	root.insert("/v/:a.:b")
+	root.insert("/v/:whole")
+	params := make(map[string]string)
+	if got := root.lookup("/v/x.y", params); got != "/v/:a.:b" || params["a"] != "x" {
+		t.Errorf("lookup = %q %v, want the first registered pattern", got, params)
+	}
+	params = make(map[string]string)
+	if got := root.lookup("/v/xy", params); got != "/v/:whole" || params["whole"] != "xy" {
+		t.Errorf("lookup = %q %v, want the pattern the first one rejects", got, params)
+	}
+}
+
+// benchmarkManyRoutes matches the last of n parameter routes, which a scan
+// over the routes would reach after trying all the others
+func benchmarkManyRoutes(b *testing.B, n int) {
+	r := newTestRouter()
+	for i := 0; i < n; i++ {
+		r.Get("/api/v1/resource"+strconv.Itoa(i)+"/:id/items/:item", func(req *Request, w *Writer) {})
+	}
+	req := newRequest()
+	req.path = "/api/v1/resource" + strconv.Itoa(n-1) + "/42/items/7"
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		clear(req.Params)
+		if h, _ := r.parseRoute(req); h == nil {
+			b.Fatal("no route")
+		}
+	}
+}
+
+func BenchmarkRouteAmong10(b *testing.B)   { benchmarkManyRoutes(b, 10) }
+func BenchmarkRouteAmong1000(b *testing.B) { benchmarkManyRoutes(b, 1000) }
@@ -0,0 +1,113 @@
+package gouter
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxTryItBytes bounds the request a "Try it" call may send and the
+// response body it shows
+const maxTryItBytes = 1 << 20
+
+// tryItRequest is a request the docs page asks the doc server to run
+type tryItRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// tryItResponse is what the router answered to a tryItRequest
+type tryItResponse struct {
+	Status     int               `json:"status,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+	Truncated  bool              `json:"truncated,omitempty"`
+	DurationMs float64           `json:"duration_ms"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// serveTryIt runs a request from the "Try it" panel of the docs page
+// The request goes through the router over an in-memory connection, like
+// RunExamples, so the browser needs no CORS access to the application.
+// Only JSON posts from the docs page itself are accepted: the content type
+// makes cross-site forms preflight, which the doc server never grants
+func (r *Router) serveTryIt(req *Request, w *Writer) {
+	if req.Method != "POST" {
+		w.SetHeader("Allow", "POST")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if ct, _, _ := strings.Cut(req.Headers.Get("Content-Type"), ";"); strings.TrimSpace(ct) != "application/json" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if origin := req.Headers.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != req.Headers.Get("Host") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+	}
+
+	var in tryItRequest
+	if err := json.NewDecoder(io.LimitReader(req.Body, 2*maxTryItBytes)).Decode(&in); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.WriteJson(tryItResponse{Error: "invalid request: " + err.Error()})
+		return
+	}
+	if msg := in.check(); msg != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.WriteJson(tryItResponse{Error: msg})
+		return
+	}
+
+	start := time.Now()
+	resp, body, err := r.record(buildRequest(in.Method, in.Path, in.Headers, []byte(in.Body)))
+	out := tryItResponse{DurationMs: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		out.Error = err.Error()
+		w.WriteHeader(http.StatusBadGateway)
+		w.WriteJson(out)
+		return
+	}
+
+	out.Status = resp.StatusCode
+	out.Headers = make(map[string]string, len(resp.Header))
+	for k, v := range resp.Header {
+		out.Headers[k] = strings.Join(v, ", ")
+	}
+	if len(body) > maxTryItBytes {
+		body, out.Truncated = body[:maxTryItBytes], true
+	}
+	out.Body = string(body)
+	w.WriteJson(out)
+}
+
+// check returns why the request cannot be sent, or ""
+// Everything ends up in a raw request line and header block, so line breaks
+// and spaces are refused where they would split them
+func (t *tryItRequest) check() string {
+	t.Method = strings.ToUpper(t.Method)
+	switch {
+	case !isToken([]byte(t.Method)):
+		return "invalid method"
+	case !strings.HasPrefix(t.Path, "/") || strings.ContainsAny(t.Path, " \r\n"):
+		return "path must start with / and hold no spaces"
+	case len(t.Body) > maxTryItBytes:
+		return "body too large"
+	}
+	for k, v := range t.Headers {
+		if !isToken([]byte(k)) || strings.ContainsAny(v, "\r\n") {
+			return "invalid header " + k
+		}
+		// Set by buildRequest from the body
+		if strings.EqualFold(k, "Content-Length") || strings.EqualFold(k, "Transfer-Encoding") {
+			delete(t.Headers, k)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package gouter
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tryItServer serves docs with the Try it panel for an app with /echo and
+// /big, returning the doc server address
+func tryItServer(t *testing.T) string {
+	r := docsRouter(0, func(d *Doc) { d.TryIt = true })
+	r.Route("/echo", func(req *Request, w *Writer) {
+		body, _ := io.ReadAll(req.Body)
+		w.SetHeader("X-Host", req.Headers.Get("Host"))
+		w.SetHeader("Content-Type", "text/plain")
+		w.Write([]byte(req.Method + " " + req.RawQuery() + " " + req.Headers.Get("X-Token") + " " + string(body)))
+	})
+	r.Get("/big", func(req *Request, w *Writer) { w.Write([]byte(strings.Repeat("b", maxTryItBytes+10))) })
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := r.DocsAddr()
+	return addr
+}
+
+// tryIt posts body to the Try it endpoint with extra request headers
+func tryIt(t *testing.T, addr, body string, headers ...string) (int, tryItResponse) {
+	t.Helper()
+	raw := "POST /try HTTP/1.1\r\nHost: " + addr + "\r\nContent-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n"
+	for _, h := range headers {
+		raw += h + "\r\n"
+	}
+	resp := rawExchange(t, addr, raw+"\r\n"+body, 1)[0]
+	var out tryItResponse
+	if b := bodyString(t, resp); b != "" {
+		if err := json.Unmarshal([]byte(b), &out); err != nil {
+			t.Fatalf("answer %q: %v", b, err)
+		}
+	}
+	return resp.StatusCode, out
+}
+
+func TestTryIt(t *testing.T) {
+	addr := tryItServer(t)
+
+	status, out := tryIt(t, addr, `{"method":"post","path":"/echo?a=1","headers":{"X-Token":"t1","content-length":"999"},"body":"payload"}`,
+		"Origin: http://"+addr)
+	if status != 200 || out.Status != 200 || out.Body != "POST a=1 t1 payload" || out.Headers["Content-Type"] != "text/plain" {
+		t.Errorf("POST /echo = %d %+v", status, out)
+	}
+	if out.DurationMs < 0 || out.Error != "" || out.Truncated {
+		t.Errorf("answer = %+v", out)
+	}
+
+	// Header names are matched without case, so a host header replaces the default
+	if _, out := tryIt(t, addr, `{"method":"GET","path":"/echo","headers":{"host":"api.example"}}`); out.Status != 200 || out.Headers["X-Host"] != "api.example" {
+		t.Errorf("custom host = %+v", out)
+	}
+
+	// HEAD answers carry a length but no body
+	start := time.Now()
+	if _, out := tryIt(t, addr, `{"method":"HEAD","path":"/big"}`); out.Status != 200 || out.Body != "" || out.Headers["Content-Length"] != strconv.Itoa(maxTryItBytes+10) {
+		t.Errorf("HEAD /big = %+v", out)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("HEAD took %v", d)
+	}
+
+	if _, out := tryIt(t, addr, `{"method":"GET","path":"/big"}`); !out.Truncated || len(out.Body) != maxTryItBytes {
+		t.Errorf("large body = %d bytes, truncated %v", len(out.Body), out.Truncated)
+	}
+	if _, out := tryIt(t, addr, `{"method":"GET","path":"/missing"}`); out.Status != 404 {
+		t.Errorf("missing route = %+v", out)
+	}
+}
+
+func TestTryItRefusals(t *testing.T) {
+	addr := tryItServer(t)
+	cases := []struct {
+		name, body string
+		headers    []string
+		status     int
+		error      string
+	}{
+		{"cross-site origin", `{"method":"GET","path":"/echo"}`, []string{"Origin: https://evil.example"}, 403, ""},
+		{"opaque origin", `{"method":"GET","path":"/echo"}`, []string{"Origin: null"}, 403, ""},
+		{"invalid JSON", `{"method":`, nil, 400, "invalid request"},
+		{"invalid method", `{"method":"GE T","path":"/echo"}`, nil, 400, "invalid method"},
+		{"relative path", `{"method":"GET","path":"echo"}`, nil, 400, "path must start with /"},
+		{"path with a space", `{"method":"GET","path":"/echo HTTP/1.1\r\nX: y"}`, nil, 400, "path must start with /"},
+		{"header injection", `{"method":"GET","path":"/echo","headers":{"X-A":"1\r\nX-B: 2"}}`, nil, 400, "invalid header X-A"},
+		{"invalid header name", `{"method":"GET","path":"/echo","headers":{"X A":"1"}}`, nil, 400, "invalid header X A"},
+		{"large body", `{"method":"POST","path":"/echo","body":"` + strings.Repeat("x", maxTryItBytes+1) + `"}`, nil, 400, "body too large"},
+	}
+	for _, c := range cases {
+		status, out := tryIt(t, addr, c.body, c.headers...)
+		if status != c.status || !strings.HasPrefix(out.Error, c.error) {
+			t.Errorf("%s = %d %q, want %d %q", c.name, status, out.Error, c.status, c.error)
+		}
+	}
+
+	// Forms cannot reach it: they post without a JSON content type
+	resp := rawExchange(t, addr, "POST /try HTTP/1.1\r\nHost: x\r\nContent-Type: text/plain\r\nContent-Length: 2\r\n\r\n{}", 1)[0]
+	if resp.StatusCode != 415 {
+		t.Errorf("text/plain post = %d, want 415", resp.StatusCode)
+	}
+	resp = rawExchange(t, addr, "GET /try HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]
+	if resp.StatusCode != 405 || resp.Header.Get("Allow") != "POST" {
+		t.Errorf("GET /try = %d, Allow %q", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+
+	// The page offers the panel
+	if page := bodyString(t, rawExchange(t, addr, "GET / HTTP/1.1\r\nHost: x\r\n\r\n", 1)[0]); !strings.Contains(page, `class="try-it"`) {
+		t.Error("docs page lacks the Try it panel")
+	}
+}
+
+func TestTryItDisabled(t *testing.T) {
+	r := docsRouter(0, func(d *Doc) {})
+	if err := serveUntilReady(t, r); err != nil {
+		t.Fatal(err)
+	}
+	addr, _ := r.DocsAddr()
+
+	// Without TryIt nothing is run: /try is the docs page like any other path
+	body := `{"method":"GET","path":"/"}`
+	raw := "POST /try HTTP/1.1\r\nHost: x\r\nContent-Type: application/json\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	resp := rawExchange(t, addr, raw, 1)[0]
+	if ct := resp.Header.Get("Content-Type"); resp.StatusCode != 200 || !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("POST /try without TryIt = %d %q, want the docs page", resp.StatusCode, ct)
+	}
+}
@@ -0,0 +1,223 @@
+package gouter
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is how many leading bytes SniffUploads inspects
+const sniffLen = 512
+
+// ErrUploadRejected wraps the error of an upload inspector; ParseMultipart
+// and ReceiveFile return it inside a 422 StatusError
+var ErrUploadRejected = errors.New("upload rejected")
+
+// UploadPartInfo describes an uploaded file to an UploadInspector
+type UploadPartInfo struct {
+	FormName    string // Multipart field name, empty for ReceiveFile
+	Filename    string // Client filename, or the base name of the ReceiveFile path
+	ContentType string // Declared Content-Type of the part or request
+}
+
+// UploadInspector checks an upload while it is spooled to disk
+// r yields the file bytes as they are written; the inspector may stop
+// reading at any point. A non-nil error aborts the upload
+type UploadInspector func(part UploadPartInfo, r io.Reader) error
+
+// UploadInspector sets the inspector run on every file received by
+// ParseMultipart and ReceiveFile
+// A rejected upload returns ErrUploadRejected in a 422 StatusError and the
+// files spooled so far are deleted
+func (r *Router) UploadInspector(fn UploadInspector) {
+	r.uploadInspector = fn
+}
+
+// ChainUploadInspectors runs inspectors on the same stream, failing with the
+// first error
+func ChainUploadInspectors(inspectors ...UploadInspector) UploadInspector {
+	return func(part UploadPartInfo, r io.Reader) error {
+		writers := make([]*io.PipeWriter, len(inspectors))
+		results := make(chan error, len(inspectors))
+		for i, fn := range inspectors {
+			pr, pw := io.Pipe()
+			writers[i] = pw
+			spawn("upload-inspector", func() {
+				err := fn(part, pr)
+				pr.CloseWithError(errInspectorDone)
+				results <- err
+			})
+		}
+
+		// Feed every inspector; one that returned early no longer receives data
+		buf := make([]byte, 32*1024)
+		var readErr error
+		for readErr == nil {
+			var n int
+			n, readErr = r.Read(buf)
+			for i, pw := range writers {
+				if pw != nil && n > 0 {
+					if _, err := pw.Write(buf[:n]); err != nil {
+						writers[i] = nil
+					}
+				}
+			}
+		}
+		for _, pw := range writers {
+			if pw != nil {
+				pw.Close()
+			}
+		}
+
+		var first error
+		for range inspectors {
+			if err := <-results; err != nil && first == nil {
+				first = err
+			}
+		}
+		if first == nil && readErr != io.EOF {
+			first = readErr
+		}
+		return first
+	}
+}
+
+// errInspectorDone closes the pipe of an inspector that returned
+var errInspectorDone = errors.New("inspector done")
+
+// SniffUploads returns an inspector rejecting files whose content does not
+// match their extension, and files over a size limit for their type
+// Args:
+//   - limits: Maximum bytes by detected media type ("image/png"), by
+//     top-level type ("image/*") or for any type ("*"); nil for no limits
+//
+// Types are detected from the first 512 bytes with http.DetectContentType.
+// Executables (ELF, PE, Mach-O) are rejected unless the extension is one of
+// an executable or unknown, and a known extension must match the detected
+// type; plain text detected for a textual extension (.json, .csv) is accepted
+func SniffUploads(limits map[string]int64) UploadInspector {
+	return func(part UploadPartInfo, r io.Reader) error {
+		head := make([]byte, sniffLen)
+		n, err := io.ReadFull(r, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+		head = head[:n]
+
+		detected, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+		if err := checkUploadType(part.Filename, detected, head); err != nil {
+			return err
+		}
+
+		limit, ok := uploadLimit(limits, detected)
+		if !ok {
+			return nil
+		}
+		rest, err := io.Copy(io.Discard, io.LimitReader(r, limit-int64(n)+1))
+		if err != nil {
+			return err
+		}
+		if int64(n)+rest > limit {
+			return fmt.Errorf("%s file larger than %d bytes", detected, limit)
+		}
+		return nil
+	}
+}
+
+// executableMagic are the leading bytes of native executables
+var executableMagic = [][]byte{
+	[]byte("\x7fELF"),
+	[]byte("MZ"),
+	{0xfe, 0xed, 0xfa, 0xce},
+	{0xfe, 0xed, 0xfa, 0xcf},
+	{0xcf, 0xfa, 0xed, 0xfe},
+	{0xce, 0xfa, 0xed, 0xfe},
+}
+
+// checkUploadType compares the detected type of a file with its extension
+func checkUploadType(filename, detected string, head []byte) error {
+	expected, _, _ := mime.ParseMediaType(mime.TypeByExtension(strings.ToLower(filepath.Ext(filename))))
+
+	for _, magic := range executableMagic {
+		if bytes.HasPrefix(head, magic) {
+			if expected != "" && !isExecutableType(expected) {
+				return fmt.Errorf("%q is an executable", filename)
+			}
+			return nil
+		}
+	}
+
+	if expected == "" || detected == "application/octet-stream" || detected == expected {
+		return nil
+	}
+	if detected == "text/plain" && isTextualType(expected) {
+		return nil
+	}
+	return fmt.Errorf("%q has %s content", filename, detected)
+}
+
+// isExecutableType reports whether a media type names a program or raw binary
+func isExecutableType(t string) bool {
+	switch t {
+	case "application/octet-stream", "application/x-executable", "application/x-msdownload",
+		"application/x-msdos-program", "application/vnd.microsoft.portable-executable":
+		return true
+	}
+	return false
+}
+
+// isTextualType reports whether content of a media type is plain text
+func isTextualType(t string) bool {
+	if strings.HasPrefix(t, "text/") {
+		return true
+	}
+	return strings.HasSuffix(t, "json") || strings.HasSuffix(t, "xml") ||
+		strings.HasSuffix(t, "javascript") || t == "application/x-yaml" || t == "application/yaml"
+}
+
+// uploadLimit returns the most specific size limit for a media type
+func uploadLimit(limits map[string]int64, mediaType string) (int64, bool) {
+	if limit, ok := limits[mediaType]; ok {
+		return limit, true
+	}
+	main, _, _ := strings.Cut(mediaType, "/")
+	if limit, ok := limits[main+"/*"]; ok {
+		return limit, true
+	}
+	limit, ok := limits["*"]
+	return limit, ok
+}
+
+// spoolUpload copies an upload to dst, running the request inspector on the
+// same bytes as they are written
+func (r *Request) spoolUpload(dst io.Writer, src io.Reader, info UploadPartInfo) error {
+	if r.inspector == nil {
+		_, err := io.Copy(dst, src)
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	result := make(chan error, 1)
+	spawn("upload-inspector", func() {
+		err := r.inspector(info, pr)
+		// Keep consuming so an inspector that stopped early does not stall the copy
+		if err == nil {
+			io.Copy(io.Discard, pr)
+		}
+		pr.CloseWithError(errInspectorDone)
+		result <- err
+	})
+
+	_, copyErr := io.Copy(io.MultiWriter(dst, pw), src)
+	pw.CloseWithError(copyErr)
+
+	if err := <-result; err != nil {
+		return StatusError(http.StatusUnprocessableEntity, fmt.Errorf("%w: %w", ErrUploadRejected, err))
+	}
+	return copyErr
+}
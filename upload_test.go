@@ -0,0 +1,201 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// elfBinary is the start of a Linux executable
+var elfBinary = append([]byte("\x7fELF\x02\x01\x01\x00"), bytes.Repeat([]byte{0}, 600)...)
+
+// pngImage is the start of a PNG image
+var pngImage = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 2000)...)
+
+// uploadStatus answers 422 for a rejected upload and 400 for other errors
+func uploadStatus(w *Writer, err error) {
+	code := http.StatusBadRequest
+	var he *httpError
+	if errors.As(err, &he) {
+		code = he.code
+	}
+	Error(w, err, code)
+}
+
+// multipartFiles builds a body with one file part per name/content pair
+func multipartFiles(files ...string) (string, []byte) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for i := 0; i < len(files); i += 2 {
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="f%d"; filename=%q`, i/2, files[i]))
+		h.Set("Content-Type", "application/octet-stream")
+		p, _ := mw.CreatePart(h)
+		io.WriteString(p, files[i+1])
+	}
+	mw.Close()
+	return mw.FormDataContentType(), buf.Bytes()
+}
+
+func TestSniffUploadsMultipart(t *testing.T) {
+	type form struct {
+		First  *FileUpload `gouter:"f0"`
+		Second *FileUpload `gouter:"f1"`
+	}
+
+	var spooled []string
+	r := newTestRouter()
+	r.UploadInspector(SniffUploads(map[string]int64{"image/*": 1500}))
+	r.Post("/upload", func(req *Request, w *Writer) {
+		var f form
+		err := req.ParseMultipart(&f)
+		for _, u := range []*FileUpload{f.First, f.Second} {
+			if u != nil {
+				spooled = append(spooled, u.File.Name())
+			}
+		}
+		if err != nil {
+			uploadStatus(w, err)
+			return
+		}
+		defer req.Cleanup()
+		w.Write([]byte("ok"))
+	})
+	addr := serveRouter(t, r)
+
+	cases := []struct {
+		name  string
+		files []string
+		code  int
+	}{
+		{"matching types", []string{"notes.txt", "hello\n", "small.png", string(pngImage[:1000])}, 200},
+		{"executable named png", []string{"notes.txt", "hello\n", "cat.png", string(elfBinary)}, 422},
+		{"executable with its own extension", []string{"tool.exe", string(elfBinary)}, 200},
+		{"image over its type limit", []string{"big.png", string(pngImage)}, 422},
+		{"text named png", []string{"fake.png", "just some words"}, 422},
+	}
+	for _, c := range cases {
+		spooled = nil
+		contentType, body := multipartFiles(c.files...)
+		code, got := postMultipart(t, addr, "/upload", contentType, body)
+		if code != c.code {
+			t.Errorf("%s = %d %q, want %d", c.name, code, got, c.code)
+		}
+		if c.code == 422 && !strings.Contains(got, ErrUploadRejected.Error()) {
+			t.Errorf("%s: body %q does not name the rejection", c.name, got)
+		}
+		if c.code == 422 {
+			for _, name := range spooled {
+				if _, err := os.Stat(name); err == nil {
+					t.Errorf("%s: temporary file %s left after a rejected upload", c.name, name)
+				}
+			}
+		}
+	}
+}
+
+func TestUploadInspectorStreams(t *testing.T) {
+	const limit = 1 << 20
+	var seen atomic.Int64
+	dst := filepath.Join(t.TempDir(), "upload.bin")
+
+	r := newTestRouter()
+	r.UploadInspector(func(part UploadPartInfo, rd io.Reader) error {
+		if part.Filename != "upload.bin" || part.ContentType != "application/octet-stream" {
+			return fmt.Errorf("unexpected part %+v", part)
+		}
+		n, err := io.Copy(io.Discard, io.LimitReader(rd, limit+1))
+		seen.Store(n)
+		if err != nil {
+			return err
+		}
+		if n > limit {
+			return errors.New("over 1 MB")
+		}
+		return nil
+	})
+	r.Post("/file", func(req *Request, w *Writer) {
+		f, err := ReceiveFile(req, dst)
+		if err != nil {
+			uploadStatus(w, err)
+			return
+		}
+		f.Close()
+		w.Write([]byte("stored"))
+	})
+	addr := serveRouter(t, r)
+
+	// The client sends 1.5 MB of a 4 MB body and waits for the answer; an
+	// inspector that needed the whole file buffered would never reply
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	go func() {
+		fmt.Fprintf(c, "POST /file HTTP/1.1\r\nHost: x\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n", 4<<20)
+		c.Write(make([]byte, 3<<19))
+	}()
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("oversized upload = %d, want 422", resp.StatusCode)
+	}
+	if n := seen.Load(); n != limit+1 {
+		t.Errorf("inspector read %d bytes, want to stop at %d", n, limit+1)
+	}
+	if _, err := os.Stat(dst); err == nil {
+		t.Error("rejected upload left its file behind")
+	}
+
+	body := strings.Repeat("a", 1000)
+	raw := "POST /file HTTP/1.1\r\nHost: x\r\nContent-Type: application/octet-stream\r\nContent-Length: " +
+		strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	if resp := rawExchange(t, addr, raw, 1)[0]; resp.StatusCode != 200 {
+		t.Errorf("small upload = %d, want 200", resp.StatusCode)
+	}
+	if stored, _ := os.ReadFile(dst); string(stored) != body {
+		t.Errorf("stored %d bytes, want %d", len(stored), len(body))
+	}
+}
+
+func TestChainUploadInspectors(t *testing.T) {
+	var total atomic.Int64
+	counter := func(_ UploadPartInfo, r io.Reader) error {
+		n, err := io.Copy(io.Discard, r)
+		total.Store(n)
+		return err
+	}
+	headOnly := func(_ UploadPartInfo, r io.Reader) error {
+		_, err := io.ReadFull(r, make([]byte, 4))
+		return err
+	}
+	reject := func(UploadPartInfo, io.Reader) error { return errors.New("nope") }
+
+	data := strings.Repeat("x", 100_000)
+	if err := ChainUploadInspectors(headOnly, counter)(UploadPartInfo{}, strings.NewReader(data)); err != nil {
+		t.Errorf("chain = %v", err)
+	}
+	if total.Load() != int64(len(data)) {
+		t.Errorf("counter saw %d bytes after another inspector returned early, want %d", total.Load(), len(data))
+	}
+	if err := ChainUploadInspectors(counter, reject)(UploadPartInfo{}, strings.NewReader(data)); err == nil || err.Error() != "nope" {
+		t.Errorf("chain with a rejecting inspector = %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package gouter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+)
+
+const (
+	// defaultMaxURILength is the request-target limit used when
+	// Server.MaxURILength is zero
+	defaultMaxURILength = 8 << 10
+
+	// displayPathLimit is the part of a long path kept in log and trace output
+	displayPathLimit = 256
+)
+
+// ErrURITooLong is wrapped by the error of requests rejected with 414
+var ErrURITooLong = errors.New("request-target too long")
+
+// maxURILength resolves a MaxURILength setting: 0 means the default and a
+// negative value disables the limit
+func maxURILength(n int) int {
+	switch {
+	case n == 0:
+		return defaultMaxURILength
+	case n < 0:
+		return 0
+	}
+	return n
+}
+
+// checkRequestTarget fails with 414 once the request-target read so far
+// exceeds limit; line holds the request line up to the bytes read yet
+// The rest of an oversized target is read from br only to hash it, up to
+// maxRead bytes, so the log shows a bounded prefix plus a hash of the full
+// value for correlation
+func checkRequestTarget(line []byte, limit int, br *bufio.Reader, maxRead int) error {
+	sp := bytes.IndexByte(line, ' ')
+	if limit <= 0 || sp < 0 {
+		return nil
+	}
+
+	target := line[sp+1:]
+	end := bytes.IndexAny(target, " \r\n")
+	if end >= 0 {
+		target = target[:end]
+	}
+	if len(target) <= limit {
+		return nil
+	}
+
+	h := fnv.New64a()
+	h.Write(target)
+	size := len(target)
+	shown := string(target[:min(len(target), displayPathLimit)])
+
+	for end < 0 && size < maxRead {
+		chunk, err := br.ReadSlice('\n')
+		if end = bytes.IndexAny(chunk, " \r\n"); end >= 0 {
+			chunk = chunk[:end]
+		}
+		h.Write(chunk)
+		size += len(chunk)
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			break
+		}
+	}
+
+	return &httpError{http.StatusRequestURITooLong, fmt.Errorf("%w (limit %d): %s", ErrURITooLong, limit, formatLongPath(shown, size, h.Sum64()))}
+}
+
+// displayPath shortens a path for log and trace output
+// Paths over displayPathLimit keep their start, followed by their length and
+// a hash of the full value
+func displayPath(path string) string {
+	if len(path) <= displayPathLimit {
+		return path
+	}
+	h := fnv.New64a()
+	h.Write([]byte(path))
+	return formatLongPath(path[:displayPathLimit], len(path), h.Sum64())
+}
+
+// formatLongPath renders a truncated path with its full length and hash
+func formatLongPath(prefix string, size int, sum uint64) string {
+	return prefix + "... [" + strconv.Itoa(size) + " bytes, fnv " + strconv.FormatUint(sum, 16) + "]"
+}
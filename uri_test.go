@@ -0,0 +1,102 @@
+package gouter
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sendTarget writes a GET for target without waiting for the server to read
+// all of it, and returns the response
+func sendTarget(t *testing.T, addr, target string) *http.Response {
+	t.Helper()
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	go fmt.Fprintf(c, "GET %s HTTP/1.1\r\nHost: x\r\n\r\n", target)
+
+	resp, err := http.ReadResponse(bufio.NewReader(c), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestMaxURILength(t *testing.T) {
+	r := newTestRouter()
+	r.Get("/*", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+
+	huge := "/" + strings.Repeat("a", 900<<10-1)
+	out := captureServe(t, func(t *testing.T) {
+		addr := serveRouter(t, r)
+		resp := sendTarget(t, addr, huge)
+		if resp.StatusCode != http.StatusRequestURITooLong || !resp.Close {
+			t.Errorf("900KB target = %d (close %v), want 414 and a closed connection", resp.StatusCode, resp.Close)
+		}
+		if resp := sendTarget(t, addr, "/"+strings.Repeat("a", 8000)); resp.StatusCode != 200 {
+			t.Errorf("8000 byte target = %d, want it under the default limit", resp.StatusCode)
+		}
+	})
+	if len(out) > 1024 {
+		t.Errorf("log of a 900KB target is %d bytes, want it bounded", len(out))
+	}
+	// The log shows the start of the target with the length and hash of all of it
+	if !strings.Contains(out, displayPath(huge)) || !strings.Contains(out, "921600 bytes") {
+		t.Errorf("log %q does not identify the full target", out)
+	}
+
+	for _, c := range []struct {
+		limit, size, code int
+	}{
+		{100, 100, 200},
+		{100, 101, 414},
+		{-1, 200 << 10, 200},
+	} {
+		addr := serveTest(t, &Server{Router: r, MaxURILength: c.limit})
+		target := "/" + strings.Repeat("b", c.size-1)
+		if resp := sendTarget(t, addr, target); resp.StatusCode != c.code {
+			t.Errorf("MaxURILength %d with a %d byte target = %d, want %d", c.limit, c.size, resp.StatusCode, c.code)
+		}
+	}
+
+	// The query counts toward the limit
+	addr := serveTest(t, &Server{Router: r, MaxURILength: 100})
+	if resp := sendTarget(t, addr, "/short?q="+strings.Repeat("c", 100)); resp.StatusCode != 414 {
+		t.Errorf("long query = %d, want 414", resp.StatusCode)
+	}
+}
+
+func TestLongPathInAccessLog(t *testing.T) {
+	var lines syncBuffer
+	r := newTestRouter()
+	r.Use(Logger(LoggerOutput(&lines)))
+	r.Get("/*", func(req *Request, w *Writer) { w.Write([]byte("ok")) })
+	addr := serveRouter(t, r)
+
+	path := "/" + strings.Repeat("p", 6000)
+	if resp := sendTarget(t, addr, path); resp.StatusCode != 200 {
+		t.Fatalf("long path = %d", resp.StatusCode)
+	}
+	var line string
+	eventually(t, "access log line", func() bool {
+		line = lines.lines()[0]
+		return line != ""
+	})
+
+	if len(line) > 512 || !strings.Contains(line, displayPath(path)) {
+		t.Errorf("access log line (%d bytes) = %q, want the shortened path", len(line), line)
+	}
+	if got := displayPath("/short"); got != "/short" {
+		t.Errorf("displayPath(/short) = %q", got)
+	}
+	if a, b := displayPath(path), displayPath(path+"x"); a == b || !strings.HasPrefix(a, path[:displayPathLimit]+"... [6001 bytes, fnv ") {
+		t.Errorf("displayPath = %q and %q, want distinct hashes after the prefix", a, b)
+	}
+}
@@ -5,17 +5,28 @@ import (
 	"encoding/base64"
 	"errors"
 	"net"
+	"net/http"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type WebSocket struct {
-	conn    net.Conn
-	headers Headers
+	conn        net.Conn
+	headers     Headers
+	subprotocol string
+
+	writeMu  sync.Mutex                // Serializes frames from WriteMessage and the send queue
+	queueMu  sync.Mutex                // Guards send queue creation
+	queue    atomic.Pointer[sendQueue] // Send queue, nil until Send, SetQueue or Hub.Join
+	counters queueCounters             // Send queue statistics
+	tracker  *connTracker              // Shutdown registry, nil outside Server
 }
 
 type WebSocketConfig struct {
-	CheckOrigin func(*Request) bool
+	CheckOrigin  func(*Request) bool
+	Subprotocols []string // Supported subprotocols; the first one offered by the client wins
 }
 
 type WebSocketHandler func(*WebSocket, *Request)
@@ -27,7 +38,7 @@ const (
 )
 
 func (r *Request) Upgrade(w *Writer, cfg WebSocketConfig) (*WebSocket, error) {
-	if strings.ToLower(r.Headers.Get("Upgrade")) != "websocket" || strings.ToLower(r.Headers.Get("Connection")) != "upgrade" {
+	if !isUpgradeRequest(r) {
 		return nil, errors.New("not a websocket handshake")
 	}
 
@@ -41,17 +52,34 @@ func (r *Request) Upgrade(w *Writer, cfg WebSocketConfig) (*WebSocket, error) {
 	}
 
 	acceptKey := computeAcceptKey(clientKey)
-	_, err := w.c.Write([]byte(
-		"HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey + "\r\n\r\n",
-	))
+	subprotocol := selectSubprotocol(r.Headers.Get("Sec-WebSocket-Protocol"), cfg.Subprotocols)
+	response := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey + "\r\n"
+	if subprotocol != "" {
+		response += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done || w.headersSent {
+		return nil, ErrResponseDone
+	}
+	// The websocket reads the connection from now on
+	if w.notifier != nil {
+		w.notifier.stop()
+	}
+	_, err := w.c.Write([]byte(response + "\r\n"))
 	if err != nil {
 		return nil, err
 	}
+	w.hijacked = true
 
-	return &WebSocket{
-		conn:    w.c,
-		headers: r.Headers,
-	}, nil
+	ws := &WebSocket{
+		conn:        w.c,
+		headers:     r.Headers,
+		subprotocol: subprotocol,
+		tracker:     w.tracker,
+	}
+	ws.tracker.addSocket(ws)
+	return ws, nil
 }
 
 func computeAcceptKey(clientKey string) string {
@@ -66,11 +94,17 @@ func (ws *WebSocket) ReadMessage() ([]byte, error) {
 }
 
 func (ws *WebSocket) WriteMessage(message []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
 	ws.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
 	return writeFrame(ws.conn, message)
 }
 
 func (ws *WebSocket) Close() error {
+	if q := ws.queue.Load(); q != nil {
+		q.close(ErrQueueClosed)
+	}
+	ws.tracker.removeSocket(ws)
 	return ws.conn.Close()
 }
 
@@ -86,3 +120,105 @@ func WebSocketRoute(handler WebSocketHandler, cfg WebSocketConfig) Handler {
 		handler(ws, r)
 	}
 }
+
+// Subprotocol returns the negotiated subprotocol, empty if none was agreed
+func (ws *WebSocket) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// isUpgradeRequest reports whether the request asks for a websocket upgrade
+// Connection is a token list, e.g. "keep-alive, Upgrade" from Firefox
+func isUpgradeRequest(r *Request) bool {
+	if !strings.EqualFold(r.Headers.Get("Upgrade"), "websocket") {
+		return false
+	}
+	for _, token := range strings.Split(r.Headers.Get("Connection"), ",") {
+		if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+			return true
+		}
+	}
+	return false
+}
+
+// selectSubprotocol picks the first subprotocol offered by the client that
+// the server supports
+func selectSubprotocol(offered string, supported []string) string {
+	for _, p := range strings.Split(offered, ",") {
+		p = strings.TrimSpace(p)
+		for _, s := range supported {
+			if p == s {
+				return p
+			}
+		}
+	}
+	return ""
+}
+
+// WebSocket registers a websocket endpoint under GET
+// The route is marked as a websocket in the docs and route table; plain
+// requests and other methods are answered with 426 Upgrade Required
+func (r *Router) WebSocket(path string, handler WebSocketHandler, cfg WebSocketConfig) *RouteInfo {
+	info := r.addRoute(path, traceLayer("handler", withGuards(websocketEndpoint(handler, cfg))), nil, callerLocation(1), "GET")
+	return markWebSocket(info, cfg)
+}
+
+// WebSocket registers a websocket endpoint under GET within the group
+func (g *Group) WebSocket(path string, handler WebSocketHandler, cfg WebSocketConfig) *RouteInfo {
+	info := g.addRoute(path, traceLayer("handler", withGuards(websocketEndpoint(handler, cfg))), nil, callerLocation(1), "GET")
+	return markWebSocket(info, cfg)
+}
+
+// markWebSocket flags a registered route as a websocket endpoint
+func markWebSocket(info *RouteInfo, cfg WebSocketConfig) *RouteInfo {
+	if info == nil {
+		return nil
+	}
+	info.Protocol = "websocket"
+	info.Subprotocols = cfg.Subprotocols
+	return info
+}
+
+// websocketEndpoint answers anything but a GET upgrade with 426
+func websocketEndpoint(handler WebSocketHandler, cfg WebSocketConfig) Handler {
+	upgrade := WebSocketRoute(handler, cfg)
+
+	return func(r *Request, w *Writer) {
+		if r.Method != "GET" || !isUpgradeRequest(r) || r.Headers.Get("Sec-WebSocket-Version") != "13" {
+			w.Headers.Add("Upgrade", "websocket")
+			w.Headers.Add("Connection", "Upgrade")
+			w.Headers.Add("Sec-WebSocket-Version", "13")
+			w.WriteHeader(http.StatusUpgradeRequired)
+			return
+		}
+		upgrade(r, w)
+	}
+}
+
+// wsBase returns the websocket base URL of the served application for the
+// docs, or "" when it is not known yet
+func (r *Router) wsBase() string {
+	r.docsMu.Lock()
+	defer r.docsMu.Unlock()
+	return r.serveURL
+}
+
+// setServeAddr records where the application is served for the docs
+// Unspecified hosts (":8080", "[::]:8080") are shown as localhost
+func (r *Router) setServeAddr(addr net.Addr, secure bool) {
+	host, port, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return
+	}
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		host = "localhost"
+	}
+
+	scheme := "ws://"
+	if secure {
+		scheme = "wss://"
+	}
+
+	r.docsMu.Lock()
+	r.serveURL = scheme + net.JoinHostPort(host, port)
+	r.docsMu.Unlock()
+}
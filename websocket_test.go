@@ -0,0 +1,92 @@
+package gouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const wsHandshake = "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+	"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"
+
+func TestWebSocketRouteUpgradeRequired(t *testing.T) {
+	r := newTestRouter()
+	r.WebSocket("/ws", func(ws *WebSocket, req *Request) { ws.Close() }, WebSocketConfig{})
+	addr := serveRouter(t, r)
+
+	for name, raw := range map[string]string{
+		"plain GET":     "GET /ws HTTP/1.1\r\nHost: x\r\n\r\n",
+		"wrong version": wsHandshake + "Sec-WebSocket-Version: 8\r\n\r\n",
+		"no upgrade":    "GET /ws HTTP/1.1\r\nHost: x\r\nConnection: keep-alive\r\nSec-WebSocket-Version: 13\r\n\r\n",
+	} {
+		resp := rawExchange(t, addr, raw, 1)[0]
+		if resp.StatusCode != http.StatusUpgradeRequired {
+			t.Errorf("%s: status %d, want 426", name, resp.StatusCode)
+			continue
+		}
+		if resp.Header.Get("Upgrade") != "websocket" || resp.Header.Get("Sec-WebSocket-Version") != "13" ||
+			!strings.EqualFold(resp.Header.Get("Connection"), "Upgrade") {
+			t.Errorf("%s: 426 headers = %v, want the upgrade headers", name, resp.Header)
+		}
+	}
+
+	// Websocket routes are only registered under GET
+	resp := rawExchange(t, addr, "POST /ws HTTP/1.1\r\nHost: x\r\nContent-Length: 0\r\n\r\n", 1)[0]
+	if resp.StatusCode != http.StatusMethodNotAllowed || !strings.Contains(resp.Header.Get("Allow"), "GET") {
+		t.Errorf("POST /ws = %d Allow %q, want 405 allowing GET", resp.StatusCode, resp.Header.Get("Allow"))
+	}
+
+	resp = rawExchange(t, addr, wsHandshake+"Sec-WebSocket-Version: 13\r\n\r\n", 1)[0]
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("handshake = %d %v, want 101", resp.StatusCode, resp.Header)
+	}
+}
+
+func TestWebSocketRouteDocs(t *testing.T) {
+	r := newTestRouter()
+	info := r.WebSocket("/ws", func(ws *WebSocket, req *Request) {}, WebSocketConfig{Subprotocols: []string{"chat.v2", "chat.v1"}})
+	r.Get("/plain", func(req *Request, w *Writer) {})
+
+	if info.Protocol != "websocket" || info.Method != "GET" {
+		t.Errorf("route info = %s %q, want a GET websocket route", info.Method, info.Protocol)
+	}
+
+	data, _ := r.ExportTable()
+	entries, err := ParseTable(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		want := ""
+		if e.Path == "/ws" {
+			want = "websocket"
+		}
+		if e.Protocol != want {
+			t.Errorf("%s protocol = %q, want %q", e.Path, e.Protocol, want)
+		}
+	}
+
+	raw, _ := json.Marshal(info)
+	if !strings.Contains(string(raw), `"Protocol":"websocket"`) || !strings.Contains(string(raw), `"Subprotocols":["chat.v2","chat.v1"]`) {
+		t.Errorf("docs JSON %s lacks the websocket flag and subprotocols", raw)
+	}
+
+	rec := &Writer{Headers: make(Headers)}
+	r.serveDocs(&Request{Headers: make(Headers)}, "/", rec)
+	page := string(rec.body)
+	for _, want := range []string{`method-ws">WS</span>`, `wsURL('\/ws')`, "<code>chat.v2</code>, <code>chat.v1</code>"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("docs page lacks %q", want)
+		}
+	}
+	if strings.Count(page, "wsURL('") != 1 {
+		t.Error("plain routes copy websocket URLs")
+	}
+
+	doc, _ := r.OpenAPI()
+	if strings.Contains(string(doc), `"/ws"`) {
+		t.Error("OpenAPI describes the websocket route as a plain operation")
+	}
+}
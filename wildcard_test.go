@@ -0,0 +1,81 @@
+package gouter
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// paramDump answers with the route name and the sorted parameters
+func paramDump(name string) Handler {
+	return func(req *Request, w *Writer) {
+		var pairs []string
+		for k, v := range req.Params {
+			pairs = append(pairs, k+"="+v)
+		}
+		sort.Strings(pairs)
+		w.Write([]byte(name + " " + strings.Join(pairs, ",")))
+	}
+}
+
+func TestNamedWildcard(t *testing.T) {
+	r := newTestRouter()
+	files := r.Get("/files/*filepath", paramDump("wild"))
+	r.Get("/files/readme", paramDump("static"))
+	r.Get("/files/:name/raw", paramDump("raw"))
+	r.Get("/users/:id/files/*rest", paramDump("nested"))
+	r.Get("/anon/*", paramDump("anon"))
+	addr := serveRouter(t, r)
+
+	cases := []struct{ target, want string }{
+		{"/files/docs/a.txt", "wild filepath=docs/a.txt"},
+		{"/files/a.txt", "wild filepath=a.txt"},
+		// The prefix itself binds an empty value
+		{"/files", "wild filepath="},
+		{"/files/", "wild filepath="},
+		// Static and parameter routes take precedence where they match
+		{"/files/readme", "static "},
+		{"/files/x/raw", "raw name=x"},
+		// A failed parameter branch leaves no parameter behind
+		{"/files/x/raw/more", "wild filepath=x/raw/more"},
+		{"/files/readme/more", "wild filepath=readme/more"},
+		{"/users/7/files/a/b", "nested id=7,rest=a/b"},
+		// An unnamed wildcard binds nothing
+		{"/anon/a/b", "anon "},
+		{"/files/a%20b/c", "wild filepath=a%20b/c"},
+	}
+	var raw strings.Builder
+	for _, c := range cases {
+		raw.WriteString("GET " + c.target + " HTTP/1.1\r\nHost: x\r\n\r\n")
+	}
+	for i, resp := range rawExchange(t, addr, raw.String(), len(cases)) {
+		if got := bodyString(t, resp); got != cases[i].want {
+			t.Errorf("GET %s = %q, want %q", cases[i].target, got, cases[i].want)
+		}
+	}
+
+	var names []string
+	for _, p := range files.Parameters {
+		names = append(names, p.Name)
+	}
+	if !reflect.DeepEqual(names, []string{"filepath"}) {
+		t.Errorf("docs parameters = %v, want [filepath]", names)
+	}
+}
+
+func TestMisplacedWildcard(t *testing.T) {
+	r := newTestRouter()
+	for _, pattern := range []string{"/files/*path/more", "/files/x*", "/files/*a-b", "/*a/*b", "/files/**"} {
+		if r.Get(pattern, paramDump("bad")) != nil {
+			t.Errorf("%s was registered", pattern)
+		}
+	}
+	if r.Get("/files/*path", paramDump("ok")) == nil {
+		t.Error("a valid wildcard was refused after the misplaced ones")
+	}
+	// One wildcard per prefix, named or not
+	if r.Get("/files/*", paramDump("anon")) != nil {
+		t.Error("a second wildcard on /files was registered")
+	}
+}
@@ -0,0 +1,357 @@
+package gouter
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SendPolicy decides what Send does when a connection's queue is full
+type SendPolicy int
+
+const (
+	BlockWhenFull     SendPolicy = iota // Wait for space; one slow reader stalls the sender
+	DropOldest                          // Discard the oldest queued message to make room
+	DropNewest                          // Discard the message being sent
+	CloseSlowConsumer                   // Drop while full, close once full for SlowAfter
+)
+
+const (
+	defaultQueueSize = 64
+	defaultSlowAfter = 5 * time.Second
+)
+
+// ErrSlowConsumer is returned by Send once a connection was closed for not
+// draining its queue
+var ErrSlowConsumer = errors.New("websocket closed: slow consumer")
+
+// ErrQueueClosed is returned by Send after the connection was closed
+var ErrQueueClosed = errors.New("websocket send queue closed")
+
+// QueueConfig configures the send queue of a connection
+type QueueConfig struct {
+	Size      int           // Maximum queued messages (default: 64)
+	Policy    SendPolicy    // Behavior when the queue is full (default: BlockWhenFull)
+	SlowAfter time.Duration // How long the queue may stay full under CloseSlowConsumer (default: 5s)
+}
+
+// QueueStats counts what happened to the messages given to Send
+type QueueStats struct {
+	Sent          uint64 // Messages written to the connection
+	DroppedOldest uint64 // Queued messages discarded by DropOldest
+	DroppedNewest uint64 // Messages rejected by DropNewest or while full under CloseSlowConsumer
+	SlowClosed    uint64 // Connections closed by CloseSlowConsumer
+}
+
+// queueCounters is the atomic form of QueueStats, shared by a connection
+// and its hub
+type queueCounters struct {
+	sent          atomic.Uint64
+	droppedOldest atomic.Uint64
+	droppedNewest atomic.Uint64
+	slowClosed    atomic.Uint64
+}
+
+func (c *queueCounters) stats() QueueStats {
+	return QueueStats{
+		Sent:          c.sent.Load(),
+		DroppedOldest: c.droppedOldest.Load(),
+		DroppedNewest: c.droppedNewest.Load(),
+		SlowClosed:    c.slowClosed.Load(),
+	}
+}
+
+// sendQueue buffers outgoing messages for a single writer goroutine
+type sendQueue struct {
+	cfg      QueueConfig
+	mu       sync.Mutex
+	space    *sync.Cond // Signaled when a message leaves the queue
+	ready    chan struct{}
+	msgs     [][]byte
+	fullAt   time.Time // When the queue last became full, zero if not full
+	closed   bool
+	closeErr error
+
+	counters []*queueCounters // Connection counters, then hub counters; guarded by mu
+}
+
+// newSendQueue applies the config defaults and creates an empty queue
+func newSendQueue(cfg QueueConfig) *sendQueue {
+	if cfg.Size <= 0 {
+		cfg.Size = defaultQueueSize
+	}
+	if cfg.SlowAfter <= 0 {
+		cfg.SlowAfter = defaultSlowAfter
+	}
+	q := &sendQueue{
+		cfg:   cfg,
+		ready: make(chan struct{}, 1),
+	}
+	q.space = sync.NewCond(&q.mu)
+	return q
+}
+
+// count increments a counter on the connection and its hubs
+// Callers hold q.mu
+func (q *sendQueue) count(field func(c *queueCounters) *atomic.Uint64) {
+	for _, c := range q.counters {
+		field(c).Add(1)
+	}
+}
+
+// push enqueues a message following the policy; with wait false,
+// BlockWhenFull drops the message like DropNewest instead of waiting
+// Returns true when the connection must be closed as a slow consumer
+func (q *sendQueue) push(msg []byte, wait bool) (bool, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false, q.closeErr
+	}
+
+	if len(q.msgs) >= q.cfg.Size {
+		if q.fullAt.IsZero() {
+			q.fullAt = time.Now()
+		}
+
+		switch q.cfg.Policy {
+		case DropOldest:
+			q.msgs = q.msgs[1:]
+			q.count(func(c *queueCounters) *atomic.Uint64 { return &c.droppedOldest })
+		case DropNewest:
+			q.count(func(c *queueCounters) *atomic.Uint64 { return &c.droppedNewest })
+			return false, nil
+		case CloseSlowConsumer:
+			if time.Since(q.fullAt) >= q.cfg.SlowAfter {
+				q.closed, q.closeErr = true, ErrSlowConsumer
+				q.count(func(c *queueCounters) *atomic.Uint64 { return &c.slowClosed })
+				q.space.Broadcast()
+				return true, ErrSlowConsumer
+			}
+			q.count(func(c *queueCounters) *atomic.Uint64 { return &c.droppedNewest })
+			return false, nil
+		default:
+			if !wait {
+				q.count(func(c *queueCounters) *atomic.Uint64 { return &c.droppedNewest })
+				return false, nil
+			}
+			for len(q.msgs) >= q.cfg.Size && !q.closed {
+				q.space.Wait()
+			}
+			if q.closed {
+				return false, q.closeErr
+			}
+		}
+	}
+
+	q.msgs = append(q.msgs, msg)
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+	return false, nil
+}
+
+// pop waits for the next message; returns false once the queue is closed
+func (q *sendQueue) pop() ([]byte, bool) {
+	for {
+		q.mu.Lock()
+		if q.closed {
+			q.mu.Unlock()
+			return nil, false
+		}
+		if len(q.msgs) > 0 {
+			msg := q.msgs[0]
+			q.msgs = q.msgs[1:]
+			q.fullAt = time.Time{}
+			q.space.Signal()
+			q.mu.Unlock()
+			return msg, true
+		}
+		q.mu.Unlock()
+		<-q.ready
+	}
+}
+
+// close stops the queue, releasing blocked senders and the writer
+func (q *sendQueue) close(err error) {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed, q.closeErr = true, err
+	}
+	q.space.Broadcast()
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// len returns the number of queued messages
+func (q *sendQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.msgs)
+}
+
+// SetQueue enables the send queue of the connection with the given config
+// It must be called before the first Send or hub Join to take effect;
+// without it the connection uses the hub config, or the defaults
+func (ws *WebSocket) SetQueue(cfg QueueConfig) {
+	ws.startQueue(cfg)
+}
+
+// startQueue returns the queue of the connection, creating it and its
+// writer goroutine on first use
+func (ws *WebSocket) startQueue(cfg QueueConfig) *sendQueue {
+	if q := ws.queue.Load(); q != nil {
+		return q
+	}
+
+	ws.queueMu.Lock()
+	defer ws.queueMu.Unlock()
+	if q := ws.queue.Load(); q != nil {
+		return q
+	}
+
+	q := newSendQueue(cfg)
+	q.counters = []*queueCounters{&ws.counters}
+	ws.queue.Store(q)
+	spawn("ws-queue", func() { ws.drain(q) })
+	return q
+}
+
+// drain writes queued messages until the queue or the connection closes
+func (ws *WebSocket) drain(q *sendQueue) {
+	for {
+		msg, ok := q.pop()
+		if !ok {
+			return
+		}
+		if err := ws.WriteMessage(msg); err != nil {
+			q.close(err)
+			ws.conn.Close()
+			return
+		}
+		q.mu.Lock()
+		q.count(func(c *queueCounters) *atomic.Uint64 { return &c.sent })
+		q.mu.Unlock()
+	}
+}
+
+// Send queues a message for writing by the connection's writer goroutine
+// Unlike WriteMessage it never waits on the network, except under
+// BlockWhenFull; a full queue is handled by the configured policy
+func (ws *WebSocket) Send(message []byte) error {
+	return ws.send(message, true)
+}
+
+// send queues a message, waiting for space under BlockWhenFull when wait
+// is true
+func (ws *WebSocket) send(message []byte, wait bool) error {
+	q := ws.startQueue(QueueConfig{})
+	slow, err := q.push(message, wait)
+	if slow {
+		ws.Close()
+	}
+	return err
+}
+
+// QueueLen returns the number of messages waiting to be written
+// Applications can use it to shed load before the policy kicks in
+func (ws *WebSocket) QueueLen() int {
+	if q := ws.queue.Load(); q != nil {
+		return q.len()
+	}
+	return 0
+}
+
+// Stats returns the send queue counters of the connection
+func (ws *WebSocket) Stats() QueueStats {
+	return ws.counters.stats()
+}
+
+// Hub broadcasts messages to a set of websocket connections
+// Each member gets its own send queue, so a slow reader only affects itself:
+// Broadcast never waits for a member, even under BlockWhenFull
+type Hub struct {
+	cfg      QueueConfig
+	mu       sync.RWMutex
+	members  map[*WebSocket]struct{}
+	counters queueCounters
+}
+
+// NewHub creates a hub whose members use cfg unless they called SetQueue
+func NewHub(cfg QueueConfig) *Hub {
+	return &Hub{
+		cfg:     cfg,
+		members: make(map[*WebSocket]struct{}),
+	}
+}
+
+// Join adds a connection to the hub; joining twice has no effect
+func (h *Hub) Join(ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.members[ws]; ok {
+		return
+	}
+	h.members[ws] = struct{}{}
+
+	q := ws.startQueue(h.cfg)
+	q.mu.Lock()
+	q.counters = append(q.counters, &h.counters)
+	q.mu.Unlock()
+}
+
+// Leave removes a connection from the hub, which stops counting its
+// messages in Stats
+func (h *Hub) Leave(ws *WebSocket) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.members[ws]; !ok {
+		return
+	}
+	delete(h.members, ws)
+
+	q := ws.queue.Load()
+	q.mu.Lock()
+	q.counters = slices.DeleteFunc(q.counters, func(c *queueCounters) bool { return c == &h.counters })
+	q.mu.Unlock()
+}
+
+// Broadcast sends a message to every member without waiting for space in
+// their queues: a full queue under BlockWhenFull drops the message for that
+// member, counted in DroppedNewest
+// Members closed as slow consumers or failing to write are removed
+func (h *Hub) Broadcast(message []byte) {
+	h.mu.RLock()
+	members := make([]*WebSocket, 0, len(h.members))
+	for ws := range h.members {
+		members = append(members, ws)
+	}
+	h.mu.RUnlock()
+
+	for _, ws := range members {
+		if err := ws.send(message, false); err != nil {
+			h.Leave(ws)
+		}
+	}
+}
+
+// Len returns the number of members
+func (h *Hub) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.members)
+}
+
+// Stats returns the send queue counters summed over every connection that
+// joined the hub
+func (h *Hub) Stats() QueueStats {
+	return h.counters.stats()
+}
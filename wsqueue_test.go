@@ -0,0 +1,179 @@
+package gouter
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// pipeSocket returns a websocket over an in-memory pipe and its client end
+// The client end is not read unless the test reads it
+func pipeSocket(t *testing.T) (*WebSocket, net.Conn) {
+	t.Helper()
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return &WebSocket{conn: server}, client
+}
+
+// readFrames counts the frames sent to client until it is closed
+func readFrames(client net.Conn) <-chan int {
+	count := make(chan int, 1)
+	go func() {
+		n := 0
+		head := make([]byte, 2)
+		for {
+			if _, err := io.ReadFull(client, head); err != nil {
+				count <- n
+				return
+			}
+			if _, err := io.CopyN(io.Discard, client, int64(head[1]&0x7f)); err != nil {
+				count <- n
+				return
+			}
+			n++
+		}
+	}()
+	return count
+}
+
+// stall fills the queue of a connection whose client does not read: the
+// writer goroutine blocks on the first message, the next ones stay queued
+func stall(t *testing.T, ws *WebSocket, queued int) {
+	t.Helper()
+	ws.Send([]byte("first"))
+	eventually(t, "the writer to take the first message", func() bool { return ws.QueueLen() == 0 })
+	for i := 0; i < queued; i++ {
+		ws.Send([]byte{byte('a' + i)})
+	}
+}
+
+func TestSendPolicies(t *testing.T) {
+	t.Run("drop oldest", func(t *testing.T) {
+		ws, _ := pipeSocket(t)
+		ws.SetQueue(QueueConfig{Size: 2, Policy: DropOldest})
+		stall(t, ws, 2)
+		ws.Send([]byte("c"))
+
+		q := ws.queue.Load()
+		q.mu.Lock()
+		queued := string(q.msgs[0]) + string(q.msgs[1])
+		q.mu.Unlock()
+		if queued != "bc" || ws.Stats().DroppedOldest != 1 {
+			t.Errorf("queue %q with %+v, want bc and one dropped", queued, ws.Stats())
+		}
+	})
+
+	t.Run("drop newest", func(t *testing.T) {
+		ws, _ := pipeSocket(t)
+		ws.SetQueue(QueueConfig{Size: 2, Policy: DropNewest})
+		stall(t, ws, 2)
+		if err := ws.Send([]byte("c")); err != nil {
+			t.Fatal(err)
+		}
+		if ws.QueueLen() != 2 || ws.Stats().DroppedNewest != 1 {
+			t.Errorf("queue len %d with %+v, want 2 and one dropped", ws.QueueLen(), ws.Stats())
+		}
+	})
+
+	t.Run("close slow consumer", func(t *testing.T) {
+		ws, _ := pipeSocket(t)
+		ws.SetQueue(QueueConfig{Size: 1, Policy: CloseSlowConsumer, SlowAfter: 20 * time.Millisecond})
+		stall(t, ws, 1)
+		if err := ws.Send([]byte("dropped")); err != nil {
+			t.Fatalf("send while full = %v, want a drop", err)
+		}
+		time.Sleep(30 * time.Millisecond)
+		if err := ws.Send([]byte("late")); !errors.Is(err, ErrSlowConsumer) {
+			t.Errorf("send past SlowAfter = %v, want ErrSlowConsumer", err)
+		}
+		if err := ws.Send([]byte("after")); err == nil {
+			t.Errorf("send after close succeeded")
+		}
+		if st := ws.Stats(); st.SlowClosed != 1 || st.DroppedNewest != 1 {
+			t.Errorf("stats = %+v, want one drop and one close", st)
+		}
+	})
+
+	t.Run("block when full", func(t *testing.T) {
+		ws, client := pipeSocket(t)
+		ws.SetQueue(QueueConfig{Size: 1})
+		stall(t, ws, 1)
+
+		sent := make(chan error, 1)
+		go func() { sent <- ws.Send([]byte("waits")) }()
+		select {
+		case err := <-sent:
+			t.Fatalf("Send returned %v on a full queue", err)
+		case <-time.After(20 * time.Millisecond):
+		}
+		readFrames(client)
+		if err := <-sent; err != nil {
+			t.Errorf("Send after the reader caught up = %v", err)
+		}
+	})
+}
+
+func TestHubBroadcastDoesNotWaitForSlowMembers(t *testing.T) {
+	// Under BlockWhenFull, the default
+	h := NewHub(QueueConfig{Size: 64})
+	slow, _ := pipeSocket(t)
+	slow.SetQueue(QueueConfig{Size: 2})
+	fast, client := pipeSocket(t)
+	frames := readFrames(client)
+	h.Join(slow)
+	h.Join(fast)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 20; i++ {
+			h.Broadcast([]byte("m"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Broadcast blocked on a member that does not read")
+	}
+
+	eventually(t, "the fast member to receive every message", func() bool { return fast.Stats().Sent == 20 })
+	client.Close()
+	if n := <-frames; n != 20 {
+		t.Errorf("fast member received %d frames, want 20", n)
+	}
+	if st := slow.Stats(); st.DroppedNewest == 0 {
+		t.Errorf("slow member stats = %+v, want dropped messages", st)
+	}
+	if h.Len() != 2 {
+		t.Errorf("hub has %d members, want 2", h.Len())
+	}
+}
+
+func TestHubLeaveStopsCounting(t *testing.T) {
+	h := NewHub(QueueConfig{})
+	ws, client := pipeSocket(t)
+	readFrames(client)
+
+	h.Join(ws)
+	h.Join(ws)
+	ws.Send([]byte("joined"))
+	eventually(t, "the first message", func() bool { return ws.Stats().Sent == 1 })
+	if st := h.Stats(); st.Sent != 1 {
+		t.Errorf("hub Sent after joining twice = %d, want 1", st.Sent)
+	}
+
+	h.Leave(ws)
+	ws.Send([]byte("left"))
+	eventually(t, "the second message", func() bool { return ws.Stats().Sent == 2 })
+	if st := h.Stats(); st.Sent != 1 {
+		t.Errorf("hub Sent after Leave = %d, want 1", st.Sent)
+	}
+	if h.Len() != 0 {
+		t.Errorf("hub has %d members after Leave, want 0", h.Len())
+	}
+}